@@ -0,0 +1,64 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+// ConvertTo converts this ImageUpdateAutomation to the hub version, v1beta2.
+// Both versions share the same fields, which are converted as they are, the
+// fields of v1beta2 only being left empty.
+func (src *ImageUpdateAutomation) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*v1beta2.ImageUpdateAutomation)
+	if !ok {
+		return fmt.Errorf("unsupported conversion hub type %T", dstRaw)
+	}
+	return convertFields(src, dst)
+}
+
+// ConvertFrom converts the hub version, v1beta2, to this
+// ImageUpdateAutomation. The fields of v1beta2 only are dropped.
+func (dst *ImageUpdateAutomation) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*v1beta2.ImageUpdateAutomation)
+	if !ok {
+		return fmt.Errorf("unsupported conversion hub type %T", srcRaw)
+	}
+	return convertFields(src, dst)
+}
+
+// convertFields converts the object to the other version through their JSON
+// fields, as the API server did before the conversion webhook was served,
+// keeping the type of the destination.
+func convertFields(src, dst runtime.Object) error {
+	gvk := dst.GetObjectKind().GroupVersionKind()
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, dst); err != nil {
+		return err
+	}
+	dst.GetObjectKind().SetGroupVersionKind(gvk)
+	return nil
+}
@@ -16,6 +16,65 @@ limitations under the License.
 
 package v1beta2
 
+const (
+	// ChecksPassedCondition indicates whether the status checks of the last
+	// pushed commit passed on the Git provider.
+	ChecksPassedCondition string = "ChecksPassed"
+
+	// IntervalStretchedCondition indicates that the automation is
+	// reconciled less often than its interval, because its reconciliations
+	// take too long compared to the interval.
+	IntervalStretchedCondition string = "IntervalStretched"
+
+	// DriftedCondition indicates that the checked out source doesn't have
+	// the latest images of policies which were already handled, e.g.
+	// because the commit of the update was reverted.
+	DriftedCondition string = "Drifted"
+
+	// CrossNamespaceMarkersCondition indicates that the checked out source
+	// has markers referring to policies in another namespace than the one
+	// of the automation, which are never updated.
+	CrossNamespaceMarkersCondition string = "CrossNamespaceMarkers"
+)
+
+const (
+	// PolicyDriftReason represents policies whose latest images were already
+	// handled, but aren't in the checked out source.
+	PolicyDriftReason string = "PolicyDrift"
+
+	// PolicyNamespaceMismatchReason represents markers referring to policies
+	// in another namespace than the one of the automation.
+	PolicyNamespaceMismatchReason string = "PolicyNamespaceMismatch"
+)
+
+const (
+	// SlowReconciliationReason represents reconciliations whose mean
+	// duration is too long compared to the interval of the automation.
+	SlowReconciliationReason string = "SlowReconciliation"
+)
+
+const (
+	// ChecksSucceededReason represents the success of all the status checks
+	// of the last pushed commit.
+	ChecksSucceededReason string = "ChecksSucceeded"
+
+	// ChecksPendingReason represents status checks of the last pushed commit
+	// which are still running.
+	ChecksPendingReason string = "ChecksPending"
+
+	// ChecksFailedReason represents a failure of a status check of the last
+	// pushed commit.
+	ChecksFailedReason string = "ChecksFailed"
+
+	// ChecksTimeoutReason represents status checks of the last pushed commit
+	// which were still pending after the timeout of the checks.
+	ChecksTimeoutReason string = "ChecksTimeout"
+
+	// ChecksUnavailableReason represents a failure to query the status
+	// checks of the last pushed commit from the Git provider.
+	ChecksUnavailableReason string = "ChecksUnavailable"
+)
+
 const (
 	// InvalidUpdateStrategyReason represents an invalid image update strategy
 	// configuration.
@@ -28,12 +87,155 @@ const (
 	// manages the source.
 	SourceManagerFailedReason string = "SourceManagerFailed"
 
+	// ImpersonationFailedReason represents a failure to create the client
+	// impersonating the ServiceAccount of the automation.
+	ImpersonationFailedReason string = "ImpersonationFailed"
+
 	// GitOperationFailedReason represents a failure in Git source operation.
 	GitOperationFailedReason string = "GitOperationFailed"
 
 	// UpdateFailedReason represents a failure during source update.
 	UpdateFailedReason string = "UpdateFailed"
 
+	// UpdateConflictReason represents an update which failed due to a marked
+	// field changed since the previous update, with the Fail conflict policy.
+	UpdateConflictReason string = "UpdateConflict"
+
+	// UpdateIncompleteReason represents an atomic update which failed due to
+	// files with markers which couldn't be processed.
+	UpdateIncompleteReason string = "UpdateIncomplete"
+
+	// SemverJumpExceededReason represents an update which was refused because
+	// the version of an image would change more than the configured maximum
+	// semver jump.
+	SemverJumpExceededReason string = "SemverJumpExceeded"
+
+	// OwnershipMismatchReason represents an update which was refused because
+	// it would modify files which aren't owned by the owner of the update.
+	OwnershipMismatchReason string = "OwnershipMismatch"
+
+	// PoliciesSkippedReason represents policies left out of an update, e.g.
+	// because they have no latest image.
+	PoliciesSkippedReason string = "PoliciesSkipped"
+
+	// RemoteChangedReason represents a push which was aborted because the
+	// head of the push branch on the remote moved since the checkout.
+	RemoteChangedReason string = "RemoteChanged"
+
+	// ObjectsIgnoredReason represents changes which weren't made because
+	// their objects opt out of the updates with the
+	// image.toolkit.fluxcd.io/ignore annotation.
+	ObjectsIgnoredReason string = "ObjectsIgnored"
+
+	// RemotePushFailedReason represents a failure to push to an additional
+	// remote.
+	RemotePushFailedReason string = "RemotePushFailed"
+
+	// CommitStatusFailedReason represents a failure to set the commit status
+	// of a pushed commit on the Git provider.
+	CommitStatusFailedReason string = "CommitStatusFailed"
+
+	// ImageRevokedReason represents a committed image which isn't a
+	// candidate of its policy anymore, reverted with .spec.update.autoRevert.
+	ImageRevokedReason string = "ImageRevoked"
+
+	// PostPushHookFailedReason represents a failure to notify a post-push
+	// hook of a successful push.
+	PostPushHookFailedReason string = "PostPushHookFailed"
+
+	// ChangesExportFailedReason represents a failure to export the changes
+	// of a successful push.
+	ChangesExportFailedReason string = "ChangesExportFailed"
+
+	// FallbackCredentialsUsedReason represents a push which succeeded with
+	// the fallback credentials, the remote having rejected the credentials
+	// of the GitRepository.
+	FallbackCredentialsUsedReason string = "FallbackCredentialsUsed"
+
+	// CommitMessageTruncatedReason represents a commit message which was
+	// truncated to the maximum size of the commit message.
+	CommitMessageTruncatedReason string = "CommitMessageTruncated"
+
 	// InvalidPolicySelectorReason represents an invalid policy selector.
 	InvalidPolicySelectorReason string = "InvalidPolicySelector"
+
+	// SourceVerificationFailedReason represents a failure to verify the
+	// signature of the checked out commit with the verification configuration
+	// of the source.
+	SourceVerificationFailedReason string = "SourceVerificationFailed"
+
+	// RepeatedFailureReason represents a reconciliation which failed
+	// repeatedly with the same error, and is retried at a long interval until
+	// the ImageUpdateAutomation, its GitRepository or the Secret of the
+	// GitRepository changes.
+	RepeatedFailureReason string = "RepeatedFailure"
+
+	// InvalidTemplateReason represents a commit message or tag name template
+	// which can't be rendered.
+	InvalidTemplateReason string = "InvalidTemplate"
+
+	// SigningVerificationFailedReason represents a signature made with the
+	// signing key which can't be verified, e.g. because the key expired or
+	// doesn't match the verification keys of the source.
+	SigningVerificationFailedReason string = "SigningVerificationFailed"
+
+	// ProtectedBranchReason represents a push branch matching one of the
+	// branches protected with the controller --protected-branches flag.
+	ProtectedBranchReason string = "ProtectedBranch"
+
+	// SigningRequiredReason represents an automation without signing key
+	// whose commits must be signed, as required with the controller
+	// --require-signed-commits flag or the annotation of its Namespace.
+	SigningRequiredReason string = "SigningRequired"
+
+	// SourceSuspendedReason represents a GitRepository which is suspended.
+	SourceSuspendedReason string = "SourceSuspended"
+
+	// SourceNotReadyReason represents a GitRepository whose Ready condition
+	// is False.
+	SourceNotReadyReason string = "SourceNotReady"
+
+	// WorktreeTooLargeReason represents a checked out source whose size
+	// exceeds the maximum size set with the controller --max-worktree-size
+	// flag.
+	WorktreeTooLargeReason string = "WorktreeTooLarge"
+
+	// ValidationFailedReason represents changed manifests which don't conform
+	// to the schemas of their kinds.
+	ValidationFailedReason string = "ValidationFailed"
+
+	// SuspendedReason represents an automation which was suspended until
+	// the time set with .spec.suspendUntil.
+	SuspendedReason string = "Suspended"
+
+	// ResumedReason represents an automation which resumed after the time set
+	// with .spec.suspendUntil, or because the field was unset.
+	ResumedReason string = "Resumed"
+
+	// RepositoryMovedReason represents a remote repository which redirected
+	// the Git operations to another URL, e.g. because it was renamed.
+	RepositoryMovedReason string = "RepositoryMoved"
+)
+
+// The reasons of the Warning events of the failures, more specific than the
+// reason of the Ready condition, e.g. GitOperationFailedReason, for the alerts
+// to be routed by reason.
+const (
+	// CloneFailedReason represents a failure to clone the source.
+	CloneFailedReason string = "CloneFailed"
+
+	// PushFailedReason represents a failure to commit or push the changes.
+	PushFailedReason string = "PushFailed"
+
+	// AuthFailedReason represents a failure to authenticate or to be
+	// authorized by the remote repository.
+	AuthFailedReason string = "AuthFailed"
+
+	// SignFailedReason represents a signing key which can't be read, decrypted
+	// or selected for the push branch.
+	SignFailedReason string = "SignFailed"
+
+	// TemplateFailedReason represents a commit message, author or tag name
+	// template which can't be rendered.
+	TemplateFailedReason string = "TemplateFailed"
 )
@@ -0,0 +1,179 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta2
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+)
+
+// NewImageUpdateAutomation returns an ImageUpdateAutomation with the given
+// name, namespace and spec, and its type meta set. The spec can be checked
+// with its Validate method before the object is applied.
+func NewImageUpdateAutomation(namespace, name string, spec ImageUpdateAutomationSpec) *ImageUpdateAutomation {
+	return &ImageUpdateAutomation{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: GroupVersion.String(),
+			Kind:       ImageUpdateAutomationKind,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: spec,
+	}
+}
+
+// NewImageUpdateAutomationSpec returns a spec running the automation every
+// interval on the GitRepository with the given name, in the namespace of the
+// automation, with the Setters strategy on the whole repository.
+func NewImageUpdateAutomationSpec(sourceName string, interval time.Duration, gitSpec *GitSpec) ImageUpdateAutomationSpec {
+	return ImageUpdateAutomationSpec{
+		SourceRef: CrossNamespaceSourceReference{
+			Kind: sourcev1.GitRepositoryKind,
+			Name: sourceName,
+		},
+		GitSpec:  gitSpec,
+		Interval: metav1.Duration{Duration: interval},
+		Update:   NewUpdateStrategy(UpdateStrategySetters, ""),
+	}
+}
+
+// NewUpdateStrategy returns an UpdateStrategy applying the given strategy to
+// the given path, which can be a glob pattern.
+func NewUpdateStrategy(strategy UpdateStrategyName, path string) *UpdateStrategy {
+	return &UpdateStrategy{
+		Strategy: strategy,
+		Path:     path,
+	}
+}
+
+// GitSpecOption is a functional option to configure a GitSpec built with
+// NewGitSpec.
+// +kubebuilder:object:generate=false
+type GitSpecOption func(*GitSpec)
+
+// WithGitSpecCheckoutBranch sets the branch checked out to make the changes.
+func WithGitSpecCheckoutBranch(branch string) GitSpecOption {
+	return func(gs *GitSpec) {
+		if gs.Checkout == nil {
+			gs.Checkout = &GitCheckoutSpec{}
+		}
+		gs.Checkout.Reference = sourcev1.GitRepositoryRef{Branch: branch}
+	}
+}
+
+// WithGitSpecCheckoutRef sets the reference checked out to make the changes,
+// e.g. a tag or a commit.
+func WithGitSpecCheckoutRef(ref sourcev1.GitRepositoryRef) GitSpecOption {
+	return func(gs *GitSpec) {
+		if gs.Checkout == nil {
+			gs.Checkout = &GitCheckoutSpec{}
+		}
+		gs.Checkout.Reference = ref
+	}
+}
+
+// WithGitSpecCheckoutStrategy sets how the source is cloned, overriding the
+// GitShallowClone feature gate of the controller.
+func WithGitSpecCheckoutStrategy(strategy CheckoutStrategy) GitSpecOption {
+	return func(gs *GitSpec) {
+		if gs.Checkout == nil {
+			gs.Checkout = &GitCheckoutSpec{}
+		}
+		gs.Checkout.Strategy = strategy
+	}
+}
+
+// WithGitSpecPushBranch sets the branch the commits are pushed to.
+func WithGitSpecPushBranch(branch string) GitSpecOption {
+	return func(gs *GitSpec) {
+		if gs.Push == nil {
+			gs.Push = &PushSpec{}
+		}
+		gs.Push.Branch = branch
+	}
+}
+
+// WithGitSpecPushRefspec sets the refspec the commits are pushed with.
+func WithGitSpecPushRefspec(refspec string) GitSpecOption {
+	return func(gs *GitSpec) {
+		if gs.Push == nil {
+			gs.Push = &PushSpec{}
+		}
+		gs.Push.Refspec = refspec
+	}
+}
+
+// WithGitSpecForcePush sets whether the commits are force pushed to a push
+// branch other than the checkout branch, overriding the GitForcePushBranch
+// feature gate of the controller.
+func WithGitSpecForcePush(force bool) GitSpecOption {
+	return func(gs *GitSpec) {
+		if gs.Push == nil {
+			gs.Push = &PushSpec{}
+		}
+		gs.Push.ForcePush = &force
+	}
+}
+
+// WithGitSpecMessageTemplate sets the template of the commit messages.
+func WithGitSpecMessageTemplate(template string) GitSpecOption {
+	return func(gs *GitSpec) {
+		gs.Commit.MessageTemplate = template
+	}
+}
+
+// WithGitSpecSigningKey signs the commits with the key of the Secret with
+// the given name.
+func WithGitSpecSigningKey(secretName string) GitSpecOption {
+	return func(gs *GitSpec) {
+		gs.Commit.SigningKey = &SigningKey{
+			SecretRef: meta.LocalObjectReference{Name: secretName},
+		}
+	}
+}
+
+// WithGitSpecTag creates or updates the annotated tag with the given name,
+// pointing at each pushed commit.
+func WithGitSpecTag(name string) GitSpecOption {
+	return func(gs *GitSpec) {
+		gs.Tag = &TagSpec{Name: name}
+	}
+}
+
+// NewGitSpec returns a GitSpec committing with the given author, configured
+// with the given options. Without options, the commits are pushed back to the
+// branch of the GitRepository.
+func NewGitSpec(authorName, authorEmail string, opts ...GitSpecOption) *GitSpec {
+	gs := &GitSpec{
+		Commit: CommitSpec{
+			Author: CommitUser{
+				Name:  authorName,
+				Email: authorEmail,
+			},
+		},
+	}
+	for _, o := range opts {
+		o(gs)
+	}
+	return gs
+}
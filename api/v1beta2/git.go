@@ -17,10 +17,18 @@ limitations under the License.
 package v1beta2
 
 import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
 	"github.com/fluxcd/pkg/apis/meta"
 	sourcev1 "github.com/fluxcd/source-controller/api/v1"
 )
 
+// +kubebuilder:validation:XValidation:rule="!has(self.checkout) || !has(self.checkout.ref) || !(has(self.checkout.ref.tag) || has(self.checkout.ref.semver) || has(self.checkout.ref.commit)) || (has(self.push) && (has(self.push.branch) || has(self.push.refspec) || has(self.push.base)))",message="push branch, refspec or base must be set to check out a tag, a semver range or a commit"
+// +kubebuilder:validation:XValidation:rule="!has(self.checkout) || !has(self.checkout.ref) || !has(self.checkout.ref.branch) || !(has(self.checkout.ref.tag) || has(self.checkout.ref.semver) || has(self.checkout.ref.commit)) || !has(self.push) || !has(self.push.branch) || self.push.branch != self.checkout.ref.branch",message="push branch must differ from the checkout branch to check out a commit"
+// +kubebuilder:validation:XValidation:rule="!has(self.checkout) || !has(self.checkout.ref) || !has(self.checkout.ref.branch) || !has(self.push) || !has(self.push.base) || (self.push.base != self.checkout.ref.branch && (!has(self.push.branch) || self.push.branch != self.checkout.ref.branch))",message="base and push branches must differ from the checkout branch"
+// +kubebuilder:validation:XValidation:rule="!has(self.push) || !has(self.push.api) || (!has(self.commit.signingKey) && !has(self.tag))",message="commits made with the provider API can't be signed with a signing key nor tagged"
 type GitSpec struct {
 	// Checkout gives the parameters for cloning the git repository,
 	// ready to make changes. If not present, the `spec.ref` field from the
@@ -37,6 +45,17 @@ type GitSpec struct {
 	// `.spec.checkout.branch` or its default.
 	// +optional
 	Push *PushSpec `json:"push,omitempty"`
+
+	// Tag specifies an annotated tag to create or update, pointing at
+	// each commit pushed by the automation.
+	// +optional
+	Tag *TagSpec `json:"tag,omitempty"`
+}
+
+// HasPerPolicyBranches returns if the changes of each ImagePolicy are pushed
+// to a branch of their own.
+func (gs GitSpec) HasPerPolicyBranches() bool {
+	return gs.Push != nil && gs.Push.PerPolicyBranches
 }
 
 // HasRefspec returns if the GitSpec has a Refspec.
@@ -47,19 +66,55 @@ func (gs GitSpec) HasRefspec() bool {
 	return gs.Push.Refspec != ""
 }
 
+// HasBase returns if the changes are merged into a base branch other than
+// the checkout branch.
+func (gs GitSpec) HasBase() bool {
+	return gs.Push != nil && gs.Push.Base != ""
+}
+
+// +kubebuilder:validation:XValidation:rule="has(self.strategy) || (has(self.ref) && (has(self.ref.branch) || has(self.ref.tag) || has(self.ref.semver) || has(self.ref.name) || has(self.ref.commit)))",message="the checkout must set a reference with a branch, tag, semver range, name or commit, or a strategy"
 type GitCheckoutSpec struct {
 	// Reference gives a branch, tag or commit to clone from the Git
-	// repository.
-	// +required
-	Reference sourcev1.GitRepositoryRef `json:"ref"`
+	// repository. If empty, the `spec.ref` field from the referenced
+	// `GitRepository` or its default will be used.
+	// +optional
+	Reference sourcev1.GitRepositoryRef `json:"ref,omitempty"`
+
+	// Strategy tells how the source is cloned, overriding the
+	// GitShallowClone feature gate of the controller for this automation:
+	// Shallow to clone the latest commit only, or Full to clone the complete
+	// history, e.g. for a Git server mishandling shallow clones.
+	// +kubebuilder:validation:Enum=Shallow;Full
+	// +optional
+	Strategy CheckoutStrategy `json:"strategy,omitempty"`
+}
+
+// HasReference returns whether the checkout gives a reference, instead of
+// using the reference of the GitRepository.
+func (in *GitCheckoutSpec) HasReference() bool {
+	return in != nil && in.Reference != (sourcev1.GitRepositoryRef{})
 }
 
+// CheckoutStrategy is the type of the strategies cloning the source.
+type CheckoutStrategy string
+
+const (
+	// CheckoutStrategyShallow clones the latest commit of the source only.
+	CheckoutStrategyShallow CheckoutStrategy = "Shallow"
+	// CheckoutStrategyFull clones the complete history of the source.
+	CheckoutStrategyFull CheckoutStrategy = "Full"
+)
+
 // CommitSpec specifies how to commit changes to the git repository
 type CommitSpec struct {
 	// Author gives the email and optionally the name to use as the
-	// author of commits.
-	// +required
-	Author CommitUser `json:"author"`
+	// author of commits. Without an email, the author is the `username` and
+	// the `email` of the Secret of the GitRepository the commits are pushed
+	// to, and the automation fails without an email in the Secret. As the
+	// Secret is only read by the controller, the email can't be required by
+	// the schema, with a CEL rule or a validating webhook.
+	// +optional
+	Author CommitUser `json:"author,omitempty"`
 	// SigningKey provides the option to sign commits with a GPG key
 	// +optional
 	SigningKey *SigningKey `json:"signingKey,omitempty"`
@@ -68,41 +123,175 @@ type CommitSpec struct {
 	// +optional
 	MessageTemplate string `json:"messageTemplate,omitempty"`
 
+	// MaxMessageBytes limits the size in bytes of the commit message. A
+	// longer message is truncated at a line boundary, keeping its first
+	// line, and a note of the omitted bytes is appended. Zero means no limit.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxMessageBytes int `json:"maxMessageBytes,omitempty"`
+
 	// MessageTemplateValues provides additional values to be available to the
 	// templating rendering.
 	MessageTemplateValues map[string]string `json:"messageTemplateValues,omitempty"`
+
+	// ValuesFrom references ConfigMaps and Secrets, in the same namespace as
+	// the ImageUpdateAutomation, whose data is merged in order into the
+	// values available to the templates. The MessageTemplateValues take
+	// precedence over the values of the references.
+	// +optional
+	ValuesFrom []ValuesReference `json:"valuesFrom,omitempty"`
+
+	// UpdatePathOnly commits only the changes of the files under the update
+	// path, i.e. the directories it matches and its lock file, discarding
+	// the changes of the other files of the worktree, e.g. made by a
+	// previous partial run or a hook, so that the commits never include
+	// unrelated content.
+	// +optional
+	UpdatePathOnly bool `json:"updatePathOnly,omitempty"`
+
+	// Timezone is the IANA name of the timezone of the dates of the commits,
+	// e.g. `Europe/Paris`. Defaults to the timezone of the controller,
+	// usually UTC.
+	// +optional
+	Timezone string `json:"timezone,omitempty"`
+
+	// Date selects the date of the commits: `Now`, the time they're made,
+	// which is the default, or `PolicyChange`, the time the automation first
+	// observed the latest of the images of the policies. Dated after the
+	// policies, the commits of the same changes on the same parent are
+	// identical, e.g. when a failed push is retried, unless they're signed.
+	// +kubebuilder:validation:Enum=Now;PolicyChange
+	// +optional
+	Date CommitDate `json:"date,omitempty"`
+}
+
+// CommitDate selects the date of the commits.
+type CommitDate string
+
+const (
+	// CommitDateNow dates the commits with the time they're made.
+	CommitDateNow CommitDate = "Now"
+	// CommitDatePolicyChange dates the commits with the time the latest of
+	// the images of the policies was first observed.
+	CommitDatePolicyChange CommitDate = "PolicyChange"
+)
+
+// ValuesReference references a ConfigMap or a Secret whose data provides
+// values to the templates.
+type ValuesReference struct {
+	// Kind of the referent.
+	// +kubebuilder:validation:Enum=ConfigMap;Secret
+	// +required
+	Kind string `json:"kind"`
+
+	// Name of the referent.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=253
+	// +required
+	Name string `json:"name"`
+
+	// Optional marks the reference as optional. A referent which doesn't
+	// exist is then ignored instead of failing the commit.
+	// +optional
+	Optional bool `json:"optional,omitempty"`
 }
 
 type CommitUser struct {
-	// Name gives the name to provide when making a commit.
+	// Name gives the name to provide when making a commit. It can be a
+	// template, rendered with the same data as the commit message template.
 	// +optional
 	Name string `json:"name,omitempty"`
-	// Email gives the email to provide when making a commit.
-	// +required
-	Email string `json:"email"`
+	// Email gives the email to provide when making a commit. It can be a
+	// template, rendered with the same data as the commit message template.
+	// +optional
+	Email string `json:"email,omitempty"`
 }
 
 // SigningKey references a Kubernetes secret that contains a GPG keypair
+// +kubebuilder:validation:XValidation:rule="(has(self.secretRef) && size(self.secretRef.name) > 0) != (has(self.secretRefs) && size(self.secretRefs) > 0)",message="exactly one of secretRef or secretRefs must be set"
 type SigningKey struct {
 	// SecretRef holds the name to a secret that contains a 'git.asc' key
 	// corresponding to the ASCII Armored file containing the GPG signing
 	// keypair as the value. It must be in the same namespace as the
 	// ImageUpdateAutomation.
-	// +required
+	// +optional
 	SecretRef meta.LocalObjectReference `json:"secretRef,omitempty"`
+
+	// SecretRefs references secrets of signing keys, like SecretRef, each
+	// used to sign the commits pushed to the matching branches, e.g. to sign
+	// the commits of the production and staging branches with different
+	// keys. Exactly one of them must match the push branch. It's an
+	// alternative to SecretRef.
+	// +optional
+	SecretRefs []BranchSigningKeyRef `json:"secretRefs,omitempty"`
+}
+
+// BranchSigningKeyRef references a secret that contains a GPG keypair, used to
+// sign the commits pushed to the matching branches.
+type BranchSigningKeyRef struct {
+	// Name of the secret, in the same namespace as the
+	// ImageUpdateAutomation, with the same keys as the one of SecretRef.
+	// +required
+	Name string `json:"name"`
+
+	// Branches are the patterns of the push branches the key signs the
+	// commits of, with the syntax of Go's path.Match, e.g. `release/*`
+	// matches `release/1.0` but not `release/1.0/rc`.
+	// +kubebuilder:validation:MinItems=1
+	// +required
+	Branches []string `json:"branches"`
+}
+
+// SecretNames returns the names of the secrets of the signing keys.
+func (in SigningKey) SecretNames() []string {
+	if len(in.SecretRefs) == 0 {
+		return []string{in.SecretRef.Name}
+	}
+	names := make([]string, 0, len(in.SecretRefs))
+	for _, ref := range in.SecretRefs {
+		names = append(names, ref.Name)
+	}
+	return names
 }
 
 // PushSpec specifies how and where to push commits.
+// +kubebuilder:validation:XValidation:rule="!has(self.perPolicyBranches) || !self.perPolicyBranches || !has(self.refspec)",message="per-policy branches can't be pushed with a refspec"
+// +kubebuilder:validation:XValidation:rule="!has(self.perPolicyBranches) || !self.perPolicyBranches || !has(self.base)",message="per-policy branches can't be pushed onto a base branch"
+// +kubebuilder:validation:XValidation:rule="!has(self.api) || (!has(self.refspec) && !has(self.options) && !has(self.additionalRemotes))",message="commits made with the provider API can't be pushed with a refspec, push options or additional remotes"
 type PushSpec struct {
+	// SourceRef refers to the GitRepository the commits are pushed to, when
+	// it's not the checked out one, e.g. a fork of it to open pull requests
+	// from. Only its URL and credentials are used. The push branch is then
+	// always created from the checkout, its state in the checked out
+	// repository being ignored.
+	// +optional
+	SourceRef *CrossNamespaceSourceReference `json:"sourceRef,omitempty"`
+
 	// Branch specifies that commits should be pushed to the branch
 	// named. The branch is created using `.spec.checkout.branch` as the
-	// starting point, if it doesn't already exist.
+	// starting point, if it doesn't already exist. It's required when the
+	// checkout reference is a tag, a semver range or a commit, unless a
+	// Refspec is provided.
 	// +optional
 	Branch string `json:"branch,omitempty"`
 
+	// Base is the branch the changes are applied to, when it's not the
+	// checked out branch, e.g. a release branch the updates of the main
+	// branch are backported to. The changes are computed against the
+	// checkout, and only the changed lines are merged into the files of the
+	// base branch, with a three-way merge, instead of committing the files
+	// of the checkout as they are. The commit is made on top of the base
+	// branch and pushed to Branch, which then starts from the base branch,
+	// or to the base branch itself when Branch is empty. Changed lines which
+	// aren't found in the base branch fail the update. It can't be used with
+	// PerPolicyBranches.
+	// +optional
+	Base string `json:"base,omitempty"`
+
 	// Refspec specifies the Git Refspec to use for a push operation.
 	// If both Branch and Refspec are provided, then the commit is pushed
-	// to the branch and also using the specified refspec.
+	// to the branch and also using the specified refspec. If only Refspec
+	// is provided, the commit is only pushed using the refspec.
 	// For more details about Git Refspecs, see:
 	// https://git-scm.com/book/en/v2/Git-Internals-The-Refspec
 	// +optional
@@ -113,4 +302,280 @@ type PushSpec struct {
 	// https://git-scm.com/docs/git-push#Documentation/git-push.txt---push-optionltoptiongt
 	// +optional
 	Options map[string]string `json:"options,omitempty"`
+
+	// PerPolicyBranches specifies that the changes of each ImagePolicy are
+	// committed and pushed to a branch of their own, created from the
+	// checkout branch, e.g. to open one pull request per image. Branch is
+	// then the template of the names of the branches, rendered with the
+	// `.Policy.Name` and `.Policy.Namespace` of each ImagePolicy, and
+	// defaults to `image-updates/{{ .Policy.Name }}`. It can't be used
+	// with Refspec.
+	// +optional
+	PerPolicyBranches bool `json:"perPolicyBranches,omitempty"`
+
+	// Checks configures waiting for the status checks of the pushed commits
+	// on the Git provider, whose result is reported by the ChecksPassed
+	// condition.
+	// +optional
+	Checks *ChecksSpec `json:"checks,omitempty"`
+
+	// CommitStatus configures setting a commit status on the pushed commits
+	// on the Git provider, e.g. `flux-image-automation: updated app to
+	// v1.2.3`, for the repository UIs to show the automation which made
+	// them. A failure to set the status is reported with an event without
+	// failing the reconciliation.
+	// +optional
+	CommitStatus *CommitStatusSpec `json:"commitStatus,omitempty"`
+
+	// AdditionalRemotes is the list of the remotes the pushed commits are
+	// also pushed to, with the same branches and tags, e.g. to keep both
+	// repositories up to date during a migration. A failure to push to an
+	// additional remote is reported in the status without failing the
+	// reconciliation.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	AdditionalRemotes []AdditionalRemote `json:"additionalRemotes,omitempty"`
+
+	// HeadCheck checks, right before pushing, that the head of the push
+	// branch on the remote didn't move since the checkout, e.g. because of a
+	// commit pushed by a human, to never overwrite it. Abort fails the push,
+	// which is retried from a new checkout. Rebase makes the commit again on
+	// top of the new head when the commits pushed meanwhile changed none of
+	// the files of the commit, and fails the push otherwise. The head isn't
+	// checked by default, nor for per-policy branches.
+	// +kubebuilder:validation:Enum=Abort;Rebase
+	// +optional
+	HeadCheck HeadCheckPolicy `json:"headCheck,omitempty"`
+
+	// FallbackSecretRef refers to a Secret, in the namespace of the
+	// ImageUpdateAutomation, with the credentials the commits are pushed
+	// with when the remote rejects the credentials of the GitRepository they
+	// are pushed to, e.g. while those are rotated. The Secret has the same
+	// format as the Secret of a GitRepository. The credentials the last push
+	// succeeded with are recorded in `.status.lastPushCredentials`.
+	// +optional
+	FallbackSecretRef *meta.LocalObjectReference `json:"fallbackSecretRef,omitempty"`
+
+	// ForcePush tells whether the commits are force pushed to a push branch
+	// other than the checkout branch, overwriting the changes made to it,
+	// overriding the GitForcePushBranch feature gate of the controller for
+	// this automation. The checkout branch itself is never force pushed.
+	// +optional
+	ForcePush *bool `json:"forcePush,omitempty"`
+
+	// API makes the commits with the REST API of the Git provider instead
+	// of pushing them, e.g. for the provider to sign them, or when pushing
+	// over SSH or HTTPS is blocked. The changes are still committed in the
+	// checkout, and only the push branch is updated on the provider. It
+	// can't be used with a Refspec, push Options or AdditionalRemotes, nor
+	// with a signing key or a tag.
+	// +optional
+	API *PushAPISpec `json:"api,omitempty"`
+}
+
+// HeadCheckPolicy is the type of the policies handling a push branch whose
+// head on the remote moved since the checkout.
+type HeadCheckPolicy string
+
+const (
+	// HeadCheckAbort fails the push.
+	HeadCheckAbort HeadCheckPolicy = "Abort"
+	// HeadCheckRebase makes the commit again on top of the new head, if the
+	// commits pushed meanwhile changed none of its files.
+	HeadCheckRebase HeadCheckPolicy = "Rebase"
+)
+
+// PushCredentials is the type of the credentials a push succeeded with.
+type PushCredentials string
+
+const (
+	// PushCredentialsPrimary are the credentials of the GitRepository the
+	// commits are pushed to.
+	PushCredentialsPrimary PushCredentials = "Primary"
+	// PushCredentialsFallback are the credentials of the fallback Secret of
+	// the push.
+	PushCredentialsFallback PushCredentials = "Fallback"
+)
+
+// AdditionalRemote is a Git remote the pushed commits are also pushed to.
+type AdditionalRemote struct {
+	// Name identifies the remote in the status.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	// +required
+	Name string `json:"name"`
+
+	// URL of the remote.
+	// +kubebuilder:validation:Pattern="^(http|https|ssh)://.*$"
+	// +required
+	URL string `json:"url"`
+
+	// SecretRef references a Secret, in the same namespace as the
+	// ImageUpdateAutomation, with the authentication credentials of the
+	// remote, in the same format as the Secret of a GitRepository.
+	// +optional
+	SecretRef *meta.LocalObjectReference `json:"secretRef,omitempty"`
+}
+
+// ChecksSpec configures the Git provider API queried for the status checks of
+// the pushed commits.
+type ChecksSpec struct {
+	// Provider is the type of the Git provider API.
+	// +kubebuilder:validation:Enum=github;gitlab
+	// +required
+	Provider string `json:"provider"`
+
+	// Address is the base URL of the provider API. It defaults to
+	// https://api.github.com for github and https://gitlab.com/api/v4 for
+	// gitlab.
+	// +optional
+	Address string `json:"address,omitempty"`
+
+	// Repository is the repository the commits are pushed to on the
+	// provider, e.g. `<owner>/<name>` for github or the full path of the
+	// project for gitlab.
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	Repository string `json:"repository"`
+
+	// SecretRef references a Secret, in the same namespace as the
+	// ImageUpdateAutomation, with the `token` key used to authenticate to
+	// the provider API.
+	// +optional
+	SecretRef *meta.LocalObjectReference `json:"secretRef,omitempty"`
+
+	// Interval at which the checks are queried while they're pending.
+	// Defaults to 30s.
+	// +kubebuilder:validation:Type=string
+	// +kubebuilder:validation:Pattern="^([0-9]+(\\.[0-9]+)?(ms|s|m|h))+$"
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// Timeout after the push beyond which the checks still pending are
+	// considered failed. Defaults to 1h.
+	// +kubebuilder:validation:Type=string
+	// +kubebuilder:validation:Pattern="^([0-9]+(\\.[0-9]+)?(ms|s|m|h))+$"
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+const (
+	// ChecksProviderGitHub is the provider of the checks of GitHub
+	// repositories.
+	ChecksProviderGitHub = "github"
+	// ChecksProviderGitLab is the provider of the checks of GitLab projects.
+	ChecksProviderGitLab = "gitlab"
+)
+
+// GetInterval returns the interval at which the pending checks are queried.
+func (in ChecksSpec) GetInterval() time.Duration {
+	if in.Interval == nil {
+		return 30 * time.Second
+	}
+	return in.Interval.Duration
+}
+
+// GetTimeout returns the timeout after the push beyond which pending checks
+// are considered failed.
+func (in ChecksSpec) GetTimeout() time.Duration {
+	if in.Timeout == nil {
+		return time.Hour
+	}
+	return in.Timeout.Duration
+}
+
+// CommitStatusSpec configures the Git provider API the commit statuses of the
+// pushed commits are set with.
+type CommitStatusSpec struct {
+	// Provider is the type of the Git provider API.
+	// +kubebuilder:validation:Enum=github;gitlab
+	// +required
+	Provider string `json:"provider"`
+
+	// Address is the base URL of the provider API. It defaults to
+	// https://api.github.com for github and https://gitlab.com/api/v4 for
+	// gitlab.
+	// +optional
+	Address string `json:"address,omitempty"`
+
+	// Repository is the repository the commits are pushed to on the
+	// provider, e.g. `<owner>/<name>` for github or the full path of the
+	// project for gitlab.
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	Repository string `json:"repository"`
+
+	// SecretRef references a Secret, in the same namespace as the
+	// ImageUpdateAutomation, with the `token` key used to authenticate to
+	// the provider API. The token must be allowed to set the statuses of
+	// the commits.
+	// +required
+	SecretRef meta.LocalObjectReference `json:"secretRef"`
+
+	// Context is the name of the commit status, which is left out of the
+	// status checks of the commits. Defaults to `flux-image-automation`.
+	// +kubebuilder:validation:MaxLength=255
+	// +optional
+	Context string `json:"context,omitempty"`
+}
+
+// DefaultCommitStatusContext is the default name of the commit statuses set
+// on the pushed commits.
+const DefaultCommitStatusContext = "flux-image-automation"
+
+// GetContext returns the name of the commit statuses.
+func (in CommitStatusSpec) GetContext() string {
+	if in.Context == "" {
+		return DefaultCommitStatusContext
+	}
+	return in.Context
+}
+
+// PushAPISpec configures the Git provider API the commits are made with.
+type PushAPISpec struct {
+	// Provider is the type of the Git provider API.
+	// +kubebuilder:validation:Enum=github;gitlab
+	// +required
+	Provider string `json:"provider"`
+
+	// Address is the base URL of the provider API. It defaults to
+	// https://api.github.com for github and https://gitlab.com/api/v4 for
+	// gitlab.
+	// +optional
+	Address string `json:"address,omitempty"`
+
+	// Repository is the repository the commits are made in on the provider,
+	// e.g. `<owner>/<name>` for github or the full path of the project for
+	// gitlab.
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	Repository string `json:"repository"`
+
+	// SecretRef references a Secret, in the same namespace as the
+	// ImageUpdateAutomation, with the `token` key used to authenticate to
+	// the provider API. The token must be allowed to write the contents of
+	// the repository.
+	// +required
+	SecretRef meta.LocalObjectReference `json:"secretRef"`
+}
+
+const (
+	// PushAPIProviderGitHub is the provider of the API of GitHub
+	// repositories.
+	PushAPIProviderGitHub = "github"
+	// PushAPIProviderGitLab is the provider of the API of GitLab projects.
+	PushAPIProviderGitLab = "gitlab"
+)
+
+// TagSpec specifies an annotated Git tag to create for pushed commits.
+type TagSpec struct {
+	// Name is a template for the name of the tag, rendered with the same
+	// data as the commit message template. In addition to the commit
+	// message template functions, the sprig date functions are available to
+	// include the time of the push, e.g.
+	// `auto/{{ .Values.cluster }}/{{ now | date "20060102" }}`.
+	// An existing tag with the same name is moved to the new commit.
+	// +required
+	Name string `json:"name"`
 }
@@ -27,6 +27,33 @@ import (
 const (
 	ImageUpdateAutomationKind      = "ImageUpdateAutomation"
 	ImageUpdateAutomationFinalizer = "finalizers.fluxcd.io"
+
+	// VerifySigningAnnotation requests a self-test of the commit signing
+	// configuration when its value changes. The value of the last handled
+	// request is recorded in .status.lastHandledRequests.
+	VerifySigningAnnotation = "image.toolkit.fluxcd.io/verifySigning"
+
+	// UpdatePathAnnotation restricts the fields set with an ImagePolicy to
+	// the files within the given path, relative to the root of the source.
+	UpdatePathAnnotation = "image.toolkit.fluxcd.io/update-path"
+
+	// FullSyncAnnotation requests a full sync of the source, ignoring the
+	// observed source revision and policies, when its value changes. The
+	// value of the last handled request is recorded in
+	// .status.lastHandledRequests.
+	FullSyncAnnotation = "image.toolkit.fluxcd.io/full-sync"
+
+	// AllowOverlapAnnotation allows an ImageUpdateAutomation to overlap
+	// another one of its namespace when set to "true", i.e. to push to the
+	// same repository and branch with overlapping update paths, which is
+	// otherwise refused or warned about at admission.
+	AllowOverlapAnnotation = "image.toolkit.fluxcd.io/allow-overlap"
+
+	// RequireSignedCommitsAnnotation requires the commits of the
+	// ImageUpdateAutomations of a Namespace to be signed when set to "true"
+	// on the Namespace, like the --require-signed-commits flag of the
+	// controller does for all the Namespaces.
+	RequireSignedCommitsAnnotation = "image.toolkit.fluxcd.io/require-signed-commits"
 )
 
 // ImageUpdateAutomationSpec defines the desired state of ImageUpdateAutomation
@@ -54,6 +81,13 @@ type ImageUpdateAutomationSpec struct {
 	// +optional
 	PolicySelector *metav1.LabelSelector `json:"policySelector,omitempty"`
 
+	// ServiceAccountName is the name of the ServiceAccount, in the namespace
+	// of the automation, impersonated to read the policies and the source.
+	// It defaults to the ServiceAccount set with the controller
+	// --default-service-account flag, if any.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
 	// Update gives the specification for how to update the files in
 	// the repository. This can be left empty, to use the default
 	// value.
@@ -64,11 +98,164 @@ type ImageUpdateAutomationSpec struct {
 	// it is unset (or set to false). Defaults to false.
 	// +optional
 	Suspend bool `json:"suspend,omitempty"`
+
+	// SuspendUntil tells the controller to not run this automation until the
+	// given time, e.g. for the duration of an incident, after which it
+	// resumes on its own. It has no effect once the time has passed.
+	// +optional
+	SuspendUntil *metav1.Time `json:"suspendUntil,omitempty"`
+
+	// Overrides pins the images of the given ImagePolicies to a fixed tag or
+	// digest, regardless of their latest image, e.g. to hold back an image
+	// during an incident. The overrides are applied until they are removed.
+	// +listType=map
+	// +listMapKey=policyName
+	// +optional
+	Overrides []PolicyOverride `json:"overrides,omitempty"`
+
+	// PostPushHooks are notified of each successful push, with the name of
+	// the automation, the pushed commit and the updated images, e.g. to
+	// trigger a deployment pipeline. A failure to notify a hook is reported
+	// with an event, and doesn't fail the reconciliation.
+	// +optional
+	PostPushHooks []PostPushHook `json:"postPushHooks,omitempty"`
+
+	// Triggers configures what triggers the runs of the automation, besides
+	// its interval and the reconcile requests.
+	// +optional
+	Triggers *Triggers `json:"triggers,omitempty"`
+
+	// Routes push the changes of the ImagePolicies they select to branches
+	// of their own, each route making a commit of its own, e.g. the changes
+	// of the policies labeled `env=staging` to the staging branch, and those
+	// of the policies labeled `env=prod` to the branch of a pull request.
+	// The policies selected by no route aren't updated. They can't be used
+	// with a push branch, a refspec, a base branch, per-policy branches or
+	// the provider API.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Routes []Route `json:"routes,omitempty"`
+}
+
+// Route pushes the changes of the ImagePolicies it selects to a branch.
+type Route struct {
+	// Name identifies the route in the status.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	// +required
+	Name string `json:"name"`
+
+	// PolicySelector selects by their labels, among the ImagePolicies of
+	// the automation, the policies whose changes are pushed by the route.
+	// +required
+	PolicySelector metav1.LabelSelector `json:"policySelector"`
+
+	// Push configures the push of the changes of the route.
+	// +required
+	Push RoutePushSpec `json:"push"`
+}
+
+// RoutePushSpec configures the push of the changes of a route.
+type RoutePushSpec struct {
+	// Branch is the branch the changes of the route are pushed to. The
+	// commits are made on top of the branch, or of the checked out commit
+	// when the branch doesn't exist yet. It must differ from the checkout
+	// branch.
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	Branch string `json:"branch"`
+
+	// Options are the push options sent to the Git server with the push of
+	// the route, merged over the options of `.spec.git.push.options`.
+	// +optional
+	Options map[string]string `json:"options,omitempty"`
+}
+
+// Triggers configures what triggers the runs of an automation.
+type Triggers struct {
+	// ImagePolicies tells whether the changes of the latest image of the
+	// ImagePolicies trigger a run, unless the controller doesn't watch the
+	// ImagePolicies. Defaults to true. Disabling it makes the automation
+	// run at its interval only.
+	// +kubebuilder:default=true
+	// +optional
+	ImagePolicies *bool `json:"imagePolicies,omitempty"`
+}
+
+// ImagePoliciesEnabled returns whether the changes of the ImagePolicies
+// trigger the runs, which they do by default.
+func (in *Triggers) ImagePoliciesEnabled() bool {
+	return in == nil || in.ImagePolicies == nil || *in.ImagePolicies
+}
+
+// Trigger is what triggers the runs of an automation.
+type Trigger string
+
+const (
+	// TriggerInterval is the interval of the automation.
+	TriggerInterval Trigger = "Interval"
+	// TriggerImagePolicies is the changes of the latest image of the
+	// ImagePolicies.
+	TriggerImagePolicies Trigger = "ImagePolicies"
+)
+
+// PostPushHook configures a provider notified after each successful push.
+type PostPushHook struct {
+	// Name of the hook, to identify it in the events.
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	Name string `json:"name"`
+
+	// Type of the provider. The only supported type at the moment is http,
+	// which POSTs the notification as JSON to the address.
+	// +kubebuilder:validation:Enum=http
+	// +kubebuilder:default=http
+	// +optional
+	Type string `json:"type,omitempty"`
+
+	// Address of the provider, e.g. the URL of the HTTP endpoint. It can be
+	// set with the `address` key of the SecretRef instead.
+	// +optional
+	Address string `json:"address,omitempty"`
+
+	// SecretRef references a Secret, in the same namespace as the
+	// ImageUpdateAutomation, with the configuration of the provider. The
+	// `address` key overrides the Address, and the `token` key is sent as a
+	// bearer token.
+	// +optional
+	SecretRef *meta.LocalObjectReference `json:"secretRef,omitempty"`
+}
+
+const (
+	// PostPushHookTypeHTTP is the type of the hooks POSTing the notification
+	// as JSON to an HTTP endpoint.
+	PostPushHookTypeHTTP = "http"
+)
+
+// PolicyOverride pins the image of an ImagePolicy to a fixed tag or digest.
+// +kubebuilder:validation:XValidation:rule="has(self.tag) != has(self.digest)",message="exactly one of tag or digest must be set"
+type PolicyOverride struct {
+	// PolicyName is the name of the ImagePolicy to pin, in the namespace of
+	// the ImageUpdateAutomation. The ImagePolicy must have a latest image,
+	// which gives the name of the image.
+	// +required
+	PolicyName string `json:"policyName"`
+
+	// Tag is the tag the image is pinned to.
+	// +kubebuilder:validation:Pattern="^[a-zA-Z0-9_][a-zA-Z0-9._-]{0,127}$"
+	// +optional
+	Tag string `json:"tag,omitempty"`
+
+	// Digest is the digest the image is pinned to.
+	// +kubebuilder:validation:Pattern="^sha256:[a-f0-9]{64}$"
+	// +optional
+	Digest string `json:"digest,omitempty"`
 }
 
 // UpdateStrategyName is the type for names that go in
 // .update.strategy. NB the value in the const immediately below.
-// +kubebuilder:validation:Enum=Setters
+// +kubebuilder:validation:Enum=Setters;KustomizeImages
 type UpdateStrategyName string
 
 const (
@@ -76,11 +263,17 @@ const (
 	// uses kyaml setters. NB the value in the enum annotation for the
 	// type, above.
 	UpdateStrategySetters UpdateStrategyName = "Setters"
+	// UpdateStrategyKustomizeImages is the name of the update strategy
+	// that maintains a Kustomize component listing the images of the
+	// policies in each updated directory, instead of updating the
+	// manifests.
+	UpdateStrategyKustomizeImages UpdateStrategyName = "KustomizeImages"
 )
 
 // UpdateStrategy is a union of the various strategies for updating
 // the Git repository. Parameters for each strategy (if any) can be
 // inlined here.
+// +kubebuilder:validation:XValidation:rule="!has(self.dataFields) || size(self.dataFields) == 0 || self.strategy == 'Setters'",message="data fields are only updated with the Setters strategy"
 type UpdateStrategy struct {
 	// Strategy names the strategy to be used.
 	// +required
@@ -89,11 +282,212 @@ type UpdateStrategy struct {
 
 	// Path to the directory containing the manifests to be updated.
 	// Defaults to 'None', which translates to the root path
-	// of the GitRepositoryRef.
+	// of the GitRepositoryRef. It can be a glob pattern, e.g.
+	// `./apps/*/staging`, to update all the matching directories. It can
+	// also be a template, e.g. `./apps/{{ .Values.env }}`, rendered with the
+	// values of the commit templates and the applied policies.
 	// +optional
 	Path string `json:"path,omitempty"`
+
+	// ConflictPolicy decides what to do with a marked field whose value was
+	// changed since the last update, e.g. manually, and so differs from the
+	// previously observed image of its policy. Overwrite updates the field
+	// anyway, Skip leaves the field unchanged and Fail fails the update.
+	// The conflicts are reported in events. Defaults to Overwrite.
+	// +kubebuilder:default=Overwrite
+	// +optional
+	ConflictPolicy ConflictPolicy `json:"conflictPolicy,omitempty"`
+
+	// MaxSemverJump limits the semver distance between the image currently
+	// committed in a marked field and the image to be written. Minor refuses
+	// to change the major version and Patch refuses to change the major or
+	// the minor version. The update fails and the automation is stalled
+	// until the field is updated manually. Tags which aren't semver are not
+	// limited.
+	// +kubebuilder:validation:Enum=Major;Minor;Patch
+	// +optional
+	MaxSemverJump SemverJump `json:"maxSemverJump,omitempty"`
+
+	// HelmTemplates decides what to do with the files with Go template
+	// syntax, e.g. the templates of a Helm chart, which can't be parsed as
+	// YAML. Skip leaves them unchanged, and Scan updates the marked fields
+	// with a plain scalar value, found by scanning the files line by line.
+	// +kubebuilder:validation:Enum=Skip;Scan
+	// +kubebuilder:default=Skip
+	// +optional
+	HelmTemplates HelmTemplatesPolicy `json:"helmTemplates,omitempty"`
+
+	// Owner restricts the update to the files owned by the given owner,
+	// e.g. a team. The owners of a file are declared by a
+	// `# flux-owner: <owner>` comment in the file, or else by the CODEOWNERS
+	// file of the repository. The update fails without modifying any file
+	// if a file to be modified isn't owned by the owner.
+	// +optional
+	Owner string `json:"owner,omitempty"`
+
+	// SymlinkPolicy decides what to do with the YAML files which are
+	// symbolic links. Follow updates the targets of the links within the
+	// Git repository and fails the update for the others, Ignore leaves the
+	// links unchanged, and Fail fails the update if any file is a link.
+	// +kubebuilder:validation:Enum=Follow;Ignore;Fail
+	// +kubebuilder:default=Follow
+	// +optional
+	SymlinkPolicy SymlinkPolicy `json:"symlinkPolicy,omitempty"`
+
+	// LockFile enables writing the `flux-images.lock.yaml` file at the root
+	// of the update path, listing the image of each applied policy, along
+	// with the changes of each update.
+	// +optional
+	LockFile bool `json:"lockFile,omitempty"`
+
+	// Atomic makes the update fail, without committing anything, when any
+	// of the files with markers can't be processed, e.g. because it's not
+	// valid YAML, instead of committing the changes of the other files.
+	// +optional
+	Atomic bool `json:"atomic,omitempty"`
+
+	// Validate enables the validation of the changed manifests against the
+	// OpenAPI schemas of the built-in Kubernetes kinds, before they're
+	// committed. Invalid manifests fail the update without pushing anything.
+	// The objects of other kinds, like custom resources, aren't validated.
+	// +optional
+	Validate bool `json:"validate,omitempty"`
+
+	// DataFields lists the fields of the documents held in the data of
+	// ConfigMaps and Secrets, e.g. JSON configuration files, which are set
+	// to the images of ImagePolicies along with the marked fields, as they
+	// can't be marked. Only with the Setters strategy.
+	// +optional
+	DataFields []DataField `json:"dataFields,omitempty"`
+
+	// AutoRevert enables reverting the images which were committed but
+	// aren't candidates of their ImagePolicy anymore, e.g. because their tag
+	// was deleted from the registry. An image is considered revoked when the
+	// latest image of its policy is ordered before it by the policy. The
+	// marked fields of the revoked images are set to the latest images of
+	// their policies in a revert commit of their own, reported in events,
+	// before the other policies are applied.
+	// +optional
+	AutoRevert bool `json:"autoRevert,omitempty"`
+}
+
+// DataField is a field of a document held in the data of ConfigMaps or
+// Secrets, set to the image of an ImagePolicy.
+type DataField struct {
+	// Kind of the objects holding the document.
+	// +kubebuilder:validation:Enum=ConfigMap;Secret
+	// +required
+	Kind string `json:"kind"`
+
+	// Name of the objects holding the document, in any namespace, found in
+	// the manifests of the update path.
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	Name string `json:"name"`
+
+	// Key of the document in the data of the objects: in `data` for a
+	// ConfigMap, or in `stringData` for a Secret.
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	Key string `json:"key"`
+
+	// Path is the JSONPath of the fields in the document, which can be JSON
+	// or YAML, e.g. `$.image` or `$.services[*].image`. Only the child
+	// (`.name` or `['name']`), index (`[0]`) and wildcard (`[*]`) operators
+	// are supported.
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	Path string `json:"path"`
+
+	// Policy is the name of the ImagePolicy, in the namespace of the
+	// ImageUpdateAutomation, whose image the fields are set to.
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	Policy string `json:"policy"`
+
+	// Value is the part of the image of the ImagePolicy the fields are set
+	// to: the image reference, its tag (or digest), or its name. Defaults
+	// to Image.
+	// +kubebuilder:validation:Enum=Image;Tag;Name
+	// +kubebuilder:default=Image
+	// +optional
+	Value DataFieldValue `json:"value,omitempty"`
+
+	// Base64 enables updating the base64-encoded documents: in `binaryData`
+	// for a ConfigMap, or in `data` for a Secret. Without it, a document
+	// only found base64-encoded fails the update, for the Secrets not to be
+	// decoded unless asked to.
+	// +optional
+	Base64 bool `json:"base64,omitempty"`
 }
 
+// DataFieldValue is the part of the image of an ImagePolicy a data field is
+// set to.
+type DataFieldValue string
+
+const (
+	// DataFieldValueImage is the image reference, e.g.
+	// ghcr.io/stefanprodan/podinfo:6.5.1.
+	DataFieldValueImage DataFieldValue = "Image"
+	// DataFieldValueTag is the tag of the image, or its digest, e.g. 6.5.1.
+	DataFieldValueTag DataFieldValue = "Tag"
+	// DataFieldValueName is the name of the image, e.g.
+	// ghcr.io/stefanprodan/podinfo.
+	DataFieldValueName DataFieldValue = "Name"
+)
+
+// ConflictPolicy is the type of the policies handling the conflicts of an
+// update.
+// +kubebuilder:validation:Enum=Overwrite;Skip;Fail
+type ConflictPolicy string
+
+const (
+	// ConflictPolicyOverwrite updates the conflicting fields.
+	ConflictPolicyOverwrite ConflictPolicy = "Overwrite"
+	// ConflictPolicySkip leaves the conflicting fields unchanged.
+	ConflictPolicySkip ConflictPolicy = "Skip"
+	// ConflictPolicyFail fails the update if any field is conflicting.
+	ConflictPolicyFail ConflictPolicy = "Fail"
+)
+
+// SemverJump is the type of the semver distances between two versions.
+type SemverJump string
+
+const (
+	// SemverJumpMajor allows any change of version.
+	SemverJumpMajor SemverJump = "Major"
+	// SemverJumpMinor allows the changes of minor and patch versions.
+	SemverJumpMinor SemverJump = "Minor"
+	// SemverJumpPatch allows the changes of patch versions.
+	SemverJumpPatch SemverJump = "Patch"
+)
+
+// HelmTemplatesPolicy is the type of the policies handling the files with Go
+// template syntax.
+type HelmTemplatesPolicy string
+
+const (
+	// HelmTemplatesSkip leaves the files with Go template syntax unchanged.
+	HelmTemplatesSkip HelmTemplatesPolicy = "Skip"
+	// HelmTemplatesScan updates the marked fields of the files with Go
+	// template syntax by scanning them line by line.
+	HelmTemplatesScan HelmTemplatesPolicy = "Scan"
+)
+
+// SymlinkPolicy is the type of the policies handling the YAML files which are
+// symbolic links.
+type SymlinkPolicy string
+
+const (
+	// SymlinkPolicyFollow updates the targets of the symbolic links within
+	// the Git repository, and fails the update for the others.
+	SymlinkPolicyFollow SymlinkPolicy = "Follow"
+	// SymlinkPolicyIgnore leaves the symbolic links unchanged.
+	SymlinkPolicyIgnore SymlinkPolicy = "Ignore"
+	// SymlinkPolicyFail fails the update if any file is a symbolic link.
+	SymlinkPolicyFail SymlinkPolicy = "Fail"
+)
+
 // ImageUpdateAutomationStatus defines the observed state of ImageUpdateAutomation
 type ImageUpdateAutomationStatus struct {
 	// LastAutomationRunTime records the last time the controller ran
@@ -108,6 +502,34 @@ type ImageUpdateAutomationStatus struct {
 	// LastPushTime records the time of the last pushed change.
 	// +optional
 	LastPushTime *metav1.Time `json:"lastPushTime,omitempty"`
+	// LastPushTag records the name of the tag created for the last pushed
+	// commit, if tagging is configured.
+	// +optional
+	LastPushTag string `json:"lastPushTag,omitempty"`
+	// LastPolicyPushes records the commits pushed by the last push to the
+	// branch of each ImagePolicy, when per-policy branches are configured.
+	// +optional
+	LastPolicyPushes []PolicyPush `json:"lastPolicyPushes,omitempty"`
+	// LastRoutePushes records the commits pushed by the last push to the
+	// branch of each route, when routes are configured.
+	// +optional
+	LastRoutePushes []RoutePush `json:"lastRoutePushes,omitempty"`
+	// LastRemotePushes records the result of the last push to each of the
+	// additional remotes.
+	// +optional
+	LastRemotePushes []RemotePush `json:"lastRemotePushes,omitempty"`
+	// LastAuthMethod records the authentication method used for the last
+	// push, e.g. "ssh-key: SHA256:...", "basic-auth: <username>",
+	// "bearer-token", "provider: azure" or "none". It identifies the
+	// credentials without disclosing them.
+	// +optional
+	LastAuthMethod string `json:"lastAuthMethod,omitempty"`
+	// LastPushCredentials records the credentials the last push succeeded
+	// with: Primary for the credentials of the GitRepository the commits
+	// are pushed to, or Fallback for the credentials of
+	// `.spec.git.push.fallbackSecretRef`.
+	// +optional
+	LastPushCredentials PushCredentials `json:"lastPushCredentials,omitempty"`
 	// +optional
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 	// +optional
@@ -121,14 +543,300 @@ type ImageUpdateAutomationStatus struct {
 	// used to determine if the source has been updated since last observation.
 	// +optional
 	ObservedSourceRevision string `json:"observedSourceRevision,omitempty"`
+	// LastHandledFullSyncRequest is the value of the
+	// image.toolkit.fluxcd.io/full-sync annotation last handled.
+	//
+	// Deprecated: Use LastHandledRequests instead.
+	// +optional
+	LastHandledFullSyncRequest string `json:"lastHandledFullSyncRequest,omitempty"`
+	// LastHandledRequests records the value of each request annotation last
+	// handled, e.g. of image.toolkit.fluxcd.io/full-sync, keyed by the name
+	// of the annotation. The reconcile requests are recorded in
+	// .status.lastHandledReconcileAt.
+	// +optional
+	LastHandledRequests map[string]string `json:"lastHandledRequests,omitempty"`
+	// SuspendedUntil is the time until which the automation is suspended
+	// with .spec.suspendUntil, while it is.
+	// +optional
+	SuspendedUntil *metav1.Time `json:"suspendedUntil,omitempty"`
+	// PinnedPolicies is the list of the names of the observed ImagePolicies
+	// whose image is pinned by an override.
+	// +optional
+	PinnedPolicies []string `json:"pinnedPolicies,omitempty"`
+	// ExcludedPolicies is the list of the names of the ImagePolicies
+	// selected by the automation whose image is excluded from updates by the
+	// controller.
+	// +optional
+	ExcludedPolicies []string `json:"excludedPolicies,omitempty"`
+	// PolicyChanges records the time the latest image of each observed
+	// ImagePolicy was first observed, keyed by the name of the policy, when
+	// the commits are dated after the policies with
+	// `.spec.git.commit.date: PolicyChange`.
+	// +optional
+	PolicyChanges map[string]PolicyChange `json:"policyChanges,omitempty"`
+	// LastRunSummary summarizes the decisions made by the last
+	// reconciliation, for debugging why an update was or wasn't pushed.
+	// +optional
+	LastRunSummary *RunSummary `json:"lastRunSummary,omitempty"`
+	// FailureStreak records the consecutive identical failures of the
+	// reconciliation, if any.
+	// +optional
+	FailureStreak *FailureStreak `json:"failureStreak,omitempty"`
+	// MeanRunDuration is the moving average of the durations of the
+	// reconciliations which synchronized the source, used to stretch the
+	// interval of the automations slower than their interval.
+	// +optional
+	MeanRunDuration *metav1.Duration `json:"meanRunDuration,omitempty"`
+	// SigningVerification records the result of the last verification of
+	// the signature of the commits with the signing key, made after the
+	// first signed push with a signing key or on demand with the
+	// image.toolkit.fluxcd.io/verifySigning annotation.
+	// +optional
+	SigningVerification *SigningVerification `json:"signingVerification,omitempty"`
+	// ActiveTriggers lists what triggers the runs of the automation, besides
+	// the reconcile requests, as configured with .spec.triggers and the
+	// flags of the controller.
+	// +optional
+	ActiveTriggers []Trigger `json:"activeTriggers,omitempty"`
+	// LastCloneStats records the size and the duration of the last clone
+	// of the source, for capacity planning.
+	// +optional
+	LastCloneStats *CloneStats `json:"lastCloneStats,omitempty"`
 
 	meta.ReconcileRequestStatus `json:",inline"`
 }
 
+// GetLastHandledRequest returns the value of the given request annotation
+// last handled. It falls back to the fields the requests were recorded in
+// before .status.lastHandledRequests, so that a request isn't handled again
+// after an upgrade.
+func (in ImageUpdateAutomationStatus) GetLastHandledRequest(annotation string) string {
+	if annotation == meta.ReconcileRequestAnnotation {
+		return in.GetLastHandledReconcileRequest()
+	}
+	if v, ok := in.LastHandledRequests[annotation]; ok {
+		return v
+	}
+	switch annotation {
+	case FullSyncAnnotation:
+		return in.LastHandledFullSyncRequest
+	case VerifySigningAnnotation:
+		if in.SigningVerification != nil {
+			return in.SigningVerification.LastHandledRequest
+		}
+	}
+	return ""
+}
+
+// SetLastHandledRequest records the given value of the request annotation as
+// handled. The deprecated fields of the requests are set as well.
+func (in *ImageUpdateAutomationStatus) SetLastHandledRequest(annotation, value string) {
+	if annotation == meta.ReconcileRequestAnnotation {
+		in.SetLastHandledReconcileRequest(value)
+		return
+	}
+	if in.LastHandledRequests == nil {
+		in.LastHandledRequests = make(map[string]string)
+	}
+	in.LastHandledRequests[annotation] = value
+	switch annotation {
+	case FullSyncAnnotation:
+		in.LastHandledFullSyncRequest = value
+	case VerifySigningAnnotation:
+		if in.SigningVerification != nil {
+			in.SigningVerification.LastHandledRequest = value
+		}
+	}
+}
+
+// CloneStats are the statistics of a clone of the source.
+type CloneStats struct {
+	// Objects is the number of Git objects fetched by the clone. The objects
+	// already in the clone cache, if enabled, aren't fetched again.
+	// +required
+	Objects int64 `json:"objects"`
+	// Bytes is the size of the fetched objects as stored, i.e. of the
+	// packfiles received for them, in bytes.
+	// +required
+	Bytes int64 `json:"bytes"`
+	// Duration is the duration of the clone.
+	// +required
+	Duration metav1.Duration `json:"duration"`
+	// Shallow tells if the clone was shallow, i.e. fetched the checked out
+	// commit only.
+	// +required
+	Shallow bool `json:"shallow"`
+}
+
+// PolicyPush is the push of the changes of an ImagePolicy to its branch.
+type PolicyPush struct {
+	// Policy is the name of the ImagePolicy.
+	// +required
+	Policy string `json:"policy"`
+	// Branch is the branch the changes were pushed to.
+	// +required
+	Branch string `json:"branch"`
+	// Commit is the SHA1 of the pushed commit.
+	// +required
+	Commit string `json:"commit"`
+}
+
+// RoutePush is the push of the changes of a route to its branch.
+type RoutePush struct {
+	// Route is the name of the route.
+	// +required
+	Route string `json:"route"`
+	// Branch is the branch the changes were pushed to.
+	// +required
+	Branch string `json:"branch"`
+	// Commit is the SHA1 of the pushed commit.
+	// +required
+	Commit string `json:"commit"`
+}
+
+// RemotePush is the result of a push to an additional remote.
+type RemotePush struct {
+	// Name is the name of the additional remote.
+	// +required
+	Name string `json:"name"`
+	// Commit is the SHA1 of the commit pushed to the remote, if the push
+	// succeeded.
+	// +optional
+	Commit string `json:"commit,omitempty"`
+	// Error is the error of the push to the remote, if it failed.
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// SigningVerification is the result of the verification of a commit
+// signature.
+type SigningVerification struct {
+	// Verified tells if the signature could be verified.
+	// +required
+	Verified bool `json:"verified"`
+	// KeyFingerprint is the fingerprint of the primary key of the signing
+	// key.
+	// +optional
+	KeyFingerprint string `json:"keyFingerprint,omitempty"`
+	// Commit is the SHA1 of the pushed commit whose signature was verified,
+	// empty for a self-test requested with the annotation.
+	// +optional
+	Commit string `json:"commit,omitempty"`
+	// Message details why the signature couldn't be verified.
+	// +optional
+	Message string `json:"message,omitempty"`
+	// LastHandledRequest is the value of the
+	// image.toolkit.fluxcd.io/verifySigning annotation last handled.
+	//
+	// Deprecated: Use .status.lastHandledRequests instead.
+	// +optional
+	LastHandledRequest string `json:"lastHandledRequest,omitempty"`
+	// Time is the time of the verification.
+	// +required
+	Time metav1.Time `json:"time"`
+}
+
+// RunSummary summarizes the decisions made by a reconciliation of an
+// ImageUpdateAutomation.
+type RunSummary struct {
+	// SyncNeeded tells if the source had to be checked out and updated.
+	// +required
+	SyncNeeded bool `json:"syncNeeded"`
+	// SyncReasons are the reasons why the source had to be checked out and
+	// updated, e.g. PoliciesChanged or SourceChanged.
+	// +optional
+	SyncReasons []string `json:"syncReasons,omitempty"`
+	// Checkout tells how the source was checked out: Full, Shallow, or
+	// Skipped when the remote branch didn't change since the last
+	// reconciliation.
+	// +optional
+	Checkout string `json:"checkout,omitempty"`
+	// FilesChanged is the number of files changed by the update.
+	// +optional
+	FilesChanged int `json:"filesChanged,omitempty"`
+	// Push is the outcome of the push: Pushed, NothingToPush or Failed. It's
+	// empty when no push was attempted.
+	// +optional
+	Push string `json:"push,omitempty"`
+	// MessageTruncated tells if the commit message was truncated to the
+	// maximum size of the commit message.
+	// +optional
+	MessageTruncated bool `json:"messageTruncated,omitempty"`
+}
+
+const (
+	// SyncReasonPoliciesChanged is the sync reason of a change of the
+	// latest images of the policies since the last update.
+	SyncReasonPoliciesChanged = "PoliciesChanged"
+	// SyncReasonSourceChanged is the sync reason of a new commit in the
+	// checked out branch since the last update.
+	SyncReasonSourceChanged = "SourceChanged"
+	// SyncReasonPushBranch is the sync reason of a push branch different
+	// from the checkout branch, which is always updated.
+	SyncReasonPushBranch = "PushBranch"
+	// SyncReasonRefspec is the sync reason of a push refspec, which is
+	// always updated.
+	SyncReasonRefspec = "Refspec"
+	// SyncReasonFullSyncRequested is the sync reason of a full sync
+	// requested with the image.toolkit.fluxcd.io/full-sync annotation.
+	SyncReasonFullSyncRequested = "FullSyncRequested"
+)
+
+const (
+	// CheckoutFull is the checkout of the complete history of the source.
+	CheckoutFull = "Full"
+	// CheckoutShallow is the checkout of the latest commit of the source.
+	CheckoutShallow = "Shallow"
+	// CheckoutSkipped is the checkout skipped because the remote branch
+	// didn't change since the last reconciliation.
+	CheckoutSkipped = "Skipped"
+)
+
+const (
+	// PushOutcomePushed is the outcome of a successful push.
+	PushOutcomePushed = "Pushed"
+	// PushOutcomeNothingToPush is the outcome of an update without any
+	// change to push.
+	PushOutcomeNothingToPush = "NothingToPush"
+	// PushOutcomeFailed is the outcome of a failed commit or push.
+	PushOutcomeFailed = "Failed"
+)
+
+// FailureStreak records consecutive failures of the reconciliation with the
+// same reason and message.
+type FailureStreak struct {
+	// Reason is the reason of the failures.
+	// +required
+	Reason string `json:"reason"`
+	// Message is the message of the failures.
+	// +required
+	Message string `json:"message"`
+	// Count is the number of consecutive failures.
+	// +required
+	Count int `json:"count"`
+	// ObservedVersions identifies the generations of the
+	// ImageUpdateAutomation and of its GitRepository, and the resource
+	// version of the Secret of the GitRepository, when the streak started.
+	// The streak is reset when any of them changes.
+	// +optional
+	ObservedVersions string `json:"observedVersions,omitempty"`
+}
+
 // ObservedPolicies is a map of policy name and ImageRef of their latest
 // ImageRef.
 type ObservedPolicies map[string]ImageRef
 
+// PolicyChange records when the latest image of an ImagePolicy was first
+// observed.
+type PolicyChange struct {
+	// Image is the latest image of the policy.
+	// +required
+	Image string `json:"image"`
+	// ObservedTime is the time the image was first observed.
+	// +required
+	ObservedTime metav1.Time `json:"observedTime"`
+}
+
 //+kubebuilder:storageversion
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
@@ -150,6 +858,16 @@ func (auto ImageUpdateAutomation) GetRequeueAfter() time.Duration {
 	return auto.Spec.Interval.Duration
 }
 
+// PendingRequest returns the value of the given request annotation of the
+// ImageUpdateAutomation, if it's set and wasn't handled yet.
+func (auto ImageUpdateAutomation) PendingRequest(annotation string) (string, bool) {
+	v, ok := auto.GetAnnotations()[annotation]
+	if !ok || v == auto.Status.GetLastHandledRequest(annotation) {
+		return "", false
+	}
+	return v, true
+}
+
 // GetConditions returns the status conditions of the object.
 func (auto ImageUpdateAutomation) GetConditions() []metav1.Condition {
 	return auto.Status.Conditions
@@ -16,10 +16,15 @@ limitations under the License.
 
 package v1beta2
 
-import "fmt"
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
 
 // CrossNamespaceSourceReference contains enough information to let you locate the
 // typed Kubernetes resource object at cluster level.
+// +kubebuilder:validation:XValidation:rule="has(self.name) != has(self.selector)",message="exactly one of name or selector must be set"
 type CrossNamespaceSourceReference struct {
 	// API version of the referent.
 	// +optional
@@ -31,9 +36,15 @@ type CrossNamespaceSourceReference struct {
 	// +required
 	Kind string `json:"kind"`
 
-	// Name of the referent.
-	// +required
-	Name string `json:"name"`
+	// Name of the referent. Exactly one of Name or Selector must be set.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Selector selects the referent by its labels, in the namespace of the
+	// reference, e.g. to use the same automation in environments where the
+	// referents are named differently. Exactly one object must match.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
 
 	// Namespace of the referent, defaults to the namespace of the Kubernetes resource object that contains the reference.
 	// +optional
@@ -41,10 +52,14 @@ type CrossNamespaceSourceReference struct {
 }
 
 func (s *CrossNamespaceSourceReference) String() string {
+	name := s.Name
+	if s.Selector != nil {
+		name = fmt.Sprintf("{%s}", metav1.FormatLabelSelector(s.Selector))
+	}
 	if s.Namespace != "" {
-		return fmt.Sprintf("%s/%s/%s", s.Kind, s.Namespace, s.Name)
+		return fmt.Sprintf("%s/%s/%s", s.Kind, s.Namespace, name)
 	}
-	return fmt.Sprintf("%s/%s", s.Kind, s.Name)
+	return fmt.Sprintf("%s/%s", s.Kind, name)
 }
 
 // ImageRef represents an image reference.
@@ -55,10 +70,16 @@ type ImageRef struct {
 	// Tag is the image's tag.
 	// +required
 	Tag string `json:"tag"`
+	// Digest is the image's digest, if the image is pinned to a digest.
+	// +optional
+	Digest string `json:"digest,omitempty"`
 }
 
 // String combines the components of ImageRef to construct a string
 // representation of the image reference.
 func (r ImageRef) String() string {
+	if r.Digest != "" {
+		return r.Name + "@" + r.Digest
+	}
 	return r.Name + ":" + r.Tag
 }
@@ -0,0 +1,422 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta2
+
+import (
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+)
+
+// The validation below mirrors the rules of the CRD schema and the checks
+// made by the controller before running an automation, so that the specs
+// generated programmatically can be validated before they're applied.
+
+// Validate returns an error listing the invalid fields of the spec, or nil
+// if the spec is valid. It includes the validation of the GitSpec and of the
+// UpdateStrategy.
+func (in ImageUpdateAutomationSpec) Validate() error {
+	return in.validate(field.NewPath("spec")).ToAggregate()
+}
+
+// Validate returns an error listing the invalid fields of the GitSpec, or
+// nil if the GitSpec is valid.
+func (in GitSpec) Validate() error {
+	return in.validate(field.NewPath("spec", "git")).ToAggregate()
+}
+
+func (in ImageUpdateAutomationSpec) validate(fldPath *field.Path) field.ErrorList {
+	allErrs := in.SourceRef.validate(fldPath.Child("sourceRef"))
+
+	if in.GitSpec == nil {
+		allErrs = append(allErrs, field.Required(fldPath.Child("git"), "must be set for a GitRepository source"))
+	} else {
+		allErrs = append(allErrs, in.GitSpec.validate(fldPath.Child("git"))...)
+	}
+
+	if in.Interval.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("interval"), in.Interval.Duration.String(), "must not be negative"))
+	}
+
+	if in.PolicySelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(in.PolicySelector); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("policySelector"), metav1.FormatLabelSelector(in.PolicySelector), err.Error()))
+		}
+	}
+
+	if in.Update != nil {
+		allErrs = append(allErrs, in.Update.validate(fldPath.Child("update"))...)
+	}
+
+	overridesPath := fldPath.Child("overrides")
+	policies := make(map[string]bool, len(in.Overrides))
+	for i, o := range in.Overrides {
+		idxPath := overridesPath.Index(i)
+		if o.PolicyName == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("policyName"), ""))
+		} else if policies[o.PolicyName] {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("policyName"), o.PolicyName))
+		}
+		policies[o.PolicyName] = true
+		if (o.Tag == "") == (o.Digest == "") {
+			allErrs = append(allErrs, field.Invalid(idxPath, o.PolicyName, "exactly one of tag or digest must be set"))
+		}
+	}
+
+	hooksPath := fldPath.Child("postPushHooks")
+	for i, h := range in.PostPushHooks {
+		idxPath := hooksPath.Index(i)
+		if h.Name == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("name"), ""))
+		}
+		if h.Type != "" && h.Type != PostPushHookTypeHTTP {
+			allErrs = append(allErrs, field.NotSupported(idxPath.Child("type"), h.Type, []string{PostPushHookTypeHTTP}))
+		}
+	}
+
+	allErrs = append(allErrs, in.validateRoutes(fldPath.Child("routes"))...)
+
+	return allErrs
+}
+
+func (in ImageUpdateAutomationSpec) validateRoutes(fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if len(in.Routes) == 0 {
+		return allErrs
+	}
+
+	if in.GitSpec != nil {
+		if push := in.GitSpec.Push; push != nil &&
+			(push.Branch != "" || push.Refspec != "" || push.Base != "" || push.PerPolicyBranches || push.API != nil) {
+			allErrs = append(allErrs, field.Forbidden(fldPath, "can't be used with a push branch, a refspec, a base branch, per-policy branches or the provider API"))
+		}
+		if in.GitSpec.Checkout != nil && isPinnedRef(in.GitSpec.Checkout.Reference) {
+			allErrs = append(allErrs, field.Forbidden(fldPath, "can't be used to check out a tag or a commit"))
+		}
+	}
+
+	names := make(map[string]bool, len(in.Routes))
+	branches := make(map[string]bool, len(in.Routes))
+	for i, r := range in.Routes {
+		idxPath := fldPath.Index(i)
+		if r.Name == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("name"), ""))
+		} else if names[r.Name] {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("name"), r.Name))
+		}
+		names[r.Name] = true
+		if _, err := metav1.LabelSelectorAsSelector(&r.PolicySelector); err != nil {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("policySelector"), metav1.FormatLabelSelector(&r.PolicySelector), err.Error()))
+		}
+		branchPath := idxPath.Child("push", "branch")
+		switch {
+		case r.Push.Branch == "":
+			allErrs = append(allErrs, field.Required(branchPath, ""))
+		case in.GitSpec != nil && in.GitSpec.Checkout != nil && r.Push.Branch == in.GitSpec.Checkout.Reference.Branch:
+			allErrs = append(allErrs, field.Invalid(branchPath, r.Push.Branch, "must differ from the checkout branch"))
+		case branches[r.Push.Branch]:
+			allErrs = append(allErrs, field.Duplicate(branchPath, r.Push.Branch))
+		}
+		branches[r.Push.Branch] = true
+	}
+	return allErrs
+}
+
+func (in CrossNamespaceSourceReference) validate(fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if in.Kind != sourcev1.GitRepositoryKind {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("kind"), in.Kind, []string{sourcev1.GitRepositoryKind}))
+	}
+	if (in.Name == "") == (in.Selector == nil) {
+		allErrs = append(allErrs, field.Invalid(fldPath, in.String(), "exactly one of name or selector must be set"))
+	}
+	if in.Selector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(in.Selector); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("selector"), metav1.FormatLabelSelector(in.Selector), err.Error()))
+		}
+	}
+	return allErrs
+}
+
+func (in GitSpec) validate(fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if in.Checkout != nil && !in.Checkout.HasReference() && in.Checkout.Strategy == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("checkout", "ref"), "must set a branch, tag, semver range, name or commit, unless a strategy is set"))
+	}
+
+	if in.Checkout != nil {
+		switch in.Checkout.Strategy {
+		case "", CheckoutStrategyShallow, CheckoutStrategyFull:
+		default:
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("checkout", "strategy"), in.Checkout.Strategy,
+				[]CheckoutStrategy{CheckoutStrategyShallow, CheckoutStrategyFull}))
+		}
+	}
+
+	if in.Checkout != nil && isPinnedRef(in.Checkout.Reference) {
+		branchPath := fldPath.Child("push", "branch")
+		switch {
+		case in.Push == nil || (in.Push.Branch == "" && in.Push.Base == "" && in.Push.Refspec == ""):
+			allErrs = append(allErrs, field.Required(branchPath, "must be set with no refspec or base to check out a tag or a commit"))
+		case in.Push.Branch != "" && in.Push.Branch == in.Checkout.Reference.Branch:
+			allErrs = append(allErrs, field.Invalid(branchPath, in.Push.Branch, "must differ from the checkout branch to check out a commit"))
+		}
+	}
+
+	if in.HasBase() && in.Checkout != nil {
+		switch branch := in.Checkout.Reference.Branch; {
+		case in.Push.Base == branch:
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("push", "base"), in.Push.Base, "must differ from the checkout branch"))
+		case in.Push.Branch != "" && in.Push.Branch == branch:
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("push", "branch"), in.Push.Branch, "must differ from the checkout branch to push onto a base branch"))
+		}
+	}
+
+	allErrs = append(allErrs, in.Commit.validate(fldPath.Child("commit"))...)
+
+	if in.Push != nil {
+		allErrs = append(allErrs, in.Push.validate(fldPath.Child("push"))...)
+	}
+
+	if in.Tag != nil && in.Tag.Name == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("tag", "name"), ""))
+	}
+
+	if in.Push != nil && in.Push.API != nil {
+		if in.Commit.SigningKey != nil {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("commit", "signingKey"), "commits made with the provider API can't be signed with a signing key"))
+		}
+		if in.Tag != nil {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("tag"), "commits made with the provider API can't be tagged"))
+		}
+	}
+
+	return allErrs
+}
+
+func (in CommitSpec) validate(fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if in.MaxMessageBytes < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxMessageBytes"), in.MaxMessageBytes, "must not be negative"))
+	}
+
+	if in.SigningKey != nil {
+		allErrs = append(allErrs, in.SigningKey.validate(fldPath.Child("signingKey"))...)
+	}
+
+	if in.Timezone != "" {
+		if _, err := time.LoadLocation(in.Timezone); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("timezone"), in.Timezone, "must be the name of a timezone"))
+		}
+	}
+
+	switch in.Date {
+	case "", CommitDateNow, CommitDatePolicyChange:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("date"), in.Date,
+			[]CommitDate{CommitDateNow, CommitDatePolicyChange}))
+	}
+
+	valuesPath := fldPath.Child("valuesFrom")
+	for i, v := range in.ValuesFrom {
+		idxPath := valuesPath.Index(i)
+		if v.Kind != "ConfigMap" && v.Kind != "Secret" {
+			allErrs = append(allErrs, field.NotSupported(idxPath.Child("kind"), v.Kind, []string{"ConfigMap", "Secret"}))
+		}
+		if v.Name == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("name"), ""))
+		} else if len(v.Name) > 253 {
+			allErrs = append(allErrs, field.TooLong(idxPath.Child("name"), v.Name, 253))
+		}
+	}
+
+	return allErrs
+}
+
+func (in SigningKey) validate(fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if (in.SecretRef.Name != "") == (len(in.SecretRefs) > 0) {
+		allErrs = append(allErrs, field.Invalid(fldPath, in.SecretNames(), "exactly one of secretRef or secretRefs must be set"))
+	}
+
+	refsPath := fldPath.Child("secretRefs")
+	for i, ref := range in.SecretRefs {
+		idxPath := refsPath.Index(i)
+		if ref.Name == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("name"), ""))
+		}
+		if len(ref.Branches) == 0 {
+			allErrs = append(allErrs, field.Required(idxPath.Child("branches"), "must list at least one branch"))
+		}
+		for j, pattern := range ref.Branches {
+			if _, err := path.Match(pattern, ""); err != nil {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("branches").Index(j), pattern, err.Error()))
+			}
+		}
+	}
+
+	return allErrs
+}
+
+func (in PushSpec) validate(fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if in.PerPolicyBranches && in.Refspec != "" {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("refspec"), "per-policy branches can't be pushed with a refspec"))
+	}
+
+	if in.PerPolicyBranches && in.Base != "" {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("base"), "per-policy branches can't be pushed onto a base branch"))
+	}
+
+	if in.SourceRef != nil {
+		allErrs = append(allErrs, in.SourceRef.validate(fldPath.Child("sourceRef"))...)
+	}
+
+	remotesPath := fldPath.Child("additionalRemotes")
+	remotes := make(map[string]bool, len(in.AdditionalRemotes))
+	for i, r := range in.AdditionalRemotes {
+		idxPath := remotesPath.Index(i)
+		switch {
+		case r.Name == "":
+			allErrs = append(allErrs, field.Required(idxPath.Child("name"), ""))
+		case len(r.Name) > 63:
+			allErrs = append(allErrs, field.TooLong(idxPath.Child("name"), r.Name, 63))
+		case remotes[r.Name]:
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("name"), r.Name))
+		}
+		remotes[r.Name] = true
+		if u, err := url.Parse(r.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "ssh") {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("url"), r.URL, "must be an http, https or ssh URL"))
+		}
+	}
+
+	allErrs = append(allErrs, validateEnum(fldPath.Child("headCheck"), in.HeadCheck,
+		HeadCheckAbort, HeadCheckRebase)...)
+
+	if in.Checks != nil {
+		checksPath := fldPath.Child("checks")
+		if in.Checks.Provider != "github" && in.Checks.Provider != "gitlab" {
+			allErrs = append(allErrs, field.NotSupported(checksPath.Child("provider"), in.Checks.Provider, []string{"github", "gitlab"}))
+		}
+		if in.Checks.Repository == "" {
+			allErrs = append(allErrs, field.Required(checksPath.Child("repository"), ""))
+		}
+	}
+
+	if in.API != nil {
+		apiPath := fldPath.Child("api")
+		if in.API.Provider != PushAPIProviderGitHub && in.API.Provider != PushAPIProviderGitLab {
+			allErrs = append(allErrs, field.NotSupported(apiPath.Child("provider"), in.API.Provider, []string{PushAPIProviderGitHub, PushAPIProviderGitLab}))
+		}
+		if in.API.Repository == "" {
+			allErrs = append(allErrs, field.Required(apiPath.Child("repository"), ""))
+		}
+		if in.API.SecretRef.Name == "" {
+			allErrs = append(allErrs, field.Required(apiPath.Child("secretRef", "name"), ""))
+		}
+		if in.Refspec != "" {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("refspec"), "commits made with the provider API can't be pushed with a refspec"))
+		}
+		if len(in.Options) > 0 {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("options"), "commits made with the provider API can't be pushed with push options"))
+		}
+		if len(in.AdditionalRemotes) > 0 {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("additionalRemotes"), "commits made with the provider API can't be pushed to additional remotes"))
+		}
+	}
+
+	return allErrs
+}
+
+func (in UpdateStrategy) validate(fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	switch in.Strategy {
+	case UpdateStrategySetters, UpdateStrategyKustomizeImages:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("strategy"), in.Strategy,
+			[]UpdateStrategyName{UpdateStrategySetters, UpdateStrategyKustomizeImages}))
+	}
+
+	for _, pattern := range strings.Split(path.Clean(in.Path), "/") {
+		if _, err := path.Match(pattern, ""); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("path"), in.Path, err.Error()))
+			break
+		}
+	}
+
+	allErrs = append(allErrs, validateEnum(fldPath.Child("conflictPolicy"), in.ConflictPolicy,
+		ConflictPolicyOverwrite, ConflictPolicySkip, ConflictPolicyFail)...)
+	allErrs = append(allErrs, validateEnum(fldPath.Child("maxSemverJump"), in.MaxSemverJump,
+		SemverJumpMajor, SemverJumpMinor, SemverJumpPatch)...)
+	allErrs = append(allErrs, validateEnum(fldPath.Child("helmTemplates"), in.HelmTemplates,
+		HelmTemplatesSkip, HelmTemplatesScan)...)
+	allErrs = append(allErrs, validateEnum(fldPath.Child("symlinkPolicy"), in.SymlinkPolicy,
+		SymlinkPolicyFollow, SymlinkPolicyIgnore, SymlinkPolicyFail)...)
+
+	if len(in.DataFields) > 0 && in.Strategy != UpdateStrategySetters {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("dataFields"), "data fields are only updated with the Setters strategy"))
+	}
+	for i, f := range in.DataFields {
+		allErrs = append(allErrs, f.validate(fldPath.Child("dataFields").Index(i))...)
+	}
+
+	return allErrs
+}
+
+func (in DataField) validate(fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	allErrs = append(allErrs, validateEnum(fldPath.Child("kind"), in.Kind, "ConfigMap", "Secret")...)
+	for name, value := range map[string]string{"kind": in.Kind, "name": in.Name, "key": in.Key, "path": in.Path, "policy": in.Policy} {
+		if value == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child(name), "must be set"))
+		}
+	}
+	allErrs = append(allErrs, validateEnum(fldPath.Child("value"), in.Value,
+		DataFieldValueImage, DataFieldValueTag, DataFieldValueName)...)
+	return allErrs
+}
+
+// validateEnum checks that the optional value is one of the supported
+// values, an empty value standing for the default.
+func validateEnum[T ~string](fldPath *field.Path, value T, supported ...T) field.ErrorList {
+	if value == "" {
+		return nil
+	}
+	for _, s := range supported {
+		if value == s {
+			return nil
+		}
+	}
+	return field.ErrorList{field.NotSupported(fldPath, value, supported)}
+}
+
+// isPinnedRef returns whether the checkout reference is a tag, a semver range
+// or a commit rather than the tip of a branch.
+func isPinnedRef(ref sourcev1.GitRepositoryRef) bool {
+	return ref.Tag != "" || ref.SemVer != "" || ref.Commit != ""
+}
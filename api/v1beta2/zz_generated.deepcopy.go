@@ -21,10 +21,97 @@ limitations under the License.
 package v1beta2
 
 import (
+	"github.com/fluxcd/pkg/apis/meta"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdditionalRemote) DeepCopyInto(out *AdditionalRemote) {
+	*out = *in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(meta.LocalObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdditionalRemote.
+func (in *AdditionalRemote) DeepCopy() *AdditionalRemote {
+	if in == nil {
+		return nil
+	}
+	out := new(AdditionalRemote)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BranchSigningKeyRef) DeepCopyInto(out *BranchSigningKeyRef) {
+	*out = *in
+	if in.Branches != nil {
+		in, out := &in.Branches, &out.Branches
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BranchSigningKeyRef.
+func (in *BranchSigningKeyRef) DeepCopy() *BranchSigningKeyRef {
+	if in == nil {
+		return nil
+	}
+	out := new(BranchSigningKeyRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChecksSpec) DeepCopyInto(out *ChecksSpec) {
+	*out = *in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(meta.LocalObjectReference)
+		**out = **in
+	}
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChecksSpec.
+func (in *ChecksSpec) DeepCopy() *ChecksSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ChecksSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CloneStats) DeepCopyInto(out *CloneStats) {
+	*out = *in
+	out.Duration = in.Duration
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CloneStats.
+func (in *CloneStats) DeepCopy() *CloneStats {
+	if in == nil {
+		return nil
+	}
+	out := new(CloneStats)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CommitSpec) DeepCopyInto(out *CommitSpec) {
 	*out = *in
@@ -32,7 +119,7 @@ func (in *CommitSpec) DeepCopyInto(out *CommitSpec) {
 	if in.SigningKey != nil {
 		in, out := &in.SigningKey, &out.SigningKey
 		*out = new(SigningKey)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.MessageTemplateValues != nil {
 		in, out := &in.MessageTemplateValues, &out.MessageTemplateValues
@@ -41,6 +128,11 @@ func (in *CommitSpec) DeepCopyInto(out *CommitSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.ValuesFrom != nil {
+		in, out := &in.ValuesFrom, &out.ValuesFrom
+		*out = make([]ValuesReference, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CommitSpec.
@@ -53,6 +145,22 @@ func (in *CommitSpec) DeepCopy() *CommitSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CommitStatusSpec) DeepCopyInto(out *CommitStatusSpec) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CommitStatusSpec.
+func (in *CommitStatusSpec) DeepCopy() *CommitStatusSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CommitStatusSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CommitUser) DeepCopyInto(out *CommitUser) {
 	*out = *in
@@ -71,6 +179,11 @@ func (in *CommitUser) DeepCopy() *CommitUser {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CrossNamespaceSourceReference) DeepCopyInto(out *CrossNamespaceSourceReference) {
 	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CrossNamespaceSourceReference.
@@ -83,6 +196,36 @@ func (in *CrossNamespaceSourceReference) DeepCopy() *CrossNamespaceSourceReferen
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataField) DeepCopyInto(out *DataField) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataField.
+func (in *DataField) DeepCopy() *DataField {
+	if in == nil {
+		return nil
+	}
+	out := new(DataField)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FailureStreak) DeepCopyInto(out *FailureStreak) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FailureStreak.
+func (in *FailureStreak) DeepCopy() *FailureStreak {
+	if in == nil {
+		return nil
+	}
+	out := new(FailureStreak)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitCheckoutSpec) DeepCopyInto(out *GitCheckoutSpec) {
 	*out = *in
@@ -113,6 +256,11 @@ func (in *GitSpec) DeepCopyInto(out *GitSpec) {
 		*out = new(PushSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Tag != nil {
+		in, out := &in.Tag, &out.Tag
+		*out = new(TagSpec)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitSpec.
@@ -202,7 +350,7 @@ func (in *ImageUpdateAutomationList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImageUpdateAutomationSpec) DeepCopyInto(out *ImageUpdateAutomationSpec) {
 	*out = *in
-	out.SourceRef = in.SourceRef
+	in.SourceRef.DeepCopyInto(&out.SourceRef)
 	if in.GitSpec != nil {
 		in, out := &in.GitSpec, &out.GitSpec
 		*out = new(GitSpec)
@@ -217,7 +365,35 @@ func (in *ImageUpdateAutomationSpec) DeepCopyInto(out *ImageUpdateAutomationSpec
 	if in.Update != nil {
 		in, out := &in.Update, &out.Update
 		*out = new(UpdateStrategy)
-		**out = **in
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SuspendUntil != nil {
+		in, out := &in.SuspendUntil, &out.SuspendUntil
+		*out = (*in).DeepCopy()
+	}
+	if in.Overrides != nil {
+		in, out := &in.Overrides, &out.Overrides
+		*out = make([]PolicyOverride, len(*in))
+		copy(*out, *in)
+	}
+	if in.PostPushHooks != nil {
+		in, out := &in.PostPushHooks, &out.PostPushHooks
+		*out = make([]PostPushHook, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Triggers != nil {
+		in, out := &in.Triggers, &out.Triggers
+		*out = new(Triggers)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Routes != nil {
+		in, out := &in.Routes, &out.Routes
+		*out = make([]Route, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 }
 
@@ -242,6 +418,21 @@ func (in *ImageUpdateAutomationStatus) DeepCopyInto(out *ImageUpdateAutomationSt
 		in, out := &in.LastPushTime, &out.LastPushTime
 		*out = (*in).DeepCopy()
 	}
+	if in.LastPolicyPushes != nil {
+		in, out := &in.LastPolicyPushes, &out.LastPolicyPushes
+		*out = make([]PolicyPush, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastRoutePushes != nil {
+		in, out := &in.LastRoutePushes, &out.LastRoutePushes
+		*out = make([]RoutePush, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastRemotePushes != nil {
+		in, out := &in.LastRemotePushes, &out.LastRemotePushes
+		*out = make([]RemotePush, len(*in))
+		copy(*out, *in)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]v1.Condition, len(*in))
@@ -256,6 +447,64 @@ func (in *ImageUpdateAutomationStatus) DeepCopyInto(out *ImageUpdateAutomationSt
 			(*out)[key] = val
 		}
 	}
+	if in.LastHandledRequests != nil {
+		in, out := &in.LastHandledRequests, &out.LastHandledRequests
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.SuspendedUntil != nil {
+		in, out := &in.SuspendedUntil, &out.SuspendedUntil
+		*out = (*in).DeepCopy()
+	}
+	if in.PinnedPolicies != nil {
+		in, out := &in.PinnedPolicies, &out.PinnedPolicies
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludedPolicies != nil {
+		in, out := &in.ExcludedPolicies, &out.ExcludedPolicies
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PolicyChanges != nil {
+		in, out := &in.PolicyChanges, &out.PolicyChanges
+		*out = make(map[string]PolicyChange, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.LastRunSummary != nil {
+		in, out := &in.LastRunSummary, &out.LastRunSummary
+		*out = new(RunSummary)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FailureStreak != nil {
+		in, out := &in.FailureStreak, &out.FailureStreak
+		*out = new(FailureStreak)
+		**out = **in
+	}
+	if in.MeanRunDuration != nil {
+		in, out := &in.MeanRunDuration, &out.MeanRunDuration
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.SigningVerification != nil {
+		in, out := &in.SigningVerification, &out.SigningVerification
+		*out = new(SigningVerification)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ActiveTriggers != nil {
+		in, out := &in.ActiveTriggers, &out.ActiveTriggers
+		*out = make([]Trigger, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastCloneStats != nil {
+		in, out := &in.LastCloneStats, &out.LastCloneStats
+		*out = new(CloneStats)
+		**out = **in
+	}
 	out.ReconcileRequestStatus = in.ReconcileRequestStatus
 }
 
@@ -290,9 +539,96 @@ func (in ObservedPolicies) DeepCopy() ObservedPolicies {
 	return *out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicyChange) DeepCopyInto(out *PolicyChange) {
+	*out = *in
+	in.ObservedTime.DeepCopyInto(&out.ObservedTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyChange.
+func (in *PolicyChange) DeepCopy() *PolicyChange {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicyChange)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicyOverride) DeepCopyInto(out *PolicyOverride) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyOverride.
+func (in *PolicyOverride) DeepCopy() *PolicyOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicyOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicyPush) DeepCopyInto(out *PolicyPush) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyPush.
+func (in *PolicyPush) DeepCopy() *PolicyPush {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicyPush)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostPushHook) DeepCopyInto(out *PostPushHook) {
+	*out = *in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(meta.LocalObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostPushHook.
+func (in *PostPushHook) DeepCopy() *PostPushHook {
+	if in == nil {
+		return nil
+	}
+	out := new(PostPushHook)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PushAPISpec) DeepCopyInto(out *PushAPISpec) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PushAPISpec.
+func (in *PushAPISpec) DeepCopy() *PushAPISpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PushAPISpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PushSpec) DeepCopyInto(out *PushSpec) {
 	*out = *in
+	if in.SourceRef != nil {
+		in, out := &in.SourceRef, &out.SourceRef
+		*out = new(CrossNamespaceSourceReference)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Options != nil {
 		in, out := &in.Options, &out.Options
 		*out = make(map[string]string, len(*in))
@@ -300,6 +636,38 @@ func (in *PushSpec) DeepCopyInto(out *PushSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.Checks != nil {
+		in, out := &in.Checks, &out.Checks
+		*out = new(ChecksSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CommitStatus != nil {
+		in, out := &in.CommitStatus, &out.CommitStatus
+		*out = new(CommitStatusSpec)
+		**out = **in
+	}
+	if in.AdditionalRemotes != nil {
+		in, out := &in.AdditionalRemotes, &out.AdditionalRemotes
+		*out = make([]AdditionalRemote, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.FallbackSecretRef != nil {
+		in, out := &in.FallbackSecretRef, &out.FallbackSecretRef
+		*out = new(meta.LocalObjectReference)
+		**out = **in
+	}
+	if in.ForcePush != nil {
+		in, out := &in.ForcePush, &out.ForcePush
+		*out = new(bool)
+		**out = **in
+	}
+	if in.API != nil {
+		in, out := &in.API, &out.API
+		*out = new(PushAPISpec)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PushSpec.
@@ -312,10 +680,106 @@ func (in *PushSpec) DeepCopy() *PushSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RemotePush) DeepCopyInto(out *RemotePush) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemotePush.
+func (in *RemotePush) DeepCopy() *RemotePush {
+	if in == nil {
+		return nil
+	}
+	out := new(RemotePush)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Route) DeepCopyInto(out *Route) {
+	*out = *in
+	in.PolicySelector.DeepCopyInto(&out.PolicySelector)
+	in.Push.DeepCopyInto(&out.Push)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Route.
+func (in *Route) DeepCopy() *Route {
+	if in == nil {
+		return nil
+	}
+	out := new(Route)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RoutePush) DeepCopyInto(out *RoutePush) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RoutePush.
+func (in *RoutePush) DeepCopy() *RoutePush {
+	if in == nil {
+		return nil
+	}
+	out := new(RoutePush)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RoutePushSpec) DeepCopyInto(out *RoutePushSpec) {
+	*out = *in
+	if in.Options != nil {
+		in, out := &in.Options, &out.Options
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RoutePushSpec.
+func (in *RoutePushSpec) DeepCopy() *RoutePushSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RoutePushSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunSummary) DeepCopyInto(out *RunSummary) {
+	*out = *in
+	if in.SyncReasons != nil {
+		in, out := &in.SyncReasons, &out.SyncReasons
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunSummary.
+func (in *RunSummary) DeepCopy() *RunSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(RunSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SigningKey) DeepCopyInto(out *SigningKey) {
 	*out = *in
 	out.SecretRef = in.SecretRef
+	if in.SecretRefs != nil {
+		in, out := &in.SecretRefs, &out.SecretRefs
+		*out = make([]BranchSigningKeyRef, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SigningKey.
@@ -328,9 +792,65 @@ func (in *SigningKey) DeepCopy() *SigningKey {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SigningVerification) DeepCopyInto(out *SigningVerification) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SigningVerification.
+func (in *SigningVerification) DeepCopy() *SigningVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(SigningVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TagSpec) DeepCopyInto(out *TagSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TagSpec.
+func (in *TagSpec) DeepCopy() *TagSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TagSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Triggers) DeepCopyInto(out *Triggers) {
+	*out = *in
+	if in.ImagePolicies != nil {
+		in, out := &in.ImagePolicies, &out.ImagePolicies
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Triggers.
+func (in *Triggers) DeepCopy() *Triggers {
+	if in == nil {
+		return nil
+	}
+	out := new(Triggers)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UpdateStrategy) DeepCopyInto(out *UpdateStrategy) {
 	*out = *in
+	if in.DataFields != nil {
+		in, out := &in.DataFields, &out.DataFields
+		*out = make([]DataField, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpdateStrategy.
@@ -342,3 +862,18 @@ func (in *UpdateStrategy) DeepCopy() *UpdateStrategy {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ValuesReference) DeepCopyInto(out *ValuesReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ValuesReference.
+func (in *ValuesReference) DeepCopy() *ValuesReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ValuesReference)
+	in.DeepCopyInto(out)
+	return out
+}
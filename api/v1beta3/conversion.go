@@ -0,0 +1,164 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta3
+
+import (
+	"fmt"
+	"reflect"
+
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+// ConvertTo converts this ImageUpdateAutomation to the hub version, v1beta2.
+// The checkout and write targets are converted to the source reference and
+// the Git specification, the write target becoming the source reference of
+// the push. An empty checkout reference and write target are converted to no
+// Git specification.
+func (src *ImageUpdateAutomation) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*v1beta2.ImageUpdateAutomation)
+	if !ok {
+		return fmt.Errorf("unsupported conversion hub type %T", dstRaw)
+	}
+
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = v1beta2.ImageUpdateAutomationSpec{
+		SourceRef:          src.Spec.Checkout.SourceRef,
+		Interval:           src.Spec.Interval,
+		PolicySelector:     src.Spec.PolicySelector,
+		ServiceAccountName: src.Spec.ServiceAccountName,
+		Update:             src.Spec.Update,
+		Suspend:            src.Spec.Suspend,
+		SuspendUntil:       src.Spec.SuspendUntil,
+		Overrides:          src.Spec.Overrides,
+		PostPushHooks:      src.Spec.PostPushHooks,
+		Triggers:           src.Spec.Triggers,
+		Routes:             src.Spec.Write.Routes,
+	}
+	dst.Status = src.Status
+	if src.Spec.Checkout.Reference == nil && src.Spec.Checkout.Strategy == "" && isEmptyWrite(src.Spec.Write) {
+		return nil
+	}
+
+	dst.Spec.GitSpec = &v1beta2.GitSpec{
+		Commit: src.Spec.Write.Commit,
+		Tag:    src.Spec.Write.Tag,
+	}
+	if ref := src.Spec.Checkout.Reference; ref != nil || src.Spec.Checkout.Strategy != "" {
+		dst.Spec.GitSpec.Checkout = &v1beta2.GitCheckoutSpec{Strategy: src.Spec.Checkout.Strategy}
+		if ref != nil {
+			dst.Spec.GitSpec.Checkout.Reference = *ref
+		}
+	}
+	if push := src.Spec.Write.Push; push != nil || src.Spec.Write.SourceRef != nil {
+		dst.Spec.GitSpec.Push = &v1beta2.PushSpec{SourceRef: src.Spec.Write.SourceRef}
+		if push != nil {
+			dst.Spec.GitSpec.Push.Branch = push.Branch
+			dst.Spec.GitSpec.Push.Base = push.Base
+			dst.Spec.GitSpec.Push.Refspec = push.Refspec
+			dst.Spec.GitSpec.Push.Options = push.Options
+			dst.Spec.GitSpec.Push.PerPolicyBranches = push.PerPolicyBranches
+			dst.Spec.GitSpec.Push.Checks = push.Checks
+			dst.Spec.GitSpec.Push.CommitStatus = push.CommitStatus
+			dst.Spec.GitSpec.Push.AdditionalRemotes = push.AdditionalRemotes
+			dst.Spec.GitSpec.Push.HeadCheck = push.HeadCheck
+			dst.Spec.GitSpec.Push.FallbackSecretRef = push.FallbackSecretRef
+			dst.Spec.GitSpec.Push.ForcePush = push.ForcePush
+			dst.Spec.GitSpec.Push.API = push.API
+		}
+	}
+	return nil
+}
+
+// ConvertFrom converts the hub version, v1beta2, to this
+// ImageUpdateAutomation. The source reference of the push becomes the write
+// target.
+func (dst *ImageUpdateAutomation) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*v1beta2.ImageUpdateAutomation)
+	if !ok {
+		return fmt.Errorf("unsupported conversion hub type %T", srcRaw)
+	}
+
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = ImageUpdateAutomationSpec{
+		Checkout: CheckoutSpec{
+			SourceRef: src.Spec.SourceRef,
+		},
+		Interval:           src.Spec.Interval,
+		PolicySelector:     src.Spec.PolicySelector,
+		ServiceAccountName: src.Spec.ServiceAccountName,
+		Update:             src.Spec.Update,
+		Suspend:            src.Spec.Suspend,
+		SuspendUntil:       src.Spec.SuspendUntil,
+		Overrides:          src.Spec.Overrides,
+		PostPushHooks:      src.Spec.PostPushHooks,
+		Triggers:           src.Spec.Triggers,
+		Write: WriteSpec{
+			Routes: src.Spec.Routes,
+		},
+	}
+	if gitSpec := src.Spec.GitSpec; gitSpec != nil {
+		if gitSpec.Checkout.HasReference() {
+			ref := gitSpec.Checkout.Reference
+			dst.Spec.Checkout.Reference = &ref
+		}
+		if gitSpec.Checkout != nil {
+			dst.Spec.Checkout.Strategy = gitSpec.Checkout.Strategy
+		}
+		dst.Spec.Write.Commit = gitSpec.Commit
+		dst.Spec.Write.Tag = gitSpec.Tag
+		if push := gitSpec.Push; push != nil {
+			dst.Spec.Write.SourceRef = push.SourceRef
+			// The write target alone is converted without a push
+			// specification, to convert it back the same way.
+			if push.SourceRef == nil || !isEmptyPush(*push) {
+				dst.Spec.Write.Push = &PushSpec{
+					Branch:            push.Branch,
+					Base:              push.Base,
+					Refspec:           push.Refspec,
+					Options:           push.Options,
+					PerPolicyBranches: push.PerPolicyBranches,
+					Checks:            push.Checks,
+					CommitStatus:      push.CommitStatus,
+					AdditionalRemotes: push.AdditionalRemotes,
+					HeadCheck:         push.HeadCheck,
+					FallbackSecretRef: push.FallbackSecretRef,
+					ForcePush:         push.ForcePush,
+					API:               push.API,
+				}
+			}
+		}
+	}
+	dst.Status = src.Status
+	return nil
+}
+
+// isEmptyPush returns whether the push specification has no other field than
+// its source reference.
+func isEmptyPush(push v1beta2.PushSpec) bool {
+	return push.Branch == "" && push.Base == "" && push.Refspec == "" && len(push.Options) == 0 && !push.PerPolicyBranches &&
+		push.Checks == nil && push.CommitStatus == nil && len(push.AdditionalRemotes) == 0 && push.HeadCheck == "" &&
+		push.FallbackSecretRef == nil && push.ForcePush == nil && push.API == nil
+}
+
+// isEmptyWrite returns whether the write target has no other field than its
+// routes, which is the case of the v1beta2 automations without Git
+// specification.
+func isEmptyWrite(write WriteSpec) bool {
+	return write.SourceRef == nil && reflect.DeepEqual(write.Commit, v1beta2.CommitSpec{}) && write.Push == nil && write.Tag == nil
+}
@@ -0,0 +1,319 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta3
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+
+	"github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+const (
+	ImageUpdateAutomationKind = "ImageUpdateAutomation"
+)
+
+// ImageUpdateAutomationSpec defines the desired state of ImageUpdateAutomation
+// +kubebuilder:validation:XValidation:rule="!has(self.checkout.ref) || !(has(self.checkout.ref.tag) || has(self.checkout.ref.semver) || has(self.checkout.ref.commit)) || (has(self.write.push) && (has(self.write.push.branch) || has(self.write.push.refspec) || has(self.write.push.base)))",message="push branch, refspec or base must be set to check out a tag, a semver range or a commit"
+// +kubebuilder:validation:XValidation:rule="!has(self.checkout.ref) || !has(self.checkout.ref.branch) || !(has(self.checkout.ref.tag) || has(self.checkout.ref.semver) || has(self.checkout.ref.commit)) || !has(self.write.push) || !has(self.write.push.branch) || self.write.push.branch != self.checkout.ref.branch",message="push branch must differ from the checkout branch to check out a commit"
+// +kubebuilder:validation:XValidation:rule="!has(self.checkout.ref) || !has(self.checkout.ref.branch) || !has(self.write.push) || !has(self.write.push.base) || (self.write.push.base != self.checkout.ref.branch && (!has(self.write.push.branch) || self.write.push.branch != self.checkout.ref.branch))",message="base and push branches must differ from the checkout branch"
+type ImageUpdateAutomationSpec struct {
+	// Checkout gives the Git repository the automation checks out to update
+	// the files, and the reference to check out.
+	// +required
+	Checkout CheckoutSpec `json:"checkout"`
+
+	// Write gives how to commit the changes and where to push the commits,
+	// by default to the checked out Git repository.
+	// +required
+	Write WriteSpec `json:"write"`
+
+	// Interval gives an lower bound for how often the automation
+	// run should be attempted.
+	// +kubebuilder:validation:Type=string
+	// +kubebuilder:validation:Pattern="^([0-9]+(\\.[0-9]+)?(ms|s|m|h))+$"
+	// +required
+	Interval metav1.Duration `json:"interval"`
+
+	// PolicySelector allows to filter applied policies based on labels.
+	// By default includes all policies in namespace.
+	// +optional
+	PolicySelector *metav1.LabelSelector `json:"policySelector,omitempty"`
+
+	// ServiceAccountName is the name of the ServiceAccount, in the namespace
+	// of the automation, impersonated to read the policies and the source.
+	// It defaults to the ServiceAccount set with the controller
+	// --default-service-account flag, if any.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// Update gives the specification for how to update the files in
+	// the repository. This can be left empty, to use the default
+	// value.
+	// +kubebuilder:default={"strategy":"Setters"}
+	Update *v1beta2.UpdateStrategy `json:"update,omitempty"`
+
+	// Suspend tells the controller to not run this automation, until
+	// it is unset (or set to false). Defaults to false.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// SuspendUntil tells the controller to not run this automation until the
+	// given time, e.g. for the duration of an incident, after which it
+	// resumes on its own. It has no effect once the time has passed.
+	// +optional
+	SuspendUntil *metav1.Time `json:"suspendUntil,omitempty"`
+
+	// Overrides pins the images of the given ImagePolicies to a fixed tag or
+	// digest, regardless of their latest image, e.g. to hold back an image
+	// during an incident. The overrides are applied until they are removed.
+	// +listType=map
+	// +listMapKey=policyName
+	// +optional
+	Overrides []v1beta2.PolicyOverride `json:"overrides,omitempty"`
+
+	// PostPushHooks are notified of each successful push, with the name of
+	// the automation, the pushed commit and the updated images, e.g. to
+	// trigger a deployment pipeline. A failure to notify a hook is reported
+	// with an event, and doesn't fail the reconciliation.
+	// +optional
+	PostPushHooks []v1beta2.PostPushHook `json:"postPushHooks,omitempty"`
+
+	// Triggers configures what triggers the runs of the automation, besides
+	// its interval and the reconcile requests.
+	// +optional
+	Triggers *v1beta2.Triggers `json:"triggers,omitempty"`
+}
+
+// CheckoutSpec gives the Git repository checked out by the automation.
+type CheckoutSpec struct {
+	// SourceRef refers to the GitRepository checked out by the automation.
+	// +required
+	SourceRef v1beta2.CrossNamespaceSourceReference `json:"sourceRef"`
+
+	// Reference gives a branch, tag or commit to clone from the Git
+	// repository. If not present, the `spec.ref` field from the referenced
+	// `GitRepository` or its default will be used.
+	// +optional
+	Reference *sourcev1.GitRepositoryRef `json:"ref,omitempty"`
+
+	// Strategy tells how the source is cloned, overriding the
+	// GitShallowClone feature gate of the controller for this automation:
+	// Shallow to clone the latest commit only, or Full to clone the complete
+	// history, e.g. for a Git server mishandling shallow clones.
+	// +kubebuilder:validation:Enum=Shallow;Full
+	// +optional
+	Strategy v1beta2.CheckoutStrategy `json:"strategy,omitempty"`
+}
+
+// WriteSpec gives how the automation commits the changes, and where it pushes
+// the commits.
+// +kubebuilder:validation:XValidation:rule="!has(self.push) || !has(self.push.api) || (!has(self.commit.signingKey) && !has(self.tag))",message="commits made with the provider API can't be signed with a signing key nor tagged"
+type WriteSpec struct {
+	// SourceRef refers to the GitRepository the commits are pushed to, when
+	// it's not the checked out one, e.g. a fork of it to open pull requests
+	// from. Only its URL and credentials are used. The push branch is then
+	// always created from the checkout, its state in the checked out
+	// repository being ignored.
+	// +optional
+	SourceRef *v1beta2.CrossNamespaceSourceReference `json:"sourceRef,omitempty"`
+
+	// Commit specifies how to commit to the git repository.
+	// +required
+	Commit v1beta2.CommitSpec `json:"commit"`
+
+	// Push specifies how and where to push commits made by the
+	// automation. If missing, commits are pushed to the checked out branch.
+	// +optional
+	Push *PushSpec `json:"push,omitempty"`
+
+	// Tag specifies an annotated tag to create or update, pointing at
+	// each commit pushed by the automation.
+	// +optional
+	Tag *v1beta2.TagSpec `json:"tag,omitempty"`
+
+	// Routes push the changes of the ImagePolicies they select to branches
+	// of their own, each route making a commit of its own, e.g. the changes
+	// of the policies labeled `env=staging` to the staging branch, and those
+	// of the policies labeled `env=prod` to the branch of a pull request.
+	// The policies selected by no route aren't updated. They can't be used
+	// with a push branch, a refspec, a base branch, per-policy branches or
+	// the provider API.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Routes []v1beta2.Route `json:"routes,omitempty"`
+}
+
+// PushSpec specifies how and where to push commits.
+// +kubebuilder:validation:XValidation:rule="!has(self.perPolicyBranches) || !self.perPolicyBranches || !has(self.refspec)",message="per-policy branches can't be pushed with a refspec"
+// +kubebuilder:validation:XValidation:rule="!has(self.perPolicyBranches) || !self.perPolicyBranches || !has(self.base)",message="per-policy branches can't be pushed onto a base branch"
+// +kubebuilder:validation:XValidation:rule="!has(self.api) || (!has(self.refspec) && !has(self.options) && !has(self.additionalRemotes))",message="commits made with the provider API can't be pushed with a refspec, push options or additional remotes"
+type PushSpec struct {
+	// Branch specifies that commits should be pushed to the branch
+	// named. The branch is created using the checked out branch as the
+	// starting point, if it doesn't already exist. It's required when the
+	// checkout reference is a tag, a semver range or a commit, unless a
+	// Refspec is provided.
+	// +optional
+	Branch string `json:"branch,omitempty"`
+
+	// Base is the branch the changes are applied to, when it's not the
+	// checked out branch, e.g. a release branch the updates of the main
+	// branch are backported to. The changes are computed against the
+	// checkout, and only the changed lines are merged into the files of the
+	// base branch, with a three-way merge, instead of committing the files
+	// of the checkout as they are. The commit is made on top of the base
+	// branch and pushed to Branch, which then starts from the base branch,
+	// or to the base branch itself when Branch is empty. Changed lines which
+	// aren't found in the base branch fail the update. It can't be used with
+	// PerPolicyBranches.
+	// +optional
+	Base string `json:"base,omitempty"`
+
+	// Refspec specifies the Git Refspec to use for a push operation.
+	// If both Branch and Refspec are provided, then the commit is pushed
+	// to the branch and also using the specified refspec. If only Refspec
+	// is provided, the commit is only pushed using the refspec.
+	// For more details about Git Refspecs, see:
+	// https://git-scm.com/book/en/v2/Git-Internals-The-Refspec
+	// +optional
+	Refspec string `json:"refspec,omitempty"`
+
+	// Options specifies the push options that are sent to the Git
+	// server when performing a push operation. For details, see:
+	// https://git-scm.com/docs/git-push#Documentation/git-push.txt---push-optionltoptiongt
+	// +optional
+	Options map[string]string `json:"options,omitempty"`
+
+	// PerPolicyBranches specifies that the changes of each ImagePolicy are
+	// committed and pushed to a branch of their own, created from the
+	// checkout branch, e.g. to open one pull request per image. Branch is
+	// then the template of the names of the branches, rendered with the
+	// `.Policy.Name` and `.Policy.Namespace` of each ImagePolicy, and
+	// defaults to `image-updates/{{ .Policy.Name }}`. It can't be used
+	// with Refspec.
+	// +optional
+	PerPolicyBranches bool `json:"perPolicyBranches,omitempty"`
+
+	// Checks configures waiting for the status checks of the pushed commits
+	// on the Git provider, whose result is reported by the ChecksPassed
+	// condition.
+	// +optional
+	Checks *v1beta2.ChecksSpec `json:"checks,omitempty"`
+
+	// CommitStatus configures setting a commit status on the pushed commits
+	// on the Git provider, e.g. `flux-image-automation: updated app to
+	// v1.2.3`, for the repository UIs to show the automation which made
+	// them. A failure to set the status is reported with an event without
+	// failing the reconciliation.
+	// +optional
+	CommitStatus *v1beta2.CommitStatusSpec `json:"commitStatus,omitempty"`
+
+	// AdditionalRemotes is the list of the remotes the pushed commits are
+	// also pushed to, with the same branches and tags, e.g. to keep both
+	// repositories up to date during a migration. A failure to push to an
+	// additional remote is reported in the status without failing the
+	// reconciliation.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	AdditionalRemotes []v1beta2.AdditionalRemote `json:"additionalRemotes,omitempty"`
+
+	// HeadCheck checks, right before pushing, that the head of the push
+	// branch on the remote didn't move since the checkout, e.g. because of a
+	// commit pushed by a human, to never overwrite it. Abort fails the push,
+	// which is retried from a new checkout. Rebase makes the commit again on
+	// top of the new head when the commits pushed meanwhile changed none of
+	// the files of the commit, and fails the push otherwise. The head isn't
+	// checked by default, nor for per-policy branches.
+	// +kubebuilder:validation:Enum=Abort;Rebase
+	// +optional
+	HeadCheck v1beta2.HeadCheckPolicy `json:"headCheck,omitempty"`
+
+	// FallbackSecretRef refers to a Secret, in the namespace of the
+	// ImageUpdateAutomation, with the credentials the commits are pushed
+	// with when the remote rejects the credentials of the GitRepository they
+	// are pushed to, e.g. while those are rotated. The Secret has the same
+	// format as the Secret of a GitRepository. The credentials the last push
+	// succeeded with are recorded in `.status.lastPushCredentials`.
+	// +optional
+	FallbackSecretRef *meta.LocalObjectReference `json:"fallbackSecretRef,omitempty"`
+
+	// ForcePush tells whether the commits are force pushed to a push branch
+	// other than the checkout branch, overwriting the changes made to it,
+	// overriding the GitForcePushBranch feature gate of the controller for
+	// this automation. The checkout branch itself is never force pushed.
+	// +optional
+	ForcePush *bool `json:"forcePush,omitempty"`
+
+	// API makes the commits with the REST API of the Git provider instead
+	// of pushing them, e.g. for the provider to sign them, or when pushing
+	// over SSH or HTTPS is blocked. The changes are still committed in the
+	// checkout, and only the push branch is updated on the provider. It
+	// can't be used with a Refspec, push Options or AdditionalRemotes, nor
+	// with a signing key or a tag.
+	// +optional
+	API *v1beta2.PushAPISpec `json:"api,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Last run",type=string,JSONPath=`.status.lastAutomationRunTime`
+
+// ImageUpdateAutomation is the Schema for the imageupdateautomations API
+type ImageUpdateAutomation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ImageUpdateAutomationSpec `json:"spec,omitempty"`
+	// +kubebuilder:default={"observedGeneration":-1}
+	Status v1beta2.ImageUpdateAutomationStatus `json:"status,omitempty"`
+}
+
+// GetRequeueAfter returns the duration after which the ImageUpdateAutomation
+// must be reconciled again.
+func (auto ImageUpdateAutomation) GetRequeueAfter() time.Duration {
+	return auto.Spec.Interval.Duration
+}
+
+// GetConditions returns the status conditions of the object.
+func (auto ImageUpdateAutomation) GetConditions() []metav1.Condition {
+	return auto.Status.Conditions
+}
+
+// SetConditions sets the status conditions on the object.
+func (auto *ImageUpdateAutomation) SetConditions(conditions []metav1.Condition) {
+	auto.Status.Conditions = conditions
+}
+
+//+kubebuilder:object:root=true
+
+// ImageUpdateAutomationList contains a list of ImageUpdateAutomation
+type ImageUpdateAutomationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ImageUpdateAutomation `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ImageUpdateAutomation{}, &ImageUpdateAutomationList{})
+}
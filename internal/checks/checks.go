@@ -0,0 +1,182 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package checks queries the Git providers for the status checks of the
+// commits pushed by an ImageUpdateAutomation, and sets the commit statuses of
+// these commits.
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fluxcd/pkg/apis/meta"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+	"github.com/fluxcd/image-automation-controller/internal/httpjson"
+)
+
+const tokenKey = "token"
+
+// ErrUnsupportedProvider is the error of checks with a provider which isn't
+// supported.
+var ErrUnsupportedProvider = errors.New("unsupported checks provider")
+
+// State is the overall state of the status checks of a commit.
+type State string
+
+const (
+	// StatePending is the state of checks which are still running, or which
+	// haven't been reported yet.
+	StatePending State = "pending"
+	// StateSuccess is the state of checks which all succeeded.
+	StateSuccess State = "success"
+	// StateFailure is the state of checks of which at least one failed.
+	StateFailure State = "failure"
+)
+
+// Result is the result of the status checks of a commit.
+type Result struct {
+	// State is the overall state of the checks.
+	State State
+	// Description summarizes the checks, e.g. "2 of 3 checks succeeded, 1
+	// pending: build".
+	Description string
+}
+
+// Checker queries a provider for the status checks of commits.
+type Checker interface {
+	Checks(ctx context.Context, commit string) (Result, error)
+}
+
+// Status is a commit status set on a commit, with the success state.
+type Status struct {
+	// Context is the name of the status.
+	Context string
+	// Description describes the status, e.g. "updated app to v1.2.3".
+	Description string
+}
+
+// StatusSetter sets the commit statuses of commits on a provider.
+type StatusSetter interface {
+	SetStatus(ctx context.Context, commit string, status Status) error
+}
+
+// provider is the client of the API of a Git provider.
+type provider interface {
+	Checker
+	StatusSetter
+}
+
+// NewChecker returns the Checker of the given checks configuration of an
+// ImageUpdateAutomation in the given namespace, authenticated with the token
+// of the Secret the configuration refers to, if any. The checks with the
+// given names, e.g. the commit status set by the automation, are left out.
+func NewChecker(ctx context.Context, c client.Client, namespace string, spec imagev1.ChecksSpec, ignored ...string) (Checker, error) {
+	return newProvider(ctx, c, namespace, spec.Provider, spec.Address, spec.Repository, spec.SecretRef, ignored)
+}
+
+// NewStatusSetter returns the StatusSetter of the given commit status
+// configuration of an ImageUpdateAutomation in the given namespace,
+// authenticated with the token of the Secret the configuration refers to.
+func NewStatusSetter(ctx context.Context, c client.Client, namespace string, spec imagev1.CommitStatusSpec) (StatusSetter, error) {
+	return newProvider(ctx, c, namespace, spec.Provider, spec.Address, spec.Repository, &spec.SecretRef, nil)
+}
+
+// newProvider returns the client of the API of the given provider at the
+// given address, for the given repository, authenticated with the token of
+// the given Secret of the given namespace, if any.
+func newProvider(ctx context.Context, c client.Client, namespace, providerType, address, repository string,
+	secretRef *meta.LocalObjectReference, ignored []string) (provider, error) {
+	var token string
+	if secretRef != nil {
+		key := types.NamespacedName{Namespace: namespace, Name: secretRef.Name}
+		var secret corev1.Secret
+		if err := c.Get(ctx, key, &secret); err != nil {
+			return nil, fmt.Errorf("failed to get provider secret '%s': %w", key, err)
+		}
+		token = string(secret.Data[tokenKey])
+	}
+
+	httpClient := httpjson.NewClient()
+	address = strings.TrimSuffix(address, "/")
+	switch providerType {
+	case imagev1.ChecksProviderGitHub:
+		if address == "" {
+			address = defaultGitHubAddress
+		}
+		return &gitHubProvider{address: address, repository: repository, token: token, ignored: ignored, client: httpClient}, nil
+	case imagev1.ChecksProviderGitLab:
+		if address == "" {
+			address = defaultGitLabAddress
+		}
+		return &gitLabProvider{address: address, project: repository, token: token, ignored: ignored, client: httpClient}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedProvider, providerType)
+	}
+}
+
+// check is the state of a single status check of a commit.
+type check struct {
+	name  string
+	state State
+}
+
+// combine returns the overall result of the given checks, leaving out the
+// ones with the given ignored names. A commit without any check is pending,
+// as the checks may not have been reported yet.
+func combine(checks []check, ignored []string) Result {
+	checks = slices.DeleteFunc(checks, func(c check) bool { return slices.Contains(ignored, c.name) })
+	if len(checks) == 0 {
+		return Result{State: StatePending, Description: "no checks reported"}
+	}
+
+	var failed, pending []string
+	for _, c := range checks {
+		switch c.state {
+		case StateFailure:
+			failed = append(failed, c.name)
+		case StatePending:
+			pending = append(pending, c.name)
+		}
+	}
+	sort.Strings(failed)
+	sort.Strings(pending)
+
+	succeeded := len(checks) - len(failed) - len(pending)
+	if len(failed) == 0 && len(pending) == 0 {
+		return Result{State: StateSuccess, Description: fmt.Sprintf("%d checks succeeded", succeeded)}
+	}
+	desc := fmt.Sprintf("%d of %d checks succeeded", succeeded, len(checks))
+	if len(failed) > 0 {
+		desc += fmt.Sprintf(", %d failed: %s", len(failed), strings.Join(failed, ", "))
+	}
+	if len(pending) > 0 {
+		desc += fmt.Sprintf(", %d pending: %s", len(pending), strings.Join(pending, ", "))
+	}
+	if len(failed) > 0 {
+		return Result{State: StateFailure, Description: desc}
+	}
+	return Result{State: StatePending, Description: desc}
+}
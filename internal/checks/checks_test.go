@@ -0,0 +1,248 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/fluxcd/pkg/apis/meta"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+const testCommit = "6a3f8b2d9c1e"
+
+func TestChecker_github(t *testing.T) {
+	tests := []struct {
+		name      string
+		statuses  string
+		checkRuns string
+		want      Result
+	}{
+		{
+			name:      "no checks",
+			statuses:  `{"state": "pending", "statuses": []}`,
+			checkRuns: `{"total_count": 0, "check_runs": []}`,
+			want:      Result{State: StatePending, Description: "no checks reported"},
+		},
+		{
+			name:      "all succeeded",
+			statuses:  `{"statuses": [{"context": "ci/build", "state": "success"}]}`,
+			checkRuns: `{"check_runs": [{"name": "lint", "status": "completed", "conclusion": "skipped"}]}`,
+			want:      Result{State: StateSuccess, Description: "2 checks succeeded"},
+		},
+		{
+			name:      "pending",
+			statuses:  `{"statuses": [{"context": "ci/build", "state": "success"}]}`,
+			checkRuns: `{"check_runs": [{"name": "e2e", "status": "in_progress"}]}`,
+			want:      Result{State: StatePending, Description: "1 of 2 checks succeeded, 1 pending: e2e"},
+		},
+		{
+			name:      "own status left out",
+			statuses:  `{"statuses": [{"context": "flux-image-automation", "state": "success"}]}`,
+			checkRuns: `{"check_runs": [{"name": "e2e", "status": "in_progress"}]}`,
+			want:      Result{State: StatePending, Description: "0 of 1 checks succeeded, 1 pending: e2e"},
+		},
+		{
+			name:      "failed",
+			statuses:  `{"statuses": [{"context": "ci/build", "state": "error"}]}`,
+			checkRuns: `{"check_runs": [{"name": "e2e", "status": "queued"}, {"name": "lint", "status": "completed", "conclusion": "timed_out"}]}`,
+			want:      Result{State: StateFailure, Description: "0 of 3 checks succeeded, 2 failed: ci/build, lint, 1 pending: e2e"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/repos/org/app/commits/"+testCommit+"/status", func(w http.ResponseWriter, r *http.Request) {
+				g.Expect(r.Header.Get("Authorization")).To(Equal("Bearer s3cr3t"))
+				fmt.Fprint(w, tt.statuses)
+			})
+			mux.HandleFunc("/repos/org/app/commits/"+testCommit+"/check-runs", func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, tt.checkRuns)
+			})
+			srv := httptest.NewServer(mux)
+			defer srv.Close()
+
+			checker, err := NewChecker(context.TODO(), tokenClient(), "default", imagev1.ChecksSpec{
+				Provider:   imagev1.ChecksProviderGitHub,
+				Address:    srv.URL + "/",
+				Repository: "org/app",
+				SecretRef:  &meta.LocalObjectReference{Name: "checks-token"},
+			}, imagev1.DefaultCommitStatusContext)
+			g.Expect(err).ToNot(HaveOccurred())
+			got, err := checker.Checks(context.TODO(), testCommit)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestChecker_gitlab(t *testing.T) {
+	g := NewWithT(t)
+
+	var gotPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		g.Expect(r.Header.Get("PRIVATE-TOKEN")).To(Equal("s3cr3t"))
+		fmt.Fprint(w, `[{"name": "build", "status": "success"}, {"name": "deploy", "status": "running"}]`)
+	}))
+	defer srv.Close()
+
+	checker, err := NewChecker(context.TODO(), tokenClient(), "default", imagev1.ChecksSpec{
+		Provider:   imagev1.ChecksProviderGitLab,
+		Address:    srv.URL,
+		Repository: "group/subgroup/app",
+		SecretRef:  &meta.LocalObjectReference{Name: "checks-token"},
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	got, err := checker.Checks(context.TODO(), testCommit)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(gotPath).To(Equal("/projects/group%2Fsubgroup%2Fapp/repository/commits/" + testCommit + "/statuses"))
+	g.Expect(got).To(Equal(Result{State: StatePending, Description: "1 of 2 checks succeeded, 1 pending: deploy"}))
+}
+
+func TestChecker_errors(t *testing.T) {
+	g := NewWithT(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	checker, err := NewChecker(context.TODO(), tokenClient(), "default", imagev1.ChecksSpec{
+		Provider:   imagev1.ChecksProviderGitHub,
+		Address:    srv.URL,
+		Repository: "org/app",
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = checker.Checks(context.TODO(), testCommit)
+	g.Expect(err).To(MatchError(ContainSubstring("unexpected status '404 Not Found'")))
+
+	_, err = NewChecker(context.TODO(), tokenClient(), "default", imagev1.ChecksSpec{
+		Provider:   "bitbucket",
+		Repository: "org/app",
+	})
+	g.Expect(err).To(MatchError(ErrUnsupportedProvider))
+
+	_, err = NewChecker(context.TODO(), tokenClient(), "default", imagev1.ChecksSpec{
+		Provider:   imagev1.ChecksProviderGitHub,
+		Repository: "org/app",
+		SecretRef:  &meta.LocalObjectReference{Name: "non-existing"},
+	})
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestStatusSetter(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		repo     string
+		wantPath string
+		wantAuth [2]string
+		wantBody map[string]string
+	}{
+		{
+			name:     "github",
+			provider: imagev1.ChecksProviderGitHub,
+			repo:     "org/app",
+			wantPath: "/repos/org/app/statuses/" + testCommit,
+			wantAuth: [2]string{"Authorization", "Bearer s3cr3t"},
+			wantBody: map[string]string{"state": "success", "context": "flux-image-automation", "description": "updated app to v1.2.3"},
+		},
+		{
+			name:     "gitlab",
+			provider: imagev1.ChecksProviderGitLab,
+			repo:     "group/app",
+			wantPath: "/projects/group%2Fapp/statuses/" + testCommit,
+			wantAuth: [2]string{"PRIVATE-TOKEN", "s3cr3t"},
+			wantBody: map[string]string{"state": "success", "name": "flux-image-automation", "description": "updated app to v1.2.3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			var gotPath string
+			var gotBody map[string]string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.EscapedPath()
+				g.Expect(r.Method).To(Equal(http.MethodPost))
+				g.Expect(r.Header.Get(tt.wantAuth[0])).To(Equal(tt.wantAuth[1]))
+				g.Expect(json.NewDecoder(r.Body).Decode(&gotBody)).To(Succeed())
+				w.WriteHeader(http.StatusCreated)
+			}))
+			defer srv.Close()
+
+			setter, err := NewStatusSetter(context.TODO(), tokenClient(), "default", imagev1.CommitStatusSpec{
+				Provider:   tt.provider,
+				Address:    srv.URL,
+				Repository: tt.repo,
+				SecretRef:  meta.LocalObjectReference{Name: "checks-token"},
+			})
+			g.Expect(err).ToNot(HaveOccurred())
+			err = setter.SetStatus(context.TODO(), testCommit, Status{
+				Context:     imagev1.DefaultCommitStatusContext,
+				Description: "updated app to v1.2.3",
+			})
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(gotPath).To(Equal(tt.wantPath))
+			g.Expect(gotBody).To(Equal(tt.wantBody))
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		g := NewWithT(t)
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		}))
+		defer srv.Close()
+
+		setter, err := NewStatusSetter(context.TODO(), tokenClient(), "default", imagev1.CommitStatusSpec{
+			Provider:   imagev1.ChecksProviderGitHub,
+			Address:    srv.URL,
+			Repository: "org/app",
+			SecretRef:  meta.LocalObjectReference{Name: "checks-token"},
+		})
+		g.Expect(err).ToNot(HaveOccurred())
+		err = setter.SetStatus(context.TODO(), testCommit, Status{Context: "ctx"})
+		g.Expect(err).To(MatchError(ContainSubstring("unexpected status '403 Forbidden'")))
+	})
+}
+
+// tokenClient returns a client with the Secret of the token of the checks.
+func tokenClient() client.Client {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "checks-token", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("s3cr3t")},
+	}
+	return fakeclient.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(secret).Build()
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/fluxcd/image-automation-controller/internal/httpjson"
+)
+
+const defaultGitHubAddress = "https://api.github.com"
+
+// gitHubProvider combines the commit statuses and the check runs of the
+// commits of a GitHub repository, and sets their commit statuses.
+type gitHubProvider struct {
+	address    string
+	repository string
+	token      string
+	ignored    []string
+	client     *http.Client
+}
+
+type gitHubCombinedStatus struct {
+	Statuses []struct {
+		Context string `json:"context"`
+		State   string `json:"state"`
+	} `json:"statuses"`
+}
+
+type gitHubCheckRuns struct {
+	CheckRuns []struct {
+		Name       string `json:"name"`
+		Status     string `json:"status"`
+		Conclusion string `json:"conclusion"`
+	} `json:"check_runs"`
+}
+
+// Checks implements Checker.
+func (c *gitHubProvider) Checks(ctx context.Context, commit string) (Result, error) {
+	var status gitHubCombinedStatus
+	if err := c.get(ctx, fmt.Sprintf("%s/repos/%s/commits/%s/status?per_page=100", c.address, c.repository, commit), &status); err != nil {
+		return Result{}, err
+	}
+	var runs gitHubCheckRuns
+	if err := c.get(ctx, fmt.Sprintf("%s/repos/%s/commits/%s/check-runs?per_page=100", c.address, c.repository, commit), &runs); err != nil {
+		return Result{}, err
+	}
+
+	var checks []check
+	for _, s := range status.Statuses {
+		state := StatePending
+		switch s.State {
+		case "success":
+			state = StateSuccess
+		case "failure", "error":
+			state = StateFailure
+		}
+		checks = append(checks, check{name: s.Context, state: state})
+	}
+	for _, r := range runs.CheckRuns {
+		state := StatePending
+		if r.Status == "completed" {
+			switch r.Conclusion {
+			case "success", "neutral", "skipped":
+				state = StateSuccess
+			default:
+				state = StateFailure
+			}
+		}
+		checks = append(checks, check{name: r.Name, state: state})
+	}
+	return combine(checks, c.ignored), nil
+}
+
+// SetStatus implements StatusSetter.
+func (c *gitHubProvider) SetStatus(ctx context.Context, commit string, status Status) error {
+	body := map[string]string{
+		"state":       "success",
+		"context":     status.Context,
+		"description": status.Description,
+	}
+	if err := httpjson.Post(ctx, c.client, fmt.Sprintf("%s/repos/%s/statuses/%s", c.address, c.repository, commit),
+		"Authorization", "Bearer "+c.token, body); err != nil {
+		return fmt.Errorf("failed to set commit status: %w", err)
+	}
+	return nil
+}
+
+func (c *gitHubProvider) get(ctx context.Context, url string, v any) error {
+	var auth string
+	if c.token != "" {
+		auth = "Bearer " + c.token
+	}
+	if err := httpjson.Get(ctx, c.client, url, "Authorization", auth, v); err != nil {
+		return fmt.Errorf("failed to query checks: %w", err)
+	}
+	return nil
+}
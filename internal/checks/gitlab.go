@@ -0,0 +1,80 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/fluxcd/image-automation-controller/internal/httpjson"
+)
+
+const defaultGitLabAddress = "https://gitlab.com/api/v4"
+
+// gitLabProvider reports the statuses of the commits of a GitLab project,
+// including the jobs of their pipelines, and sets their commit statuses.
+type gitLabProvider struct {
+	address string
+	project string
+	token   string
+	ignored []string
+	client  *http.Client
+}
+
+type gitLabStatus struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// Checks implements Checker.
+func (c *gitLabProvider) Checks(ctx context.Context, commit string) (Result, error) {
+	u := fmt.Sprintf("%s/projects/%s/repository/commits/%s/statuses?per_page=100",
+		c.address, url.PathEscape(c.project), commit)
+	var statuses []gitLabStatus
+	if err := httpjson.Get(ctx, c.client, u, "PRIVATE-TOKEN", c.token, &statuses); err != nil {
+		return Result{}, fmt.Errorf("failed to query checks: %w", err)
+	}
+
+	var checks []check
+	for _, s := range statuses {
+		state := StatePending
+		switch s.Status {
+		case "success", "skipped":
+			state = StateSuccess
+		case "failed", "canceled":
+			state = StateFailure
+		}
+		checks = append(checks, check{name: s.Name, state: state})
+	}
+	return combine(checks, c.ignored), nil
+}
+
+// SetStatus implements StatusSetter.
+func (c *gitLabProvider) SetStatus(ctx context.Context, commit string, status Status) error {
+	body := map[string]string{
+		"state":       "success",
+		"name":        status.Context,
+		"description": status.Description,
+	}
+	if err := httpjson.Post(ctx, c.client, fmt.Sprintf("%s/projects/%s/statuses/%s", c.address, url.PathEscape(c.project), commit),
+		"PRIVATE-TOKEN", c.token, body); err != nil {
+		return fmt.Errorf("failed to set commit status: %w", err)
+	}
+	return nil
+}
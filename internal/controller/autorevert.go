@@ -0,0 +1,81 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	imagev1_reflect "github.com/fluxcd/image-reflector-controller/api/v1beta2"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+	"github.com/fluxcd/image-automation-controller/internal/policy"
+)
+
+// revertedPolicies returns the given policies whose image is revoked, along
+// with the revoked images, when the ImageUpdateAutomation reverts them. The
+// given policies are returned as is otherwise, or when no image is revoked.
+func revertedPolicies(obj *imagev1.ImageUpdateAutomation,
+	policies []imagev1_reflect.ImagePolicy) ([]imagev1_reflect.ImagePolicy, []policy.RevokedImage) {
+	if obj.Spec.Update == nil || !obj.Spec.Update.AutoRevert {
+		return policies, nil
+	}
+	revoked := policy.RevokedImages(obj, policies)
+	if len(revoked) == 0 {
+		return policies, nil
+	}
+	return policy.RevokedPolicies(policies, revoked), revoked
+}
+
+// revertMessage returns the message of the commit reverting the given revoked
+// images.
+func revertMessage(revoked []policy.RevokedImage) string {
+	var b strings.Builder
+	b.WriteString("Revert revoked images\n\n")
+	b.WriteString("The images aren't candidates of their ImagePolicy anymore:\n")
+	for _, r := range revoked {
+		fmt.Fprintf(&b, "- %s: %s -> %s\n", r.Policy, r.Image, r.Restored)
+	}
+	return b.String()
+}
+
+// revertedObservations returns the previous observations of the policies
+// updated with the observations of the reverted policies, for the other
+// policies to be applied by the next reconciliation.
+func revertedObservations(previous, reverted imagev1.ObservedPolicies) imagev1.ObservedPolicies {
+	observed := maps.Clone(previous)
+	if observed == nil {
+		observed = imagev1.ObservedPolicies{}
+	}
+	maps.Copy(observed, reverted)
+	return observed
+}
+
+// reportReverts emits an event for each of the given revoked images, reverted
+// by the given commit.
+func (r *ImageUpdateAutomationReconciler) reportReverts(ctx context.Context, obj *imagev1.ImageUpdateAutomation,
+	revoked []policy.RevokedImage, commit string) {
+	for _, rev := range revoked {
+		eventLogf(ctx, r.EventRecorder, obj, corev1.EventTypeWarning, imagev1.ImageRevokedReason,
+			"reverted the image of policy '%s' from '%s' to '%s' in commit '%s': '%s' isn't a candidate of the policy anymore",
+			rev.Policy, rev.Image, rev.Restored, commit, rev.Image)
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/tools/record"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	imagev1_reflect "github.com/fluxcd/image-reflector-controller/api/v1beta2"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+	"github.com/fluxcd/image-automation-controller/internal/policy"
+)
+
+func Test_revertedPolicies(t *testing.T) {
+	g := NewWithT(t)
+
+	semver := func(p *imagev1_reflect.ImagePolicy) *imagev1_reflect.ImagePolicy {
+		p.Spec.Policy.SemVer = &imagev1_reflect.SemVerPolicy{Range: ">=1.0.0"}
+		return p
+	}
+	// The tag 1.2.0 of app was deleted from the registry.
+	app := semver(testPolicy("app", "apps", "ghcr.io/org/app:1.1.0", nil))
+	sidecar := semver(testPolicy("sidecar", "apps", "ghcr.io/org/sidecar:1.1.0", nil))
+
+	// The policies are read from the cache of the manager, with their spec.
+	c := fakeclient.NewClientBuilder().
+		WithScheme(policyScheme(g)).
+		WithObjects(cachedPolicies(g, app, sidecar)...).
+		Build()
+	policies, _, err := NewPolicyCache().getPolicies(context.TODO(), c, "apps", nil)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	obj := &imagev1.ImageUpdateAutomation{}
+	obj.Spec.Update = &imagev1.UpdateStrategy{AutoRevert: true}
+	obj.Status.ObservedPolicies = imagev1.ObservedPolicies{
+		"app":     {Name: "ghcr.io/org/app", Tag: "1.2.0"},
+		"sidecar": {Name: "ghcr.io/org/sidecar", Tag: "1.0.0"},
+	}
+	reverted, revoked := revertedPolicies(obj, policies)
+	g.Expect(revoked).To(Equal([]policy.RevokedImage{
+		{Policy: "app", Image: "ghcr.io/org/app:1.2.0", Restored: "ghcr.io/org/app:1.1.0"},
+	}))
+	g.Expect(reverted).To(HaveLen(1))
+	g.Expect(reverted[0].Name).To(Equal("app"))
+
+	// Nothing is reverted unless enabled.
+	obj.Spec.Update.AutoRevert = false
+	reverted, revoked = revertedPolicies(obj, policies)
+	g.Expect(revoked).To(BeEmpty())
+	g.Expect(reverted).To(Equal(policies))
+}
+
+func Test_revertMessage(t *testing.T) {
+	g := NewWithT(t)
+
+	revoked := []policy.RevokedImage{
+		{Policy: "app", Image: "ghcr.io/org/app:1.2.0", Restored: "ghcr.io/org/app:1.1.0"},
+		{Policy: "sidecar", Image: "ghcr.io/org/sidecar:2.0.0", Restored: "ghcr.io/org/sidecar:1.9.0"},
+	}
+	g.Expect(revertMessage(revoked)).To(Equal(`Revert revoked images
+
+The images aren't candidates of their ImagePolicy anymore:
+- app: ghcr.io/org/app:1.2.0 -> ghcr.io/org/app:1.1.0
+- sidecar: ghcr.io/org/sidecar:2.0.0 -> ghcr.io/org/sidecar:1.9.0
+`))
+}
+
+func Test_revertedObservations(t *testing.T) {
+	g := NewWithT(t)
+
+	previous := imagev1.ObservedPolicies{
+		"app":     {Name: "ghcr.io/org/app", Tag: "1.2.0"},
+		"sidecar": {Name: "ghcr.io/org/sidecar", Tag: "1.0.0"},
+	}
+	reverted := imagev1.ObservedPolicies{
+		"app": {Name: "ghcr.io/org/app", Tag: "1.1.0"},
+	}
+	g.Expect(revertedObservations(previous, reverted)).To(Equal(imagev1.ObservedPolicies{
+		"app":     {Name: "ghcr.io/org/app", Tag: "1.1.0"},
+		"sidecar": {Name: "ghcr.io/org/sidecar", Tag: "1.0.0"},
+	}))
+	// The previous observations are left as they were.
+	g.Expect(previous["app"].Tag).To(Equal("1.2.0"))
+
+	g.Expect(revertedObservations(nil, reverted)).To(Equal(reverted))
+}
+
+func TestReportReverts(t *testing.T) {
+	g := NewWithT(t)
+
+	recorder := record.NewFakeRecorder(32)
+	r := &ImageUpdateAutomationReconciler{EventRecorder: recorder}
+	obj := &imagev1.ImageUpdateAutomation{}
+
+	r.reportReverts(context.TODO(), obj, []policy.RevokedImage{
+		{Policy: "app", Image: "ghcr.io/org/app:1.2.0", Restored: "ghcr.io/org/app:1.1.0"},
+	}, "abc")
+	g.Expect(recorder.Events).To(Receive(Equal("Warning ImageRevoked reverted the image of policy 'app' from " +
+		"'ghcr.io/org/app:1.2.0' to 'ghcr.io/org/app:1.1.0' in commit 'abc': 'ghcr.io/org/app:1.2.0' isn't a candidate of the policy anymore")))
+	g.Expect(recorder.Events).To(BeEmpty())
+}
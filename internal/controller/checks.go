@@ -0,0 +1,112 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/fluxcd/pkg/runtime/conditions"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+	"github.com/fluxcd/image-automation-controller/internal/checks"
+)
+
+// The status checks of the last pushed commit are queried from the Git
+// provider when configured, and reported with the ChecksPassed condition, so
+// that downstream automation can rely on the ImageUpdateAutomation instead of
+// the provider. The condition is Unknown while the checks are pending, and the
+// object is then requeued at the interval of the checks until they complete or
+// time out. Once the checks completed, they're only queried again after a new
+// push or a change of the object.
+
+// reconcileChecks reports the status checks of the last pushed commit of the
+// object in its ChecksPassed condition, and returns the result the
+// reconciliation should end with. The pushed tells if a commit was pushed by
+// the reconciliation.
+func (r *ImageUpdateAutomationReconciler) reconcileChecks(ctx context.Context, obj *imagev1.ImageUpdateAutomation,
+	result ctrl.Result, pushed bool) ctrl.Result {
+	if obj.Spec.GitSpec == nil || obj.Spec.GitSpec.Push == nil || obj.Spec.GitSpec.Push.Checks == nil ||
+		obj.Status.LastPushCommit == "" {
+		conditions.Delete(obj, imagev1.ChecksPassedCondition)
+		return result
+	}
+	spec := *obj.Spec.GitSpec.Push.Checks
+
+	// The checks of a commit don't change once completed.
+	if c := conditions.Get(obj, imagev1.ChecksPassedCondition); !pushed && c != nil &&
+		c.Status != metav1.ConditionUnknown && c.ObservedGeneration == obj.Generation {
+		return result
+	}
+
+	commit := obj.Status.LastPushCommit
+	// The commit status set by the automation isn't one of the checks.
+	var ignored []string
+	if status := obj.Spec.GitSpec.Push.CommitStatus; status != nil {
+		ignored = append(ignored, status.GetContext())
+	}
+	checker, err := checks.NewChecker(ctx, r.Client, obj.Namespace, spec, ignored...)
+	if err != nil {
+		conditions.MarkUnknown(obj, imagev1.ChecksPassedCondition, imagev1.ChecksUnavailableReason,
+			"failed to query the checks of commit '%s': %s", commit, err)
+		return requeueChecks(result, spec)
+	}
+	checksResult, err := checker.Checks(ctx, commit)
+	if err != nil {
+		conditions.MarkUnknown(obj, imagev1.ChecksPassedCondition, imagev1.ChecksUnavailableReason,
+			"failed to query the checks of commit '%s': %s", commit, err)
+		return requeueChecks(result, spec)
+	}
+
+	switch checksResult.State {
+	case checks.StateSuccess:
+		conditions.MarkTrue(obj, imagev1.ChecksPassedCondition, imagev1.ChecksSucceededReason,
+			"checks of commit '%s' passed: %s", commit, checksResult.Description)
+		eventLogf(ctx, r.EventRecorder, obj, corev1.EventTypeNormal, imagev1.ChecksSucceededReason,
+			"checks of commit '%s' passed: %s", commit, checksResult.Description)
+	case checks.StateFailure:
+		conditions.MarkFalse(obj, imagev1.ChecksPassedCondition, imagev1.ChecksFailedReason,
+			"checks of commit '%s' failed: %s", commit, checksResult.Description)
+		eventLogf(ctx, r.EventRecorder, obj, corev1.EventTypeWarning, imagev1.ChecksFailedReason,
+			"checks of commit '%s' failed: %s", commit, checksResult.Description)
+	default:
+		if obj.Status.LastPushTime != nil && time.Since(obj.Status.LastPushTime.Time) > spec.GetTimeout() {
+			conditions.MarkFalse(obj, imagev1.ChecksPassedCondition, imagev1.ChecksTimeoutReason,
+				"checks of commit '%s' still pending after %s: %s", commit, spec.GetTimeout(), checksResult.Description)
+			eventLogf(ctx, r.EventRecorder, obj, corev1.EventTypeWarning, imagev1.ChecksTimeoutReason,
+				"checks of commit '%s' still pending after %s: %s", commit, spec.GetTimeout(), checksResult.Description)
+			return result
+		}
+		conditions.MarkUnknown(obj, imagev1.ChecksPassedCondition, imagev1.ChecksPendingReason,
+			"checks of commit '%s' pending: %s", commit, checksResult.Description)
+		return requeueChecks(result, spec)
+	}
+	return result
+}
+
+// requeueChecks returns the given result requeued no later than the interval
+// of the checks.
+func requeueChecks(result ctrl.Result, spec imagev1.ChecksSpec) ctrl.Result {
+	if result.RequeueAfter <= 0 || result.RequeueAfter > spec.GetInterval() {
+		result.RequeueAfter = spec.GetInterval()
+	}
+	return result
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/fluxcd/pkg/runtime/conditions"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+func TestReconcileChecks(t *testing.T) {
+	g := NewWithT(t)
+
+	state := "pending"
+	queries := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/org/app/commits/abc123/status", func(w http.ResponseWriter, r *http.Request) {
+		queries++
+		fmt.Fprintf(w, `{"statuses": [{"context": "ci/build", "state": "%s"}]}`, state)
+	})
+	mux.HandleFunc("/repos/org/app/commits/abc123/check-runs", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"check_runs": []}`)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	r := &ImageUpdateAutomationReconciler{
+		Client:        fakeclient.NewClientBuilder().WithScheme(scheme.Scheme).Build(),
+		EventRecorder: record.NewFakeRecorder(32),
+	}
+	obj := &imagev1.ImageUpdateAutomation{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-update", Namespace: "default", Generation: 1},
+		Spec: imagev1.ImageUpdateAutomationSpec{
+			GitSpec: &imagev1.GitSpec{
+				Push: &imagev1.PushSpec{
+					Checks: &imagev1.ChecksSpec{
+						Provider:   imagev1.ChecksProviderGitHub,
+						Address:    srv.URL,
+						Repository: "org/app",
+						Interval:   &metav1.Duration{Duration: time.Minute},
+						Timeout:    &metav1.Duration{Duration: time.Hour},
+					},
+				},
+			},
+		},
+		Status: imagev1.ImageUpdateAutomationStatus{
+			LastPushCommit: "abc123",
+			LastPushTime:   &metav1.Time{Time: time.Now()},
+		},
+	}
+
+	// Pending checks are requeued at the interval of the checks.
+	result := r.reconcileChecks(context.TODO(), obj, ctrl.Result{RequeueAfter: time.Hour}, true)
+	g.Expect(result.RequeueAfter).To(Equal(time.Minute))
+	g.Expect(conditions.IsUnknown(obj, imagev1.ChecksPassedCondition)).To(BeTrue())
+	g.Expect(conditions.GetReason(obj, imagev1.ChecksPassedCondition)).To(Equal(imagev1.ChecksPendingReason))
+
+	// Completed checks are reported, and not queried again.
+	state = "success"
+	result = r.reconcileChecks(context.TODO(), obj, ctrl.Result{RequeueAfter: time.Hour}, false)
+	g.Expect(result.RequeueAfter).To(Equal(time.Hour))
+	g.Expect(conditions.IsTrue(obj, imagev1.ChecksPassedCondition)).To(BeTrue())
+	g.Expect(conditions.GetMessage(obj, imagev1.ChecksPassedCondition)).To(ContainSubstring("abc123"))
+	r.reconcileChecks(context.TODO(), obj, ctrl.Result{}, false)
+	g.Expect(queries).To(Equal(2))
+
+	// A new push queries the checks again.
+	state = "failure"
+	r.reconcileChecks(context.TODO(), obj, ctrl.Result{}, true)
+	g.Expect(queries).To(Equal(3))
+	g.Expect(conditions.IsFalse(obj, imagev1.ChecksPassedCondition)).To(BeTrue())
+	g.Expect(conditions.GetReason(obj, imagev1.ChecksPassedCondition)).To(Equal(imagev1.ChecksFailedReason))
+
+	// Checks still pending after the timeout fail.
+	state = "pending"
+	obj.Status.LastPushTime = &metav1.Time{Time: time.Now().Add(-2 * time.Hour)}
+	result = r.reconcileChecks(context.TODO(), obj, ctrl.Result{}, true)
+	g.Expect(result.RequeueAfter).To(BeZero())
+	g.Expect(conditions.GetReason(obj, imagev1.ChecksPassedCondition)).To(Equal(imagev1.ChecksTimeoutReason))
+
+	// The condition is removed when the checks aren't configured anymore.
+	obj.Spec.GitSpec.Push.Checks = nil
+	r.reconcileChecks(context.TODO(), obj, ctrl.Result{}, false)
+	g.Expect(conditions.Get(obj, imagev1.ChecksPassedCondition)).To(BeNil())
+}
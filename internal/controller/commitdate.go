@@ -0,0 +1,63 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"maps"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+// policyChanges returns the time the image of each of the observed policies
+// was first observed: the time of the previous change of the policy if its
+// image is the same, and now otherwise. The previous changes of the other
+// policies are dropped, unless keepOthers is set, e.g. when only the
+// reverted policies are observed.
+func policyChanges(previous map[string]imagev1.PolicyChange, observed imagev1.ObservedPolicies,
+	now time.Time, keepOthers bool) map[string]imagev1.PolicyChange {
+	changes := make(map[string]imagev1.PolicyChange, len(observed))
+	if keepOthers {
+		maps.Copy(changes, previous)
+	}
+	// The dates of the commits have a precision of a second, like the times
+	// of the status.
+	observedTime := metav1.NewTime(now.Truncate(time.Second))
+	for name, ref := range observed {
+		image := ref.String()
+		if change, ok := previous[name]; ok && change.Image == image {
+			changes[name] = change
+			continue
+		}
+		changes[name] = imagev1.PolicyChange{Image: image, ObservedTime: observedTime}
+	}
+	return changes
+}
+
+// latestPolicyChange returns the latest of the times the images of the
+// observed policies were first observed, or zero if none was recorded.
+func latestPolicyChange(changes map[string]imagev1.PolicyChange, observed imagev1.ObservedPolicies) time.Time {
+	var latest time.Time
+	for name := range observed {
+		if change, ok := changes[name]; ok && change.ObservedTime.After(latest) {
+			latest = change.ObservedTime.Time
+		}
+	}
+	return latest
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+func Test_policyChanges(t *testing.T) {
+	g := NewWithT(t)
+
+	before := metav1.NewTime(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))
+	now := time.Date(2024, 5, 2, 10, 0, 0, 500, time.UTC)
+	previous := map[string]imagev1.PolicyChange{
+		"app":     {Image: "ghcr.io/org/app:1.1.0", ObservedTime: before},
+		"sidecar": {Image: "ghcr.io/org/sidecar:1.0.0", ObservedTime: before},
+		"removed": {Image: "ghcr.io/org/removed:1.0.0", ObservedTime: before},
+	}
+	observed := imagev1.ObservedPolicies{
+		"app":     {Name: "ghcr.io/org/app", Tag: "1.2.0"},
+		"sidecar": {Name: "ghcr.io/org/sidecar", Tag: "1.0.0"},
+		"new":     {Name: "ghcr.io/org/new", Tag: "0.1.0"},
+	}
+
+	// The changed and new images are recorded at now, truncated to the
+	// second, and the removed policies are dropped.
+	changes := policyChanges(previous, observed, now, false)
+	nowSecond := metav1.NewTime(now.Truncate(time.Second))
+	g.Expect(changes).To(Equal(map[string]imagev1.PolicyChange{
+		"app":     {Image: "ghcr.io/org/app:1.2.0", ObservedTime: nowSecond},
+		"sidecar": {Image: "ghcr.io/org/sidecar:1.0.0", ObservedTime: before},
+		"new":     {Image: "ghcr.io/org/new:0.1.0", ObservedTime: nowSecond},
+	}))
+	g.Expect(latestPolicyChange(changes, observed)).To(Equal(nowSecond.Time))
+
+	// The same images keep their time.
+	g.Expect(policyChanges(changes, observed, now.Add(time.Hour), false)).To(Equal(changes))
+
+	// The other policies are kept when only some are observed.
+	reverted := imagev1.ObservedPolicies{"sidecar": observed["sidecar"]}
+	g.Expect(policyChanges(previous, reverted, now, true)).To(Equal(previous))
+	g.Expect(latestPolicyChange(previous, reverted)).To(Equal(before.Time))
+
+	g.Expect(latestPolicyChange(nil, observed)).To(BeZero())
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+	"github.com/fluxcd/image-automation-controller/internal/checks"
+	"github.com/fluxcd/image-automation-controller/internal/source"
+	"github.com/fluxcd/image-automation-controller/pkg/update"
+)
+
+// maxStatusDescription is the maximum length of the description of a commit
+// status, as limited by GitHub.
+const maxStatusDescription = 140
+
+// setCommitStatus sets the commit status of the object, if configured, on the
+// commit of the given successful push, describing the images of the given
+// changes. The failures are reported with events, as the push can't be
+// undone.
+func (r *ImageUpdateAutomationReconciler) setCommitStatus(ctx context.Context, obj *imagev1.ImageUpdateAutomation,
+	pushResult *source.PushResult, policyResult update.ResultV2) {
+	if obj.Spec.GitSpec == nil || obj.Spec.GitSpec.Push == nil || obj.Spec.GitSpec.Push.CommitStatus == nil {
+		return
+	}
+	spec := *obj.Spec.GitSpec.Push.CommitStatus
+
+	commit := pushResult.Commit().Hash.String()
+	setter, err := checks.NewStatusSetter(ctx, r.Client, obj.Namespace, spec)
+	if err == nil {
+		err = setter.SetStatus(ctx, commit, checks.Status{
+			Context:     spec.GetContext(),
+			Description: commitStatusDescription(policyResult),
+		})
+	}
+	if err != nil {
+		eventLogf(ctx, r.EventRecorder, obj, corev1.EventTypeWarning, imagev1.CommitStatusFailedReason,
+			"failed to set the commit status of commit '%s': %s", commit, err)
+	}
+}
+
+// commitStatusDescription returns the description of the commit status of the
+// given changes, e.g. "updated app to v1.2.3, sidecar to 1.1", listing the new
+// tag or digest of each policy.
+func commitStatusDescription(result update.ResultV2) string {
+	var updates []string
+	for _, ref := range result.ImageResult.Images() {
+		u := fmt.Sprintf("%s to %s", ref.Policy().Name, ref.Identifier())
+		if !slices.Contains(updates, u) {
+			updates = append(updates, u)
+		}
+	}
+	slices.Sort(updates)
+	desc := "updated " + strings.Join(updates, ", ")
+	if len(updates) == 0 {
+		desc = "updated images"
+	}
+	if len(desc) > maxStatusDescription {
+		desc = desc[:maxStatusDescription-3] + "..."
+	}
+	return desc
+}
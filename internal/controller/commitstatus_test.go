@@ -0,0 +1,75 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+
+	templatesv1 "github.com/fluxcd/image-automation-controller/pkg/templates/v1"
+	"github.com/fluxcd/image-automation-controller/pkg/update"
+)
+
+func Test_commitStatusDescription(t *testing.T) {
+	imageRef := func(image, policy string) update.ImageRef {
+		ref, err := name.ParseReference(image)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return templatesv1.NewImageRef(ref, types.NamespacedName{Namespace: "default", Name: policy})
+	}
+	result := func(refs ...update.ImageRef) update.ResultV2 {
+		oid := update.ObjectIdentifier{}
+		return update.ResultV2{ImageResult: update.Result{Files: map[string]update.FileResult{
+			"app.yaml": {Objects: map[update.ObjectIdentifier][]update.ImageRef{oid: refs}},
+		}}}
+	}
+
+	tests := []struct {
+		name   string
+		result update.ResultV2
+		want   string
+	}{
+		{
+			name:   "policies",
+			result: result(imageRef("ghcr.io/org/sidecar:1.1", "sidecar"), imageRef("ghcr.io/org/app:v1.2.3", "app")),
+			want:   "updated app to v1.2.3, sidecar to 1.1",
+		},
+		{
+			name:   "no images",
+			result: update.ResultV2{},
+			want:   "updated images",
+		},
+		{
+			name:   "truncated",
+			result: result(imageRef("ghcr.io/org/app:"+strings.Repeat("a", 128), "app")),
+			want:   "updated app to " + strings.Repeat("a", 122) + "...",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			got := commitStatusDescription(tt.result)
+			g.Expect(got).To(Equal(tt.want))
+			g.Expect(len(got)).To(BeNumerically("<=", maxStatusDescription))
+		})
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	aclapi "github.com/fluxcd/pkg/apis/acl"
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	runtimereconcile "github.com/fluxcd/pkg/runtime/reconcile"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+// The conditions of an ImageUpdateAutomation transition as follows:
+//
+//   - A reconciliation starts by marking Reconciling=True, which removes any
+//     Stalled condition of the previous reconciliation.
+//   - A failure which can't be recovered from by retrying marks Stalled=True
+//     with one of the stalledReasons, and the reconciliation returns without
+//     error, so the object isn't requeued. It is reconciled again when the
+//     object or one of the objects it refers to changes.
+//   - Any other failure marks Ready=False with one of the retryable reasons
+//     and the reconciliation returns the error, to be retried with backoff.
+//   - Once a step succeeds, a Ready=False condition with one of the reasons
+//     the step can fail with is stale and is reset to Unknown by
+//     resetStaleReadyCondition.
+//   - At the end of the reconciliation, finalizeResult removes Reconciling if
+//     the result is a success or the object is stalled, and otherwise sets its
+//     reason to ProgressingWithRetry. A Stalled condition is mirrored into
+//     Ready=False with the same reason and message. Ready=True is only set
+//     when no failure was recorded.
+
+// stalledReasons are the reasons of the Stalled condition. They represent
+// failures which need a change of the ImageUpdateAutomation, or of the objects
+// it refers to, to be recovered from.
+var stalledReasons = []string{
+	aclapi.AccessDeniedReason,
+	imagev1.InvalidSourceConfigReason,
+	imagev1.InvalidPolicySelectorReason,
+	imagev1.InvalidUpdateStrategyReason,
+	imagev1.InvalidTemplateReason,
+	imagev1.SemverJumpExceededReason,
+	imagev1.OwnershipMismatchReason,
+}
+
+// resetStaleReadyCondition sets the Ready condition of the object to Unknown
+// if it has one of the given reasons, left by a failure of a step which has
+// now succeeded.
+func resetStaleReadyCondition(obj conditions.Setter, reasons ...string) {
+	if conditions.HasAnyReason(obj, meta.ReadyCondition, reasons...) {
+		conditions.MarkUnknown(obj, meta.ReadyCondition, meta.ProgressingReason, "reconciliation in progress")
+	}
+}
+
+// finalizeResult computes the status conditions and the error of the
+// reconciliation of the object from its result, as described above.
+func finalizeResult(obj *imagev1.ImageUpdateAutomation, result ctrl.Result, recErr error) error {
+	// Define the meaning of success based on the requeue interval.
+	isSuccess := func(res ctrl.Result, err error) bool {
+		if err != nil || res.RequeueAfter != obj.GetRequeueAfter() || res.Requeue {
+			return false
+		}
+		return true
+	}
+
+	rs := runtimereconcile.NewResultFinalizer(isSuccess, readyMessage)
+	recErr = rs.Finalize(obj, result, recErr)
+
+	// Presence of reconciling means that the reconciliation didn't succeed.
+	// Set the Reconciling reason to ProgressingWithRetry to indicate a
+	// failure retry.
+	if conditions.IsReconciling(obj) {
+		reconciling := conditions.Get(obj, meta.ReconcilingCondition)
+		reconciling.Reason = meta.ProgressingWithRetryReason
+		conditions.Set(obj, reconciling)
+	}
+	return recErr
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	conditionscheck "github.com/fluxcd/pkg/runtime/conditions/check"
+	runtimereconcile "github.com/fluxcd/pkg/runtime/reconcile"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+func Test_finalizeResult(t *testing.T) {
+	interval := 5 * time.Minute
+
+	type testCase struct {
+		name           string
+		beforeFunc     func(obj *imagev1.ImageUpdateAutomation)
+		result         ctrl.Result
+		recErr         error
+		wantErr        bool
+		wantConditions []metav1.Condition
+	}
+	tests := []testCase{
+		{
+			name: "success after stalled",
+			beforeFunc: func(obj *imagev1.ImageUpdateAutomation) {
+				conditions.MarkStalled(obj, imagev1.InvalidTemplateReason, "invalid template")
+				conditions.MarkFalse(obj, meta.ReadyCondition, imagev1.InvalidTemplateReason, "invalid template")
+				runtimereconcile.ProgressiveStatus(false, obj, meta.ProgressingReason, "reconciliation in progress")
+				resetStaleReadyCondition(obj, imagev1.InvalidTemplateReason)
+			},
+			result: ctrl.Result{RequeueAfter: interval},
+			wantConditions: []metav1.Condition{
+				*conditions.TrueCondition(meta.ReadyCondition, meta.SucceededReason, readyMessage),
+			},
+		},
+		{
+			name: "retryable failure",
+			beforeFunc: func(obj *imagev1.ImageUpdateAutomation) {
+				runtimereconcile.ProgressiveStatus(false, obj, meta.ProgressingReason, "reconciliation in progress")
+				conditions.MarkFalse(obj, meta.ReadyCondition, imagev1.GitOperationFailedReason, "push failed")
+			},
+			recErr:  errors.New("push failed"),
+			wantErr: true,
+			wantConditions: []metav1.Condition{
+				*conditions.TrueCondition(meta.ReconcilingCondition, meta.ProgressingWithRetryReason, "reconciliation in progress"),
+				*conditions.FalseCondition(meta.ReadyCondition, imagev1.GitOperationFailedReason, "push failed"),
+			},
+		},
+		{
+			name: "stalled after a stale retryable failure",
+			beforeFunc: func(obj *imagev1.ImageUpdateAutomation) {
+				conditions.MarkFalse(obj, meta.ReadyCondition, imagev1.GitOperationFailedReason, "push failed")
+				runtimereconcile.ProgressiveStatus(false, obj, meta.ProgressingReason, "reconciliation in progress")
+				resetStaleReadyCondition(obj, imagev1.GitOperationFailedReason)
+				conditions.MarkStalled(obj, imagev1.InvalidTemplateReason, "invalid template")
+			},
+			wantConditions: []metav1.Condition{
+				*conditions.TrueCondition(meta.StalledCondition, imagev1.InvalidTemplateReason, "invalid template"),
+				*conditions.FalseCondition(meta.ReadyCondition, imagev1.InvalidTemplateReason, "invalid template"),
+			},
+		},
+	}
+	// All the stalled reasons result in Stalled=True and Ready=False.
+	for _, reason := range stalledReasons {
+		tests = append(tests, testCase{
+			name: "stalled with " + reason,
+			beforeFunc: func(obj *imagev1.ImageUpdateAutomation) {
+				runtimereconcile.ProgressiveStatus(false, obj, meta.ProgressingReason, "reconciliation in progress")
+				conditions.MarkStalled(obj, reason, "failure")
+			},
+			wantConditions: []metav1.Condition{
+				*conditions.TrueCondition(meta.StalledCondition, reason, "failure"),
+				*conditions.FalseCondition(meta.ReadyCondition, reason, "failure"),
+			},
+		})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			obj := &imagev1.ImageUpdateAutomation{}
+			obj.Name = "test-update"
+			obj.Namespace = "default"
+			obj.Generation = 2
+			obj.Spec.Interval = metav1.Duration{Duration: interval}
+			if tt.beforeFunc != nil {
+				tt.beforeFunc(obj)
+			}
+
+			err := finalizeResult(obj, tt.result, tt.recErr)
+			g.Expect(err != nil).To(Equal(tt.wantErr))
+			g.Expect(obj.Status.Conditions).To(conditions.MatchConditions(tt.wantConditions))
+
+			// Check if the object status is valid.
+			obj.Status.ObservedGeneration = obj.Generation
+			condns := &conditionscheck.Conditions{NegativePolarity: imageUpdateAutomationNegativeConditions}
+			checker := conditionscheck.NewChecker(nil, condns)
+			checker.DisableFetch = true
+			checker.WithT(g).CheckErr(context.TODO(), obj)
+		})
+	}
+}
+
+func Test_resetStaleReadyCondition(t *testing.T) {
+	g := NewWithT(t)
+
+	obj := &imagev1.ImageUpdateAutomation{}
+	conditions.MarkFalse(obj, meta.ReadyCondition, imagev1.UpdateFailedReason, "update failed")
+
+	// A reason the step doesn't fail with is kept.
+	resetStaleReadyCondition(obj, imagev1.GitOperationFailedReason)
+	g.Expect(conditions.IsFalse(obj, meta.ReadyCondition)).To(BeTrue())
+	g.Expect(conditions.GetReason(obj, meta.ReadyCondition)).To(Equal(imagev1.UpdateFailedReason))
+
+	resetStaleReadyCondition(obj, imagev1.InvalidUpdateStrategyReason, imagev1.UpdateFailedReason)
+	g.Expect(conditions.IsUnknown(obj, meta.ReadyCondition)).To(BeTrue())
+	g.Expect(conditions.GetReason(obj, meta.ReadyCondition)).To(Equal(meta.ProgressingReason))
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/fluxcd/pkg/runtime/conditions"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+	"github.com/fluxcd/image-automation-controller/pkg/update"
+)
+
+// The policies applied by an automation are the ones of its namespace, and
+// the markers referring to a policy in another namespace, e.g.
+// `{"$imagepolicy": "other-ns:app"}`, are never updated. They're reported
+// with the CrossNamespaceMarkers condition, for the users to understand why
+// these fields aren't updated.
+
+// maxReportedMarkers is the maximum number of markers listed in the message
+// of the CrossNamespaceMarkers condition.
+const maxReportedMarkers = 10
+
+// crossNamespaceMarkers returns the sorted markers of the given result
+// referring to a policy in another namespace than the given one, as
+// `<file>:<line> (<setter>)`.
+func crossNamespaceMarkers(result update.ResultV2, namespace string) []string {
+	var markers []string
+	for file, objChanges := range result.UnresolvedMarkers {
+		for _, changes := range objChanges {
+			for _, ch := range changes {
+				ns, _, _ := strings.Cut(ch.Setter, ":")
+				if ns == namespace {
+					continue
+				}
+				marker := fmt.Sprintf("%s:%d (%s)", file, ch.Line, ch.Setter)
+				if !slices.Contains(markers, marker) {
+					markers = append(markers, marker)
+				}
+			}
+		}
+	}
+	slices.Sort(markers)
+	return markers
+}
+
+// reportCrossNamespaceMarkers reports in the CrossNamespaceMarkers condition
+// of the object the given markers of policies in other namespaces, or removes
+// the condition when there are none. It returns whether the condition was set
+// with a new message.
+func reportCrossNamespaceMarkers(obj *imagev1.ImageUpdateAutomation, markers []string) bool {
+	if len(markers) == 0 {
+		conditions.Delete(obj, imagev1.CrossNamespaceMarkersCondition)
+		return false
+	}
+	listed := strings.Join(markers, ", ")
+	if len(markers) > maxReportedMarkers {
+		listed = fmt.Sprintf("%s and %d more", strings.Join(markers[:maxReportedMarkers], ", "), len(markers)-maxReportedMarkers)
+	}
+	previous := conditions.GetMessage(obj, imagev1.CrossNamespaceMarkersCondition)
+	conditions.MarkTrue(obj, imagev1.CrossNamespaceMarkersCondition, imagev1.PolicyNamespaceMismatchReason,
+		"markers refer to policies outside of the namespace '%s', and are never updated: %s",
+		obj.Namespace, listed)
+	return conditions.GetMessage(obj, imagev1.CrossNamespaceMarkersCondition) != previous
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+
+	"github.com/fluxcd/pkg/runtime/conditions"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+	"github.com/fluxcd/image-automation-controller/pkg/update"
+)
+
+func Test_crossNamespaceMarkers(t *testing.T) {
+	g := NewWithT(t)
+
+	deploy := update.ObjectIdentifier{ResourceIdentifier: yaml.ResourceIdentifier{
+		TypeMeta: yaml.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		NameMeta: yaml.NameMeta{Name: "app", Namespace: "default"},
+	}}
+	var result update.ResultV2
+	result.AddUnresolved("apps/app.yaml", deploy,
+		update.Change{OldValue: "app:1.0.0", Setter: "other:app", Line: 12},
+		// A policy of the namespace which wasn't applied, e.g. because it
+		// doesn't have a latest image yet.
+		update.Change{OldValue: "sidecar:1.0", Setter: "default:sidecar", Line: 14},
+	)
+	result.AddUnresolved("apps/values.yaml", deploy,
+		update.Change{OldValue: "1.0.0", Setter: "flux-system:app:tag", Line: 3},
+	)
+
+	g.Expect(crossNamespaceMarkers(result, "default")).To(Equal([]string{
+		"apps/app.yaml:12 (other:app)",
+		"apps/values.yaml:3 (flux-system:app:tag)",
+	}))
+	g.Expect(crossNamespaceMarkers(update.ResultV2{}, "default")).To(BeEmpty())
+}
+
+func Test_reportCrossNamespaceMarkers(t *testing.T) {
+	g := NewWithT(t)
+
+	obj := &imagev1.ImageUpdateAutomation{}
+	obj.Namespace = "default"
+
+	g.Expect(reportCrossNamespaceMarkers(obj, []string{"app.yaml:12 (other:app)"})).To(BeTrue())
+	g.Expect(conditions.IsTrue(obj, imagev1.CrossNamespaceMarkersCondition)).To(BeTrue())
+	g.Expect(conditions.GetReason(obj, imagev1.CrossNamespaceMarkersCondition)).To(Equal(imagev1.PolicyNamespaceMismatchReason))
+	g.Expect(conditions.GetMessage(obj, imagev1.CrossNamespaceMarkersCondition)).To(Equal(
+		"markers refer to policies outside of the namespace 'default', and are never updated: app.yaml:12 (other:app)"))
+
+	// The same markers are only reported once.
+	g.Expect(reportCrossNamespaceMarkers(obj, []string{"app.yaml:12 (other:app)"})).To(BeFalse())
+
+	var markers []string
+	for i := range maxReportedMarkers + 2 {
+		markers = append(markers, fmt.Sprintf("app.yaml:%d (other:app)", i+1))
+	}
+	g.Expect(reportCrossNamespaceMarkers(obj, markers)).To(BeTrue())
+	g.Expect(conditions.GetMessage(obj, imagev1.CrossNamespaceMarkersCondition)).To(HaveSuffix("app.yaml:10 (other:app) and 2 more"))
+
+	g.Expect(reportCrossNamespaceMarkers(obj, nil)).To(BeFalse())
+	g.Expect(conditions.Has(obj, imagev1.CrossNamespaceMarkersCondition)).To(BeFalse())
+}
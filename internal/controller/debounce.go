@@ -0,0 +1,74 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// policyDebouncer delays the changes of the latest image of the
+// ImagePolicies until the images of their namespace stabilize, for a
+// reflector updating them in a loop not to flood the automations with
+// reconciliations.
+type policyDebouncer struct {
+	window time.Duration
+	// events receives the last changed ImagePolicy of a namespace, once no
+	// ImagePolicy of the namespace changed for the window.
+	events chan event.GenericEvent
+
+	mu sync.Mutex
+	// pending is the timer of the pending change of each namespace.
+	pending map[string]*time.Timer
+}
+
+// newPolicyDebouncer returns a policyDebouncer with the given window.
+func newPolicyDebouncer(window time.Duration) *policyDebouncer {
+	return &policyDebouncer{
+		window:  window,
+		events:  make(chan event.GenericEvent, 1024),
+		pending: make(map[string]*time.Timer),
+	}
+}
+
+// debounce records a change of the given ImagePolicy, restarting the window
+// of its namespace.
+func (d *policyDebouncer) debounce(obj client.Object) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ns := obj.GetNamespace()
+	if timer, ok := d.pending[ns]; ok {
+		timer.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(d.window, func() {
+		d.mu.Lock()
+		// A change which was replaced after its timer fired is dropped.
+		if d.pending[ns] != timer {
+			d.mu.Unlock()
+			return
+		}
+		delete(d.pending, ns)
+		d.mu.Unlock()
+		d.events <- event.GenericEvent{Object: obj}
+	})
+	d.pending[ns] = timer
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/fluxcd/pkg/runtime/conditions"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+	"github.com/fluxcd/image-automation-controller/pkg/update"
+)
+
+// The images of the policies are committed once, when their latest images
+// change. A source whose files don't have the latest images of the policies
+// the previous reconciliations already handled has drifted since, e.g.
+// because the commit was reverted manually or the push branch was never
+// merged. The policies are compared to the checked out source at every full
+// synchronization, and the drifted ones are reported with the Drifted
+// condition.
+
+// driftedPolicies returns the sorted names of the policies whose latest images
+// are the ones observed by the previous successful reconciliation, but which
+// the given result of their application to the checked out source changes.
+func driftedPolicies(previous, current imagev1.ObservedPolicies, result update.ResultV2) []string {
+	var drifted []string
+	for _, objChanges := range result.FileChanges {
+		for _, changes := range objChanges {
+			for _, ch := range changes {
+				name := setterPolicy(ch.Setter)
+				image, ok := current[name]
+				if !ok || previous[name] != image || slices.Contains(drifted, name) {
+					continue
+				}
+				drifted = append(drifted, name)
+			}
+		}
+	}
+	slices.Sort(drifted)
+	return drifted
+}
+
+// reportDrift reports in the Drifted condition of the object the given
+// drifted policies of the checked out revision, or removes the condition when
+// none drifted.
+func reportDrift(obj *imagev1.ImageUpdateAutomation, drifted []string, revision string) {
+	if len(drifted) == 0 {
+		conditions.Delete(obj, imagev1.DriftedCondition)
+		return
+	}
+	conditions.MarkTrue(obj, imagev1.DriftedCondition, imagev1.PolicyDriftReason,
+		"revision '%s' doesn't have the latest images already handled of the policies: %s",
+		revision, strings.Join(drifted, ", "))
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+
+	"github.com/fluxcd/pkg/runtime/conditions"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+	"github.com/fluxcd/image-automation-controller/pkg/update"
+)
+
+func Test_driftedPolicies(t *testing.T) {
+	deploy := update.ObjectIdentifier{ResourceIdentifier: yaml.ResourceIdentifier{
+		TypeMeta: yaml.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		NameMeta: yaml.NameMeta{Name: "app", Namespace: "default"},
+	}}
+	var result update.ResultV2
+	result.AddChange("app.yaml", deploy,
+		update.Change{OldValue: "app:1.0.0", NewValue: "app:1.0.1", Setter: "default:app"},
+		update.Change{OldValue: "sidecar:1.0", NewValue: "sidecar:1.1", Setter: "default:sidecar"},
+	)
+	result.AddChange("values.yaml", deploy,
+		update.Change{OldValue: "1.0.0", NewValue: "1.0.1", Setter: "default:app:tag"},
+	)
+	current := imagev1.ObservedPolicies{
+		"app":     {Name: "app", Tag: "1.0.1"},
+		"sidecar": {Name: "sidecar", Tag: "1.1"},
+		"db":      {Name: "db", Tag: "15"},
+	}
+
+	tests := []struct {
+		name     string
+		previous imagev1.ObservedPolicies
+		result   update.ResultV2
+		want     []string
+	}{
+		{
+			name:   "first reconciliation",
+			result: result,
+		},
+		{
+			name: "new latest images",
+			previous: imagev1.ObservedPolicies{
+				"app":     {Name: "app", Tag: "1.0.0"},
+				"sidecar": {Name: "sidecar", Tag: "1.0"},
+			},
+			result: result,
+		},
+		{
+			name:     "no changes",
+			previous: current,
+		},
+		{
+			name: "reverted images",
+			previous: imagev1.ObservedPolicies{
+				"app":     {Name: "app", Tag: "1.0.1"},
+				"sidecar": {Name: "sidecar", Tag: "1.0"},
+				"db":      {Name: "db", Tag: "15"},
+			},
+			result: result,
+			want:   []string{"app"},
+		},
+		{
+			name:     "all reverted",
+			previous: current,
+			result:   result,
+			want:     []string{"app", "sidecar"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(driftedPolicies(tt.previous, current, tt.result)).To(Equal(tt.want))
+		})
+	}
+}
+
+func Test_reportDrift(t *testing.T) {
+	g := NewWithT(t)
+
+	obj := &imagev1.ImageUpdateAutomation{}
+	reportDrift(obj, []string{"app", "sidecar"}, "main@sha1:e3b0c442")
+	g.Expect(conditions.IsTrue(obj, imagev1.DriftedCondition)).To(BeTrue())
+	g.Expect(conditions.GetReason(obj, imagev1.DriftedCondition)).To(Equal(imagev1.PolicyDriftReason))
+	g.Expect(conditions.GetMessage(obj, imagev1.DriftedCondition)).To(Equal(
+		"revision 'main@sha1:e3b0c442' doesn't have the latest images already handled of the policies: app, sidecar"))
+
+	reportDrift(obj, nil, "main@sha1:5d41402a")
+	g.Expect(conditions.Has(obj, imagev1.DriftedCondition)).To(BeFalse())
+}
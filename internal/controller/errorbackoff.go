@@ -0,0 +1,80 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+	"github.com/fluxcd/image-automation-controller/internal/source"
+)
+
+// errorBackoff is the backoff of a class of errors: the delay of the first
+// retry, doubled on each consecutive failure up to the maximum delay.
+type errorBackoff struct {
+	base time.Duration
+	max  time.Duration
+}
+
+// errorBackoffs are the backoffs of the classes of errors of the Git
+// operations. A rejected push is retried almost immediately, as checking out
+// the remote branch again resolves it, while an authentication failure is
+// retried slowly, as it's unlikely to be resolved until the credentials are
+// changed. The other errors are retried with the rate limiter of the
+// controller.
+var errorBackoffs = map[source.ErrorClass]errorBackoff{
+	source.ErrorClassConflict: {base: time.Second, max: 30 * time.Second},
+	source.ErrorClassNetwork:  {base: 10 * time.Second, max: 5 * time.Minute},
+	source.ErrorClassAuth:     {base: 5 * time.Minute, max: time.Hour},
+}
+
+// delay returns the delay before the given attempt, counted from 1.
+func (b errorBackoff) delay(attempt int) time.Duration {
+	d := b.base
+	for i := 1; i < attempt && d < b.max; i++ {
+		d *= 2
+	}
+	return min(d, b.max)
+}
+
+// backoffClassifiedError requeues the reconciliations which failed with a
+// classified error of a Git operation after the delay of the backoff of its
+// class, instead of returning the error to the rate limiter of the
+// controller. The consecutive failures are counted by the failure streak,
+// without which the first delay of the backoff is always used.
+func backoffClassifiedError(ctx context.Context, obj *imagev1.ImageUpdateAutomation,
+	result ctrl.Result, recErr error) (ctrl.Result, error) {
+	if recErr == nil {
+		return result, nil
+	}
+	class := source.ClassOf(recErr)
+	backoff, ok := errorBackoffs[class]
+	if !ok {
+		return result, recErr
+	}
+	attempt := 1
+	if streak := obj.Status.FailureStreak; streak != nil {
+		attempt = streak.Count
+	}
+	retryAfter := backoff.delay(attempt)
+	ctrl.LoggerFrom(ctx).Error(recErr, "Git operation failed, retrying", "class", string(class),
+		"attempt", attempt, "retryAfter", retryAfter.String())
+	return ctrl.Result{RequeueAfter: retryAfter}, nil
+}
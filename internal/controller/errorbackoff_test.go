@@ -0,0 +1,100 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	. "github.com/onsi/gomega"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+	"github.com/fluxcd/image-automation-controller/internal/source"
+)
+
+func Test_errorBackoff_delay(t *testing.T) {
+	g := NewWithT(t)
+
+	b := errorBackoff{base: time.Second, max: 5 * time.Second}
+	g.Expect(b.delay(1)).To(Equal(time.Second))
+	g.Expect(b.delay(2)).To(Equal(2 * time.Second))
+	g.Expect(b.delay(3)).To(Equal(4 * time.Second))
+	g.Expect(b.delay(4)).To(Equal(5 * time.Second))
+	g.Expect(b.delay(100)).To(Equal(5 * time.Second))
+}
+
+func Test_backoffClassifiedError(t *testing.T) {
+	authErr := &source.GitOperationError{
+		Operation: source.GitOperationCheckout,
+		Class:     source.ErrorClassAuth,
+		Err:       transport.ErrAuthenticationRequired,
+	}
+	conflictErr := &source.GitOperationError{
+		Operation: source.GitOperationPush,
+		Class:     source.ErrorClassConflict,
+		Err:       errors.New("non-fast-forward update"),
+	}
+
+	tests := []struct {
+		name       string
+		err        error
+		streak     *imagev1.FailureStreak
+		wantResult ctrl.Result
+		wantErr    bool
+	}{
+		{
+			name:       "no error",
+			wantResult: ctrl.Result{RequeueAfter: time.Hour},
+		},
+		{
+			name:    "unclassified error",
+			err:     errors.New("boom"),
+			wantErr: true,
+		},
+		{
+			name:       "push conflict",
+			err:        fmt.Errorf("failed to update source: %w", conflictErr),
+			wantResult: ctrl.Result{RequeueAfter: time.Second},
+		},
+		{
+			name:       "repeated authentication failure",
+			err:        fmt.Errorf("failed to checkout source: %w", authErr),
+			streak:     &imagev1.FailureStreak{Count: 3},
+			wantResult: ctrl.Result{RequeueAfter: 20 * time.Minute},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			obj := &imagev1.ImageUpdateAutomation{}
+			obj.Status.FailureStreak = tt.streak
+			var result ctrl.Result
+			if tt.err == nil {
+				result = ctrl.Result{RequeueAfter: time.Hour}
+			}
+			result, err := backoffClassifiedError(context.TODO(), obj, result, tt.err)
+			g.Expect(err != nil).To(Equal(tt.wantErr))
+			g.Expect(result).To(Equal(tt.wantResult))
+		})
+	}
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+	"github.com/fluxcd/image-automation-controller/internal/source"
+)
+
+// gitFailureReason returns the reason of the Warning event of the failure of a
+// Git operation, which is the given reason unless the failure is caused by the
+// signing key or the authentication with the remote repository.
+func gitFailureReason(err error, reason string) string {
+	switch {
+	case source.IsSigningError(err):
+		return imagev1.SignFailedReason
+	case source.ClassOf(err) == source.ErrorClassAuth:
+		return imagev1.AuthFailedReason
+	default:
+		return reason
+	}
+}
+
+// warningReason returns the reason of the Warning event of a failure with the
+// given Ready condition reason, replaced by the more specific failure reason
+// if any. The specific reasons of the Ready condition, e.g. RepeatedFailure
+// once the failure repeats, are kept.
+func warningReason(readyReason, failureReason string) string {
+	switch readyReason {
+	case imagev1.InvalidTemplateReason:
+		return imagev1.TemplateFailedReason
+	case imagev1.GitOperationFailedReason, imagev1.SourceManagerFailedReason, imagev1.InvalidSourceConfigReason:
+		if failureReason != "" {
+			return failureReason
+		}
+	}
+	return readyReason
+}
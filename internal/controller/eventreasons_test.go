@@ -0,0 +1,108 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+	"github.com/fluxcd/image-automation-controller/internal/source"
+)
+
+func Test_gitFailureReason(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "unclassified",
+			err:  errors.New("couldn't find remote ref"),
+			want: imagev1.PushFailedReason,
+		},
+		{
+			name: "authentication",
+			err: &source.GitOperationError{Operation: source.GitOperationPush, Class: source.ErrorClassAuth,
+				Err: errors.New("authentication required")},
+			want: imagev1.AuthFailedReason,
+		},
+		{
+			name: "network",
+			err: &source.GitOperationError{Operation: source.GitOperationPush, Class: source.ErrorClassNetwork,
+				Err: errors.New("connection refused")},
+			want: imagev1.PushFailedReason,
+		},
+		{
+			name: "signing key",
+			err:  &source.SigningError{Err: errors.New("could not find signing key secret 'key'")},
+			want: imagev1.SignFailedReason,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(gitFailureReason(tt.err, imagev1.PushFailedReason)).To(Equal(tt.want))
+		})
+	}
+}
+
+func Test_warningReason(t *testing.T) {
+	tests := []struct {
+		name          string
+		readyReason   string
+		failureReason string
+		want          string
+	}{
+		{
+			name:        "git operation without specific reason",
+			readyReason: imagev1.GitOperationFailedReason,
+			want:        imagev1.GitOperationFailedReason,
+		},
+		{
+			name:          "git operation with specific reason",
+			readyReason:   imagev1.GitOperationFailedReason,
+			failureReason: imagev1.CloneFailedReason,
+			want:          imagev1.CloneFailedReason,
+		},
+		{
+			name:          "invalid signing configuration",
+			readyReason:   imagev1.InvalidSourceConfigReason,
+			failureReason: imagev1.SignFailedReason,
+			want:          imagev1.SignFailedReason,
+		},
+		{
+			name:        "invalid template",
+			readyReason: imagev1.InvalidTemplateReason,
+			want:        imagev1.TemplateFailedReason,
+		},
+		{
+			name:          "repeated failure",
+			readyReason:   imagev1.RepeatedFailureReason,
+			failureReason: imagev1.PushFailedReason,
+			want:          imagev1.RepeatedFailureReason,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(warningReason(tt.readyReason, tt.failureReason)).To(Equal(tt.want))
+		})
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+	"github.com/fluxcd/image-automation-controller/internal/source"
+)
+
+// The failures of an ImageUpdateAutomation which can be retried are retried
+// with backoff. When the same failure persists, e.g. because of a revoked
+// credential, the retries are pointless and load the Git server. After
+// RepeatedFailureThreshold consecutive failures with the same reason and
+// message, recorded in the status as a failure streak, the object is marked
+// Stalled with RepeatedFailureReason and is only retried every
+// RepeatedFailureInterval. The streak is reset when the object, its
+// GitRepository or the Secret of the GitRepository changes, as those changes
+// may fix the failure, and by any reconciliation which doesn't fail.
+
+// resetFailureStreak resets the failure streak of the object if the objects
+// it was observed with have changed since it started.
+func (r *ImageUpdateAutomationReconciler) resetFailureStreak(ctx context.Context, obj *imagev1.ImageUpdateAutomation) {
+	if obj.Status.FailureStreak == nil {
+		return
+	}
+	if r.RepeatedFailureThreshold <= 0 || obj.Status.FailureStreak.ObservedVersions != r.observedVersions(ctx, obj) {
+		obj.Status.FailureStreak = nil
+	}
+}
+
+// recordFailureStreak records the result of the reconciliation of the object
+// in its failure streak, and returns the result and error the reconciliation
+// should end with. When the streak reaches the threshold, the object is marked
+// Stalled and the failure is retried after RepeatedFailureInterval instead of
+// with backoff. It must be called once the conditions of the result are
+// finalized.
+func (r *ImageUpdateAutomationReconciler) recordFailureStreak(ctx context.Context, obj *imagev1.ImageUpdateAutomation,
+	result ctrl.Result, recErr error) (ctrl.Result, error) {
+	if r.RepeatedFailureThreshold <= 0 || recErr == nil {
+		obj.Status.FailureStreak = nil
+		return result, recErr
+	}
+
+	reason, message := meta.FailedReason, recErr.Error()
+	if ready := conditions.Get(obj, meta.ReadyCondition); ready != nil && ready.Status != metav1.ConditionTrue {
+		reason, message = ready.Reason, ready.Message
+	}
+
+	streak := obj.Status.FailureStreak
+	if streak == nil || streak.Reason != reason || streak.Message != message {
+		streak = &imagev1.FailureStreak{
+			Reason:           reason,
+			Message:          message,
+			ObservedVersions: r.observedVersions(ctx, obj),
+		}
+	}
+	streak.Count++
+	obj.Status.FailureStreak = streak
+	if streak.Count < r.RepeatedFailureThreshold {
+		return result, recErr
+	}
+
+	msg := fmt.Sprintf("reconciliation failed %d consecutive times with %s, retrying in %s: %s",
+		streak.Count, reason, r.RepeatedFailureInterval, message)
+	conditions.MarkStalled(obj, imagev1.RepeatedFailureReason, "%s", msg)
+	conditions.MarkFalse(obj, meta.ReadyCondition, imagev1.RepeatedFailureReason, "%s", msg)
+	conditions.Delete(obj, meta.ReconcilingCondition)
+	return ctrl.Result{RequeueAfter: r.RepeatedFailureInterval}, nil
+}
+
+// observedVersions returns the versions of the object, of its GitRepository
+// and of the Secret of the GitRepository, which identify a failure streak.
+// The objects which can't be read are omitted.
+func (r *ImageUpdateAutomationReconciler) observedVersions(ctx context.Context, obj *imagev1.ImageUpdateAutomation) string {
+	versions := fmt.Sprintf("imageupdateautomation/%d", obj.Generation)
+
+	srcKey, err := source.SourceKey(ctx, r.Client, obj)
+	if err != nil {
+		return versions
+	}
+	srcNamespace := srcKey.Namespace
+	var repo sourcev1.GitRepository
+	if err := r.Get(ctx, srcKey, &repo); err != nil {
+		return versions
+	}
+	versions += fmt.Sprintf(",gitrepository/%d", repo.Generation)
+
+	if repo.Spec.SecretRef == nil {
+		return versions
+	}
+	var secret corev1.Secret
+	if err := r.Get(ctx, types.NamespacedName{Namespace: srcNamespace, Name: repo.Spec.SecretRef.Name}, &secret); err != nil {
+		return versions
+	}
+	return versions + ",secret/" + secret.ResourceVersion
+}
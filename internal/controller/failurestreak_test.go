@@ -0,0 +1,119 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+func TestFailureStreak(t *testing.T) {
+	g := NewWithT(t)
+
+	s := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(s)).To(Succeed())
+	g.Expect(sourcev1.AddToScheme(s)).To(Succeed())
+
+	repo := &sourcev1.GitRepository{
+		ObjectMeta: metav1.ObjectMeta{Name: "repo", Namespace: "default", Generation: 1},
+		Spec: sourcev1.GitRepositorySpec{
+			SecretRef: &meta.LocalObjectReference{Name: "git-creds"},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "git-creds", Namespace: "default"},
+	}
+	c := fakeclient.NewClientBuilder().WithScheme(s).WithObjects(repo, secret).Build()
+
+	r := &ImageUpdateAutomationReconciler{
+		Client:                   c,
+		RepeatedFailureThreshold: 3,
+		RepeatedFailureInterval:  time.Hour,
+	}
+	obj := &imagev1.ImageUpdateAutomation{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-update", Namespace: "default", Generation: 1},
+		Spec: imagev1.ImageUpdateAutomationSpec{
+			SourceRef: imagev1.CrossNamespaceSourceReference{Kind: sourcev1.GitRepositoryKind, Name: "repo"},
+		},
+	}
+
+	fail := func(msg string) (ctrl.Result, error) {
+		r.resetFailureStreak(context.TODO(), obj)
+		conditions.MarkFalse(obj, meta.ReadyCondition, imagev1.GitOperationFailedReason, "%s", msg)
+		return r.recordFailureStreak(context.TODO(), obj, ctrl.Result{}, errors.New(msg))
+	}
+
+	// Failures below the threshold are returned to be retried with backoff.
+	for i := 1; i < 3; i++ {
+		_, err := fail("authentication required")
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(obj.Status.FailureStreak.Count).To(Equal(i))
+	}
+
+	// A different failure starts a new streak.
+	_, err := fail("connection refused")
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(obj.Status.FailureStreak.Count).To(Equal(1))
+	g.Expect(obj.Status.FailureStreak.Message).To(Equal("connection refused"))
+
+	// The failure reaching the threshold stalls the object.
+	_, err = fail("connection refused")
+	g.Expect(err).To(HaveOccurred())
+	result, err := fail("connection refused")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result).To(Equal(ctrl.Result{RequeueAfter: time.Hour}))
+	g.Expect(obj.Status.FailureStreak.Count).To(Equal(3))
+	g.Expect(obj.Status.FailureStreak.ObservedVersions).To(Equal(
+		"imageupdateautomation/1,gitrepository/1,secret/" + secret.ResourceVersion))
+	g.Expect(conditions.IsStalled(obj)).To(BeTrue())
+	g.Expect(conditions.GetReason(obj, meta.StalledCondition)).To(Equal(imagev1.RepeatedFailureReason))
+	g.Expect(conditions.GetReason(obj, meta.ReadyCondition)).To(Equal(imagev1.RepeatedFailureReason))
+
+	// The failure persisting keeps the object stalled.
+	result, err = fail("connection refused")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result).To(Equal(ctrl.Result{RequeueAfter: time.Hour}))
+	g.Expect(obj.Status.FailureStreak.Count).To(Equal(4))
+
+	// A change of the Secret resets the streak.
+	secret.StringData = map[string]string{"password": "rotated"}
+	g.Expect(c.Update(context.TODO(), secret)).To(Succeed())
+	_, err = fail("connection refused")
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(obj.Status.FailureStreak.Count).To(Equal(1))
+
+	// A successful reconciliation removes the streak.
+	result, err = r.recordFailureStreak(context.TODO(), obj, ctrl.Result{RequeueAfter: time.Minute}, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result).To(Equal(ctrl.Result{RequeueAfter: time.Minute}))
+	g.Expect(obj.Status.FailureStreak).To(BeNil())
+}
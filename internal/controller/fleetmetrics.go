@@ -0,0 +1,181 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crtlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+// States of the ImageUpdateAutomations in the fleet metrics. An automation is
+// in the first state which applies, in this order.
+const (
+	FleetStateSuspended = "suspended"
+	FleetStateStalled   = "stalled"
+	FleetStateReady     = "ready"
+	FleetStateNotReady  = "not_ready"
+)
+
+// fleetCollectTimeout is the timeout of listing the ImageUpdateAutomations
+// when the fleet metrics are collected.
+const fleetCollectTimeout = 10 * time.Second
+
+// FleetMetrics summarizes all the ImageUpdateAutomations the controller
+// watches in a few metrics, for the dashboards of the platform teams to show
+// the state of the fleet without aggregating the metrics of every namespace.
+// The metrics are computed from the automations in the cache of the manager
+// when they are collected.
+type FleetMetrics struct {
+	reader client.Reader
+
+	automationsDesc *prometheus.Desc
+	latestPushDesc  *prometheus.Desc
+	oldestPushDesc  *prometheus.Desc
+}
+
+var _ prometheus.Collector = &FleetMetrics{}
+
+// MustMakeFleetMetrics returns a new FleetMetrics reading the automations
+// with the given client.Reader, registered in the controller-runtime metrics
+// registry, which panics if it's already registered.
+func MustMakeFleetMetrics(reader client.Reader) *FleetMetrics {
+	m := NewFleetMetrics(reader)
+	crtlmetrics.Registry.MustRegister(m)
+	return m
+}
+
+// NewFleetMetrics returns a new FleetMetrics reading the automations with the
+// given client.Reader.
+func NewFleetMetrics(reader client.Reader) *FleetMetrics {
+	return &FleetMetrics{
+		reader: reader,
+		automationsDesc: prometheus.NewDesc(
+			"image_automation_fleet_automations",
+			"The number of ImageUpdateAutomations by state: suspended, stalled, ready or not_ready.",
+			[]string{"state"}, nil,
+		),
+		latestPushDesc: prometheus.NewDesc(
+			"image_automation_fleet_latest_push_timestamp_seconds",
+			"The time in seconds since the epoch of the latest push of all the ImageUpdateAutomations.",
+			nil, nil,
+		),
+		oldestPushDesc: prometheus.NewDesc(
+			"image_automation_fleet_oldest_push_timestamp_seconds",
+			"The time in seconds since the epoch of the last push of the ImageUpdateAutomation which hasn't pushed for the longest time, not suspended.",
+			[]string{"name", "namespace"}, nil,
+		),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (m *FleetMetrics) Describe(ch chan<- *prometheus.Desc) {
+	ch <- m.automationsDesc
+	ch <- m.latestPushDesc
+	ch <- m.oldestPushDesc
+}
+
+// Collect implements prometheus.Collector.
+func (m *FleetMetrics) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), fleetCollectTimeout)
+	defer cancel()
+	var list imagev1.ImageUpdateAutomationList
+	if err := m.reader.List(ctx, &list); err != nil {
+		ch <- prometheus.NewInvalidMetric(m.automationsDesc, err)
+		return
+	}
+
+	summary := summarizeFleet(list.Items, time.Now())
+	for _, state := range []string{FleetStateSuspended, FleetStateStalled, FleetStateReady, FleetStateNotReady} {
+		ch <- prometheus.MustNewConstMetric(m.automationsDesc, prometheus.GaugeValue, float64(summary.states[state]), state)
+	}
+	if summary.latestPush != nil {
+		ch <- prometheus.MustNewConstMetric(m.latestPushDesc, prometheus.GaugeValue, float64(summary.latestPush.Unix()))
+	}
+	if summary.oldestPush != nil {
+		ch <- prometheus.MustNewConstMetric(m.oldestPushDesc, prometheus.GaugeValue, float64(summary.oldestPush.Time.Unix()),
+			summary.oldestPush.Name, summary.oldestPush.Namespace)
+	}
+}
+
+// fleetSummary is the summary of the ImageUpdateAutomations of the fleet
+// metrics.
+type fleetSummary struct {
+	// states is the number of automations in each state.
+	states map[string]int
+	// latestPush is the time of the latest push of all the automations,
+	// nil if none pushed.
+	latestPush *time.Time
+	// oldestPush is the last push of the automation which hasn't pushed
+	// for the longest time, not suspended, nil if none pushed.
+	oldestPush *fleetPush
+}
+
+// fleetPush is the last push of an automation.
+type fleetPush struct {
+	Name, Namespace string
+	Time            time.Time
+}
+
+// summarizeFleet returns the fleetSummary of the given automations at the
+// given time.
+func summarizeFleet(automations []imagev1.ImageUpdateAutomation, now time.Time) fleetSummary {
+	summary := fleetSummary{states: map[string]int{}}
+	for i := range automations {
+		obj := &automations[i]
+		state := fleetState(obj, now)
+		summary.states[state]++
+
+		if obj.Status.LastPushTime == nil {
+			continue
+		}
+		pushed := obj.Status.LastPushTime.Time
+		if summary.latestPush == nil || pushed.After(*summary.latestPush) {
+			summary.latestPush = &pushed
+		}
+		if state == FleetStateSuspended {
+			continue
+		}
+		if summary.oldestPush == nil || pushed.Before(summary.oldestPush.Time) {
+			summary.oldestPush = &fleetPush{Name: obj.Name, Namespace: obj.Namespace, Time: pushed}
+		}
+	}
+	return summary
+}
+
+// fleetState returns the state of the given automation at the given time in
+// the fleet metrics.
+func fleetState(obj *imagev1.ImageUpdateAutomation, now time.Time) string {
+	if _, ok := suspendedUntil(obj, now); ok || obj.Spec.Suspend {
+		return FleetStateSuspended
+	}
+	switch {
+	case conditions.IsStalled(obj):
+		return FleetStateStalled
+	case conditions.IsTrue(obj, meta.ReadyCondition):
+		return FleetStateReady
+	default:
+		return FleetStateNotReady
+	}
+}
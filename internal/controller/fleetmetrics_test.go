@@ -0,0 +1,105 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+func TestFleetMetrics(t *testing.T) {
+	g := NewWithT(t)
+
+	now := time.Now()
+	lastPush := time.Date(2024, 6, 5, 9, 0, 0, 0, time.UTC)
+	automation := func(name, namespace string, pushed time.Time, mutate func(*imagev1.ImageUpdateAutomation)) *imagev1.ImageUpdateAutomation {
+		obj := &imagev1.ImageUpdateAutomation{}
+		obj.Name = name
+		obj.Namespace = namespace
+		if !pushed.IsZero() {
+			obj.Status.LastPushTime = &metav1.Time{Time: pushed}
+		}
+		if mutate != nil {
+			mutate(obj)
+		}
+		return obj
+	}
+	ready := func(obj *imagev1.ImageUpdateAutomation) {
+		conditions.MarkTrue(obj, meta.ReadyCondition, meta.SucceededReason, "ok")
+	}
+
+	s := runtime.NewScheme()
+	g.Expect(imagev1.AddToScheme(s)).To(Succeed())
+	c := fakeclient.NewClientBuilder().WithScheme(s).WithObjects(
+		automation("apps", "team-a", lastPush, ready),
+		automation("infra", "team-b", lastPush.Add(-48*time.Hour), func(obj *imagev1.ImageUpdateAutomation) {
+			conditions.MarkStalled(obj, imagev1.InvalidSourceConfigReason, "invalid")
+			conditions.MarkFalse(obj, meta.ReadyCondition, imagev1.InvalidSourceConfigReason, "invalid")
+		}),
+		automation("frozen", "team-b", lastPush.Add(-72*time.Hour), func(obj *imagev1.ImageUpdateAutomation) {
+			obj.Spec.SuspendUntil = &metav1.Time{Time: now.Add(time.Hour)}
+		}),
+		automation("new", "team-c", time.Time{}, func(obj *imagev1.ImageUpdateAutomation) {
+			obj.Spec.Suspend = true
+		}),
+		automation("failing", "team-c", lastPush.Add(-time.Hour), func(obj *imagev1.ImageUpdateAutomation) {
+			conditions.MarkFalse(obj, meta.ReadyCondition, imagev1.GitOperationFailedReason, "failed")
+		}),
+		automation("idle", "team-a", time.Time{}, ready),
+	).Build()
+
+	m := NewFleetMetrics(c)
+	want := fmt.Sprintf(`
+# HELP image_automation_fleet_automations The number of ImageUpdateAutomations by state: suspended, stalled, ready or not_ready.
+# TYPE image_automation_fleet_automations gauge
+image_automation_fleet_automations{state="not_ready"} 1
+image_automation_fleet_automations{state="ready"} 2
+image_automation_fleet_automations{state="stalled"} 1
+image_automation_fleet_automations{state="suspended"} 2
+# HELP image_automation_fleet_latest_push_timestamp_seconds The time in seconds since the epoch of the latest push of all the ImageUpdateAutomations.
+# TYPE image_automation_fleet_latest_push_timestamp_seconds gauge
+image_automation_fleet_latest_push_timestamp_seconds %d
+# HELP image_automation_fleet_oldest_push_timestamp_seconds The time in seconds since the epoch of the last push of the ImageUpdateAutomation which hasn't pushed for the longest time, not suspended.
+# TYPE image_automation_fleet_oldest_push_timestamp_seconds gauge
+image_automation_fleet_oldest_push_timestamp_seconds{name="infra",namespace="team-b"} %d
+`, lastPush.Unix(), lastPush.Add(-48*time.Hour).Unix())
+	g.Expect(testutil.CollectAndCompare(m, strings.NewReader(want))).To(Succeed())
+}
+
+func TestFleetMetrics_noAutomation(t *testing.T) {
+	g := NewWithT(t)
+
+	s := runtime.NewScheme()
+	g.Expect(imagev1.AddToScheme(s)).To(Succeed())
+	m := NewFleetMetrics(fakeclient.NewClientBuilder().WithScheme(s).Build())
+
+	// Only the counts are reported, without any push.
+	g.Expect(testutil.CollectAndCount(m)).To(Equal(4))
+	g.Expect(testutil.CollectAndCount(m, "image_automation_fleet_oldest_push_timestamp_seconds")).To(Equal(0))
+}
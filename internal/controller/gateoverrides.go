@@ -0,0 +1,42 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+// shallowClone returns whether the source of the object is cloned shallowly,
+// as set by the GitShallowClone feature gate unless the checkout strategy of
+// the object overrides it.
+func shallowClone(obj *imagev1.ImageUpdateAutomation, gate bool) bool {
+	if gitSpec := obj.Spec.GitSpec; gitSpec != nil && gitSpec.Checkout != nil && gitSpec.Checkout.Strategy != "" {
+		return gitSpec.Checkout.Strategy == imagev1.CheckoutStrategyShallow
+	}
+	return gate
+}
+
+// forcePush returns whether the commits of the object are force pushed to a
+// push branch other than the checkout branch, as set by the
+// GitForcePushBranch feature gate unless the push specification of the object
+// overrides it.
+func forcePush(obj *imagev1.ImageUpdateAutomation, gate bool) bool {
+	if gitSpec := obj.Spec.GitSpec; gitSpec != nil && gitSpec.Push != nil && gitSpec.Push.ForcePush != nil {
+		return *gitSpec.Push.ForcePush
+	}
+	return gate
+}
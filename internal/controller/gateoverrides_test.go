@@ -0,0 +1,66 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+func TestGateOverrides(t *testing.T) {
+	tests := []struct {
+		name        string
+		gitSpec     *imagev1.GitSpec
+		gate        bool
+		wantShallow bool
+		wantForce   bool
+	}{
+		{
+			name:        "gates enabled",
+			gitSpec:     imagev1.NewGitSpec("flux", "flux@example.com"),
+			gate:        true,
+			wantShallow: true,
+			wantForce:   true,
+		},
+		{
+			name:    "gates disabled",
+			gitSpec: imagev1.NewGitSpec("flux", "flux@example.com", imagev1.WithGitSpecCheckoutBranch("main")),
+		},
+		{
+			name:    "full clone and no force push",
+			gitSpec: imagev1.NewGitSpec("flux", "flux@example.com", imagev1.WithGitSpecCheckoutStrategy(imagev1.CheckoutStrategyFull), imagev1.WithGitSpecForcePush(false)),
+			gate:    true,
+		},
+		{
+			name:        "shallow clone and force push",
+			gitSpec:     imagev1.NewGitSpec("flux", "flux@example.com", imagev1.WithGitSpecCheckoutStrategy(imagev1.CheckoutStrategyShallow), imagev1.WithGitSpecForcePush(true)),
+			wantShallow: true,
+			wantForce:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			obj := &imagev1.ImageUpdateAutomation{Spec: imagev1.ImageUpdateAutomationSpec{GitSpec: tt.gitSpec}}
+			g.Expect(shallowClone(obj, tt.gate)).To(Equal(tt.wantShallow))
+			g.Expect(forcePush(obj, tt.gate)).To(Equal(tt.wantForce))
+		})
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	imagev1_reflect "github.com/fluxcd/image-reflector-controller/api/v1beta2"
+	"github.com/fluxcd/pkg/runtime/logger"
+
+	templatesv1 "github.com/fluxcd/image-automation-controller/pkg/templates/v1"
+)
+
+// getImageRepositories returns the ImageRepositories of the policies given to
+// the templates, by name of the policy. The policies whose ImageRepository
+// doesn't exist are left out.
+func getImageRepositories(ctx context.Context, c client.Client, policies []imagev1_reflect.ImagePolicy) (map[string]templatesv1.ImageRepositoryData, error) {
+	repos := make(map[string]templatesv1.ImageRepositoryData, len(policies))
+	fetched := map[types.NamespacedName]*templatesv1.ImageRepositoryData{}
+	for _, pol := range policies {
+		key := types.NamespacedName{
+			Namespace: pol.Spec.ImageRepositoryRef.Namespace,
+			Name:      pol.Spec.ImageRepositoryRef.Name,
+		}
+		if key.Namespace == "" {
+			key.Namespace = pol.Namespace
+		}
+		data, ok := fetched[key]
+		if !ok {
+			var err error
+			if data, err = getImageRepository(ctx, c, key); err != nil {
+				return nil, err
+			}
+			fetched[key] = data
+		}
+		if data != nil {
+			repos[pol.Name] = *data
+		}
+	}
+	return repos, nil
+}
+
+// getImageRepository returns the template data of the ImageRepository with
+// the given key, nil if it doesn't exist.
+func getImageRepository(ctx context.Context, c client.Client, key types.NamespacedName) (*templatesv1.ImageRepositoryData, error) {
+	var repo imagev1_reflect.ImageRepository
+	if err := c.Get(ctx, key, &repo); err != nil {
+		if apierrors.IsNotFound(err) {
+			log.FromContext(ctx).V(logger.DebugLevel).Info("ImageRepository of policy not found", "imagerepository", key)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get ImageRepository '%s': %w", key, err)
+	}
+	data := &templatesv1.ImageRepositoryData{
+		Name:      repo.Name,
+		Namespace: repo.Namespace,
+		Image:     repo.Spec.Image,
+	}
+	// The canonical name of the image is only known once scanned.
+	image := repo.Status.CanonicalImageName
+	if image == "" {
+		image = repo.Spec.Image
+	}
+	if ref, err := name.NewRepository(image); err == nil {
+		data.Registry = ref.RegistryStr()
+	}
+	return data, nil
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	imagev1_reflect "github.com/fluxcd/image-reflector-controller/api/v1beta2"
+
+	templatesv1 "github.com/fluxcd/image-automation-controller/pkg/templates/v1"
+)
+
+func Test_getImageRepositories(t *testing.T) {
+	g := NewWithT(t)
+
+	podinfo := &imagev1_reflect.ImageRepository{}
+	podinfo.Name = "podinfo"
+	podinfo.Namespace = "apps"
+	podinfo.Spec.Image = "ghcr.io/stefanprodan/podinfo"
+	nginx := &imagev1_reflect.ImageRepository{}
+	nginx.Name = "nginx"
+	nginx.Namespace = "images"
+	nginx.Spec.Image = "nginx"
+	nginx.Status.CanonicalImageName = "index.docker.io/library/nginx"
+
+	withRepository := func(p *imagev1_reflect.ImagePolicy, name, namespace string) imagev1_reflect.ImagePolicy {
+		p.Spec.ImageRepositoryRef.Name = name
+		p.Spec.ImageRepositoryRef.Namespace = namespace
+		return *p
+	}
+	policies := []imagev1_reflect.ImagePolicy{
+		withRepository(testPolicy("podinfo", "apps", "podinfo:1.0.0", nil), "podinfo", ""),
+		withRepository(testPolicy("podinfo-canary", "apps", "podinfo:1.1.0", nil), "podinfo", ""),
+		withRepository(testPolicy("nginx", "apps", "nginx:1.27.0", nil), "nginx", "images"),
+		withRepository(testPolicy("missing", "apps", "missing:1.0.0", nil), "missing", ""),
+	}
+
+	c := fakeclient.NewClientBuilder().
+		WithScheme(policyScheme(g)).
+		WithObjects(podinfo, nginx).
+		Build()
+
+	repos, err := getImageRepositories(context.TODO(), c, policies)
+	g.Expect(err).ToNot(HaveOccurred())
+	podinfoData := templatesv1.ImageRepositoryData{
+		Name:      "podinfo",
+		Namespace: "apps",
+		Image:     "ghcr.io/stefanprodan/podinfo",
+		Registry:  "ghcr.io",
+	}
+	g.Expect(repos).To(Equal(map[string]templatesv1.ImageRepositoryData{
+		"podinfo":        podinfoData,
+		"podinfo-canary": podinfoData,
+		"nginx": {
+			Name:      "nginx",
+			Namespace: "images",
+			Image:     "nginx",
+			Registry:  "index.docker.io",
+		},
+	}))
+}
+
+func Test_getImageRepositories_cachedPolicies(t *testing.T) {
+	g := NewWithT(t)
+
+	podinfo := &imagev1_reflect.ImageRepository{}
+	podinfo.Name = "podinfo"
+	podinfo.Namespace = "apps"
+	podinfo.Spec.Image = "ghcr.io/stefanprodan/podinfo"
+	policy := testPolicy("podinfo", "apps", "podinfo:1.0.0", nil)
+	policy.Spec.ImageRepositoryRef.Name = "podinfo"
+
+	// The policies are read from the cache of the manager, with their spec.
+	c := fakeclient.NewClientBuilder().
+		WithScheme(policyScheme(g)).
+		WithObjects(podinfo).
+		WithObjects(cachedPolicies(g, policy)...).
+		Build()
+	policies, _, err := NewPolicyCache().getPolicies(context.TODO(), c, "apps", nil)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	repos, err := getImageRepositories(context.TODO(), c, policies)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(repos).To(Equal(map[string]templatesv1.ImageRepositoryData{
+		"podinfo": {
+			Name:      "podinfo",
+			Namespace: "apps",
+			Image:     "ghcr.io/stefanprodan/podinfo",
+			Registry:  "ghcr.io",
+		},
+	}))
+}
@@ -20,7 +20,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
@@ -28,6 +30,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	kerrors "k8s.io/apimachinery/pkg/util/errors"
 	kuberecorder "k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
@@ -39,6 +42,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	ctrlsource "sigs.k8s.io/controller-runtime/pkg/source"
 
 	imagev1_reflect "github.com/fluxcd/image-reflector-controller/api/v1beta2"
 	aclapi "github.com/fluxcd/pkg/apis/acl"
@@ -48,19 +52,39 @@ import (
 	"github.com/fluxcd/pkg/runtime/acl"
 	"github.com/fluxcd/pkg/runtime/conditions"
 	helper "github.com/fluxcd/pkg/runtime/controller"
+	"github.com/fluxcd/pkg/runtime/logger"
 	"github.com/fluxcd/pkg/runtime/patch"
 	"github.com/fluxcd/pkg/runtime/predicates"
 	runtimereconcile "github.com/fluxcd/pkg/runtime/reconcile"
 	sourcev1 "github.com/fluxcd/source-controller/api/v1"
 
 	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+	"github.com/fluxcd/image-automation-controller/internal/export"
 	"github.com/fluxcd/image-automation-controller/internal/features"
+	"github.com/fluxcd/image-automation-controller/internal/hook"
 	"github.com/fluxcd/image-automation-controller/internal/policy"
 	"github.com/fluxcd/image-automation-controller/internal/source"
+	"github.com/fluxcd/image-automation-controller/pkg/update"
 )
 
 const repoRefKey = ".spec.gitRepository"
 
+// repoSelectorRefValue is the value of the repoRefKey index of the
+// automations selecting their GitRepository by labels, which can't be a name.
+const repoSelectorRefValue = "<selector>"
+
+// valuesFromKey is the index of the ConfigMaps and Secrets referenced as
+// template values by the automations, as "<kind>/<name>".
+const valuesFromKey = ".spec.git.commit.valuesFrom"
+
+// signingKeyKey is the index of the Secret of the signing key of the
+// ImageUpdateAutomations.
+const signingKeyKey = ".spec.git.commit.signingKey"
+
+// gitRepoSecretKey is the index of the Secret of the credentials of the
+// GitRepositories.
+const gitRepoSecretKey = ".spec.secretRef"
+
 const readyMessage = "repository up-to-date"
 
 // imageUpdateAutomationOwnedConditions is a list of conditions owned by the
@@ -69,6 +93,10 @@ var imageUpdateAutomationOwnedConditions = []string{
 	meta.ReadyCondition,
 	meta.ReconcilingCondition,
 	meta.StalledCondition,
+	imagev1.ChecksPassedCondition,
+	imagev1.IntervalStretchedCondition,
+	imagev1.DriftedCondition,
+	imagev1.CrossNamespaceMarkersCondition,
 }
 
 // imageUpdateAutomationNegativeConditions is a list of negative polarity
@@ -94,9 +122,13 @@ func getPatchOptions(ownedConditions []string, controllerName string) []patch.Op
 // +kubebuilder:rbac:groups=image.toolkit.fluxcd.io,resources=imageupdateautomations/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=image.toolkit.fluxcd.io,resources=imagepolicies,verbs=get;list;watch
 // +kubebuilder:rbac:groups=image.toolkit.fluxcd.io,resources=imagepolicies/status,verbs=get
+// +kubebuilder:rbac:groups=image.toolkit.fluxcd.io,resources=imagerepositories,verbs=get;list;watch
 // +kubebuilder:rbac:groups=source.toolkit.fluxcd.io,resources=gitrepositories,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=impersonate
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
 
 // ImageUpdateAutomationReconciler reconciles a ImageUpdateAutomation object
 type ImageUpdateAutomationReconciler struct {
@@ -106,16 +138,101 @@ type ImageUpdateAutomationReconciler struct {
 
 	ControllerName      string
 	NoCrossNamespaceRef bool
+	// PolicyApplyWorkers is the number of workers used to apply the
+	// policies to the files in the source. If zero or less, one worker
+	// per usable CPU is used.
+	PolicyApplyWorkers int
+	// WorkingDir is the directory the sources are checked out in. If empty,
+	// the default directory for temporary files is used.
+	WorkingDir string
+	// MaxWorktreeSize, if not zero, is the maximum size in bytes of a
+	// checked out source. A larger source fails the reconciliation.
+	MaxWorktreeSize int64
+	// UpdateMemoryBudget, if not zero, is the estimated memory in bytes
+	// the files being updated may take at once.
+	UpdateMemoryBudget int64
+	// CloneCache, if set, is used to clone the sources when the
+	// GitCloneCache feature gate is enabled.
+	CloneCache *source.CloneCache
+	// RepeatedFailureThreshold is the number of consecutive identical
+	// failures after which an automation is marked Stalled and retried every
+	// RepeatedFailureInterval. If zero or less, failures are always retried
+	// with backoff.
+	RepeatedFailureThreshold int
+	// RepeatedFailureInterval is the interval at which the automations
+	// stalled by repeated failures are retried.
+	RepeatedFailureInterval time.Duration
+	// NeverUpdateImages is the list of the names of the images which are
+	// never updated, whatever the automation.
+	NeverUpdateImages []string
+	// DefaultServiceAccount is the name of the ServiceAccount impersonated
+	// to read the policies and the source of the automations which don't
+	// set one. If empty, they're read with the controller ServiceAccount.
+	DefaultServiceAccount string
+	// FaultInjector, if set, injects synthetic failures in the pushes when
+	// the GitFaultInjection feature gate is enabled.
+	FaultInjector *source.FaultInjector
+	// ProtectedBranches is the list of the patterns of the branches the
+	// automations can't push to directly, with the syntax of path.Match.
+	// The automations pushing to a branch per policy, to open pull
+	// requests from, aren't affected.
+	ProtectedBranches []string
+	// RequireSignedCommits refuses to push unsigned commits, stalling the
+	// automations without signing key. Without it, the commits must only be
+	// signed in the namespaces with the RequireSignedCommitsAnnotation.
+	RequireSignedCommits bool
+	// ChangesExporter, if set, exports the report of the changes of each
+	// successful push.
+	ChangesExporter export.Exporter
+	// PushMetrics, if set, records the pushes of the automations.
+	PushMetrics *PushMetrics
+	// PolicyCache, if set, memoizes the policies selected by the automations
+	// which don't impersonate a ServiceAccount.
+	PolicyCache *PolicyCache
+	// StartupJitter, if not zero, is the window the reconciliations
+	// following the start of the controller are spread over.
+	StartupJitter time.Duration
 
 	features map[string]bool
 
+	startOnce sync.Once
+	startTime time.Time
+
+	pushTargetLocks pushTargetLocks
+
+	// imagePolicyWatchDisabled is set when the changes of the
+	// ImagePolicies trigger no reconciliation.
+	imagePolicyWatchDisabled bool
+
 	patchOptions []patch.Option
 }
 
+// sourceNotReadyRequeueDelay is the delay after which an automation whose
+// GitRepository is suspended or not ready is retried, if the GitRepository
+// didn't become ready meanwhile.
+const sourceNotReadyRequeueDelay = time.Minute
+
+// policyListPageSize is the number of policies listed per request when they
+// are listed from the API server.
+const policyListPageSize = 500
+
+// shutdownPatchTimeout is the timeout of the patch of the status of an
+// automation whose reconciliation was interrupted by the shutdown of the
+// controller.
+const shutdownPatchTimeout = 10 * time.Second
+
 type ImageUpdateAutomationReconcilerOptions struct {
 	MaxConcurrentReconciles int
 	RateLimiter             workqueue.TypedRateLimiter[reconcile.Request]
 	RecoverPanic            bool
+	// PolicyDebounceWindow, if not zero, delays the reconciliations for the
+	// changes of the latest image of the ImagePolicies until no ImagePolicy
+	// of their namespace changed for the window.
+	PolicyDebounceWindow time.Duration
+	// DisableImagePolicyWatch stops the changes of the ImagePolicies from
+	// triggering reconciliations, for the automations to run at their
+	// interval only.
+	DisableImagePolicyWatch bool
 }
 
 func (r *ImageUpdateAutomationReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager, opts ImageUpdateAutomationReconcilerOptions) error {
@@ -126,17 +243,61 @@ func (r *ImageUpdateAutomationReconciler) SetupWithManager(ctx context.Context,
 	}
 
 	// Index the git repository object that each I-U-A refers to
-	if err := mgr.GetFieldIndexer().IndexField(ctx, &imagev1.ImageUpdateAutomation{}, repoRefKey, func(obj client.Object) []string {
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &imagev1.ImageUpdateAutomation{}, repoRefKey, indexGitRepoRef); err != nil {
+		return err
+	}
+
+	// Index the ConfigMaps and Secrets providing template values to each
+	// I-U-A.
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &imagev1.ImageUpdateAutomation{}, valuesFromKey, func(obj client.Object) []string {
 		updater := obj.(*imagev1.ImageUpdateAutomation)
-		ref := updater.Spec.SourceRef
-		return []string{ref.Name}
+		if updater.Spec.GitSpec == nil {
+			return nil
+		}
+		var keys []string
+		for _, ref := range updater.Spec.GitSpec.Commit.ValuesFrom {
+			keys = append(keys, ref.Kind+"/"+ref.Name)
+		}
+		return keys
 	}); err != nil {
 		return err
 	}
 
-	return ctrl.NewControllerManagedBy(mgr).
+	// Index the Secrets holding the credentials of the I-U-As and of their
+	// git repositories, to retry the failing I-U-As when the credentials are
+	// rotated. This is only enabled along with the caching of the Secrets, for
+	// the retries not to read the Secrets from the API server.
+	if r.features[features.CacheSecretsAndConfigMaps] {
+		if err := mgr.GetFieldIndexer().IndexField(ctx, &imagev1.ImageUpdateAutomation{}, signingKeyKey, indexSigningKey); err != nil {
+			return err
+		}
+		if err := mgr.GetFieldIndexer().IndexField(ctx, &sourcev1.GitRepository{}, gitRepoSecretKey, indexGitRepoSecret); err != nil {
+			return err
+		}
+	}
+
+	r.imagePolicyWatchDisabled = opts.DisableImagePolicyWatch
+	var debouncer *policyDebouncer
+	if opts.PolicyDebounceWindow > 0 && !opts.DisableImagePolicyWatch {
+		debouncer = newPolicyDebouncer(opts.PolicyDebounceWindow)
+	}
+	// The memoized policies must be invalidated before any reconciliation is
+	// requested for a change of the policies. They are still invalidated
+	// when the changes of the policies trigger no reconciliation.
+	var policyPredicates []predicate.Predicate
+	if r.PolicyCache != nil {
+		policyPredicates = append(policyPredicates, r.PolicyCache.Predicate())
+	}
+	if opts.DisableImagePolicyWatch {
+		policyPredicates = append(policyPredicates, predicate.NewPredicateFuncs(func(client.Object) bool { return false }))
+	} else {
+		policyPredicates = append(policyPredicates, latestImageChangePredicate{debouncer: debouncer})
+	}
+
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&imagev1.ImageUpdateAutomation{}, builder.WithPredicates(
-			predicate.Or(predicate.GenerationChangedPredicate{}, predicates.ReconcileRequestedPredicate{}))).
+			predicate.Or(predicate.GenerationChangedPredicate{}, predicates.ReconcileRequestedPredicate{},
+				fullSyncRequestedPredicate{}))).
 		Watches(
 			&sourcev1.GitRepository{},
 			handler.EnqueueRequestsFromMapFunc(r.automationsForGitRepo),
@@ -145,20 +306,34 @@ func (r *ImageUpdateAutomationReconciler) SetupWithManager(ctx context.Context,
 		Watches(
 			&imagev1_reflect.ImagePolicy{},
 			handler.EnqueueRequestsFromMapFunc(r.automationsForImagePolicy),
-			builder.WithPredicates(latestImageChangePredicate{}),
+			builder.WithPredicates(policyPredicates...),
+		).
+		Watches(
+			&corev1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(r.automationsForValues("ConfigMap")),
+			builder.OnlyMetadata,
+		).
+		Watches(
+			&corev1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.automationsForSecret),
+			builder.OnlyMetadata,
 		).
 		WithOptions(controller.Options{
 			RateLimiter: opts.RateLimiter,
-		}).
-		Complete(r)
+		})
+	if debouncer != nil {
+		b = b.WatchesRawSource(ctrlsource.Channel(debouncer.events,
+			handler.EnqueueRequestsFromMapFunc(r.automationsForImagePolicy)))
+	}
+	return b.Complete(r)
 }
 
-// automationsForGitRepo fetches all the automations that refer to a
-// particular source.GitRepository object.
+// automationsForGitRepo fetches all the automations that check out or push to
+// a particular source.GitRepository object, referring to it by name or by a
+// label selector matching it.
 func (r *ImageUpdateAutomationReconciler) automationsForGitRepo(ctx context.Context, obj client.Object) []reconcile.Request {
 	var autoList imagev1.ImageUpdateAutomationList
-	if err := r.List(ctx, &autoList, client.InNamespace(obj.GetNamespace()),
-		client.MatchingFields{repoRefKey: obj.GetName()}); err != nil {
+	if err := r.listAutomationsForGitRepo(ctx, obj, &autoList); err != nil {
 		ctrl.LoggerFrom(ctx).Error(err, "failed to list ImageUpdateAutomations for GitRepository change")
 		return nil
 	}
@@ -170,6 +345,181 @@ func (r *ImageUpdateAutomationReconciler) automationsForGitRepo(ctx context.Cont
 	return reqs
 }
 
+// listAutomationsForGitRepo lists the automations that check out or push to
+// the given source.GitRepository object, referring to it by name or by a label
+// selector matching it.
+func (r *ImageUpdateAutomationReconciler) listAutomationsForGitRepo(ctx context.Context, repo client.Object, autoList *imagev1.ImageUpdateAutomationList) error {
+	if err := r.List(ctx, autoList, client.InNamespace(repo.GetNamespace()),
+		client.MatchingFields{repoRefKey: repo.GetName()}); err != nil {
+		return err
+	}
+	var selectorList imagev1.ImageUpdateAutomationList
+	if err := r.List(ctx, &selectorList, client.InNamespace(repo.GetNamespace()),
+		client.MatchingFields{repoRefKey: repoSelectorRefValue}); err != nil {
+		return err
+	}
+	for _, auto := range selectorList.Items {
+		selected := slices.ContainsFunc(gitRepoRefs(&auto), func(ref imagev1.CrossNamespaceSourceReference) bool {
+			if ref.Selector == nil {
+				return false
+			}
+			selector, err := metav1.LabelSelectorAsSelector(ref.Selector)
+			return err == nil && selector.Matches(labels.Set(repo.GetLabels()))
+		})
+		// An automation referring to the repository both by name and by
+		// labels is already listed.
+		listed := slices.ContainsFunc(autoList.Items, func(listed imagev1.ImageUpdateAutomation) bool {
+			return listed.Name == auto.Name
+		})
+		if selected && !listed {
+			autoList.Items = append(autoList.Items, auto)
+		}
+	}
+	return nil
+}
+
+// automationsForValues returns a function fetching all the automations
+// referring to a particular ConfigMap or Secret, of the given kind, for their
+// template values.
+func (r *ImageUpdateAutomationReconciler) automationsForValues(kind string) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		var autoList imagev1.ImageUpdateAutomationList
+		if err := r.List(ctx, &autoList, client.InNamespace(obj.GetNamespace()),
+			client.MatchingFields{valuesFromKey: kind + "/" + obj.GetName()}); err != nil {
+			ctrl.LoggerFrom(ctx).Error(err, "failed to list ImageUpdateAutomations for "+kind+" change")
+			return nil
+		}
+		reqs := make([]reconcile.Request, len(autoList.Items))
+		for i := range autoList.Items {
+			reqs[i].NamespacedName.Name = autoList.Items[i].GetName()
+			reqs[i].NamespacedName.Namespace = autoList.Items[i].GetNamespace()
+		}
+		return reqs
+	}
+}
+
+// automationsForSecret fetches all the automations referring to a particular
+// Secret for their template values, and, when the Secrets are cached, the
+// failing automations using it as credentials, directly for their signing key
+// or through their git repository.
+func (r *ImageUpdateAutomationReconciler) automationsForSecret(ctx context.Context, obj client.Object) []reconcile.Request {
+	reqs := r.automationsForValues("Secret")(ctx, obj)
+	if !r.features[features.CacheSecretsAndConfigMaps] {
+		return reqs
+	}
+
+	seen := make(map[types.NamespacedName]bool)
+	for _, req := range reqs {
+		seen[req.NamespacedName] = true
+	}
+	enqueueFailing := func(autoList *imagev1.ImageUpdateAutomationList) {
+		for i := range autoList.Items {
+			auto := &autoList.Items[i]
+			key := client.ObjectKeyFromObject(auto)
+			if seen[key] || !conditions.IsFalse(auto, meta.ReadyCondition) {
+				continue
+			}
+			seen[key] = true
+			reqs = append(reqs, reconcile.Request{NamespacedName: key})
+		}
+	}
+
+	var autoList imagev1.ImageUpdateAutomationList
+	if err := r.List(ctx, &autoList, client.InNamespace(obj.GetNamespace()),
+		client.MatchingFields{signingKeyKey: obj.GetName()}); err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "failed to list ImageUpdateAutomations for Secret change")
+		return reqs
+	}
+	enqueueFailing(&autoList)
+
+	var repoList sourcev1.GitRepositoryList
+	if err := r.List(ctx, &repoList, client.InNamespace(obj.GetNamespace()),
+		client.MatchingFields{gitRepoSecretKey: obj.GetName()}); err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "failed to list GitRepositories for Secret change")
+		return reqs
+	}
+	for i := range repoList.Items {
+		var autoList imagev1.ImageUpdateAutomationList
+		if err := r.listAutomationsForGitRepo(ctx, &repoList.Items[i], &autoList); err != nil {
+			ctrl.LoggerFrom(ctx).Error(err, "failed to list ImageUpdateAutomations for Secret change")
+			return reqs
+		}
+		enqueueFailing(&autoList)
+	}
+	return reqs
+}
+
+// indexGitRepoRef indexes an ImageUpdateAutomation by the names of the git
+// repositories it checks out and pushes to, or by repoSelectorRefValue when it
+// selects them by labels.
+func indexGitRepoRef(obj client.Object) []string {
+	var keys []string
+	for _, ref := range gitRepoRefs(obj.(*imagev1.ImageUpdateAutomation)) {
+		key := ref.Name
+		if ref.Selector != nil {
+			key = repoSelectorRefValue
+		}
+		if !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// gitRepoRefs returns the references to the git repository the automation
+// checks out and, when it's another one, to the one it pushes to.
+func gitRepoRefs(auto *imagev1.ImageUpdateAutomation) []imagev1.CrossNamespaceSourceReference {
+	refs := []imagev1.CrossNamespaceSourceReference{auto.Spec.SourceRef}
+	if auto.Spec.GitSpec != nil && auto.Spec.GitSpec.Push != nil && auto.Spec.GitSpec.Push.SourceRef != nil {
+		refs = append(refs, *auto.Spec.GitSpec.Push.SourceRef)
+	}
+	return refs
+}
+
+// indexSigningKey indexes an ImageUpdateAutomation by the names of the Secrets
+// of its signing keys.
+func indexSigningKey(obj client.Object) []string {
+	auto := obj.(*imagev1.ImageUpdateAutomation)
+	if auto.Spec.GitSpec == nil || auto.Spec.GitSpec.Commit.SigningKey == nil {
+		return nil
+	}
+	return auto.Spec.GitSpec.Commit.SigningKey.SecretNames()
+}
+
+// indexGitRepoSecret indexes a GitRepository by the name of the Secret of its
+// credentials.
+func indexGitRepoSecret(obj client.Object) []string {
+	repo := obj.(*sourcev1.GitRepository)
+	if repo.Spec.SecretRef == nil {
+		return nil
+	}
+	return []string{repo.Spec.SecretRef.Name}
+}
+
+// remotePushes returns the status of the pushes to the additional remotes of
+// the given push results, one per remote. With per-policy branches, the push
+// of a commit to a remote failed if any of them did, and succeeded with the
+// last commit otherwise.
+func remotePushes(results []*source.PushResult) []imagev1.RemotePush {
+	var pushes []imagev1.RemotePush
+	for _, result := range results {
+		for _, rp := range result.RemotePushes() {
+			i := slices.IndexFunc(pushes, func(p imagev1.RemotePush) bool { return p.Name == rp.Name })
+			if i < 0 {
+				pushes = append(pushes, imagev1.RemotePush{Name: rp.Name})
+				i = len(pushes) - 1
+			}
+			switch {
+			case rp.Err != nil:
+				pushes[i] = imagev1.RemotePush{Name: rp.Name, Error: rp.Err.Error()}
+			case pushes[i].Error == "":
+				pushes[i].Commit = result.Commit().Hash.String()
+			}
+		}
+	}
+	return pushes
+}
+
 // automationsForImagePolicy fetches all the automation objects that
 // might depend on a image policy object. Since the link is via
 // markers in the git repo, _any_ automation object in the same
@@ -180,10 +530,13 @@ func (r *ImageUpdateAutomationReconciler) automationsForImagePolicy(ctx context.
 		ctrl.LoggerFrom(ctx).Error(err, "failed to list ImageUpdateAutomations for ImagePolicy change")
 		return nil
 	}
-	reqs := make([]reconcile.Request, len(autoList.Items))
-	for i := range autoList.Items {
-		reqs[i].NamespacedName.Name = autoList.Items[i].GetName()
-		reqs[i].NamespacedName.Namespace = autoList.Items[i].GetNamespace()
+	reqs := make([]reconcile.Request, 0, len(autoList.Items))
+	for _, auto := range autoList.Items {
+		// The automations opting out run at their interval only.
+		if !auto.Spec.Triggers.ImagePoliciesEnabled() {
+			continue
+		}
+		reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&auto)})
 	}
 	return reqs
 }
@@ -203,9 +556,17 @@ func (r *ImageUpdateAutomationReconciler) Reconcile(ctx context.Context, req ctr
 
 	// Always attempt to patch the object after each reconciliation.
 	defer func() {
+		// The outcome of a reconciliation interrupted by the shutdown of the
+		// controller is still recorded.
+		patchCtx := ctx
+		if ctx.Err() != nil {
+			var cancel context.CancelFunc
+			patchCtx, cancel = context.WithTimeout(context.WithoutCancel(ctx), shutdownPatchTimeout)
+			defer cancel()
+		}
 		// Create patch options for the final patch of the object.
 		patchOpts := runtimereconcile.AddPatchOptions(obj, r.patchOptions, imageUpdateAutomationOwnedConditions, r.ControllerName)
-		if err := serialPatcher.Patch(ctx, obj, patchOpts...); err != nil {
+		if err := serialPatcher.Patch(patchCtx, obj, patchOpts...); err != nil {
 			// Ignore patch error "not found" when the object is being deleted.
 			if !obj.GetDeletionTimestamp().IsZero() {
 				err = kerrors.FilterOut(err, func(e error) bool { return apierrors.IsNotFound(e) })
@@ -243,6 +604,17 @@ func (r *ImageUpdateAutomationReconciler) Reconcile(ctx context.Context, req ctr
 		log.Info("reconciliation is suspended for this object")
 		return ctrl.Result{}, nil
 	}
+	// Return and requeue at the end of the suspension if the object is
+	// suspended until a given time.
+	if delay := r.reconcileSuspension(ctx, obj, start); delay > 0 {
+		return ctrl.Result{RequeueAfter: delay}, nil
+	}
+
+	// Spread the reconciliations following the start of the controller.
+	if delay := r.startupDelay(obj, start); delay > 0 {
+		log.V(logger.DebugLevel).Info("delaying the reconciliation after the controller start", "delay", delay.String())
+		return ctrl.Result{RequeueAfter: delay}, nil
+	}
 
 	result, retErr = r.reconcile(ctx, serialPatcher, obj, start)
 	return
@@ -252,41 +624,52 @@ func (r *ImageUpdateAutomationReconciler) reconcile(ctx context.Context, sp *pat
 	obj *imagev1.ImageUpdateAutomation, startTime time.Time) (result ctrl.Result, retErr error) {
 	oldObj := obj.DeepCopy()
 
-	var pushResult *source.PushResult
+	var pushResults []*source.PushResult
+	// skipped records the policies left out of the reconciliation.
+	var skipped skippedPolicies
+	// failureReason is the reason of the Warning event of a failure, when
+	// it's more specific than the reason of the Ready condition.
+	var failureReason string
 
 	// syncNeeded decides if full reconciliation with image update is needed.
 	syncNeeded := false
+	// summary records the decisions of the reconciliation in the status.
+	summary := &imagev1.RunSummary{}
 
 	defer func() {
-		// Define the meaning of success based on the requeue interval.
-		isSuccess := func(res ctrl.Result, err error) bool {
-			if err != nil || res.RequeueAfter != obj.GetRequeueAfter() || res.Requeue {
-				return false
-			}
-			return true
+		if summary != nil {
+			summary.SyncNeeded = syncNeeded
+			obj.Status.LastRunSummary = summary
 		}
 
-		rs := runtimereconcile.NewResultFinalizer(isSuccess, readyMessage)
-		retErr = rs.Finalize(obj, result, retErr)
-
-		// Presence of reconciling means that the reconciliation didn't succeed.
-		// Set the Reconciling reason to ProgressingWithRetry to indicate a
-		// failure retry.
-		if conditions.IsReconciling(obj) {
-			reconciling := conditions.Get(obj, meta.ReconcilingCondition)
-			reconciling.Reason = meta.ProgressingWithRetryReason
-			conditions.Set(obj, reconciling)
+		if operation, ok := source.IsDeadlineExceeded(retErr); ok && r.PushMetrics != nil {
+			r.PushMetrics.RecordDeadlineExceeded(obj.Name, obj.Namespace, operation)
+		}
+		retErr = finalizeResult(obj, result, retErr)
+		result, retErr = r.recordFailureStreak(ctx, obj, result, retErr)
+		if retErr == nil && !conditions.IsStalled(obj) {
+			result = r.reconcileChecks(ctx, obj, result, len(pushResults) > 0)
+		}
+		if syncNeeded {
+			recordRunDuration(obj, time.Since(startTime))
 		}
+		result = stretchInterval(obj, result)
+		result, retErr = backoffClassifiedError(ctx, obj, result, retErr)
 
-		r.notify(ctx, oldObj, obj, pushResult, syncNeeded)
+		r.notify(ctx, oldObj, obj, pushResults, syncNeeded, skipped, failureReason)
 	}()
 
 	// TODO: Maybe move this to Reconcile()'s defer and avoid passing startTime
 	// to reconcile()?
 	obj.Status.LastAutomationRunTime = &metav1.Time{Time: startTime}
+	obj.Status.ActiveTriggers = r.activeTriggers(obj)
 
 	// Set reconciling condition.
 	runtimereconcile.ProgressiveStatus(false, obj, meta.ProgressingReason, "reconciliation in progress")
+	// Retry any repeated failure, from scratch if the objects it was observed
+	// with have changed.
+	resetStaleReadyCondition(obj, imagev1.RepeatedFailureReason)
+	r.resetFailureStreak(ctx, obj)
 
 	var reconcileAtVal string
 	if v, ok := meta.ReconcileAnnotationValue(obj.GetAnnotations()); ok {
@@ -309,8 +692,40 @@ func (r *ImageUpdateAutomationReconciler) reconcile(ctx context.Context, sp *pat
 		}
 	}
 
-	// List the policies and construct observed policies.
-	policies, err := getPolicies(ctx, r.Client, obj.Namespace, obj.Spec.PolicySelector)
+	// Validate the templates before anything is checked out.
+	if err := source.ValidateTemplates(obj); err != nil {
+		conditions.MarkStalled(obj, imagev1.InvalidTemplateReason, "%s", err)
+		result, retErr = ctrl.Result{}, nil
+		return
+	}
+	// Update any stale Ready=False condition from templates failure.
+	resetStaleReadyCondition(obj, imagev1.InvalidTemplateReason)
+
+	// Read the policies and the source as the ServiceAccount of the
+	// automation, if any.
+	tenantClient, err := r.tenantClient(ctx, obj)
+	if err != nil {
+		e := fmt.Errorf("failed to impersonate ServiceAccount: %w", err)
+		conditions.MarkFalse(obj, meta.ReadyCondition, imagev1.ImpersonationFailedReason, "%s", e)
+		result, retErr = ctrl.Result{}, e
+		return
+	}
+	// Update any stale Ready=False condition from impersonation failure.
+	resetStaleReadyCondition(obj, imagev1.ImpersonationFailedReason)
+
+	// List the policies and construct observed policies. The policies listed
+	// from the cache of the manager are memoized, while the ones listed as
+	// the ServiceAccount of the automation are paginated, as they're listed
+	// from the API server.
+	var policies []imagev1_reflect.ImagePolicy
+	switch {
+	case tenantClient != r.Client:
+		policies, skipped, err = getPolicies(ctx, tenantClient, obj.Namespace, obj.Spec.PolicySelector, policyListPageSize)
+	case r.PolicyCache != nil:
+		policies, skipped, err = r.PolicyCache.getPolicies(ctx, r.Client, obj.Namespace, obj.Spec.PolicySelector)
+	default:
+		policies, skipped, err = getPolicies(ctx, r.Client, obj.Namespace, obj.Spec.PolicySelector, 0)
+	}
 	if err != nil {
 		if errors.Is(err, errParsePolicySelector) {
 			conditions.MarkStalled(obj, imagev1.InvalidPolicySelectorReason, "%s", err)
@@ -321,9 +736,30 @@ func (r *ImageUpdateAutomationReconciler) reconcile(ctx context.Context, sp *pat
 		return
 	}
 	// Update any stale Ready=False condition from policies config failure.
-	if conditions.HasAnyReason(obj, meta.ReadyCondition, imagev1.InvalidPolicySelectorReason) {
-		conditions.MarkUnknown(obj, meta.ReadyCondition, meta.ProgressingReason, "reconciliation in progress")
+	resetStaleReadyCondition(obj, imagev1.InvalidPolicySelectorReason)
+
+	// Leave out the policies of the images excluded from updates by the
+	// controller.
+	policies, obj.Status.ExcludedPolicies = policy.ExcludePolicies(policies, r.NeverUpdateImages)
+	skipped.add(skipReasonExcluded, obj.Status.ExcludedPolicies...)
+
+	// Leave out the policies selected by no route, which are never pushed.
+	var unrouted []string
+	if policies, unrouted, err = routedPolicies(obj.Spec.Routes, policies); err != nil {
+		conditions.MarkStalled(obj, imagev1.InvalidPolicySelectorReason, "%s", err)
+		result, retErr = ctrl.Result{}, nil
+		return
 	}
+	skipped.add(skipReasonUnrouted, unrouted...)
+
+	// Prefer the pinned images over the latest images of the policies, for
+	// the changes of the overrides to be observed.
+	policies, obj.Status.PinnedPolicies = policy.PinPolicies(obj, policies)
+
+	// Revert the images revoked from their policies on their own, the other
+	// policies are applied by the next reconciliation.
+	var revoked []policy.RevokedImage
+	policies, revoked = revertedPolicies(obj, policies)
 
 	observedPolicies, err := observedPolicies(policies)
 	if err != nil {
@@ -331,9 +767,24 @@ func (r *ImageUpdateAutomationReconciler) reconcile(ctx context.Context, sp *pat
 		return
 	}
 
+	// Record when the images of the policies were first observed, for the
+	// commits to be dated after them.
+	var commitTime time.Time
+	if obj.Spec.GitSpec != nil && obj.Spec.GitSpec.Commit.Date == imagev1.CommitDatePolicyChange {
+		obj.Status.PolicyChanges = policyChanges(obj.Status.PolicyChanges, observedPolicies, time.Now(), len(revoked) > 0)
+		commitTime = latestPolicyChange(obj.Status.PolicyChanges, observedPolicies)
+	} else {
+		obj.Status.PolicyChanges = nil
+	}
+
+	if len(revoked) > 0 {
+		observedPolicies = revertedObservations(obj.Status.ObservedPolicies, observedPolicies)
+	}
+
 	// If the policies have changed, require a full sync.
 	if observedPoliciesChanged(obj.Status.ObservedPolicies, observedPolicies) {
 		syncNeeded = true
+		summary.SyncReasons = append(summary.SyncReasons, imagev1.SyncReasonPoliciesChanged)
 	}
 
 	// Create source manager with options.
@@ -344,8 +795,53 @@ func (r *ImageUpdateAutomationReconciler) reconcile(ctx context.Context, sp *pat
 	if r.features[features.GitAllBranchReferences] {
 		smOpts = append(smOpts, source.WithSourceOptionGitAllBranchReferences())
 	}
-	sm, err := source.NewSourceManager(ctx, r.Client, obj, smOpts...)
+	if r.features[features.GitInMemoryWorkTree] {
+		smOpts = append(smOpts, source.WithSourceOptionInMemory())
+	}
+	if r.features[features.GitCloneCache] && r.CloneCache != nil {
+		smOpts = append(smOpts, source.WithSourceOptionCloneCache(r.CloneCache))
+	}
+	if r.WorkingDir != "" {
+		smOpts = append(smOpts, source.WithSourceOptionWorkingDir(r.WorkingDir))
+	}
+	if r.MaxWorktreeSize > 0 {
+		smOpts = append(smOpts, source.WithSourceOptionMaxWorktreeSize(r.MaxWorktreeSize))
+	}
+	if !commitTime.IsZero() {
+		smOpts = append(smOpts, source.WithSourceOptionCommitTime(commitTime))
+	}
+	if r.features[features.GitFaultInjection] && r.FaultInjector.Enabled(obj.Namespace) {
+		smOpts = append(smOpts, source.WithSourceOptionFaultInjector(r.FaultInjector))
+	}
+	if r.features[features.TemplateImageRepositories] {
+		repos, err := getImageRepositories(ctx, tenantClient, policies)
+		if err != nil {
+			result, retErr = ctrl.Result{}, err
+			return
+		}
+		smOpts = append(smOpts, source.WithSourceOptionImageRepositories(repos))
+	}
+	signingRequired, err := r.signedCommitsRequired(ctx, obj.Namespace)
 	if err != nil {
+		result, retErr = ctrl.Result{}, err
+		return
+	}
+	if signingRequired {
+		smOpts = append(smOpts, source.WithSourceOptionRequireSignedCommits())
+	}
+	if len(revoked) > 0 {
+		smOpts = append(smOpts, source.WithSourceOptionCommitMessage(revertMessage(revoked)))
+	}
+	sm, err := source.NewSourceManager(ctx, tenantClient, obj, smOpts...)
+	if err != nil {
+		if source.IsSigningError(err) {
+			failureReason = imagev1.SignFailedReason
+		}
+		if errors.Is(err, source.ErrSigningRequired) {
+			conditions.MarkStalled(obj, imagev1.SigningRequiredReason, "%s", err)
+			result, retErr = ctrl.Result{}, nil
+			return
+		}
 		if acl.IsAccessDenied(err) {
 			conditions.MarkStalled(obj, aclapi.AccessDeniedReason, "%s", err)
 			result, retErr = ctrl.Result{}, nil
@@ -366,23 +862,87 @@ func (r *ImageUpdateAutomationReconciler) reconcile(ctx context.Context, sp *pat
 			retErr = err
 		}
 	}()
-	// Update any stale Ready=False condition from SourceManager failure.
-	if conditions.HasAnyReason(obj, meta.ReadyCondition, aclapi.AccessDeniedCondition, imagev1.InvalidSourceConfigReason, imagev1.SourceManagerFailedReason) {
-		conditions.MarkUnknown(obj, meta.ReadyCondition, meta.ProgressingReason, "reconciliation in progress")
+	// Wait for a failing or suspended source to recover, rather than failing
+	// to clone it.
+	if err := sm.CheckSourceReady(); err != nil {
+		reason := imagev1.SourceNotReadyReason
+		if errors.Is(err, source.ErrSourceSuspended) {
+			reason = imagev1.SourceSuspendedReason
+		}
+		conditions.MarkFalse(obj, meta.ReadyCondition, reason, "%s", err)
+		result, retErr = ctrl.Result{RequeueAfter: sourceNotReadyRequeueDelay}, nil
+		return
+	}
+	// Update any stale Ready=False condition from the source not being ready.
+	resetStaleReadyCondition(obj, imagev1.SourceSuspendedReason, imagev1.SourceNotReadyReason)
+	// Check the signing configuration on demand, before anything is pushed.
+	r.selfTestSigning(ctx, obj, sm)
+	// Refuse to push directly to a protected branch, with the push branch or
+	// the refspec.
+	url, branch := sm.PushTarget()
+	var pushBranches []string
+	switch {
+	case sm.PerPolicyBranches():
+	case sm.Routes():
+		pushBranches = routeBranches(obj.Spec.Routes)
+	case !sm.RefspecOnly():
+		pushBranches = []string{branch}
+	}
+	var refspec string
+	if obj.Spec.GitSpec.HasRefspec() {
+		refspec = obj.Spec.GitSpec.Push.Refspec
+	}
+	if err := protectedPushError(r.ProtectedBranches, pushBranches, refspec); err != nil {
+		conditions.MarkStalled(obj, imagev1.ProtectedBranchReason, "%s", err)
+		result, retErr = ctrl.Result{}, nil
+		return
 	}
+	// Serialize the reconciliations of the automations pushing to the same
+	// repository and branch.
+	pushTarget := url + "#" + branch
+	objKey := client.ObjectKeyFromObject(obj)
+	if holder, ok := r.pushTargetLocks.tryLock(pushTarget, objKey); !ok {
+		ctrl.LoggerFrom(ctx).V(logger.DebugLevel).Info("push target is locked by another automation, requeueing",
+			"pushTarget", pushTarget, "holder", holder.String())
+		conditions.MarkReconciling(obj, meta.ProgressingReason,
+			"waiting for the reconciliation of %s, which pushes to the same repository and branch", holder)
+		// Nothing was decided, keep the summary of the previous run.
+		summary = nil
+		result, retErr = ctrl.Result{RequeueAfter: pushTargetRequeueDelay}, nil
+		return
+	}
+	defer r.pushTargetLocks.unlock(pushTarget, objKey)
+
+	// Update any stale Ready=False condition from SourceManager failure.
+	resetStaleReadyCondition(obj, aclapi.AccessDeniedReason, imagev1.InvalidSourceConfigReason, imagev1.SourceManagerFailedReason,
+		imagev1.SigningRequiredReason)
 
 	// When the checkout and push branches are different or a refspec is
 	// defined, always perform a full sync.
 	// This can be worked around in the future by also querying the HEAD of push
 	// branch to detech if it has drifted.
-	if sm.SwitchBranch() || obj.Spec.GitSpec.HasRefspec() {
+	if sm.SwitchBranch() {
 		syncNeeded = true
+		summary.SyncReasons = append(summary.SyncReasons, imagev1.SyncReasonPushBranch)
+	}
+	if obj.Spec.GitSpec.HasRefspec() {
+		syncNeeded = true
+		summary.SyncReasons = append(summary.SyncReasons, imagev1.SyncReasonRefspec)
+	}
+	// A full sync requested with the annotation ignores the observations,
+	// e.g. when the history of the source was rewritten.
+	fullSyncVal, fullSyncRequested := obj.PendingRequest(imagev1.FullSyncAnnotation)
+	if fullSyncRequested {
+		syncNeeded = true
+		summary.SyncReasons = append(summary.SyncReasons, imagev1.SyncReasonFullSyncRequested)
 	}
 
 	// Build checkout options.
 	checkoutOpts := []source.CheckoutOption{}
-	if r.features[features.GitShallowClone] {
+	summary.Checkout = imagev1.CheckoutFull
+	if shallowClone(obj, r.features[features.GitShallowClone]) {
 		checkoutOpts = append(checkoutOpts, source.WithCheckoutOptionShallowClone())
+		summary.Checkout = imagev1.CheckoutShallow
 	}
 	// If full sync is still not needed, configure last observed commit to
 	// perform optimized clone and obtain a non-concrete commit if the remote
@@ -393,20 +953,43 @@ func (r *ImageUpdateAutomationReconciler) reconcile(ctx context.Context, sp *pat
 
 	commit, err := sm.CheckoutSource(ctx, checkoutOpts...)
 	if err != nil {
+		summary.Checkout = ""
+		// A moved repository needs the URL of the GitRepository to be
+		// updated.
+		if _, ok := source.IsRepositoryMoved(err); ok {
+			conditions.MarkStalled(obj, imagev1.RepositoryMovedReason, "%s", err)
+			result, retErr = ctrl.Result{}, nil
+			return
+		}
 		e := fmt.Errorf("failed to checkout source: %w", err)
-		conditions.MarkFalse(obj, meta.ReadyCondition, imagev1.GitOperationFailedReason, "%s", e)
+		reason := imagev1.GitOperationFailedReason
+		// A verification failure can be resolved by a new commit in the
+		// remote repository, so it's retried.
+		if errors.Is(err, source.ErrSourceVerificationFailed) {
+			reason = imagev1.SourceVerificationFailedReason
+		}
+		if errors.Is(err, source.ErrWorktreeTooLarge) {
+			reason = imagev1.WorktreeTooLargeReason
+		}
+		if reason == imagev1.GitOperationFailedReason {
+			failureReason = gitFailureReason(err, imagev1.CloneFailedReason)
+		}
+		conditions.MarkFalse(obj, meta.ReadyCondition, reason, "%s", e)
 		result, retErr = ctrl.Result{}, e
 		return
 	}
-	// Update any stale Ready=False condition from checkout failure.
-	if conditions.HasAnyReason(obj, meta.ReadyCondition, imagev1.GitOperationFailedReason) {
-		conditions.MarkUnknown(obj, meta.ReadyCondition, meta.ProgressingReason, "reconciliation in progress")
+	if r.PushMetrics != nil {
+		r.PushMetrics.RecordGitOperationDuration(obj.Name, obj.Namespace, source.GitOperationCheckout, sm.CloneDuration())
 	}
+	// Update any stale Ready=False condition from checkout failure.
+	resetStaleReadyCondition(obj, imagev1.SourceVerificationFailedReason, imagev1.GitOperationFailedReason,
+		imagev1.WorktreeTooLargeReason)
 
 	// If it's a partial commit, the reconciliation can be skipped. The last
 	// observed commit is only configured above when full sync is not needed.
 	// No change in the policies and remote git repository. Skip reconciliation.
 	if !git.IsConcreteCommit(*commit) {
+		summary.Checkout = imagev1.CheckoutSkipped
 		// Remove any stale Ready condition, most likely False, set above. Its value
 		// is derived from the overall result of the reconciliation in the deferred
 		// block at the very end.
@@ -417,28 +1000,126 @@ func (r *ImageUpdateAutomationReconciler) reconcile(ctx context.Context, sp *pat
 		// Concrete commit indicates full sync is needed due to new remote
 		// revision.
 		syncNeeded = true
+		// Only the clones of a sync are recorded, the skipped ones
+		// fetching nothing.
+		obj.Status.LastCloneStats = sm.CloneStats()
+	}
+	if commit.String() != obj.Status.ObservedSourceRevision {
+		summary.SyncReasons = append(summary.SyncReasons, imagev1.SyncReasonSourceChanged)
 	}
 	// Continue with full sync with a concrete commit.
 
+	// Render the update path, when it's a template, with the values of the
+	// commit and the policies.
+	policyKeys := make([]types.NamespacedName, 0, len(policies))
+	for i := range policies {
+		policyKeys = append(policyKeys, client.ObjectKeyFromObject(&policies[i]))
+	}
+	updatePath, err := sm.UpdatePath(obj, policyKeys)
+	if err != nil {
+		conditions.MarkStalled(obj, imagev1.InvalidTemplateReason, "%s", err)
+		result, retErr = ctrl.Result{}, nil
+		return
+	}
+
 	// Apply the policies and check if there's anything to update.
-	policyResult, err := policy.ApplyPolicies(ctx, sm.WorkDirectory(), obj, policies)
+	applyOpts := []policy.ApplyOption{
+		policy.WithApplyOptionWorkers(r.PolicyApplyWorkers),
+		policy.WithApplyOptionMemoryBudget(r.UpdateMemoryBudget),
+		policy.WithApplyOptionUpdatePath(updatePath),
+	}
+	if wt := sm.WorkTree(); wt != nil {
+		applyOpts = append(applyOpts, policy.WithApplyOptionWorkTree(wt))
+	}
+	policyResult, err := policy.ApplyPolicies(ctx, sm.WorkDirectory(), obj, policies, applyOpts...)
 	if err != nil {
-		if errors.Is(err, policy.ErrNoUpdateStrategy) || errors.Is(err, policy.ErrUnsupportedUpdateStrategy) {
+		if errors.Is(err, policy.ErrNoUpdateStrategy) || errors.Is(err, policy.ErrUnsupportedUpdateStrategy) ||
+			errors.Is(err, policy.ErrInvalidUpdatePathHint) {
 			conditions.MarkStalled(obj, imagev1.InvalidUpdateStrategyReason, "%s", err)
 			result, retErr = ctrl.Result{}, nil
 			return
 		}
 		e := fmt.Errorf("failed to apply policies: %w", err)
-		conditions.MarkFalse(obj, meta.ReadyCondition, imagev1.UpdateFailedReason, "%s", e)
+		// The location of a failure in a file is in the message as well, but
+		// it's logged in a structured way for the logs to be searched.
+		var fileErr *update.FileError
+		if errors.As(err, &fileErr) {
+			ctrl.LoggerFrom(ctx).Error(err, "failed to apply policies to file", "file", fileErr.Path,
+				"document", fileErr.Document, "line", fileErr.Line, "setter", fileErr.Setter)
+		}
+		// Exceeding the maximum semver jump needs the field to be updated
+		// manually.
+		if errors.Is(err, update.ErrSemverJumpExceeded) {
+			conditions.MarkStalled(obj, imagev1.SemverJumpExceededReason, "%s", e)
+			result, retErr = ctrl.Result{}, nil
+			return
+		}
+		// Modifying files of other owners needs the ownership or the owner
+		// to be changed.
+		if errors.Is(err, update.ErrNotOwned) {
+			conditions.MarkStalled(obj, imagev1.OwnershipMismatchReason, "%s", e)
+			result, retErr = ctrl.Result{}, nil
+			return
+		}
+		reason := imagev1.UpdateFailedReason
+		// A conflict can be resolved by a new commit in the remote
+		// repository, so it's retried.
+		if errors.Is(err, update.ErrConflict) {
+			reason = imagev1.UpdateConflictReason
+		}
+		// The files which couldn't be processed by an atomic update can be
+		// fixed by a new commit as well.
+		if errors.Is(err, policy.ErrIncompleteUpdate) {
+			reason = imagev1.UpdateIncompleteReason
+		}
+		conditions.MarkFalse(obj, meta.ReadyCondition, reason, "%s", e)
 		result, retErr = ctrl.Result{}, e
 		return
 	}
 	// Update any stale Ready=False condition from apply policies failure.
-	if conditions.HasAnyReason(obj, meta.ReadyCondition, imagev1.InvalidUpdateStrategyReason, imagev1.UpdateFailedReason) {
-		conditions.MarkUnknown(obj, meta.ReadyCondition, meta.ProgressingReason, "reconciliation in progress")
+	resetStaleReadyCondition(obj, imagev1.InvalidUpdateStrategyReason, imagev1.UpdateConflictReason,
+		imagev1.UpdateIncompleteReason, imagev1.UpdateFailedReason)
+
+	// Report the policies whose images were already handled, but aren't in
+	// the checked out source anymore.
+	drifted := driftedPolicies(obj.Status.ObservedPolicies, observedPolicies, policyResult)
+	reportDrift(obj, drifted, commit.String())
+	if r.PushMetrics != nil {
+		r.PushMetrics.RecordDriftedPolicies(obj.Name, obj.Namespace, len(drifted))
 	}
 
-	if len(policyResult.FileChanges) == 0 {
+	// Report the markers of the policies of other namespaces, which are
+	// never updated.
+	if reportCrossNamespaceMarkers(obj, crossNamespaceMarkers(policyResult, obj.Namespace)) {
+		eventLogf(ctx, r.EventRecorder, obj, corev1.EventTypeWarning, imagev1.PolicyNamespaceMismatchReason,
+			"%s", conditions.GetMessage(obj, imagev1.CrossNamespaceMarkersCondition))
+	}
+
+	// Report the files which were skipped, e.g. Helm chart templates.
+	if len(policyResult.SkippedFiles) > 0 {
+		ctrl.LoggerFrom(ctx).Info("skipped files with markers which can't be updated", "files", policyResult.SkippedFiles)
+	}
+
+	// Report the changes of the objects opting out of the updates.
+	if len(policyResult.IgnoredChanges) > 0 {
+		eventLogf(ctx, r.EventRecorder, obj, corev1.EventTypeNormal, imagev1.ObjectsIgnoredReason,
+			"%s", ignoredMessage(policyResult))
+	}
+
+	// Report the conflicting changes, which were either made or skipped.
+	if len(policyResult.FileConflicts) > 0 {
+		eventLogf(ctx, r.EventRecorder, obj, corev1.EventTypeWarning, imagev1.UpdateConflictReason,
+			"%s", conflictsMessage(policyResult, obj.Spec.Update.ConflictPolicy))
+	}
+
+	summary.FilesChanged = len(policyResult.FileChanges)
+	if policyResult.LockFile != "" {
+		summary.FilesChanged++
+	}
+	// The branches of the routes may need the changes already made in the
+	// checkout.
+	if !policyResult.HasChanges() && !sm.Routes() {
+		summary.Push = imagev1.PushOutcomeNothingToPush
 		// Remove any stale Ready condition, most likely False, set above. Its
 		// value is derived from the overall result of the reconciliation in the
 		// deferred block at the very end.
@@ -447,15 +1128,54 @@ func (r *ImageUpdateAutomationReconciler) reconcile(ctx context.Context, sp *pat
 		// Persist observations.
 		obj.Status.ObservedSourceRevision = commit.String()
 		obj.Status.ObservedPolicies = observedPolicies
+		if fullSyncRequested {
+			obj.Status.SetLastHandledRequest(imagev1.FullSyncAnnotation, fullSyncVal)
+		}
 
 		result, retErr = ctrl.Result{RequeueAfter: obj.GetRequeueAfter()}, nil
+		// The revoked images were already reverted in the source, the other
+		// policies are applied right away.
+		if len(revoked) > 0 {
+			ctrl.LoggerFrom(ctx).Info("revoked images already reverted in the source", "revoked", revoked)
+			result = ctrl.Result{Requeue: true}
+		}
 		return
 	}
 
+	// Merge the changes into the base branch when they're pushed onto
+	// another branch than the checkout. A conflict can be resolved by a new
+	// commit in the remote repository, so it's retried.
+	if err := sm.MergeOntoBase(ctx); err != nil {
+		e := fmt.Errorf("failed to merge the changes into the base branch: %w", err)
+		reason := imagev1.GitOperationFailedReason
+		if errors.Is(err, source.ErrBaseConflict) {
+			reason = imagev1.UpdateConflictReason
+		} else {
+			failureReason = gitFailureReason(err, imagev1.CloneFailedReason)
+		}
+		conditions.MarkFalse(obj, meta.ReadyCondition, reason, "%s", e)
+		result, retErr = ctrl.Result{}, e
+		return
+	}
+
+	// Validate the changed manifests before committing them. Invalid
+	// manifests can be fixed by a new commit in the remote repository, so
+	// the validation is retried.
+	if obj.Spec.Update.Validate {
+		if err := policy.ValidateChanges(sm.WorkDirectory(), obj, policyResult, applyOpts...); err != nil {
+			e := fmt.Errorf("failed to validate the changed manifests: %w", err)
+			conditions.MarkFalse(obj, meta.ReadyCondition, imagev1.ValidationFailedReason, "%s", e)
+			result, retErr = ctrl.Result{}, e
+			return
+		}
+	}
+	// Update any stale Ready=False condition from validation failure.
+	resetStaleReadyCondition(obj, imagev1.ValidationFailedReason)
+
 	// Build push config.
 	pushCfg := []source.PushConfig{}
 	// Enable force only when branch is changed for push.
-	if r.features[features.GitForcePushBranch] && sm.SwitchBranch() {
+	if forcePush(obj, r.features[features.GitForcePushBranch]) && sm.SwitchBranch() {
 		pushCfg = append(pushCfg, source.WithPushConfigForce())
 	}
 	// Include any push options.
@@ -463,30 +1183,99 @@ func (r *ImageUpdateAutomationReconciler) reconcile(ctx context.Context, sp *pat
 		pushCfg = append(pushCfg, source.WithPushConfigOptions(obj.Spec.GitSpec.Push.Options))
 	}
 
-	pushResult, err = sm.CommitAndPush(ctx, obj, policyResult, pushCfg...)
+	// Commit and push the changes, all together, to the branch of each
+	// policy or to the branch of each route.
+	var pushes []policyPush
+	switch {
+	case sm.PerPolicyBranches():
+		pushes, err = pushPolicyBranches(ctx, sm, obj, policies, policyResult, commit, applyOpts, pushCfg)
+	case sm.Routes():
+		pushes, err = pushRoutes(ctx, sm, obj, policies, commit, applyOpts, pushCfg)
+	default:
+		var pr *source.PushResult
+		if pr, err = sm.CommitAndPush(ctx, obj, policyResult, pushCfg...); pr != nil {
+			pushes = append(pushes, policyPush{result: pr, changes: policyResult})
+		}
+	}
 	if err != nil {
+		summary.Push = imagev1.PushOutcomeFailed
+		if errors.Is(err, source.ErrInvalidTemplate) {
+			conditions.MarkStalled(obj, imagev1.InvalidTemplateReason, "%s", err)
+			result, retErr = ctrl.Result{}, nil
+			return
+		}
+		if _, ok := source.IsRepositoryMoved(err); ok {
+			conditions.MarkStalled(obj, imagev1.RepositoryMovedReason, "%s", err)
+			result, retErr = ctrl.Result{}, nil
+			return
+		}
+		if errors.Is(err, source.ErrSigningRequired) {
+			conditions.MarkStalled(obj, imagev1.SigningRequiredReason, "%s", err)
+			result, retErr = ctrl.Result{}, nil
+			return
+		}
+		// No signing key, or several of them, may match a per-policy branch.
+		if errors.Is(err, source.ErrInvalidSourceConfiguration) {
+			if source.IsSigningError(err) {
+				failureReason = imagev1.SignFailedReason
+			}
+			conditions.MarkStalled(obj, imagev1.InvalidSourceConfigReason, "%s", err)
+			result, retErr = ctrl.Result{}, nil
+			return
+		}
+		// The push is retried from a new checkout of the moved branch.
+		if errors.Is(err, source.ErrRemoteChanged) {
+			if r.PushMetrics != nil {
+				r.PushMetrics.RecordRemoteChange(obj.Name, obj.Namespace, RemoteChangeAborted)
+			}
+			e := fmt.Errorf("failed to update source: %w", err)
+			conditions.MarkFalse(obj, meta.ReadyCondition, imagev1.RemoteChangedReason, "%s", e)
+			result, retErr = ctrl.Result{}, e
+			return
+		}
 		e := fmt.Errorf("failed to update source: %w", err)
 		conditions.MarkFalse(obj, meta.ReadyCondition, imagev1.GitOperationFailedReason, "%s", e)
+		failureReason = gitFailureReason(err, imagev1.PushFailedReason)
 		result, retErr = ctrl.Result{}, e
 		return
 	}
 	// Update any stale Ready=False condition from commit and push failure.
-	if conditions.HasAnyReason(obj, meta.ReadyCondition, imagev1.GitOperationFailedReason) {
-		conditions.MarkUnknown(obj, meta.ReadyCondition, meta.ProgressingReason, "reconciliation in progress")
-	}
+	resetStaleReadyCondition(obj, imagev1.InvalidTemplateReason, imagev1.GitOperationFailedReason,
+		imagev1.RemoteChangedReason)
 
-	if pushResult == nil {
-		// NOTE: This should not happen. This exists as a legacy behavior from
+	if len(pushes) == 0 {
+		// NOTE: This should not happen, unless the branches of the routes
+		// already have the changes. This exists as a legacy behavior from
 		// the old implementation where no commit is made due to no stagged
 		// files. If nothing is pushed, the repository is up-to-date. Persist
 		// observations and return with successful result.
+		summary.Push = imagev1.PushOutcomeNothingToPush
 		conditions.Delete(obj, meta.ReadyCondition)
 		obj.Status.ObservedSourceRevision = commit.String()
 		obj.Status.ObservedPolicies = observedPolicies
+		if fullSyncRequested {
+			obj.Status.SetLastHandledRequest(imagev1.FullSyncAnnotation, fullSyncVal)
+		}
 		result, retErr = ctrl.Result{RequeueAfter: obj.GetRequeueAfter()}, nil
 		return
 	}
 
+	summary.Push = imagev1.PushOutcomePushed
+	for _, push := range pushes {
+		pushResults = append(pushResults, push.result)
+		if omitted := push.result.TruncatedMessageBytes(); omitted > 0 {
+			summary.MessageTruncated = true
+			eventLogf(ctx, r.EventRecorder, obj, corev1.EventTypeWarning, imagev1.CommitMessageTruncatedReason,
+				"truncated the message of commit '%s' by %d bytes to the maximum of %d bytes",
+				push.result.Commit().Hash.String(), omitted, obj.Spec.GitSpec.Commit.MaxMessageBytes)
+		}
+	}
+	if r.PushMetrics != nil {
+		recordPushes(r.PushMetrics, obj.Name, obj.Namespace, pushes)
+	}
+	// The last push is reported as the last pushed commit.
+	pushResult := pushResults[len(pushResults)-1]
+
 	// Persist observations.
 	obj.Status.ObservedSourceRevision = pushResult.Commit().String()
 	// If the push branch is different, store the checkout branch commit as the
@@ -495,52 +1284,284 @@ func (r *ImageUpdateAutomationReconciler) reconcile(ctx context.Context, sp *pat
 		obj.Status.ObservedSourceRevision = commit.String()
 	}
 	obj.Status.ObservedPolicies = observedPolicies
+	if fullSyncRequested {
+		obj.Status.SetLastHandledRequest(imagev1.FullSyncAnnotation, fullSyncVal)
+	}
 	obj.Status.LastPushCommit = pushResult.Commit().Hash.String()
 	obj.Status.LastPushTime = pushResult.Time()
+	obj.Status.LastPushTag = pushResult.Tag()
+	obj.Status.LastPolicyPushes = nil
+	if sm.PerPolicyBranches() {
+		for _, push := range pushes {
+			obj.Status.LastPolicyPushes = append(obj.Status.LastPolicyPushes, imagev1.PolicyPush{
+				Policy: push.policy,
+				Branch: push.result.Branch(),
+				Commit: push.result.Commit().Hash.String(),
+			})
+		}
+	}
+	obj.Status.LastRoutePushes = nil
+	if sm.Routes() {
+		for _, push := range pushes {
+			obj.Status.LastRoutePushes = append(obj.Status.LastRoutePushes, imagev1.RoutePush{
+				Route:  push.route,
+				Branch: push.result.Branch(),
+				Commit: push.result.Commit().Hash.String(),
+			})
+		}
+	}
+	obj.Status.LastRemotePushes = remotePushes(pushResults)
+	for _, push := range obj.Status.LastRemotePushes {
+		if push.Error != "" {
+			eventLogf(ctx, r.EventRecorder, obj, corev1.EventTypeWarning, imagev1.RemotePushFailedReason,
+				"failed to push to additional remote '%s': %s", push.Name, push.Error)
+		}
+	}
+	r.verifyPushedSigning(ctx, obj, sm, obj.Status.LastPushCommit)
+	obj.Status.LastAuthMethod = sm.AuthMethod()
+	// The credentials are only recorded when there are fallback credentials
+	// to choose from.
+	obj.Status.LastPushCredentials = ""
+	if push := obj.Spec.GitSpec.Push; push != nil && push.FallbackSecretRef != nil {
+		obj.Status.LastPushCredentials = sm.PushCredentials()
+		if obj.Status.LastPushCredentials == imagev1.PushCredentialsFallback {
+			eventLogf(ctx, r.EventRecorder, obj, corev1.EventTypeWarning, imagev1.FallbackCredentialsUsedReason,
+				"pushed with the fallback credentials of the Secret '%s', the remote rejected the credentials of the GitRepository",
+				push.FallbackSecretRef.Name)
+		}
+	}
+	ctrl.LoggerFrom(ctx).V(logger.DebugLevel).Info("pushed changes", "commit", obj.Status.LastPushCommit,
+		"authMethod", obj.Status.LastAuthMethod)
+
+	for _, push := range pushes {
+		r.setCommitStatus(ctx, obj, push.result, push.changes)
+		r.notifyPostPushHooks(ctx, obj, push.result, push.changes)
+		r.exportChanges(ctx, obj, sm, updatePath, push.result, push.changes)
+	}
 
 	// Remove any stale Ready condition, most likely False, set above. Its value
 	// is derived from the overall result of the reconciliation in the deferred
 	// block at the very end.
 	conditions.Delete(obj, meta.ReadyCondition)
 	result, retErr = ctrl.Result{RequeueAfter: obj.GetRequeueAfter()}, nil
+	// The other policies are applied right after the revert.
+	if len(revoked) > 0 {
+		r.reportReverts(ctx, obj, revoked, obj.Status.LastPushCommit)
+		result = ctrl.Result{Requeue: true}
+	}
 	return
 }
 
+// policyPush is a successful push of the changes of the policies.
+type policyPush struct {
+	// policy is the name of the policy whose changes were pushed to a
+	// branch of their own, and route the name of the route whose changes
+	// were pushed to its branch. They're empty when the changes of all the
+	// policies were pushed together.
+	policy  string
+	route   string
+	result  *source.PushResult
+	changes update.ResultV2
+}
+
+// recordPushes records the given pushes of the ImageUpdateAutomation with the
+// given name and namespace in the given metrics, along with the policy updates
+// they pushed. The updates which weren't pushed aren't recorded.
+func recordPushes(m *PushMetrics, name, namespace string, pushes []policyPush) {
+	for _, push := range pushes {
+		m.RecordPush(name, namespace, push.result.Time().Time)
+		m.RecordGitOperationDuration(name, namespace, source.GitOperationPush, push.result.Duration())
+		m.RecordPolicyUpdates(name, namespace, push.changes)
+		if push.result.Rebased() {
+			m.RecordRemoteChange(name, namespace, RemoteChangeRebased)
+		}
+		for _, rp := range push.result.RemotePushes() {
+			if operation, ok := source.IsDeadlineExceeded(rp.Err); ok {
+				m.RecordDeadlineExceeded(name, namespace, operation)
+			}
+		}
+	}
+}
+
+// pushPolicyBranches commits and pushes the changes of each updated policy to
+// a branch of its own, created from the checked out commit. The changes of all
+// the policies are already made in the worktree, they are discarded and made
+// again for each policy.
+func pushPolicyBranches(ctx context.Context, sm *source.SourceManager, obj *imagev1.ImageUpdateAutomation,
+	policies []imagev1_reflect.ImagePolicy, policyResult update.ResultV2, base *git.Commit,
+	applyOpts []policy.ApplyOption, pushCfg []source.PushConfig) ([]policyPush, error) {
+	var updated []string
+	for _, ref := range policyResult.ImageResult.Images() {
+		if name := ref.Policy().Name; !slices.Contains(updated, name) {
+			updated = append(updated, name)
+		}
+	}
+	slices.Sort(updated)
+
+	var pushes []policyPush
+	for _, name := range updated {
+		// Stop between the branches when the reconciliation is cancelled,
+		// e.g. on shutdown.
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		i := slices.IndexFunc(policies, func(p imagev1_reflect.ImagePolicy) bool { return p.Name == name })
+		if i < 0 {
+			continue
+		}
+		branch, err := sm.PolicyBranch(client.ObjectKeyFromObject(&policies[i]))
+		if err != nil {
+			return nil, err
+		}
+		if err := sm.CheckoutPolicyBranch(branch, base); err != nil {
+			return nil, err
+		}
+		changes, err := policy.ApplyPolicies(ctx, sm.WorkDirectory(), obj, policies[i:i+1], applyOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply policy '%s': %w", name, err)
+		}
+		pr, err := sm.CommitAndPush(ctx, obj, changes, pushCfg...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to push the changes of policy '%s': %w", name, err)
+		}
+		if pr != nil {
+			pushes = append(pushes, policyPush{policy: name, result: pr, changes: changes})
+		}
+	}
+	return pushes, nil
+}
+
+// notifyPostPushHooks notifies the post-push hooks of the object of the given
+// successful push. The failures are reported with events, as the push can't be
+// undone.
+func (r *ImageUpdateAutomationReconciler) notifyPostPushHooks(ctx context.Context, obj *imagev1.ImageUpdateAutomation,
+	pushResult *source.PushResult, policyResult update.ResultV2) {
+	if len(obj.Spec.PostPushHooks) == 0 {
+		return
+	}
+
+	images := []string{}
+	for _, ref := range policyResult.ImageResult.Images() {
+		images = append(images, ref.String())
+	}
+	slices.Sort(images)
+	notification := hook.Notification{
+		Automation: client.ObjectKeyFromObject(obj).String(),
+		Branch:     pushResult.Branch(),
+		Commit:     pushResult.Commit().Hash.String(),
+		Tag:        pushResult.Tag(),
+		Images:     images,
+	}
+	for _, h := range obj.Spec.PostPushHooks {
+		notifier, err := hook.NewNotifier(ctx, r.Client, obj.GetNamespace(), h)
+		if err == nil {
+			err = notifier.Notify(ctx, notification)
+		}
+		if err != nil {
+			eventLogf(ctx, r.EventRecorder, obj, corev1.EventTypeWarning, imagev1.PostPushHookFailedReason,
+				"failed to notify post-push hook '%s': %s", h.Name, err)
+		}
+	}
+}
+
+// exportChanges exports the report of the changes of the given successful
+// push, if an exporter is configured. The failures are reported with events,
+// as the push can't be undone.
+func (r *ImageUpdateAutomationReconciler) exportChanges(ctx context.Context, obj *imagev1.ImageUpdateAutomation,
+	sm *source.SourceManager, updatePath string, pushResult *source.PushResult, policyResult update.ResultV2) {
+	if r.ChangesExporter == nil {
+		return
+	}
+
+	url, _ := sm.PushTarget()
+	report := export.Report{
+		SchemaVersion: export.SchemaVersion,
+		Automation:    client.ObjectKeyFromObject(obj).String(),
+		Source:        sm.SourceKey().String(),
+		URL:           url,
+		Branch:        pushResult.Branch(),
+		Commit:        pushResult.Commit().Hash.String(),
+		Tag:           pushResult.Tag(),
+		Time:          pushResult.Time().UTC().Format(time.RFC3339),
+		Changes:       export.Changes(policy.UpdatePathBase(updatePath), policyResult),
+	}
+	if err := r.ChangesExporter.Export(ctx, report); err != nil {
+		eventLogf(ctx, r.EventRecorder, obj, corev1.EventTypeWarning, imagev1.ChangesExportFailedReason,
+			"failed to export the changes of commit '%s': %s", report.Commit, err)
+	}
+}
+
 // reconcileDelete handles the deletion of the object.
 func (r *ImageUpdateAutomationReconciler) reconcileDelete(obj *imagev1.ImageUpdateAutomation) (ctrl.Result, error) {
 	// Remove our finalizer from the list.
 	controllerutil.RemoveFinalizer(obj, imagev1.ImageUpdateAutomationFinalizer)
 
+	if r.PushMetrics != nil {
+		r.PushMetrics.Delete(obj.Name, obj.Namespace)
+	}
+
 	// Stop reconciliation as the object is being deleted.
 	return ctrl.Result{}, nil
 }
 
-// getPolicies returns list of policies in the given namespace that have latest
-// image.
-func getPolicies(ctx context.Context, kclient client.Client, namespace string, selector *metav1.LabelSelector) ([]imagev1_reflect.ImagePolicy, error) {
+// getPolicies returns the policies of the namespace selected by the selector
+// which have a latest image, along with the other policies of the namespace,
+// by the reason they were skipped for. The policies are listed by pages of
+// the given size, if not zero.
+func getPolicies(ctx context.Context, kclient client.Client, namespace string,
+	selector *metav1.LabelSelector, pageSize int64) ([]imagev1_reflect.ImagePolicy, skippedPolicies, error) {
 	policySelector := labels.Everything()
 	var err error
 	if selector != nil {
 		if policySelector, err = metav1.LabelSelectorAsSelector(selector); err != nil {
-			return nil, fmt.Errorf("%w: %w", errParsePolicySelector, err)
+			return nil, nil, fmt.Errorf("%w: %w", errParsePolicySelector, err)
 		}
 	}
 
-	var policies imagev1_reflect.ImagePolicyList
-	if err := kclient.List(ctx, &policies, &client.ListOptions{Namespace: namespace, LabelSelector: policySelector}); err != nil {
-		return nil, fmt.Errorf("failed to list policies: %w", err)
+	// List all the policies of the namespace to report the ones which
+	// aren't selected.
+	policies, err := listPolicies(ctx, kclient, namespace, pageSize)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	readyPolicies := []imagev1_reflect.ImagePolicy{}
-	for _, policy := range policies.Items {
+	skipped := skippedPolicies{}
+	for _, policy := range policies {
+		if !policySelector.Matches(labels.Set(policy.GetLabels())) {
+			skipped.add(skipReasonNotSelected, policy.Name)
+			continue
+		}
 		// Ignore the policies that don't have a latest image.
 		if policy.Status.LatestImage == "" {
+			skipped.add(skipReasonNoLatestImage, policy.Name)
 			continue
 		}
 		readyPolicies = append(readyPolicies, policy)
 	}
 
-	return readyPolicies, nil
+	return readyPolicies, skipped, nil
+}
+
+// listPolicies lists the policies of the namespace, by pages of the given
+// size if not zero. The cache of the manager doesn't paginate, it must list
+// them all at once.
+func listPolicies(ctx context.Context, kclient client.Client, namespace string, pageSize int64) ([]imagev1_reflect.ImagePolicy, error) {
+	var policies []imagev1_reflect.ImagePolicy
+	var list imagev1_reflect.ImagePolicyList
+	for {
+		opts := []client.ListOption{client.InNamespace(namespace)}
+		if pageSize > 0 {
+			opts = append(opts, client.Limit(pageSize), client.Continue(list.Continue))
+		}
+		if err := kclient.List(ctx, &list, opts...); err != nil {
+			return nil, fmt.Errorf("failed to list policies: %w", err)
+		}
+		policies = append(policies, list.Items...)
+		if list.Continue == "" {
+			return policies, nil
+		}
+	}
 }
 
 // observedPolicies takes a list of ImagePolicies and returns an
@@ -548,6 +1569,14 @@ func getPolicies(ctx context.Context, kclient client.Client, namespace string, s
 func observedPolicies(policies []imagev1_reflect.ImagePolicy) (imagev1.ObservedPolicies, error) {
 	observedPolicies := imagev1.ObservedPolicies{}
 	for _, policy := range policies {
+		// The image of a policy pinned to a digest has no tag.
+		if name, digest, ok := strings.Cut(policy.Status.LatestImage, "@"); ok {
+			observedPolicies[policy.Name] = imagev1.ImageRef{
+				Name:   name,
+				Digest: digest,
+			}
+			continue
+		}
 		parts := strings.SplitN(policy.Status.LatestImage, ":", 2)
 		if len(parts) != 2 {
 			return nil, fmt.Errorf("failed parsing image: %s", policy.Status.LatestImage)
@@ -579,23 +1608,72 @@ func observedPoliciesChanged(previous, current imagev1.ObservedPolicies) bool {
 	return false
 }
 
+// conflictsMessage returns a message listing the conflicting changes of the
+// given result, handled with the given conflict policy.
+func conflictsMessage(result update.ResultV2, conflictPolicy imagev1.ConflictPolicy) string {
+	action := "overwrote"
+	if conflictPolicy == imagev1.ConflictPolicySkip {
+		action = "skipped"
+	}
+	var lines []string
+	for file, objChanges := range result.FileConflicts {
+		for _, changes := range objChanges {
+			for _, ch := range changes {
+				lines = append(lines, fmt.Sprintf("- %s: '%s' -> '%s' (%s)", file, ch.OldValue, ch.NewValue, ch.Setter))
+			}
+		}
+	}
+	slices.Sort(lines)
+	return fmt.Sprintf("%s the update of fields changed since the previous update:\n%s", action, strings.Join(lines, "\n"))
+}
+
+// ignoredMessage returns a message listing the changes of the given result
+// which weren't made because their objects opt out of the updates.
+func ignoredMessage(result update.ResultV2) string {
+	var lines []string
+	for file, objChanges := range result.IgnoredChanges {
+		for oid, changes := range objChanges {
+			for _, ch := range changes {
+				lines = append(lines, fmt.Sprintf("- %s: %s/%s '%s' -> '%s' (%s)", file, oid.Kind, oid.Name, ch.OldValue, ch.NewValue, ch.Setter))
+			}
+		}
+	}
+	slices.Sort(lines)
+	return fmt.Sprintf("skipped the update of objects annotated with %s:\n%s", update.IgnoreAnnotation, strings.Join(lines, "\n"))
+}
+
 // notify emits notifications and events based on the state of the object and
 // the given PushResult. It tries to always send the PushResult commit message
 // if there has been any update. Otherwise, a generic up-to-date message. In
 // case of any failure, the failure message is read from the Ready condition and
 // included in the event.
-func (r *ImageUpdateAutomationReconciler) notify(ctx context.Context, oldObj, newObj conditions.Setter, result *source.PushResult, syncNeeded bool) {
+func (r *ImageUpdateAutomationReconciler) notify(ctx context.Context, oldObj, newObj conditions.Setter, results []*source.PushResult,
+	syncNeeded bool, skipped skippedPolicies, failureReason string) {
+	// Tell which policies were left out of the sync after the result, for
+	// the users wondering why an image never lands.
+	defer func() {
+		if syncNeeded && len(skipped) > 0 {
+			eventLogf(ctx, r.EventRecorder, newObj, eventv1.EventTypeTrace, imagev1.PoliciesSkippedReason,
+				"skipped policies: %s", skipped)
+		}
+	}()
+
 	// Use the Ready message as the notification message by default.
 	ready := conditions.Get(newObj, meta.ReadyCondition)
 	msg := ready.Message
 
-	// If there's a PushResult, use the summary as the notification message.
-	if result != nil {
-		msg = result.Summary()
+	// If there are PushResults, use their summaries as the notification
+	// message.
+	if len(results) > 0 {
+		summaries := make([]string, 0, len(results))
+		for _, result := range results {
+			summaries = append(summaries, result.Summary())
+		}
+		msg = strings.Join(summaries, "\n\n")
 	}
 
 	// Was ready before and is ready now, with new push result,
-	if conditions.IsReady(oldObj) && conditions.IsReady(newObj) && result != nil {
+	if conditions.IsReady(oldObj) && conditions.IsReady(newObj) && len(results) > 0 {
 		eventLogf(ctx, r.EventRecorder, newObj, corev1.EventTypeNormal, ready.Reason, msg)
 		return
 	}
@@ -607,9 +1685,10 @@ func (r *ImageUpdateAutomationReconciler) notify(ctx context.Context, oldObj, ne
 		eventLogf(ctx, r.EventRecorder, newObj, corev1.EventTypeNormal, ready.Reason, msg)
 		return
 	}
-	// Not ready, failed. Use the failure message from ready condition.
+	// Not ready, failed. Use the failure message from ready condition, with
+	// the most specific reason of the failure.
 	if !conditions.IsReady(newObj) {
-		eventLogf(ctx, r.EventRecorder, newObj, corev1.EventTypeWarning, ready.Reason, ready.Message)
+		eventLogf(ctx, r.EventRecorder, newObj, corev1.EventTypeWarning, warningReason(ready.Reason, failureReason), ready.Message)
 		return
 	}
 
@@ -0,0 +1,55 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fluxcd/cli-utils/pkg/kstatus/polling"
+	runtimeclient "github.com/fluxcd/pkg/runtime/client"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+// serviceAccountName returns the name of the ServiceAccount impersonated for
+// the automation, or an empty string if none is.
+func (r *ImageUpdateAutomationReconciler) serviceAccountName(obj *imagev1.ImageUpdateAutomation) string {
+	if obj.Spec.ServiceAccountName != "" {
+		return obj.Spec.ServiceAccountName
+	}
+	return r.DefaultServiceAccount
+}
+
+// tenantClient returns the client the policies and the source of the
+// automation are read with. It impersonates the ServiceAccount of the
+// automation, or the default ServiceAccount, for the API server to enforce
+// the isolation of the tenants. Without a ServiceAccount, the client of the
+// controller is returned.
+func (r *ImageUpdateAutomationReconciler) tenantClient(ctx context.Context, obj *imagev1.ImageUpdateAutomation) (client.Client, error) {
+	if r.serviceAccountName(obj) == "" {
+		return r.Client, nil
+	}
+	impersonator := runtimeclient.NewImpersonator(r.Client, nil, polling.Options{}, nil,
+		runtimeclient.KubeConfigOptions{}, r.DefaultServiceAccount, obj.Spec.ServiceAccountName, obj.GetNamespace())
+	c, _, err := impersonator.GetClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
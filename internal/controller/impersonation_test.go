@@ -0,0 +1,50 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+func Test_serviceAccountName(t *testing.T) {
+	g := NewWithT(t)
+
+	r := &ImageUpdateAutomationReconciler{}
+	obj := &imagev1.ImageUpdateAutomation{}
+	g.Expect(r.serviceAccountName(obj)).To(BeEmpty())
+
+	r.DefaultServiceAccount = "default-sa"
+	g.Expect(r.serviceAccountName(obj)).To(Equal("default-sa"))
+
+	obj.Spec.ServiceAccountName = "tenant-sa"
+	g.Expect(r.serviceAccountName(obj)).To(Equal("tenant-sa"))
+}
+
+func Test_tenantClient_noServiceAccount(t *testing.T) {
+	g := NewWithT(t)
+
+	r := &ImageUpdateAutomationReconciler{Client: fakeclient.NewClientBuilder().Build()}
+	c, err := r.tenantClient(context.TODO(), &imagev1.ImageUpdateAutomation{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(c).To(BeIdenticalTo(r.Client))
+}
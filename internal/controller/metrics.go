@@ -0,0 +1,211 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	crtlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/fluxcd/image-automation-controller/pkg/update"
+)
+
+// PushMetrics records the pushes of the ImageUpdateAutomations, for alerting
+// on automations which haven't pushed any update for a while, and the updates
+// of each of their policies, to see which applications are updated the most.
+type PushMetrics struct {
+	lastPushGauge        *prometheus.GaugeVec
+	pushesCounter        *prometheus.CounterVec
+	policyUpdatesCounter *prometheus.CounterVec
+	policyFieldsCounter  *prometheus.CounterVec
+	remoteChangesCounter *prometheus.CounterVec
+	deadlinesCounter     *prometheus.CounterVec
+	driftedPoliciesGauge *prometheus.GaugeVec
+	gitDurationHistogram *prometheus.HistogramVec
+}
+
+// MustMakePushMetrics returns a new PushMetrics with its collectors
+// registered in the controller-runtime metrics registry, which panics if they
+// are already registered.
+func MustMakePushMetrics() *PushMetrics {
+	m := NewPushMetrics()
+	crtlmetrics.Registry.MustRegister(m.Collectors()...)
+	return m
+}
+
+// NewPushMetrics returns a new PushMetrics.
+func NewPushMetrics() *PushMetrics {
+	return &PushMetrics{
+		lastPushGauge: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "image_automation_last_push_timestamp_seconds",
+				Help: "The time in seconds since the epoch of the last push of an ImageUpdateAutomation.",
+			},
+			[]string{"name", "namespace"},
+		),
+		pushesCounter: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "image_automation_pushes_total",
+				Help: "The number of commits pushed by an ImageUpdateAutomation.",
+			},
+			[]string{"name", "namespace"},
+		),
+		policyUpdatesCounter: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "image_automation_policy_updates_total",
+				Help: "The number of updates of an ImageUpdateAutomation which changed fields marked with an ImagePolicy.",
+			},
+			[]string{"name", "namespace", "policy"},
+		),
+		policyFieldsCounter: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "image_automation_policy_changed_fields_total",
+				Help: "The number of fields marked with an ImagePolicy changed by the updates of an ImageUpdateAutomation.",
+			},
+			[]string{"name", "namespace", "policy"},
+		),
+		remoteChangesCounter: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "image_automation_remote_changes_total",
+				Help: "The number of pushes of an ImageUpdateAutomation which found the push branch moved on the remote since the checkout.",
+			},
+			[]string{"name", "namespace", "outcome"},
+		),
+		deadlinesCounter: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "image_automation_git_operations_deadline_exceeded_total",
+				Help: "The number of Git operations of an ImageUpdateAutomation cancelled because they outlived the timeout of the source.",
+			},
+			[]string{"name", "namespace", "operation"},
+		),
+		driftedPoliciesGauge: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "image_automation_drifted_policies",
+				Help: "The number of policies of an ImageUpdateAutomation whose latest images were already handled, but aren't in the checked out source.",
+			},
+			[]string{"name", "namespace"},
+		),
+		gitDurationHistogram: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "image_automation_git_operation_duration_seconds",
+				Help:    "The duration in seconds of the clones and pushes of an ImageUpdateAutomation, by Git operation.",
+				Buckets: prometheus.ExponentialBuckets(0.1, 2, 12),
+			},
+			[]string{"name", "namespace", "operation"},
+		),
+	}
+}
+
+// Collectors returns the Prometheus collectors of the PushMetrics.
+func (m *PushMetrics) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.lastPushGauge,
+		m.pushesCounter,
+		m.policyUpdatesCounter,
+		m.policyFieldsCounter,
+		m.remoteChangesCounter,
+		m.deadlinesCounter,
+		m.driftedPoliciesGauge,
+		m.gitDurationHistogram,
+	}
+}
+
+// RecordPush records a push of the ImageUpdateAutomation with the given name
+// and namespace at the given time.
+func (m *PushMetrics) RecordPush(name, namespace string, t time.Time) {
+	m.lastPushGauge.WithLabelValues(name, namespace).Set(float64(t.Unix()))
+	m.pushesCounter.WithLabelValues(name, namespace).Inc()
+}
+
+// RecordPolicyUpdates records the changes of the given update result pushed by
+// the ImageUpdateAutomation with the given name and namespace, per policy:
+// each policy with changes is counted as updated once, along with the number
+// of fields it changed.
+func (m *PushMetrics) RecordPolicyUpdates(name, namespace string, result update.ResultV2) {
+	fields := map[string]int{}
+	for _, objChanges := range result.FileChanges {
+		for _, changes := range objChanges {
+			for _, ch := range changes {
+				fields[setterPolicy(ch.Setter)]++
+			}
+		}
+	}
+	for policy, n := range fields {
+		m.policyUpdatesCounter.WithLabelValues(name, namespace, policy).Inc()
+		m.policyFieldsCounter.WithLabelValues(name, namespace, policy).Add(float64(n))
+	}
+}
+
+// Outcomes of the pushes which found the push branch moved on the remote.
+const (
+	RemoteChangeAborted = "aborted"
+	RemoteChangeRebased = "rebased"
+)
+
+// RecordRemoteChange records a push of the ImageUpdateAutomation with the
+// given name and namespace which found the push branch moved on the remote
+// since the checkout, with the given outcome, e.g. RemoteChangeAborted.
+func (m *PushMetrics) RecordRemoteChange(name, namespace, outcome string) {
+	m.remoteChangesCounter.WithLabelValues(name, namespace, outcome).Inc()
+}
+
+// RecordDeadlineExceeded records a Git operation of the ImageUpdateAutomation
+// with the given name and namespace cancelled because it outlived the timeout
+// of the source, e.g. source.GitOperationPush.
+func (m *PushMetrics) RecordDeadlineExceeded(name, namespace, operation string) {
+	m.deadlinesCounter.WithLabelValues(name, namespace, operation).Inc()
+}
+
+// RecordDriftedPolicies records the number of drifted policies of the
+// ImageUpdateAutomation with the given name and namespace, found by its last
+// full synchronization.
+func (m *PushMetrics) RecordDriftedPolicies(name, namespace string, n int) {
+	m.driftedPoliciesGauge.WithLabelValues(name, namespace).Set(float64(n))
+}
+
+// RecordGitOperationDuration records the duration of a clone or push of the
+// ImageUpdateAutomation with the given name and namespace, e.g. with
+// source.GitOperationCheckout for a clone.
+func (m *PushMetrics) RecordGitOperationDuration(name, namespace, operation string, d time.Duration) {
+	m.gitDurationHistogram.WithLabelValues(name, namespace, operation).Observe(d.Seconds())
+}
+
+// Delete deletes the metrics of the ImageUpdateAutomation with the given name
+// and namespace.
+func (m *PushMetrics) Delete(name, namespace string) {
+	m.lastPushGauge.DeleteLabelValues(name, namespace)
+	m.pushesCounter.DeleteLabelValues(name, namespace)
+	m.driftedPoliciesGauge.DeleteLabelValues(name, namespace)
+	labels := prometheus.Labels{"name": name, "namespace": namespace}
+	m.policyUpdatesCounter.DeletePartialMatch(labels)
+	m.policyFieldsCounter.DeletePartialMatch(labels)
+	m.remoteChangesCounter.DeletePartialMatch(labels)
+	m.deadlinesCounter.DeletePartialMatch(labels)
+	m.gitDurationHistogram.DeletePartialMatch(labels)
+}
+
+// setterPolicy returns the name of the policy of the given setter, e.g.
+// "app" for "flux-system:app:tag".
+func setterPolicy(setter string) string {
+	parts := strings.SplitN(setter, ":", 3)
+	if len(parts) < 2 {
+		return setter
+	}
+	return parts[1]
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+
+	"github.com/fluxcd/image-automation-controller/internal/source"
+	"github.com/fluxcd/image-automation-controller/pkg/update"
+)
+
+func TestPushMetrics(t *testing.T) {
+	g := NewWithT(t)
+
+	m := NewPushMetrics()
+	pushTime := time.Date(2024, 6, 5, 9, 12, 45, 0, time.UTC)
+	m.RecordPush("test-update", "default", pushTime.Add(-time.Hour))
+	m.RecordPush("test-update", "default", pushTime)
+	m.RecordPush("other-update", "default", pushTime)
+
+	g.Expect(testutil.ToFloat64(m.lastPushGauge.WithLabelValues("test-update", "default"))).To(Equal(float64(pushTime.Unix())))
+	g.Expect(testutil.ToFloat64(m.pushesCounter.WithLabelValues("test-update", "default"))).To(Equal(float64(2)))
+	g.Expect(testutil.ToFloat64(m.pushesCounter.WithLabelValues("other-update", "default"))).To(Equal(float64(1)))
+
+	m.Delete("test-update", "default")
+	g.Expect(testutil.CollectAndCount(m.pushesCounter)).To(Equal(1))
+	g.Expect(testutil.CollectAndCount(m.lastPushGauge)).To(Equal(1))
+}
+
+func TestPushMetrics_policyUpdates(t *testing.T) {
+	g := NewWithT(t)
+
+	m := NewPushMetrics()
+	deploy := update.ObjectIdentifier{ResourceIdentifier: yaml.ResourceIdentifier{
+		TypeMeta: yaml.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		NameMeta: yaml.NameMeta{Name: "app", Namespace: "default"},
+	}}
+	var result update.ResultV2
+	result.AddChange("app.yaml", deploy,
+		update.Change{OldValue: "app:1.0.0", NewValue: "app:1.0.1", Setter: "default:app"},
+		update.Change{OldValue: "sidecar:1.0", NewValue: "sidecar:1.1", Setter: "default:sidecar"},
+	)
+	result.AddChange("values.yaml", deploy,
+		update.Change{OldValue: "1.0.0", NewValue: "1.0.1", Setter: "default:app:tag"},
+	)
+	m.RecordPolicyUpdates("test-update", "default", result)
+	m.RecordPolicyUpdates("test-update", "default", update.ResultV2{})
+	m.RecordPolicyUpdates("other-update", "default", result)
+
+	g.Expect(testutil.ToFloat64(m.policyUpdatesCounter.WithLabelValues("test-update", "default", "app"))).To(Equal(float64(1)))
+	g.Expect(testutil.ToFloat64(m.policyFieldsCounter.WithLabelValues("test-update", "default", "app"))).To(Equal(float64(2)))
+	g.Expect(testutil.ToFloat64(m.policyUpdatesCounter.WithLabelValues("test-update", "default", "sidecar"))).To(Equal(float64(1)))
+	g.Expect(testutil.ToFloat64(m.policyFieldsCounter.WithLabelValues("test-update", "default", "sidecar"))).To(Equal(float64(1)))
+
+	m.Delete("test-update", "default")
+	g.Expect(testutil.CollectAndCount(m.policyUpdatesCounter)).To(Equal(2))
+	g.Expect(testutil.CollectAndCount(m.policyFieldsCounter)).To(Equal(2))
+}
+
+func Test_recordPushes(t *testing.T) {
+	g := NewWithT(t)
+
+	deploy := update.ObjectIdentifier{ResourceIdentifier: yaml.ResourceIdentifier{
+		TypeMeta: yaml.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		NameMeta: yaml.NameMeta{Name: "app", Namespace: "default"},
+	}}
+	changes := func(setter string) update.ResultV2 {
+		var result update.ResultV2
+		result.AddChange("app.yaml", deploy, update.Change{OldValue: "1.0.0", NewValue: "1.0.1", Setter: setter})
+		return result
+	}
+	appPush, err := source.NewPushResult("auto/app", "rev1", "msg", source.WithPushResultDuration(2*time.Second))
+	g.Expect(err).ToNot(HaveOccurred())
+	sidecarPush, err := source.NewPushResult("auto/sidecar", "rev2", "msg")
+	g.Expect(err).ToNot(HaveOccurred())
+
+	m := NewPushMetrics()
+	// Nothing was pushed, e.g. the push failed.
+	recordPushes(m, "test-update", "default", nil)
+	g.Expect(testutil.CollectAndCount(m.pushesCounter)).To(Equal(0))
+	g.Expect(testutil.CollectAndCount(m.policyUpdatesCounter)).To(Equal(0))
+
+	recordPushes(m, "test-update", "default", []policyPush{
+		{policy: "app", result: appPush, changes: changes("default:app")},
+		{policy: "sidecar", result: sidecarPush, changes: changes("default:sidecar")},
+	})
+	g.Expect(testutil.ToFloat64(m.pushesCounter.WithLabelValues("test-update", "default"))).To(Equal(float64(2)))
+	g.Expect(testutil.ToFloat64(m.policyUpdatesCounter.WithLabelValues("test-update", "default", "app"))).To(Equal(float64(1)))
+	g.Expect(testutil.ToFloat64(m.policyUpdatesCounter.WithLabelValues("test-update", "default", "sidecar"))).To(Equal(float64(1)))
+	g.Expect(testutil.CollectAndCount(m.remoteChangesCounter)).To(Equal(0))
+	// The durations of both pushes are observed in the same series.
+	g.Expect(testutil.CollectAndCount(m.gitDurationHistogram)).To(Equal(1))
+}
+
+func TestPushMetrics_gitOperationDuration(t *testing.T) {
+	g := NewWithT(t)
+
+	m := NewPushMetrics()
+	m.RecordGitOperationDuration("test-update", "default", source.GitOperationCheckout, 3*time.Second)
+	m.RecordGitOperationDuration("test-update", "default", source.GitOperationPush, time.Second)
+	m.RecordGitOperationDuration("other-update", "default", source.GitOperationCheckout, time.Second)
+	g.Expect(testutil.CollectAndCount(m.gitDurationHistogram)).To(Equal(3))
+
+	m.Delete("test-update", "default")
+	g.Expect(testutil.CollectAndCount(m.gitDurationHistogram)).To(Equal(1))
+}
+
+func TestPushMetrics_remoteChanges(t *testing.T) {
+	g := NewWithT(t)
+
+	m := NewPushMetrics()
+	m.RecordRemoteChange("test-update", "default", RemoteChangeAborted)
+	m.RecordRemoteChange("test-update", "default", RemoteChangeRebased)
+	m.RecordRemoteChange("test-update", "default", RemoteChangeRebased)
+	m.RecordRemoteChange("other-update", "default", RemoteChangeAborted)
+
+	g.Expect(testutil.ToFloat64(m.remoteChangesCounter.WithLabelValues("test-update", "default", RemoteChangeAborted))).To(Equal(float64(1)))
+	g.Expect(testutil.ToFloat64(m.remoteChangesCounter.WithLabelValues("test-update", "default", RemoteChangeRebased))).To(Equal(float64(2)))
+
+	m.Delete("test-update", "default")
+	g.Expect(testutil.CollectAndCount(m.remoteChangesCounter)).To(Equal(1))
+}
+
+func TestPushMetrics_deadlineExceeded(t *testing.T) {
+	g := NewWithT(t)
+
+	m := NewPushMetrics()
+	m.RecordDeadlineExceeded("test-update", "default", source.GitOperationCheckout)
+	m.RecordDeadlineExceeded("test-update", "default", source.GitOperationPush)
+	m.RecordDeadlineExceeded("test-update", "default", source.GitOperationPush)
+	m.RecordDeadlineExceeded("other-update", "default", source.GitOperationPush)
+
+	g.Expect(testutil.ToFloat64(m.deadlinesCounter.WithLabelValues("test-update", "default", source.GitOperationCheckout))).To(Equal(float64(1)))
+	g.Expect(testutil.ToFloat64(m.deadlinesCounter.WithLabelValues("test-update", "default", source.GitOperationPush))).To(Equal(float64(2)))
+
+	m.Delete("test-update", "default")
+	g.Expect(testutil.CollectAndCount(m.deadlinesCounter)).To(Equal(1))
+}
+
+func TestPushMetrics_driftedPolicies(t *testing.T) {
+	g := NewWithT(t)
+
+	m := NewPushMetrics()
+	m.RecordDriftedPolicies("test-update", "default", 2)
+	m.RecordDriftedPolicies("other-update", "default", 0)
+	g.Expect(testutil.ToFloat64(m.driftedPoliciesGauge.WithLabelValues("test-update", "default"))).To(Equal(float64(2)))
+
+	m.RecordDriftedPolicies("test-update", "default", 0)
+	g.Expect(testutil.ToFloat64(m.driftedPoliciesGauge.WithLabelValues("test-update", "default"))).To(Equal(float64(0)))
+
+	m.Delete("test-update", "default")
+	g.Expect(testutil.CollectAndCount(m.driftedPoliciesGauge)).To(Equal(1))
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	crtlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	imagev1_reflect "github.com/fluxcd/image-reflector-controller/api/v1beta2"
+)
+
+// PolicyCache memoizes the ImagePolicies selected in each namespace with each
+// policy selector, listed from the cache of the manager, until an ImagePolicy
+// of the namespace changes. It saves listing, copying and filtering all the
+// policies of the namespace on every reconciliation of the automations.
+type PolicyCache struct {
+	mu         sync.Mutex
+	namespaces map[string]*namespacePolicies
+
+	requestsCounter *prometheus.CounterVec
+}
+
+// namespacePolicies are the policies selected in a namespace. Its generation
+// is incremented on every change of a policy of the namespace, for the
+// listings which started before a change not to be memoized.
+type namespacePolicies struct {
+	generation uint64
+	selections map[string]policySelection
+}
+
+type policySelection struct {
+	policies []imagev1_reflect.ImagePolicy
+	skipped  skippedPolicies
+}
+
+// MustMakePolicyCache returns a new PolicyCache with its metrics registered
+// in the controller-runtime metrics registry, which panics if they are
+// already registered.
+func MustMakePolicyCache() *PolicyCache {
+	c := NewPolicyCache()
+	crtlmetrics.Registry.MustRegister(c.Collectors()...)
+	return c
+}
+
+// NewPolicyCache returns a new PolicyCache.
+func NewPolicyCache() *PolicyCache {
+	return &PolicyCache{
+		namespaces: map[string]*namespacePolicies{},
+		requestsCounter: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "image_automation_policy_cache_requests_total",
+				Help: "The number of listings of the ImagePolicies of an automation, by whether they were memoized.",
+			},
+			[]string{"result"},
+		),
+	}
+}
+
+// Collectors returns the Prometheus collectors of the PolicyCache.
+func (c *PolicyCache) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{c.requestsCounter}
+}
+
+// getPolicies returns the policies of the namespace selected by the selector,
+// like getPolicies, listed with the given client the first time, and
+// memoized until a policy of the namespace changes. The client must read
+// from the cache the invalidating predicate is registered with.
+func (c *PolicyCache) getPolicies(ctx context.Context, kclient client.Client, namespace string,
+	selector *metav1.LabelSelector) ([]imagev1_reflect.ImagePolicy, skippedPolicies, error) {
+	key := metav1.FormatLabelSelector(selector)
+
+	c.mu.Lock()
+	ns, ok := c.namespaces[namespace]
+	if !ok {
+		ns = &namespacePolicies{selections: map[string]policySelection{}}
+		c.namespaces[namespace] = ns
+	}
+	generation := ns.generation
+	selection, hit := ns.selections[key]
+	c.mu.Unlock()
+
+	if hit {
+		c.requestsCounter.WithLabelValues("hit").Inc()
+		return slices.Clone(selection.policies), cloneSkipped(selection.skipped), nil
+	}
+	c.requestsCounter.WithLabelValues("miss").Inc()
+
+	policies, skipped, err := getPolicies(ctx, kclient, namespace, selector, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.mu.Lock()
+	if ns := c.namespaces[namespace]; ns != nil && ns.generation == generation {
+		ns.selections[key] = policySelection{
+			policies: slices.Clone(policies),
+			skipped:  cloneSkipped(skipped),
+		}
+	}
+	c.mu.Unlock()
+	return policies, skipped, nil
+}
+
+// invalidate forgets the policies selected in the namespace.
+func (c *PolicyCache) invalidate(namespace string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ns, ok := c.namespaces[namespace]
+	if !ok {
+		return
+	}
+	ns.generation++
+	clear(ns.selections)
+}
+
+// Predicate returns a predicate which invalidates the policies of the
+// namespace of every ImagePolicy event, and lets all the events through. It
+// must be the first predicate of the watch of the ImagePolicies, for the
+// policies to be invalidated before any reconciliation is requested for the
+// event.
+func (c *PolicyCache) Predicate() predicate.Predicate {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			c.invalidate(e.Object.GetNamespace())
+			return true
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			c.invalidate(e.ObjectNew.GetNamespace())
+			return true
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			c.invalidate(e.Object.GetNamespace())
+			return true
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			c.invalidate(e.Object.GetNamespace())
+			return true
+		},
+	}
+}
+
+func cloneSkipped(s skippedPolicies) skippedPolicies {
+	clone := make(skippedPolicies, len(s))
+	for reason, names := range s {
+		clone[reason] = slices.Clone(names)
+	}
+	return clone
+}
+
+// TransformImagePolicy strips the ImagePolicies stored in the cache of the
+// manager down to the fields the automations read, their metadata, their spec
+// and their latest image, to reduce the memory used by namespaces with many
+// policies. The spec is kept for the ImageRepository of the policies given to
+// the templates, and for the ordering of the tags when reverting the revoked
+// images.
+func TransformImagePolicy(obj interface{}) (interface{}, error) {
+	policy, ok := obj.(*imagev1_reflect.ImagePolicy)
+	if !ok {
+		return obj, nil
+	}
+	policy.ManagedFields = nil
+	policy.Status = imagev1_reflect.ImagePolicyStatus{
+		LatestImage:        policy.Status.LatestImage,
+		ObservedGeneration: policy.Status.ObservedGeneration,
+	}
+	return policy, nil
+}
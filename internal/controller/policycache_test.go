@@ -0,0 +1,204 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	imagev1_reflect "github.com/fluxcd/image-reflector-controller/api/v1beta2"
+	"github.com/fluxcd/pkg/apis/meta"
+)
+
+func policyScheme(g *WithT) *runtime.Scheme {
+	s := runtime.NewScheme()
+	g.Expect(imagev1_reflect.AddToScheme(s)).To(Succeed())
+	return s
+}
+
+func testPolicy(name, namespace, latestImage string, labels map[string]string) *imagev1_reflect.ImagePolicy {
+	policy := &imagev1_reflect.ImagePolicy{}
+	policy.Name = name
+	policy.Namespace = namespace
+	policy.Labels = labels
+	policy.Status.LatestImage = latestImage
+	return policy
+}
+
+// cachedPolicies returns the given policies as stored in the cache of the
+// manager, transformed by TransformImagePolicy.
+func cachedPolicies(g *WithT, policies ...*imagev1_reflect.ImagePolicy) []client.Object {
+	var objs []client.Object
+	for _, p := range policies {
+		obj, err := TransformImagePolicy(p.DeepCopy())
+		g.Expect(err).ToNot(HaveOccurred())
+		objs = append(objs, obj.(client.Object))
+	}
+	return objs
+}
+
+func TestPolicyCache(t *testing.T) {
+	g := NewWithT(t)
+
+	var lists int
+	var onList func()
+	c := fakeclient.NewClientBuilder().
+		WithScheme(policyScheme(g)).
+		WithObjects(
+			testPolicy("app", "apps", "app:1.0.0", map[string]string{"tier": "web"}),
+			testPolicy("db", "apps", "db:1.0.0", nil),
+			testPolicy("new", "apps", "", map[string]string{"tier": "web"}),
+		).
+		WithInterceptorFuncs(interceptor.Funcs{
+			List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+				lists++
+				if onList != nil {
+					onList()
+				}
+				return c.List(ctx, list, opts...)
+			},
+		}).
+		Build()
+
+	cache := NewPolicyCache()
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "web"}}
+
+	policies, skipped, err := cache.getPolicies(context.TODO(), c, "apps", selector)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(policies).To(HaveLen(1))
+	g.Expect(skipped.String()).To(Equal("no latest image: new; not selected by the policy selector: db"))
+	g.Expect(lists).To(Equal(1))
+
+	// The selected policies are memoized, and can't be changed by the
+	// callers.
+	skipped.add(skipReasonExcluded, "app")
+	policies[0].Status.LatestImage = "app:2.0.0"
+	policies, skipped, err = cache.getPolicies(context.TODO(), c, "apps", selector)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(policies[0].Status.LatestImage).To(Equal("app:1.0.0"))
+	g.Expect(skipped.String()).To(Equal("no latest image: new; not selected by the policy selector: db"))
+	g.Expect(lists).To(Equal(1))
+
+	// Each selector is memoized on its own.
+	policies, _, err = cache.getPolicies(context.TODO(), c, "apps", nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(policies).To(HaveLen(2))
+	g.Expect(lists).To(Equal(2))
+
+	// A change of a policy of another namespace leaves them memoized.
+	pred := cache.Predicate()
+	g.Expect(pred.Update(event.UpdateEvent{
+		ObjectOld: testPolicy("app", "other", "", nil),
+		ObjectNew: testPolicy("app", "other", "", nil),
+	})).To(BeTrue())
+	_, _, err = cache.getPolicies(context.TODO(), c, "apps", selector)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(lists).To(Equal(2))
+
+	// A change of a policy of the namespace invalidates them, even when it
+	// happens while they're being listed.
+	g.Expect(pred.Create(event.CreateEvent{Object: testPolicy("db", "apps", "", nil)})).To(BeTrue())
+	onList = func() {
+		pred.Delete(event.DeleteEvent{Object: testPolicy("db", "apps", "", nil)})
+	}
+	_, _, err = cache.getPolicies(context.TODO(), c, "apps", selector)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(lists).To(Equal(3))
+	onList = nil
+	_, _, err = cache.getPolicies(context.TODO(), c, "apps", selector)
+	g.Expect(err).ToNot(HaveOccurred())
+	_, _, err = cache.getPolicies(context.TODO(), c, "apps", selector)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(lists).To(Equal(4))
+
+	g.Expect(testutil.ToFloat64(cache.requestsCounter.WithLabelValues("hit"))).To(Equal(float64(3)))
+	g.Expect(testutil.ToFloat64(cache.requestsCounter.WithLabelValues("miss"))).To(Equal(float64(4)))
+}
+
+func Test_listPolicies_pages(t *testing.T) {
+	g := NewWithT(t)
+
+	var objects []client.Object
+	for i := range 5 {
+		objects = append(objects, testPolicy("policy"+strconv.Itoa(i), "apps", "app:1.0.0", nil))
+	}
+	var requests []string
+	c := fakeclient.NewClientBuilder().
+		WithScheme(policyScheme(g)).
+		WithObjects(objects...).
+		WithInterceptorFuncs(interceptor.Funcs{
+			// The fake client doesn't paginate.
+			List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+				listOpts := (&client.ListOptions{}).ApplyOptions(opts)
+				requests = append(requests, listOpts.Continue)
+				if err := c.List(ctx, list, opts...); err != nil {
+					return err
+				}
+				policies := list.(*imagev1_reflect.ImagePolicyList)
+				start, _ := strconv.Atoi(listOpts.Continue)
+				end := min(start+int(listOpts.Limit), len(policies.Items))
+				policies.Items = policies.Items[start:end]
+				policies.Continue = ""
+				if end < len(objects) {
+					policies.Continue = strconv.Itoa(end)
+				}
+				return nil
+			},
+		}).
+		Build()
+
+	policies, err := listPolicies(context.TODO(), c, "apps", 2)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(policies).To(HaveLen(5))
+	g.Expect(requests).To(Equal([]string{"", "2", "4"}))
+}
+
+func TestTransformImagePolicy(t *testing.T) {
+	g := NewWithT(t)
+
+	policy := testPolicy("app", "apps", "app:1.0.0", map[string]string{"tier": "web"})
+	policy.Spec = imagev1_reflect.ImagePolicySpec{
+		ImageRepositoryRef: meta.NamespacedObjectReference{Name: "app", Namespace: "images"},
+		Policy: imagev1_reflect.ImagePolicyChoice{
+			SemVer: &imagev1_reflect.SemVerPolicy{Range: ">=1.0.0"},
+		},
+		FilterTags: &imagev1_reflect.TagFilter{Pattern: `^v(?P<version>.*)`, Extract: "$version"},
+	}
+	policy.Status.ObservedPreviousImage = "app:0.9.0"
+	policy.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: "image-reflector-controller"}}
+
+	obj, err := TransformImagePolicy(policy)
+	g.Expect(err).ToNot(HaveOccurred())
+	transformed := obj.(*imagev1_reflect.ImagePolicy)
+	g.Expect(transformed.Labels).To(Equal(map[string]string{"tier": "web"}))
+	g.Expect(transformed.Status.LatestImage).To(Equal("app:1.0.0"))
+	g.Expect(transformed.Status.ObservedPreviousImage).To(BeEmpty())
+	g.Expect(transformed.Spec.ImageRepositoryRef).To(Equal(meta.NamespacedObjectReference{Name: "app", Namespace: "images"}))
+	g.Expect(transformed.Spec.Policy.SemVer.Range).To(Equal(">=1.0.0"))
+	g.Expect(transformed.Spec.FilterTags.Extract).To(Equal("$version"))
+	g.Expect(transformed.ManagedFields).To(BeNil())
+}
@@ -17,17 +17,27 @@ limitations under the License.
 package controller
 
 import (
+	"maps"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+
 	imagev1_reflect "github.com/fluxcd/image-reflector-controller/api/v1beta2"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
 )
 
 // latestImageChangePredicate implements a predicate for latest image change.
 // This can be used to filter events from ImagePolicies for change in the latest
-// image.
+// image. With a debouncer, the changes are filtered out and passed to the
+// debouncer instead, which sends them once the images stabilize.
 type latestImageChangePredicate struct {
 	predicate.Funcs
+	debouncer *policyDebouncer
 }
 
 func (latestImageChangePredicate) Create(e event.CreateEvent) bool {
@@ -38,7 +48,7 @@ func (latestImageChangePredicate) Delete(e event.DeleteEvent) bool {
 	return false
 }
 
-func (latestImageChangePredicate) Update(e event.UpdateEvent) bool {
+func (p latestImageChangePredicate) Update(e event.UpdateEvent) bool {
 	if e.ObjectOld == nil || e.ObjectNew == nil {
 		return false
 	}
@@ -53,11 +63,14 @@ func (latestImageChangePredicate) Update(e event.UpdateEvent) bool {
 		return false
 	}
 
-	if oldSource.Status.LatestImage != newSource.Status.LatestImage {
-		return true
+	if oldSource.Status.LatestImage == newSource.Status.LatestImage {
+		return false
 	}
-
-	return false
+	if p.debouncer != nil {
+		p.debouncer.debounce(newSource)
+		return false
+	}
+	return true
 }
 
 // sourceConfigChangePredicate implements a predicate for source configuration
@@ -80,5 +93,39 @@ func (sourceConfigChangePredicate) Update(e event.UpdateEvent) bool {
 		return false
 	}
 
-	return e.ObjectOld.GetGeneration() != e.ObjectNew.GetGeneration()
+	// The labels select the GitRepositories of some automations, and the
+	// automations wait for their GitRepository to be ready.
+	return e.ObjectOld.GetGeneration() != e.ObjectNew.GetGeneration() ||
+		!maps.Equal(e.ObjectOld.GetLabels(), e.ObjectNew.GetLabels()) ||
+		becameReady(e.ObjectOld, e.ObjectNew)
+}
+
+// becameReady returns whether the Ready condition of the given object turned
+// True, when the object has conditions.
+func becameReady(oldObj, newObj client.Object) bool {
+	oldGetter, ok := oldObj.(conditions.Getter)
+	if !ok {
+		return false
+	}
+	newGetter, ok := newObj.(conditions.Getter)
+	if !ok {
+		return false
+	}
+	return !conditions.IsTrue(oldGetter, meta.ReadyCondition) && conditions.IsTrue(newGetter, meta.ReadyCondition)
+}
+
+// fullSyncRequestedPredicate implements a predicate for a full sync requested
+// with the image.toolkit.fluxcd.io/full-sync annotation, which doesn't change
+// the generation of the automation.
+type fullSyncRequestedPredicate struct {
+	predicate.Funcs
+}
+
+func (fullSyncRequestedPredicate) Update(e event.UpdateEvent) bool {
+	if e.ObjectOld == nil || e.ObjectNew == nil {
+		return false
+	}
+
+	newVal, ok := e.ObjectNew.GetAnnotations()[imagev1.FullSyncAnnotation]
+	return ok && newVal != e.ObjectOld.GetAnnotations()[imagev1.FullSyncAnnotation]
 }
@@ -18,12 +18,18 @@ package controller
 
 import (
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 
+	"github.com/fluxcd/pkg/apis/meta"
+
 	imagev1_reflect "github.com/fluxcd/image-reflector-controller/api/v1beta2"
 	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
 )
 
 func Test_latestImageChangePredicate_Update(t *testing.T) {
@@ -76,6 +82,40 @@ func Test_latestImageChangePredicate_Update(t *testing.T) {
 	}
 }
 
+func Test_latestImageChangePredicate_debounce(t *testing.T) {
+	g := NewWithT(t)
+
+	debouncer := newPolicyDebouncer(100 * time.Millisecond)
+	p := latestImageChangePredicate{debouncer: debouncer}
+
+	update := func(ns, name, oldImage, newImage string) bool {
+		oldObj := &imagev1_reflect.ImagePolicy{}
+		oldObj.Namespace, oldObj.Name = ns, name
+		oldObj.Status.LatestImage = oldImage
+		newObj := oldObj.DeepCopy()
+		newObj.Status.LatestImage = newImage
+		return p.Update(event.UpdateEvent{ObjectOld: oldObj, ObjectNew: newObj})
+	}
+
+	// The changes are delayed, and the ones of the same namespace are
+	// coalesced into the last one.
+	g.Expect(update("ns1", "policy1", "foo:1", "foo:2")).To(BeFalse())
+	g.Expect(update("ns1", "policy2", "bar:1", "bar:2")).To(BeFalse())
+	g.Expect(update("ns2", "policy1", "foo:1", "foo:2")).To(BeFalse())
+	// An unchanged image isn't debounced.
+	g.Expect(update("ns3", "policy1", "foo:1", "foo:1")).To(BeFalse())
+	g.Consistently(debouncer.events, 50*time.Millisecond).ShouldNot(Receive())
+
+	received := map[string]string{}
+	for range 2 {
+		var e event.GenericEvent
+		g.Eventually(debouncer.events, time.Second).Should(Receive(&e))
+		received[e.Object.GetNamespace()] = e.Object.GetName()
+	}
+	g.Expect(received).To(Equal(map[string]string{"ns1": "policy2", "ns2": "policy1"}))
+	g.Consistently(debouncer.events, 200*time.Millisecond).ShouldNot(Receive())
+}
+
 func Test_sourceConfigChangePredicate_Update(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -98,6 +138,31 @@ func Test_sourceConfigChangePredicate_Update(t *testing.T) {
 			},
 			want: true,
 		},
+		{
+			name: "no generation change, labels change",
+			beforeFunc: func(oldObj, newObj *sourcev1.GitRepository) {
+				oldObj.Generation = 1
+				newObj.Generation = 1
+				newObj.Labels = map[string]string{"app": "foo"}
+			},
+			want: true,
+		},
+		{
+			name: "no generation change, became ready",
+			beforeFunc: func(oldObj, newObj *sourcev1.GitRepository) {
+				oldObj.Status.Conditions = []metav1.Condition{{Type: meta.ReadyCondition, Status: metav1.ConditionFalse}}
+				newObj.Status.Conditions = []metav1.Condition{{Type: meta.ReadyCondition, Status: metav1.ConditionTrue}}
+			},
+			want: true,
+		},
+		{
+			name: "no generation change, still ready",
+			beforeFunc: func(oldObj, newObj *sourcev1.GitRepository) {
+				oldObj.Status.Conditions = []metav1.Condition{{Type: meta.ReadyCondition, Status: metav1.ConditionTrue}}
+				newObj.Status.Conditions = []metav1.Condition{{Type: meta.ReadyCondition, Status: metav1.ConditionTrue, Message: "new revision"}}
+			},
+			want: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -118,3 +183,55 @@ func Test_sourceConfigChangePredicate_Update(t *testing.T) {
 	}
 
 }
+
+func Test_fullSyncRequestedPredicate_Update(t *testing.T) {
+	tests := []struct {
+		name           string
+		oldAnnotations map[string]string
+		newAnnotations map[string]string
+		want           bool
+	}{
+		{
+			name: "no annotation",
+			want: false,
+		},
+		{
+			name:           "new annotation",
+			newAnnotations: map[string]string{imagev1.FullSyncAnnotation: "1"},
+			want:           true,
+		},
+		{
+			name:           "same value",
+			oldAnnotations: map[string]string{imagev1.FullSyncAnnotation: "1"},
+			newAnnotations: map[string]string{imagev1.FullSyncAnnotation: "1", "foo": "bar"},
+			want:           false,
+		},
+		{
+			name:           "new value",
+			oldAnnotations: map[string]string{imagev1.FullSyncAnnotation: "1"},
+			newAnnotations: map[string]string{imagev1.FullSyncAnnotation: "2"},
+			want:           true,
+		},
+		{
+			name:           "removed annotation",
+			oldAnnotations: map[string]string{imagev1.FullSyncAnnotation: "1"},
+			want:           false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			oldObj := &imagev1.ImageUpdateAutomation{}
+			oldObj.Annotations = tt.oldAnnotations
+			newObj := &imagev1.ImageUpdateAutomation{}
+			newObj.Annotations = tt.newAnnotations
+			e := event.UpdateEvent{
+				ObjectOld: oldObj,
+				ObjectNew: newObj,
+			}
+			p := fullSyncRequestedPredicate{}
+			g.Expect(p.Update(e)).To(Equal(tt.want))
+		})
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ValidateProtectedBranches returns an error if any of the protected branch
+// patterns is malformed.
+func ValidateProtectedBranches(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid protected branch pattern '%s': %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// protectedBranchPattern returns the first of the patterns matching the
+// branch, with the syntax of path.Match, e.g. 'release/*' matches
+// 'release/1.0' but not 'release/1.0/rc'.
+func protectedBranchPattern(patterns []string, branch string) (string, bool) {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, branch); ok {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// protectedPushError returns an error if any of the given push branches, or
+// the branch the given refspec pushes to, matches one of the protected branch
+// patterns. The additional remotes are pushed the same references, and are
+// therefore covered as well.
+func protectedPushError(patterns, branches []string, refspec string) error {
+	for _, branch := range branches {
+		if pattern, ok := protectedBranchPattern(patterns, branch); ok {
+			return fmt.Errorf("push branch '%s' matches the protected branch pattern '%s': push to another branch or to a branch per policy",
+				branch, pattern)
+		}
+	}
+	if branch, ok := refspecBranch(refspec); ok {
+		if pattern, ok := protectedBranchPattern(patterns, branch); ok {
+			return fmt.Errorf("refspec '%s' pushes to the branch '%s', which matches the protected branch pattern '%s': push to another branch",
+				refspec, branch, pattern)
+		}
+	}
+	return nil
+}
+
+// refspecBranch returns the branch the given push refspec pushes to, if its
+// destination is a branch, e.g. 'main' for 'refs/heads/auto:refs/heads/main'
+// or 'HEAD:main', but not for the Gerrit refspec 'HEAD:refs/for/main'. A
+// refspec without destination pushes to its source.
+func refspecBranch(refspec string) (string, bool) {
+	spec := strings.TrimPrefix(refspec, "+")
+	if spec == "" {
+		return "", false
+	}
+	dst := spec
+	if i := strings.LastIndex(spec, ":"); i >= 0 {
+		dst = spec[i+1:]
+	}
+	switch {
+	case dst == "" || dst == "HEAD":
+		return "", false
+	case strings.HasPrefix(dst, "refs/heads/"):
+		return strings.TrimPrefix(dst, "refs/heads/"), true
+	case strings.HasPrefix(dst, "refs/"):
+		return "", false
+	default:
+		return dst, true
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func Test_protectedBranchPattern(t *testing.T) {
+	patterns := []string{"main", "master", "release/*"}
+	tests := []struct {
+		branch      string
+		wantPattern string
+		wantMatch   bool
+	}{
+		{branch: "main", wantPattern: "main", wantMatch: true},
+		{branch: "release/1.0", wantPattern: "release/*", wantMatch: true},
+		{branch: "release/1.0/rc"},
+		{branch: "image-updates"},
+		{branch: "maintenance"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.branch, func(t *testing.T) {
+			g := NewWithT(t)
+
+			pattern, ok := protectedBranchPattern(patterns, tt.branch)
+			g.Expect(ok).To(Equal(tt.wantMatch))
+			g.Expect(pattern).To(Equal(tt.wantPattern))
+		})
+	}
+}
+
+func TestValidateProtectedBranches(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(ValidateProtectedBranches([]string{"main", "release/*"})).To(Succeed())
+	g.Expect(ValidateProtectedBranches([]string{"release/["})).To(MatchError(ContainSubstring("release/[")))
+}
+
+func Test_refspecBranch(t *testing.T) {
+	tests := []struct {
+		refspec    string
+		wantBranch string
+		wantOK     bool
+	}{
+		{refspec: "refs/heads/auto:refs/heads/main", wantBranch: "main", wantOK: true},
+		{refspec: "+refs/heads/auto:refs/heads/release/1.0", wantBranch: "release/1.0", wantOK: true},
+		{refspec: "HEAD:main", wantBranch: "main", wantOK: true},
+		{refspec: "refs/heads/main", wantBranch: "main", wantOK: true},
+		{refspec: "HEAD:refs/for/main"},
+		{refspec: "refs/heads/auto:refs/tags/v1.0.0"},
+		{refspec: ":refs/heads/auto", wantBranch: "auto", wantOK: true},
+		{refspec: "HEAD"},
+		{refspec: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.refspec, func(t *testing.T) {
+			g := NewWithT(t)
+
+			branch, ok := refspecBranch(tt.refspec)
+			g.Expect(ok).To(Equal(tt.wantOK))
+			g.Expect(branch).To(Equal(tt.wantBranch))
+		})
+	}
+}
+
+func Test_protectedPushError(t *testing.T) {
+	patterns := []string{"main", "release/*"}
+	tests := []struct {
+		name     string
+		branches []string
+		refspec  string
+		wantErr  string
+	}{
+		{
+			name:     "push branch",
+			branches: []string{"auto"},
+		},
+		{
+			name:     "protected push branch",
+			branches: []string{"staging", "release/1.0"},
+			wantErr:  "push branch 'release/1.0' matches the protected branch pattern 'release/*'",
+		},
+		{
+			name:     "refspec",
+			branches: []string{"auto"},
+			refspec:  "refs/heads/auto:refs/heads/auto-mirror",
+		},
+		{
+			name:     "refspec to a protected branch",
+			branches: []string{"auto"},
+			refspec:  "refs/heads/auto:refs/heads/main",
+			wantErr:  "refspec 'refs/heads/auto:refs/heads/main' pushes to the branch 'main', which matches the protected branch pattern 'main'",
+		},
+		{
+			name:    "refspec only to a protected branch",
+			refspec: "HEAD:main",
+			wantErr: "pushes to the branch 'main'",
+		},
+		{
+			name:    "Gerrit refspec",
+			refspec: "HEAD:refs/for/main",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			err := protectedPushError(patterns, tt.branches, tt.refspec)
+			if tt.wantErr == "" {
+				g.Expect(err).ToNot(HaveOccurred())
+				return
+			}
+			g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+		})
+	}
+}
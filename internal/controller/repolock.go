@@ -0,0 +1,66 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// pushTargetRequeueDelay is the delay after which a reconciliation waiting
+// for the push target of the automation is retried.
+const pushTargetRequeueDelay = 5 * time.Second
+
+// pushTargetLocks serializes the reconciliations of the automations pushing
+// to the same repository and branch, so that they don't collide on push,
+// while the automations of other push targets are reconciled concurrently.
+// The zero value is ready to use.
+//
+// A reconciliation which can't lock its push target doesn't wait for it, so as
+// to not hold a worker; it's requeued after pushTargetRequeueDelay instead.
+type pushTargetLocks struct {
+	mu sync.Mutex
+	// holders are the automations holding the lock of each push target.
+	holders map[string]types.NamespacedName
+}
+
+// tryLock locks the given push target for the given automation, and returns
+// true, unless it's already locked by another automation, which is returned.
+func (l *pushTargetLocks) tryLock(target string, automation types.NamespacedName) (types.NamespacedName, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if holder, ok := l.holders[target]; ok && holder != automation {
+		return holder, false
+	}
+	if l.holders == nil {
+		l.holders = map[string]types.NamespacedName{}
+	}
+	l.holders[target] = automation
+	return automation, true
+}
+
+// unlock unlocks the given push target, if it's locked by the given
+// automation.
+func (l *pushTargetLocks) unlock(target string, automation types.NamespacedName) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.holders[target] == automation {
+		delete(l.holders, target)
+	}
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func Test_pushTargetLocks(t *testing.T) {
+	g := NewWithT(t)
+
+	var locks pushTargetLocks
+	first := types.NamespacedName{Namespace: "default", Name: "first"}
+	second := types.NamespacedName{Namespace: "default", Name: "second"}
+	target := "https://example.com/repo#main"
+	otherTarget := "https://example.com/repo#staging"
+
+	_, ok := locks.tryLock(target, first)
+	g.Expect(ok).To(BeTrue())
+
+	// Another automation can't lock the same target, but can lock another.
+	holder, ok := locks.tryLock(target, second)
+	g.Expect(ok).To(BeFalse())
+	g.Expect(holder).To(Equal(first))
+	_, ok = locks.tryLock(otherTarget, second)
+	g.Expect(ok).To(BeTrue())
+
+	// Only the holder unlocks the target.
+	locks.unlock(target, second)
+	_, ok = locks.tryLock(target, second)
+	g.Expect(ok).To(BeFalse())
+
+	locks.unlock(target, first)
+	_, ok = locks.tryLock(target, second)
+	g.Expect(ok).To(BeTrue())
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/fluxcd/pkg/git"
+
+	imagev1_reflect "github.com/fluxcd/image-reflector-controller/api/v1beta2"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+	"github.com/fluxcd/image-automation-controller/internal/policy"
+	"github.com/fluxcd/image-automation-controller/internal/source"
+)
+
+// routePolicies returns the given policies selected by the policy selector of
+// the route.
+func routePolicies(route imagev1.Route, policies []imagev1_reflect.ImagePolicy) ([]imagev1_reflect.ImagePolicy, error) {
+	selector, err := metav1.LabelSelectorAsSelector(&route.PolicySelector)
+	if err != nil {
+		return nil, fmt.Errorf("%w of route '%s': %w", errParsePolicySelector, route.Name, err)
+	}
+	var selected []imagev1_reflect.ImagePolicy
+	for _, p := range policies {
+		if selector.Matches(labels.Set(p.GetLabels())) {
+			selected = append(selected, p)
+		}
+	}
+	return selected, nil
+}
+
+// routedPolicies returns the given policies selected by any of the given
+// routes, along with the sorted names of the policies selected by none, which
+// are never pushed. Without routes, all the policies are returned.
+func routedPolicies(routes []imagev1.Route, policies []imagev1_reflect.ImagePolicy) ([]imagev1_reflect.ImagePolicy, []string, error) {
+	if len(routes) == 0 {
+		return policies, nil, nil
+	}
+	routed := map[string]bool{}
+	for _, route := range routes {
+		selected, err := routePolicies(route, policies)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, p := range selected {
+			routed[p.Name] = true
+		}
+	}
+
+	var result []imagev1_reflect.ImagePolicy
+	var unrouted []string
+	for _, p := range policies {
+		if !routed[p.Name] {
+			unrouted = append(unrouted, p.Name)
+			continue
+		}
+		result = append(result, p)
+	}
+	slices.Sort(unrouted)
+	return result, unrouted, nil
+}
+
+// routeBranches returns the branches the given routes push to.
+func routeBranches(routes []imagev1.Route) []string {
+	branches := make([]string, 0, len(routes))
+	for _, route := range routes {
+		branches = append(branches, route.Push.Branch)
+	}
+	return branches
+}
+
+// routePushConfig returns the given push configuration with the push options
+// of the route merged over the push options of the automation.
+func routePushConfig(obj *imagev1.ImageUpdateAutomation, route imagev1.Route, pushCfg []source.PushConfig) []source.PushConfig {
+	if len(route.Push.Options) == 0 {
+		return pushCfg
+	}
+	options := map[string]string{}
+	if push := obj.Spec.GitSpec.Push; push != nil {
+		maps.Copy(options, push.Options)
+	}
+	maps.Copy(options, route.Push.Options)
+	return append(slices.Clip(pushCfg), source.WithPushConfigOptions(options))
+}
+
+// pushRoutes commits and pushes the changes of the policies of each route to
+// the branch of the route, on top of its head on the remote, or of the
+// checked out commit when it doesn't exist yet. The changes of the policies
+// made in the worktree are discarded, and made again on the branch of each
+// route, which is left as is when they're already made.
+func pushRoutes(ctx context.Context, sm *source.SourceManager, obj *imagev1.ImageUpdateAutomation,
+	policies []imagev1_reflect.ImagePolicy, base *git.Commit, applyOpts []policy.ApplyOption,
+	pushCfg []source.PushConfig) ([]policyPush, error) {
+	var pushes []policyPush
+	for _, route := range obj.Spec.Routes {
+		// Stop between the routes when the reconciliation is cancelled,
+		// e.g. on shutdown.
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		selected, err := routePolicies(route, policies)
+		if err != nil {
+			return nil, err
+		}
+		if len(selected) == 0 {
+			continue
+		}
+		if err := sm.CheckoutRouteBranch(route.Push.Branch, base); err != nil {
+			return nil, err
+		}
+		changes, err := policy.ApplyPolicies(ctx, sm.WorkDirectory(), obj, selected, applyOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply the policies of route '%s': %w", route.Name, err)
+		}
+		if obj.Spec.Update.Validate && changes.HasChanges() {
+			if err := policy.ValidateChanges(sm.WorkDirectory(), obj, changes, applyOpts...); err != nil {
+				return nil, fmt.Errorf("failed to validate the changes of route '%s': %w", route.Name, err)
+			}
+		}
+		pr, err := sm.CommitAndPush(ctx, obj, changes, routePushConfig(obj, route, pushCfg)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to push the changes of route '%s': %w", route.Name, err)
+		}
+		if pr != nil {
+			pushes = append(pushes, policyPush{route: route.Name, result: pr, changes: changes})
+		}
+	}
+	return pushes, nil
+}
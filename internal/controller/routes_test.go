@@ -0,0 +1,150 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fluxcd/pkg/git/repository"
+
+	imagev1_reflect "github.com/fluxcd/image-reflector-controller/api/v1beta2"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+	"github.com/fluxcd/image-automation-controller/internal/source"
+)
+
+func Test_routedPolicies(t *testing.T) {
+	policy := func(name, env string) imagev1_reflect.ImagePolicy {
+		p := imagev1_reflect.ImagePolicy{}
+		p.Name = name
+		if env != "" {
+			p.Labels = map[string]string{"env": env}
+		}
+		return p
+	}
+	route := func(name string, selector metav1.LabelSelector) imagev1.Route {
+		return imagev1.Route{Name: name, PolicySelector: selector, Push: imagev1.RoutePushSpec{Branch: name}}
+	}
+	policies := []imagev1_reflect.ImagePolicy{
+		policy("web", "staging"),
+		policy("worker", "prod"),
+		policy("db", "dev"),
+		policy("cache", ""),
+	}
+
+	tests := []struct {
+		name         string
+		routes       []imagev1.Route
+		wantPolicies []string
+		wantUnrouted []string
+		wantErr      bool
+	}{
+		{
+			name:         "no routes",
+			wantPolicies: []string{"web", "worker", "db", "cache"},
+		},
+		{
+			name: "routes",
+			routes: []imagev1.Route{
+				route("staging", metav1.LabelSelector{MatchLabels: map[string]string{"env": "staging"}}),
+				route("prod", metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}),
+			},
+			wantPolicies: []string{"web", "worker"},
+			wantUnrouted: []string{"cache", "db"},
+		},
+		{
+			name: "overlapping routes",
+			routes: []imagev1.Route{
+				route("all", metav1.LabelSelector{}),
+				route("prod", metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}),
+			},
+			wantPolicies: []string{"web", "worker", "db", "cache"},
+		},
+		{
+			name: "invalid selector",
+			routes: []imagev1.Route{
+				route("staging", metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "env", Operator: "Unknown"},
+				}}),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			routed, unrouted, err := routedPolicies(tt.routes, policies)
+			if tt.wantErr {
+				g.Expect(err).To(MatchError(errParsePolicySelector))
+				g.Expect(err.Error()).To(ContainSubstring("route 'staging'"))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			var names []string
+			for _, p := range routed {
+				names = append(names, p.Name)
+			}
+			g.Expect(names).To(Equal(tt.wantPolicies))
+			g.Expect(unrouted).To(Equal(tt.wantUnrouted))
+		})
+	}
+}
+
+func Test_routePushConfig(t *testing.T) {
+	g := NewWithT(t)
+
+	obj := &imagev1.ImageUpdateAutomation{}
+	obj.Spec.GitSpec = &imagev1.GitSpec{
+		Push: &imagev1.PushSpec{Options: map[string]string{"ci.skip": "", "merge_request.create": ""}},
+	}
+	pushCfg := []source.PushConfig{
+		source.WithPushConfigForce(),
+		source.WithPushConfigOptions(obj.Spec.GitSpec.Push.Options),
+	}
+	apply := func(cfgs []source.PushConfig) repository.PushConfig {
+		pc := repository.PushConfig{}
+		for _, cfg := range cfgs {
+			cfg(&pc)
+		}
+		return pc
+	}
+
+	// Without options of its own, the route pushes with the options of the
+	// automation.
+	route := imagev1.Route{Name: "prod", Push: imagev1.RoutePushSpec{Branch: "prod"}}
+	g.Expect(apply(routePushConfig(obj, route, pushCfg))).To(Equal(repository.PushConfig{
+		Force:   true,
+		Options: map[string]string{"ci.skip": "", "merge_request.create": ""},
+	}))
+
+	// The options of the route are merged over them.
+	route.Push.Options = map[string]string{"merge_request.create": "false", "merge_request.target": "main"}
+	g.Expect(apply(routePushConfig(obj, route, pushCfg))).To(Equal(repository.PushConfig{
+		Force: true,
+		Options: map[string]string{
+			"ci.skip":              "",
+			"merge_request.create": "false",
+			"merge_request.target": "main",
+		},
+	}))
+	g.Expect(pushCfg).To(HaveLen(2))
+	g.Expect(obj.Spec.GitSpec.Push.Options).To(HaveLen(2))
+}
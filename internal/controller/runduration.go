@@ -0,0 +1,83 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/fluxcd/pkg/runtime/conditions"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+// The reconciliations of an object never overlap, but an automation whose
+// Git operations take about as long as its interval, e.g. cloning a big
+// repository every minute, keeps a worker busy all the time and starves the
+// other automations. The mean duration of the reconciliations which
+// synchronize the source is recorded in the status, and the interval of an
+// automation is stretched to runDurationIntervalRatio times this mean when it
+// is shorter, which is reported with the IntervalStretched condition.
+
+const (
+	// runDurationIntervalRatio is the minimum ratio between the effective
+	// interval of an automation and the mean duration of its
+	// reconciliations.
+	runDurationIntervalRatio = 2
+	// runDurationWeight is the weight of the last duration in the moving
+	// average of the durations, the inverse of the number of
+	// reconciliations it mostly averages.
+	runDurationWeight = 4
+)
+
+// recordRunDuration records the duration of a reconciliation of the object
+// which synchronized the source in its mean run duration.
+func recordRunDuration(obj *imagev1.ImageUpdateAutomation, d time.Duration) {
+	mean := d
+	if obj.Status.MeanRunDuration != nil {
+		mean = obj.Status.MeanRunDuration.Duration
+		mean += (d - mean) / runDurationWeight
+	}
+	obj.Status.MeanRunDuration = &metav1.Duration{Duration: mean.Round(time.Millisecond)}
+}
+
+// stretchInterval reports in the IntervalStretched condition whether the
+// interval of the object is stretched by its mean run duration, and returns
+// the given result requeued after the stretched interval when it was
+// requeued after the interval of the object.
+func stretchInterval(obj *imagev1.ImageUpdateAutomation, result ctrl.Result) ctrl.Result {
+	interval := obj.GetRequeueAfter()
+	if obj.Status.MeanRunDuration == nil || interval <= 0 {
+		conditions.Delete(obj, imagev1.IntervalStretchedCondition)
+		return result
+	}
+	mean := obj.Status.MeanRunDuration.Duration
+	stretched := mean * runDurationIntervalRatio
+	if stretched <= interval {
+		conditions.Delete(obj, imagev1.IntervalStretchedCondition)
+		return result
+	}
+	stretched = stretched.Round(time.Second)
+	conditions.MarkTrue(obj, imagev1.IntervalStretchedCondition, imagev1.SlowReconciliationReason,
+		"interval stretched from %s to %s as the mean reconciliation duration is %s", interval, stretched, mean)
+	if result.RequeueAfter == interval {
+		result.RequeueAfter = stretched
+	}
+	return result
+}
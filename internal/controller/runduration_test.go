@@ -0,0 +1,94 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/fluxcd/pkg/runtime/conditions"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+func Test_recordRunDuration(t *testing.T) {
+	g := NewWithT(t)
+
+	obj := &imagev1.ImageUpdateAutomation{}
+	recordRunDuration(obj, 80*time.Second)
+	g.Expect(obj.Status.MeanRunDuration.Duration).To(Equal(80 * time.Second))
+	recordRunDuration(obj, 40*time.Second)
+	g.Expect(obj.Status.MeanRunDuration.Duration).To(Equal(70 * time.Second))
+	recordRunDuration(obj, 110*time.Second)
+	g.Expect(obj.Status.MeanRunDuration.Duration).To(Equal(80 * time.Second))
+}
+
+func Test_stretchInterval(t *testing.T) {
+	tests := []struct {
+		name          string
+		meanDuration  time.Duration
+		requeueAfter  time.Duration
+		wantRequeue   time.Duration
+		wantStretched bool
+	}{
+		{
+			name:         "no mean duration",
+			requeueAfter: time.Minute,
+			wantRequeue:  time.Minute,
+		},
+		{
+			name:         "fast reconciliations",
+			meanDuration: 10 * time.Second,
+			requeueAfter: time.Minute,
+			wantRequeue:  time.Minute,
+		},
+		{
+			name:          "slow reconciliations",
+			meanDuration:  90 * time.Second,
+			requeueAfter:  time.Minute,
+			wantRequeue:   3 * time.Minute,
+			wantStretched: true,
+		},
+		{
+			name:          "slow reconciliations requeued earlier",
+			meanDuration:  90 * time.Second,
+			requeueAfter:  10 * time.Second,
+			wantRequeue:   10 * time.Second,
+			wantStretched: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			obj := &imagev1.ImageUpdateAutomation{}
+			obj.Spec.Interval = metav1.Duration{Duration: time.Minute}
+			if tt.meanDuration > 0 {
+				obj.Status.MeanRunDuration = &metav1.Duration{Duration: tt.meanDuration}
+			}
+			conditions.MarkTrue(obj, imagev1.IntervalStretchedCondition, imagev1.SlowReconciliationReason, "stale")
+
+			result := stretchInterval(obj, ctrl.Result{RequeueAfter: tt.requeueAfter})
+			g.Expect(result.RequeueAfter).To(Equal(tt.wantRequeue))
+			g.Expect(conditions.Has(obj, imagev1.IntervalStretchedCondition)).To(Equal(tt.wantStretched))
+		})
+	}
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/runtime/conditions"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+	"github.com/fluxcd/image-automation-controller/internal/features"
+)
+
+func TestAutomationsForSecret(t *testing.T) {
+	newAutomation := func(name, repo string, ready bool, mutate func(*imagev1.GitSpec)) *imagev1.ImageUpdateAutomation {
+		auto := &imagev1.ImageUpdateAutomation{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: imagev1.ImageUpdateAutomationSpec{
+				SourceRef: imagev1.CrossNamespaceSourceReference{Kind: sourcev1.GitRepositoryKind, Name: repo},
+				GitSpec:   &imagev1.GitSpec{},
+			},
+		}
+		if mutate != nil {
+			mutate(auto.Spec.GitSpec)
+		}
+		if ready {
+			conditions.MarkTrue(auto, meta.ReadyCondition, meta.SucceededReason, "ready")
+		} else {
+			conditions.MarkFalse(auto, meta.ReadyCondition, imagev1.GitOperationFailedReason, "authentication required")
+		}
+		return auto
+	}
+	selectingAutomation := func(name, tier string, ready bool) *imagev1.ImageUpdateAutomation {
+		auto := newAutomation(name, "", ready, nil)
+		auto.Spec.SourceRef.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"tier": tier}}
+		return auto
+	}
+	signedWith := func(secret string) func(*imagev1.GitSpec) {
+		return func(spec *imagev1.GitSpec) {
+			spec.Commit.SigningKey = &imagev1.SigningKey{SecretRef: meta.LocalObjectReference{Name: secret}}
+		}
+	}
+	pushedTo := func(ref imagev1.CrossNamespaceSourceReference) func(*imagev1.GitSpec) {
+		return func(spec *imagev1.GitSpec) {
+			spec.Push = &imagev1.PushSpec{SourceRef: &ref}
+		}
+	}
+	valuesFrom := func(secret string) func(*imagev1.GitSpec) {
+		return func(spec *imagev1.GitSpec) {
+			spec.Commit.ValuesFrom = []imagev1.ValuesReference{{Kind: "Secret", Name: secret}}
+		}
+	}
+
+	objects := []client.Object{
+		&sourcev1.GitRepository{
+			ObjectMeta: metav1.ObjectMeta{Name: "repo", Namespace: "default", Labels: map[string]string{"tier": "apps"}},
+			Spec:       sourcev1.GitRepositorySpec{SecretRef: &meta.LocalObjectReference{Name: "git-creds"}},
+		},
+		&sourcev1.GitRepository{
+			ObjectMeta: metav1.ObjectMeta{Name: "public-repo", Namespace: "default"},
+		},
+		newAutomation("failing-repo", "repo", false, nil),
+		newAutomation("ready-repo", "repo", true, nil),
+		newAutomation("failing-signing", "public-repo", false, signedWith("signing-key")),
+		newAutomation("ready-signing", "public-repo", true, signedWith("signing-key")),
+		newAutomation("ready-values", "public-repo", true, valuesFrom("git-creds")),
+		newAutomation("failing-public", "public-repo", false, nil),
+		selectingAutomation("failing-selector", "apps", false),
+		selectingAutomation("ready-selector", "apps", true),
+		selectingAutomation("failing-other-selector", "infra", false),
+		newAutomation("failing-fork", "public-repo", false,
+			pushedTo(imagev1.CrossNamespaceSourceReference{Kind: sourcev1.GitRepositoryKind, Name: "repo"})),
+		newAutomation("failing-fork-selector", "public-repo", false,
+			pushedTo(imagev1.CrossNamespaceSourceReference{Kind: sourcev1.GitRepositoryKind,
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "apps"}}})),
+		newAutomation("failing-same-repo", "repo", false,
+			pushedTo(imagev1.CrossNamespaceSourceReference{Kind: sourcev1.GitRepositoryKind,
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "apps"}}})),
+	}
+
+	tests := []struct {
+		name        string
+		secret      string
+		cacheSecret bool
+		want        []string
+	}{
+		{
+			name:        "git repository credentials",
+			secret:      "git-creds",
+			cacheSecret: true,
+			want: []string{"ready-values", "failing-fork", "failing-repo", "failing-same-repo",
+				"failing-fork-selector", "failing-selector"},
+		},
+		{
+			name:        "signing key",
+			secret:      "signing-key",
+			cacheSecret: true,
+			want:        []string{"failing-signing"},
+		},
+		{
+			name:   "credentials without caching",
+			secret: "git-creds",
+			want:   []string{"ready-values"},
+		},
+		{
+			name:        "unreferenced secret",
+			secret:      "other",
+			cacheSecret: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			s := runtime.NewScheme()
+			g.Expect(corev1.AddToScheme(s)).To(Succeed())
+			g.Expect(sourcev1.AddToScheme(s)).To(Succeed())
+			g.Expect(imagev1.AddToScheme(s)).To(Succeed())
+
+			c := fakeclient.NewClientBuilder().WithScheme(s).WithObjects(objects...).
+				WithIndex(&imagev1.ImageUpdateAutomation{}, repoRefKey, indexGitRepoRef).
+				WithIndex(&imagev1.ImageUpdateAutomation{}, valuesFromKey, func(obj client.Object) []string {
+					var keys []string
+					for _, ref := range obj.(*imagev1.ImageUpdateAutomation).Spec.GitSpec.Commit.ValuesFrom {
+						keys = append(keys, ref.Kind+"/"+ref.Name)
+					}
+					return keys
+				}).
+				WithIndex(&imagev1.ImageUpdateAutomation{}, signingKeyKey, indexSigningKey).
+				WithIndex(&sourcev1.GitRepository{}, gitRepoSecretKey, indexGitRepoSecret).
+				Build()
+
+			r := &ImageUpdateAutomationReconciler{
+				Client:   c,
+				features: map[string]bool{features.CacheSecretsAndConfigMaps: tt.cacheSecret},
+			}
+			secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: tt.secret, Namespace: "default"}}
+
+			var got []string
+			for _, req := range r.automationsForSecret(context.TODO(), secret) {
+				g.Expect(req.Namespace).To(Equal("default"))
+				got = append(got, req.Name)
+			}
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+// signedCommitsRequired returns whether the commits of the automations of the
+// given namespace must be signed, because of the RequireSignedCommits option
+// of the reconciler or of the annotation of the namespace. Only the metadata
+// of the namespace is read.
+func (r *ImageUpdateAutomationReconciler) signedCommitsRequired(ctx context.Context, namespace string) (bool, error) {
+	if r.RequireSignedCommits {
+		return true, nil
+	}
+	ns := &metav1.PartialObjectMetadata{}
+	ns.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Namespace"))
+	if err := r.Get(ctx, client.ObjectKey{Name: namespace}, ns); client.IgnoreNotFound(err) != nil {
+		return false, fmt.Errorf("failed to get namespace '%s': %w", namespace, err)
+	}
+	return ns.GetAnnotations()[imagev1.RequireSignedCommitsAnnotation] == "true", nil
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+func TestSignedCommitsRequired(t *testing.T) {
+	c := fakeclient.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:        "tenant-a",
+			Annotations: map[string]string{imagev1.RequireSignedCommitsAnnotation: "true"},
+		}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:        "tenant-b",
+			Annotations: map[string]string{imagev1.RequireSignedCommitsAnnotation: "false"},
+		}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-c"}},
+	).Build()
+
+	tests := []struct {
+		name      string
+		required  bool
+		namespace string
+		want      bool
+	}{
+		{name: "annotated namespace", namespace: "tenant-a", want: true},
+		{name: "annotation not true", namespace: "tenant-b", want: false},
+		{name: "namespace without annotation", namespace: "tenant-c", want: false},
+		{name: "missing namespace", namespace: "tenant-d", want: false},
+		{name: "required for all namespaces", required: true, namespace: "tenant-c", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			r := &ImageUpdateAutomationReconciler{Client: c, RequireSignedCommits: tt.required}
+			required, err := r.signedCommitsRequired(context.TODO(), tt.namespace)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(required).To(Equal(tt.want))
+		})
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+	"github.com/fluxcd/image-automation-controller/internal/source"
+)
+
+// signingVerifier verifies the signatures made with the signing key of a
+// source. It's implemented by source.SourceManager.
+type signingVerifier interface {
+	SigningKeyFingerprint() string
+	VerifyCommitSignature(rev string) error
+	SelfTestSigning() error
+}
+
+var _ signingVerifier = source.SourceManager{}
+
+// selfTestSigningRequested tells if a self-test of the signing configuration
+// was requested with the annotation and not handled yet.
+func selfTestSigningRequested(obj *imagev1.ImageUpdateAutomation) (string, bool) {
+	return obj.PendingRequest(imagev1.VerifySigningAnnotation)
+}
+
+// selfTestSigning runs the self-test of the signing configuration when one was
+// requested with the annotation, and records its result in the status. The
+// result of a previous verification is dropped when the commits aren't signed
+// anymore.
+func (r *ImageUpdateAutomationReconciler) selfTestSigning(ctx context.Context,
+	obj *imagev1.ImageUpdateAutomation, sv signingVerifier) {
+	fingerprint := sv.SigningKeyFingerprint()
+	if fingerprint == "" {
+		obj.Status.SigningVerification = nil
+		return
+	}
+	requested, ok := selfTestSigningRequested(obj)
+	if !ok {
+		return
+	}
+	r.recordSigningVerification(ctx, obj, fingerprint, "", sv.SelfTestSigning())
+	obj.Status.SetLastHandledRequest(imagev1.VerifySigningAnnotation, requested)
+}
+
+// verifyPushedSigning verifies the signature of the pushed commit the first
+// time a signing key is used, and records its result in the status.
+func (r *ImageUpdateAutomationReconciler) verifyPushedSigning(ctx context.Context,
+	obj *imagev1.ImageUpdateAutomation, sv signingVerifier, commit string) {
+	fingerprint := sv.SigningKeyFingerprint()
+	if fingerprint == "" {
+		return
+	}
+	if v := obj.Status.SigningVerification; v != nil && v.KeyFingerprint == fingerprint {
+		return
+	}
+	r.recordSigningVerification(ctx, obj, fingerprint, commit, sv.VerifyCommitSignature(commit))
+}
+
+// recordSigningVerification records the result of a verification in the
+// status, keeping the last handled request, and emits a warning event when
+// the signature couldn't be verified. A failed verification doesn't fail the
+// reconciliation, as the commits were signed and pushed anyway.
+func (r *ImageUpdateAutomationReconciler) recordSigningVerification(ctx context.Context,
+	obj *imagev1.ImageUpdateAutomation, fingerprint, commit string, err error) {
+	v := &imagev1.SigningVerification{
+		Verified:       err == nil,
+		KeyFingerprint: fingerprint,
+		Commit:         commit,
+		Time:           metav1.Now(),
+		// Deprecated, kept for the clients reading the last handled request
+		// from the verification.
+		LastHandledRequest: obj.Status.GetLastHandledRequest(imagev1.VerifySigningAnnotation),
+	}
+	if err != nil {
+		v.Message = err.Error()
+		eventLogf(ctx, r.EventRecorder, obj, corev1.EventTypeWarning, imagev1.SigningVerificationFailedReason,
+			"failed to verify the signature made with signing key %s: %s", fingerprint, err)
+	}
+	obj.Status.SigningVerification = v
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/fluxcd/pkg/apis/meta"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+type fakeSigningVerifier struct {
+	fingerprint string
+	err         error
+	verified    []string
+}
+
+func (f *fakeSigningVerifier) SigningKeyFingerprint() string { return f.fingerprint }
+
+func (f *fakeSigningVerifier) VerifyCommitSignature(rev string) error {
+	f.verified = append(f.verified, rev)
+	return f.err
+}
+
+func (f *fakeSigningVerifier) SelfTestSigning() error {
+	f.verified = append(f.verified, "")
+	return f.err
+}
+
+func Test_signingVerification(t *testing.T) {
+	g := NewWithT(t)
+
+	r := &ImageUpdateAutomationReconciler{EventRecorder: record.NewFakeRecorder(32)}
+	obj := &imagev1.ImageUpdateAutomation{}
+	sv := &fakeSigningVerifier{fingerprint: "AAAA"}
+
+	// The first push with a signing key is verified, once.
+	r.verifyPushedSigning(context.TODO(), obj, sv, "abc")
+	r.verifyPushedSigning(context.TODO(), obj, sv, "def")
+	g.Expect(sv.verified).To(Equal([]string{"abc"}))
+	g.Expect(obj.Status.SigningVerification.Verified).To(BeTrue())
+	g.Expect(obj.Status.SigningVerification.Commit).To(Equal("abc"))
+
+	// A new signing key is verified again, and the failure is recorded.
+	sv.fingerprint, sv.err = "BBBB", errors.New("wrong key")
+	r.verifyPushedSigning(context.TODO(), obj, sv, "def")
+	g.Expect(sv.verified).To(Equal([]string{"abc", "def"}))
+	g.Expect(obj.Status.SigningVerification.Verified).To(BeFalse())
+	g.Expect(obj.Status.SigningVerification.KeyFingerprint).To(Equal("BBBB"))
+	g.Expect(obj.Status.SigningVerification.Message).To(Equal("wrong key"))
+
+	// A self-test is run once per annotation value.
+	r.selfTestSigning(context.TODO(), obj, sv)
+	g.Expect(sv.verified).To(HaveLen(2))
+	obj.SetAnnotations(map[string]string{imagev1.VerifySigningAnnotation: "1"})
+	sv.err = nil
+	r.selfTestSigning(context.TODO(), obj, sv)
+	r.selfTestSigning(context.TODO(), obj, sv)
+	g.Expect(sv.verified).To(Equal([]string{"abc", "def", ""}))
+	g.Expect(obj.Status.SigningVerification.Verified).To(BeTrue())
+	g.Expect(obj.Status.SigningVerification.Commit).To(BeEmpty())
+	g.Expect(obj.Status.SigningVerification.LastHandledRequest).To(Equal("1"))
+	g.Expect(obj.Status.LastHandledRequests).To(HaveKeyWithValue(imagev1.VerifySigningAnnotation, "1"))
+
+	// The last handled request is kept by the verification of a push.
+	sv.fingerprint = "CCCC"
+	r.verifyPushedSigning(context.TODO(), obj, sv, "ghi")
+	g.Expect(obj.Status.SigningVerification.LastHandledRequest).To(Equal("1"))
+
+	// The result is dropped when the commits aren't signed anymore, but not
+	// the last handled request.
+	sv.fingerprint = ""
+	r.selfTestSigning(context.TODO(), obj, sv)
+	g.Expect(obj.Status.SigningVerification).To(BeNil())
+	sv.fingerprint = "CCCC"
+	r.selfTestSigning(context.TODO(), obj, sv)
+	g.Expect(sv.verified).To(HaveLen(4))
+}
+
+func TestImageUpdateAutomation_PendingRequest(t *testing.T) {
+	g := NewWithT(t)
+
+	obj := &imagev1.ImageUpdateAutomation{}
+	_, ok := obj.PendingRequest(imagev1.FullSyncAnnotation)
+	g.Expect(ok).To(BeFalse())
+
+	obj.SetAnnotations(map[string]string{
+		imagev1.FullSyncAnnotation:      "1",
+		imagev1.VerifySigningAnnotation: "a",
+		meta.ReconcileRequestAnnotation: "now",
+	})
+	v, ok := obj.PendingRequest(imagev1.FullSyncAnnotation)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(v).To(Equal("1"))
+
+	// The requests recorded before the map aren't handled again.
+	obj.Status.LastHandledFullSyncRequest = "1"
+	obj.Status.SigningVerification = &imagev1.SigningVerification{LastHandledRequest: "a"}
+	_, ok = obj.PendingRequest(imagev1.FullSyncAnnotation)
+	g.Expect(ok).To(BeFalse())
+	_, ok = obj.PendingRequest(imagev1.VerifySigningAnnotation)
+	g.Expect(ok).To(BeFalse())
+
+	// The requests are tracked independently, with the deprecated fields.
+	obj.SetAnnotations(map[string]string{
+		imagev1.FullSyncAnnotation:      "2",
+		imagev1.VerifySigningAnnotation: "a",
+		meta.ReconcileRequestAnnotation: "now",
+	})
+	_, ok = obj.PendingRequest(imagev1.FullSyncAnnotation)
+	g.Expect(ok).To(BeTrue())
+	obj.Status.SetLastHandledRequest(imagev1.FullSyncAnnotation, "2")
+	_, ok = obj.PendingRequest(imagev1.FullSyncAnnotation)
+	g.Expect(ok).To(BeFalse())
+	g.Expect(obj.Status.LastHandledFullSyncRequest).To(Equal("2"))
+	g.Expect(obj.Status.LastHandledRequests).To(Equal(map[string]string{imagev1.FullSyncAnnotation: "2"}))
+
+	// The reconcile requests are recorded where the runtime expects them.
+	_, ok = obj.PendingRequest(meta.ReconcileRequestAnnotation)
+	g.Expect(ok).To(BeTrue())
+	obj.Status.SetLastHandledRequest(meta.ReconcileRequestAnnotation, "now")
+	_, ok = obj.PendingRequest(meta.ReconcileRequestAnnotation)
+	g.Expect(ok).To(BeFalse())
+	g.Expect(obj.Status.LastHandledReconcileAt).To(Equal("now"))
+	g.Expect(obj.Status.LastHandledRequests).ToNot(HaveKey(meta.ReconcileRequestAnnotation))
+}
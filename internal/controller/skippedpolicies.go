@@ -0,0 +1,79 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Reasons of the policies skipped by an automation.
+const (
+	// skipReasonNoLatestImage is the reason of the policies without a
+	// latest image yet.
+	skipReasonNoLatestImage = "no latest image"
+	// skipReasonNotSelected is the reason of the policies which don't match
+	// the policy selector of the automation.
+	skipReasonNotSelected = "not selected by the policy selector"
+	// skipReasonExcluded is the reason of the policies whose image is never
+	// updated by the controller.
+	skipReasonExcluded = "image never updated by the controller"
+	// skipReasonUnrouted is the reason of the policies selected by none of
+	// the routes of the automation.
+	skipReasonUnrouted = "not selected by any route"
+)
+
+// skipReasons is the order in which the reasons are reported.
+var skipReasons = []string{skipReasonNoLatestImage, skipReasonNotSelected, skipReasonExcluded, skipReasonUnrouted}
+
+// maxSkippedPolicyNames is the maximum number of names of skipped policies
+// reported for each reason, to keep the message of the event short in
+// namespaces with many policies.
+const maxSkippedPolicyNames = 10
+
+// skippedPolicies records the names of the policies skipped by an automation,
+// by reason.
+type skippedPolicies map[string][]string
+
+// add records the policy with the given name as skipped for the reason.
+func (s skippedPolicies) add(reason string, names ...string) {
+	if len(names) == 0 {
+		return
+	}
+	s[reason] = append(s[reason], names...)
+}
+
+// String returns the skipped policies sorted by name for each reason, e.g.
+// "no latest image: app1, app2; not selected by the policy selector: app3".
+func (s skippedPolicies) String() string {
+	var parts []string
+	for _, reason := range skipReasons {
+		names := slices.Clone(s[reason])
+		if len(names) == 0 {
+			continue
+		}
+		slices.Sort(names)
+		list := strings.Join(names, ", ")
+		if len(names) > maxSkippedPolicyNames {
+			list = fmt.Sprintf("%s and %d more", strings.Join(names[:maxSkippedPolicyNames], ", "),
+				len(names)-maxSkippedPolicyNames)
+		}
+		parts = append(parts, reason+": "+list)
+	}
+	return strings.Join(parts, "; ")
+}
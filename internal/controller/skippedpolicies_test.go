@@ -0,0 +1,74 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func Test_skippedPolicies_String(t *testing.T) {
+	many := []string{}
+	for i := 12; i > 0; i-- {
+		many = append(many, fmt.Sprintf("app%02d", i))
+	}
+
+	tests := []struct {
+		name    string
+		skipped skippedPolicies
+		want    string
+	}{
+		{
+			name:    "no skipped policies",
+			skipped: skippedPolicies{},
+			want:    "",
+		},
+		{
+			name: "reasons in order",
+			skipped: skippedPolicies{
+				skipReasonExcluded:      {"postgres"},
+				skipReasonNoLatestImage: {"b", "a"},
+			},
+			want: "no latest image: a, b; image never updated by the controller: postgres",
+		},
+		{
+			name:    "many policies",
+			skipped: skippedPolicies{skipReasonNotSelected: many},
+			want: "not selected by the policy selector: app01, app02, app03, app04, app05, " +
+				"app06, app07, app08, app09, app10 and 2 more",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(tt.skipped.String()).To(Equal(tt.want))
+		})
+	}
+}
+
+func Test_skippedPolicies_add(t *testing.T) {
+	g := NewWithT(t)
+
+	skipped := skippedPolicies{}
+	skipped.add(skipReasonExcluded)
+	g.Expect(skipped).To(BeEmpty())
+	skipped.add(skipReasonExcluded, "a", "b")
+	skipped.add(skipReasonExcluded, "c")
+	g.Expect(skipped).To(Equal(skippedPolicies{skipReasonExcluded: {"a", "b", "c"}}))
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"hash/fnv"
+	"time"
+
+	"github.com/fluxcd/pkg/apis/meta"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+// startupDelay returns how long to delay the reconciliation of the given
+// automation at the given time, for the reconciliations following the start
+// of the controller to be spread over the StartupJitter window instead of
+// hammering the Git servers all at once. Each automation is given a stable
+// slot in the window, from its namespaced name. A requested reconciliation,
+// or one of a new generation, is never delayed.
+func (r *ImageUpdateAutomationReconciler) startupDelay(obj *imagev1.ImageUpdateAutomation, now time.Time) time.Duration {
+	if r.StartupJitter <= 0 {
+		return 0
+	}
+	// The window starts with the first reconciliation, which is when the
+	// leader is elected rather than when the process starts.
+	r.startOnce.Do(func() {
+		r.startTime = now
+	})
+	if now.Sub(r.startTime) >= r.StartupJitter {
+		return 0
+	}
+	if obj.Generation != obj.Status.ObservedGeneration {
+		return 0
+	}
+	if _, ok := obj.PendingRequest(meta.ReconcileRequestAnnotation); ok {
+		return 0
+	}
+	return r.startTime.Add(startupSlot(obj.Namespace+"/"+obj.Name, r.StartupJitter)).Sub(now)
+}
+
+// startupSlot returns the offset of the given key in a window of the given
+// duration.
+func startupSlot(key string, window time.Duration) time.Duration {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return time.Duration(h.Sum64() % uint64(window))
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/fluxcd/pkg/apis/meta"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+func TestStartupDelay(t *testing.T) {
+	g := NewWithT(t)
+
+	window := time.Minute
+	r := &ImageUpdateAutomationReconciler{StartupJitter: window}
+	start := time.Now()
+
+	obj := &imagev1.ImageUpdateAutomation{}
+	obj.Namespace, obj.Name = "default", "app"
+	obj.Generation = 1
+	obj.Status.ObservedGeneration = 1
+
+	// The automation is delayed until its slot in the window.
+	slot := startupSlot("default/app", window)
+	g.Expect(slot).To(BeNumerically("<", window))
+	g.Expect(r.startupDelay(obj, start)).To(Equal(slot))
+	g.Expect(r.startupDelay(obj, start.Add(slot/2))).To(Equal(slot - slot/2))
+	g.Expect(r.startupDelay(obj, start.Add(slot))).To(BeZero())
+
+	// The slots are spread over the window.
+	other := startupSlot("default/other", window)
+	g.Expect(other).ToNot(Equal(slot))
+
+	// A requested reconciliation isn't delayed.
+	obj.SetAnnotations(map[string]string{meta.ReconcileRequestAnnotation: "now"})
+	g.Expect(r.startupDelay(obj, start)).To(BeZero())
+	obj.Status.LastHandledReconcileAt = "now"
+	g.Expect(r.startupDelay(obj, start)).To(Equal(slot))
+
+	// Nor is a new generation.
+	obj.Generation = 2
+	g.Expect(r.startupDelay(obj, start)).To(BeZero())
+	obj.Generation = 1
+
+	// Nothing is delayed after the window.
+	g.Expect(r.startupDelay(obj, start.Add(window))).To(BeZero())
+
+	// Nor without jitter.
+	g.Expect((&ImageUpdateAutomationReconciler{}).startupDelay(obj, start)).To(BeZero())
+}
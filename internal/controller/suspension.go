@@ -0,0 +1,59 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+// suspendedUntil returns the time until which the object is suspended with
+// .spec.suspendUntil, if it's after now.
+func suspendedUntil(obj *imagev1.ImageUpdateAutomation, now time.Time) (time.Time, bool) {
+	if obj.Spec.SuspendUntil == nil || !now.Before(obj.Spec.SuspendUntil.Time) {
+		return time.Time{}, false
+	}
+	return obj.Spec.SuspendUntil.Time, true
+}
+
+// reconcileSuspension records the suspension of the object with
+// .spec.suspendUntil in its status, and emits an event when the object is
+// suspended or resumes. It returns the delay until the end of the suspension,
+// or zero if the object isn't suspended.
+func (r *ImageUpdateAutomationReconciler) reconcileSuspension(ctx context.Context,
+	obj *imagev1.ImageUpdateAutomation, now time.Time) time.Duration {
+	until, ok := suspendedUntil(obj, now)
+	if !ok {
+		if obj.Status.SuspendedUntil != nil {
+			obj.Status.SuspendedUntil = nil
+			eventLogf(ctx, r.EventRecorder, obj, corev1.EventTypeNormal, imagev1.ResumedReason,
+				"reconciliation resumed")
+		}
+		return 0
+	}
+	if prev := obj.Status.SuspendedUntil; prev == nil || !prev.Time.Equal(until) {
+		obj.Status.SuspendedUntil = &metav1.Time{Time: until}
+		eventLogf(ctx, r.EventRecorder, obj, corev1.EventTypeNormal, imagev1.SuspendedReason,
+			"reconciliation suspended until %s", until.UTC().Format(time.RFC3339))
+	}
+	return until.Sub(now)
+}
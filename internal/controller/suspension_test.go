@@ -0,0 +1,68 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+func TestReconcileSuspension(t *testing.T) {
+	g := NewWithT(t)
+
+	recorder := record.NewFakeRecorder(32)
+	r := &ImageUpdateAutomationReconciler{EventRecorder: recorder}
+	obj := &imagev1.ImageUpdateAutomation{}
+	now := time.Date(2024, 10, 17, 10, 0, 0, 0, time.UTC)
+
+	// Not suspended.
+	g.Expect(r.reconcileSuspension(context.TODO(), obj, now)).To(BeZero())
+	g.Expect(obj.Status.SuspendedUntil).To(BeNil())
+	g.Expect(recorder.Events).To(BeEmpty())
+
+	// Suspended until a time in the future.
+	until := now.Add(time.Hour)
+	obj.Spec.SuspendUntil = &metav1.Time{Time: until}
+	g.Expect(r.reconcileSuspension(context.TODO(), obj, now)).To(Equal(time.Hour))
+	g.Expect(obj.Status.SuspendedUntil.Time).To(Equal(until))
+	g.Expect(recorder.Events).To(Receive(Equal("Normal Suspended reconciliation suspended until 2024-10-17T11:00:00Z")))
+
+	// Still suspended, with no new event.
+	g.Expect(r.reconcileSuspension(context.TODO(), obj, now.Add(30*time.Minute))).To(Equal(30 * time.Minute))
+	g.Expect(recorder.Events).To(BeEmpty())
+
+	// Suspension extended.
+	obj.Spec.SuspendUntil = &metav1.Time{Time: until.Add(time.Hour)}
+	g.Expect(r.reconcileSuspension(context.TODO(), obj, now.Add(30*time.Minute))).To(Equal(90 * time.Minute))
+	g.Expect(recorder.Events).To(Receive(Equal("Normal Suspended reconciliation suspended until 2024-10-17T12:00:00Z")))
+
+	// Resumed once the time has passed.
+	g.Expect(r.reconcileSuspension(context.TODO(), obj, now.Add(2*time.Hour))).To(BeZero())
+	g.Expect(obj.Status.SuspendedUntil).To(BeNil())
+	g.Expect(recorder.Events).To(Receive(Equal("Normal Resumed reconciliation resumed")))
+
+	// A time in the past doesn't suspend the object.
+	g.Expect(r.reconcileSuspension(context.TODO(), obj, now.Add(3*time.Hour))).To(BeZero())
+	g.Expect(recorder.Events).To(BeEmpty())
+}
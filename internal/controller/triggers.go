@@ -0,0 +1,32 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+// activeTriggers returns what triggers the runs of the object besides the
+// reconcile requests: its interval, and the changes of the ImagePolicies
+// unless either the object or the controller disables them.
+func (r *ImageUpdateAutomationReconciler) activeTriggers(obj *imagev1.ImageUpdateAutomation) []imagev1.Trigger {
+	triggers := []imagev1.Trigger{imagev1.TriggerInterval}
+	if !r.imagePolicyWatchDisabled && obj.Spec.Triggers.ImagePoliciesEnabled() {
+		triggers = append(triggers, imagev1.TriggerImagePolicies)
+	}
+	return triggers
+}
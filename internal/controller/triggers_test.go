@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	imagev1_reflect "github.com/fluxcd/image-reflector-controller/api/v1beta2"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+func TestActiveTriggers(t *testing.T) {
+	tests := []struct {
+		name         string
+		triggers     *imagev1.Triggers
+		watchDisable bool
+		want         []imagev1.Trigger
+	}{
+		{
+			name: "default",
+			want: []imagev1.Trigger{imagev1.TriggerInterval, imagev1.TriggerImagePolicies},
+		},
+		{
+			name:     "image policies enabled",
+			triggers: &imagev1.Triggers{ImagePolicies: ptr.To(true)},
+			want:     []imagev1.Trigger{imagev1.TriggerInterval, imagev1.TriggerImagePolicies},
+		},
+		{
+			name:     "image policies disabled",
+			triggers: &imagev1.Triggers{ImagePolicies: ptr.To(false)},
+			want:     []imagev1.Trigger{imagev1.TriggerInterval},
+		},
+		{
+			name:         "watch disabled",
+			triggers:     &imagev1.Triggers{ImagePolicies: ptr.To(true)},
+			watchDisable: true,
+			want:         []imagev1.Trigger{imagev1.TriggerInterval},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			r := &ImageUpdateAutomationReconciler{imagePolicyWatchDisabled: tt.watchDisable}
+			obj := &imagev1.ImageUpdateAutomation{Spec: imagev1.ImageUpdateAutomationSpec{Triggers: tt.triggers}}
+			g.Expect(r.activeTriggers(obj)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestAutomationsForImagePolicy(t *testing.T) {
+	g := NewWithT(t)
+
+	newAutomation := func(name string, triggers *imagev1.Triggers) client.Object {
+		return &imagev1.ImageUpdateAutomation{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       imagev1.ImageUpdateAutomationSpec{Triggers: triggers},
+		}
+	}
+	s := runtime.NewScheme()
+	g.Expect(imagev1.AddToScheme(s)).To(Succeed())
+	c := fakeclient.NewClientBuilder().WithScheme(s).WithObjects(
+		newAutomation("default", nil),
+		newAutomation("enabled", &imagev1.Triggers{ImagePolicies: ptr.To(true)}),
+		newAutomation("interval-only", &imagev1.Triggers{ImagePolicies: ptr.To(false)}),
+	).Build()
+	r := &ImageUpdateAutomationReconciler{Client: c}
+
+	policy := &imagev1_reflect.ImagePolicy{ObjectMeta: metav1.ObjectMeta{Name: "policy", Namespace: "default"}}
+	var names []string
+	for _, req := range r.automationsForImagePolicy(context.TODO(), policy) {
+		names = append(names, req.Name)
+	}
+	g.Expect(names).To(ConsistOf("default", "enabled"))
+}
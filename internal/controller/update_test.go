@@ -18,6 +18,7 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
 	"os"
@@ -522,6 +523,11 @@ func TestImageUpdateAutomationReconciler_Reconcile(t *testing.T) {
 				g.Expect(obj.Status.LastAutomationRunTime).ToNot(BeNil())
 				g.Expect(obj.Status.ObservedSourceRevision).To(ContainSubstring("%s@sha1", s.branch))
 				g.Expect(obj.Status.ObservedPolicies).To(HaveLen(1))
+				g.Expect(obj.Status.LastRunSummary).ToNot(BeNil())
+				g.Expect(obj.Status.LastRunSummary.SyncNeeded).To(BeTrue())
+				g.Expect(obj.Status.LastRunSummary.SyncReasons).To(ContainElement(imagev1.SyncReasonPoliciesChanged))
+				g.Expect(obj.Status.LastRunSummary.FilesChanged).To(Equal(1))
+				g.Expect(obj.Status.LastRunSummary.Push).To(Equal(imagev1.PushOutcomePushed))
 
 				// Check if the object status is valid.
 				condns := &conditionscheck.Conditions{NegativePolarity: imageUpdateAutomationNegativeConditions}
@@ -606,6 +612,10 @@ func TestImageUpdateAutomationReconciler_Reconcile(t *testing.T) {
 				g.Expect(obj.Status.ObservedSourceRevision).To(Equal(srcRevBefore))
 				g.Expect(obj.Status.LastPushCommit).To(Equal(pushCommitBefore))
 				g.Expect(obj.Status.LastPushTime).To(Equal(pushTimeBefore))
+				g.Expect(obj.Status.LastRunSummary).To(Equal(&imagev1.RunSummary{
+					SyncNeeded: false,
+					Checkout:   imagev1.CheckoutSkipped,
+				}))
 
 				// Push a new commit such that there's no new update and
 				// reconcile again.
@@ -1342,18 +1352,22 @@ func TestImageUpdateAutomationReconciler_notify(t *testing.T) {
 	g := NewWithT(t)
 	testPushResult, err := source.NewPushResult("branch", "rev", "test commit message")
 	g.Expect(err).ToNot(HaveOccurred())
+	testPolicyPushResult, err := source.NewPushResult("image-updates/policy", "rev2", "test commit message")
+	g.Expect(err).ToNot(HaveOccurred())
 
 	tests := []struct {
 		name             string
-		pushResult       *source.PushResult
+		pushResults      []*source.PushResult
 		syncNeeded       bool
+		skipped          skippedPolicies
+		failureReason    string
 		oldObjBeforeFunc func(obj conditions.Setter)
 		newObjBeforeFunc func(obj conditions.Setter)
 		wantEvent        string
+		wantSkipEvent    string
 	}{
 		{
 			name:       "first time reconciliation, no update",
-			pushResult: nil,
 			syncNeeded: true,
 			newObjBeforeFunc: func(obj conditions.Setter) {
 				conditions.MarkTrue(obj, meta.ReadyCondition, meta.SucceededReason, "%s", readyMessage)
@@ -1362,7 +1376,6 @@ func TestImageUpdateAutomationReconciler_notify(t *testing.T) {
 		},
 		{
 			name:       "second reconciliation, syncNeeded=false, no update",
-			pushResult: nil,
 			syncNeeded: false,
 			oldObjBeforeFunc: func(obj conditions.Setter) {
 				conditions.MarkTrue(obj, meta.ReadyCondition, meta.SucceededReason, "%s", readyMessage)
@@ -1373,9 +1386,24 @@ func TestImageUpdateAutomationReconciler_notify(t *testing.T) {
 			wantEvent: "Trace Succeeded no change since last reconciliation",
 		},
 		{
-			name:       "second reconciliation, syncNeeded=true, no update",
-			pushResult: nil,
+			name:       "second reconciliation, syncNeeded=false, skipped policies",
+			syncNeeded: false,
+			skipped:    skippedPolicies{skipReasonNoLatestImage: {"app"}},
+			oldObjBeforeFunc: func(obj conditions.Setter) {
+				conditions.MarkTrue(obj, meta.ReadyCondition, meta.SucceededReason, "%s", readyMessage)
+			},
+			newObjBeforeFunc: func(obj conditions.Setter) {
+				conditions.MarkTrue(obj, meta.ReadyCondition, meta.SucceededReason, "%s", readyMessage)
+			},
+			wantEvent: "Trace Succeeded no change since last reconciliation",
+		},
+		{
+			name:       "second reconciliation, syncNeeded=true, skipped policies",
 			syncNeeded: true,
+			skipped: skippedPolicies{
+				skipReasonNoLatestImage: {"app2", "app1"},
+				skipReasonNotSelected:   {"other"},
+			},
 			oldObjBeforeFunc: func(obj conditions.Setter) {
 				conditions.MarkTrue(obj, meta.ReadyCondition, meta.SucceededReason, "%s", readyMessage)
 			},
@@ -1383,10 +1411,11 @@ func TestImageUpdateAutomationReconciler_notify(t *testing.T) {
 				conditions.MarkTrue(obj, meta.ReadyCondition, meta.SucceededReason, "%s", readyMessage)
 			},
 			wantEvent: "Trace Succeeded repository up-to-date",
+			wantSkipEvent: "Trace PoliciesSkipped skipped policies: no latest image: app1, app2; " +
+				"not selected by the policy selector: other",
 		},
 		{
-			name:       "was ready, new update, is ready",
-			pushResult: testPushResult,
+			name:       "second reconciliation, syncNeeded=true, no update",
 			syncNeeded: true,
 			oldObjBeforeFunc: func(obj conditions.Setter) {
 				conditions.MarkTrue(obj, meta.ReadyCondition, meta.SucceededReason, "%s", readyMessage)
@@ -1394,11 +1423,35 @@ func TestImageUpdateAutomationReconciler_notify(t *testing.T) {
 			newObjBeforeFunc: func(obj conditions.Setter) {
 				conditions.MarkTrue(obj, meta.ReadyCondition, meta.SucceededReason, "%s", readyMessage)
 			},
+			wantEvent: "Trace Succeeded repository up-to-date",
+		},
+		{
+			name:        "was ready, new update, is ready",
+			pushResults: []*source.PushResult{testPushResult},
+			syncNeeded:  true,
+			oldObjBeforeFunc: func(obj conditions.Setter) {
+				conditions.MarkTrue(obj, meta.ReadyCondition, meta.SucceededReason, "%s", readyMessage)
+			},
+			newObjBeforeFunc: func(obj conditions.Setter) {
+				conditions.MarkTrue(obj, meta.ReadyCondition, meta.SucceededReason, "%s", readyMessage)
+			},
 			wantEvent: "Normal Succeeded pushed commit 'rev' to branch 'branch'\ntest commit message",
 		},
+		{
+			name:        "was ready, new updates of policy branches, is ready",
+			pushResults: []*source.PushResult{testPushResult, testPolicyPushResult},
+			syncNeeded:  true,
+			oldObjBeforeFunc: func(obj conditions.Setter) {
+				conditions.MarkTrue(obj, meta.ReadyCondition, meta.SucceededReason, "%s", readyMessage)
+			},
+			newObjBeforeFunc: func(obj conditions.Setter) {
+				conditions.MarkTrue(obj, meta.ReadyCondition, meta.SucceededReason, "%s", readyMessage)
+			},
+			wantEvent: "Normal Succeeded pushed commit 'rev' to branch 'branch'\ntest commit message\n\n" +
+				"pushed commit 'rev2' to branch 'image-updates/policy'\ntest commit message",
+		},
 		{
 			name:       "failure recovery, no update",
-			pushResult: nil,
 			syncNeeded: true,
 			oldObjBeforeFunc: func(obj conditions.Setter) {
 				conditions.MarkFalse(obj, meta.ReadyCondition, meta.FailedReason, "failed to checkout source")
@@ -1409,9 +1462,9 @@ func TestImageUpdateAutomationReconciler_notify(t *testing.T) {
 			wantEvent: "Normal Succeeded repository up-to-date",
 		},
 		{
-			name:       "failure recovery, with new update",
-			pushResult: testPushResult,
-			syncNeeded: true,
+			name:        "failure recovery, with new update",
+			pushResults: []*source.PushResult{testPushResult},
+			syncNeeded:  true,
 			oldObjBeforeFunc: func(obj conditions.Setter) {
 				conditions.MarkFalse(obj, meta.ReadyCondition, meta.FailedReason, "failed to checkout source")
 			},
@@ -1422,7 +1475,6 @@ func TestImageUpdateAutomationReconciler_notify(t *testing.T) {
 		},
 		{
 			name:       "failed",
-			pushResult: nil,
 			syncNeeded: true,
 			oldObjBeforeFunc: func(obj conditions.Setter) {
 				conditions.MarkTrue(obj, meta.ReadyCondition, meta.SucceededReason, "%s", readyMessage)
@@ -1432,6 +1484,18 @@ func TestImageUpdateAutomationReconciler_notify(t *testing.T) {
 			},
 			wantEvent: "Warning GitOperationFailed failed to checkout source",
 		},
+		{
+			name:          "failed with a specific reason",
+			syncNeeded:    true,
+			failureReason: imagev1.CloneFailedReason,
+			oldObjBeforeFunc: func(obj conditions.Setter) {
+				conditions.MarkTrue(obj, meta.ReadyCondition, meta.SucceededReason, "%s", readyMessage)
+			},
+			newObjBeforeFunc: func(obj conditions.Setter) {
+				conditions.MarkFalse(obj, meta.ReadyCondition, imagev1.GitOperationFailedReason, "failed to checkout source")
+			},
+			wantEvent: "Warning CloneFailed failed to checkout source",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -1451,23 +1515,50 @@ func TestImageUpdateAutomationReconciler_notify(t *testing.T) {
 			reconciler := &ImageUpdateAutomationReconciler{
 				EventRecorder: recorder,
 			}
-			reconciler.notify(ctx, oldObj, newObj, tt.pushResult, tt.syncNeeded)
-
-			select {
-			case x, ok := <-recorder.Events:
-				g.Expect(ok).To(Equal(tt.wantEvent != ""), "unexpected event received")
-				if tt.wantEvent != "" {
-					g.Expect(x).To(ContainSubstring(tt.wantEvent))
-				}
-			default:
-				if tt.wantEvent != "" {
-					g.Fail("expected some event to be emitted")
+			reconciler.notify(ctx, oldObj, newObj, tt.pushResults, tt.syncNeeded, tt.skipped, tt.failureReason)
+
+			for _, wantEvent := range []string{tt.wantEvent, tt.wantSkipEvent} {
+				select {
+				case x, ok := <-recorder.Events:
+					g.Expect(ok).To(Equal(wantEvent != ""), "unexpected event received")
+					if wantEvent != "" {
+						g.Expect(x).To(ContainSubstring(wantEvent))
+					}
+				default:
+					if wantEvent != "" {
+						g.Fail("expected some event to be emitted")
+					}
 				}
 			}
 		})
 	}
 }
 
+func Test_remotePushes(t *testing.T) {
+	g := NewWithT(t)
+
+	first, err := source.NewPushResult("auto/app1", "rev1", "msg",
+		source.WithPushResultRemotePushes([]source.RemotePushResult{
+			{Name: "mirror"},
+			{Name: "backup", Err: errors.New("connection refused")},
+		}))
+	g.Expect(err).ToNot(HaveOccurred())
+	second, err := source.NewPushResult("auto/app2", "rev2", "msg",
+		source.WithPushResultRemotePushes([]source.RemotePushResult{
+			{Name: "mirror"},
+			{Name: "backup"},
+		}))
+	g.Expect(err).ToNot(HaveOccurred())
+	noRemotes, err := source.NewPushResult("main", "rev3", "msg")
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(remotePushes([]*source.PushResult{noRemotes})).To(BeEmpty())
+	g.Expect(remotePushes([]*source.PushResult{first, second})).To(Equal([]imagev1.RemotePush{
+		{Name: "mirror", Commit: "rev2"},
+		{Name: "backup", Error: "connection refused"},
+	}))
+}
+
 func Test_getPolicies(t *testing.T) {
 	testNS1 := "foo"
 	testNS2 := "bar"
@@ -1485,6 +1576,7 @@ func Test_getPolicies(t *testing.T) {
 		selector      *metav1.LabelSelector
 		policies      []policyArgs
 		wantPolicies  []string
+		wantSkipped   skippedPolicies
 	}{
 		{
 			name:          "lists policies with image and in same namespace",
@@ -1496,6 +1588,7 @@ func Test_getPolicies(t *testing.T) {
 				{name: "p4", namespace: testNS1, latestImage: ""},
 			},
 			wantPolicies: []string{"p1", "p2"},
+			wantSkipped:  skippedPolicies{skipReasonNoLatestImage: {"p4"}},
 		},
 		{
 			name:          "lists policies with label selector in same namespace",
@@ -1511,6 +1604,7 @@ func Test_getPolicies(t *testing.T) {
 				{name: "p3", namespace: testNS2, latestImage: "eee:fff", labels: map[string]string{"label": "one"}},
 			},
 			wantPolicies: []string{"p1"},
+			wantSkipped:  skippedPolicies{skipReasonNotSelected: {"p2"}},
 		},
 		{
 			name:          "no policies in empty namespace",
@@ -1519,6 +1613,7 @@ func Test_getPolicies(t *testing.T) {
 				{name: "p1", namespace: testNS1, latestImage: "aaa:bbb"},
 			},
 			wantPolicies: []string{},
+			wantSkipped:  skippedPolicies{},
 		},
 	}
 	for _, tt := range tests {
@@ -1541,8 +1636,9 @@ func Test_getPolicies(t *testing.T) {
 				WithScheme(testEnv.GetScheme()).
 				WithObjects(testObjects...).Build()
 
-			result, err := getPolicies(context.TODO(), kClient, tt.listNamespace, tt.selector)
+			result, skipped, err := getPolicies(context.TODO(), kClient, tt.listNamespace, tt.selector, 0)
 			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(skipped).To(Equal(tt.wantSkipped))
 
 			// Extract policy name from the result and compare with the expected
 			// result.
@@ -1577,6 +1673,15 @@ func Test_observedPolicies(t *testing.T) {
 				"p4": imagev1.ImageRef{Name: "fff", Tag: "ggg:hhh"},
 			},
 		},
+		{
+			name: "policy pinned to a digest",
+			policyWithImage: map[string]string{
+				"p1": "aaa@sha256:8b3c8f3e7e8a9c1f0a9e2a7e1f4c4b3d2e1f0a9b8c7d6e5f4a3b2c1d0e9f8a7b",
+			},
+			want: imagev1.ObservedPolicies{
+				"p1": imagev1.ImageRef{Name: "aaa", Digest: "sha256:8b3c8f3e7e8a9c1f0a9e2a7e1f4c4b3d2e1f0a9b8c7d6e5f4a3b2c1d0e9f8a7b"},
+			},
+		},
 		{
 			name: "bad policy image with no tag",
 			policyWithImage: map[string]string{
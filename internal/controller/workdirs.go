@@ -0,0 +1,104 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	crtlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/fluxcd/image-automation-controller/internal/source"
+)
+
+// WorkingDirCleaner removes the working directories orphaned by the previous
+// runs of the controller when the manager starts, e.g. after a crash, for
+// them not to fill the disk of the node. It records the number of directories
+// it removed and their size.
+type WorkingDirCleaner struct {
+	root string
+
+	removedDirsCounter  prometheus.Counter
+	removedBytesCounter prometheus.Counter
+}
+
+var _ manager.LeaderElectionRunnable = &WorkingDirCleaner{}
+
+// MustMakeWorkingDirCleaner returns a new WorkingDirCleaner of the given
+// working directory, with its collectors registered in the controller-runtime
+// metrics registry, which panics if they are already registered.
+func MustMakeWorkingDirCleaner(root string) *WorkingDirCleaner {
+	c := NewWorkingDirCleaner(root)
+	crtlmetrics.Registry.MustRegister(c.Collectors()...)
+	return c
+}
+
+// NewWorkingDirCleaner returns a new WorkingDirCleaner of the given working
+// directory, the default directory for temporary files if it's empty.
+func NewWorkingDirCleaner(root string) *WorkingDirCleaner {
+	return &WorkingDirCleaner{
+		root: root,
+		removedDirsCounter: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "image_automation_orphaned_working_dirs_removed_total",
+				Help: "The number of orphaned working directories removed when the controller started.",
+			},
+		),
+		removedBytesCounter: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "image_automation_orphaned_working_dirs_removed_bytes_total",
+				Help: "The total size in bytes of the orphaned working directories removed when the controller started.",
+			},
+		),
+	}
+}
+
+// Collectors returns the Prometheus collectors of the WorkingDirCleaner.
+func (c *WorkingDirCleaner) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		c.removedDirsCounter,
+		c.removedBytesCounter,
+	}
+}
+
+// Start removes the orphaned working directories once. The directories it
+// fails to remove are logged, and removed by the next start.
+func (c *WorkingDirCleaner) Start(ctx context.Context) error {
+	log := ctrl.LoggerFrom(ctx)
+	removed, err := source.RemoveOrphanedWorkingDirs(c.root)
+	var size int64
+	for _, dir := range removed {
+		size += dir.Size
+		c.removedDirsCounter.Inc()
+		c.removedBytesCounter.Add(float64(dir.Size))
+	}
+	if len(removed) > 0 {
+		log.Info("removed orphaned working directories", "count", len(removed), "bytes", size)
+	}
+	if err != nil {
+		log.Error(err, "failed to remove orphaned working directories")
+	}
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable: the working
+// directories of each replica are removed, as they're local to it.
+func (c *WorkingDirCleaner) NeedLeaderElection() bool {
+	return false
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestWorkingDirCleaner(t *testing.T) {
+	g := NewWithT(t)
+
+	root := t.TempDir()
+	for _, name := range []string{"image-automation-a", "image-automation-b"} {
+		g.Expect(os.Mkdir(filepath.Join(root, name), 0o700)).To(Succeed())
+		g.Expect(os.WriteFile(filepath.Join(root, name, "file"), make([]byte, 512), 0o600)).To(Succeed())
+	}
+	g.Expect(os.Mkdir(filepath.Join(root, "other"), 0o700)).To(Succeed())
+
+	c := NewWorkingDirCleaner(root)
+	g.Expect(c.NeedLeaderElection()).To(BeFalse())
+	g.Expect(c.Start(context.TODO())).To(Succeed())
+	g.Expect(testutil.ToFloat64(c.removedDirsCounter)).To(Equal(float64(2)))
+	g.Expect(testutil.ToFloat64(c.removedBytesCounter)).To(Equal(float64(1024)))
+
+	entries, err := os.ReadDir(root)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(entries).To(HaveLen(1))
+	g.Expect(entries[0].Name()).To(Equal("other"))
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package export exports the changes pushed by the ImageUpdateAutomations as
+// JSON reports, for compliance tooling to track which workloads changed
+// image versions and when.
+package export
+
+import (
+	"cmp"
+	"context"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/fluxcd/image-automation-controller/pkg/update"
+)
+
+// SchemaVersion is the version of the schema of the reports. The fields of a
+// version are never removed nor changed, new fields may only be added.
+const SchemaVersion = "v1"
+
+// Report is the report of the changes of a push.
+type Report struct {
+	// SchemaVersion is the version of the schema of the report.
+	SchemaVersion string `json:"schemaVersion"`
+	// Automation is the namespaced name of the ImageUpdateAutomation.
+	Automation string `json:"automation"`
+	// Source is the namespaced name of the GitRepository the changes were
+	// pushed to.
+	Source string `json:"source"`
+	// URL is the URL of the Git repository.
+	URL string `json:"url"`
+	// Branch is the branch the commit was pushed to.
+	Branch string `json:"branch"`
+	// Commit is the hash of the pushed commit.
+	Commit string `json:"commit"`
+	// Tag is the name of the tag pushed along with the commit, if any.
+	Tag string `json:"tag,omitempty"`
+	// Time is the time of the commit, in RFC 3339 format.
+	Time string `json:"time"`
+	// Changes are the changes of the commit, sorted by file, object and
+	// setter.
+	Changes []Change `json:"changes"`
+}
+
+// Change is the change of a field of a workload by an image policy.
+type Change struct {
+	// File is the path of the file of the field, relative to the root of
+	// the repository.
+	File string `json:"file"`
+	// Object is the object of the field.
+	Object Object `json:"object"`
+	// Policy is the namespaced name of the ImagePolicy of the field marker.
+	Policy string `json:"policy"`
+	// Setter is the value of the field marker, e.g. "flux-system:app:tag".
+	Setter string `json:"setter"`
+	// OldValue is the value of the field before the change.
+	OldValue string `json:"oldValue"`
+	// NewValue is the value of the field after the change.
+	NewValue string `json:"newValue"`
+	// Workload is the workload running the image of the field, when the
+	// object is a custom resource whose images are run by other objects,
+	// e.g. a Kafka cluster.
+	Workload *Object `json:"workload,omitempty"`
+}
+
+// Object identifies a Kubernetes object.
+type Object struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name,omitempty"`
+}
+
+// Exporter exports the reports of the pushed changes.
+type Exporter interface {
+	Export(ctx context.Context, r Report) error
+}
+
+// Changes returns the changes of the given result, sorted by file, object and
+// setter, with the paths of the files prefixed with the given directory of the
+// update in the repository.
+func Changes(dir string, result update.ResultV2) []Change {
+	changes := []Change{}
+	for file, objects := range result.FileChanges {
+		file = path.Join(dir, file)
+		for oid, objChanges := range objects {
+			obj := Object{
+				APIVersion: oid.APIVersion,
+				Kind:       oid.Kind,
+				Namespace:  oid.Namespace,
+				Name:       oid.Name,
+			}
+			for _, c := range objChanges {
+				change := Change{
+					File:     file,
+					Object:   obj,
+					Policy:   policyName(c.Setter),
+					Setter:   c.Setter,
+					OldValue: c.OldValue,
+					NewValue: c.NewValue,
+				}
+				if !c.Workload.IsZero() {
+					change.Workload = &Object{
+						Kind:      c.Workload.Kind,
+						Namespace: oid.Namespace,
+						Name:      c.Workload.Name,
+					}
+				}
+				changes = append(changes, change)
+			}
+		}
+	}
+	slices.SortFunc(changes, func(a, b Change) int {
+		return cmp.Or(
+			cmp.Compare(a.File, b.File),
+			cmp.Compare(a.Object.APIVersion, b.Object.APIVersion),
+			cmp.Compare(a.Object.Kind, b.Object.Kind),
+			cmp.Compare(a.Object.Namespace, b.Object.Namespace),
+			cmp.Compare(a.Object.Name, b.Object.Name),
+			cmp.Compare(a.Setter, b.Setter),
+		)
+	})
+	return changes
+}
+
+// policyName returns the namespaced name of the ImagePolicy of the given
+// setter, which is "<namespace>:<name>" optionally followed by ":tag" or
+// ":name".
+func policyName(setter string) string {
+	parts := strings.SplitN(setter, ":", 3)
+	if len(parts) < 2 {
+		return setter
+	}
+	return parts[0] + "/" + parts[1]
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+
+	"github.com/fluxcd/image-automation-controller/pkg/update"
+)
+
+func TestChanges(t *testing.T) {
+	g := NewWithT(t)
+
+	deployment := update.ObjectIdentifier{ResourceIdentifier: yaml.ResourceIdentifier{
+		TypeMeta: yaml.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		NameMeta: yaml.NameMeta{Namespace: "apps", Name: "app"},
+	}}
+	kustomization := update.ObjectIdentifier{ResourceIdentifier: yaml.ResourceIdentifier{
+		TypeMeta: yaml.TypeMeta{APIVersion: "kustomize.config.k8s.io/v1beta1", Kind: "Kustomization"},
+	}}
+	result := update.ResultV2{}
+	result.AddChange("kustomization.yaml", kustomization, update.Change{
+		OldValue: "v1.0.0", NewValue: "v1.0.1", Setter: "flux-system:app:tag",
+	})
+	result.AddChange("app/deployment.yaml", deployment,
+		update.Change{OldValue: "v1.0.0", NewValue: "v1.0.1", Setter: "flux-system:app:tag"},
+		update.Change{OldValue: "sidecar:v2", NewValue: "sidecar:v3", Setter: "flux-system:sidecar"},
+	)
+
+	g.Expect(Changes("./clusters/prod", result)).To(Equal([]Change{
+		{
+			File:     "clusters/prod/app/deployment.yaml",
+			Object:   Object{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "apps", Name: "app"},
+			Policy:   "flux-system/app",
+			Setter:   "flux-system:app:tag",
+			OldValue: "v1.0.0",
+			NewValue: "v1.0.1",
+		},
+		{
+			File:     "clusters/prod/app/deployment.yaml",
+			Object:   Object{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "apps", Name: "app"},
+			Policy:   "flux-system/sidecar",
+			Setter:   "flux-system:sidecar",
+			OldValue: "sidecar:v2",
+			NewValue: "sidecar:v3",
+		},
+		{
+			File:     "clusters/prod/kustomization.yaml",
+			Object:   Object{APIVersion: "kustomize.config.k8s.io/v1beta1", Kind: "Kustomization"},
+			Policy:   "flux-system/app",
+			Setter:   "flux-system:app:tag",
+			OldValue: "v1.0.0",
+			NewValue: "v1.0.1",
+		},
+	}))
+	g.Expect(Changes("", update.ResultV2{})).To(BeEmpty())
+}
+
+func TestHTTPExporter(t *testing.T) {
+	report := Report{
+		SchemaVersion: SchemaVersion,
+		Automation:    "default/test-update",
+		Source:        "default/test-repo",
+		URL:           "https://example.com/org/repo",
+		Branch:        "main",
+		Commit:        "6a3f8b2d9c1e",
+		Time:          "2024-05-01T10:00:00Z",
+		Changes: []Change{{
+			File:     "deployment.yaml",
+			Object:   Object{APIVersion: "apps/v1", Kind: "Deployment", Name: "app"},
+			Policy:   "default/app",
+			Setter:   "default:app",
+			OldValue: "app:v1.0.0",
+			NewValue: "app:v1.0.1",
+		}},
+	}
+
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{name: "accepted", status: http.StatusAccepted},
+		{name: "failed request", status: http.StatusInternalServerError, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			var gotBody map[string]interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				g.Expect(r.Method).To(Equal(http.MethodPost))
+				g.Expect(r.Header.Get("Content-Type")).To(Equal("application/json"))
+				g.Expect(json.NewDecoder(r.Body).Decode(&gotBody)).To(Succeed())
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			err := NewHTTPExporter(server.URL).Export(context.TODO(), report)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			// The field names are part of the schema.
+			g.Expect(gotBody).To(HaveKeyWithValue("schemaVersion", "v1"))
+			g.Expect(gotBody).To(HaveKeyWithValue("commit", "6a3f8b2d9c1e"))
+			g.Expect(gotBody).ToNot(HaveKey("tag"))
+			g.Expect(gotBody["changes"]).To(ConsistOf(map[string]interface{}{
+				"file": "deployment.yaml",
+				"object": map[string]interface{}{
+					"apiVersion": "apps/v1",
+					"kind":       "Deployment",
+					"name":       "app",
+				},
+				"policy":   "default/app",
+				"setter":   "default:app",
+				"oldValue": "app:v1.0.0",
+				"newValue": "app:v1.0.1",
+			}))
+		})
+	}
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package export
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/fluxcd/image-automation-controller/internal/httpjson"
+)
+
+// httpExporter POSTs the reports as JSON to an HTTP endpoint.
+type httpExporter struct {
+	address string
+	client  *http.Client
+}
+
+// NewHTTPExporter returns an Exporter POSTing the reports as JSON to the
+// given address.
+func NewHTTPExporter(address string) Exporter {
+	return &httpExporter{
+		address: address,
+		client:  httpjson.NewClient(),
+	}
+}
+
+// Export implements Exporter.
+func (e *httpExporter) Export(ctx context.Context, r Report) error {
+	if err := httpjson.Post(ctx, e.client, e.address, "", "", r); err != nil {
+		return fmt.Errorf("failed to post report: %w", err)
+	}
+	return nil
+}
@@ -37,6 +37,28 @@ const (
 	// When enabled, it will cache both object types, resulting in increased
 	// memory usage and cluster-wide RBAC permissions (list and watch).
 	CacheSecretsAndConfigMaps = "CacheSecretsAndConfigMaps"
+	// GitCloneCache enables an on-disk cache of the Git objects of the
+	// sources, so that clones only fetch what changed since the previous
+	// reconciliation.
+	GitCloneCache = "GitCloneCache"
+	// GitInMemoryWorkTree enables checking out the sources in memory instead
+	// of in temporary directories, so that no writable filesystem is
+	// required for the worktrees.
+	GitInMemoryWorkTree = "GitInMemoryWorkTree"
+	// GitFaultInjection enables the injection of synthetic failures in the
+	// pushes of the automations of the namespaces given with the
+	// --fault-injection-namespaces flag, for testing the resilience and the
+	// alerting. It's meant for test environments only.
+	GitFaultInjection = "GitFaultInjection"
+	// GitFollowRedirects enables following the HTTP redirects of the remote
+	// repositories, e.g. of a renamed repository, instead of failing with the
+	// URL the repository moved to.
+	GitFollowRedirects = "GitFollowRedirects"
+
+	// TemplateImageRepositories enables giving the ImageRepository of each
+	// policy to the commit message templates, which requires reading the
+	// ImageRepositories of the policies.
+	TemplateImageRepositories = "TemplateImageRepositories"
 )
 
 var features = map[string]bool{
@@ -55,6 +77,26 @@ var features = map[string]bool{
 	// CacheSecretsAndConfigMaps
 	// opt-in from v0.29
 	CacheSecretsAndConfigMaps: false,
+
+	// GitCloneCache
+	// opt-in from v0.40
+	GitCloneCache: false,
+
+	// GitInMemoryWorkTree
+	// opt-in from v0.40
+	GitInMemoryWorkTree: false,
+
+	// GitFaultInjection
+	// opt-in from v0.40
+	GitFaultInjection: false,
+
+	// GitFollowRedirects
+	// opt-in from v0.40
+	GitFollowRedirects: false,
+
+	// TemplateImageRepositories
+	// opt-in from v0.40
+	TemplateImageRepositories: false,
 }
 
 // FeatureGates contains a list of all supported feature gates and
@@ -0,0 +1,89 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package hook notifies the post-push hooks of an ImageUpdateAutomation of
+// its successful pushes.
+package hook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+const (
+	addressKey = "address"
+	tokenKey   = "token"
+)
+
+// ErrUnsupportedType is the error of a hook with a provider type which isn't
+// supported.
+var ErrUnsupportedType = errors.New("unsupported post-push hook type")
+
+// Notification is the message a hook is notified with after a successful
+// push.
+type Notification struct {
+	// Automation is the namespaced name of the ImageUpdateAutomation.
+	Automation string `json:"automation"`
+	// Branch is the branch the commit was pushed to.
+	Branch string `json:"branch"`
+	// Commit is the hash of the pushed commit.
+	Commit string `json:"commit"`
+	// Tag is the name of the tag pushed along with the commit, if any.
+	Tag string `json:"tag,omitempty"`
+	// Images are the sorted references of the images updated by the commit.
+	Images []string `json:"images"`
+}
+
+// Notifier notifies a provider of successful pushes.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// NewNotifier returns the Notifier of the given hook of an
+// ImageUpdateAutomation in the given namespace, configured with the Secret the
+// hook refers to, if any.
+func NewNotifier(ctx context.Context, c client.Client, namespace string, hook imagev1.PostPushHook) (Notifier, error) {
+	address := hook.Address
+	var token string
+	if hook.SecretRef != nil {
+		key := types.NamespacedName{Namespace: namespace, Name: hook.SecretRef.Name}
+		var secret corev1.Secret
+		if err := c.Get(ctx, key, &secret); err != nil {
+			return nil, fmt.Errorf("failed to get post-push hook secret '%s': %w", key, err)
+		}
+		if v, ok := secret.Data[addressKey]; ok {
+			address = string(v)
+		}
+		token = string(secret.Data[tokenKey])
+	}
+	if address == "" {
+		return nil, fmt.Errorf("post-push hook '%s' has no address", hook.Name)
+	}
+
+	switch hook.Type {
+	case "", imagev1.PostPushHookTypeHTTP:
+		return newHTTPNotifier(address, token), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, hook.Type)
+	}
+}
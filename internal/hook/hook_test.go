@@ -0,0 +1,132 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/fluxcd/pkg/apis/meta"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+func TestNotifier_http(t *testing.T) {
+	notification := Notification{
+		Automation: "default/test-update",
+		Branch:     "main",
+		Commit:     "6a3f8b2d9c1e",
+		Images:     []string{"helloworld:v1.0.1"},
+	}
+
+	tests := []struct {
+		name       string
+		hook       imagev1.PostPushHook
+		status     int
+		wantToken  string
+		wantNewErr bool
+		wantErr    bool
+	}{
+		{
+			name:   "address in spec",
+			hook:   imagev1.PostPushHook{Name: "ci", Type: imagev1.PostPushHookTypeHTTP},
+			status: http.StatusOK,
+		},
+		{
+			name: "address and token in secret",
+			hook: imagev1.PostPushHook{
+				Name:      "ci",
+				Address:   "http://overridden.example.com",
+				SecretRef: &meta.LocalObjectReference{Name: "hook-secret"},
+			},
+			status:    http.StatusAccepted,
+			wantToken: "Bearer s3cr3t",
+		},
+		{
+			name:    "failed request",
+			hook:    imagev1.PostPushHook{Name: "ci"},
+			status:  http.StatusInternalServerError,
+			wantErr: true,
+		},
+		{
+			name: "missing secret",
+			hook: imagev1.PostPushHook{
+				Name:      "ci",
+				SecretRef: &meta.LocalObjectReference{Name: "non-existing"},
+			},
+			wantNewErr: true,
+		},
+		{
+			name:       "unsupported type",
+			hook:       imagev1.PostPushHook{Name: "ci", Type: "nats"},
+			wantNewErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			var gotNotification Notification
+			var gotToken string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				g.Expect(r.Method).To(Equal(http.MethodPost))
+				g.Expect(r.Header.Get("Content-Type")).To(Equal("application/json"))
+				g.Expect(json.NewDecoder(r.Body).Decode(&gotNotification)).To(Succeed())
+				gotToken = r.Header.Get("Authorization")
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "hook-secret", Namespace: "default"},
+				Data: map[string][]byte{
+					"address": []byte(server.URL),
+					"token":   []byte("s3cr3t"),
+				},
+			}
+			c := fakeclient.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(secret).Build()
+
+			if tt.hook.SecretRef == nil {
+				tt.hook.Address = server.URL
+			}
+			notifier, err := NewNotifier(context.TODO(), c, "default", tt.hook)
+			if tt.wantNewErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+
+			err = notifier.Notify(context.TODO(), notification)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(gotNotification).To(Equal(notification))
+			g.Expect(gotToken).To(Equal(tt.wantToken))
+		})
+	}
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/fluxcd/image-automation-controller/internal/httpjson"
+)
+
+// httpNotifier POSTs the notifications as JSON to an HTTP endpoint.
+type httpNotifier struct {
+	address string
+	token   string
+	client  *http.Client
+}
+
+func newHTTPNotifier(address, token string) *httpNotifier {
+	return &httpNotifier{
+		address: address,
+		token:   token,
+		client:  httpjson.NewClient(),
+	}
+}
+
+// Notify implements Notifier.
+func (n *httpNotifier) Notify(ctx context.Context, notification Notification) error {
+	var auth string
+	if n.token != "" {
+		auth = "Bearer " + n.token
+	}
+	if err := httpjson.Post(ctx, n.client, n.address, "Authorization", auth, notification); err != nil {
+		return fmt.Errorf("failed to post notification: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package httpjson sends the JSON requests of the hooks, the exporters and
+// the commit checks to HTTP endpoints.
+package httpjson
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Timeout is the timeout of the requests of the clients returned by
+// NewClient.
+const Timeout = 15 * time.Second
+
+// NewClient returns an HTTP client whose requests time out after Timeout.
+func NewClient() *http.Client {
+	return &http.Client{Timeout: Timeout}
+}
+
+// Get sends a GET request to the given URL with the given authentication
+// header, when its value isn't empty, and decodes the JSON body of the
+// response into v when its status is OK.
+func Get(ctx context.Context, c *http.Client, url, authHeader, authValue string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if authValue != "" {
+		req.Header.Set(authHeader, authValue)
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer closeBody(resp)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status '%s'", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// Post sends a POST request with the given body encoded as JSON to the given
+// URL with the given authentication header, when its value isn't empty, and
+// checks that it succeeded.
+func Post(ctx context.Context, c *http.Client, url, authHeader, authValue string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	if authValue != "" {
+		req.Header.Set(authHeader, authValue)
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer closeBody(resp)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status '%s'", resp.Status)
+	}
+	return nil
+}
+
+// closeBody drains and closes the body of the given response, for its
+// connection to be reused.
+func closeBody(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	resp.Body.Close()
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httpjson
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestPost(t *testing.T) {
+	g := NewWithT(t)
+
+	var got map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" ||
+			r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	err := Post(context.TODO(), NewClient(), server.URL, "Authorization", "Bearer token", map[string]string{"state": "success"})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(got).To(Equal(map[string]string{"state": "success"}))
+
+	err = Post(context.TODO(), NewClient(), server.URL, "", "", map[string]string{"state": "success"})
+	g.Expect(err).To(MatchError("unexpected status '400 Bad Request'"))
+}
+
+func TestGet(t *testing.T) {
+	g := NewWithT(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get("PRIVATE-TOKEN") != "token":
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/invalid":
+			_, _ = w.Write([]byte("not json"))
+		default:
+			_, _ = w.Write([]byte(`[{"status":"success"}]`))
+		}
+	}))
+	defer server.Close()
+
+	var statuses []map[string]string
+	g.Expect(Get(context.TODO(), NewClient(), server.URL, "PRIVATE-TOKEN", "token", &statuses)).To(Succeed())
+	g.Expect(statuses).To(Equal([]map[string]string{{"status": "success"}}))
+
+	err := Get(context.TODO(), NewClient(), server.URL, "PRIVATE-TOKEN", "", &statuses)
+	g.Expect(err).To(MatchError("unexpected status '401 Unauthorized'"))
+
+	err = Get(context.TODO(), NewClient(), server.URL+"/invalid", "PRIVATE-TOKEN", "token", &statuses)
+	g.Expect(err).To(MatchError(ContainSubstring("failed to decode response")))
+}
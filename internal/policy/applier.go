@@ -17,17 +17,28 @@ limitations under the License.
 package policy
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
 
 	securejoin "github.com/cyphar/filepath-securejoin"
 	"github.com/fluxcd/pkg/runtime/logger"
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	imagev1_reflect "github.com/fluxcd/image-reflector-controller/api/v1beta2"
 
 	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+	"github.com/fluxcd/image-automation-controller/internal/validation"
 	"github.com/fluxcd/image-automation-controller/pkg/update"
 )
 
@@ -38,29 +49,595 @@ var (
 	// ErrUnsupportedUpdateStrategy is an update error when the provided update
 	// strategy is not supported.
 	ErrUnsupportedUpdateStrategy = errors.New("unsupported update strategy")
+	// ErrIncompleteUpdate is an update error when some files couldn't be
+	// processed by an atomic update.
+	ErrIncompleteUpdate = errors.New("incomplete atomic update")
+	// ErrInvalidUpdatePathHint is an update error when the update path
+	// annotation of a policy isn't a relative path within the source.
+	ErrInvalidUpdatePathHint = errors.New("invalid update path hint")
 )
 
+// ApplyOptions contains the optional attributes of ApplyPolicies.
+type ApplyOptions struct {
+	workers      int
+	memoryBudget int64
+	workTree     billy.Filesystem
+	updatePath   *string
+}
+
+// ApplyOption configures the ApplyPolicies options.
+type ApplyOption func(*ApplyOptions)
+
+// WithApplyOptionWorkers configures the number of workers used to apply the
+// policies to the files concurrently. If zero or less, one worker per usable
+// CPU is used.
+func WithApplyOptionWorkers(workers int) ApplyOption {
+	return func(ao *ApplyOptions) {
+		ao.workers = workers
+	}
+}
+
+// WithApplyOptionMemoryBudget configures the estimated memory in bytes the
+// files being updated may take at once. If zero or less, all the files are
+// updated at once.
+func WithApplyOptionMemoryBudget(bytes int64) ApplyOption {
+	return func(ao *ApplyOptions) {
+		ao.memoryBudget = bytes
+	}
+}
+
+// WithApplyOptionWorkTree configures ApplyPolicies to apply the policies to
+// the source in the given billy.Filesystem, e.g., a source checked out in
+// memory, instead of on disk. The workDir is then a path in the filesystem.
+func WithApplyOptionWorkTree(wt billy.Filesystem) ApplyOption {
+	return func(ao *ApplyOptions) {
+		ao.workTree = wt
+	}
+}
+
+// WithApplyOptionUpdatePath configures ApplyPolicies to update the given
+// path instead of the update path of the ImageUpdateAutomation, e.g. the
+// rendering of its template.
+func WithApplyOptionUpdatePath(updatePath string) ApplyOption {
+	return func(ao *ApplyOptions) {
+		ao.updatePath = &updatePath
+	}
+}
+
+// path returns the update path of the given ImageUpdateAutomation, unless
+// overridden by the options.
+func (ao *ApplyOptions) path(obj *imagev1.ImageUpdateAutomation) string {
+	if ao.updatePath != nil {
+		return *ao.updatePath
+	}
+	return obj.Spec.Update.Path
+}
+
 // ApplyPolicies applies the given set of policies on the source present in the
 // workDir based on the provided ImageUpdateAutomation configuration.
-func ApplyPolicies(ctx context.Context, workDir string, obj *imagev1.ImageUpdateAutomation, policies []imagev1_reflect.ImagePolicy) (update.ResultV2, error) {
+func ApplyPolicies(ctx context.Context, workDir string, obj *imagev1.ImageUpdateAutomation, policies []imagev1_reflect.ImagePolicy, options ...ApplyOption) (update.ResultV2, error) {
+	opts := &ApplyOptions{}
+	for _, o := range options {
+		o(opts)
+	}
+
 	var result update.ResultV2
 	if obj.Spec.Update == nil {
 		return result, ErrNoUpdateStrategy
 	}
-	if obj.Spec.Update.Strategy != imagev1.UpdateStrategySetters {
+	switch obj.Spec.Update.Strategy {
+	case imagev1.UpdateStrategySetters, imagev1.UpdateStrategyKustomizeImages:
+	default:
 		return result, fmt.Errorf("%w: %s", ErrUnsupportedUpdateStrategy, obj.Spec.Update.Strategy)
 	}
 
-	// Resolve the path to the manifests to apply policies on.
-	manifestPath := workDir
-	if obj.Spec.Update.Path != "" {
-		p, err := securejoin.SecureJoin(workDir, obj.Spec.Update.Path)
+	// Resolve the path to the manifests to apply policies on. A glob update
+	// path is resolved to its base directory, and expanded to the matching
+	// directories below it.
+	updatePath := opts.path(obj)
+	basePath, err := resolveUpdatePathBase(workDir, updatePath, opts.workTree)
+	if err != nil {
+		return result, err
+	}
+	_, patterns := splitUpdatePath(updatePath)
+	manifestPaths := []string{basePath}
+	if len(patterns) > 0 {
+		manifestPaths, err = expandUpdatePath(workDir, basePath, patterns, opts.workTree)
 		if err != nil {
-			return result, fmt.Errorf("failed to secure join manifest path: %w", err)
+			return result, err
+		}
+		if len(manifestPaths) == 0 {
+			return result, fmt.Errorf("update path '%s' matches no directory", updatePath)
 		}
-		manifestPath = p
+	}
+
+	// Apply the policies in a stable order, whatever the order they were
+	// listed in, for the changes to be the same on every run.
+	policies = slices.Clone(policies)
+	slices.SortFunc(policies, func(a, b imagev1_reflect.ImagePolicy) int {
+		return cmp.Or(cmp.Compare(a.Namespace, b.Namespace), cmp.Compare(a.Name, b.Name))
+	})
+
+	// Prefer the pinned images over the latest images of the policies.
+	policies, _ = PinPolicies(obj, policies)
+
+	hints, err := updatePathHints(policies)
+	if err != nil {
+		return result, err
 	}
 
 	tracelog := log.FromContext(ctx).V(logger.TraceLevel)
-	return update.UpdateV2WithSetters(tracelog, manifestPath, manifestPath, policies)
+	updateOpts := []update.UpdateOption{
+		update.WithUpdateOptionWorkers(opts.workers),
+		update.WithUpdateOptionMemoryBudget(opts.memoryBudget),
+	}
+	if opts.workTree != nil {
+		updateOpts = append(updateOpts, update.WithUpdateOptionWorkTree(opts.workTree))
+	}
+	// Detect the conflicts with the images observed in the previous update.
+	if len(obj.Status.ObservedPolicies) > 0 {
+		previous := make(map[types.NamespacedName]string, len(obj.Status.ObservedPolicies))
+		for name, ref := range obj.Status.ObservedPolicies {
+			previous[types.NamespacedName{Namespace: obj.Namespace, Name: name}] = ref.String()
+		}
+		updateOpts = append(updateOpts, update.WithUpdateOptionConflictPolicy(update.ConflictPolicy(obj.Spec.Update.ConflictPolicy), previous))
+	}
+	if obj.Spec.Update.MaxSemverJump != "" {
+		updateOpts = append(updateOpts, update.WithUpdateOptionMaxSemverJump(update.SemverJump(obj.Spec.Update.MaxSemverJump)))
+	}
+	// The symbolic links are never followed outside of the source.
+	updateOpts = append(updateOpts, update.WithUpdateOptionSymlinkPolicy(update.SymlinkPolicy(obj.Spec.Update.SymlinkPolicy), workDir))
+	if obj.Spec.Update.HelmTemplates == imagev1.HelmTemplatesScan {
+		updateOpts = append(updateOpts, update.WithUpdateOptionScanTemplates())
+	}
+	var codeOwners []byte
+	if obj.Spec.Update.Owner != "" {
+		updateOpts = append(updateOpts, update.WithUpdateOptionOwner(obj.Spec.Update.Owner))
+		if codeOwners, err = readCodeOwners(workDir, opts.workTree); err != nil {
+			return result, err
+		}
+	}
+
+	// The files of the results of the matching directories are relative to
+	// the base directory of the glob update path.
+	for _, manifestPath := range manifestPaths {
+		sourceDir, err := filepath.Rel(workDir, manifestPath)
+		if err != nil {
+			return result, err
+		}
+		sourceDir = filepath.ToSlash(sourceDir)
+		pathOpts := updateOpts
+		if obj.Spec.Update.Owner != "" {
+			pathOpts = append(slices.Clip(pathOpts), update.WithUpdateOptionCodeOwners(codeOwners, sourceDir))
+		}
+		pathPolicies, policyPaths := scopePolicies(policies, hints, sourceDir)
+		if len(pathPolicies) == 0 {
+			continue
+		}
+		if len(policyPaths) > 0 {
+			pathOpts = append(slices.Clip(pathOpts), update.WithUpdateOptionPolicyPaths(policyPaths))
+		}
+		var pathResult update.ResultV2
+		if obj.Spec.Update.Strategy == imagev1.UpdateStrategyKustomizeImages {
+			pathResult, err = update.UpdateKustomizeImages(tracelog, manifestPath, pathPolicies, pathOpts...)
+		} else {
+			pathResult, err = update.UpdateV2WithSetters(tracelog, manifestPath, manifestPath, pathPolicies, pathOpts...)
+			// The data fields are set once the marked fields are, the
+			// files holding both being updated in turn.
+			if err == nil && len(obj.Spec.Update.DataFields) > 0 {
+				var dataResult update.ResultV2
+				dataResult, err = update.UpdateDataFields(tracelog, manifestPath, pathPolicies, dataFields(obj), pathOpts...)
+				pathResult.Add(dataResult)
+			}
+		}
+		if len(patterns) == 0 {
+			if err != nil {
+				return result, err
+			}
+			result = pathResult
+			continue
+		}
+		dir, relErr := filepath.Rel(basePath, manifestPath)
+		if relErr != nil {
+			return result, relErr
+		}
+		dir = filepath.ToSlash(dir)
+		if err != nil {
+			var fileErr *update.FileError
+			if errors.As(err, &fileErr) {
+				fileErr.Path = path.Join(dir, fileErr.Path)
+			}
+			return result, err
+		}
+		result.Merge(dir, pathResult)
+	}
+
+	// An atomic update is all or nothing.
+	if obj.Spec.Update.Atomic && len(result.SkippedFiles) > 0 {
+		return result, incompleteUpdateError(result.SkippedFiles)
+	}
+
+	// Record the images of the policies in the lock file at the root of the
+	// update path.
+	if obj.Spec.Update.LockFile {
+		changed, err := update.WriteLockFile(basePath, policies, updateOpts...)
+		if err != nil {
+			return result, err
+		}
+		if changed {
+			result.LockFile = update.LockFileName
+		}
+	}
+	return result, nil
+}
+
+// dataFields returns the data fields of the given automation, with the
+// setters of their policies, which are in the namespace of the automation.
+func dataFields(obj *imagev1.ImageUpdateAutomation) []update.DataField {
+	fields := make([]update.DataField, 0, len(obj.Spec.Update.DataFields))
+	for _, f := range obj.Spec.Update.DataFields {
+		setter := fmt.Sprintf("%s:%s", obj.Namespace, f.Policy)
+		switch f.Value {
+		case imagev1.DataFieldValueTag:
+			setter += ":tag"
+		case imagev1.DataFieldValueName:
+			setter += ":name"
+		}
+		fields = append(fields, update.DataField{
+			Kind:   f.Kind,
+			Name:   f.Name,
+			Key:    f.Key,
+			Path:   f.Path,
+			Setter: setter,
+			Base64: f.Base64,
+		})
+	}
+	return fields
+}
+
+// updatePathHints returns the cleaned paths of the UpdatePathAnnotation of
+// the given policies, by policy. A path must be relative to the root of the
+// source, and within it.
+func updatePathHints(policies []imagev1_reflect.ImagePolicy) (map[types.NamespacedName]string, error) {
+	var hints map[types.NamespacedName]string
+	for _, policy := range policies {
+		hint, ok := policy.GetAnnotations()[imagev1.UpdatePathAnnotation]
+		if !ok {
+			continue
+		}
+		p := path.Clean(filepath.ToSlash(strings.TrimSpace(hint)))
+		if hint == "" || path.IsAbs(p) || p == ".." || strings.HasPrefix(p, "../") {
+			return nil, fmt.Errorf("%w: policy '%s' has update path '%s', which isn't a relative path within the source",
+				ErrInvalidUpdatePathHint, policy.Name, hint)
+		}
+		if hints == nil {
+			hints = map[types.NamespacedName]string{}
+		}
+		hints[types.NamespacedName{Namespace: policy.Namespace, Name: policy.Name}] = p
+	}
+	return hints, nil
+}
+
+// scopePolicies returns the policies to apply to the given directory of the
+// source, along with the paths relative to it the policies are restricted
+// to. The policies whose hint is outside of the directory are left out, and
+// the ones whose hint contains it aren't restricted.
+func scopePolicies(policies []imagev1_reflect.ImagePolicy, hints map[types.NamespacedName]string, sourceDir string) ([]imagev1_reflect.ImagePolicy, map[types.NamespacedName]string) {
+	if len(hints) == 0 {
+		return policies, nil
+	}
+	var paths map[types.NamespacedName]string
+	result := make([]imagev1_reflect.ImagePolicy, 0, len(policies))
+	for _, policy := range policies {
+		key := types.NamespacedName{Namespace: policy.Namespace, Name: policy.Name}
+		hint, ok := hints[key]
+		switch {
+		case !ok || update.IsWithinPath(sourceDir, hint):
+		case update.IsWithinPath(hint, sourceDir):
+			if paths == nil {
+				paths = map[types.NamespacedName]string{}
+			}
+			rel := hint
+			if dir := path.Clean(sourceDir); dir != "." {
+				rel = strings.TrimPrefix(hint, dir+"/")
+			}
+			paths[key] = rel
+		default:
+			continue
+		}
+		result = append(result, policy)
+	}
+	return result, paths
+}
+
+// incompleteUpdateError returns the error of an atomic update naming the
+// files which couldn't be processed, with the reason they were skipped for.
+func incompleteUpdateError(skippedFiles map[string]string) error {
+	files := slices.Sorted(maps.Keys(skippedFiles))
+	reasons := make([]string, 0, len(files))
+	for _, file := range files {
+		reasons = append(reasons, fmt.Sprintf("'%s' (%s)", file, skippedFiles[file]))
+	}
+	return fmt.Errorf("%w: files couldn't be processed: %s", ErrIncompleteUpdate, strings.Join(reasons, ", "))
+}
+
+// ValidateChanges validates the files changed by the given result of
+// ApplyPolicies, against the schemas of the built-in Kubernetes kinds. The
+// options must be the same as the ones the policies were applied with, for
+// the files to be read from the same worktree. It returns a
+// *validation.Error listing the invalid fields, if any.
+func ValidateChanges(workDir string, obj *imagev1.ImageUpdateAutomation, result update.ResultV2, options ...ApplyOption) error {
+	opts := &ApplyOptions{}
+	for _, o := range options {
+		o(opts)
+	}
+	if obj.Spec.Update == nil {
+		return ErrNoUpdateStrategy
+	}
+
+	basePath, err := resolveUpdatePathBase(workDir, opts.path(obj), opts.workTree)
+	if err != nil {
+		return err
+	}
+	files := make(map[string][]byte, len(result.FileChanges))
+	for file := range result.FileChanges {
+		p := filepath.Join(basePath, filepath.FromSlash(file))
+		var b []byte
+		if opts.workTree != nil {
+			b, err = util.ReadFile(opts.workTree, p)
+		} else {
+			b, err = os.ReadFile(p)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read changed file '%s': %w", file, err)
+		}
+		files[file] = b
+	}
+	return validation.Validate(files)
+}
+
+// UpdatePathBase returns the directory of the given update path before its
+// first segment with a glob pattern, to which the files of the results are
+// relative, or the update path itself when it has no glob pattern. The update
+// path of a template which isn't rendered is the directory before its first
+// action.
+func UpdatePathBase(updatePath string) string {
+	if i := strings.Index(updatePath, "{{"); i >= 0 {
+		updatePath = path.Dir(updatePath[:i])
+	}
+	base, patterns := splitUpdatePath(updatePath)
+	if len(patterns) == 0 {
+		return updatePath
+	}
+	return base
+}
+
+// resolveUpdatePathBase returns the path of the base directory of the given
+// update path within the working directory. A billy.Filesystem implements the
+// securejoin.VFS interface, to resolve the symlinks within the filesystem.
+func resolveUpdatePathBase(workDir, updatePath string, wt billy.Filesystem) (string, error) {
+	base, _ := splitUpdatePath(updatePath)
+	if base == "" {
+		return workDir, nil
+	}
+	var vfs securejoin.VFS
+	if wt != nil {
+		vfs = wt
+	}
+	p, err := securejoin.SecureJoinVFS(workDir, base, vfs)
+	if err != nil {
+		return "", fmt.Errorf("failed to secure join manifest path: %w", err)
+	}
+	return p, nil
+}
+
+// splitUpdatePath splits the given update path into its directory before the
+// first segment with a glob pattern, and the segments from it, if any.
+func splitUpdatePath(updatePath string) (string, []string) {
+	if updatePath == "" {
+		return "", nil
+	}
+	segments := strings.Split(path.Clean(filepath.ToSlash(updatePath)), "/")
+	for i, segment := range segments {
+		if strings.ContainsAny(segment, "*?[") {
+			return path.Join(segments[:i]...), segments[i:]
+		}
+	}
+	return updatePath, nil
+}
+
+// expandUpdatePath returns the sorted directories below the basePath
+// matching the given segments of a glob update path, resolved within the
+// workDir. Like in a shell, the hidden directories are only matched by a
+// segment starting with a dot.
+func expandUpdatePath(workDir, basePath string, patterns []string, wt billy.Filesystem) ([]string, error) {
+	var vfs securejoin.VFS
+	if wt != nil {
+		vfs = wt
+	}
+	dirs := []string{basePath}
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid update path pattern '%s': %w", pattern, err)
+		}
+		var matches []string
+		for _, dir := range dirs {
+			names, err := readDirNames(dir, wt)
+			if err != nil {
+				return nil, fmt.Errorf("failed to expand update path: %w", err)
+			}
+			for _, name := range names {
+				if strings.HasPrefix(name, ".") && !strings.HasPrefix(pattern, ".") {
+					continue
+				}
+				if ok, _ := path.Match(pattern, name); !ok {
+					continue
+				}
+				rel, err := filepath.Rel(workDir, filepath.Join(dir, name))
+				if err != nil {
+					return nil, err
+				}
+				p, err := securejoin.SecureJoinVFS(workDir, rel, vfs)
+				if err != nil {
+					return nil, fmt.Errorf("failed to secure join manifest path: %w", err)
+				}
+				if isDir(p, wt) {
+					matches = append(matches, p)
+				}
+			}
+		}
+		slices.Sort(matches)
+		dirs = slices.Compact(matches)
+	}
+	return dirs, nil
+}
+
+// readDirNames returns the names of the entries of the given directory.
+func readDirNames(dir string, wt billy.Filesystem) ([]string, error) {
+	var names []string
+	if wt != nil {
+		infos, err := wt.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range infos {
+			names = append(names, info.Name())
+		}
+		return names, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names, nil
+}
+
+// isDir returns whether the given path is a directory.
+func isDir(p string, wt billy.Filesystem) bool {
+	var info os.FileInfo
+	var err error
+	if wt != nil {
+		info, err = wt.Stat(p)
+	} else {
+		info, err = os.Stat(p)
+	}
+	return err == nil && info.IsDir()
+}
+
+// codeOwnersPaths are the paths of the CODEOWNERS file in a repository, in
+// the order GitHub looks them up.
+var codeOwnersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// readCodeOwners returns the content of the CODEOWNERS file of the source in
+// the workDir, if any.
+func readCodeOwners(workDir string, wt billy.Filesystem) ([]byte, error) {
+	for _, p := range codeOwnersPaths {
+		var data []byte
+		var err error
+		if wt != nil {
+			data, err = util.ReadFile(wt, filepath.Join(workDir, p))
+		} else {
+			data, err = os.ReadFile(filepath.Join(workDir, p))
+		}
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CODEOWNERS file: %w", err)
+		}
+		return data, nil
+	}
+	return nil, nil
+}
+
+// PinPolicies returns a copy of the given policies in which the latest image
+// of the policies with an override in the ImageUpdateAutomation is replaced by
+// the pinned image, along with the sorted names of the pinned policies.
+// Overrides of policies which aren't in the list are ignored.
+func PinPolicies(obj *imagev1.ImageUpdateAutomation, policies []imagev1_reflect.ImagePolicy) ([]imagev1_reflect.ImagePolicy, []string) {
+	if len(obj.Spec.Overrides) == 0 {
+		return policies, nil
+	}
+	overrides := make(map[string]imagev1.PolicyOverride, len(obj.Spec.Overrides))
+	for _, o := range obj.Spec.Overrides {
+		overrides[o.PolicyName] = o
+	}
+
+	var pinned []string
+	result := make([]imagev1_reflect.ImagePolicy, 0, len(policies))
+	for _, policy := range policies {
+		if o, ok := overrides[policy.Name]; ok && policy.Status.LatestImage != "" {
+			policy = *policy.DeepCopy()
+			policy.Status.LatestImage = pinImage(policy.Status.LatestImage, o)
+			pinned = append(pinned, policy.Name)
+		}
+		result = append(result, policy)
+	}
+	slices.Sort(pinned)
+	return result, pinned
+}
+
+// ExcludePolicies returns the given policies without the ones whose latest
+// image is in the given list of images never to update, along with the sorted
+// names of the excluded policies. An entry of the list matches an image with
+// the same name, or with a name ending in the entry after a slash, so that
+// "postgres" matches "docker.io/library/postgres" as well.
+func ExcludePolicies(policies []imagev1_reflect.ImagePolicy, images []string) ([]imagev1_reflect.ImagePolicy, []string) {
+	if len(images) == 0 {
+		return policies, nil
+	}
+
+	var excluded []string
+	result := make([]imagev1_reflect.ImagePolicy, 0, len(policies))
+	for _, policy := range policies {
+		if policy.Status.LatestImage != "" && isExcludedImage(imageName(policy.Status.LatestImage), images) {
+			excluded = append(excluded, policy.Name)
+			continue
+		}
+		result = append(result, policy)
+	}
+	slices.Sort(excluded)
+	return result, excluded
+}
+
+// isExcludedImage returns whether the image name matches any of the given
+// images never to update.
+func isExcludedImage(name string, images []string) bool {
+	for _, image := range images {
+		image = strings.TrimSpace(image)
+		if image == "" {
+			continue
+		}
+		if name == image || strings.HasSuffix(name, "/"+image) {
+			return true
+		}
+	}
+	return false
+}
+
+// pinImage returns the image reference with the name of the given image and
+// the tag or digest of the override.
+func pinImage(image string, o imagev1.PolicyOverride) string {
+	name := imageName(image)
+	if o.Digest != "" {
+		return name + "@" + o.Digest
+	}
+	return name + ":" + o.Tag
+}
+
+// imageName returns the given image reference without its tag or digest.
+func imageName(image string) string {
+	name := image
+	if i := strings.Index(name, "@"); i >= 0 {
+		name = name[:i]
+	}
+	// A colon after the last slash separates the tag, any other is part of
+	// the registry host.
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name = name[:i]
+	}
+	return name
 }
@@ -18,9 +18,19 @@ package policy
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-billy/v5/util"
 	. "github.com/onsi/gomega"
 	"github.com/otiai10/copy"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -30,6 +40,7 @@ import (
 
 	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
 	"github.com/fluxcd/image-automation-controller/internal/testutil"
+	"github.com/fluxcd/image-automation-controller/internal/validation"
 	"github.com/fluxcd/image-automation-controller/pkg/test"
 	"github.com/fluxcd/image-automation-controller/pkg/update"
 )
@@ -43,6 +54,7 @@ func Test_applyPolicies(t *testing.T) {
 		name               string
 		updateStrategy     *imagev1.UpdateStrategy
 		policyLatestImages map[string]string
+		overrides          []imagev1.PolicyOverride
 		targetPolicyName   string
 		replaceMarkerFunc  func(g *WithT, path string, policyKey types.NamespacedName)
 		inputPath          string
@@ -90,6 +102,22 @@ func Test_applyPolicies(t *testing.T) {
 			expectedPath:     testdataPath("appconfig-setters-expected"),
 			wantErr:          false,
 		},
+		{
+			name: "pinned policy",
+			updateStrategy: &imagev1.UpdateStrategy{
+				Strategy: imagev1.UpdateStrategySetters,
+			},
+			policyLatestImages: map[string]string{
+				"policy1": "helloworld:1.2.0",
+			},
+			overrides: []imagev1.PolicyOverride{
+				{PolicyName: "policy1", Tag: "1.0.1"},
+			},
+			targetPolicyName: "policy1",
+			inputPath:        testdataPath("appconfig"),
+			expectedPath:     testdataPath("appconfig-setters-expected"),
+			wantErr:          false,
+		},
 		{
 			name: "valid update strategy with update path",
 			updateStrategy: &imagev1.UpdateStrategy{
@@ -146,7 +174,8 @@ func Test_applyPolicies(t *testing.T) {
 			updateAuto.Name = "test-update"
 			updateAuto.Namespace = testNS
 			updateAuto.Spec = imagev1.ImageUpdateAutomationSpec{
-				Update: tt.updateStrategy,
+				Update:    tt.updateStrategy,
+				Overrides: tt.overrides,
 			}
 
 			scheme := runtime.NewScheme()
@@ -171,3 +200,820 @@ func Test_applyPolicies(t *testing.T) {
 		})
 	}
 }
+
+func Test_applyPolicies_workTree(t *testing.T) {
+	g := NewWithT(t)
+
+	testNS := "test-ns"
+	policy := imagev1_reflect.ImagePolicy{}
+	policy.Name = "policy1"
+	policy.Namespace = testNS
+	policy.Status = imagev1_reflect.ImagePolicyStatus{
+		LatestImage: "helloworld:1.0.1",
+	}
+	policyKey := types.NamespacedName{Name: policy.Name, Namespace: testNS}
+	replaceMarkers := func(path string) {
+		g.Expect(testutil.ReplaceMarker(filepath.Join(path, "yes", "deploy.yaml"), policyKey)).ToNot(HaveOccurred())
+		g.Expect(testutil.ReplaceMarker(filepath.Join(path, "no", "deploy.yaml"), policyKey)).ToNot(HaveOccurred())
+	}
+
+	// Load the source into an in-memory worktree.
+	input := t.TempDir()
+	g.Expect(copy.Copy(testdataPath("pathconfig"), input)).ToNot(HaveOccurred())
+	replaceMarkers(input)
+	wt := memfs.New()
+	copyDir(g, osfs.New(input), wt)
+
+	updateAuto := &imagev1.ImageUpdateAutomation{}
+	updateAuto.Name = "test-update"
+	updateAuto.Namespace = testNS
+	updateAuto.Spec = imagev1.ImageUpdateAutomationSpec{
+		Update: &imagev1.UpdateStrategy{
+			Strategy: imagev1.UpdateStrategySetters,
+			Path:     "./yes",
+		},
+	}
+
+	_, err := ApplyPolicies(context.TODO(), "/", updateAuto, []imagev1_reflect.ImagePolicy{policy}, WithApplyOptionWorkTree(wt))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	expected := t.TempDir()
+	g.Expect(copy.Copy(testdataPath("pathconfig-expected"), expected)).ToNot(HaveOccurred())
+	replaceMarkers(expected)
+	output := t.TempDir()
+	copyDir(g, wt, osfs.New(output))
+	test.ExpectMatchingDirectories(g, output, expected)
+}
+
+func Test_applyPolicies_deterministic(t *testing.T) {
+	g := NewWithT(t)
+
+	testNS := "test-ns"
+	var policies []imagev1_reflect.ImagePolicy
+	var manifest strings.Builder
+	manifest.WriteString(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+`)
+	for i := 0; i < 10; i++ {
+		policy := imagev1_reflect.ImagePolicy{}
+		policy.Name = fmt.Sprintf("policy-%d", i)
+		policy.Namespace = testNS
+		policy.Status.LatestImage = fmt.Sprintf("image-%d:v1.0.1", i)
+		policies = append(policies, policy)
+		fmt.Fprintf(&manifest, "      - name: c%d\n        image: image-%d:v1.0.0 # {\"$imagepolicy\": \"%s:%s\"}\n",
+			i, i, testNS, policy.Name)
+	}
+
+	updateAuto := &imagev1.ImageUpdateAutomation{}
+	updateAuto.Name = "test-update"
+	updateAuto.Namespace = testNS
+	updateAuto.Spec = imagev1.ImageUpdateAutomationSpec{
+		Update: &imagev1.UpdateStrategy{
+			Strategy: imagev1.UpdateStrategySetters,
+		},
+	}
+
+	// The policies are applied in different orders, with the same result.
+	var wantFiles []byte
+	var wantChanges []update.Change
+	for i := 0; i < 5; i++ {
+		wt := memfs.New()
+		for _, file := range []string{"a.yaml", "b.yaml", "c/d.yaml"} {
+			g.Expect(util.WriteFile(wt, file, []byte(manifest.String()), 0o644)).To(Succeed())
+		}
+		shuffled := slices.Clone(policies)
+		rand.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+
+		result, err := ApplyPolicies(context.TODO(), "/", updateAuto, shuffled, WithApplyOptionWorkTree(wt))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result.Changes()).To(HaveLen(10))
+
+		var files []byte
+		for _, file := range []string{"a.yaml", "b.yaml", "c/d.yaml"} {
+			b, err := util.ReadFile(wt, file)
+			g.Expect(err).ToNot(HaveOccurred())
+			files = append(files, b...)
+		}
+		if i == 0 {
+			wantFiles, wantChanges = files, result.Changes()
+			continue
+		}
+		g.Expect(files).To(Equal(wantFiles))
+		g.Expect(result.Changes()).To(Equal(wantChanges))
+	}
+}
+
+func Test_applyPolicies_globPath(t *testing.T) {
+	g := NewWithT(t)
+
+	testNS := "test-ns"
+	policy := imagev1_reflect.ImagePolicy{}
+	policy.Name = "policy1"
+	policy.Namespace = testNS
+	policy.Status.LatestImage = "helloworld:1.0.1"
+	manifest := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+      - name: hello
+        image: helloworld:1.0.0 # {"$imagepolicy": "test-ns:policy1"}
+`
+	files := []string{
+		"apps/a/staging/deploy.yaml",
+		"apps/b/staging/deploy.yaml",
+		"apps/b/prod/deploy.yaml",
+		"apps/.c/staging/deploy.yaml",
+		"clusters/staging/deploy.yaml",
+	}
+	wt := memfs.New()
+	for _, file := range files {
+		g.Expect(util.WriteFile(wt, file, []byte(manifest), 0o644)).To(Succeed())
+	}
+
+	updateAuto := &imagev1.ImageUpdateAutomation{}
+	updateAuto.Name = "test-update"
+	updateAuto.Namespace = testNS
+	updateAuto.Spec = imagev1.ImageUpdateAutomationSpec{
+		Update: &imagev1.UpdateStrategy{
+			Strategy: imagev1.UpdateStrategySetters,
+			Path:     "./apps/*/staging",
+		},
+	}
+
+	result, err := ApplyPolicies(context.TODO(), "/", updateAuto, []imagev1_reflect.ImagePolicy{policy}, WithApplyOptionWorkTree(wt))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.FileChanges).To(HaveLen(2))
+	g.Expect(result.FileChanges).To(HaveKey("a/staging/deploy.yaml"))
+	g.Expect(result.FileChanges).To(HaveKey("b/staging/deploy.yaml"))
+	g.Expect(result.ImageResult.Files).To(HaveLen(2))
+	for _, file := range files {
+		b, err := util.ReadFile(wt, file)
+		g.Expect(err).ToNot(HaveOccurred())
+		updated := file == "apps/a/staging/deploy.yaml" || file == "apps/b/staging/deploy.yaml"
+		g.Expect(strings.Contains(string(b), "helloworld:1.0.1")).To(Equal(updated), file)
+	}
+	g.Expect(UpdatePathBase(updateAuto.Spec.Update.Path)).To(Equal("apps"))
+
+	// A glob matching no directory is an error.
+	updateAuto.Spec.Update.Path = "./apps/*/dev"
+	_, err = ApplyPolicies(context.TODO(), "/", updateAuto, []imagev1_reflect.ImagePolicy{policy}, WithApplyOptionWorkTree(wt))
+	g.Expect(err).To(MatchError(ContainSubstring("matches no directory")))
+
+	// An invalid glob is an error.
+	updateAuto.Spec.Update.Path = "./apps/[a"
+	_, err = ApplyPolicies(context.TODO(), "/", updateAuto, []imagev1_reflect.ImagePolicy{policy}, WithApplyOptionWorkTree(wt))
+	g.Expect(err).To(MatchError(ContainSubstring("invalid update path pattern")))
+}
+
+func Test_applyPolicies_renderedUpdatePath(t *testing.T) {
+	g := NewWithT(t)
+
+	testNS := "test-ns"
+	policy := imagev1_reflect.ImagePolicy{}
+	policy.Name = "policy1"
+	policy.Namespace = testNS
+	policy.Status.LatestImage = "helloworld:1.0.1"
+	manifest := `image: helloworld:1.0.0 # {"$imagepolicy": "test-ns:policy1"}
+`
+	wt := memfs.New()
+	for _, file := range []string{"apps/staging/deploy.yaml", "apps/prod/deploy.yaml"} {
+		g.Expect(util.WriteFile(wt, file, []byte(manifest), 0o644)).To(Succeed())
+	}
+
+	updateAuto := &imagev1.ImageUpdateAutomation{}
+	updateAuto.Name = "test-update"
+	updateAuto.Namespace = testNS
+	updateAuto.Spec = imagev1.ImageUpdateAutomationSpec{
+		Update: &imagev1.UpdateStrategy{
+			Strategy: imagev1.UpdateStrategySetters,
+			Path:     "./apps/{{ .Values.env }}",
+		},
+	}
+
+	// The rendered update path is updated instead of the template.
+	opts := []ApplyOption{WithApplyOptionWorkTree(wt), WithApplyOptionUpdatePath("./apps/staging")}
+	result, err := ApplyPolicies(context.TODO(), "/", updateAuto, []imagev1_reflect.ImagePolicy{policy}, opts...)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.FileChanges).To(HaveLen(1))
+	g.Expect(result.FileChanges).To(HaveKey("deploy.yaml"))
+	b, err := util.ReadFile(wt, "apps/prod/deploy.yaml")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(b)).To(Equal(manifest))
+	g.Expect(ValidateChanges("/", updateAuto, result, opts...)).To(Succeed())
+
+	// The base of a template which isn't rendered is the directory before
+	// its first action.
+	g.Expect(UpdatePathBase(updateAuto.Spec.Update.Path)).To(Equal("apps"))
+	g.Expect(UpdatePathBase("./apps/env-{{ .Values.env }}/*")).To(Equal("apps"))
+	g.Expect(UpdatePathBase("{{ .Values.dir }}")).To(Equal("."))
+}
+
+func Test_applyPolicies_updatePathHint(t *testing.T) {
+	g := NewWithT(t)
+
+	testNS := "test-ns"
+	policy := imagev1_reflect.ImagePolicy{}
+	policy.Name = "policy1"
+	policy.Namespace = testNS
+	policy.Status.LatestImage = "helloworld:1.0.1"
+	manifest := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+      - name: hello
+        image: helloworld:1.0.0 # {"$imagepolicy": "test-ns:policy1"}
+`
+	files := []string{
+		"apps/foo/deploy.yaml",
+		"apps/foo/staging/deploy.yaml",
+		"apps/bar/deploy.yaml",
+		"clusters/deploy.yaml",
+	}
+
+	tests := []struct {
+		name       string
+		updatePath string
+		hint       string
+		wantFiles  []string
+		wantErr    string
+	}{
+		{
+			name:       "hint within update path",
+			updatePath: "./",
+			hint:       "./apps/foo",
+			wantFiles:  []string{"apps/foo/deploy.yaml", "apps/foo/staging/deploy.yaml"},
+		},
+		{
+			name:       "update path within hint",
+			updatePath: "./apps/foo/staging",
+			hint:       "apps",
+			wantFiles:  []string{"apps/foo/staging/deploy.yaml"},
+		},
+		{
+			name:       "hint within glob update path",
+			updatePath: "./apps/*",
+			hint:       "apps/foo/staging",
+			wantFiles:  []string{"apps/foo/staging/deploy.yaml"},
+		},
+		{
+			name:       "hint outside update path",
+			updatePath: "./clusters",
+			hint:       "apps",
+		},
+		{
+			name:       "absolute hint",
+			updatePath: "./",
+			hint:       "/apps",
+			wantErr:    "isn't a relative path within the source",
+		},
+		{
+			name:       "hint outside source",
+			updatePath: "./",
+			hint:       "apps/../../foo",
+			wantErr:    "isn't a relative path within the source",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			wt := memfs.New()
+			for _, file := range files {
+				g.Expect(util.WriteFile(wt, file, []byte(manifest), 0o644)).To(Succeed())
+			}
+
+			updateAuto := &imagev1.ImageUpdateAutomation{}
+			updateAuto.Name = "test-update"
+			updateAuto.Namespace = testNS
+			updateAuto.Spec = imagev1.ImageUpdateAutomationSpec{
+				Update: &imagev1.UpdateStrategy{
+					Strategy: imagev1.UpdateStrategySetters,
+					Path:     tt.updatePath,
+				},
+			}
+			p := *policy.DeepCopy()
+			p.SetAnnotations(map[string]string{imagev1.UpdatePathAnnotation: tt.hint})
+
+			_, err := ApplyPolicies(context.TODO(), "/", updateAuto, []imagev1_reflect.ImagePolicy{p}, WithApplyOptionWorkTree(wt))
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ErrInvalidUpdatePathHint))
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			for _, file := range files {
+				b, err := util.ReadFile(wt, file)
+				g.Expect(err).ToNot(HaveOccurred())
+				g.Expect(strings.Contains(string(b), "helloworld:1.0.1")).To(Equal(slices.Contains(tt.wantFiles, file)), file)
+			}
+		})
+	}
+
+	// The policies without a hint aren't restricted.
+	wt := memfs.New()
+	for _, file := range files {
+		g.Expect(util.WriteFile(wt, file, []byte(manifest), 0o644)).To(Succeed())
+	}
+	updateAuto := &imagev1.ImageUpdateAutomation{}
+	updateAuto.Namespace = testNS
+	updateAuto.Spec.Update = &imagev1.UpdateStrategy{Strategy: imagev1.UpdateStrategySetters}
+	result, err := ApplyPolicies(context.TODO(), "/", updateAuto, []imagev1_reflect.ImagePolicy{policy}, WithApplyOptionWorkTree(wt))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.FileChanges).To(HaveLen(len(files)))
+}
+
+func Test_applyPolicies_kustomizeImages(t *testing.T) {
+	g := NewWithT(t)
+
+	testNS := "test-ns"
+	policy := imagev1_reflect.ImagePolicy{}
+	policy.Name = "policy1"
+	policy.Namespace = testNS
+	policy.Status.LatestImage = "helloworld:1.0.1"
+	manifest := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+      - name: hello
+        image: helloworld:1.0.0 # {"$imagepolicy": "test-ns:policy1"}
+`
+	wt := memfs.New()
+	for _, file := range []string{"clusters/staging/deploy.yaml", "clusters/prod/deploy.yaml"} {
+		g.Expect(util.WriteFile(wt, file, []byte(manifest), 0o644)).To(Succeed())
+	}
+
+	updateAuto := &imagev1.ImageUpdateAutomation{}
+	updateAuto.Name = "test-update"
+	updateAuto.Namespace = testNS
+	updateAuto.Spec = imagev1.ImageUpdateAutomationSpec{
+		Update: &imagev1.UpdateStrategy{
+			Strategy: imagev1.UpdateStrategyKustomizeImages,
+			Path:     "./clusters/*",
+		},
+	}
+
+	// A component is maintained in each overlay, and the manifests are
+	// left unchanged.
+	result, err := ApplyPolicies(context.TODO(), "/", updateAuto, []imagev1_reflect.ImagePolicy{policy}, WithApplyOptionWorkTree(wt))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.FileChanges).To(HaveLen(2))
+	for _, overlay := range []string{"staging", "prod"} {
+		g.Expect(result.FileChanges).To(HaveKey(overlay + "/" + update.KustomizeImagesFile))
+		b, err := util.ReadFile(wt, "clusters/"+overlay+"/"+update.KustomizeImagesFile)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(string(b)).To(ContainSubstring("- name: helloworld\n  newTag: 1.0.1\n"))
+		b, err = util.ReadFile(wt, "clusters/"+overlay+"/deploy.yaml")
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(string(b)).To(Equal(manifest))
+	}
+
+	// Nothing changes when the images are the same.
+	result, err = ApplyPolicies(context.TODO(), "/", updateAuto, []imagev1_reflect.ImagePolicy{policy}, WithApplyOptionWorkTree(wt))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.HasChanges()).To(BeFalse())
+}
+
+func Test_applyPolicies_dataFields(t *testing.T) {
+	g := NewWithT(t)
+
+	testNS := "test-ns"
+	policy := imagev1_reflect.ImagePolicy{}
+	policy.Name = "policy1"
+	policy.Namespace = testNS
+	policy.Status.LatestImage = "helloworld:1.0.1"
+	wt := memfs.New()
+	g.Expect(util.WriteFile(wt, "apps/app.yaml", []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+      - name: hello
+        image: helloworld:1.0.0 # {"$imagepolicy": "test-ns:policy1"}
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+data:
+  config.json: |
+    {"hello": {"tag": "1.0.0"}}
+`), 0o644)).To(Succeed())
+
+	updateAuto := &imagev1.ImageUpdateAutomation{}
+	updateAuto.Name = "test-update"
+	updateAuto.Namespace = testNS
+	updateAuto.Spec = imagev1.ImageUpdateAutomationSpec{
+		Update: &imagev1.UpdateStrategy{
+			Strategy: imagev1.UpdateStrategySetters,
+			Path:     "./apps",
+			DataFields: []imagev1.DataField{{
+				Kind:   "ConfigMap",
+				Name:   "app-config",
+				Key:    "config.json",
+				Path:   "$.hello.tag",
+				Policy: "policy1",
+				Value:  imagev1.DataFieldValueTag,
+			}},
+		},
+	}
+
+	// The marked field and the data field of the file are both set.
+	result, err := ApplyPolicies(context.TODO(), "/", updateAuto, []imagev1_reflect.ImagePolicy{policy}, WithApplyOptionWorkTree(wt))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.Changes()).To(Equal([]update.Change{
+		{OldValue: "helloworld:1.0.0", NewValue: "helloworld:1.0.1", Setter: "test-ns:policy1", Line: 10},
+		{OldValue: "1.0.0", NewValue: "1.0.1", Setter: "test-ns:policy1:tag", Line: 18},
+	}))
+	g.Expect(result.UpdatePaths).To(BeEmpty())
+	b, err := util.ReadFile(wt, "apps/app.yaml")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(b)).To(ContainSubstring("image: helloworld:1.0.1 # {\"$imagepolicy\": \"test-ns:policy1\"}\n"))
+	g.Expect(string(b)).To(ContainSubstring("config.json: |\n    {\"hello\": {\"tag\": \"1.0.1\"}}\n"))
+
+	// Nothing changes when the images are the same.
+	result, err = ApplyPolicies(context.TODO(), "/", updateAuto, []imagev1_reflect.ImagePolicy{policy}, WithApplyOptionWorkTree(wt))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.HasChanges()).To(BeFalse())
+}
+
+func Test_applyPolicies_lockFile(t *testing.T) {
+	g := NewWithT(t)
+
+	testNS := "test-ns"
+	policy := imagev1_reflect.ImagePolicy{}
+	policy.Name = "policy1"
+	policy.Namespace = testNS
+	policy.Status.LatestImage = "helloworld:1.0.1"
+	wt := memfs.New()
+	g.Expect(util.WriteFile(wt, "apps/deploy.yaml", []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+      - name: hello
+        image: helloworld:1.0.0 # {"$imagepolicy": "test-ns:policy1"}
+`), 0o644)).To(Succeed())
+
+	updateAuto := &imagev1.ImageUpdateAutomation{}
+	updateAuto.Name = "test-update"
+	updateAuto.Namespace = testNS
+	updateAuto.Spec = imagev1.ImageUpdateAutomationSpec{
+		Update: &imagev1.UpdateStrategy{
+			Strategy: imagev1.UpdateStrategySetters,
+			Path:     "./apps",
+			LockFile: true,
+		},
+	}
+
+	result, err := ApplyPolicies(context.TODO(), "/", updateAuto, []imagev1_reflect.ImagePolicy{policy}, WithApplyOptionWorkTree(wt))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.LockFile).To(Equal(update.LockFileName))
+	b, err := util.ReadFile(wt, "apps/"+update.LockFileName)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(b)).To(ContainSubstring("policy: test-ns/policy1\n  image: helloworld:1.0.1\n"))
+
+	// Nothing changes when the images are the same.
+	result, err = ApplyPolicies(context.TODO(), "/", updateAuto, []imagev1_reflect.ImagePolicy{policy}, WithApplyOptionWorkTree(wt))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.HasChanges()).To(BeFalse())
+}
+
+func Test_applyPolicies_atomic(t *testing.T) {
+	testNS := "test-ns"
+	policy := imagev1_reflect.ImagePolicy{}
+	policy.Name = "policy1"
+	policy.Namespace = testNS
+	policy.Status.LatestImage = "helloworld:1.0.1"
+
+	for _, atomic := range []bool{false, true} {
+		t.Run(fmt.Sprintf("atomic=%t", atomic), func(t *testing.T) {
+			g := NewWithT(t)
+
+			wt := memfs.New()
+			g.Expect(util.WriteFile(wt, "deploy.yaml", []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+      - name: hello
+        image: helloworld:1.0.0 # {"$imagepolicy": "test-ns:policy1"}
+`), 0o644)).To(Succeed())
+			g.Expect(util.WriteFile(wt, "broken.yaml", []byte(`image: helloworld:1.0.0 # {"$imagepolicy": "test-ns:policy1"}
+  broken: [
+`), 0o644)).To(Succeed())
+
+			updateAuto := &imagev1.ImageUpdateAutomation{}
+			updateAuto.Name = "test-update"
+			updateAuto.Namespace = testNS
+			updateAuto.Spec = imagev1.ImageUpdateAutomationSpec{
+				Update: &imagev1.UpdateStrategy{
+					Strategy: imagev1.UpdateStrategySetters,
+					Atomic:   atomic,
+				},
+			}
+
+			result, err := ApplyPolicies(context.TODO(), "/", updateAuto, []imagev1_reflect.ImagePolicy{policy}, WithApplyOptionWorkTree(wt))
+			g.Expect(result.SkippedFiles).To(HaveKey("broken.yaml"))
+			if !atomic {
+				g.Expect(err).ToNot(HaveOccurred())
+				g.Expect(result.FileChanges).To(HaveKey("deploy.yaml"))
+				return
+			}
+			g.Expect(err).To(MatchError(ErrIncompleteUpdate))
+			g.Expect(err).To(MatchError(ContainSubstring("files couldn't be processed: 'broken.yaml' (invalid YAML: ")))
+		})
+	}
+}
+
+// copyDir copies all the files from one billy.Filesystem to another.
+func copyDir(g *WithT, from, to billy.Filesystem) {
+	g.THelper()
+	g.Expect(util.Walk(from, "/", func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		b, err := util.ReadFile(from, path)
+		if err != nil {
+			return err
+		}
+		return util.WriteFile(to, path, b, info.Mode())
+	})).To(Succeed())
+}
+
+func Test_validateChanges(t *testing.T) {
+	g := NewWithT(t)
+
+	testNS := "test-ns"
+	policy := imagev1_reflect.ImagePolicy{}
+	policy.Name = "policy1"
+	policy.Namespace = testNS
+	policy.Status.LatestImage = "helloworld:1.0.1"
+	manifest := func(replicas string) string {
+		return fmt.Sprintf(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: %s
+  selector:
+    matchLabels:
+      app: app
+  template:
+    spec:
+      containers:
+      - name: hello
+        image: helloworld:1.0.0 # {"$imagepolicy": "test-ns:policy1"}
+`, replicas)
+	}
+	wt := memfs.New()
+	g.Expect(util.WriteFile(wt, "apps/valid/deploy.yaml", []byte(manifest("1")), 0o644)).To(Succeed())
+	g.Expect(util.WriteFile(wt, "apps/invalid/deploy.yaml", []byte(manifest("two")), 0o644)).To(Succeed())
+	// Files which aren't changed aren't validated.
+	g.Expect(util.WriteFile(wt, "apps/invalid/service.yaml", []byte(`apiVersion: v1
+kind: Service
+metadata:
+  name: app
+spec:
+  ports:
+  - port: http
+`), 0o644)).To(Succeed())
+
+	updateAuto := &imagev1.ImageUpdateAutomation{}
+	updateAuto.Name = "test-update"
+	updateAuto.Namespace = testNS
+	updateAuto.Spec = imagev1.ImageUpdateAutomationSpec{
+		Update: &imagev1.UpdateStrategy{
+			Strategy: imagev1.UpdateStrategySetters,
+			Path:     "./apps",
+			Validate: true,
+		},
+	}
+
+	result, err := ApplyPolicies(context.TODO(), "/", updateAuto, []imagev1_reflect.ImagePolicy{policy}, WithApplyOptionWorkTree(wt))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.FileChanges).To(HaveLen(2))
+
+	err = ValidateChanges("/", updateAuto, result, WithApplyOptionWorkTree(wt))
+	var verr *validation.Error
+	g.Expect(errors.As(err, &verr)).To(BeTrue())
+	g.Expect(verr.Problems).To(Equal([]string{
+		`invalid/deploy.yaml: Deployment/app: spec.replicas in body must be of type integer: "string"`,
+	}))
+
+	// The valid changes pass.
+	delete(result.FileChanges, "invalid/deploy.yaml")
+	g.Expect(ValidateChanges("/", updateAuto, result, WithApplyOptionWorkTree(wt))).To(Succeed())
+}
+
+func Test_readCodeOwners(t *testing.T) {
+	g := NewWithT(t)
+
+	// No CODEOWNERS file.
+	dir := t.TempDir()
+	data, err := readCodeOwners(dir, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(data).To(BeNil())
+
+	// The file in .github takes precedence.
+	g.Expect(os.WriteFile(filepath.Join(dir, "CODEOWNERS"), []byte("* @root"), 0o644)).To(Succeed())
+	data, err = readCodeOwners(dir, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(data)).To(Equal("* @root"))
+
+	g.Expect(os.MkdirAll(filepath.Join(dir, ".github"), 0o755)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, ".github", "CODEOWNERS"), []byte("* @github"), 0o644)).To(Succeed())
+	data, err = readCodeOwners(dir, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(data)).To(Equal("* @github"))
+
+	// In a worktree.
+	wt := memfs.New()
+	g.Expect(util.WriteFile(wt, "/docs/CODEOWNERS", []byte("* @docs"), 0o644)).To(Succeed())
+	data, err = readCodeOwners("/", wt)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(data)).To(Equal("* @docs"))
+}
+
+func TestPinPolicies(t *testing.T) {
+	digest := "sha256:6c3c624b58dbbcd3c0dd82b4c53f04194d1247c6eebdaab7c610cf7d66709b3b"
+
+	tests := []struct {
+		name       string
+		images     map[string]string
+		overrides  []imagev1.PolicyOverride
+		wantImages map[string]string
+		wantPinned []string
+	}{
+		{
+			name:       "no overrides",
+			images:     map[string]string{"p1": "foo:1.0.0"},
+			wantImages: map[string]string{"p1": "foo:1.0.0"},
+		},
+		{
+			name: "pinned tag",
+			images: map[string]string{
+				"p1": "foo:1.0.0",
+				"p2": "bar:2.0.0",
+			},
+			overrides: []imagev1.PolicyOverride{
+				{PolicyName: "p2", Tag: "1.9.0"},
+			},
+			wantImages: map[string]string{
+				"p1": "foo:1.0.0",
+				"p2": "bar:1.9.0",
+			},
+			wantPinned: []string{"p2"},
+		},
+		{
+			name: "pinned digest with registry port",
+			images: map[string]string{
+				"p1": "localhost:5000/foo:1.0.0",
+			},
+			overrides: []imagev1.PolicyOverride{
+				{PolicyName: "p1", Digest: digest},
+			},
+			wantImages: map[string]string{
+				"p1": "localhost:5000/foo@" + digest,
+			},
+			wantPinned: []string{"p1"},
+		},
+		{
+			name: "pinned digest replaced by tag",
+			images: map[string]string{
+				"p1": "foo@" + digest,
+			},
+			overrides: []imagev1.PolicyOverride{
+				{PolicyName: "p1", Tag: "1.0.0"},
+			},
+			wantImages: map[string]string{
+				"p1": "foo:1.0.0",
+			},
+			wantPinned: []string{"p1"},
+		},
+		{
+			name: "override of unknown policy",
+			images: map[string]string{
+				"p1": "foo:1.0.0",
+			},
+			overrides: []imagev1.PolicyOverride{
+				{PolicyName: "p2", Tag: "1.9.0"},
+			},
+			wantImages: map[string]string{
+				"p1": "foo:1.0.0",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			policies := []imagev1_reflect.ImagePolicy{}
+			for name, image := range tt.images {
+				policy := imagev1_reflect.ImagePolicy{}
+				policy.Name = name
+				policy.Status.LatestImage = image
+				policies = append(policies, policy)
+			}
+			obj := &imagev1.ImageUpdateAutomation{}
+			obj.Spec.Overrides = tt.overrides
+
+			got, pinned := PinPolicies(obj, policies)
+			g.Expect(pinned).To(Equal(tt.wantPinned))
+			gotImages := map[string]string{}
+			for _, policy := range got {
+				gotImages[policy.Name] = policy.Status.LatestImage
+			}
+			g.Expect(gotImages).To(Equal(tt.wantImages))
+
+			// The given policies are left as they were.
+			for _, policy := range policies {
+				g.Expect(policy.Status.LatestImage).To(Equal(tt.images[policy.Name]))
+			}
+		})
+	}
+}
+
+func TestExcludePolicies(t *testing.T) {
+	policies := []imagev1_reflect.ImagePolicy{}
+	for name, image := range map[string]string{
+		"db":     "docker.io/library/postgres:16.1",
+		"cache":  "redis@sha256:6c42cce2871e8dc5fb7e843ed5c4e7f8d6e0b1e7b2b3c8a3d4d56a17c2d1d8e1",
+		"app":    "ghcr.io/org/app:v1.0.0",
+		"mypg":   "ghcr.io/org/mypostgres:1.0",
+		"notyet": "",
+	} {
+		policy := imagev1_reflect.ImagePolicy{}
+		policy.Name = name
+		policy.Status.LatestImage = image
+		policies = append(policies, policy)
+	}
+
+	tests := []struct {
+		name         string
+		images       []string
+		wantPolicies []string
+		wantExcluded []string
+	}{
+		{
+			name:         "no exclusions",
+			wantPolicies: []string{"app", "cache", "db", "mypg", "notyet"},
+		},
+		{
+			name:         "short names",
+			images:       []string{"postgres", "redis"},
+			wantPolicies: []string{"app", "mypg", "notyet"},
+			wantExcluded: []string{"cache", "db"},
+		},
+		{
+			name:         "full name",
+			images:       []string{"ghcr.io/org/app"},
+			wantPolicies: []string{"cache", "db", "mypg", "notyet"},
+			wantExcluded: []string{"app"},
+		},
+		{
+			name:         "partial names don't match",
+			images:       []string{"gres", "org/ap", " "},
+			wantPolicies: []string{"app", "cache", "db", "mypg", "notyet"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			got, excluded := ExcludePolicies(policies, tt.images)
+			g.Expect(excluded).To(Equal(tt.wantExcluded))
+			var gotNames []string
+			for _, policy := range got {
+				gotNames = append(gotNames, policy.Name)
+			}
+			g.Expect(gotNames).To(ConsistOf(tt.wantPolicies))
+		})
+	}
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+
+	imagev1_reflect "github.com/fluxcd/image-reflector-controller/api/v1beta2"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+// orderDesc is the descending order of the alphabetical and numerical
+// policies, which select the first tag in this order instead of the last one.
+const orderDesc = "desc"
+
+// RevokedImage is an image which was committed for an ImagePolicy, but isn't
+// a candidate of the policy anymore.
+type RevokedImage struct {
+	// Policy is the name of the ImagePolicy.
+	Policy string
+	// Image is the revoked image.
+	Image string
+	// Restored is the latest image of the policy, restored in its place.
+	Restored string
+}
+
+// RevokedImages returns the images observed for the given policies by the
+// ImageUpdateAutomation, and so committed, which aren't candidates of their
+// policy anymore, e.g. because their tag was deleted from the registry. As a
+// policy selects the first of its candidates, an image is revoked when the
+// latest image of its policy is ordered before it. The pinned policies and the
+// images pinned to a digest are left out. The result is sorted by policy.
+func RevokedImages(obj *imagev1.ImageUpdateAutomation, policies []imagev1_reflect.ImagePolicy) []RevokedImage {
+	var revoked []RevokedImage
+	for _, policy := range policies {
+		observed, ok := obj.Status.ObservedPolicies[policy.Name]
+		if !ok || observed.Tag == "" || slices.Contains(obj.Status.PinnedPolicies, policy.Name) {
+			continue
+		}
+		latest := policy.Status.LatestImage
+		if strings.Contains(latest, "@") || imageName(latest) != observed.Name {
+			continue
+		}
+		tag := strings.TrimPrefix(latest, observed.Name+":")
+		if tag == observed.Tag || !orderedBefore(policy.Spec, observed.Tag, tag) {
+			continue
+		}
+		revoked = append(revoked, RevokedImage{
+			Policy:   policy.Name,
+			Image:    observed.String(),
+			Restored: latest,
+		})
+	}
+	slices.SortFunc(revoked, func(a, b RevokedImage) int { return strings.Compare(a.Policy, b.Policy) })
+	return revoked
+}
+
+// RevokedPolicies returns the given policies whose image is revoked.
+func RevokedPolicies(policies []imagev1_reflect.ImagePolicy, revoked []RevokedImage) []imagev1_reflect.ImagePolicy {
+	var result []imagev1_reflect.ImagePolicy
+	for _, policy := range policies {
+		if slices.ContainsFunc(revoked, func(r RevokedImage) bool { return r.Policy == policy.Name }) {
+			result = append(result, policy)
+		}
+	}
+	return result
+}
+
+// orderedBefore returns if the given policy selects the first tag over the
+// second one when both are candidates, or if the first tag doesn't pass the
+// filter of the policy anymore. The tags which can't be compared by the
+// policy are not ordered.
+func orderedBefore(spec imagev1_reflect.ImagePolicySpec, first, second string) bool {
+	first, ok := filterTag(spec.FilterTags, first)
+	if !ok {
+		return true
+	}
+	if second, ok = filterTag(spec.FilterTags, second); !ok {
+		return false
+	}
+
+	switch choice := spec.Policy; {
+	case choice.SemVer != nil:
+		v1, err := semver.NewVersion(first)
+		if err != nil {
+			return false
+		}
+		v2, err := semver.NewVersion(second)
+		if err != nil {
+			return false
+		}
+		return v1.GreaterThan(v2)
+	case choice.Alphabetical != nil:
+		if choice.Alphabetical.Order == orderDesc {
+			return first < second
+		}
+		return first > second
+	case choice.Numerical != nil:
+		n1, err := strconv.ParseFloat(first, 64)
+		if err != nil {
+			return false
+		}
+		n2, err := strconv.ParseFloat(second, 64)
+		if err != nil {
+			return false
+		}
+		if choice.Numerical.Order == orderDesc {
+			return n1 < n2
+		}
+		return n1 > n2
+	default:
+		return false
+	}
+}
+
+// filterTag returns the value of the given tag compared by a policy with the
+// given filter, i.e. the extracted value, and if the tag passes the filter.
+// The tags pass an invalid filter, which is reported by the policy.
+func filterTag(filter *imagev1_reflect.TagFilter, tag string) (string, bool) {
+	if filter == nil || filter.Pattern == "" {
+		return tag, true
+	}
+	re, err := regexp.Compile(filter.Pattern)
+	if err != nil {
+		return tag, true
+	}
+	match := re.FindStringSubmatchIndex(tag)
+	if match == nil {
+		return "", false
+	}
+	if filter.Extract == "" {
+		return tag, true
+	}
+	return string(re.ExpandString(nil, filter.Extract, tag, match)), true
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	imagev1_reflect "github.com/fluxcd/image-reflector-controller/api/v1beta2"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+func TestRevokedImages(t *testing.T) {
+	semverPolicy := imagev1_reflect.ImagePolicyChoice{
+		SemVer: &imagev1_reflect.SemVerPolicy{Range: ">=1.0.0"},
+	}
+
+	tests := []struct {
+		name     string
+		spec     imagev1_reflect.ImagePolicySpec
+		observed imagev1.ImageRef
+		latest   string
+		pinned   bool
+		want     []RevokedImage
+	}{
+		{
+			name:     "newer semver",
+			spec:     imagev1_reflect.ImagePolicySpec{Policy: semverPolicy},
+			observed: imagev1.ImageRef{Name: "foo", Tag: "1.1.0"},
+			latest:   "foo:1.2.0",
+		},
+		{
+			name:     "older semver",
+			spec:     imagev1_reflect.ImagePolicySpec{Policy: semverPolicy},
+			observed: imagev1.ImageRef{Name: "foo", Tag: "1.1.0"},
+			latest:   "foo:1.0.0",
+			want:     []RevokedImage{{Policy: "p1", Image: "foo:1.1.0", Restored: "foo:1.0.0"}},
+		},
+		{
+			name:     "unchanged",
+			spec:     imagev1_reflect.ImagePolicySpec{Policy: semverPolicy},
+			observed: imagev1.ImageRef{Name: "foo", Tag: "1.1.0"},
+			latest:   "foo:1.1.0",
+		},
+		{
+			name:     "pinned policy",
+			spec:     imagev1_reflect.ImagePolicySpec{Policy: semverPolicy},
+			observed: imagev1.ImageRef{Name: "foo", Tag: "1.1.0"},
+			latest:   "foo:1.0.0",
+			pinned:   true,
+		},
+		{
+			name:     "other image",
+			spec:     imagev1_reflect.ImagePolicySpec{Policy: semverPolicy},
+			observed: imagev1.ImageRef{Name: "foo", Tag: "1.1.0"},
+			latest:   "bar:1.0.0",
+		},
+		{
+			name:     "digest",
+			spec:     imagev1_reflect.ImagePolicySpec{Policy: semverPolicy},
+			observed: imagev1.ImageRef{Name: "foo", Digest: "sha256:6c3c624b58dbbcd3c0dd82b4c53f04194d1247c6eebdaab7c610cf7d66709b3b"},
+			latest:   "foo:1.0.0",
+		},
+		{
+			name: "older alphabetical",
+			spec: imagev1_reflect.ImagePolicySpec{Policy: imagev1_reflect.ImagePolicyChoice{
+				Alphabetical: &imagev1_reflect.AlphabeticalPolicy{Order: "asc"},
+			}},
+			observed: imagev1.ImageRef{Name: "localhost:5000/foo", Tag: "b"},
+			latest:   "localhost:5000/foo:a",
+			want:     []RevokedImage{{Policy: "p1", Image: "localhost:5000/foo:b", Restored: "localhost:5000/foo:a"}},
+		},
+		{
+			name: "newer descending alphabetical",
+			spec: imagev1_reflect.ImagePolicySpec{Policy: imagev1_reflect.ImagePolicyChoice{
+				Alphabetical: &imagev1_reflect.AlphabeticalPolicy{Order: "desc"},
+			}},
+			observed: imagev1.ImageRef{Name: "foo", Tag: "b"},
+			latest:   "foo:a",
+		},
+		{
+			name: "older extracted numerical",
+			spec: imagev1_reflect.ImagePolicySpec{
+				Policy: imagev1_reflect.ImagePolicyChoice{
+					Numerical: &imagev1_reflect.NumericalPolicy{Order: "asc"},
+				},
+				FilterTags: &imagev1_reflect.TagFilter{Pattern: `^main-[a-f0-9]+-(?P<ts>[0-9]+)$`, Extract: "$ts"},
+			},
+			observed: imagev1.ImageRef{Name: "foo", Tag: "main-abc123-20"},
+			latest:   "foo:main-def456-9",
+			want:     []RevokedImage{{Policy: "p1", Image: "foo:main-abc123-20", Restored: "foo:main-def456-9"}},
+		},
+		{
+			name: "filtered out",
+			spec: imagev1_reflect.ImagePolicySpec{
+				Policy:     semverPolicy,
+				FilterTags: &imagev1_reflect.TagFilter{Pattern: `^1\.0\.`},
+			},
+			observed: imagev1.ImageRef{Name: "foo", Tag: "1.1.0"},
+			latest:   "foo:1.0.1",
+			want:     []RevokedImage{{Policy: "p1", Image: "foo:1.1.0", Restored: "foo:1.0.1"}},
+		},
+		{
+			name:     "not semver",
+			spec:     imagev1_reflect.ImagePolicySpec{Policy: semverPolicy},
+			observed: imagev1.ImageRef{Name: "foo", Tag: "latest"},
+			latest:   "foo:1.0.0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			policy := imagev1_reflect.ImagePolicy{Spec: tt.spec}
+			policy.Name = "p1"
+			policy.Status.LatestImage = tt.latest
+			other := imagev1_reflect.ImagePolicy{}
+			other.Name = "p2"
+			other.Status.LatestImage = "bar:1.0.0"
+			policies := []imagev1_reflect.ImagePolicy{other, policy}
+
+			obj := &imagev1.ImageUpdateAutomation{}
+			obj.Status.ObservedPolicies = imagev1.ObservedPolicies{"p1": tt.observed}
+			if tt.pinned {
+				obj.Status.PinnedPolicies = []string{"p1"}
+			}
+
+			got := RevokedImages(obj, policies)
+			g.Expect(got).To(Equal(tt.want))
+			if len(tt.want) > 0 {
+				g.Expect(RevokedPolicies(policies, got)).To(Equal([]imagev1_reflect.ImagePolicy{policy}))
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fluxcd/pkg/git"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+// apiTokenKey is the key of the token of the provider API in its Secret.
+const apiTokenKey = "token"
+
+// apiCommit is a commit made with the API of a Git provider.
+type apiCommit struct {
+	// branch is the branch the commit is made on, created if it doesn't
+	// exist.
+	branch string
+	// parent is the commit the commit is made on top of, and parentTree
+	// its tree.
+	parent     string
+	parentTree string
+	message    string
+	author     git.Signature
+	changes    []apiFileChange
+	// force tells whether the branch is overwritten when its head isn't the
+	// parent.
+	force bool
+}
+
+// apiFileChange is the change of a file of an apiCommit. A file is either
+// created, deleted, or updated when it's neither.
+type apiFileChange struct {
+	path    string
+	mode    filemode.FileMode
+	created bool
+	deleted bool
+	content []byte
+}
+
+// apiCommitter makes commits with the API of a Git provider.
+type apiCommitter interface {
+	// commit makes the given commit, and returns its revision on the
+	// provider.
+	commit(ctx context.Context, c apiCommit) (string, error)
+}
+
+// getAPICommitter returns the apiCommitter of the given provider API
+// configuration of an ImageUpdateAutomation in the given namespace,
+// authenticated with the token of the Secret it refers to.
+func getAPICommitter(ctx context.Context, c client.Client, namespace string, spec *imagev1.PushAPISpec) (apiCommitter, error) {
+	data, err := getSecretData(ctx, c, spec.SecretRef.Name, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider API secret '%s/%s': %w", namespace, spec.SecretRef.Name, err)
+	}
+	token := strings.TrimSpace(string(data[apiTokenKey]))
+	if token == "" {
+		return nil, fmt.Errorf("provider API secret '%s/%s' does not contain a '%s' key: %w",
+			namespace, spec.SecretRef.Name, apiTokenKey, ErrInvalidSourceConfiguration)
+	}
+
+	address := strings.TrimSuffix(spec.Address, "/")
+	switch spec.Provider {
+	case imagev1.PushAPIProviderGitHub:
+		if address == "" {
+			address = defaultGitHubAPIAddress
+		}
+		return &gitHubCommitter{address: address, repository: spec.Repository, token: token, client: &http.Client{}}, nil
+	case imagev1.PushAPIProviderGitLab:
+		if address == "" {
+			address = defaultGitLabAPIAddress
+		}
+		return &gitLabCommitter{address: address, project: spec.Repository, token: token, client: &http.Client{}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported provider API '%s': %w", spec.Provider, ErrInvalidSourceConfiguration)
+	}
+}
+
+// commitWithAPI makes the local commit with the given revision again with the
+// API of the Git provider, on the push branch, and returns the revision of the
+// commit made on the provider. The commit is made on top of the same parent,
+// with the same changes, author and message.
+func (sm SourceManager) commitWithAPI(ctx context.Context, rev string, force bool) (string, error) {
+	repo, err := sm.openRepository()
+	if err != nil {
+		return "", err
+	}
+	local, err := repo.CommitObject(plumbing.NewHash(rev))
+	if err != nil {
+		return "", fmt.Errorf("failed to read commit '%s': %w", rev, err)
+	}
+	parent, err := local.Parent(0)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the parent of commit '%s': %w", rev, err)
+	}
+	changes, err := apiFileChanges(parent, local)
+	if err != nil {
+		return "", err
+	}
+
+	remoteRev, err := sm.srcCfg.apiCommitter.commit(ctx, apiCommit{
+		branch:     sm.srcCfg.pushBranch,
+		parent:     parent.Hash.String(),
+		parentTree: parent.TreeHash.String(),
+		message:    local.Message,
+		author: git.Signature{
+			Name:  local.Author.Name,
+			Email: local.Author.Email,
+			When:  local.Author.When,
+		},
+		changes: changes,
+		force:   force,
+	})
+	if err != nil {
+		err = sm.deadlineError(ctx, GitOperationPush, err)
+		if ClassOf(err) != "" {
+			return "", err
+		}
+		var apiErr *apiError
+		if errors.As(err, &apiErr) && (apiErr.statusCode == http.StatusUnauthorized || apiErr.statusCode == http.StatusForbidden) {
+			return "", &GitOperationError{Operation: GitOperationPush, Class: ErrorClassAuth, Err: err}
+		}
+		return "", classifyGitError(GitOperationPush, err)
+	}
+	return remoteRev, nil
+}
+
+// apiFileChanges returns the changes of the files of the given commit from
+// its parent.
+func apiFileChanges(parent, commit *object.Commit) ([]apiFileChange, error) {
+	from, err := parent.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the tree of commit '%s': %w", parent.Hash, err)
+	}
+	to, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the tree of commit '%s': %w", commit.Hash, err)
+	}
+	diff, err := object.DiffTree(from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute the changes of commit '%s': %w", commit.Hash, err)
+	}
+
+	changes := make([]apiFileChange, 0, len(diff))
+	for _, d := range diff {
+		if d.To.Name == "" {
+			changes = append(changes, apiFileChange{path: d.From.Name, mode: d.From.TreeEntry.Mode, deleted: true})
+			continue
+		}
+		_, file, err := d.Files()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the changes of '%s': %w", d.To.Name, err)
+		}
+		r, err := file.Reader()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read '%s': %w", d.To.Name, err)
+		}
+		content, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read '%s': %w", d.To.Name, err)
+		}
+		changes = append(changes, apiFileChange{path: d.To.Name, mode: d.To.TreeEntry.Mode, created: d.From.Name == "", content: content})
+	}
+	return changes, nil
+}
+
+// branchMovedError returns the error of a commit made on top of a parent which
+// isn't the head of the branch on the provider.
+func branchMovedError(branch, parent, head string) error {
+	return &GitOperationError{Operation: GitOperationPush, Class: ErrorClassConflict,
+		Err: fmt.Errorf("%w: the head of branch '%s' is '%s' instead of '%s'", ErrRemoteChanged, branch, head, parent)}
+}
+
+// apiError is the error of a request to a provider API which didn't succeed.
+type apiError struct {
+	statusCode int
+	status     string
+	message    string
+}
+
+// Error implements error.
+func (e *apiError) Error() string {
+	if e.message == "" {
+		return fmt.Sprintf("unexpected status '%s'", e.status)
+	}
+	return fmt.Sprintf("unexpected status '%s': %s", e.status, e.message)
+}
+
+// apiRequest sends a request with the given body encoded in JSON, unless nil,
+// and decodes the JSON response into v, unless nil. The error of a response
+// which doesn't succeed is an *apiError, with the message of its body.
+func apiRequest(ctx context.Context, c *http.Client, method, url string, header http.Header, body, v any) error {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reqBody = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	for k, values := range header {
+		req.Header[k] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var msg struct {
+			Message any `json:"message"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&msg)
+		apiErr := &apiError{statusCode: resp.StatusCode, status: resp.Status}
+		if msg.Message != nil {
+			apiErr.message = fmt.Sprint(msg.Message)
+		}
+		return apiErr
+	}
+	if v == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// isAPINotFound returns whether the error is the error of a request for a
+// resource which doesn't exist.
+func isAPINotFound(err error) bool {
+	var apiErr *apiError
+	return errors.As(err, &apiErr) && apiErr.statusCode == http.StatusNotFound
+}
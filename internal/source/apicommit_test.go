@@ -0,0 +1,270 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	extgogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	. "github.com/onsi/gomega"
+
+	"github.com/fluxcd/pkg/git"
+)
+
+func Test_apiFileChanges(t *testing.T) {
+	g := NewWithT(t)
+
+	repo, err := extgogit.Init(memory.NewStorage(), memfs.New())
+	g.Expect(err).ToNot(HaveOccurred())
+	wt, err := repo.Worktree()
+	g.Expect(err).ToNot(HaveOccurred())
+	commit := func(files map[string]string, removed ...string) *object.Commit {
+		for name, content := range files {
+			g.Expect(util.WriteFile(wt.Filesystem, name, []byte(content), 0o644)).To(Succeed())
+			_, err := wt.Add(name)
+			g.Expect(err).ToNot(HaveOccurred())
+		}
+		for _, name := range removed {
+			_, err := wt.Remove(name)
+			g.Expect(err).ToNot(HaveOccurred())
+		}
+		hash, err := wt.Commit("commit", &extgogit.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		})
+		g.Expect(err).ToNot(HaveOccurred())
+		c, err := repo.CommitObject(hash)
+		g.Expect(err).ToNot(HaveOccurred())
+		return c
+	}
+
+	parent := commit(map[string]string{"deploy.yaml": "image: foo:v1\n", "old.yaml": "old\n", "same.yaml": "same\n"})
+	updated := commit(map[string]string{"deploy.yaml": "image: foo:v2\n", "apps/new.yaml": "new\n"}, "old.yaml")
+
+	changes, err := apiFileChanges(parent, updated)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(changes).To(ConsistOf(
+		apiFileChange{path: "apps/new.yaml", mode: filemode.Regular, created: true, content: []byte("new\n")},
+		apiFileChange{path: "deploy.yaml", mode: filemode.Regular, content: []byte("image: foo:v2\n")},
+		apiFileChange{path: "old.yaml", mode: filemode.Regular, deleted: true},
+	))
+}
+
+func testAPICommit(force bool) apiCommit {
+	return apiCommit{
+		branch:     "main",
+		parent:     "parent-sha",
+		parentTree: "parent-tree",
+		message:    "Update images",
+		author:     git.Signature{Name: "Flux", Email: "flux@example.com", When: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)},
+		changes: []apiFileChange{
+			{path: "deploy.yaml", mode: filemode.Regular, content: []byte("image: foo:v2\n")},
+			{path: "apps/new.yaml", mode: filemode.Executable, created: true, content: []byte("new\n")},
+			{path: "old.yaml", mode: filemode.Regular, deleted: true},
+		},
+		force: force,
+	}
+}
+
+func Test_gitHubCommitter(t *testing.T) {
+	tests := []struct {
+		name       string
+		head       string
+		force      bool
+		wantMethod string
+		wantErr    string
+	}{
+		{name: "updates the branch", head: "parent-sha", wantMethod: http.MethodPatch},
+		{name: "creates the branch", wantMethod: http.MethodPost},
+		{name: "rejects a moved branch", head: "other-sha", wantErr: "the head of branch 'main' is 'other-sha' instead of 'parent-sha'"},
+		{name: "overwrites a moved branch when forced", head: "other-sha", force: true, wantMethod: http.MethodPatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			var (
+				blobs     []string
+				tree      map[string]any
+				commit    map[string]any
+				refMethod string
+				ref       map[string]any
+			)
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST /repos/org/app/git/blobs", func(w http.ResponseWriter, r *http.Request) {
+				g.Expect(r.Header.Get("Authorization")).To(Equal("Bearer t0k3n"))
+				var blob gitHubBlob
+				g.Expect(json.NewDecoder(r.Body).Decode(&blob)).To(Succeed())
+				content, err := base64.StdEncoding.DecodeString(blob.Content)
+				g.Expect(err).ToNot(HaveOccurred())
+				blobs = append(blobs, string(content))
+				_ = json.NewEncoder(w).Encode(gitHubObject{SHA: "blob-" + string(rune('0'+len(blobs)))})
+			})
+			mux.HandleFunc("POST /repos/org/app/git/trees", func(w http.ResponseWriter, r *http.Request) {
+				g.Expect(json.NewDecoder(r.Body).Decode(&tree)).To(Succeed())
+				_ = json.NewEncoder(w).Encode(gitHubObject{SHA: "tree-sha"})
+			})
+			mux.HandleFunc("POST /repos/org/app/git/commits", func(w http.ResponseWriter, r *http.Request) {
+				g.Expect(json.NewDecoder(r.Body).Decode(&commit)).To(Succeed())
+				_ = json.NewEncoder(w).Encode(gitHubObject{SHA: "commit-sha"})
+			})
+			mux.HandleFunc("GET /repos/org/app/git/ref/heads/main", func(w http.ResponseWriter, r *http.Request) {
+				if tt.head == "" {
+					w.WriteHeader(http.StatusNotFound)
+					_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+					return
+				}
+				_ = json.NewEncoder(w).Encode(gitHubRef{Object: gitHubObject{SHA: tt.head}})
+			})
+			refHandler := func(w http.ResponseWriter, r *http.Request) {
+				refMethod = r.Method
+				g.Expect(json.NewDecoder(r.Body).Decode(&ref)).To(Succeed())
+				_, _ = w.Write([]byte(`{}`))
+			}
+			mux.HandleFunc("POST /repos/org/app/git/refs", refHandler)
+			mux.HandleFunc("PATCH /repos/org/app/git/refs/heads/main", refHandler)
+			srv := httptest.NewServer(mux)
+			defer srv.Close()
+
+			c := &gitHubCommitter{address: srv.URL, repository: "org/app", token: "t0k3n", client: srv.Client()}
+			rev, err := c.commit(context.TODO(), testAPICommit(tt.force))
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+				g.Expect(ClassOf(err)).To(Equal(ErrorClassConflict))
+				g.Expect(refMethod).To(BeEmpty())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(rev).To(Equal("commit-sha"))
+
+			g.Expect(blobs).To(Equal([]string{"image: foo:v2\n", "new\n"}))
+			g.Expect(tree).To(Equal(map[string]any{
+				"base_tree": "parent-tree",
+				"tree": []any{
+					map[string]any{"path": "deploy.yaml", "mode": "100644", "type": "blob", "sha": "blob-1"},
+					map[string]any{"path": "apps/new.yaml", "mode": "100755", "type": "blob", "sha": "blob-2"},
+					map[string]any{"path": "old.yaml", "mode": "100644", "type": "blob", "sha": nil},
+				},
+			}))
+			g.Expect(commit).To(Equal(map[string]any{
+				"message": "Update images",
+				"tree":    "tree-sha",
+				"parents": []any{"parent-sha"},
+				"author":  map[string]any{"name": "Flux", "email": "flux@example.com", "date": "2024-05-01T10:00:00Z"},
+			}))
+			g.Expect(refMethod).To(Equal(tt.wantMethod))
+			g.Expect(ref).To(HaveKeyWithValue("sha", "commit-sha"))
+			if tt.wantMethod == http.MethodPost {
+				g.Expect(ref).To(HaveKeyWithValue("ref", "refs/heads/main"))
+			}
+			if tt.force {
+				g.Expect(ref).To(HaveKeyWithValue("force", true))
+			}
+		})
+	}
+}
+
+func Test_gitLabCommitter(t *testing.T) {
+	tests := []struct {
+		name         string
+		head         string
+		force        bool
+		wantStartSHA string
+		wantErr      string
+	}{
+		{name: "commits on the head of the branch", head: "parent-sha"},
+		{name: "creates the branch from the parent", wantStartSHA: "parent-sha"},
+		{name: "rejects a moved branch", head: "other-sha", wantErr: "the head of branch 'main' is 'other-sha' instead of 'parent-sha'"},
+		{name: "overwrites a moved branch when forced", head: "other-sha", force: true, wantStartSHA: "parent-sha"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			var commit *gitLabCommit
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /projects/group%2Fapp/repository/branches/main", func(w http.ResponseWriter, r *http.Request) {
+				g.Expect(r.Header.Get("PRIVATE-TOKEN")).To(Equal("t0k3n"))
+				if tt.head == "" {
+					w.WriteHeader(http.StatusNotFound)
+					_, _ = w.Write([]byte(`{"message":"404 Branch Not Found"}`))
+					return
+				}
+				_, _ = w.Write([]byte(`{"commit":{"id":"` + tt.head + `"}}`))
+			})
+			mux.HandleFunc("POST /projects/group%2Fapp/repository/commits", func(w http.ResponseWriter, r *http.Request) {
+				commit = &gitLabCommit{}
+				g.Expect(json.NewDecoder(r.Body).Decode(commit)).To(Succeed())
+				w.WriteHeader(http.StatusCreated)
+				_ = json.NewEncoder(w).Encode(gitLabCommitID{ID: "commit-sha"})
+			})
+			srv := httptest.NewServer(mux)
+			defer srv.Close()
+
+			c := &gitLabCommitter{address: srv.URL, project: "group/app", token: "t0k3n", client: srv.Client()}
+			rev, err := c.commit(context.TODO(), testAPICommit(tt.force))
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+				g.Expect(ClassOf(err)).To(Equal(ErrorClassConflict))
+				g.Expect(commit).To(BeNil())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(rev).To(Equal("commit-sha"))
+
+			encode := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+			no, yes := false, true
+			g.Expect(commit).To(Equal(&gitLabCommit{
+				Branch:        "main",
+				StartSHA:      tt.wantStartSHA,
+				CommitMessage: "Update images",
+				AuthorName:    "Flux",
+				AuthorEmail:   "flux@example.com",
+				Actions: []gitLabAction{
+					{Action: "delete", FilePath: "old.yaml"},
+					{Action: "update", FilePath: "deploy.yaml", Content: encode("image: foo:v2\n"), Encoding: "base64", ExecuteFilemode: &no},
+					{Action: "create", FilePath: "apps/new.yaml", Content: encode("new\n"), Encoding: "base64", ExecuteFilemode: &yes},
+				},
+				Force: tt.force,
+			}))
+		})
+	}
+}
+
+func Test_apiRequest_error(t *testing.T) {
+	g := NewWithT(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"message":"Resource not accessible by integration"}`))
+	}))
+	defer srv.Close()
+
+	err := apiRequest(context.TODO(), srv.Client(), http.MethodGet, srv.URL, nil, nil, nil)
+	g.Expect(err).To(MatchError("unexpected status '403 Forbidden': Resource not accessible by integration"))
+	g.Expect(isAPINotFound(err)).To(BeFalse())
+}
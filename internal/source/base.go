@@ -0,0 +1,315 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-billy/v5/util"
+	extgogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// ErrBaseConflict is the error of changes which can't be merged into the base
+// branch, the lines they change not being found there.
+var ErrBaseConflict = errors.New("changes conflict with the base branch")
+
+// baseChange is the change of a file of the worktree since the checkout.
+type baseChange struct {
+	path string
+	// orig and updated are the contents of the file at the checkout and in
+	// the worktree, nil when the file doesn't exist.
+	orig, updated *string
+	mode          os.FileMode
+}
+
+// MergeOntoBase merges the changes made in the worktree since the checkout
+// into the base branch, and checks out the result on the push branch, for the
+// changes to be committed on top of the base branch rather than of the
+// checkout. Like a push branch created from the checkout, a push branch other
+// than the base branch is overwritten. Only the changed lines are merged, the
+// other lines of the files being kept as they are. It does nothing when
+// there's no base branch.
+func (sm *SourceManager) MergeOntoBase(ctx context.Context) error {
+	if sm.srcCfg.baseBranch == "" {
+		return nil
+	}
+	repo, err := sm.openRepository()
+	if err != nil {
+		return err
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to load worktree: %w", err)
+	}
+	changes, err := worktreeChanges(repo, wt)
+	if err != nil {
+		return err
+	}
+
+	gitOpCtx, cancel := sm.gitOperationContext(ctx)
+	defer cancel()
+	baseHead, err := fetchBranch(gitOpCtx, repo, sm.srcCfg.url, sm.srcCfg.authOpts, sm.srcCfg.proxyOpts, sm.srcCfg.baseBranch)
+	if err != nil {
+		return classifyGitError(GitOperationCheckout, sm.deadlineError(gitOpCtx, GitOperationCheckout,
+			fmt.Errorf("failed to fetch the base branch '%s': %w", sm.srcCfg.baseBranch, err)))
+	}
+	baseCommit, err := repo.CommitObject(baseHead)
+	if err != nil {
+		return fmt.Errorf("failed to read the head of the base branch: %w", err)
+	}
+	baseTree, err := baseCommit.Tree()
+	if err != nil {
+		return err
+	}
+
+	branchRef := plumbing.NewBranchReferenceName(sm.srcCfg.pushBranch)
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(branchRef, baseHead)); err != nil {
+		return fmt.Errorf("failed to create branch '%s': %w", sm.srcCfg.pushBranch, err)
+	}
+	if err := wt.Checkout(&extgogit.CheckoutOptions{Branch: branchRef, Force: true}); err != nil {
+		return fmt.Errorf("failed to checkout branch '%s': %w", sm.srcCfg.pushBranch, err)
+	}
+
+	var conflicts []string
+	for _, ch := range changes {
+		var base *string
+		f, err := baseTree.File(ch.path)
+		switch {
+		case err == nil:
+			contents, err := f.Contents()
+			if err != nil {
+				return err
+			}
+			base = &contents
+		case !errors.Is(err, object.ErrFileNotFound):
+			return err
+		}
+		merged, ok := mergeFile(ch, base)
+		if !ok {
+			conflicts = append(conflicts, ch.path)
+			continue
+		}
+		switch {
+		case merged == nil && base != nil:
+			if err := util.RemoveAll(wt.Filesystem, ch.path); err != nil {
+				return err
+			}
+		case merged != nil && (base == nil || *merged != *base):
+			if err := util.WriteFile(wt.Filesystem, ch.path, []byte(*merged), ch.mode); err != nil {
+				return err
+			}
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%w '%s': the changed lines of '%s' aren't found", ErrBaseConflict, sm.srcCfg.baseBranch,
+			strings.Join(conflicts, "', '"))
+	}
+
+	// The head of the base branch is checked from where the commit is made
+	// when it's pushed to.
+	if sm.srcCfg.pushBranch == sm.srcCfg.baseBranch && sm.srcCfg.writeTarget == nil {
+		sm.pushBranchHead, sm.pushBranchHeadKnown = baseHead.String(), true
+	}
+	return nil
+}
+
+// worktreeChanges returns the changes of the files of the worktree since the
+// commit of its HEAD, sorted by path.
+func worktreeChanges(repo *extgogit.Repository, wt *extgogit.Worktree) ([]baseChange, error) {
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	headCommit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the checked out commit: %w", err)
+	}
+	headTree, err := headCommit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	status, err := wt.Status()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the worktree status: %w", err)
+	}
+
+	var changes []baseChange
+	for path, st := range status {
+		if st.Worktree == extgogit.Unmodified && st.Staging == extgogit.Unmodified {
+			continue
+		}
+		ch := baseChange{path: path, mode: 0o644}
+		f, err := headTree.File(path)
+		switch {
+		case err == nil:
+			contents, err := f.Contents()
+			if err != nil {
+				return nil, err
+			}
+			ch.orig = &contents
+		case !errors.Is(err, object.ErrFileNotFound):
+			return nil, err
+		}
+		fi, err := wt.Filesystem.Lstat(path)
+		switch {
+		case err == nil:
+			data, err := util.ReadFile(wt.Filesystem, path)
+			if err != nil {
+				return nil, err
+			}
+			updated := string(data)
+			ch.updated, ch.mode = &updated, fi.Mode().Perm()
+		case !errors.Is(err, os.ErrNotExist):
+			return nil, err
+		}
+		changes = append(changes, ch)
+	}
+	slices.SortFunc(changes, func(a, b baseChange) int { return strings.Compare(a.path, b.path) })
+	return changes, nil
+}
+
+// mergeFile merges the change of a file into its contents in the base branch,
+// nil when it doesn't exist there. It returns the merged contents, nil when
+// the file is removed, and false when the change conflicts with the base
+// branch.
+func mergeFile(ch baseChange, base *string) (*string, bool) {
+	switch {
+	case ch.orig == nil:
+		// A new file is added, unless the base branch has another one.
+		return ch.updated, base == nil || *base == *ch.updated
+	case ch.updated == nil:
+		// A removed file is removed, unless it was changed in the base
+		// branch.
+		return nil, base == nil || *base == *ch.orig
+	case base == nil:
+		return nil, false
+	}
+	merged, ok := mergeLines(*ch.orig, *ch.updated, *base)
+	return &merged, ok
+}
+
+// lineEdit replaces the lines from start to end of a file.
+type lineEdit struct {
+	start, end int
+	lines      []string
+}
+
+// mergeLines merges the changes from orig to updated into base, line by line.
+// Each block of changed lines is matched with its occurrence of the same rank
+// in base, which must have as many occurrences of it as orig, and the lines
+// inserted alone are placed after the line preceding them. It returns false
+// when a change can't be placed, a block of changed lines being skipped when
+// base already has its updated lines instead.
+func mergeLines(orig, updated, base string) (string, bool) {
+	origLines, baseLines := splitLines(orig), splitLines(base)
+	diffs := diff.Do(orig, updated)
+
+	var edits []lineEdit
+	pos := 0
+	for i := 0; i < len(diffs); {
+		if diffs[i].Type == diffmatchpatch.DiffEqual {
+			pos += len(splitLines(diffs[i].Text))
+			i++
+			continue
+		}
+		var deleted, inserted []string
+		for ; i < len(diffs) && diffs[i].Type != diffmatchpatch.DiffEqual; i++ {
+			if diffs[i].Type == diffmatchpatch.DiffDelete {
+				deleted = append(deleted, splitLines(diffs[i].Text)...)
+			} else {
+				inserted = append(inserted, splitLines(diffs[i].Text)...)
+			}
+		}
+
+		var start, end int
+		switch {
+		case len(deleted) > 0:
+			at, ok := matchBlock(origLines, baseLines, pos, len(deleted))
+			if !ok {
+				if len(occurrences(baseLines, deleted)) == 0 && len(occurrences(baseLines, inserted)) > 0 {
+					pos += len(deleted)
+					continue
+				}
+				return "", false
+			}
+			start, end = at, at+len(deleted)
+		case pos > 0:
+			at, ok := matchBlock(origLines, baseLines, pos-1, 1)
+			if !ok {
+				return "", false
+			}
+			start, end = at+1, at+1
+		}
+		edits = append(edits, lineEdit{start: start, end: end, lines: inserted})
+		pos += len(deleted)
+	}
+
+	slices.SortStableFunc(edits, func(a, b lineEdit) int { return a.start - b.start })
+	var merged strings.Builder
+	last := 0
+	for _, e := range edits {
+		if e.start < last {
+			return "", false
+		}
+		merged.WriteString(strings.Join(baseLines[last:e.start], ""))
+		merged.WriteString(strings.Join(e.lines, ""))
+		last = e.end
+	}
+	merged.WriteString(strings.Join(baseLines[last:], ""))
+	return merged.String(), true
+}
+
+// matchBlock returns the index in base of the block of n lines at pos in
+// orig, being the occurrence of the same rank.
+func matchBlock(orig, base []string, pos, n int) (int, bool) {
+	block := orig[pos : pos+n]
+	origOcc, baseOcc := occurrences(orig, block), occurrences(base, block)
+	if len(origOcc) != len(baseOcc) {
+		return 0, false
+	}
+	return baseOcc[slices.Index(origOcc, pos)], true
+}
+
+// occurrences returns the indices of the occurrences of the block of lines in
+// the lines.
+func occurrences(lines, block []string) []int {
+	var indices []int
+	for i := 0; i+len(block) <= len(lines); i++ {
+		if slices.Equal(lines[i:i+len(block)], block) {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// splitLines splits the text in lines, keeping their line endings.
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,282 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	. "github.com/onsi/gomega"
+	"github.com/otiai10/copy"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	imagev1_reflect "github.com/fluxcd/image-reflector-controller/api/v1beta2"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+	"github.com/fluxcd/image-automation-controller/internal/policy"
+	"github.com/fluxcd/image-automation-controller/internal/testutil"
+)
+
+func Test_mergeLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		orig     string
+		updated  string
+		base     string
+		want     string
+		wantFail bool
+	}{
+		{
+			name:    "changed line",
+			orig:    "a\nimage: foo:1\nb\n",
+			updated: "a\nimage: foo:2\nb\n",
+			base:    "x\na\nimage: foo:1\nb\ny\n",
+			want:    "x\na\nimage: foo:2\nb\ny\n",
+		},
+		{
+			name:    "other lines changed in base",
+			orig:    "name: app\nimage: foo:1\n",
+			updated: "name: app\nimage: foo:2\n",
+			base:    "name: app-release\nreplicas: 2\nimage: foo:1\n",
+			want:    "name: app-release\nreplicas: 2\nimage: foo:2\n",
+		},
+		{
+			name:    "occurrences matched by rank",
+			orig:    "image: foo:1\n---\nimage: foo:1\n",
+			updated: "image: foo:1\n---\nimage: foo:2\n",
+			base:    "image: foo:1\nx\n---\nimage: foo:1\n",
+			want:    "image: foo:1\nx\n---\nimage: foo:2\n",
+		},
+		{
+			name:    "inserted line",
+			orig:    "images:\n- name: foo\n",
+			updated: "images:\n- name: foo\n  newTag: \"2\"\n",
+			base:    "resources: []\nimages:\n- name: foo\n",
+			want:    "resources: []\nimages:\n- name: foo\n  newTag: \"2\"\n",
+		},
+		{
+			name:    "already changed in base",
+			orig:    "image: foo:1\n",
+			updated: "image: foo:2\n",
+			base:    "x\nimage: foo:2\n",
+			want:    "x\nimage: foo:2\n",
+		},
+		{
+			name:     "changed line not in base",
+			orig:     "image: foo:1\n",
+			updated:  "image: foo:2\n",
+			base:     "image: foo:0\n",
+			wantFail: true,
+		},
+		{
+			name:     "changed line duplicated in base",
+			orig:     "image: foo:1\n",
+			updated:  "image: foo:2\n",
+			base:     "image: foo:1\nimage: foo:1\n",
+			wantFail: true,
+		},
+		{
+			name:    "no trailing newline",
+			orig:    "a\nimage: foo:1",
+			updated: "a\nimage: foo:2",
+			base:    "b\na\nimage: foo:1",
+			want:    "b\na\nimage: foo:2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			got, ok := mergeLines(tt.orig, tt.updated, tt.base)
+			g.Expect(ok).To(Equal(!tt.wantFail))
+			if !tt.wantFail {
+				g.Expect(got).To(Equal(tt.want))
+			}
+		})
+	}
+}
+
+func TestSourceManager_MergeOntoBase(t *testing.T) {
+	tests := []struct {
+		name       string
+		pushBranch string
+		baseImage  string
+		wantErr    bool
+		wantNoPush bool
+	}{
+		{
+			name: "push to base branch",
+		},
+		{
+			name:       "push branch from base branch",
+			pushBranch: "auto",
+		},
+		{
+			name:      "image changed in base branch",
+			baseImage: "helloworld:0.9.0",
+			wantErr:   true,
+		},
+		{
+			name:       "image already updated in base branch",
+			baseImage:  "helloworld:1.0.1",
+			wantNoPush: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			ctx := context.TODO()
+
+			gitServer := testutil.SetUpGitTestServer(g)
+			t.Cleanup(func() {
+				g.Expect(os.RemoveAll(gitServer.Root())).ToNot(HaveOccurred())
+				gitServer.StopHTTP()
+			})
+
+			workDir := t.TempDir()
+			testNS := "test-ns"
+			baseBranch := "release-1"
+
+			imgPolicy := &imagev1_reflect.ImagePolicy{}
+			imgPolicy.Name = "policy1"
+			imgPolicy.Namespace = testNS
+			imgPolicy.Status = imagev1_reflect.ImagePolicyStatus{
+				LatestImage: "helloworld:1.0.1",
+			}
+			g.Expect(copy.Copy("testdata/appconfig", workDir)).ToNot(HaveOccurred())
+			g.Expect(testutil.ReplaceMarker(filepath.Join(workDir, "deploy.yaml"), client.ObjectKeyFromObject(imgPolicy))).To(Succeed())
+
+			repoPath := "/config-" + rand.String(5) + ".git"
+			repo := testutil.InitGitRepo(g, gitServer, workDir, "main", repoPath)
+			repoURL := gitServer.HTTPAddressWithCredentials() + repoPath
+
+			// The base branch diverges from the checkout branch.
+			head, err := repo.Head()
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName(baseBranch), head.Hash()))).To(Succeed())
+			baseHead := testutil.CommitInRepo(ctx, g, repoURL, baseBranch, originRemote, "Release change", func(path string) {
+				deploy := filepath.Join(path, "deploy.yaml")
+				data, err := os.ReadFile(deploy)
+				g.Expect(err).ToNot(HaveOccurred())
+				contents := strings.Replace(string(data), "name: test", "name: test-release", 1)
+				if tt.baseImage != "" {
+					contents = strings.Replace(contents, "helloworld:1.0.0", tt.baseImage, 1)
+				}
+				g.Expect(os.WriteFile(deploy, []byte(contents), 0o644)).To(Succeed())
+			})
+
+			gitRepo := &sourcev1.GitRepository{}
+			gitRepo.Name = "test-repo"
+			gitRepo.Namespace = testNS
+			gitRepo.Spec = sourcev1.GitRepositorySpec{
+				URL:       repoURL,
+				Reference: &sourcev1.GitRepositoryRef{Branch: "main"},
+			}
+
+			updateAuto := &imagev1.ImageUpdateAutomation{}
+			updateAuto.Name = "test-update"
+			updateAuto.Namespace = testNS
+			updateAuto.Spec = imagev1.ImageUpdateAutomationSpec{
+				SourceRef: imagev1.CrossNamespaceSourceReference{
+					Kind: sourcev1.GitRepositoryKind,
+					Name: gitRepo.Name,
+				},
+				GitSpec: &imagev1.GitSpec{
+					Push: &imagev1.PushSpec{
+						Branch: tt.pushBranch,
+						Base:   baseBranch,
+					},
+					Commit: imagev1.CommitSpec{
+						Author:          testAuthor,
+						MessageTemplate: testCommitTemplate,
+					},
+				},
+				Update: &imagev1.UpdateStrategy{
+					Strategy: imagev1.UpdateStrategySetters,
+				},
+			}
+
+			kClient := fakeclient.NewClientBuilder().WithScheme(scheme.Scheme).
+				WithObjects(imgPolicy, gitRepo, updateAuto).Build()
+
+			sm, err := NewSourceManager(ctx, kClient, updateAuto)
+			g.Expect(err).ToNot(HaveOccurred())
+			defer func() {
+				g.Expect(sm.Cleanup()).ToNot(HaveOccurred())
+			}()
+			_, err = sm.CheckoutSource(ctx)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			result, err := policy.ApplyPolicies(ctx, sm.workingDir, updateAuto, []imagev1_reflect.ImagePolicy{*imgPolicy})
+			g.Expect(err).ToNot(HaveOccurred())
+
+			err = sm.MergeOntoBase(ctx)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(errors.Is(err, ErrBaseConflict)).To(BeTrue())
+				g.Expect(err.Error()).To(ContainSubstring("'deploy.yaml'"))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+
+			pushResult, err := sm.CommitAndPush(ctx, updateAuto, result)
+			g.Expect(err).ToNot(HaveOccurred())
+			if tt.wantNoPush {
+				g.Expect(pushResult).To(BeNil())
+				return
+			}
+			g.Expect(pushResult).ToNot(BeNil())
+
+			pushBranch := tt.pushBranch
+			if pushBranch == "" {
+				pushBranch = baseBranch
+			}
+			pushed, cloneDir, err := testutil.Clone(ctx, repoURL, pushBranch, originRemote)
+			g.Expect(err).ToNot(HaveOccurred())
+			defer func() { os.RemoveAll(cloneDir) }()
+			pushedHead, err := pushed.Head()
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(pushedHead.Hash().String()).To(Equal(pushResult.Commit().Hash.String()))
+
+			// The commit is made on top of the base branch, with both the
+			// change of the base branch and the update.
+			commit, err := pushed.CommitObject(pushedHead.Hash())
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(commit.ParentHashes).To(Equal([]plumbing.Hash{baseHead}))
+			deploy, err := os.ReadFile(filepath.Join(cloneDir, "deploy.yaml"))
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(string(deploy)).To(ContainSubstring("name: test-release"))
+			g.Expect(string(deploy)).To(ContainSubstring("helloworld:1.0.1"))
+
+			// The checkout branch is left as it is.
+			main, err := testutil.HeadFromBranch(repo, "main")
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(main.Hash).To(Equal(head.Hash()))
+		})
+	}
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/fluxcd/pkg/git"
+	extgogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// defaultPolicyBranchTemplate is the template of the names of the branches
+// the changes of each policy are pushed to, when none is configured.
+const defaultPolicyBranchTemplate = `image-updates/{{ .Policy.Name }}`
+
+// PolicyBranchData is the type of the value given to the template of the
+// names of the per-policy branches.
+type PolicyBranchData struct {
+	Policy types.NamespacedName
+	Values map[string]string
+}
+
+// PerPolicyBranches returns if the changes of each policy are pushed to a
+// branch of their own, see CheckoutPolicyBranch.
+func (sm SourceManager) PerPolicyBranches() bool {
+	return sm.srcCfg.perPolicyBranches
+}
+
+// PolicyBranch renders the name of the branch the changes of the given policy
+// are pushed to. The returned error wraps ErrInvalidTemplate.
+func (sm SourceManager) PolicyBranch(policy types.NamespacedName) (string, error) {
+	name, err := templateBranchName(sm.srcCfg.policyBranchTemplate, &PolicyBranchData{
+		Policy: policy,
+		Values: sm.srcCfg.templateValues,
+	})
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidTemplate, err)
+	}
+	return name, nil
+}
+
+// CheckoutPolicyBranch discards any change in the worktree and checks out the
+// given branch of a policy at its head on the remote, or at the given commit
+// when the branch doesn't exist on the remote, or when the heads of the
+// remote branches weren't fetched. The following commits are pushed to the
+// branch, so that a branch which already has the changes of the policy is
+// left as is.
+func (sm *SourceManager) CheckoutPolicyBranch(branch string, base *git.Commit) error {
+	return sm.checkoutPushBranch(branch, base)
+}
+
+// checkoutPushBranch discards any change in the worktree and checks out the
+// given push branch at its head on the remote, or at the given commit when
+// the branch doesn't exist on the remote, or when the heads of the remote
+// branches weren't fetched.
+func (sm *SourceManager) checkoutPushBranch(branch string, base *git.Commit) error {
+	repo, err := sm.openRepository()
+	if err != nil {
+		return err
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to load worktree: %w", err)
+	}
+
+	start := plumbing.NewHash(base.Hash.String())
+	sm.pushBranchHead, sm.pushBranchHeadKnown = "", false
+	if !sm.srcCfg.singleBranch {
+		ref, err := repo.Reference(plumbing.NewRemoteReferenceName(extgogit.DefaultRemoteName, branch), true)
+		switch {
+		case err == nil:
+			start = ref.Hash()
+			sm.pushBranchHead = ref.Hash().String()
+		case !errors.Is(err, plumbing.ErrReferenceNotFound):
+			return fmt.Errorf("failed to resolve the branch '%s': %w", branch, err)
+		}
+		sm.pushBranchHeadKnown = true
+	}
+
+	refName := plumbing.NewBranchReferenceName(branch)
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(refName, start)); err != nil {
+		return fmt.Errorf("failed to create branch '%s': %w", branch, err)
+	}
+	if err := wt.Checkout(&extgogit.CheckoutOptions{Branch: refName, Force: true}); err != nil {
+		return fmt.Errorf("failed to checkout branch '%s': %w", branch, err)
+	}
+	sm.srcCfg.pushBranch = branch
+	if len(sm.srcCfg.signingKeys) > 0 {
+		if sm.srcCfg.signingEntity, err = selectSigningKey(sm.srcCfg.signingKeys, branch); err != nil {
+			return &SigningError{Err: err}
+		}
+	}
+	return nil
+}
+
+// parseBranchNameTemplate parses a per-policy branch name template.
+func parseBranchNameTemplate(nameTemplate string) (*template.Template, error) {
+	// The branch of a policy is expected to be the same between runs, for the
+	// pull request opened from it to be updated, so only the hermetic
+	// functions are available.
+	t, err := template.New("branch name").Funcs(sprig.HermeticTxtFuncMap()).Parse(nameTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create branch name template from spec: %w", err)
+	}
+	return t, nil
+}
+
+// checkPolicyBranchTemplate checks that a per-policy branch name template
+// renders different names for different policies, so that the changes of
+// each policy are pushed to a branch of its own.
+func checkPolicyBranchTemplate(t *template.Template) error {
+	var names [2]string
+	for i, name := range []string{"policy-a", "policy-b"} {
+		b := &strings.Builder{}
+		if err := t.Execute(b, PolicyBranchData{Policy: types.NamespacedName{Name: name}}); err != nil {
+			return fmt.Errorf("failed to run branch name template from spec: %w", err)
+		}
+		names[i] = b.String()
+	}
+	if names[0] == names[1] {
+		return errors.New("branch name template must reference the name of the policy, with '.Policy.Name'")
+	}
+	return nil
+}
+
+// templateBranchName renders a per-policy branch name template, returning a
+// valid branch name or an error.
+func templateBranchName(nameTemplate string, data *PolicyBranchData) (string, error) {
+	t, err := parseBranchNameTemplate(nameTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	b := &strings.Builder{}
+	if err := t.Execute(b, *data); err != nil {
+		return "", fmt.Errorf("failed to run branch name template from spec: %w", err)
+	}
+	name := strings.TrimSpace(b.String())
+	if err := plumbing.NewBranchReferenceName(name).Validate(); err != nil {
+		return "", fmt.Errorf("invalid branch name '%s': %w", name, err)
+	}
+	return name, nil
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// cachedRefPrefix is the reference namespace the remote references of a
+// previous clone are moved to before a clone from the cache. The references
+// are not seen as remote branches by the clone, but their objects are
+// advertised to the remote as already present, so only the missing objects
+// are fetched.
+const cachedRefPrefix = "refs/cache/"
+
+// CloneCache is an on-disk cache of the Git objects of the sources, kept
+// between reconciliations. A clone with the cache only fetches the objects it
+// doesn't already have, instead of the whole repository.
+//
+// The cache holds one Git object store per GitRepository. A store is locked
+// for the whole time a SourceManager uses it, from the checkout to the
+// cleanup, so the automations of the same GitRepository are serialized. The
+// locks are in-process; the cache directory must not be shared by multiple
+// controller instances.
+type CloneCache struct {
+	dir string
+
+	mu    sync.Mutex
+	locks map[types.NamespacedName]*sync.Mutex
+}
+
+// NewCloneCache returns a CloneCache which stores its data in the given
+// directory.
+func NewCloneCache(dir string) (*CloneCache, error) {
+	if dir == "" {
+		return nil, errors.New("clone cache directory must not be empty")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create clone cache directory: %w", err)
+	}
+	return &CloneCache{
+		dir:   dir,
+		locks: map[types.NamespacedName]*sync.Mutex{},
+	}, nil
+}
+
+// cacheEntry is a locked Git object store of the cache.
+type cacheEntry struct {
+	dir    string
+	storer *filesystem.Storage
+	// reused is true if the store contains objects from a previous clone.
+	reused bool
+	unlock func()
+}
+
+// acquire locks and returns the store for the given GitRepository, ready to
+// be cloned into. A store that can't be opened or fails verification is
+// discarded and replaced by an empty one.
+func (c *CloneCache) acquire(key types.NamespacedName) (*cacheEntry, error) {
+	c.mu.Lock()
+	l, ok := c.locks[key]
+	if !ok {
+		l = &sync.Mutex{}
+		c.locks[key] = l
+	}
+	c.mu.Unlock()
+	l.Lock()
+
+	e := &cacheEntry{
+		dir:    filepath.Join(c.dir, key.Namespace, key.Name),
+		unlock: l.Unlock,
+	}
+	if _, err := os.Stat(e.dir); err == nil {
+		e.storer = filesystem.NewStorage(osfs.New(e.dir, osfs.WithBoundOS()), cache.NewObjectLRUDefault())
+		if err := prepareCachedStore(e.storer); err == nil {
+			e.reused = true
+			return e, nil
+		}
+	}
+	if err := e.reset(); err != nil {
+		e.release()
+		return nil, err
+	}
+	return e, nil
+}
+
+// reset discards the content of the store and replaces it by an empty one.
+func (e *cacheEntry) reset() error {
+	if err := os.RemoveAll(e.dir); err != nil {
+		return fmt.Errorf("failed to remove clone cache: %w", err)
+	}
+	if err := os.MkdirAll(e.dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create clone cache: %w", err)
+	}
+	e.storer = filesystem.NewStorage(osfs.New(e.dir, osfs.WithBoundOS()), cache.NewObjectLRUDefault())
+	e.reused = false
+	return nil
+}
+
+// release unlocks the store. The entry must not be used afterwards.
+func (e *cacheEntry) release() {
+	if e.unlock != nil {
+		e.unlock()
+		e.unlock = nil
+	}
+}
+
+// prepareCachedStore verifies the store left by a previous clone and turns it
+// back into one that can be cloned into, while keeping its objects. The
+// remote references are moved under cachedRefPrefix, and all the other
+// references, the remotes configuration and the index are removed.
+func prepareCachedStore(s *filesystem.Storage) error {
+	cfg, err := s.Config()
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	iter, err := s.IterReferences()
+	if err != nil {
+		return err
+	}
+	var refs []*plumbing.Reference
+	if err := iter.ForEach(func(ref *plumbing.Reference) error {
+		refs = append(refs, ref)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for _, ref := range refs {
+		name := ref.Name().String()
+		isCached := strings.HasPrefix(name, cachedRefPrefix)
+		if (isCached || ref.Name().IsRemote()) && ref.Type() == plumbing.HashReference {
+			// Verify the object the reference points to is present, as a
+			// corrupt store would otherwise only fail halfway through a
+			// clone.
+			if _, err := s.EncodedObject(plumbing.AnyObject, ref.Hash()); err != nil {
+				return fmt.Errorf("failed to verify reference '%s': %w", name, err)
+			}
+		}
+		if isCached {
+			continue
+		}
+		if err := s.RemoveReference(ref.Name()); err != nil {
+			return err
+		}
+		if ref.Name().IsRemote() && ref.Type() == plumbing.HashReference {
+			cached := plumbing.ReferenceName(cachedRefPrefix + strings.TrimPrefix(name, "refs/remotes/"))
+			if err := s.SetReference(plumbing.NewHashReference(cached, ref.Hash())); err != nil {
+				return err
+			}
+		}
+	}
+
+	cfg.Remotes = map[string]*config.RemoteConfig{}
+	cfg.Branches = map[string]*config.Branch{}
+	if err := s.SetConfig(cfg); err != nil {
+		return err
+	}
+	return s.SetIndex(&index.Index{Version: 2})
+}
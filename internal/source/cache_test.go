@@ -0,0 +1,142 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/osfs"
+	extgogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestCloneCache_acquire(t *testing.T) {
+	key := types.NamespacedName{Namespace: "test-ns", Name: "test-repo"}
+
+	tests := []struct {
+		name       string
+		corrupt    bool
+		wantReused bool
+	}{
+		{
+			name:       "reuses a valid store",
+			wantReused: true,
+		},
+		{
+			name:       "resets a corrupt store",
+			corrupt:    true,
+			wantReused: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			c, err := NewCloneCache(t.TempDir())
+			g.Expect(err).ToNot(HaveOccurred())
+
+			// Populate the store as a clone would.
+			s := filesystem.NewStorage(osfs.New(filepath.Join(c.dir, key.Namespace, key.Name)), cache.NewObjectLRUDefault())
+			repo, err := extgogit.Init(s, memfs.New())
+			g.Expect(err).ToNot(HaveOccurred())
+			_, err = repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{"https://example.com/repo.git"}})
+			g.Expect(err).ToNot(HaveOccurred())
+			wt, err := repo.Worktree()
+			g.Expect(err).ToNot(HaveOccurred())
+			hash, err := wt.Commit("initial", &extgogit.CommitOptions{
+				AllowEmptyCommits: true,
+				Author:            &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+			})
+			g.Expect(err).ToNot(HaveOccurred())
+			remoteHash := hash
+			if tt.corrupt {
+				remoteHash = plumbing.NewHash("0123456789012345678901234567890123456789")
+			}
+			g.Expect(s.SetReference(plumbing.NewHashReference("refs/remotes/origin/main", remoteHash))).To(Succeed())
+
+			e, err := c.acquire(key)
+			g.Expect(err).ToNot(HaveOccurred())
+			defer e.release()
+			g.Expect(e.reused).To(Equal(tt.wantReused))
+
+			refs, err := e.storer.IterReferences()
+			g.Expect(err).ToNot(HaveOccurred())
+			var names []string
+			g.Expect(refs.ForEach(func(ref *plumbing.Reference) error {
+				names = append(names, ref.Name().String())
+				return nil
+			})).To(Succeed())
+			cfg, err := e.storer.Config()
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(cfg.Remotes).To(BeEmpty())
+
+			if tt.wantReused {
+				// Only the remote reference is kept, as a cached reference,
+				// along with its objects.
+				g.Expect(names).To(ConsistOf(cachedRefPrefix + "origin/main"))
+				_, err = e.storer.EncodedObject(plumbing.CommitObject, hash)
+				g.Expect(err).ToNot(HaveOccurred())
+			} else {
+				g.Expect(names).To(BeEmpty())
+				_, err = e.storer.EncodedObject(plumbing.CommitObject, hash)
+				g.Expect(err).To(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestCloneCache_acquireLocks(t *testing.T) {
+	g := NewWithT(t)
+
+	c, err := NewCloneCache(t.TempDir())
+	g.Expect(err).ToNot(HaveOccurred())
+	key := types.NamespacedName{Namespace: "test-ns", Name: "test-repo"}
+
+	e, err := c.acquire(key)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	acquired := make(chan *cacheEntry)
+	go func() {
+		e2, err := c.acquire(key)
+		if err != nil {
+			close(acquired)
+			return
+		}
+		acquired <- e2
+	}()
+	g.Consistently(acquired, 100*time.Millisecond).ShouldNot(Receive())
+
+	// A store of another GitRepository isn't locked.
+	other, err := c.acquire(types.NamespacedName{Namespace: "test-ns", Name: "other-repo"})
+	g.Expect(err).ToNot(HaveOccurred())
+	other.release()
+
+	e.release()
+	var e2 *cacheEntry
+	g.Eventually(acquired).Should(Receive(&e2))
+	g.Expect(e2).ToNot(BeNil())
+	e2.release()
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/idxfile"
+	"github.com/go-git/go-git/v5/storage"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+// storedObjects is the number of Git objects in a storage, and their size
+// as stored.
+type storedObjects struct {
+	count int64
+	bytes int64
+}
+
+// CloneStats returns the statistics of the last clone of CheckoutSource, or
+// nil if they're unknown.
+func (sm SourceManager) CloneStats() *imagev1.CloneStats {
+	return sm.cloneStats
+}
+
+// CloneDuration returns the duration of the last clone of CheckoutSource,
+// including the clones fetching nothing as the remote didn't change.
+func (sm SourceManager) CloneDuration() time.Duration {
+	return sm.cloneDuration
+}
+
+// storedObjects returns the objects stored in the repository cloned in the
+// working directory.
+func (sm SourceManager) storedObjects() (storedObjects, error) {
+	s := sm.storer
+	if s == nil {
+		repo, err := sm.openRepository()
+		if err != nil {
+			return storedObjects{}, err
+		}
+		s = repo.Storer
+	}
+	return countObjects(s)
+}
+
+// countObjects returns the objects of the given storage. With a storage on
+// a filesystem, the objects of the packfiles are counted from their index,
+// without reading the packfiles, and their size is the size of the
+// packfiles. Otherwise, the objects are iterated and their size is their
+// uncompressed size.
+func countObjects(s storage.Storer) (storedObjects, error) {
+	var objects storedObjects
+	fsStorage, ok := s.(*filesystem.Storage)
+	if !ok {
+		iter, err := s.IterEncodedObjects(plumbing.AnyObject)
+		if err != nil {
+			return objects, err
+		}
+		err = iter.ForEach(func(o plumbing.EncodedObject) error {
+			objects.count++
+			objects.bytes += o.Size()
+			return nil
+		})
+		return objects, err
+	}
+
+	fs := fsStorage.Filesystem()
+	packs, err := fsStorage.ObjectPacks()
+	if err != nil {
+		return objects, err
+	}
+	for _, pack := range packs {
+		name := path.Join("objects", "pack", "pack-"+pack.String())
+		f, err := fs.Open(name + ".idx")
+		if err != nil {
+			return objects, err
+		}
+		idx := idxfile.NewMemoryIndex()
+		err = idxfile.NewDecoder(f).Decode(idx)
+		f.Close()
+		if err != nil {
+			return objects, fmt.Errorf("failed to decode index of packfile %s: %w", pack, err)
+		}
+		count, err := idx.Count()
+		if err != nil {
+			return objects, err
+		}
+		info, err := fs.Stat(name + ".pack")
+		if err != nil {
+			return objects, err
+		}
+		objects.count += count
+		objects.bytes += info.Size()
+	}
+	// The loose objects are those committed since, e.g. by a previous
+	// reconciliation with a clone cache.
+	err = fsStorage.ForEachObjectHash(func(h plumbing.Hash) error {
+		hex := h.String()
+		info, err := fs.Stat(path.Join("objects", hex[:2], hex[2:]))
+		if err != nil {
+			return err
+		}
+		objects.count++
+		objects.bytes += info.Size()
+		return nil
+	})
+	return objects, err
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	extgogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	. "github.com/onsi/gomega"
+)
+
+func Test_countObjects(t *testing.T) {
+	commitFile := func(g *WithT, repo *extgogit.Repository) {
+		wt, err := repo.Worktree()
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(util.WriteFile(wt.Filesystem, "deploy.yaml", []byte("kind: Deployment\n"), 0o644)).To(Succeed())
+		_, err = wt.Add("deploy.yaml")
+		g.Expect(err).ToNot(HaveOccurred())
+		_, err = wt.Commit("Add deployment", &extgogit.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		})
+		g.Expect(err).ToNot(HaveOccurred())
+	}
+
+	t.Run("on disk", func(t *testing.T) {
+		g := NewWithT(t)
+
+		repo, err := extgogit.PlainInit(t.TempDir(), false)
+		g.Expect(err).ToNot(HaveOccurred())
+		objects, err := countObjects(repo.Storer)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(objects).To(Equal(storedObjects{}))
+
+		// The blob, the tree and the commit are stored as loose objects.
+		commitFile(g, repo)
+		objects, err = countObjects(repo.Storer)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(objects.count).To(Equal(int64(3)))
+		g.Expect(objects.bytes).To(BeNumerically(">", 0))
+
+		// The packed objects are counted from the index of the packfile.
+		g.Expect(repo.RepackObjects(&extgogit.RepackConfig{})).To(Succeed())
+		packed, err := countObjects(repo.Storer)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(packed.count).To(Equal(int64(3)))
+		g.Expect(packed.bytes).To(BeNumerically(">", 0))
+	})
+
+	t.Run("in memory", func(t *testing.T) {
+		g := NewWithT(t)
+
+		repo, err := extgogit.Init(memory.NewStorage(), memfs.New())
+		g.Expect(err).ToNot(HaveOccurred())
+		commitFile(g, repo)
+		objects, err := countObjects(repo.Storer)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(objects.count).To(Equal(int64(3)))
+		g.Expect(objects.bytes).To(BeNumerically(">", 0))
+	})
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"fmt"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	extgogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/fluxcd/pkg/git"
+)
+
+// commitDated commits all the changes of the worktree like the Commit of the
+// Git client, but with the date of the given commit as the author and
+// committer date, for the same changes on the same parent to make the same
+// commit.
+func (sm SourceManager) commitDated(info git.Commit, signer *openpgp.Entity) (string, error) {
+	repo, err := sm.openRepository()
+	if err != nil {
+		return "", err
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return "", fmt.Errorf("failed to load worktree: %w", err)
+	}
+	status, err := wt.Status()
+	if err != nil {
+		return "", fmt.Errorf("failed to get the worktree status: %w", err)
+	}
+
+	var changed bool
+	for file := range status {
+		_, _ = wt.Add(file)
+		changed = true
+	}
+	if !changed {
+		head, err := repo.Head()
+		if err != nil {
+			return "", err
+		}
+		return head.Hash().String(), git.ErrNoStagedFiles
+	}
+
+	signature := &object.Signature{
+		Name:  info.Author.Name,
+		Email: info.Author.Email,
+		When:  info.Author.When,
+	}
+	hash, err := wt.Commit(info.Message, &extgogit.CommitOptions{
+		Author:    signature,
+		Committer: signature,
+		SignKey:   signer,
+	})
+	if err != nil {
+		return "", err
+	}
+	return hash.String(), nil
+}
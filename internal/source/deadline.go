@@ -0,0 +1,78 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DeadlineExceededError is the error of a Git operation with the remote
+// repository cancelled because it outlived the timeout of the source. It
+// wraps both the error of the operation and context.DeadlineExceeded.
+type DeadlineExceededError struct {
+	// Operation is the Git operation which was cancelled, e.g.
+	// GitOperationPush.
+	Operation string
+	// Timeout is the timeout of the source the operation outlived.
+	Timeout time.Duration
+	// Err is the error of the operation.
+	Err error
+}
+
+// Error implements error.
+func (e *DeadlineExceededError) Error() string {
+	return fmt.Sprintf("%s timed out after %s: %s", e.Operation, e.Timeout, e.Err)
+}
+
+// Unwrap returns the error of the operation and context.DeadlineExceeded.
+func (e *DeadlineExceededError) Unwrap() []error {
+	return []error{e.Err, context.DeadlineExceeded}
+}
+
+// IsDeadlineExceeded returns whether the error is a DeadlineExceededError,
+// and the Git operation which was cancelled if it is.
+func IsDeadlineExceeded(err error) (string, bool) {
+	var deadlineErr *DeadlineExceededError
+	if errors.As(err, &deadlineErr) {
+		return deadlineErr.Operation, true
+	}
+	return "", false
+}
+
+// gitOperationContext returns the context of the Git operations with the
+// remote repository, whose deadline is the timeout of the source. It's
+// cancelled as well when the given context is, e.g. on shutdown, for the
+// operations not to outlive the reconciliation.
+func (sm SourceManager) gitOperationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, sm.srcCfg.timeout.Duration)
+}
+
+// deadlineError wraps the error of the Git operation run with the given
+// context in a DeadlineExceededError when the context reached its deadline,
+// and returns it as is otherwise.
+func (sm SourceManager) deadlineError(ctx context.Context, operation string, err error) error {
+	if err == nil || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	if _, ok := IsDeadlineExceeded(err); ok {
+		return err
+	}
+	return &DeadlineExceededError{Operation: operation, Timeout: sm.srcCfg.timeout.Duration, Err: err}
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fluxcd/pkg/git"
+)
+
+func TestSourceManager_deadlineError(t *testing.T) {
+	sm := SourceManager{srcCfg: &gitSrcCfg{timeout: &metav1.Duration{Duration: time.Second}}}
+	opErr := errors.New("failed to push")
+
+	expired, cancel := context.WithDeadline(context.TODO(), time.Now())
+	defer cancel()
+	<-expired.Done()
+	cancelled, cancel := context.WithCancel(context.TODO())
+	cancel()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		err     error
+		wantErr string
+	}{
+		{
+			name: "no error",
+			ctx:  expired,
+		},
+		{
+			name:    "deadline exceeded",
+			ctx:     expired,
+			err:     opErr,
+			wantErr: "push timed out after 1s: failed to push",
+		},
+		{
+			name:    "already wrapped",
+			ctx:     expired,
+			err:     &DeadlineExceededError{Operation: GitOperationCheckout, Timeout: time.Minute, Err: opErr},
+			wantErr: "checkout timed out after 1m0s: failed to push",
+		},
+		{
+			name:    "cancelled",
+			ctx:     cancelled,
+			err:     opErr,
+			wantErr: "failed to push",
+		},
+		{
+			name:    "context not done",
+			ctx:     context.TODO(),
+			err:     opErr,
+			wantErr: "failed to push",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			err := sm.deadlineError(tt.ctx, GitOperationPush, tt.err)
+			if tt.wantErr == "" {
+				g.Expect(err).ToNot(HaveOccurred())
+				return
+			}
+			g.Expect(err).To(MatchError(tt.wantErr))
+			g.Expect(err).To(MatchError(opErr))
+			_, ok := IsDeadlineExceeded(err)
+			g.Expect(ok).To(Equal(errors.Is(tt.ctx.Err(), context.DeadlineExceeded)))
+		})
+	}
+}
+
+func TestSourceManager_gitOperationDeadline(t *testing.T) {
+	g := NewWithT(t)
+
+	// The remote never answers.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	sm := SourceManager{srcCfg: &gitSrcCfg{
+		url:        srv.URL + "/org/repo",
+		authOpts:   &git.AuthOptions{Transport: git.HTTP},
+		pushBranch: "main",
+		timeout:    &metav1.Duration{Duration: 100 * time.Millisecond},
+	}}
+	ctx, cancel := sm.gitOperationContext(context.TODO())
+	defer cancel()
+	_, err := sm.remotePushBranchHead(ctx)
+	operation, ok := IsDeadlineExceeded(err)
+	g.Expect(ok).To(BeTrue(), "unexpected error: %v", err)
+	g.Expect(operation).To(Equal(GitOperationPush))
+	g.Expect(err).To(MatchError(context.DeadlineExceeded))
+	g.Expect(ClassOf(err)).To(Equal(ErrorClassNetwork))
+
+	// The operation stops when the reconciliation is cancelled, without
+	// waiting for the deadline.
+	parent, cancelParent := context.WithCancel(context.TODO())
+	ctx, cancel = sm.gitOperationContext(parent)
+	defer cancel()
+	cancelParent()
+	_, err = sm.remotePushBranchHead(ctx)
+	g.Expect(err).To(HaveOccurred())
+	_, ok = IsDeadlineExceeded(err)
+	g.Expect(ok).To(BeFalse())
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	extgogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// ErrorClass classifies the errors of the Git operations with the remote
+// repository, for them to be retried with a backoff of their own.
+type ErrorClass string
+
+const (
+	// ErrorClassAuth is the class of the errors of authentication or
+	// authorization with the remote, unlikely to be resolved until the
+	// credentials are changed.
+	ErrorClassAuth ErrorClass = "Auth"
+	// ErrorClassNetwork is the class of the errors reaching the remote,
+	// e.g. timeouts or refused connections.
+	ErrorClassNetwork ErrorClass = "Network"
+	// ErrorClassConflict is the class of the pushes rejected because the
+	// remote branch moved since it was checked out, resolved by checking it
+	// out again.
+	ErrorClassConflict ErrorClass = "Conflict"
+)
+
+// Git operations whose errors are classified.
+const (
+	GitOperationCheckout = "checkout"
+	GitOperationPush     = "push"
+)
+
+// GitOperationError is a classified error of a Git operation with the remote
+// repository. Its message is the message of the wrapped error.
+type GitOperationError struct {
+	// Operation is the Git operation which failed, e.g. GitOperationPush.
+	Operation string
+	// Class is the class of the error.
+	Class ErrorClass
+	// Err is the error of the operation.
+	Err error
+}
+
+// Error implements error.
+func (e *GitOperationError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error of the operation.
+func (e *GitOperationError) Unwrap() error {
+	return e.Err
+}
+
+// ClassOf returns the class of the error, or an empty class if it isn't a
+// classified error of a Git operation.
+func ClassOf(err error) ErrorClass {
+	var opErr *GitOperationError
+	if errors.As(err, &opErr) {
+		return opErr.Class
+	}
+	return ""
+}
+
+// classifyGitError wraps the error of the operation in a GitOperationError if
+// it can be classified, and returns it as is otherwise.
+func classifyGitError(operation string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if class := classify(err); class != "" {
+		return &GitOperationError{Operation: operation, Class: class, Err: err}
+	}
+	return err
+}
+
+// errorMessages are the substrings of the lower case error messages of each
+// class, in the order they're matched.
+var errorMessages = []struct {
+	class      ErrorClass
+	substrings []string
+}{
+	{ErrorClassAuth, []string{"unable to authenticate", "permission denied", "authentication required", "authorization failed"}},
+	{ErrorClassConflict, []string{"non-fast-forward", "fetch first", "failed to update ref", "cannot lock ref"}},
+	{ErrorClassNetwork, []string{"connection refused", "connection reset", "no such host", "i/o timeout", "network is unreachable"}},
+}
+
+// classify returns the class of the error, from the errors of go-git when
+// they're wrapped, and from their message otherwise, e.g. for the errors
+// reported by the remote.
+func classify(err error) ErrorClass {
+	// The redirect of a moved repository fails the HTTP request, but isn't
+	// a network error.
+	if _, ok := IsRepositoryMoved(err); ok {
+		return ""
+	}
+	if errors.Is(err, transport.ErrAuthenticationRequired) || errors.Is(err, transport.ErrAuthorizationFailed) {
+		return ErrorClassAuth
+	}
+	if errors.Is(err, extgogit.ErrNonFastForwardUpdate) {
+		return ErrorClassConflict
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) {
+		return ErrorClassNetwork
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, m := range errorMessages {
+		for _, s := range m.substrings {
+			if strings.Contains(msg, s) {
+				return m.class
+			}
+		}
+	}
+	return ""
+}
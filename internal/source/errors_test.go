@@ -0,0 +1,85 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	extgogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	. "github.com/onsi/gomega"
+)
+
+func Test_classifyGitError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantClass ErrorClass
+	}{
+		{
+			name:      "authentication required",
+			err:       fmt.Errorf("unable to clone 'https://example.com/repo': %w", transport.ErrAuthenticationRequired),
+			wantClass: ErrorClassAuth,
+		},
+		{
+			name:      "ssh authentication failure",
+			err:       errors.New("ssh: handshake failed: ssh: unable to authenticate, attempted methods [none publickey]"),
+			wantClass: ErrorClassAuth,
+		},
+		{
+			name:      "non-fast-forward update",
+			err:       fmt.Errorf("failed to push to remote: %w", extgogit.ErrNonFastForwardUpdate),
+			wantClass: ErrorClassConflict,
+		},
+		{
+			name:      "push rejected by the remote",
+			err:       errors.New("failed to push to remote: command error on refs/heads/main: failed to update ref"),
+			wantClass: ErrorClassConflict,
+		},
+		{
+			name:      "dial error",
+			err:       fmt.Errorf("unable to clone: %w", &net.OpError{Op: "dial", Err: errors.New("connection refused")}),
+			wantClass: ErrorClassNetwork,
+		},
+		{
+			name:      "timeout",
+			err:       fmt.Errorf("unable to clone: %w", context.DeadlineExceeded),
+			wantClass: ErrorClassNetwork,
+		},
+		{
+			name: "unclassified error",
+			err:  errors.New("reference not found"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			err := classifyGitError(GitOperationPush, tt.err)
+			g.Expect(err).To(MatchError(tt.err))
+			g.Expect(err.Error()).To(Equal(tt.err.Error()))
+			g.Expect(ClassOf(fmt.Errorf("failed: %w", err))).To(Equal(tt.wantClass))
+		})
+	}
+
+	g := NewWithT(t)
+	g.Expect(classifyGitError(GitOperationPush, nil)).To(BeNil())
+}
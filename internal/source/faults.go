@@ -0,0 +1,79 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+
+	extgogit "github.com/go-git/go-git/v5"
+)
+
+// errInjectedFault is wrapped by the errors of the faults injected by a
+// FaultInjector, for them to be told apart from real failures.
+var errInjectedFault = errors.New("injected fault")
+
+// injectedFaults are the faults a FaultInjector injects in the pushes, with
+// the same classes as the real failures they simulate.
+var injectedFaults = []error{
+	context.DeadlineExceeded,
+	extgogit.ErrNonFastForwardUpdate,
+}
+
+// FaultInjector injects synthetic failures in the pushes of the
+// SourceManagers, for the resilience of the automations and the alerting on
+// their failures to be tested. It only injects faults in the automations of
+// the namespaces it's enabled in.
+type FaultInjector struct {
+	rate       float64
+	namespaces []string
+	// random returns a random number in [0.0,1.0).
+	random func() float64
+}
+
+// NewFaultInjector returns a FaultInjector failing the given rate of the
+// pushes of the automations of the given namespaces. It returns an error if
+// the rate isn't in (0.0,1.0] or if no namespace is given, for faults not to
+// be injected in all the automations by mistake.
+func NewFaultInjector(rate float64, namespaces []string) (*FaultInjector, error) {
+	if rate <= 0 || rate > 1 {
+		return nil, fmt.Errorf("fault injection rate %v must be in (0.0,1.0]", rate)
+	}
+	if len(namespaces) == 0 {
+		return nil, errors.New("fault injection requires the namespaces of the test automations")
+	}
+	return &FaultInjector{rate: rate, namespaces: namespaces, random: rand.Float64}, nil
+}
+
+// Enabled returns if faults are injected in the automations of the given
+// namespace.
+func (fi *FaultInjector) Enabled(namespace string) bool {
+	return fi != nil && slices.Contains(fi.namespaces, namespace)
+}
+
+// fault returns the error of the Git operation to inject, or nil if no fault
+// is injected this time.
+func (fi *FaultInjector) fault(operation string) error {
+	if fi == nil || fi.random() >= fi.rate {
+		return nil
+	}
+	fault := injectedFaults[int(fi.random()*float64(len(injectedFaults)))%len(injectedFaults)]
+	return classifyGitError(operation, fmt.Errorf("%w: %w", errInjectedFault, fault))
+}
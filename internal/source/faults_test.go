@@ -0,0 +1,71 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestNewFaultInjector(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := NewFaultInjector(0, []string{"test"})
+	g.Expect(err).To(HaveOccurred())
+	_, err = NewFaultInjector(1.5, []string{"test"})
+	g.Expect(err).To(HaveOccurred())
+	_, err = NewFaultInjector(0.5, nil)
+	g.Expect(err).To(MatchError(ContainSubstring("namespaces")))
+
+	fi, err := NewFaultInjector(0.5, []string{"test"})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(fi.Enabled("test")).To(BeTrue())
+	g.Expect(fi.Enabled("flux-system")).To(BeFalse())
+
+	var nilInjector *FaultInjector
+	g.Expect(nilInjector.Enabled("test")).To(BeFalse())
+	g.Expect(nilInjector.fault(GitOperationPush)).To(Succeed())
+}
+
+func TestFaultInjector_fault(t *testing.T) {
+	g := NewWithT(t)
+
+	fi, err := NewFaultInjector(0.5, []string{"test"})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// Draw below the rate, then the fault.
+	draws := []float64{0.6, 0.4, 0.1, 0.4, 0.9}
+	fi.random = func() float64 {
+		d := draws[0]
+		draws = draws[1:]
+		return d
+	}
+
+	g.Expect(fi.fault(GitOperationPush)).To(Succeed())
+
+	err = fi.fault(GitOperationPush)
+	g.Expect(err).To(MatchError(errInjectedFault))
+	g.Expect(ClassOf(err)).To(Equal(ErrorClassNetwork))
+
+	err = fi.fault(GitOperationPush)
+	g.Expect(err).To(MatchError(errInjectedFault))
+	g.Expect(ClassOf(err)).To(Equal(ErrorClassConflict))
+
+	g.Expect(errors.Is(errors.New("push failed"), errInjectedFault)).To(BeFalse())
+}
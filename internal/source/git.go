@@ -22,18 +22,28 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/go-git/go-git/v5/plumbing/transport"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/net/http/httpproxy"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 
+	"github.com/fluxcd/pkg/apis/meta"
 	"github.com/fluxcd/pkg/auth/azure"
 	"github.com/fluxcd/pkg/git"
 	"github.com/fluxcd/pkg/git/gogit"
+	"github.com/fluxcd/pkg/runtime/conditions"
 	sourcev1 "github.com/fluxcd/source-controller/api/v1"
 
 	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
@@ -42,6 +52,9 @@ import (
 const (
 	signingSecretKey     = "git.asc"
 	signingPassphraseKey = "passphrase"
+	// sshCertificateKey is the key of the OpenSSH user certificate of the
+	// SSH identity in the Secret of a GitRepository.
+	sshCertificateKey = "identity-cert.pub"
 )
 
 // gitSrcCfg contains all the Git configurations related to a source derived
@@ -54,8 +67,68 @@ type gitSrcCfg struct {
 	timeout       *metav1.Duration
 	checkoutRef   *sourcev1.GitRepositoryRef
 	authOpts      *git.AuthOptions
+	proxyOpts     *transport.ProxyOptions
 	clientOpts    []gogit.ClientOption
 	signingEntity *openpgp.Entity
+	// requireSignedCommits refuses to push the commits without
+	// signingEntity.
+	requireSignedCommits bool
+	// signingKeys are the signing keys selected by the push branch, the
+	// signingEntity being the one of the current push branch.
+	signingKeys []branchSigningKey
+	// verification and verificationKeyRings are set when the signatures of
+	// the checked out commit must be verified, like by the source-controller.
+	verification         *sourcev1.GitRepositoryVerification
+	verificationKeyRings []string
+	// templateValues are the values given to the templates, merged from the
+	// valuesFrom references and the messageTemplateValues of the commit.
+	templateValues map[string]string
+	// perPolicyBranches is set when the changes of each policy are pushed
+	// to a branch of their own, whose names are rendered from
+	// policyBranchTemplate. pushBranch is the template until a branch is
+	// checked out.
+	perPolicyBranches    bool
+	policyBranchTemplate string
+	// routes is set when the changes are pushed by the routes of the
+	// automation, to the branch of each route. pushBranch is empty until a
+	// branch is checked out.
+	routes bool
+	// authMethod describes the authentication method of the Git
+	// operations, without any secret.
+	authMethod string
+	// additionalRemotes are the remotes the pushed commits are also pushed
+	// to.
+	additionalRemotes []additionalRemote
+	// srcSuspended is set when the GitRepository is suspended, and
+	// srcNotReady is its Ready condition when it's False.
+	srcSuspended bool
+	srcNotReady  *metav1.Condition
+	// singleBranch is set when only the checkout branch is fetched, the
+	// push branch being overwritten when it's different.
+	singleBranch bool
+	// baseBranch is the branch the changes are merged into, when it's not
+	// the checkout branch, the commit being made on top of it.
+	baseBranch string
+	// refspecOnly is set when the commits are only pushed with the refspec,
+	// without a push branch, pushBranch being empty.
+	refspecOnly bool
+	// writeTarget is the GitRepository the commits are pushed to, when it's
+	// not the checked out one.
+	writeTarget *writeTarget
+	// headCheck is the check of the head of the push branch before pushing.
+	headCheck imagev1.HeadCheckPolicy
+	// fallbackAuthOpts are the credentials the Git operations with the
+	// repository the commits are pushed to are retried with when the remote
+	// rejects its own credentials, fallbackUsed being set once they're used.
+	fallbackAuthOpts *git.AuthOptions
+	fallbackUsed     bool
+	// credentialsAuthor is the author of the commits when the spec has no
+	// author email, read from the Secret of the GitRepository the commits
+	// are pushed to.
+	credentialsAuthor imagev1.CommitUser
+	// apiCommitter makes the commits with the API of the Git provider
+	// instead of pushing them, when set.
+	apiCommitter apiCommitter
 }
 
 func buildGitConfig(ctx context.Context, c client.Client, originKey, srcKey types.NamespacedName, gitSpec *imagev1.GitSpec, opts SourceOptions) (*gitSrcCfg, error) {
@@ -71,6 +144,10 @@ func buildGitConfig(ctx context.Context, c client.Client, originKey, srcKey type
 		}
 	}
 	cfg.url = repo.Spec.URL
+	cfg.srcSuspended = repo.Spec.Suspend
+	if conditions.IsFalse(repo, meta.ReadyCondition) {
+		cfg.srcNotReady = conditions.Get(repo, meta.ReadyCondition)
+	}
 
 	// Configure Git operation timeout from the GitRepository configuration.
 	if repo.Spec.Timeout != nil {
@@ -83,7 +160,7 @@ func buildGitConfig(ctx context.Context, c client.Client, originKey, srcKey type
 	// object gitSpec checkout reference and falling back to the GitRepository
 	// reference if not provided.
 	// var checkoutRef *sourcev1.GitRepositoryRef
-	if gitSpec.Checkout != nil {
+	if gitSpec.Checkout.HasReference() {
 		cfg.checkoutRef = &gitSpec.Checkout.Reference
 	} else if repo.Spec.Reference != nil {
 		cfg.checkoutRef = repo.Spec.Reference
@@ -91,16 +168,54 @@ func buildGitConfig(ctx context.Context, c client.Client, originKey, srcKey type
 
 	// Configure push first as the client options below depend on the push
 	// configuration.
-	if err := configurePush(cfg, gitSpec, cfg.checkoutRef); err != nil {
+	var err error
+	if len(opts.routes) > 0 {
+		err = configureRoutes(cfg, gitSpec, opts.routes, cfg.checkoutRef)
+	} else {
+		err = configurePush(cfg, gitSpec, cfg.checkoutRef)
+	}
+	if err != nil {
 		return nil, err
 	}
 
-	var err error
+	if cfg.writeTarget, err = getWriteTarget(ctx, c, originKey.Namespace, gitSpec.Push, opts.noCrossNamespaceRef); err != nil {
+		return nil, err
+	}
+	if gitSpec.Push != nil {
+		cfg.headCheck = gitSpec.Push.HeadCheck
+		if gitSpec.Push.API != nil {
+			if cfg.apiCommitter, err = getAPICommitter(ctx, c, originKey.Namespace, gitSpec.Push.API); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// Without an author email, the commits are authored by the identity of
+	// the credentials they're pushed with.
+	if gitSpec.Commit.Author.Email == "" {
+		pushKey, pushSecretRef := srcKey, repo.Spec.SecretRef
+		if cfg.writeTarget != nil {
+			pushKey, pushSecretRef = cfg.writeTarget.key, cfg.writeTarget.secretRef
+		}
+		if cfg.credentialsAuthor, err = getCredentialsAuthor(ctx, c, pushKey, pushSecretRef); err != nil {
+			return nil, err
+		}
+		if cfg.credentialsAuthor.Email == "" {
+			return nil, fmt.Errorf("no commit author email: set .spec.git.commit.author.email, or the email key of the Secret of the GitRepository '%s': %w",
+				pushKey, ErrInvalidSourceConfiguration)
+		}
+	}
+
 	cfg.authOpts, err = getAuthOpts(ctx, c, repo)
 	if err != nil {
 		return nil, err
 	}
-	proxyOpts, err := getProxyOpts(ctx, c, repo)
+	cfg.authMethod = describeAuthMethod(cfg.authOpts)
+	if gitSpec.Push != nil && gitSpec.Push.FallbackSecretRef != nil {
+		if cfg.fallbackAuthOpts, err = getFallbackAuthOpts(ctx, c, originKey.Namespace, gitSpec.Push.FallbackSecretRef.Name, cfg); err != nil {
+			return nil, err
+		}
+	}
+	proxyOpts, err := getProxyOpts(ctx, c, repo, cfg.authOpts.Transport)
 	if err != nil {
 		return nil, err
 	}
@@ -109,6 +224,7 @@ func buildGitConfig(ctx context.Context, c client.Client, originKey, srcKey type
 		cfg.clientOpts = append(cfg.clientOpts, gogit.WithInsecureCredentialsOverHTTP())
 	}
 	if proxyOpts != nil {
+		cfg.proxyOpts = proxyOpts
 		cfg.clientOpts = append(cfg.clientOpts, gogit.WithProxy(*proxyOpts))
 	}
 	// If the push branch is different from the checkout ref, we need to
@@ -117,13 +233,46 @@ func buildGitConfig(ctx context.Context, c client.Client, originKey, srcKey type
 	//
 	// To always overwrite the push branch, the feature gate
 	// GitAllBranchReferences can be set to false, which will cause
-	// the SwitchBranch operation to ignore the remote branch state.
+	// the SwitchBranch operation to ignore the remote branch state. The
+	// state of the push branch in the checked out repository is always
+	// ignored when the commits are pushed to another repository.
 	if cfg.switchBranch {
-		cfg.clientOpts = append(cfg.clientOpts, gogit.WithSingleBranch(!opts.gitAllBranchReferences))
+		cfg.singleBranch = !opts.gitAllBranchReferences || cfg.writeTarget != nil
+		cfg.clientOpts = append(cfg.clientOpts, gogit.WithSingleBranch(cfg.singleBranch))
+	}
+
+	cfg.requireSignedCommits = opts.requireSignedCommits
+	if cfg.requireSignedCommits && gitSpec.Commit.SigningKey == nil {
+		return nil, fmt.Errorf("commits must be signed, but .spec.git.commit.signingKey isn't set: %w", ErrSigningRequired)
+	}
+	if signingKey := gitSpec.Commit.SigningKey; signingKey != nil {
+		if len(signingKey.SecretRefs) == 0 {
+			if cfg.signingEntity, err = getSigningEntity(ctx, c, originKey.Namespace, signingKey.SecretRef.Name); err != nil {
+				return nil, &SigningError{Err: err}
+			}
+		} else {
+			if cfg.signingKeys, err = getBranchSigningKeys(ctx, c, originKey.Namespace, signingKey.SecretRefs); err != nil {
+				return nil, &SigningError{Err: err}
+			}
+			// The per-policy and route branches are only known once
+			// checked out.
+			if !cfg.perPolicyBranches && !cfg.routes {
+				if cfg.signingEntity, err = selectSigningKey(cfg.signingKeys, cfg.pushBranch); err != nil {
+					return nil, &SigningError{Err: err}
+				}
+			}
+		}
 	}
 
-	if gitSpec.Commit.SigningKey != nil {
-		if cfg.signingEntity, err = getSigningEntity(ctx, c, originKey.Namespace, gitSpec); err != nil {
+	if cfg.templateValues, err = getTemplateValues(ctx, c, originKey.Namespace, gitSpec.Commit); err != nil {
+		return nil, err
+	}
+
+	cfg.additionalRemotes = getAdditionalRemotes(ctx, c, originKey.Namespace, gitSpec.Push)
+
+	if repo.Spec.Verification != nil {
+		cfg.verification = repo.Spec.Verification
+		if cfg.verificationKeyRings, err = getVerificationKeyRings(ctx, c, repo); err != nil {
 			return nil, err
 		}
 	}
@@ -132,6 +281,63 @@ func buildGitConfig(ctx context.Context, c client.Client, originKey, srcKey type
 }
 
 func configurePush(cfg *gitSrcCfg, gitSpec *imagev1.GitSpec, checkoutRef *sourcev1.GitRepositoryRef) error {
+	// The per-policy branches are always different from the checkout branch,
+	// and are created from it.
+	if gitSpec.HasPerPolicyBranches() {
+		if gitSpec.HasBase() {
+			return fmt.Errorf("per-policy branches can't be pushed onto a base branch: %w", ErrInvalidSourceConfiguration)
+		}
+		cfg.perPolicyBranches = true
+		cfg.switchBranch = true
+		cfg.policyBranchTemplate = gitSpec.Push.Branch
+		if cfg.policyBranchTemplate == "" {
+			cfg.policyBranchTemplate = defaultPolicyBranchTemplate
+		}
+		cfg.pushBranch = cfg.policyBranchTemplate
+		return nil
+	}
+
+	// The changes are merged into the base branch, and pushed to the push
+	// branch or to the base branch itself, which both differ from the
+	// checkout branch.
+	if gitSpec.HasBase() {
+		cfg.baseBranch = gitSpec.Push.Base
+		cfg.pushBranch = gitSpec.Push.Branch
+		if cfg.pushBranch == "" {
+			cfg.pushBranch = cfg.baseBranch
+		}
+		checkoutBranch := git.DefaultBranch
+		if checkoutRef != nil {
+			checkoutBranch = checkoutRef.Branch
+		}
+		if cfg.baseBranch == checkoutBranch || cfg.pushBranch == checkoutBranch {
+			return fmt.Errorf("base and push branches must differ from the checkout branch '%s': %w", checkoutBranch, ErrInvalidSourceConfiguration)
+		}
+		cfg.switchBranch = true
+		return nil
+	}
+
+	// A refspec without a push branch is the only push, e.g. to a Gerrit
+	// server, and the commit is made on top of the checkout as is.
+	if gitSpec.HasRefspec() && gitSpec.Push.Branch == "" {
+		cfg.refspecOnly = true
+		return nil
+	}
+
+	// A tag or a commit isn't a branch to push to, the changes are always
+	// pushed to a new branch starting from it.
+	if isPinnedRef(checkoutRef) {
+		if gitSpec.Push == nil || gitSpec.Push.Branch == "" {
+			return fmt.Errorf("push branch or refspec must be set in .spec.git.push to check out a tag or a commit: %w", ErrInvalidSourceConfiguration)
+		}
+		if gitSpec.Push.Branch == checkoutRef.Branch {
+			return fmt.Errorf("push branch '%s' must differ from the checkout branch to check out a commit: %w", gitSpec.Push.Branch, ErrInvalidSourceConfiguration)
+		}
+		cfg.pushBranch = gitSpec.Push.Branch
+		cfg.switchBranch = true
+		return nil
+	}
+
 	if gitSpec.Push != nil && gitSpec.Push.Branch != "" {
 		cfg.pushBranch = gitSpec.Push.Branch
 
@@ -161,6 +367,12 @@ func configurePush(cfg *gitSrcCfg, gitSpec *imagev1.GitSpec, checkoutRef *source
 	return nil
 }
 
+// isPinnedRef returns whether the checkout reference is a tag, a semver range
+// or a commit rather than the tip of a branch.
+func isPinnedRef(ref *sourcev1.GitRepositoryRef) bool {
+	return ref != nil && (ref.Tag != "" || ref.SemVer != "" || ref.Commit != "")
+}
+
 func getAuthOpts(ctx context.Context, c client.Client, repo *sourcev1.GitRepository) (*git.AuthOptions, error) {
 	var data map[string][]byte
 	var err error
@@ -181,6 +393,13 @@ func getAuthOpts(ctx context.Context, c client.Client, repo *sourcev1.GitReposit
 		return nil, fmt.Errorf("failed to configure authentication options: %w", err)
 	}
 
+	if repo.Spec.SecretRef != nil {
+		secretKey := types.NamespacedName{Namespace: repo.GetNamespace(), Name: repo.Spec.SecretRef.Name}
+		if err := certifySSHIdentity(opts, data, secretKey, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
 	if repo.GetProvider() == sourcev1.GitProviderAzure {
 		opts.ProviderOpts = &git.ProviderOptions{
 			Name: sourcev1.GitProviderAzure,
@@ -193,9 +412,185 @@ func getAuthOpts(ctx context.Context, c client.Client, repo *sourcev1.GitReposit
 	return opts, nil
 }
 
-func getProxyOpts(ctx context.Context, c client.Client, repo *sourcev1.GitRepository) (*transport.ProxyOptions, error) {
+// getFallbackAuthOpts returns the fallback credentials of the push from the
+// Secret with the given name, for the repository the commits are pushed to.
+// The CA and known hosts of its own credentials are kept when the Secret has
+// none.
+func getFallbackAuthOpts(ctx context.Context, c client.Client, namespace, name string, cfg *gitSrcCfg) (*git.AuthOptions, error) {
+	data, err := getSecretData(ctx, c, name, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fallback secret '%s/%s': %w", namespace, name, err)
+	}
+	pushURL, primary, _ := cfg.pushEndpoint()
+	u, err := url.Parse(pushURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse URL '%s': %w", pushURL, err)
+	}
+	opts, err := git.NewAuthOptions(*u, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure fallback authentication options: %w", err)
+	}
+	if err := certifySSHIdentity(opts, data, types.NamespacedName{Namespace: namespace, Name: name}, time.Now()); err != nil {
+		return nil, err
+	}
+	if len(opts.CAFile) == 0 {
+		opts.CAFile = primary.CAFile
+	}
+	if len(opts.KnownHosts) == 0 {
+		opts.KnownHosts = primary.KnownHosts
+	}
+	return opts, nil
+}
+
+// getCredentialsAuthor returns the author of the commits from the `username`
+// and `email` keys of the given Secret of the GitRepository with the given
+// key. The author is empty when the GitRepository has no Secret, or its Secret
+// has no email.
+func getCredentialsAuthor(ctx context.Context, c client.Client, repoKey types.NamespacedName,
+	secretRef *meta.LocalObjectReference) (imagev1.CommitUser, error) {
+	if secretRef == nil {
+		return imagev1.CommitUser{}, nil
+	}
+	data, err := getSecretData(ctx, c, secretRef.Name, repoKey.Namespace)
+	if err != nil {
+		return imagev1.CommitUser{}, fmt.Errorf("failed to get auth secret '%s/%s': %w", repoKey.Namespace, secretRef.Name, err)
+	}
+	author := imagev1.CommitUser{
+		Name:  strings.TrimSpace(string(data["username"])),
+		Email: strings.TrimSpace(string(data["email"])),
+	}
+	if author.Email == "" {
+		return imagev1.CommitUser{}, nil
+	}
+	if strings.ContainsAny(author.Name+author.Email, "<>\n") {
+		return imagev1.CommitUser{}, fmt.Errorf("invalid author in the Secret '%s/%s': must not contain angle brackets or line breaks: %w",
+			repoKey.Namespace, secretRef.Name, ErrInvalidSourceConfiguration)
+	}
+	return author, nil
+}
+
+const (
+	// AuthMethodNone is the authentication method of the Git operations
+	// without credentials.
+	AuthMethodNone = "none"
+	// AuthMethodBasicAuth is the authentication method of the Git
+	// operations with a username and password.
+	AuthMethodBasicAuth = "basic-auth"
+	// AuthMethodBearerToken is the authentication method of the Git
+	// operations with a bearer token.
+	AuthMethodBearerToken = "bearer-token"
+	// AuthMethodSSHKey is the authentication method of the Git operations
+	// with an SSH private key.
+	AuthMethodSSHKey = "ssh-key"
+	// AuthMethodProvider is the prefix of the authentication methods of
+	// the Git operations with the identity of a cloud provider.
+	AuthMethodProvider = "provider"
+)
+
+// describeAuthMethod returns the authentication method of the given options,
+// with details identifying the credentials without disclosing them: the
+// username of the basic authentication, the SHA256 fingerprint of the SSH
+// key, or the name of the provider, e.g. "ssh-key: SHA256:...".
+func describeAuthMethod(opts *git.AuthOptions) string {
+	switch {
+	case opts == nil:
+		return AuthMethodNone
+	case opts.ProviderOpts != nil:
+		return AuthMethodProvider + ": " + opts.ProviderOpts.Name
+	case opts.Transport == git.SSH && len(opts.Identity) > 0:
+		var signer ssh.Signer
+		var err error
+		if opts.Password != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(opts.Identity, []byte(opts.Password))
+		} else {
+			signer, err = ssh.ParsePrivateKey(opts.Identity)
+		}
+		if err != nil {
+			return AuthMethodSSHKey
+		}
+		return AuthMethodSSHKey + ": " + ssh.FingerprintSHA256(signer.PublicKey())
+	case opts.BearerToken != "":
+		return AuthMethodBearerToken
+	case opts.Username != "" || opts.Password != "":
+		return AuthMethodBasicAuth + ": " + opts.Username
+	default:
+		return AuthMethodNone
+	}
+}
+
+// checkSSHCertificate checks that the given OpenSSH user certificate
+// certifies the SSH identity of the options, for their username, at the given
+// time, and returns the certified identity.
+func checkSSHCertificate(opts *git.AuthOptions, certData []byte, now time.Time) (*sshCertificate, error) {
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(certData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse '%s': %w", sshCertificateKey, err)
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("'%s' is a public key of type '%s', not a certificate", sshCertificateKey, pub.Type())
+	}
+	if cert.CertType != ssh.UserCert {
+		return nil, fmt.Errorf("certificate '%s' is not a user certificate", cert.KeyId)
+	}
+
+	var signer ssh.Signer
+	if opts.Password != "" {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(opts.Identity, []byte(opts.Password))
+	} else {
+		signer, err = ssh.ParsePrivateKey(opts.Identity)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the identity certified by '%s': %w", cert.KeyId, err)
+	}
+	if !bytes.Equal(cert.Key.Marshal(), signer.PublicKey().Marshal()) {
+		return nil, fmt.Errorf("certificate '%s' certifies the key %s, not the identity %s", cert.KeyId,
+			ssh.FingerprintSHA256(cert.Key), ssh.FingerprintSHA256(signer.PublicKey()))
+	}
+
+	if len(cert.ValidPrincipals) > 0 && !slices.Contains(cert.ValidPrincipals, opts.Username) {
+		return nil, fmt.Errorf("certificate '%s' is not valid for the user '%s', only for %v",
+			cert.KeyId, opts.Username, cert.ValidPrincipals)
+	}
+	if err := checkSSHCertificateValidity(cert, now); err != nil {
+		return nil, err
+	}
+
+	certSigner, err := ssh.NewCertSigner(cert, signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign with certificate '%s': %w", cert.KeyId, err)
+	}
+	return &sshCertificate{user: opts.Username, cert: cert, signer: certSigner}, nil
+}
+
+// checkSSHCertificateValidity checks that the given certificate is valid at
+// the given time. Short-lived certificates expire often, so the error of an
+// expired certificate tells how to renew it.
+func checkSSHCertificateValidity(cert *ssh.Certificate, now time.Time) error {
+	unix := uint64(now.Unix())
+	if unix < cert.ValidAfter {
+		return fmt.Errorf("certificate '%s' is not valid before %s", cert.KeyId,
+			time.Unix(int64(cert.ValidAfter), 0).UTC().Format(time.RFC3339))
+	}
+	if cert.ValidBefore != ssh.CertTimeInfinity && unix >= cert.ValidBefore {
+		return fmt.Errorf("certificate '%s' expired at %s, sign the identity with the SSH CA again "+
+			"and update '%s' with the renewed certificate", cert.KeyId,
+			time.Unix(int64(cert.ValidBefore), 0).UTC().Format(time.RFC3339), sshCertificateKey)
+	}
+	return nil
+}
+
+// getProxyOpts returns the proxy configured with the proxy Secret of the
+// given GitRepository, if any. The SSH transport only supports SOCKS5
+// proxies, and uses the SOCKS5 proxy of the ALL_PROXY environment variable of
+// the controller when the GitRepository has no proxy Secret, like the HTTP
+// transport uses the proxy of HTTPS_PROXY.
+func getProxyOpts(ctx context.Context, c client.Client, repo *sourcev1.GitRepository, transportType git.TransportType) (*transport.ProxyOptions, error) {
 	if repo.Spec.ProxySecretRef == nil {
-		return nil, nil
+		if transportType != git.SSH {
+			return nil, nil
+		}
+		return sshProxyFromEnvironment(ctx, repo.Spec.URL)
 	}
 	name := repo.Spec.ProxySecretRef.Name
 	namespace := repo.GetNamespace()
@@ -213,11 +608,69 @@ func getProxyOpts(ctx context.Context, c client.Client, repo *sourcev1.GitReposi
 		Username: string(proxyData["username"]),
 		Password: string(proxyData["password"]),
 	}
+	if transportType == git.SSH {
+		if err := checkSOCKS5Proxy(proxyOpts.URL); err != nil {
+			return nil, fmt.Errorf("invalid proxy secret '%s/%s': %w", namespace, name, err)
+		}
+	}
 	return proxyOpts, nil
 }
 
-func getSigningEntity(ctx context.Context, c client.Client, namespace string, gitSpec *imagev1.GitSpec) (*openpgp.Entity, error) {
-	secretName := gitSpec.Commit.SigningKey.SecretRef.Name
+// sshProxyFromEnvironment returns the SOCKS5 proxy of the ALL_PROXY (or
+// all_proxy) environment variable for the given SSH repository URL, unless
+// its host is excluded by NO_PROXY (or no_proxy). Any other kind of proxy is
+// meant for the other protocols, and is skipped.
+func sshProxyFromEnvironment(ctx context.Context, repoURL string) (*transport.ProxyOptions, error) {
+	proxyConfig := httpproxy.Config{
+		HTTPSProxy: getEnvAny("ALL_PROXY", "all_proxy"),
+		NoProxy:    getEnvAny("NO_PROXY", "no_proxy"),
+	}
+	if proxyConfig.HTTPSProxy == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse URL '%s': %w", repoURL, err)
+	}
+	// The NO_PROXY rules are matched like for an HTTPS request to the host.
+	proxyURL, err := proxyConfig.ProxyFunc()(&url.URL{Scheme: "https", Host: u.Host})
+	if err != nil || proxyURL == nil {
+		return nil, err
+	}
+	if err := checkSOCKS5Proxy(proxyURL.String()); err != nil {
+		log.FromContext(ctx).Info("skipped the ALL_PROXY proxy, the SSH transport only supports SOCKS5 proxies",
+			"proxy", proxyURL.Redacted())
+		return nil, nil
+	}
+	return &transport.ProxyOptions{URL: proxyURL.String()}, nil
+}
+
+// checkSOCKS5Proxy returns an error if the given proxy address isn't the
+// address of a SOCKS5 proxy, the only kind of proxy the SSH transport can go
+// through.
+func checkSOCKS5Proxy(address string) error {
+	u, err := url.Parse(address)
+	if err != nil {
+		return fmt.Errorf("failed to parse proxy address: %w", err)
+	}
+	if u.Scheme != "socks5" && u.Scheme != "socks5h" {
+		return fmt.Errorf("proxy scheme '%s' isn't supported with the SSH transport, only socks5 is: %w", u.Scheme, ErrInvalidSourceConfiguration)
+	}
+	return nil
+}
+
+// getEnvAny returns the value of the first of the given environment
+// variables which is set.
+func getEnvAny(names ...string) string {
+	for _, n := range names {
+		if val := os.Getenv(n); val != "" {
+			return val
+		}
+	}
+	return ""
+}
+
+func getSigningEntity(ctx context.Context, c client.Client, namespace, secretName string) (*openpgp.Entity, error) {
 	secretData, err := getSecretData(ctx, c, secretName, namespace)
 	if err != nil {
 		return nil, fmt.Errorf("could not find signing key secret '%s': %w", secretName, err)
@@ -251,6 +704,121 @@ func getSigningEntity(ctx context.Context, c client.Client, namespace string, gi
 	return entity, nil
 }
 
+// branchSigningKey is a signing key used to sign the commits pushed to the
+// branches matching its patterns.
+type branchSigningKey struct {
+	secretName string
+	branches   []string
+	entity     *openpgp.Entity
+}
+
+// getBranchSigningKeys returns the signing keys of the given references.
+func getBranchSigningKeys(ctx context.Context, c client.Client, namespace string, refs []imagev1.BranchSigningKeyRef) ([]branchSigningKey, error) {
+	keys := make([]branchSigningKey, 0, len(refs))
+	for _, ref := range refs {
+		for _, pattern := range ref.Branches {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid branch pattern '%s' of signing key secret '%s': %w: %w",
+					pattern, ref.Name, err, ErrInvalidSourceConfiguration)
+			}
+		}
+		entity, err := getSigningEntity(ctx, c, namespace, ref.Name)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, branchSigningKey{secretName: ref.Name, branches: ref.Branches, entity: entity})
+	}
+	return keys, nil
+}
+
+// selectSigningKey returns the entity of the only signing key matching the
+// given push branch. It's an error for none or several of them to match.
+func selectSigningKey(keys []branchSigningKey, branch string) (*openpgp.Entity, error) {
+	var matching []branchSigningKey
+	for _, key := range keys {
+		if slices.ContainsFunc(key.branches, func(pattern string) bool {
+			ok, _ := path.Match(pattern, branch)
+			return ok
+		}) {
+			matching = append(matching, key)
+		}
+	}
+	switch len(matching) {
+	case 1:
+		return matching[0].entity, nil
+	case 0:
+		return nil, fmt.Errorf("no signing key matches the push branch '%s': %w", branch, ErrInvalidSourceConfiguration)
+	default:
+		names := make([]string, 0, len(matching))
+		for _, key := range matching {
+			names = append(names, key.secretName)
+		}
+		return nil, fmt.Errorf("signing key secrets '%s' all match the push branch '%s': %w",
+			strings.Join(names, "', '"), branch, ErrInvalidSourceConfiguration)
+	}
+}
+
+// getVerificationKeyRings returns the public key rings of the trusted Git
+// authors of the GitRepository, from the values of its verification Secret.
+func getVerificationKeyRings(ctx context.Context, c client.Client, repo *sourcev1.GitRepository) ([]string, error) {
+	secretName := repo.Spec.Verification.SecretRef.Name
+	secretData, err := getSecretData(ctx, c, secretName, repo.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("could not find verification secret '%s': %w", secretName, err)
+	}
+	keyRings := make([]string, 0, len(secretData))
+	for _, v := range secretData {
+		keyRings = append(keyRings, string(v))
+	}
+	if len(keyRings) == 0 {
+		return nil, fmt.Errorf("verification secret '%s' does not contain any public keys", secretName)
+	}
+	return keyRings, nil
+}
+
+// getTemplateValues returns the data of the valuesFrom references of the
+// commit spec merged in order, overridden by its messageTemplateValues.
+func getTemplateValues(ctx context.Context, c client.Client, namespace string, commit imagev1.CommitSpec) (map[string]string, error) {
+	if len(commit.ValuesFrom) == 0 {
+		return commit.MessageTemplateValues, nil
+	}
+
+	values := map[string]string{}
+	for _, ref := range commit.ValuesFrom {
+		key := types.NamespacedName{Namespace: namespace, Name: ref.Name}
+		switch ref.Kind {
+		case "ConfigMap":
+			var cm corev1.ConfigMap
+			if err := c.Get(ctx, key, &cm); err != nil {
+				if apierrors.IsNotFound(err) && ref.Optional {
+					continue
+				}
+				return nil, fmt.Errorf("failed to get values ConfigMap '%s': %w", key, err)
+			}
+			for k, v := range cm.Data {
+				values[k] = v
+			}
+		case "Secret":
+			data, err := getSecretData(ctx, c, ref.Name, namespace)
+			if err != nil {
+				if apierrors.IsNotFound(err) && ref.Optional {
+					continue
+				}
+				return nil, fmt.Errorf("failed to get values Secret '%s': %w", key, err)
+			}
+			for k, v := range data {
+				values[k] = string(v)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported values reference kind '%s': %w", ref.Kind, ErrInvalidSourceConfiguration)
+		}
+	}
+	for k, v := range commit.MessageTemplateValues {
+		values[k] = v
+	}
+	return values, nil
+}
+
 func getSecretData(ctx context.Context, c client.Client, name, namespace string) (map[string][]byte, error) {
 	key := types.NamespacedName{
 		Namespace: namespace,
@@ -18,12 +18,18 @@ package source
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"slices"
 	"testing"
 	"time"
 
 	"github.com/go-git/go-git/v5/plumbing/transport"
 	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/ssh"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -189,6 +195,176 @@ func Test_getAuthOpts_providerAuth(t *testing.T) {
 	}
 }
 
+func Test_describeAuthMethod(t *testing.T) {
+	g := NewWithT(t)
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	g.Expect(err).ToNot(HaveOccurred())
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	g.Expect(err).ToNot(HaveOccurred())
+	encryptedBlock, err := ssh.MarshalPrivateKeyWithPassphrase(priv, "", []byte("s3cr3t"))
+	g.Expect(err).ToNot(HaveOccurred())
+	sshPub, err := ssh.NewPublicKey(pub)
+	g.Expect(err).ToNot(HaveOccurred())
+	fingerprint := ssh.FingerprintSHA256(sshPub)
+
+	tests := []struct {
+		name string
+		opts *git.AuthOptions
+		want string
+	}{
+		{
+			name: "no options",
+			want: "none",
+		},
+		{
+			name: "no credentials",
+			opts: &git.AuthOptions{Transport: git.HTTPS},
+			want: "none",
+		},
+		{
+			name: "basic auth",
+			opts: &git.AuthOptions{Transport: git.HTTPS, Username: "user", Password: "pass"},
+			want: "basic-auth: user",
+		},
+		{
+			name: "bearer token",
+			opts: &git.AuthOptions{Transport: git.HTTPS, BearerToken: "token"},
+			want: "bearer-token",
+		},
+		{
+			name: "ssh key",
+			opts: &git.AuthOptions{Transport: git.SSH, Identity: pem.EncodeToMemory(block)},
+			want: "ssh-key: " + fingerprint,
+		},
+		{
+			name: "ssh key with passphrase",
+			opts: &git.AuthOptions{Transport: git.SSH, Identity: pem.EncodeToMemory(encryptedBlock), Password: "s3cr3t"},
+			want: "ssh-key: " + fingerprint,
+		},
+		{
+			name: "invalid ssh key",
+			opts: &git.AuthOptions{Transport: git.SSH, Identity: []byte("invalid")},
+			want: "ssh-key",
+		},
+		{
+			name: "provider",
+			opts: &git.AuthOptions{Transport: git.HTTPS, ProviderOpts: &git.ProviderOptions{Name: git.ProviderAzure}},
+			want: "provider: azure",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			got := describeAuthMethod(tt.opts)
+			g.Expect(got).To(Equal(tt.want))
+			if tt.opts != nil && tt.opts.Password != "" {
+				g.Expect(got).ToNot(ContainSubstring(tt.opts.Password))
+			}
+		})
+	}
+}
+
+func Test_checkSSHCertificate(t *testing.T) {
+	g := NewWithT(t)
+
+	_, caKey, err := ed25519.GenerateKey(rand.Reader)
+	g.Expect(err).ToNot(HaveOccurred())
+	caSigner, err := ssh.NewSignerFromKey(caKey)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	newIdentity := func() (ssh.PublicKey, []byte) {
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		g.Expect(err).ToNot(HaveOccurred())
+		block, err := ssh.MarshalPrivateKey(priv, "")
+		g.Expect(err).ToNot(HaveOccurred())
+		sshPub, err := ssh.NewPublicKey(pub)
+		g.Expect(err).ToNot(HaveOccurred())
+		return sshPub, pem.EncodeToMemory(block)
+	}
+	pub, identity := newIdentity()
+	otherPub, _ := newIdentity()
+
+	now := time.Date(2024, 6, 5, 9, 0, 0, 0, time.UTC)
+	newCert := func(key ssh.PublicKey, certType uint32, principals []string, validAfter, validBefore time.Time) []byte {
+		cert := &ssh.Certificate{
+			Key:             key,
+			KeyId:           "flux",
+			CertType:        certType,
+			ValidPrincipals: principals,
+			ValidAfter:      uint64(validAfter.Unix()),
+			ValidBefore:     uint64(validBefore.Unix()),
+		}
+		g.Expect(cert.SignCert(rand.Reader, caSigner)).To(Succeed())
+		return ssh.MarshalAuthorizedKey(cert)
+	}
+
+	tests := []struct {
+		name    string
+		cert    []byte
+		wantErr string
+	}{
+		{
+			name: "valid certificate",
+			cert: newCert(pub, ssh.UserCert, []string{"git"}, now.Add(-time.Hour), now.Add(time.Hour)),
+		},
+		{
+			name: "certificate without principals",
+			cert: newCert(pub, ssh.UserCert, nil, now.Add(-time.Hour), now.Add(time.Hour)),
+		},
+		{
+			name:    "not a certificate",
+			cert:    ssh.MarshalAuthorizedKey(pub),
+			wantErr: "not a certificate",
+		},
+		{
+			name:    "invalid certificate",
+			cert:    []byte("invalid"),
+			wantErr: "failed to parse 'identity-cert.pub'",
+		},
+		{
+			name:    "host certificate",
+			cert:    newCert(pub, ssh.HostCert, nil, now.Add(-time.Hour), now.Add(time.Hour)),
+			wantErr: "not a user certificate",
+		},
+		{
+			name:    "certificate of another key",
+			cert:    newCert(otherPub, ssh.UserCert, nil, now.Add(-time.Hour), now.Add(time.Hour)),
+			wantErr: "certifies the key " + ssh.FingerprintSHA256(otherPub),
+		},
+		{
+			name:    "certificate of another user",
+			cert:    newCert(pub, ssh.UserCert, []string{"alice"}, now.Add(-time.Hour), now.Add(time.Hour)),
+			wantErr: "not valid for the user 'git'",
+		},
+		{
+			name:    "certificate not valid yet",
+			cert:    newCert(pub, ssh.UserCert, nil, now.Add(time.Hour), now.Add(2*time.Hour)),
+			wantErr: "not valid before 2024-06-05T10:00:00Z",
+		},
+		{
+			name:    "expired certificate",
+			cert:    newCert(pub, ssh.UserCert, nil, now.Add(-2*time.Hour), now.Add(-time.Hour)),
+			wantErr: "expired at 2024-06-05T08:00:00Z, sign the identity with the SSH CA again",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			opts := &git.AuthOptions{Transport: git.SSH, Username: "git", Identity: identity}
+			cert, err := checkSSHCertificate(opts, tt.cert, now)
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(cert.user).To(Equal("git"))
+			g.Expect(cert.signer.PublicKey().Type()).To(Equal(ssh.CertAlgoED25519v01))
+		})
+	}
+}
+
 func Test_getProxyOpts(t *testing.T) {
 	namespace := "default"
 	invalidProxy := &corev1.Secret{
@@ -211,10 +387,24 @@ func Test_getProxyOpts(t *testing.T) {
 			"password": []byte("pass"),
 		},
 	}
+	socksProxy := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "socks-proxy",
+			Namespace: namespace,
+		},
+		Data: map[string][]byte{
+			"address":  []byte("socks5://proxy.example.com:1080"),
+			"username": []byte("user"),
+			"password": []byte("pass"),
+		},
+	}
 
 	tests := []struct {
 		name       string
 		secretName string
+		transport  git.TransportType
+		url        string
+		env        map[string]string
 		want       *transport.ProxyOptions
 		wantErr    bool
 	}{
@@ -240,25 +430,74 @@ func Test_getProxyOpts(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name:       "SOCKS5 proxy secret with SSH",
+			secretName: "socks-proxy",
+			transport:  git.SSH,
+			want: &transport.ProxyOptions{
+				URL:      "socks5://proxy.example.com:1080",
+				Username: "user",
+				Password: "pass",
+			},
+		},
+		{
+			name:       "HTTP proxy secret with SSH",
+			secretName: "valid-proxy",
+			transport:  git.SSH,
+			wantErr:    true,
+		},
+		{
+			name:      "SSH without proxy",
+			transport: git.SSH,
+			url:       "ssh://git@github.com/fluxcd/flux2",
+		},
+		{
+			name:      "SSH with ALL_PROXY",
+			transport: git.SSH,
+			url:       "ssh://git@github.com/fluxcd/flux2",
+			env:       map[string]string{"ALL_PROXY": "socks5://proxy.example.com:1080"},
+			want:      &transport.ProxyOptions{URL: "socks5://proxy.example.com:1080"},
+		},
+		{
+			name:      "SSH with ALL_PROXY and NO_PROXY",
+			transport: git.SSH,
+			url:       "ssh://git@github.com/fluxcd/flux2",
+			env:       map[string]string{"ALL_PROXY": "socks5://proxy.example.com:1080", "NO_PROXY": "github.com"},
+		},
+		{
+			name:      "SSH with HTTP ALL_PROXY",
+			transport: git.SSH,
+			url:       "ssh://git@github.com/fluxcd/flux2",
+			env:       map[string]string{"ALL_PROXY": "http://proxy.example.com:3128"},
+		},
+		{
+			name:      "HTTP with ALL_PROXY",
+			transport: git.HTTPS,
+			url:       "https://github.com/fluxcd/flux2",
+			env:       map[string]string{"ALL_PROXY": "socks5://proxy.example.com:1080"},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			g := NewWithT(t)
 
+			for _, name := range []string{"ALL_PROXY", "all_proxy", "NO_PROXY", "no_proxy"} {
+				t.Setenv(name, tt.env[name])
+			}
+
 			clientBuilder := fakeclient.NewClientBuilder().
 				WithScheme(scheme.Scheme).
-				WithObjects(invalidProxy, validProxy)
+				WithObjects(invalidProxy, validProxy, socksProxy)
 			c := clientBuilder.Build()
 
 			gitRepo := &sourcev1.GitRepository{}
 			gitRepo.Namespace = namespace
+			gitRepo.Spec.URL = tt.url
 			if tt.secretName != "" {
-				gitRepo.Spec = sourcev1.GitRepositorySpec{
-					ProxySecretRef: &meta.LocalObjectReference{Name: tt.secretName},
-				}
+				gitRepo.Spec.ProxySecretRef = &meta.LocalObjectReference{Name: tt.secretName}
 			}
 
-			got, err := getProxyOpts(context.TODO(), c, gitRepo)
+			got, err := getProxyOpts(context.TODO(), c, gitRepo, tt.transport)
 			if (err != nil) != tt.wantErr {
 				g.Fail(fmt.Sprintf("unexpected error: %v", err))
 				return
@@ -268,6 +507,97 @@ func Test_getProxyOpts(t *testing.T) {
 	}
 }
 
+func Test_getTemplateValues(t *testing.T) {
+	namespace := "default"
+	clusterValues := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cluster-values",
+			Namespace: namespace,
+		},
+		Data: map[string]string{
+			"cluster": "prod",
+			"region":  "eu-west-1",
+		},
+	}
+	secretValues := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "secret-values",
+			Namespace: namespace,
+		},
+		Data: map[string][]byte{
+			"region": []byte("us-east-1"),
+			"team":   []byte("platform"),
+		},
+	}
+
+	tests := []struct {
+		name       string
+		valuesFrom []imagev1.ValuesReference
+		values     map[string]string
+		want       map[string]string
+		wantErr    bool
+	}{
+		{
+			name:   "inline values only",
+			values: map[string]string{"cluster": "dev"},
+			want:   map[string]string{"cluster": "dev"},
+		},
+		{
+			name: "merged in order with inline values taking precedence",
+			valuesFrom: []imagev1.ValuesReference{
+				{Kind: "ConfigMap", Name: "cluster-values"},
+				{Kind: "Secret", Name: "secret-values"},
+			},
+			values: map[string]string{"team": "apps"},
+			want: map[string]string{
+				"cluster": "prod",
+				"region":  "us-east-1",
+				"team":    "apps",
+			},
+		},
+		{
+			name: "optional reference not found",
+			valuesFrom: []imagev1.ValuesReference{
+				{Kind: "ConfigMap", Name: "cluster-values"},
+				{Kind: "Secret", Name: "non-existing", Optional: true},
+			},
+			want: map[string]string{
+				"cluster": "prod",
+				"region":  "eu-west-1",
+			},
+		},
+		{
+			name: "reference not found",
+			valuesFrom: []imagev1.ValuesReference{
+				{Kind: "ConfigMap", Name: "non-existing"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			c := fakeclient.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(clusterValues, secretValues).
+				Build()
+
+			commit := imagev1.CommitSpec{
+				ValuesFrom:            tt.valuesFrom,
+				MessageTemplateValues: tt.values,
+			}
+			got, err := getTemplateValues(context.TODO(), c, namespace, commit)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
+
 func Test_getSigningEntity(t *testing.T) {
 	g := NewWithT(t)
 
@@ -327,16 +657,7 @@ func Test_getSigningEntity(t *testing.T) {
 				WithObjects(encryptedKeySecret, unencryptedKeySecret)
 			c := clientBuilder.Build()
 
-			gitSpec := &imagev1.GitSpec{}
-			if tt.secretName != "" {
-				gitSpec.Commit = imagev1.CommitSpec{
-					SigningKey: &imagev1.SigningKey{
-						SecretRef: meta.LocalObjectReference{Name: tt.secretName},
-					},
-				}
-			}
-
-			_, err := getSigningEntity(context.TODO(), c, namespace, gitSpec)
+			_, err := getSigningEntity(context.TODO(), c, namespace, tt.secretName)
 			if (err != nil) != tt.wantErr {
 				g.Fail(fmt.Sprintf("unexpected error: %v", err))
 				return
@@ -345,6 +666,131 @@ func Test_getSigningEntity(t *testing.T) {
 	}
 }
 
+func Test_getCredentialsAuthor(t *testing.T) {
+	namespace := "default"
+	repoKey := types.NamespacedName{Namespace: namespace, Name: "repo"}
+	secret := func(name string, data map[string]string) *corev1.Secret {
+		s := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}, Data: map[string][]byte{}}
+		for k, v := range data {
+			s.Data[k] = []byte(v)
+		}
+		return s
+	}
+
+	tests := []struct {
+		name       string
+		secretRef  *meta.LocalObjectReference
+		wantAuthor imagev1.CommitUser
+		wantErr    string
+	}{
+		{
+			name: "no secret",
+		},
+		{
+			name:       "username and email",
+			secretRef:  &meta.LocalObjectReference{Name: "full"},
+			wantAuthor: imagev1.CommitUser{Name: "flux-bot", Email: "flux-bot@example.com"},
+		},
+		{
+			name:       "email only",
+			secretRef:  &meta.LocalObjectReference{Name: "email"},
+			wantAuthor: imagev1.CommitUser{Email: "flux-bot@example.com"},
+		},
+		{
+			name:      "no email",
+			secretRef: &meta.LocalObjectReference{Name: "username"},
+		},
+		{
+			name:      "invalid email",
+			secretRef: &meta.LocalObjectReference{Name: "invalid"},
+			wantErr:   "must not contain angle brackets",
+		},
+		{
+			name:      "non-existing secret",
+			secretRef: &meta.LocalObjectReference{Name: "non-existing"},
+			wantErr:   "failed to get auth secret 'default/non-existing'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			c := fakeclient.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(
+					secret("full", map[string]string{"username": "flux-bot", "password": "token", "email": "flux-bot@example.com"}),
+					secret("email", map[string]string{"email": "flux-bot@example.com\n"}),
+					secret("username", map[string]string{"username": "flux-bot", "password": "token"}),
+					secret("invalid", map[string]string{"email": "<flux-bot@example.com>"}),
+				).Build()
+
+			author, err := getCredentialsAuthor(context.TODO(), c, repoKey, tt.secretRef)
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(author).To(Equal(tt.wantAuthor))
+		})
+	}
+}
+
+func Test_selectSigningKey(t *testing.T) {
+	g := NewWithT(t)
+
+	namespace := "default"
+	var objects []client.Object
+	for _, name := range []string{"prod-key", "staging-key", "release-key"} {
+		_, key := testutil.GetSigningKeyPair(g, "")
+		objects = append(objects, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Data:       map[string][]byte{signingSecretKey: key},
+		})
+	}
+	c := fakeclient.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objects...).Build()
+
+	keys, err := getBranchSigningKeys(context.TODO(), c, namespace, []imagev1.BranchSigningKeyRef{
+		{Name: "prod-key", Branches: []string{"main", "prod"}},
+		{Name: "staging-key", Branches: []string{"staging"}},
+		{Name: "release-key", Branches: []string{"release/*", "prod"}},
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(keys).To(HaveLen(3))
+
+	tests := []struct {
+		branch  string
+		want    string
+		wantErr string
+	}{
+		{branch: "main", want: "prod-key"},
+		{branch: "staging", want: "staging-key"},
+		{branch: "release/1.0", want: "release-key"},
+		{branch: "release/1.0/rc", wantErr: "no signing key matches the push branch 'release/1.0/rc'"},
+		{branch: "prod", wantErr: "signing key secrets 'prod-key', 'release-key' all match the push branch 'prod'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.branch, func(t *testing.T) {
+			g := NewWithT(t)
+
+			entity, err := selectSigningKey(keys, tt.branch)
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+				g.Expect(errors.Is(err, ErrInvalidSourceConfiguration)).To(BeTrue())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			i := slices.IndexFunc(keys, func(k branchSigningKey) bool { return k.secretName == tt.want })
+			g.Expect(entity).To(Equal(keys[i].entity))
+		})
+	}
+
+	// The patterns are validated.
+	_, err = getBranchSigningKeys(context.TODO(), c, namespace, []imagev1.BranchSigningKeyRef{
+		{Name: "prod-key", Branches: []string{"[main"}},
+	})
+	g.Expect(errors.Is(err, ErrInvalidSourceConfiguration)).To(BeTrue())
+}
+
 func Test_buildGitConfig(t *testing.T) {
 	testGitRepoName := "test-gitrepo"
 	namespace := "foo-ns"
@@ -359,8 +805,10 @@ func Test_buildGitConfig(t *testing.T) {
 		gitRepoTimeout   *metav1.Duration
 		gitRepoURL       string
 		gitRepoProxyData map[string][]byte
+		gitRepoSecret    map[string][]byte
 		srcOpts          SourceOptions
 		wantErr          bool
+		wantErrIs        error
 		wantCheckoutRef  *sourcev1.GitRepositoryRef
 		wantPushBranch   string
 		wantSwitchBranch bool
@@ -369,6 +817,7 @@ func Test_buildGitConfig(t *testing.T) {
 		{
 			name: "same branch, gitSpec checkoutRef",
 			gitSpec: &imagev1.GitSpec{
+				Commit: imagev1.CommitSpec{Author: testAuthor},
 				Checkout: &imagev1.GitCheckoutSpec{
 					Reference: sourcev1.GitRepositoryRef{Branch: "aaa"},
 				},
@@ -386,6 +835,7 @@ func Test_buildGitConfig(t *testing.T) {
 		{
 			name: "different branch, gitSpec checkoutRef",
 			gitSpec: &imagev1.GitSpec{
+				Commit: imagev1.CommitSpec{Author: testAuthor},
 				Checkout: &imagev1.GitCheckoutSpec{
 					Reference: sourcev1.GitRepositoryRef{Branch: "aaa"},
 				},
@@ -405,7 +855,7 @@ func Test_buildGitConfig(t *testing.T) {
 		},
 		{
 			name:        "same branch, gitrepo checkoutRef",
-			gitSpec:     &imagev1.GitSpec{},
+			gitSpec:     &imagev1.GitSpec{Commit: imagev1.CommitSpec{Author: testAuthor}},
 			gitRepoName: testGitRepoName,
 			gitRepoURL:  testGitURL,
 			gitRepoRef: &sourcev1.GitRepositoryRef{
@@ -419,9 +869,27 @@ func Test_buildGitConfig(t *testing.T) {
 			wantSwitchBranch: false,
 			wantTimeout:      testTimeout,
 		},
+		{
+			name: "checkout strategy only, gitrepo checkoutRef",
+			gitSpec: &imagev1.GitSpec{
+				Commit:   imagev1.CommitSpec{Author: testAuthor},
+				Checkout: &imagev1.GitCheckoutSpec{Strategy: imagev1.CheckoutStrategyFull},
+			},
+			gitRepoName: testGitRepoName,
+			gitRepoURL:  testGitURL,
+			gitRepoRef: &sourcev1.GitRepositoryRef{
+				Branch: "ccc",
+			},
+			wantCheckoutRef: &sourcev1.GitRepositoryRef{
+				Branch: "ccc",
+			},
+			wantPushBranch: "ccc",
+			wantTimeout:    testTimeout,
+		},
 		{
 			name: "different branch, gitrepo checkoutRef",
 			gitSpec: &imagev1.GitSpec{
+				Commit: imagev1.CommitSpec{Author: testAuthor},
 				Push: &imagev1.PushSpec{
 					Branch: "ddd",
 				},
@@ -442,6 +910,7 @@ func Test_buildGitConfig(t *testing.T) {
 		{
 			name: "no checkoutRef defined",
 			gitSpec: &imagev1.GitSpec{
+				Commit: imagev1.CommitSpec{Author: testAuthor},
 				Push: &imagev1.PushSpec{
 					Branch: "aaa",
 				},
@@ -457,6 +926,7 @@ func Test_buildGitConfig(t *testing.T) {
 		{
 			name: "gitSpec override gitRepo checkout config",
 			gitSpec: &imagev1.GitSpec{
+				Commit: imagev1.CommitSpec{Author: testAuthor},
 				Checkout: &imagev1.GitCheckoutSpec{
 					Reference: sourcev1.GitRepositoryRef{Branch: "aaa"},
 				},
@@ -477,14 +947,72 @@ func Test_buildGitConfig(t *testing.T) {
 			wantSwitchBranch: true,
 			wantTimeout:      testTimeout,
 		},
+		{
+			name: "tag checkoutRef with push branch",
+			gitSpec: &imagev1.GitSpec{
+				Commit: imagev1.CommitSpec{Author: testAuthor},
+				Checkout: &imagev1.GitCheckoutSpec{
+					Reference: sourcev1.GitRepositoryRef{Tag: "v1.0.0"},
+				},
+				Push: &imagev1.PushSpec{
+					Branch: "release-1.0",
+				},
+			},
+			gitRepoName: testGitRepoName,
+			gitRepoURL:  testGitURL,
+			wantErr:     false,
+			wantCheckoutRef: &sourcev1.GitRepositoryRef{
+				Tag: "v1.0.0",
+			},
+			wantPushBranch:   "release-1.0",
+			wantSwitchBranch: true,
+			wantTimeout:      testTimeout,
+		},
+		{
+			name:        "tag checkoutRef without push branch",
+			gitSpec:     &imagev1.GitSpec{Commit: imagev1.CommitSpec{Author: testAuthor}},
+			gitRepoName: testGitRepoName,
+			gitRepoURL:  testGitURL,
+			gitRepoRef: &sourcev1.GitRepositoryRef{
+				Branch: "main",
+				Tag:    "v1.0.0",
+			},
+			wantErr: true,
+		},
+		{
+			name: "commit checkoutRef pushing to the checkout branch",
+			gitSpec: &imagev1.GitSpec{
+				Commit: imagev1.CommitSpec{Author: testAuthor},
+				Checkout: &imagev1.GitCheckoutSpec{
+					Reference: sourcev1.GitRepositoryRef{
+						Branch: "main",
+						Commit: "6a3f8b2d9c1e4f5a6b7c8d9e0f1a2b3c4d5e6f7a",
+					},
+				},
+				Push: &imagev1.PushSpec{
+					Branch: "main",
+				},
+			},
+			gitRepoName: testGitRepoName,
+			gitRepoURL:  testGitURL,
+			wantErr:     true,
+		},
 		{
 			name:    "non-existing gitRepo",
-			gitSpec: &imagev1.GitSpec{},
+			gitSpec: &imagev1.GitSpec{Commit: imagev1.CommitSpec{Author: testAuthor}},
 			wantErr: true,
 		},
+		{
+			name:        "signed commits required without signing key",
+			gitSpec:     &imagev1.GitSpec{Commit: imagev1.CommitSpec{Author: testAuthor}},
+			gitRepoName: testGitRepoName,
+			gitRepoURL:  testGitURL,
+			srcOpts:     SourceOptions{requireSignedCommits: true},
+			wantErr:     true,
+		},
 		{
 			name:        "use gitrepo timeout",
-			gitSpec:     &imagev1.GitSpec{},
+			gitSpec:     &imagev1.GitSpec{Commit: imagev1.CommitSpec{Author: testAuthor}},
 			gitRepoName: testGitRepoName,
 			gitRepoURL:  testGitURL,
 			gitRepoRef: &sourcev1.GitRepositoryRef{
@@ -501,7 +1029,7 @@ func Test_buildGitConfig(t *testing.T) {
 		},
 		{
 			name:        "bad git URL",
-			gitSpec:     &imagev1.GitSpec{},
+			gitSpec:     &imagev1.GitSpec{Commit: imagev1.CommitSpec{Author: testAuthor}},
 			gitRepoName: testGitRepoName,
 			gitRepoURL:  "://example.com",
 			gitRepoRef: &sourcev1.GitRepositoryRef{
@@ -510,13 +1038,58 @@ func Test_buildGitConfig(t *testing.T) {
 			wantErr: true,
 		},
 		{
-			name:        "proxy config",
+			name:        "no author email",
 			gitSpec:     &imagev1.GitSpec{},
 			gitRepoName: testGitRepoName,
 			gitRepoURL:  testGitURL,
 			gitRepoRef: &sourcev1.GitRepositoryRef{
 				Branch: "ccc",
 			},
+			wantErr:   true,
+			wantErrIs: ErrInvalidSourceConfiguration,
+		},
+		{
+			name:        "no author email in the spec or the Secret",
+			gitSpec:     &imagev1.GitSpec{},
+			gitRepoName: testGitRepoName,
+			gitRepoURL:  testGitURL,
+			gitRepoRef: &sourcev1.GitRepositoryRef{
+				Branch: "ccc",
+			},
+			gitRepoSecret: map[string][]byte{
+				"username": []byte("flux-bot"),
+				"password": []byte("token"),
+			},
+			wantErr:   true,
+			wantErrIs: ErrInvalidSourceConfiguration,
+		},
+		{
+			name:        "author email in the Secret",
+			gitSpec:     &imagev1.GitSpec{},
+			gitRepoName: testGitRepoName,
+			gitRepoURL:  testGitURL,
+			gitRepoRef: &sourcev1.GitRepositoryRef{
+				Branch: "ccc",
+			},
+			gitRepoSecret: map[string][]byte{
+				"username": []byte("flux-bot"),
+				"password": []byte("token"),
+				"email":    []byte("flux-bot@example.com"),
+			},
+			wantCheckoutRef: &sourcev1.GitRepositoryRef{
+				Branch: "ccc",
+			},
+			wantPushBranch: "ccc",
+			wantTimeout:    testTimeout,
+		},
+		{
+			name:        "proxy config",
+			gitSpec:     &imagev1.GitSpec{Commit: imagev1.CommitSpec{Author: testAuthor}},
+			gitRepoName: testGitRepoName,
+			gitRepoURL:  testGitURL,
+			gitRepoRef: &sourcev1.GitRepositoryRef{
+				Branch: "ccc",
+			},
 			gitRepoProxyData: map[string][]byte{
 				"address": []byte("http://example.com"),
 			},
@@ -547,6 +1120,18 @@ func Test_buildGitConfig(t *testing.T) {
 				testObjects = append(testObjects, proxySecret)
 			}
 
+			var authSecret *corev1.Secret
+			if tt.gitRepoSecret != nil {
+				authSecret = &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "git-creds",
+						Namespace: namespace,
+					},
+					Data: tt.gitRepoSecret,
+				}
+				testObjects = append(testObjects, authSecret)
+			}
+
 			var gitRepo *sourcev1.GitRepository
 			if tt.gitRepoName != "" {
 				gitRepo = &sourcev1.GitRepository{}
@@ -565,6 +1150,9 @@ func Test_buildGitConfig(t *testing.T) {
 				if proxySecret != nil {
 					gitRepo.Spec.ProxySecretRef = &meta.LocalObjectReference{Name: proxySecret.Name}
 				}
+				if authSecret != nil {
+					gitRepo.Spec.SecretRef = &meta.LocalObjectReference{Name: authSecret.Name}
+				}
 				testObjects = append(testObjects, gitRepo)
 			}
 
@@ -588,6 +1176,9 @@ func Test_buildGitConfig(t *testing.T) {
 				g.Fail(fmt.Sprintf("unexpected error: %v", err))
 				return
 			}
+			if tt.wantErrIs != nil {
+				g.Expect(err).To(MatchError(tt.wantErrIs))
+			}
 			if err == nil {
 				g.Expect(gitSrcCfg.checkoutRef).To(Equal(tt.wantCheckoutRef), "unexpected checkoutRef")
 				g.Expect(gitSrcCfg.pushBranch).To(Equal(tt.wantPushBranch), "unexpected push branch")
@@ -0,0 +1,154 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const defaultGitHubAPIAddress = "https://api.github.com"
+
+// gitHubCommitter makes the commits with the Git database API of a GitHub
+// repository. The commits have no committer, for GitHub to sign them when
+// the token is the one of a GitHub App.
+type gitHubCommitter struct {
+	address    string
+	repository string
+	token      string
+	client     *http.Client
+}
+
+type gitHubObject struct {
+	SHA string `json:"sha"`
+}
+
+type gitHubBlob struct {
+	Content  string `json:"content"`
+	Encoding string `json:"encoding"`
+}
+
+type gitHubTreeEntry struct {
+	Path string `json:"path"`
+	Mode string `json:"mode"`
+	Type string `json:"type"`
+	// SHA is the blob of the file, null when it's deleted.
+	SHA *string `json:"sha"`
+}
+
+type gitHubTree struct {
+	BaseTree string            `json:"base_tree"`
+	Tree     []gitHubTreeEntry `json:"tree"`
+}
+
+type gitHubAuthor struct {
+	Name  string    `json:"name"`
+	Email string    `json:"email"`
+	Date  time.Time `json:"date"`
+}
+
+type gitHubCommit struct {
+	Message string       `json:"message"`
+	Tree    string       `json:"tree"`
+	Parents []string     `json:"parents"`
+	Author  gitHubAuthor `json:"author"`
+}
+
+type gitHubRef struct {
+	Object gitHubObject `json:"object"`
+}
+
+type gitHubRefUpdate struct {
+	Ref   string `json:"ref,omitempty"`
+	SHA   string `json:"sha"`
+	Force bool   `json:"force,omitempty"`
+}
+
+// commit implements apiCommitter. The blobs of the changed files, the tree
+// on top of the tree of the parent, and the commit are created before the
+// branch is created or updated to the commit. The update is rejected when
+// the head of the branch isn't the parent, unless forced.
+func (c *gitHubCommitter) commit(ctx context.Context, commit apiCommit) (string, error) {
+	tree := gitHubTree{BaseTree: commit.parentTree}
+	for _, change := range commit.changes {
+		entry := gitHubTreeEntry{Path: change.path, Mode: fmt.Sprintf("%06o", uint32(change.mode)), Type: "blob"}
+		if !change.deleted {
+			var blob gitHubObject
+			if err := c.request(ctx, http.MethodPost, "/git/blobs", gitHubBlob{
+				Content:  base64.StdEncoding.EncodeToString(change.content),
+				Encoding: "base64",
+			}, &blob); err != nil {
+				return "", fmt.Errorf("failed to create the blob of '%s': %w", change.path, err)
+			}
+			entry.SHA = &blob.SHA
+		}
+		tree.Tree = append(tree.Tree, entry)
+	}
+	var newTree gitHubObject
+	if err := c.request(ctx, http.MethodPost, "/git/trees", tree, &newTree); err != nil {
+		return "", fmt.Errorf("failed to create tree: %w", err)
+	}
+
+	var newCommit gitHubObject
+	if err := c.request(ctx, http.MethodPost, "/git/commits", gitHubCommit{
+		Message: commit.message,
+		Tree:    newTree.SHA,
+		Parents: []string{commit.parent},
+		Author: gitHubAuthor{
+			Name:  commit.author.Name,
+			Email: commit.author.Email,
+			Date:  commit.author.When,
+		},
+	}, &newCommit); err != nil {
+		return "", fmt.Errorf("failed to create commit: %w", err)
+	}
+
+	var head gitHubRef
+	err := c.request(ctx, http.MethodGet, "/git/ref/heads/"+commit.branch, nil, &head)
+	switch {
+	case isAPINotFound(err):
+		if err := c.request(ctx, http.MethodPost, "/git/refs", gitHubRefUpdate{
+			Ref: "refs/heads/" + commit.branch,
+			SHA: newCommit.SHA,
+		}, nil); err != nil {
+			return "", fmt.Errorf("failed to create branch '%s': %w", commit.branch, err)
+		}
+	case err != nil:
+		return "", fmt.Errorf("failed to get branch '%s': %w", commit.branch, err)
+	default:
+		if head.Object.SHA != commit.parent && !commit.force {
+			return "", branchMovedError(commit.branch, commit.parent, head.Object.SHA)
+		}
+		if err := c.request(ctx, http.MethodPatch, "/git/refs/heads/"+commit.branch, gitHubRefUpdate{
+			SHA:   newCommit.SHA,
+			Force: commit.force,
+		}, nil); err != nil {
+			return "", fmt.Errorf("failed to update branch '%s': %w", commit.branch, err)
+		}
+	}
+	return newCommit.SHA, nil
+}
+
+func (c *gitHubCommitter) request(ctx context.Context, method, path string, body, v any) error {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+c.token)
+	header.Set("X-GitHub-Api-Version", "2022-11-28")
+	return apiRequest(ctx, c.client, method, fmt.Sprintf("%s/repos/%s%s", c.address, c.repository, path), header, body, v)
+}
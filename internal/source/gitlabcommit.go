@@ -0,0 +1,131 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+)
+
+const defaultGitLabAPIAddress = "https://gitlab.com/api/v4"
+
+// gitLabCommitter makes the commits with the commits API of a GitLab project.
+type gitLabCommitter struct {
+	address string
+	project string
+	token   string
+	client  *http.Client
+}
+
+type gitLabBranch struct {
+	Commit struct {
+		ID string `json:"id"`
+	} `json:"commit"`
+}
+
+type gitLabAction struct {
+	Action          string `json:"action"`
+	FilePath        string `json:"file_path"`
+	Content         string `json:"content,omitempty"`
+	Encoding        string `json:"encoding,omitempty"`
+	ExecuteFilemode *bool  `json:"execute_filemode,omitempty"`
+}
+
+type gitLabCommit struct {
+	Branch        string         `json:"branch"`
+	StartSHA      string         `json:"start_sha,omitempty"`
+	CommitMessage string         `json:"commit_message"`
+	AuthorName    string         `json:"author_name"`
+	AuthorEmail   string         `json:"author_email"`
+	Actions       []gitLabAction `json:"actions"`
+	Force         bool           `json:"force,omitempty"`
+}
+
+type gitLabCommitID struct {
+	ID string `json:"id"`
+}
+
+// commit implements apiCommitter. The commit is made on top of the head of
+// the branch when it's the parent, and on top of the parent otherwise,
+// overwriting the branch only when forced.
+func (c *gitLabCommitter) commit(ctx context.Context, commit apiCommit) (string, error) {
+	req := gitLabCommit{
+		Branch:        commit.branch,
+		CommitMessage: commit.message,
+		AuthorName:    commit.author.Name,
+		AuthorEmail:   commit.author.Email,
+	}
+
+	var branch gitLabBranch
+	err := c.request(ctx, http.MethodGet, "/repository/branches/"+url.PathEscape(commit.branch), nil, &branch)
+	switch {
+	case isAPINotFound(err):
+		req.StartSHA = commit.parent
+	case err != nil:
+		return "", fmt.Errorf("failed to get branch '%s': %w", commit.branch, err)
+	case branch.Commit.ID != commit.parent:
+		if !commit.force {
+			return "", branchMovedError(commit.branch, commit.parent, branch.Commit.ID)
+		}
+		req.StartSHA = commit.parent
+		req.Force = true
+	}
+
+	// Deleted files are deleted first, for a file replacing a directory to
+	// be created after the files of the directory are deleted.
+	for _, change := range commit.changes {
+		if change.deleted {
+			req.Actions = append(req.Actions, gitLabAction{Action: "delete", FilePath: change.path})
+		}
+	}
+	for _, change := range commit.changes {
+		if change.deleted {
+			continue
+		}
+		if change.mode != filemode.Regular && change.mode != filemode.Executable {
+			return "", fmt.Errorf("file '%s' with mode %s can't be committed with the GitLab API", change.path, change.mode)
+		}
+		action := "update"
+		if change.created {
+			action = "create"
+		}
+		executable := change.mode == filemode.Executable
+		req.Actions = append(req.Actions, gitLabAction{
+			Action:          action,
+			FilePath:        change.path,
+			Content:         base64.StdEncoding.EncodeToString(change.content),
+			Encoding:        "base64",
+			ExecuteFilemode: &executable,
+		})
+	}
+	var created gitLabCommitID
+	if err := c.request(ctx, http.MethodPost, "/repository/commits", req, &created); err != nil {
+		return "", fmt.Errorf("failed to create commit: %w", err)
+	}
+	return created.ID, nil
+}
+
+func (c *gitLabCommitter) request(ctx context.Context, method, path string, body, v any) error {
+	header := http.Header{}
+	header.Set("PRIVATE-TOKEN", c.token)
+	return apiRequest(ctx, c.client, method, fmt.Sprintf("%s/projects/%s%s", c.address, url.PathEscape(c.project), path), header, body, v)
+}
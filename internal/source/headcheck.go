@@ -0,0 +1,333 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-billy/v5/util"
+	extgogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage"
+	"github.com/go-git/go-git/v5/storage/memory"
+
+	"github.com/fluxcd/pkg/git"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+// ErrRemoteChanged is the error of a push aborted because the head of the
+// push branch on the remote moved since the checkout.
+var ErrRemoteChanged = errors.New("remote branch changed since checkout")
+
+// recordPushBranchHead records the head of the push branch on the remote at
+// the checkout of the given commit, for it to be checked before pushing. The
+// head is unknown when the push branch isn't fetched, and is then not checked.
+// The head of the push branch of another repository the commits are pushed to
+// is listed when it's checked.
+func (sm *SourceManager) recordPushBranchHead(ctx context.Context, checkout *git.Commit) error {
+	sm.pushBranchHead, sm.pushBranchHeadKnown = "", false
+	// There's no push branch to check with a refspec-only push.
+	if sm.srcCfg.refspecOnly {
+		return nil
+	}
+	if sm.srcCfg.writeTarget != nil {
+		if sm.srcCfg.headCheck == "" {
+			return nil
+		}
+		head, err := sm.remotePushBranchHead(ctx)
+		if err != nil {
+			return err
+		}
+		sm.pushBranchHead, sm.pushBranchHeadKnown = head, true
+		return nil
+	}
+	if !sm.srcCfg.switchBranch {
+		sm.pushBranchHead, sm.pushBranchHeadKnown = checkout.Hash.String(), true
+		return nil
+	}
+	if sm.srcCfg.singleBranch {
+		return nil
+	}
+	repo, err := sm.openRepository()
+	if err != nil {
+		return err
+	}
+	// A push branch which doesn't exist yet must still not exist.
+	ref, err := repo.Reference(plumbing.NewRemoteReferenceName(extgogit.DefaultRemoteName, sm.srcCfg.pushBranch), true)
+	switch {
+	case err == nil:
+		sm.pushBranchHead = ref.Hash().String()
+	case !errors.Is(err, plumbing.ErrReferenceNotFound):
+		return fmt.Errorf("failed to resolve the push branch '%s': %w", sm.srcCfg.pushBranch, err)
+	}
+	sm.pushBranchHeadKnown = true
+	return nil
+}
+
+// checkPushBranchHead checks that the head of the push branch on the remote
+// didn't move since the checkout, before pushing the commit with the given
+// revision. With HeadCheckRebase, the commit is made again on top of the new
+// head with the given commit function, when the commits pushed meanwhile
+// changed none of its files. It returns the revision to push, and whether the
+// commit was made again.
+func (sm SourceManager) checkPushBranchHead(ctx context.Context, policy imagev1.HeadCheckPolicy, rev string,
+	commit func() (string, error)) (string, bool, error) {
+	if policy == "" || !sm.pushBranchHeadKnown {
+		return rev, false, nil
+	}
+	head, err := sm.remotePushBranchHead(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	if head == sm.pushBranchHead {
+		return rev, false, nil
+	}
+
+	changed := fmt.Errorf("%w: branch '%s' moved from %s to %s", ErrRemoteChanged, sm.srcCfg.pushBranch,
+		describeHead(sm.pushBranchHead), describeHead(head))
+	if policy != imagev1.HeadCheckRebase || head == "" {
+		return "", false, &GitOperationError{Operation: GitOperationPush, Class: ErrorClassConflict, Err: changed}
+	}
+	newRev, err := sm.rebaseCommit(ctx, head, rev, commit)
+	if err != nil {
+		return "", false, &GitOperationError{Operation: GitOperationPush, Class: ErrorClassConflict,
+			Err: fmt.Errorf("%w, and the commit can't be made again on top of it: %w", changed, err)}
+	}
+	return newRev, true, nil
+}
+
+// describeHead describes the head of a branch for the error messages.
+func describeHead(head string) string {
+	if head == "" {
+		return "nothing"
+	}
+	return fmt.Sprintf("'%s'", head)
+}
+
+// remotePushBranchHead returns the head of the push branch on the remote, or
+// an empty string if the branch doesn't exist.
+func (sm SourceManager) remotePushBranchHead(ctx context.Context) (string, error) {
+	var head string
+	err := sm.withFallbackCredentials(ctx, func() error {
+		var err error
+		head, err = sm.listPushBranchHead(ctx)
+		return err
+	})
+	return head, err
+}
+
+// listPushBranchHead lists the references of the remote to return the head
+// of the push branch.
+func (sm SourceManager) listPushBranchHead(ctx context.Context) (string, error) {
+	url, authOpts, proxyOpts := sm.srcCfg.pushEndpoint()
+	auth, err := remoteTransportAuth(authOpts)
+	if err != nil {
+		return "", fmt.Errorf("failed to construct auth method with options: %w", err)
+	}
+	r := extgogit.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: extgogit.DefaultRemoteName,
+		URLs: []string{url},
+	})
+	listOpts := &extgogit.ListOptions{
+		Auth:     auth,
+		CABundle: authOpts.CAFile,
+	}
+	if proxyOpts != nil {
+		listOpts.ProxyOptions = *proxyOpts
+	}
+	refs, err := r.ListContext(ctx, listOpts)
+	if err != nil {
+		if errors.Is(err, transport.ErrEmptyRemoteRepository) {
+			return "", nil
+		}
+		return "", classifyGitError(GitOperationPush, sm.deadlineError(ctx, GitOperationPush,
+			fmt.Errorf("failed to list the remote references: %w", err)))
+	}
+	name := plumbing.NewBranchReferenceName(sm.srcCfg.pushBranch)
+	for _, ref := range refs {
+		if ref.Name() == name {
+			return ref.Hash().String(), nil
+		}
+	}
+	return "", nil
+}
+
+// rebaseCommit fetches the given head of the push branch, and checks it out
+// with the files changed by the commit with the given revision, to make the
+// commit again with the given commit function. The files changed by the
+// commit must not have been changed by the commits pushed since its parent.
+func (sm SourceManager) rebaseCommit(ctx context.Context, head, rev string, commit func() (string, error)) (string, error) {
+	repo, err := sm.openRepository()
+	if err != nil {
+		return "", err
+	}
+	if err := sm.withFallbackCredentials(ctx, func() error {
+		url, authOpts, proxyOpts := sm.srcCfg.pushEndpoint()
+		if _, err := fetchBranch(ctx, repo, url, authOpts, proxyOpts, sm.srcCfg.pushBranch); err != nil {
+			return classifyGitError(GitOperationPush, sm.deadlineError(ctx, GitOperationPush,
+				fmt.Errorf("failed to fetch the push branch: %w", err)))
+		}
+		return nil
+	}); err != nil {
+		return "", err
+	}
+	branchRef := plumbing.NewBranchReferenceName(sm.srcCfg.pushBranch)
+
+	headCommit, err := repo.CommitObject(plumbing.NewHash(head))
+	if err != nil {
+		return "", fmt.Errorf("failed to read the new head: %w", err)
+	}
+	ours, err := repo.CommitObject(plumbing.NewHash(rev))
+	if err != nil {
+		return "", fmt.Errorf("failed to read the commit: %w", err)
+	}
+	base, err := ours.Parent(0)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the parent of the commit: %w", err)
+	}
+	baseTree, err := base.Tree()
+	if err != nil {
+		return "", err
+	}
+	ourTree, err := ours.Tree()
+	if err != nil {
+		return "", err
+	}
+	headTree, err := headCommit.Tree()
+	if err != nil {
+		return "", err
+	}
+	ourPaths, err := changedPaths(baseTree, ourTree)
+	if err != nil {
+		return "", err
+	}
+	theirPaths, err := changedPaths(baseTree, headTree)
+	if err != nil {
+		return "", err
+	}
+	var both []string
+	for _, p := range ourPaths {
+		if slices.Contains(theirPaths, p) {
+			both = append(both, p)
+		}
+	}
+	if len(both) > 0 {
+		return "", fmt.Errorf("the pushed commits also changed '%s'", strings.Join(both, "', '"))
+	}
+
+	// Check out the new head, and change the files again.
+	wt, err := repo.Worktree()
+	if err != nil {
+		return "", fmt.Errorf("failed to load worktree: %w", err)
+	}
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(branchRef, headCommit.Hash)); err != nil {
+		return "", fmt.Errorf("failed to reset branch '%s': %w", sm.srcCfg.pushBranch, err)
+	}
+	if err := wt.Checkout(&extgogit.CheckoutOptions{Branch: branchRef, Force: true}); err != nil {
+		return "", fmt.Errorf("failed to checkout branch '%s': %w", sm.srcCfg.pushBranch, err)
+	}
+	for _, p := range ourPaths {
+		f, err := ourTree.File(p)
+		if errors.Is(err, object.ErrFileNotFound) {
+			if err := util.RemoveAll(wt.Filesystem, p); err != nil {
+				return "", err
+			}
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		contents, err := f.Contents()
+		if err != nil {
+			return "", err
+		}
+		mode, err := f.Mode.ToOSFileMode()
+		if err != nil {
+			return "", err
+		}
+		if err := util.WriteFile(wt.Filesystem, p, []byte(contents), mode); err != nil {
+			return "", err
+		}
+	}
+	return commit()
+}
+
+// fetchBranch fetches the given branch of the repository at the URL into its
+// remote-tracking reference, and returns its head.
+func fetchBranch(ctx context.Context, repo *extgogit.Repository, url string, authOpts *git.AuthOptions,
+	proxyOpts *transport.ProxyOptions, branch string) (plumbing.Hash, error) {
+	auth, err := remoteTransportAuth(authOpts)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to construct auth method with options: %w", err)
+	}
+	remoteRef := plumbing.NewRemoteReferenceName(extgogit.DefaultRemoteName, branch)
+	r := extgogit.NewRemote(looseObjectStorer{repo.Storer}, &config.RemoteConfig{
+		Name: extgogit.DefaultRemoteName,
+		URLs: []string{url},
+	})
+	fetchOpts := &extgogit.FetchOptions{
+		RemoteName: extgogit.DefaultRemoteName,
+		RefSpecs:   []config.RefSpec{config.RefSpec(fmt.Sprintf("+%s:%s", plumbing.NewBranchReferenceName(branch), remoteRef))},
+		Auth:       auth,
+		CABundle:   authOpts.CAFile,
+	}
+	if proxyOpts != nil {
+		fetchOpts.ProxyOptions = *proxyOpts
+	}
+	if err := r.FetchContext(ctx, fetchOpts); err != nil && !errors.Is(err, extgogit.NoErrAlreadyUpToDate) {
+		return plumbing.ZeroHash, err
+	}
+	ref, err := repo.Reference(remoteRef, true)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to resolve the fetched branch '%s': %w", branch, err)
+	}
+	return ref.Hash(), nil
+}
+
+// looseObjectStorer hides the packfile writer of a storer, for the fetched
+// objects to be written as loose objects. The Git client opened the repository
+// with its own storage, which indexes the packfiles only once.
+type looseObjectStorer struct {
+	storage.Storer
+}
+
+// changedPaths returns the paths of the files which differ between the given
+// trees.
+func changedPaths(from, to *object.Tree) ([]string, error) {
+	changes, err := object.DiffTree(from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare the trees: %w", err)
+	}
+	var paths []string
+	for _, ch := range changes {
+		for _, name := range []string{ch.From.Name, ch.To.Name} {
+			if name != "" && !slices.Contains(paths, name) {
+				paths = append(paths, name)
+			}
+		}
+	}
+	slices.Sort(paths)
+	return paths, nil
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// DefaultUserAgent is the HTTP user agent of the Git operations when none is
+// given.
+const DefaultUserAgent = "image-automation-controller"
+
+// HTTPOptions configures the HTTP clients of the Git operations.
+type HTTPOptions struct {
+	// FollowRedirects makes the clients follow the redirects of the remote
+	// repositories, like Git does, instead of failing with a
+	// RepositoryMovedError.
+	FollowRedirects bool
+	// UserAgent is the user agent of the requests, DefaultUserAgent if
+	// empty.
+	UserAgent string
+	// Headers are static headers set on every request, e.g. for a proxy in
+	// front of the Git servers to route the requests.
+	Headers map[string]string
+}
+
+// InstallHTTPClient installs the HTTP clients of the Git operations,
+// configured with the given HTTPOptions.
+func InstallHTTPClient(opts HTTPOptions) {
+	checkRedirect := checkRepositoryMoved
+	if opts.FollowRedirects {
+		checkRedirect = nil
+	}
+	userAgent := opts.UserAgent
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	c := &gitHTTPTransport{
+		base:          http.DefaultTransport.(*http.Transport).Clone(),
+		checkRedirect: checkRedirect,
+		header:        http.Header{},
+	}
+	c.header.Set("User-Agent", userAgent)
+	for k, v := range opts.Headers {
+		c.header.Set(k, v)
+	}
+	client.InstallProtocol("http", c)
+	client.InstallProtocol("https", c)
+}
+
+// gitHTTPTransport is the transport.Transport of the Git operations over
+// HTTP, setting the headers of the requests. The go-git HTTP client requires
+// its http.Client to have an http.Transport to configure the CA bundle, the
+// TLS verification and the proxy of an endpoint, the requests are therefore
+// given their headers by a round tripper wrapping the http.Transport, already
+// configured for the endpoint.
+type gitHTTPTransport struct {
+	base          *http.Transport
+	checkRedirect func(req *http.Request, via []*http.Request) error
+	header        http.Header
+}
+
+var _ transport.Transport = &gitHTTPTransport{}
+
+func (t *gitHTTPTransport) NewUploadPackSession(ep *transport.Endpoint, auth transport.AuthMethod) (transport.UploadPackSession, error) {
+	c, ep, err := t.clientFor(ep)
+	if err != nil {
+		return nil, err
+	}
+	return c.NewUploadPackSession(ep, auth)
+}
+
+func (t *gitHTTPTransport) NewReceivePackSession(ep *transport.Endpoint, auth transport.AuthMethod) (transport.ReceivePackSession, error) {
+	c, ep, err := t.clientFor(ep)
+	if err != nil {
+		return nil, err
+	}
+	return c.NewReceivePackSession(ep, auth)
+}
+
+// clientFor returns the go-git HTTP client of the given endpoint, and the
+// endpoint to give it, without the options already applied to the
+// http.Transport of the client.
+func (t *gitHTTPTransport) clientFor(ep *transport.Endpoint) (transport.Transport, *transport.Endpoint, error) {
+	tr := t.base
+	if len(ep.CaBundle) > 0 || ep.InsecureSkipTLS || ep.Proxy.URL != "" {
+		tr = tr.Clone()
+		if err := configureTransport(tr, ep); err != nil {
+			return nil, nil, err
+		}
+		e := *ep
+		e.CaBundle, e.InsecureSkipTLS, e.Proxy = nil, false, transport.ProxyOptions{}
+		ep = &e
+	}
+	c := githttp.NewClient(&http.Client{
+		Transport:     &headerRoundTripper{next: tr, header: t.header},
+		CheckRedirect: t.checkRedirect,
+	})
+	return c, ep, nil
+}
+
+// configureTransport applies the CA bundle, the TLS verification and the
+// proxy of the given endpoint to the http.Transport, like the go-git HTTP
+// client does.
+func configureTransport(tr *http.Transport, ep *transport.Endpoint) error {
+	if len(ep.CaBundle) > 0 || ep.InsecureSkipTLS {
+		if tr.TLSClientConfig == nil {
+			tr.TLSClientConfig = &tls.Config{}
+		}
+	}
+	if len(ep.CaBundle) > 0 {
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil {
+			return err
+		}
+		if rootCAs == nil {
+			rootCAs = x509.NewCertPool()
+		}
+		rootCAs.AppendCertsFromPEM(ep.CaBundle)
+		tr.TLSClientConfig.RootCAs = rootCAs
+	}
+	if ep.InsecureSkipTLS {
+		tr.TLSClientConfig.InsecureSkipVerify = true
+	}
+	if ep.Proxy.URL != "" {
+		proxyURL, err := ep.Proxy.FullURL()
+		if err != nil {
+			return err
+		}
+		tr.Proxy = http.ProxyURL(proxyURL)
+	}
+	return nil
+}
+
+// headerRoundTripper sets the given headers on the requests, replacing the
+// ones set by the go-git HTTP client, like its user agent.
+type headerRoundTripper struct {
+	next   http.RoundTripper
+	header http.Header
+}
+
+func (rt *headerRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for k, v := range rt.header {
+		req.Header[k] = v
+	}
+	return rt.next.RoundTrip(req)
+}
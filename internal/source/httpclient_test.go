@@ -0,0 +1,104 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/fluxcd/pkg/git"
+	"github.com/fluxcd/pkg/git/gogit"
+	"github.com/fluxcd/pkg/git/repository"
+)
+
+func TestInstallHTTPClient_headers(t *testing.T) {
+	var userAgents, teams []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgents = append(userAgents, r.Header.Values("User-Agent")...)
+		teams = append(teams, r.Header.Values("X-Team")...)
+		http.NotFound(w, r)
+	})
+
+	tests := []struct {
+		name          string
+		opts          HTTPOptions
+		tls           bool
+		wantUserAgent string
+		wantTeam      []string
+	}{
+		{
+			name:          "default user agent",
+			wantUserAgent: DefaultUserAgent,
+		},
+		{
+			name: "user agent and headers",
+			opts: HTTPOptions{
+				UserAgent: "image-automation-controller/v1.0.0",
+				Headers:   map[string]string{"x-team": "platform"},
+			},
+			wantUserAgent: "image-automation-controller/v1.0.0",
+			wantTeam:      []string{"platform"},
+		},
+		{
+			name: "with CA bundle",
+			opts: HTTPOptions{
+				Headers: map[string]string{"X-Team": "platform"},
+			},
+			tls:           true,
+			wantUserAgent: DefaultUserAgent,
+			wantTeam:      []string{"platform"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			userAgents, teams = nil, nil
+
+			authOpts := &git.AuthOptions{Transport: git.HTTP}
+			var srv *httptest.Server
+			if tt.tls {
+				srv = httptest.NewTLSServer(handler)
+				authOpts.Transport = git.HTTPS
+				authOpts.CAFile = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+			} else {
+				srv = httptest.NewServer(handler)
+			}
+			defer srv.Close()
+
+			InstallHTTPClient(tt.opts)
+			defer InstallHTTPClient(HTTPOptions{FollowRedirects: true})
+
+			c, err := gogit.NewClient(t.TempDir(), authOpts, gogit.WithDiskStorage(), gogit.WithInsecureCredentialsOverHTTP())
+			g.Expect(err).ToNot(HaveOccurred())
+			defer c.Close()
+			_, err = c.Clone(context.TODO(), srv.URL+"/org/repo", repository.CloneConfig{
+				CheckoutStrategy: repository.CheckoutStrategy{Branch: "main"},
+			})
+			// The server doesn't serve the repository, but got the request.
+			g.Expect(err).To(HaveOccurred())
+			g.Expect(err.Error()).ToNot(ContainSubstring("certificate"))
+
+			g.Expect(userAgents).To(Equal([]string{tt.wantUserAgent}))
+			g.Expect(teams).To(Equal(tt.wantTeam))
+		})
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+// PushTargetOf returns the push target of the given ImageUpdateAutomation,
+// like PushTarget of its SourceManager, from its GitRepositories only,
+// without reading any secret. It's meant to compare the automations before
+// they're reconciled, e.g. at admission.
+func PushTargetOf(ctx context.Context, c client.Client, obj *imagev1.ImageUpdateAutomation) (string, string, error) {
+	if obj.Spec.SourceRef.Kind != sourcev1.GitRepositoryKind {
+		return "", "", fmt.Errorf("source kind '%s' not supported: %w", obj.Spec.SourceRef.Kind, ErrInvalidSourceConfiguration)
+	}
+	gitSpec := obj.Spec.GitSpec
+	if gitSpec == nil {
+		return "", "", fmt.Errorf("source kind '%s' necessitates field .spec.git: %w", sourcev1.GitRepositoryKind, ErrInvalidSourceConfiguration)
+	}
+
+	srcKey, err := SourceKey(ctx, c, obj)
+	if err != nil {
+		return "", "", err
+	}
+	repo := &sourcev1.GitRepository{}
+	if err := c.Get(ctx, srcKey, repo); err != nil {
+		return "", "", fmt.Errorf("failed to get the git repository '%s': %w", srcKey, err)
+	}
+	cfg := &gitSrcCfg{srcKey: srcKey, url: repo.Spec.URL}
+	checkoutRef := repo.Spec.Reference
+	if gitSpec.Checkout.HasReference() {
+		checkoutRef = &gitSpec.Checkout.Reference
+	}
+	if len(obj.Spec.Routes) > 0 {
+		err = configureRoutes(cfg, gitSpec, obj.Spec.Routes, checkoutRef)
+	} else {
+		err = configurePush(cfg, gitSpec, checkoutRef)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if gitSpec.Push != nil && gitSpec.Push.SourceRef != nil {
+		key, err := sourceKey(ctx, c, obj.Namespace, *gitSpec.Push.SourceRef)
+		if err != nil {
+			return "", "", err
+		}
+		pushRepo := &sourcev1.GitRepository{}
+		if err := c.Get(ctx, key, pushRepo); err != nil {
+			return "", "", fmt.Errorf("failed to get the push git repository '%s': %w", key, err)
+		}
+		cfg.writeTarget = &writeTarget{key: key, url: pushRepo.Spec.URL}
+	}
+	url, branch := cfg.pushTarget()
+	return url, branch, nil
+}
+
+// pushTarget returns the URL of the repository the commits are pushed to,
+// without any trailing slash or .git suffix, and the branch they're pushed
+// to.
+func (cfg *gitSrcCfg) pushTarget() (string, string) {
+	url, _, _ := cfg.pushEndpoint()
+	url = strings.TrimSuffix(strings.TrimSuffix(url, "/"), ".git")
+	return url, cfg.pushBranch
+}
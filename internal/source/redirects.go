@@ -0,0 +1,62 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// infoRefsPath is the path of the first request of the Git operations over
+// HTTP, which is redirected when the repository moved.
+const infoRefsPath = "/info/refs"
+
+// RepositoryMovedError is the error of a Git operation with a remote
+// repository over HTTP which redirected it to another URL, e.g. because the
+// repository was renamed.
+type RepositoryMovedError struct {
+	// URL is the URL the repository was redirected to.
+	URL string
+}
+
+// Error implements error.
+func (e *RepositoryMovedError) Error() string {
+	return fmt.Sprintf("repository moved to '%s', the URL of the GitRepository must be updated", e.URL)
+}
+
+// IsRepositoryMoved returns whether the error is a RepositoryMovedError, and
+// the URL the repository moved to if it is.
+func IsRepositoryMoved(err error) (string, bool) {
+	var movedErr *RepositoryMovedError
+	if errors.As(err, &movedErr) {
+		return movedErr.URL, true
+	}
+	return "", false
+}
+
+// checkRepositoryMoved rejects the redirects of the requests to the remote
+// repositories.
+func checkRepositoryMoved(req *http.Request, _ []*http.Request) error {
+	u := *req.URL
+	u.RawQuery = ""
+	u.Path = strings.TrimSuffix(u.Path, infoRefsPath)
+	u.RawPath = ""
+	u.User = nil
+	return &RepositoryMovedError{URL: u.String()}
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/fluxcd/pkg/git"
+	"github.com/fluxcd/pkg/git/gogit"
+	"github.com/fluxcd/pkg/git/repository"
+)
+
+func TestInstallHTTPClient_redirects(t *testing.T) {
+	g := NewWithT(t)
+
+	var redirected bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/org/old-name/info/refs" {
+			http.Redirect(w, r, "/org/new-name/info/refs?"+r.URL.RawQuery, http.StatusMovedPermanently)
+			return
+		}
+		redirected = true
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	clone := func() error {
+		c, err := gogit.NewClient(t.TempDir(), &git.AuthOptions{Transport: git.HTTP},
+			gogit.WithDiskStorage(), gogit.WithInsecureCredentialsOverHTTP())
+		g.Expect(err).ToNot(HaveOccurred())
+		defer c.Close()
+		_, err = c.Clone(context.TODO(), srv.URL+"/org/old-name", repository.CloneConfig{
+			CheckoutStrategy: repository.CheckoutStrategy{Branch: "main"},
+		})
+		return err
+	}
+
+	// The redirect fails the operation with the new URL.
+	InstallHTTPClient(HTTPOptions{})
+	defer InstallHTTPClient(HTTPOptions{FollowRedirects: true})
+	err := clone()
+	url, ok := IsRepositoryMoved(err)
+	g.Expect(ok).To(BeTrue(), "unexpected error: %v", err)
+	g.Expect(url).To(Equal(srv.URL + "/org/new-name"))
+	g.Expect(err.Error()).To(ContainSubstring("repository moved to '" + srv.URL + "/org/new-name'"))
+	g.Expect(ClassOf(classifyGitError(GitOperationPush, err))).To(BeEmpty())
+	g.Expect(redirected).To(BeFalse())
+
+	// The redirect is followed.
+	InstallHTTPClient(HTTPOptions{FollowRedirects: true})
+	err = clone()
+	g.Expect(err).To(HaveOccurred())
+	_, ok = IsRepositoryMoved(err)
+	g.Expect(ok).To(BeFalse())
+	g.Expect(redirected).To(BeTrue())
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	extgogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fluxcd/pkg/git"
+	"github.com/fluxcd/pkg/ssh/knownhosts"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+// additionalRemote is a remote the pushed commits are also pushed to.
+type additionalRemote struct {
+	name     string
+	url      string
+	authOpts *git.AuthOptions
+	// err is the error of the configuration of the remote, e.g. a missing
+	// Secret, reported as the error of its pushes rather than failing the
+	// reconciliation.
+	err error
+}
+
+// getAdditionalRemotes returns the additional remotes of the push
+// configuration, with their authentication options read from the Secrets of
+// the namespace.
+func getAdditionalRemotes(ctx context.Context, c client.Client, namespace string, push *imagev1.PushSpec) []additionalRemote {
+	if push == nil {
+		return nil
+	}
+	remotes := make([]additionalRemote, 0, len(push.AdditionalRemotes))
+	for _, spec := range push.AdditionalRemotes {
+		remote := additionalRemote{name: spec.Name, url: spec.URL}
+		remote.authOpts, remote.err = getRemoteAuthOpts(ctx, c, namespace, spec)
+		remotes = append(remotes, remote)
+	}
+	return remotes
+}
+
+// getRemoteAuthOpts returns the authentication options of the additional
+// remote.
+func getRemoteAuthOpts(ctx context.Context, c client.Client, namespace string, spec imagev1.AdditionalRemote) (*git.AuthOptions, error) {
+	var data map[string][]byte
+	if spec.SecretRef != nil {
+		var err error
+		if data, err = getSecretData(ctx, c, spec.SecretRef.Name, namespace); err != nil {
+			return nil, fmt.Errorf("failed to get auth secret '%s/%s': %w", namespace, spec.SecretRef.Name, err)
+		}
+	}
+	u, err := url.Parse(spec.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse URL '%s': %w", spec.URL, err)
+	}
+	opts, err := git.NewAuthOptions(*u, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure authentication options: %w", err)
+	}
+	if spec.SecretRef != nil {
+		secretKey := types.NamespacedName{Namespace: namespace, Name: spec.SecretRef.Name}
+		if err := certifySSHIdentity(opts, data, secretKey, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	return opts, nil
+}
+
+// remoteTransportAuth returns the go-git authentication method of the given
+// options, like the Git client does for the origin.
+func remoteTransportAuth(opts *git.AuthOptions) (transport.AuthMethod, error) {
+	switch opts.Transport {
+	case git.HTTP, git.HTTPS:
+		if opts.Username != "" || opts.Password != "" {
+			return &http.BasicAuth{Username: opts.Username, Password: opts.Password}, nil
+		}
+		if opts.BearerToken != "" {
+			return &http.TokenAuth{Token: opts.BearerToken}, nil
+		}
+		return nil, nil
+	case git.SSH:
+		if len(opts.Identity) == 0 {
+			return nil, errors.New("an SSH identity is required")
+		}
+		pk, err := gitssh.NewPublicKeys(opts.Username, opts.Identity, opts.Password)
+		if err != nil {
+			return nil, err
+		}
+		if len(opts.KnownHosts) > 0 {
+			if pk.HostKeyCallback, err = knownhosts.New(opts.KnownHosts); err != nil {
+				return nil, err
+			}
+		}
+		return pk, nil
+	default:
+		return nil, fmt.Errorf("unknown transport '%s'", opts.Transport)
+	}
+}
+
+// RemotePushResult is the result of a push to an additional remote.
+type RemotePushResult struct {
+	// Name is the name of the additional remote.
+	Name string
+	// Err is the error of the push, if it failed.
+	Err error
+}
+
+// pushAdditionalRemotes pushes the given refspecs to all the additional
+// remotes, and returns the result of each push. The pushes are independent,
+// a failure to push to a remote doesn't prevent pushing to the others.
+func (sm SourceManager) pushAdditionalRemotes(ctx context.Context, refspecs []string, force bool,
+	options map[string]string) []RemotePushResult {
+	if len(sm.srcCfg.additionalRemotes) == 0 {
+		return nil
+	}
+	results := make([]RemotePushResult, 0, len(sm.srcCfg.additionalRemotes))
+	for _, remote := range sm.srcCfg.additionalRemotes {
+		err := remote.err
+		if err == nil {
+			pushCtx, cancel := sm.gitOperationContext(ctx)
+			err = sm.deadlineError(pushCtx, GitOperationPush, sm.pushRemote(pushCtx, remote, refspecs, force, options))
+			cancel()
+		}
+		results = append(results, RemotePushResult{Name: remote.name, Err: err})
+	}
+	return results
+}
+
+// pushRemote pushes the given refspecs to the additional remote.
+func (sm SourceManager) pushRemote(ctx context.Context, remote additionalRemote, refspecs []string, force bool,
+	options map[string]string) error {
+	repo, err := sm.openRepository()
+	if err != nil {
+		return err
+	}
+	auth, err := remoteTransportAuth(remote.authOpts)
+	if err != nil {
+		return fmt.Errorf("failed to construct auth method with options: %w", err)
+	}
+	specs := make([]config.RefSpec, 0, len(refspecs))
+	for _, refspec := range refspecs {
+		specs = append(specs, config.RefSpec(refspec))
+	}
+	// The remote isn't stored in the configuration of the repository, to not
+	// be used by any other operation.
+	r := extgogit.NewRemote(repo.Storer, &config.RemoteConfig{
+		Name: remote.name,
+		URLs: []string{remote.url},
+	})
+	err = r.PushContext(ctx, &extgogit.PushOptions{
+		RemoteName: remote.name,
+		RefSpecs:   specs,
+		Force:      force,
+		Auth:       auth,
+		CABundle:   remote.authOpts.CAFile,
+		Options:    options,
+	})
+	if err != nil && !errors.Is(err, extgogit.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("failed to push to remote '%s': %w", remote.url, err)
+	}
+	return nil
+}
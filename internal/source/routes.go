@@ -0,0 +1,75 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"fmt"
+
+	"github.com/fluxcd/pkg/git"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+// configureRoutes configures the pushes of the given routes, each to a branch
+// of its own checked out with CheckoutRouteBranch, instead of the push
+// configured in the GitSpec.
+func configureRoutes(cfg *gitSrcCfg, gitSpec *imagev1.GitSpec, routes []imagev1.Route, checkoutRef *sourcev1.GitRepositoryRef) error {
+	if push := gitSpec.Push; push != nil &&
+		(push.Branch != "" || push.Refspec != "" || push.Base != "" || push.PerPolicyBranches || push.API != nil) {
+		return fmt.Errorf("routes can't be used with a push branch, a refspec, a base branch, per-policy branches or the provider API: %w",
+			ErrInvalidSourceConfiguration)
+	}
+	if isPinnedRef(checkoutRef) {
+		return fmt.Errorf("routes can't be used to check out a tag or a commit: %w", ErrInvalidSourceConfiguration)
+	}
+	checkoutBranch := git.DefaultBranch
+	if checkoutRef != nil {
+		checkoutBranch = checkoutRef.Branch
+	}
+	branches := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		branch := route.Push.Branch
+		switch {
+		case branch == "":
+			return fmt.Errorf("branch of route '%s' must be set: %w", route.Name, ErrInvalidSourceConfiguration)
+		case branch == checkoutBranch:
+			return fmt.Errorf("branch '%s' of route '%s' must differ from the checkout branch: %w", branch, route.Name, ErrInvalidSourceConfiguration)
+		case branches[branch]:
+			return fmt.Errorf("branch '%s' is pushed to by several routes: %w", branch, ErrInvalidSourceConfiguration)
+		}
+		branches[branch] = true
+	}
+	cfg.routes = true
+	cfg.switchBranch = true
+	return nil
+}
+
+// Routes returns whether the changes are pushed by the routes of the
+// ImageUpdateAutomation, to the branch of each route.
+func (sm SourceManager) Routes() bool {
+	return sm.srcCfg.routes
+}
+
+// CheckoutRouteBranch discards any change in the worktree and checks out the
+// given branch of a route at its head on the remote, or at the given commit
+// when the branch doesn't exist on the remote, or when the heads of the
+// remote branches weren't fetched. The following commits are pushed to the
+// branch.
+func (sm *SourceManager) CheckoutRouteBranch(branch string, base *git.Commit) error {
+	return sm.checkoutPushBranch(branch, base)
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// ErrSigningRequired is the error of pushing unsigned commits when the
+// commits must be signed.
+var ErrSigningRequired = errors.New("signed commits required")
+
+// SigningError is an error of the signing key of the commits, e.g. a missing
+// Secret or a key which can't be decrypted. Its message is the message of the
+// wrapped error.
+type SigningError struct {
+	Err error
+}
+
+// Error implements error.
+func (e *SigningError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error of the signing key.
+func (e *SigningError) Unwrap() error {
+	return e.Err
+}
+
+// IsSigningError returns whether the error is a SigningError.
+func IsSigningError(err error) bool {
+	var signingErr *SigningError
+	return errors.As(err, &signingErr)
+}
+
+// SigningKeyFingerprint returns the upper case hexadecimal fingerprint of the
+// primary key of the signing key, or an empty string if the commits aren't
+// signed.
+func (sm SourceManager) SigningKeyFingerprint() string {
+	if sm.srcCfg.signingEntity == nil {
+		return ""
+	}
+	return strings.ToUpper(fmt.Sprintf("%x", sm.srcCfg.signingEntity.PrimaryKey.Fingerprint))
+}
+
+// VerifyCommitSignature verifies the signature of the commit with the given
+// revision in the local repository, with the public keys the GitRepository
+// verifies the commits with if any, or with the public key of the signing
+// key otherwise.
+func (sm SourceManager) VerifyCommitSignature(rev string) error {
+	repo, err := sm.openRepository()
+	if err != nil {
+		return err
+	}
+	commit, err := repo.CommitObject(plumbing.NewHash(rev))
+	if err != nil {
+		return fmt.Errorf("failed to read commit '%s': %w", rev, err)
+	}
+	return sm.verifySignature(commit, time.Now())
+}
+
+// SelfTestSigning signs a test commit, which isn't stored, with the signing
+// key and verifies its signature like VerifyCommitSignature, to check the
+// signing configuration without pushing anything.
+func (sm SourceManager) SelfTestSigning() error {
+	now := time.Now()
+	if err := sm.checkSigningKey(now); err != nil {
+		return err
+	}
+	signature := object.Signature{Name: "Flux", Email: "flux@localhost", When: now}
+	commit := &object.Commit{
+		Author:    signature,
+		Committer: signature,
+		Message:   "Signing self-test",
+		TreeHash:  plumbing.ZeroHash,
+	}
+	encoded := &plumbing.MemoryObject{}
+	if err := commit.EncodeWithoutSignature(encoded); err != nil {
+		return err
+	}
+	r, err := encoded.Reader()
+	if err != nil {
+		return err
+	}
+	var sig bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&sig, sm.srcCfg.signingEntity, r, nil); err != nil {
+		return fmt.Errorf("failed to sign test commit: %w", err)
+	}
+	commit.PGPSignature = sig.String()
+	return sm.verifySignature(commit, now)
+}
+
+// checkSigningKey checks that the signing key can sign at the given time.
+func (sm SourceManager) checkSigningKey(now time.Time) error {
+	if sm.srcCfg.signingEntity == nil {
+		return errors.New("no signing key configured")
+	}
+	if _, ok := sm.srcCfg.signingEntity.SigningKey(now); !ok {
+		return fmt.Errorf("signing key %s has no valid signing key, it may have expired or been revoked",
+			sm.SigningKeyFingerprint())
+	}
+	return nil
+}
+
+// verifySignature verifies the signature of the commit at the given time.
+func (sm SourceManager) verifySignature(commit *object.Commit, now time.Time) error {
+	if err := sm.checkSigningKey(now); err != nil {
+		return err
+	}
+	entity := sm.srcCfg.signingEntity
+	if commit.PGPSignature == "" {
+		return fmt.Errorf("commit '%s' isn't signed", commit.Hash)
+	}
+
+	keyRings := sm.srcCfg.verificationKeyRings
+	if len(keyRings) == 0 {
+		keyRing, err := armoredPublicKeyRing(entity)
+		if err != nil {
+			return err
+		}
+		keyRings = []string{keyRing}
+	}
+	var errs []error
+	for _, keyRing := range keyRings {
+		_, err := commit.Verify(keyRing)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+	if len(sm.srcCfg.verificationKeyRings) > 0 {
+		return fmt.Errorf("signature of signing key %s can't be verified with the verification keys of the GitRepository: %w",
+			sm.SigningKeyFingerprint(), errors.Join(errs...))
+	}
+	return fmt.Errorf("signature can't be verified with the public key of signing key %s: %w",
+		sm.SigningKeyFingerprint(), errors.Join(errs...))
+}
+
+// armoredPublicKeyRing returns the armored public key of the entity.
+func armoredPublicKeyRing(entity *openpgp.Entity) (string, error) {
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return "", err
+	}
+	if err := entity.Serialize(w); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	extgogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	. "github.com/onsi/gomega"
+
+	"github.com/fluxcd/image-automation-controller/internal/testutil"
+)
+
+func TestSourceManager_SelfTestSigning(t *testing.T) {
+	g := NewWithT(t)
+
+	signer, _ := testutil.GetSigningKeyPair(g, "")
+	other, _ := testutil.GetSigningKeyPair(g, "")
+
+	past := time.Now().Add(-48 * time.Hour)
+	expired, err := openpgp.NewEntity("expired", "", "expired@example.com", &packet.Config{
+		Time:            func() time.Time { return past },
+		KeyLifetimeSecs: 60,
+	})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	tests := []struct {
+		name         string
+		entity       *openpgp.Entity
+		keyRings     []string
+		wantErr      string
+		wantVerified bool
+	}{
+		{
+			name:    "no signing key",
+			wantErr: "no signing key configured",
+		},
+		{
+			name:         "verified with the signing key",
+			entity:       signer,
+			wantVerified: true,
+		},
+		{
+			name:         "verified with the verification keys",
+			entity:       signer,
+			keyRings:     []string{string(armoredPublicKey(g, other)), string(armoredPublicKey(g, signer))},
+			wantVerified: true,
+		},
+		{
+			name:     "not in the verification keys",
+			entity:   signer,
+			keyRings: []string{string(armoredPublicKey(g, other))},
+			wantErr:  "can't be verified with the verification keys of the GitRepository",
+		},
+		{
+			name:    "expired signing key",
+			entity:  expired,
+			wantErr: "may have expired or been revoked",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			sm := SourceManager{srcCfg: &gitSrcCfg{
+				signingEntity:        tt.entity,
+				verificationKeyRings: tt.keyRings,
+			}}
+			err := sm.SelfTestSigning()
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(sm.SigningKeyFingerprint()).To(HaveLen(40))
+		})
+	}
+}
+
+func TestSourceManager_VerifyCommitSignature(t *testing.T) {
+	g := NewWithT(t)
+
+	signer, _ := testutil.GetSigningKeyPair(g, "")
+	other, _ := testutil.GetSigningKeyPair(g, "")
+
+	dir := t.TempDir()
+	repo, err := extgogit.PlainInit(dir, false)
+	g.Expect(err).ToNot(HaveOccurred())
+	wt, err := repo.Worktree()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(os.WriteFile(filepath.Join(dir, "file"), []byte("test"), 0o600)).To(Succeed())
+	_, err = wt.Add("file")
+	g.Expect(err).ToNot(HaveOccurred())
+	sig := &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}
+	signed, err := wt.Commit("signed", &extgogit.CommitOptions{Author: sig, SignKey: signer})
+	g.Expect(err).ToNot(HaveOccurred())
+	unsigned, err := wt.Commit("unsigned", &extgogit.CommitOptions{Author: sig, AllowEmptyCommits: true})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	sm := SourceManager{workingDir: dir, srcCfg: &gitSrcCfg{signingEntity: signer}}
+	g.Expect(sm.VerifyCommitSignature(signed.String())).To(Succeed())
+	g.Expect(sm.VerifyCommitSignature(unsigned.String())).To(MatchError(ContainSubstring("isn't signed")))
+
+	sm.srcCfg.signingEntity = other
+	g.Expect(sm.VerifyCommitSignature(signed.String())).To(MatchError(ContainSubstring("public key of signing key")))
+}
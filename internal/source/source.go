@@ -20,17 +20,28 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"text/template"
 	"time"
+	"unicode/utf8"
 
 	"github.com/Masterminds/sprig/v3"
 	"github.com/fluxcd/pkg/git"
 	"github.com/fluxcd/pkg/git/gogit"
 	"github.com/fluxcd/pkg/git/repository"
 	"github.com/fluxcd/pkg/runtime/acl"
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/osfs"
+	extgogit "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage"
+	"github.com/go-git/go-git/v5/storage/memory"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -40,22 +51,34 @@ import (
 	sourcev1 "github.com/fluxcd/source-controller/api/v1"
 
 	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+	templatesv1 "github.com/fluxcd/image-automation-controller/pkg/templates/v1"
 	"github.com/fluxcd/image-automation-controller/pkg/update"
 )
 
 // ErrInvalidSourceConfiguration is an error for invalid source configuration.
 var ErrInvalidSourceConfiguration = errors.New("invalid source configuration")
 
-const defaultMessageTemplate = `Update from image update automation`
+// ErrSourceVerificationFailed is an error for a checked out commit, or the tag
+// referring to it, whose signature can't be verified with the verification
+// configuration of the GitRepository.
+var ErrSourceVerificationFailed = errors.New("source verification failed")
 
-// TemplateData is the type of the value given to the commit message
-// template.
-type TemplateData struct {
-	AutomationObject types.NamespacedName
-	Updated          update.Result
-	Changed          update.ResultV2
-	Values           map[string]string
-}
+// ErrSourceSuspended is an error for a GitRepository which is suspended.
+var ErrSourceSuspended = errors.New("source is suspended")
+
+// ErrSourceNotReady is an error for a GitRepository whose Ready condition is
+// False, e.g. because it can't be fetched.
+var ErrSourceNotReady = errors.New("source is not ready")
+
+// ErrWorktreeTooLarge is an error for a checked out source whose size exceeds
+// the maximum size of the SourceManager.
+var ErrWorktreeTooLarge = errors.New("worktree too large")
+
+// ErrInvalidTemplate is an error for a commit message or tag name template
+// which can't be rendered.
+var ErrInvalidTemplate = errors.New("invalid template")
+
+const defaultMessageTemplate = `Update from image update automation`
 
 // SourceManager manages source.
 type SourceManager struct {
@@ -63,12 +86,60 @@ type SourceManager struct {
 	automationObjKey types.NamespacedName
 	gitClient        *gogit.Client
 	workingDir       string
+	inMemory         bool
+	cloneCache       *CloneCache
+	cacheEntry       *cacheEntry
+	// storer and workTree are set when the repository isn't entirely
+	// stored in the working directory on disk.
+	storer   storage.Storer
+	workTree billy.Filesystem
+	// faultInjector, if set, injects synthetic failures in the pushes.
+	faultInjector *FaultInjector
+	// checkoutRevision is the revision checked out by CheckoutSource.
+	checkoutRevision string
+	// pushBranchHead is the head of the push branch on the remote at the
+	// checkout, empty if the branch didn't exist, when pushBranchHeadKnown.
+	pushBranchHead      string
+	pushBranchHeadKnown bool
+	// maxWorktreeSize is the maximum size of the checked out source, in
+	// bytes, if not zero.
+	maxWorktreeSize int64
+	// imageRepositories are the ImageRepositories of the policies given to
+	// the templates, by name of the policy.
+	imageRepositories map[string]templatesv1.ImageRepositoryData
+	// storedBeforeClone are the objects stored before the last clone, with
+	// a reused clone cache, and cloneStats the statistics of the clone.
+	storedBeforeClone storedObjects
+	cloneStats        *imagev1.CloneStats
+	// cloneDuration is the duration of the last clone, including the clones
+	// fetching nothing.
+	cloneDuration time.Duration
+	// updatePath is the update path rendered by UpdatePath.
+	updatePath *string
+	// commitMessage, if set, is the message of the commits instead of the
+	// rendered message template.
+	commitMessage string
+	// commitTime, if not zero, is the date of the commits instead of the
+	// time they're made, and commitLocation, if set, their timezone.
+	commitTime     time.Time
+	commitLocation *time.Location
 }
 
 // SourceOptions contains the optional attributes of SourceManager.
 type SourceOptions struct {
 	noCrossNamespaceRef    bool
 	gitAllBranchReferences bool
+	cloneCache             *CloneCache
+	inMemory               bool
+	faultInjector          *FaultInjector
+	workingDir             string
+	maxWorktreeSize        int64
+	imageRepositories      map[string]templatesv1.ImageRepositoryData
+	requireSignedCommits   bool
+	commitMessage          string
+	commitTime             time.Time
+	// routes are the routes of the ImageUpdateAutomation.
+	routes []imagev1.Route
 }
 
 // SourceOption configures the SourceManager options.
@@ -90,6 +161,90 @@ func WithSourceOptionGitAllBranchReferences() SourceOption {
 	}
 }
 
+// WithSourceOptionCloneCache configures the SourceManager to clone the source
+// using the given CloneCache.
+func WithSourceOptionCloneCache(c *CloneCache) SourceOption {
+	return func(so *SourceOptions) {
+		so.cloneCache = c
+	}
+}
+
+// WithSourceOptionFaultInjector configures the SourceManager to inject the
+// faults of the given FaultInjector in its pushes.
+func WithSourceOptionFaultInjector(fi *FaultInjector) SourceOption {
+	return func(so *SourceOptions) {
+		so.faultInjector = fi
+	}
+}
+
+// WithSourceOptionWorkingDir configures the SourceManager to check out the
+// source in a directory named after the UID of the automation in the given
+// directory, instead of in the default directory for temporary files.
+func WithSourceOptionWorkingDir(dir string) SourceOption {
+	return func(so *SourceOptions) {
+		so.workingDir = dir
+	}
+}
+
+// WithSourceOptionMaxWorktreeSize configures the SourceManager to fail the
+// checkout of a source whose size exceeds the given number of bytes, with an
+// error wrapping ErrWorktreeTooLarge. The clone is aborted as soon as it
+// writes more bytes.
+func WithSourceOptionMaxWorktreeSize(size int64) SourceOption {
+	return func(so *SourceOptions) {
+		so.maxWorktreeSize = size
+	}
+}
+
+// WithSourceOptionRequireSignedCommits configures the SourceManager to refuse
+// to push unsigned commits. Building the SourceManager of an automation
+// without signing key fails with an error wrapping ErrSigningRequired.
+func WithSourceOptionRequireSignedCommits() SourceOption {
+	return func(so *SourceOptions) {
+		so.requireSignedCommits = true
+	}
+}
+
+// WithSourceOptionInMemory configures the SourceManager to check out the
+// source in memory instead of in a temporary directory on disk. The working
+// directory is then the root of the in-memory worktree, see WorkTree.
+func WithSourceOptionInMemory() SourceOption {
+	return func(so *SourceOptions) {
+		so.inMemory = true
+	}
+}
+
+// WithSourceOptionImageRepositories sets the ImageRepositories of the
+// policies, by name of the policy, given to the templates.
+func WithSourceOptionImageRepositories(repos map[string]templatesv1.ImageRepositoryData) SourceOption {
+	return func(so *SourceOptions) {
+		so.imageRepositories = repos
+	}
+}
+
+// WithSourceOptionCommitMessage configures the SourceManager to make the
+// commits with the given message instead of the commit message template of
+// the automation, e.g. for a revert.
+func WithSourceOptionCommitMessage(message string) SourceOption {
+	return func(so *SourceOptions) {
+		so.commitMessage = message
+	}
+}
+
+// WithSourceOptionCommitTime configures the SourceManager to date the commits
+// with the given time instead of the time they're made.
+func WithSourceOptionCommitTime(t time.Time) SourceOption {
+	return func(so *SourceOptions) {
+		so.commitTime = t
+	}
+}
+
+// memoryClientPath is the path given to the Git client when the source is
+// checked out in memory. The client only uses its path on disk to reset the
+// clone of an empty repository, which must not touch the disk in this mode;
+// nothing can exist under /dev/null, so the reset fails instead.
+const memoryClientPath = "/dev/null/in-memory"
+
 // NewSourceManager takes all the provided inputs, validates them and returns a
 // SourceManager which can be used to operate on the configured source.
 func NewSourceManager(ctx context.Context, c client.Client, obj *imagev1.ImageUpdateAutomation, options ...SourceOption) (*SourceManager, error) {
@@ -107,28 +262,38 @@ func NewSourceManager(ctx context.Context, c client.Client, obj *imagev1.ImageUp
 		return nil, fmt.Errorf("source kind '%s' necessitates field .spec.git: %w", sourcev1.GitRepositoryKind, ErrInvalidSourceConfiguration)
 	}
 
+	if err := obj.Spec.GitSpec.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSourceConfiguration, err)
+	}
+	var commitLocation *time.Location
+	if tz := obj.Spec.GitSpec.Commit.Timezone; tz != "" {
+		var err error
+		if commitLocation, err = time.LoadLocation(tz); err != nil {
+			return nil, fmt.Errorf("%w: invalid commit timezone: %w", ErrInvalidSourceConfiguration, err)
+		}
+	}
+
 	// Build source reference configuration to fetch and validate it.
 	srcNamespace := obj.GetNamespace()
 	if obj.Spec.SourceRef.Namespace != "" {
 		srcNamespace = obj.Spec.SourceRef.Namespace
 	}
 
-	// srcKey is the GitRepository object key.
-	srcKey := types.NamespacedName{Name: obj.Spec.SourceRef.Name, Namespace: srcNamespace}
-	// originKey is the update automation object key.
-	originKey := client.ObjectKeyFromObject(obj)
-
 	// Check if the source is accessible.
-	if opts.noCrossNamespaceRef && srcKey.Namespace != obj.GetNamespace() {
-		return nil, acl.AccessDeniedError(fmt.Sprintf("can't access '%s/%s', cross-namespace references have been blocked", sourcev1.GitRepositoryKind, srcKey))
+	if opts.noCrossNamespaceRef && srcNamespace != obj.GetNamespace() {
+		return nil, acl.AccessDeniedError(fmt.Sprintf("can't access '%s', cross-namespace references have been blocked", obj.Spec.SourceRef.String()))
 	}
 
-	gitSrcCfg, err := buildGitConfig(ctx, c, originKey, srcKey, obj.Spec.GitSpec, *opts)
+	// srcKey is the GitRepository object key.
+	srcKey, err := SourceKey(ctx, c, obj)
 	if err != nil {
 		return nil, err
 	}
+	// originKey is the update automation object key.
+	originKey := client.ObjectKeyFromObject(obj)
 
-	workDir, err := os.MkdirTemp("", fmt.Sprintf("%s-%s", gitSrcCfg.srcKey.Namespace, gitSrcCfg.srcKey.Name))
+	opts.routes = obj.Spec.Routes
+	gitSrcCfg, err := buildGitConfig(ctx, c, originKey, srcKey, obj.Spec.GitSpec, *opts)
 	if err != nil {
 		return nil, err
 	}
@@ -136,21 +301,149 @@ func NewSourceManager(ctx context.Context, c client.Client, obj *imagev1.ImageUp
 	sm := &SourceManager{
 		srcCfg:           gitSrcCfg,
 		automationObjKey: originKey,
-		workingDir:       workDir,
+		inMemory:         opts.inMemory,
+		cloneCache:       opts.cloneCache,
+		faultInjector:    opts.faultInjector,
+		maxWorktreeSize:  opts.maxWorktreeSize,
+
+		imageRepositories: opts.imageRepositories,
+		commitMessage:     opts.commitMessage,
+		commitTime:        opts.commitTime,
+		commitLocation:    commitLocation,
+	}
+	if sm.inMemory {
+		sm.workingDir = string(filepath.Separator)
+		return sm, nil
+	}
+
+	sm.workingDir, err = makeWorkingDir(opts.workingDir, obj.GetUID())
+	if err != nil {
+		return nil, err
 	}
 	return sm, nil
 }
 
+// SourceKey returns the key of the GitRepository the given
+// ImageUpdateAutomation refers to, by name or by label selector. Selecting no
+// GitRepository is an error, and selecting several GitRepositories is an error
+// wrapping ErrInvalidSourceConfiguration.
+func SourceKey(ctx context.Context, c client.Client, obj *imagev1.ImageUpdateAutomation) (types.NamespacedName, error) {
+	return sourceKey(ctx, c, obj.GetNamespace(), obj.Spec.SourceRef)
+}
+
+// sourceKey returns the key of the GitRepository the given reference refers
+// to, from an object in the given namespace.
+func sourceKey(ctx context.Context, c client.Client, namespace string, ref imagev1.CrossNamespaceSourceReference) (types.NamespacedName, error) {
+	key := types.NamespacedName{Namespace: namespace, Name: ref.Name}
+	if ref.Namespace != "" {
+		key.Namespace = ref.Namespace
+	}
+	if ref.Selector == nil {
+		return key, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(ref.Selector)
+	if err != nil {
+		return key, fmt.Errorf("invalid source selector: %w: %w", err, ErrInvalidSourceConfiguration)
+	}
+	var repos sourcev1.GitRepositoryList
+	if err := c.List(ctx, &repos, client.InNamespace(key.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return key, fmt.Errorf("failed to list GitRepositories: %w", err)
+	}
+	switch len(repos.Items) {
+	case 0:
+		return key, fmt.Errorf("no GitRepository in namespace '%s' matches the selector '%s'", key.Namespace, selector)
+	case 1:
+		key.Name = repos.Items[0].Name
+		return key, nil
+	default:
+		names := make([]string, 0, len(repos.Items))
+		for _, repo := range repos.Items {
+			names = append(names, repo.Name)
+		}
+		slices.Sort(names)
+		return key, fmt.Errorf("multiple GitRepositories in namespace '%s' match the selector '%s': %s: %w",
+			key.Namespace, selector, strings.Join(names, ", "), ErrInvalidSourceConfiguration)
+	}
+}
+
+// SourceKey returns the key of the GitRepository of the SourceManager.
+func (sm SourceManager) SourceKey() types.NamespacedName {
+	return sm.srcCfg.srcKey
+}
+
+// CheckSourceReady returns an error wrapping ErrSourceSuspended when the
+// GitRepository is suspended, or ErrSourceNotReady with the message of its
+// Ready condition when the condition is False. A GitRepository which is
+// reconciling or hasn't been reconciled yet is considered ready.
+func (sm SourceManager) CheckSourceReady() error {
+	if sm.srcCfg.srcSuspended {
+		return fmt.Errorf("GitRepository '%s' is suspended: %w", sm.srcCfg.srcKey, ErrSourceSuspended)
+	}
+	if c := sm.srcCfg.srcNotReady; c != nil {
+		return fmt.Errorf("GitRepository '%s' is not ready: %s: %s: %w", sm.srcCfg.srcKey, c.Reason, c.Message, ErrSourceNotReady)
+	}
+	return nil
+}
+
 // CreateWorkingDirectory creates a working directory for the SourceManager.
 func (sm SourceManager) WorkDirectory() string {
 	return sm.workingDir
 }
 
-// Cleanup deletes the working directory of the SourceManager.
+// WorkTree returns the in-memory worktree the source is checked out in, or
+// nil if the source is checked out on disk.
+func (sm SourceManager) WorkTree() billy.Filesystem {
+	if !sm.inMemory {
+		return nil
+	}
+	return sm.workTree
+}
+
+// Cleanup deletes the working directory of the SourceManager, and releases
+// the clone cache if used.
 func (sm SourceManager) Cleanup() error {
+	if sm.cacheEntry != nil {
+		sm.cacheEntry.release()
+	}
+	if sm.inMemory {
+		return nil
+	}
+	defer releaseWorkingDir(sm.workingDir)
 	return os.RemoveAll(sm.workingDir)
 }
 
+// PushTarget returns the URL of the repository the SourceManager pushes to,
+// without any trailing slash or .git suffix, and the branch it pushes to. The
+// automations with the same push target push to the same branch.
+func (sm SourceManager) PushTarget() (string, string) {
+	return sm.srcCfg.pushTarget()
+}
+
+// AuthMethod returns the authentication method of the Git operations of the
+// SourceManager, e.g. "ssh-key: SHA256:...". It never contains any secret.
+func (sm SourceManager) AuthMethod() string {
+	if sm.srcCfg.fallbackUsed {
+		return describeAuthMethod(sm.srcCfg.fallbackAuthOpts)
+	}
+	return sm.srcCfg.authMethod
+}
+
+// PushCredentials returns the credentials the commits were pushed with,
+// Fallback once the remote rejected the credentials of the GitRepository.
+func (sm SourceManager) PushCredentials() imagev1.PushCredentials {
+	if sm.srcCfg.fallbackUsed {
+		return imagev1.PushCredentialsFallback
+	}
+	return imagev1.PushCredentialsPrimary
+}
+
+// RefspecOnly returns if the commits are only pushed with the refspec, and
+// not to the push branch.
+func (sm SourceManager) RefspecOnly() bool {
+	return sm.srcCfg.refspecOnly
+}
+
 // SwitchBranch returns if the checkout branch and push branch are different.
 func (sm SourceManager) SwitchBranch() bool {
 	return sm.srcCfg.switchBranch
@@ -193,26 +486,107 @@ func (sm *SourceManager) CheckoutSource(ctx context.Context, options ...Checkout
 		o(&cloneCfg)
 	}
 
-	var err error
-	sm.gitClient, err = gogit.NewClient(sm.workingDir, sm.srcCfg.authOpts, sm.srcCfg.clientOpts...)
-	if err != nil {
-		return nil, err
-	}
-
-	gitOpCtx, cancel := context.WithTimeout(ctx, sm.srcCfg.timeout.Duration)
+	gitOpCtx, cancel := sm.gitOperationContext(ctx)
 	defer cancel()
-	commit, err := sm.gitClient.Clone(gitOpCtx, sm.srcCfg.url, cloneCfg)
+	start := time.Now()
+	commit, err := sm.clone(gitOpCtx, cloneCfg)
 	if err != nil {
-		return nil, err
+		return nil, classifyGitError(GitOperationCheckout, sm.deadlineError(gitOpCtx, GitOperationCheckout, err))
 	}
-	if sm.srcCfg.switchBranch {
-		if err := sm.gitClient.SwitchBranch(gitOpCtx, sm.srcCfg.pushBranch); err != nil {
+	duration := time.Since(start)
+	sm.cloneDuration = duration
+	// go-git advertises the objects of all the local references as already
+	// present, including the ones of a reused clone cache.
+	log.FromContext(ctx).V(logger.DebugLevel).Info("cloned source", "url", sm.srcCfg.url,
+		"duration", duration.String(), "shallow", cloneCfg.ShallowClone,
+		"negotiated", sm.cacheEntry != nil && sm.cacheEntry.reused)
+	// Nothing is fetched when the remote didn't change since the last
+	// observed commit. The statistics are informative, failing to count the
+	// objects doesn't fail the checkout.
+	sm.cloneStats = &imagev1.CloneStats{
+		Duration: metav1.Duration{Duration: duration},
+		Shallow:  cloneCfg.ShallowClone,
+	}
+	if git.IsConcreteCommit(*commit) {
+		if stored, err := sm.storedObjects(); err == nil {
+			sm.cloneStats.Objects = stored.count - sm.storedBeforeClone.count
+			sm.cloneStats.Bytes = stored.bytes - sm.storedBeforeClone.bytes
+		} else {
+			log.FromContext(ctx).V(logger.DebugLevel).Info("failed to count the cloned objects", "error", err.Error())
+			sm.cloneStats = nil
+		}
+	}
+	// Verify the checked out commit before anything is committed on top of
+	// it. A partial commit means nothing changed since the last verified
+	// commit.
+	if git.IsConcreteCommit(*commit) {
+		if err := sm.verifyCommit(commit); err != nil {
+			return nil, err
+		}
+		if err := sm.checkWorktreeSize(); err != nil {
 			return nil, err
 		}
 	}
+	// The per-policy and route branches are checked out when the changes of
+	// each policy or route are made, and the base branch when the changes are
+	// merged into it.
+	if sm.srcCfg.switchBranch && !sm.srcCfg.perPolicyBranches && !sm.srcCfg.routes && sm.srcCfg.baseBranch == "" {
+		if err := sm.gitClient.SwitchBranch(gitOpCtx, sm.srcCfg.pushBranch); err != nil {
+			return nil, classifyGitError(GitOperationCheckout, err)
+		}
+	}
+	if err := sm.recordPushBranchHead(gitOpCtx, commit); err != nil {
+		return nil, err
+	}
+	sm.checkoutRevision = commit.String()
 	return commit, nil
 }
 
+// sourceData returns the description of the source for the templates.
+func (sm SourceManager) sourceData() templatesv1.SourceData {
+	return templatesv1.SourceData{
+		URL:      redactURL(sm.srcCfg.url),
+		Branch:   sm.srcCfg.pushBranch,
+		Revision: sm.checkoutRevision,
+	}
+}
+
+// redactURL returns the URL without its user information, which may contain
+// credentials. URLs which can't be parsed, like the SCP-like SSH addresses,
+// are returned as is.
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.User == nil {
+		return s
+	}
+	u.User = nil
+	return u.String()
+}
+
+// verifyCommit verifies the signatures of the given commit, or of the tag
+// referring to it, according to the verification mode of the GitRepository.
+// It's a no-op if the GitRepository has no verification configured.
+func (sm *SourceManager) verifyCommit(commit *git.Commit) error {
+	verification := sm.srcCfg.verification
+	if verification == nil {
+		return nil
+	}
+	if verification.VerifyTag() {
+		if commit.ReferencingTag == nil {
+			return fmt.Errorf("%w: no tag referring to commit '%s' to verify", ErrSourceVerificationFailed, commit.Hash.String())
+		}
+		if _, err := commit.ReferencingTag.Verify(sm.srcCfg.verificationKeyRings...); err != nil {
+			return fmt.Errorf("%w: %w", ErrSourceVerificationFailed, err)
+		}
+	}
+	if verification.VerifyHEAD() {
+		if _, err := commit.Verify(sm.srcCfg.verificationKeyRings...); err != nil {
+			return fmt.Errorf("%w: %w", ErrSourceVerificationFailed, err)
+		}
+	}
+	return nil
+}
+
 // PushConfig configures the options used in push operation.
 type PushConfig func(*repository.PushConfig)
 
@@ -231,43 +605,183 @@ func WithPushConfigOptions(opts map[string]string) PushConfig {
 	}
 }
 
+// clone clones the source into the working directory. With a clone cache,
+// the Git objects are stored in the cache instead of the working directory,
+// and a failed clone from a previously used cache is retried once with an
+// emptied cache, in case the cache was corrupt.
+func (sm *SourceManager) clone(ctx context.Context, cloneCfg repository.CloneConfig) (*git.Commit, error) {
+	if sm.cloneCache != nil && sm.cacheEntry == nil {
+		entry, err := sm.cloneCache.acquire(sm.srcCfg.srcKey)
+		if err != nil {
+			return nil, err
+		}
+		sm.cacheEntry = entry
+	}
+
+	for {
+		clientPath := sm.workingDir
+		clientOpts := append([]gogit.ClientOption{}, sm.srcCfg.clientOpts...)
+		limit := newCloneLimit(sm.maxWorktreeSize)
+		switch {
+		case sm.inMemory:
+			clientPath = memoryClientPath
+			sm.storer = memory.NewStorage()
+			sm.workTree = memfs.New()
+		case sm.cacheEntry != nil:
+			sm.workTree = osfs.New(sm.workingDir, osfs.WithBoundOS())
+		}
+		if sm.cacheEntry != nil {
+			sm.storer = sm.cacheEntry.storer
+		}
+		switch {
+		case sm.storer != nil:
+			clientOpts = append(clientOpts, gogit.WithStorer(limit.storer(sm.storer)), gogit.WithWorkTreeFS(limit.fs(sm.workTree)))
+		case limit != nil:
+			clientOpts = append(clientOpts, limit.diskStorage(sm.workingDir))
+		}
+
+		var err error
+		sm.gitClient, err = gogit.NewClient(clientPath, sm.srcCfg.authOpts, clientOpts...)
+		if err != nil {
+			return nil, err
+		}
+		// Only the objects fetched in addition to those of the clone cache
+		// are accounted for.
+		sm.storedBeforeClone = storedObjects{}
+		if sm.cacheEntry != nil && sm.cacheEntry.reused {
+			if sm.storedBeforeClone, err = countObjects(sm.storer); err != nil {
+				sm.storedBeforeClone = storedObjects{}
+			}
+		}
+		commit, err := sm.gitClient.Clone(ctx, sm.srcCfg.url, cloneCfg)
+		limit.stop()
+		// The error of the limit may not be wrapped by the Git client.
+		if limit.exceeded() {
+			return nil, limit.err()
+		}
+		if err == nil || sm.cacheEntry == nil || !sm.cacheEntry.reused || ctx.Err() != nil {
+			return commit, err
+		}
+
+		if err := sm.cacheEntry.reset(); err != nil {
+			return nil, err
+		}
+		if sm.inMemory {
+			continue
+		}
+		// Remove anything the failed clone checked out.
+		if err := os.RemoveAll(sm.workingDir); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(sm.workingDir, 0o700); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// openRepository opens the repository cloned in the working directory.
+func (sm SourceManager) openRepository() (*extgogit.Repository, error) {
+	if sm.storer != nil {
+		return extgogit.Open(sm.storer, sm.workTree)
+	}
+	return extgogit.PlainOpen(sm.workingDir)
+}
+
 // CommitAndPush performs a commit in the source and pushes it to the remote
 // repository.
 func (sm SourceManager) CommitAndPush(ctx context.Context, obj *imagev1.ImageUpdateAutomation, policyResult update.ResultV2, pushOptions ...PushConfig) (*PushResult, error) {
 	tracelog := log.FromContext(ctx).V(logger.TraceLevel)
 
 	// Make sure there were file changes that need to be committed.
-	if len(policyResult.FileChanges) == 0 {
+	if !policyResult.HasChanges() {
 		return nil, nil
 	}
 
 	// Perform a Git commit.
-	templateValues := &TemplateData{
+	templateValues := &templatesv1.TemplateData{
 		AutomationObject: sm.automationObjKey,
 		Updated:          policyResult.ImageResult,
 		Changed:          policyResult,
-		Values:           obj.Spec.GitSpec.Commit.MessageTemplateValues,
+		Values:           sm.srcCfg.templateValues,
+		Source:           sm.sourceData(),
+
+		ImageRepositories: sm.imageRepositories,
+	}
+	commitMsg := sm.commitMessage
+	var err error
+	if commitMsg == "" {
+		if commitMsg, err = templateMsg(obj.Spec.GitSpec.Commit.MessageTemplate, templateValues); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidTemplate, err)
+		}
+	}
+	// Keep the message within the limits of the git server.
+	commitMsg, truncatedBytes := truncateMessage(commitMsg, obj.Spec.GitSpec.Commit.MaxMessageBytes)
+	if truncatedBytes > 0 {
+		log.FromContext(ctx).Info("truncated commit message", "omittedBytes", truncatedBytes,
+			"maxMessageBytes", obj.Spec.GitSpec.Commit.MaxMessageBytes)
 	}
-	commitMsg, err := templateMsg(obj.Spec.GitSpec.Commit.MessageTemplate, templateValues)
+	// Render the tag name before committing to not push anything when the
+	// tag template is invalid.
+	var tagName string
+	if obj.Spec.GitSpec.Tag != nil {
+		if tagName, err = templateTagName(obj.Spec.GitSpec.Tag.Name, templateValues); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidTemplate, err)
+		}
+	}
+	authorName, authorEmail, err := templateAuthor(obj.Spec.GitSpec.Commit.Author, templateValues)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTemplate, err)
+	}
+	if obj.Spec.GitSpec.Commit.Author.Email == "" {
+		authorEmail = sm.srcCfg.credentialsAuthor.Email
+		if authorName == "" {
+			authorName = sm.srcCfg.credentialsAuthor.Name
+		}
 	}
 	signature := git.Signature{
-		Name:  obj.Spec.GitSpec.Commit.Author.Name,
-		Email: obj.Spec.GitSpec.Commit.Author.Email,
+		Name:  authorName,
+		Email: authorEmail,
 		When:  time.Now(),
 	}
+	// The Git client dates the commits with the time they're made, the dated
+	// commits are made in the worktree.
+	dated := !sm.commitTime.IsZero() || sm.commitLocation != nil
+	if !sm.commitTime.IsZero() {
+		signature.When = sm.commitTime
+	}
+	if sm.commitLocation != nil {
+		signature.When = signature.When.In(sm.commitLocation)
+	}
+
+	// Leave the changes of the other files out of the commit.
+	if obj.Spec.GitSpec.Commit.UpdatePathOnly {
+		discarded, err := sm.discardChangesOutside(sm.stagedPaths(obj, policyResult))
+		if err != nil {
+			return nil, fmt.Errorf("failed to discard the changes outside of the update path: %w", err)
+		}
+		if len(discarded) > 0 {
+			log.FromContext(ctx).Info("discarded the changes outside of the update path", "files", discarded)
+		}
+	}
+
+	// Never push an unsigned commit when it must be signed, whatever the
+	// signing key selected for the push branch.
+	if sm.srcCfg.requireSignedCommits && sm.srcCfg.signingEntity == nil {
+		return nil, fmt.Errorf("commits to branch '%s' must be signed, but no signing key is selected: %w",
+			sm.srcCfg.pushBranch, ErrSigningRequired)
+	}
 
-	var rev string
-	var commitErr error
-	rev, commitErr = sm.gitClient.Commit(
-		git.Commit{
+	commit := func() (string, error) {
+		info := git.Commit{
 			Author:  signature,
 			Message: commitMsg,
-		},
-		repository.WithSigner(sm.srcCfg.signingEntity),
-	)
-
+		}
+		if dated {
+			return sm.commitDated(info, sm.srcCfg.signingEntity)
+		}
+		return sm.gitClient.Commit(info, repository.WithSigner(sm.srcCfg.signingEntity))
+	}
+	rev, commitErr := commit()
 	if commitErr != nil {
 		if !errors.Is(commitErr, git.ErrNoStagedFiles) {
 			return nil, commitErr
@@ -277,46 +791,178 @@ func (sm SourceManager) CommitAndPush(ctx context.Context, obj *imagev1.ImageUpd
 	}
 
 	// Push the commit to push branch.
-	gitOpCtx, cancel := context.WithTimeout(ctx, sm.srcCfg.timeout.Duration)
+	gitOpCtx, cancel := sm.gitOperationContext(ctx)
 	defer cancel()
 	pushConfig := repository.PushConfig{}
 	for _, po := range pushOptions {
 		po(&pushConfig)
 	}
-	if err := sm.gitClient.Push(gitOpCtx, pushConfig); err != nil {
+	if err := sm.faultInjector.fault(GitOperationPush); err != nil {
 		return nil, err
 	}
-	tracelog.Info("pushed commit to push branch", "revision", rev, "branch", sm.srcCfg.pushBranch)
+	// Never overwrite the commits pushed since the checkout.
+	var rebased bool
+	if push := obj.Spec.GitSpec.Push; push != nil {
+		var err error
+		if rev, rebased, err = sm.checkPushBranchHead(gitOpCtx, push.HeadCheck, rev, commit); err != nil {
+			return nil, err
+		}
+		if rebased {
+			tracelog.Info("made the commit again on top of the new head of the push branch", "revision", rev,
+				"branch", sm.srcCfg.pushBranch)
+		}
+	}
+	pushStart := time.Now()
+	switch {
+	case sm.srcCfg.apiCommitter != nil:
+		// The commit is made again with the provider API, which gives it
+		// another revision.
+		start := time.Now()
+		localRev := rev
+		if rev, err = sm.commitWithAPI(gitOpCtx, localRev, pushConfig.Force); err != nil {
+			return nil, err
+		}
+		tracelog.Info("made commit on push branch with the provider API", "revision", rev, "localRevision", localRev,
+			"branch", sm.srcCfg.pushBranch, "duration", time.Since(start).String())
+	case !sm.srcCfg.refspecOnly:
+		start := time.Now()
+		if err := sm.push(gitOpCtx, pushConfig); err != nil {
+			return nil, err
+		}
+		tracelog.Info("pushed commit to push branch", "revision", rev, "branch", sm.srcCfg.pushBranch,
+			"duration", time.Since(start).String())
+	}
 
 	// Push to any provided refspec.
 	if obj.Spec.GitSpec.HasRefspec() {
 		pushConfig.Refspecs = append(pushConfig.Refspecs, obj.Spec.GitSpec.Push.Refspec)
-		if err := sm.gitClient.Push(gitOpCtx, pushConfig); err != nil {
+		if err := sm.push(gitOpCtx, pushConfig); err != nil {
 			return nil, err
 		}
 		tracelog.Info("pushed commit to refspec", "revision", rev, "refspecs", pushConfig.Refspecs)
 	}
 
+	// Create an annotated tag for the pushed commit and force push it, so that
+	// an existing tag with the same name is moved to the new commit.
+	if tagName != "" {
+		if err := sm.tagCommit(tagName, rev, signature, commitMsg); err != nil {
+			return nil, fmt.Errorf("failed to create tag '%s': %w", tagName, err)
+		}
+		tagRef := plumbing.NewTagReferenceName(tagName)
+		tagPushConfig := repository.PushConfig{
+			Refspecs: []string{fmt.Sprintf("+%s:%s", tagRef, tagRef)},
+			Options:  pushConfig.Options,
+		}
+		if err := sm.push(gitOpCtx, tagPushConfig); err != nil {
+			return nil, err
+		}
+		tracelog.Info("pushed tag", "revision", rev, "tag", tagName)
+	}
+	pushDuration := time.Since(pushStart)
+
+	// Push the same references to the additional remotes.
+	var remoteRefspecs []string
+	if !sm.srcCfg.refspecOnly {
+		remoteRefspecs = append(remoteRefspecs, fmt.Sprintf("%s:%[1]s", plumbing.NewBranchReferenceName(sm.srcCfg.pushBranch)))
+	}
+	if obj.Spec.GitSpec.HasRefspec() {
+		remoteRefspecs = append(remoteRefspecs, obj.Spec.GitSpec.Push.Refspec)
+	}
+	if tagName != "" {
+		tagRef := plumbing.NewTagReferenceName(tagName)
+		remoteRefspecs = append(remoteRefspecs, fmt.Sprintf("+%s:%s", tagRef, tagRef))
+	}
+	remotePushes := sm.pushAdditionalRemotes(ctx, remoteRefspecs, pushConfig.Force, pushConfig.Options)
+	for _, push := range remotePushes {
+		if push.Err == nil {
+			tracelog.Info("pushed commit to additional remote", "revision", rev, "remote", push.Name)
+		}
+	}
+
 	// Construct the result of the push operation and return.
-	prOpts := []PushResultOption{WithPushResultRefspec(pushConfig.Refspecs)}
+	prOpts := []PushResultOption{WithPushResultRefspec(pushConfig.Refspecs), WithPushResultDuration(pushDuration)}
 	if sm.srcCfg.switchBranch {
 		prOpts = append(prOpts, WithPushResultSwitchBranch())
 	}
+	if tagName != "" {
+		prOpts = append(prOpts, WithPushResultTag(tagName))
+	}
+	if truncatedBytes > 0 {
+		prOpts = append(prOpts, WithPushResultTruncatedMessage(truncatedBytes))
+	}
+	if len(remotePushes) > 0 {
+		prOpts = append(prOpts, WithPushResultRemotePushes(remotePushes))
+	}
+	if rebased {
+		prOpts = append(prOpts, WithPushResultRebased())
+	}
 	return NewPushResult(sm.srcCfg.pushBranch, rev, commitMsg, prOpts...)
 }
 
-// templateMsg renders a msg template, returning the message or an error.
-func templateMsg(messageTemplate string, templateValues *TemplateData) (string, error) {
+// ValidateTemplates parses the commit message, author, tag name, per-policy
+// branch name and update path templates of the ImageUpdateAutomation, to
+// report invalid templates before anything is checked out. The returned error wraps ErrInvalidTemplate.
+func ValidateTemplates(obj *imagev1.ImageUpdateAutomation) error {
+	if obj.Spec.GitSpec == nil {
+		return nil
+	}
+	commit := obj.Spec.GitSpec.Commit
+	messageTemplate := commit.MessageTemplate
 	if messageTemplate == "" {
 		messageTemplate = defaultMessageTemplate
 	}
+	if _, err := parseMessageTemplate(messageTemplate); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidTemplate, err)
+	}
+	if _, err := parseAuthorTemplate("author name", commit.Author.Name); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidTemplate, err)
+	}
+	if _, err := parseAuthorTemplate("author email", commit.Author.Email); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidTemplate, err)
+	}
+	if obj.Spec.GitSpec.Tag != nil {
+		if _, err := parseTagNameTemplate(obj.Spec.GitSpec.Tag.Name); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidTemplate, err)
+		}
+	}
+	if obj.Spec.GitSpec.HasPerPolicyBranches() && obj.Spec.GitSpec.Push.Branch != "" {
+		t, err := parseBranchNameTemplate(obj.Spec.GitSpec.Push.Branch)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidTemplate, err)
+		}
+		if err := checkPolicyBranchTemplate(t); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidTemplate, err)
+		}
+	}
+	if obj.Spec.Update != nil && IsUpdatePathTemplate(obj.Spec.Update.Path) {
+		if _, err := parseUpdatePathTemplate(obj.Spec.Update.Path); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidTemplate, err)
+		}
+	}
+	return nil
+}
 
+// parseMessageTemplate parses a commit message template.
+func parseMessageTemplate(messageTemplate string) (*template.Template, error) {
 	// Includes only functions that are guaranteed to always evaluate to the same result for given input.
 	// This removes the possibility of accidentally relying on where or when the template runs.
 	// https://github.com/Masterminds/sprig/blob/3ac42c7bc5e4be6aa534e036fb19dde4a996da2e/functions.go#L70
 	t, err := template.New("commit message").Funcs(sprig.HermeticTxtFuncMap()).Parse(messageTemplate)
 	if err != nil {
-		return "", fmt.Errorf("unable to create commit message template from spec: %w", err)
+		return nil, fmt.Errorf("unable to create commit message template from spec: %w", err)
+	}
+	return t, nil
+}
+
+// templateMsg renders a msg template, returning the message or an error.
+func templateMsg(messageTemplate string, templateValues *templatesv1.TemplateData) (string, error) {
+	if messageTemplate == "" {
+		messageTemplate = defaultMessageTemplate
+	}
+
+	t, err := parseMessageTemplate(messageTemplate)
+	if err != nil {
+		return "", err
 	}
 
 	b := &strings.Builder{}
@@ -326,6 +972,153 @@ func templateMsg(messageTemplate string, templateValues *TemplateData) (string,
 	return b.String(), nil
 }
 
+// truncatedMessageNote is appended to a truncated commit message, with the
+// number of omitted bytes.
+const truncatedMessageNote = "\n\n[commit message truncated, %d bytes omitted]"
+
+// truncateMessage truncates the given commit message to maxBytes, returning
+// the message and the number of bytes omitted from it. The first line of the
+// message, its summary, is kept, and the rest is cut at a line boundary
+// followed by a note of the omitted bytes. When even the summary doesn't fit,
+// it's cut at a character boundary without note. A maxBytes of zero or less
+// means no limit.
+func truncateMessage(msg string, maxBytes int) (string, int) {
+	if maxBytes <= 0 || len(msg) <= maxBytes {
+		return msg, 0
+	}
+
+	// The note is at most as long as the note of the whole message.
+	noteLen := len(fmt.Sprintf(truncatedMessageNote, len(msg)))
+	summary, body, _ := strings.Cut(msg, "\n")
+	if len(summary)+noteLen > maxBytes {
+		n := min(len(summary), maxBytes)
+		for n > 0 && !utf8.RuneStart(summary[n]) {
+			n--
+		}
+		return summary[:n], len(msg) - n
+	}
+
+	kept := summary
+	for _, line := range strings.Split(body, "\n") {
+		if len(kept)+1+len(line)+noteLen > maxBytes {
+			break
+		}
+		kept += "\n" + line
+	}
+	kept = strings.TrimRight(kept, "\n")
+	omitted := len(msg) - len(kept)
+	return kept + fmt.Sprintf(truncatedMessageNote, omitted), omitted
+}
+
+// parseAuthorTemplate parses a commit author name or email template.
+func parseAuthorTemplate(name, authorTemplate string) (*template.Template, error) {
+	// Like commit messages, the author is expected to be the same between
+	// runs for the same changes, so only the hermetic functions are available.
+	t, err := template.New(name).Funcs(sprig.HermeticTxtFuncMap()).Parse(authorTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create %s template from spec: %w", name, err)
+	}
+	return t, nil
+}
+
+// templateAuthor renders the commit author name and email templates,
+// returning the name and email of the author or an error.
+func templateAuthor(author imagev1.CommitUser, templateValues *templatesv1.TemplateData) (string, string, error) {
+	render := func(name, authorTemplate string) (string, error) {
+		t, err := parseAuthorTemplate(name, authorTemplate)
+		if err != nil {
+			return "", err
+		}
+		b := &strings.Builder{}
+		if err := t.Execute(b, *templateValues); err != nil {
+			return "", fmt.Errorf("failed to run %s template from spec: %w", name, err)
+		}
+		value := strings.TrimSpace(b.String())
+		// Angle brackets and line breaks would corrupt the author line of
+		// the commit.
+		if strings.ContainsAny(value, "<>\n") {
+			return "", fmt.Errorf("invalid %s '%s': must not contain angle brackets or line breaks", name, value)
+		}
+		return value, nil
+	}
+
+	name, err := render("author name", author.Name)
+	if err != nil {
+		return "", "", err
+	}
+	email, err := render("author email", author.Email)
+	if err != nil {
+		return "", "", err
+	}
+	return name, email, nil
+}
+
+// parseTagNameTemplate parses a tag name template.
+func parseTagNameTemplate(nameTemplate string) (*template.Template, error) {
+	// Unlike commit messages, tag names are expected to differ between runs
+	// to be unique, so the time functions are made available in addition to
+	// the hermetic functions. Other non-hermetic functions, like env, remain
+	// excluded.
+	funcs := sprig.HermeticTxtFuncMap()
+	for name, f := range sprig.TxtFuncMap() {
+		switch name {
+		case "now", "date", "dateInZone", "date_in_zone", "unixEpoch":
+			funcs[name] = f
+		}
+	}
+	t, err := template.New("tag name").Funcs(funcs).Parse(nameTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create tag name template from spec: %w", err)
+	}
+	return t, nil
+}
+
+// templateTagName renders a tag name template, returning a valid tag name or
+// an error.
+func templateTagName(nameTemplate string, templateValues *templatesv1.TemplateData) (string, error) {
+	t, err := parseTagNameTemplate(nameTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	b := &strings.Builder{}
+	if err := t.Execute(b, *templateValues); err != nil {
+		return "", fmt.Errorf("failed to run tag name template from spec: %w", err)
+	}
+	name := strings.TrimSpace(b.String())
+	if err := plumbing.NewTagReferenceName(name).Validate(); err != nil {
+		return "", fmt.Errorf("invalid tag name '%s': %w", name, err)
+	}
+	return name, nil
+}
+
+// tagCommit creates an annotated tag with the given name pointing at the
+// given revision, replacing any existing local tag with the same name. The tag
+// is signed with the signing key of the source, if configured.
+func (sm SourceManager) tagCommit(name, rev string, tagger git.Signature, msg string) error {
+	repo, err := sm.openRepository()
+	if err != nil {
+		return err
+	}
+	if err := repo.DeleteTag(name); err != nil && !errors.Is(err, extgogit.ErrTagNotFound) {
+		return err
+	}
+	// Annotated tags require a message.
+	if msg == "" {
+		msg = name
+	}
+	_, err = repo.CreateTag(name, plumbing.NewHash(rev), &extgogit.CreateTagOptions{
+		Tagger: &object.Signature{
+			Name:  tagger.Name,
+			Email: tagger.Email,
+			When:  tagger.When,
+		},
+		Message: msg,
+		SignKey: sm.srcCfg.signingEntity,
+	})
+	return err
+}
+
 // PushResultOption allows configuring the options of PushResult.
 type PushResultOption func(*PushResult)
 
@@ -343,16 +1136,62 @@ func WithPushResultRefspec(refspecs []string) func(*PushResult) {
 	}
 }
 
+// WithPushResultTag sets the name of the tag pushed along with the commit in
+// the PushResult.
+func WithPushResultTag(tag string) func(*PushResult) {
+	return func(pr *PushResult) {
+		pr.tag = tag
+	}
+}
+
+// WithPushResultTruncatedMessage sets the number of bytes omitted from the
+// truncated commit message in the PushResult.
+func WithPushResultTruncatedMessage(omitted int) func(*PushResult) {
+	return func(pr *PushResult) {
+		pr.truncatedBytes = omitted
+	}
+}
+
+// WithPushResultRemotePushes sets the results of the pushes to the
+// additional remotes in the PushResult.
+func WithPushResultRemotePushes(pushes []RemotePushResult) func(*PushResult) {
+	return func(pr *PushResult) {
+		pr.remotePushes = pushes
+	}
+}
+
+// WithPushResultRebased sets in the PushResult that the commit was made again
+// on top of a push branch which moved since the checkout.
+func WithPushResultRebased() func(*PushResult) {
+	return func(pr *PushResult) {
+		pr.rebased = true
+	}
+}
+
+// WithPushResultDuration sets in the PushResult the duration of the push of
+// the commit and its tag to the remote.
+func WithPushResultDuration(d time.Duration) func(*PushResult) {
+	return func(pr *PushResult) {
+		pr.duration = d
+	}
+}
+
 // PushResult is the result of a push operation.
 type PushResult struct {
-	commit       *git.Commit
-	switchBranch bool
-	branch       string
-	refspecs     []string
-	creationTime *metav1.Time
+	commit         *git.Commit
+	switchBranch   bool
+	branch         string
+	refspecs       []string
+	tag            string
+	truncatedBytes int
+	remotePushes   []RemotePushResult
+	rebased        bool
+	duration       time.Duration
+	creationTime   *metav1.Time
 }
 
-// NewPushResult returns a new PushResult.
+// NewPushResult returns a new PushResult. The branch is empty when the commit
+// was only pushed with refspecs.
 func NewPushResult(branch string, rev string, commitMsg string, opts ...PushResultOption) (*PushResult, error) {
 	if rev == "" {
 		return nil, errors.New("empty push commit revision")
@@ -363,9 +1202,11 @@ func NewPushResult(branch string, rev string, commitMsg string, opts ...PushResu
 		o(pr)
 	}
 	pr.commit = &git.Commit{
-		Hash:      git.ExtractHashFromRevision(rev),
-		Reference: plumbing.NewBranchReferenceName(branch).String(),
-		Message:   commitMsg,
+		Hash:    git.ExtractHashFromRevision(rev),
+		Message: commitMsg,
+	}
+	if branch != "" {
+		pr.commit.Reference = plumbing.NewBranchReferenceName(branch).String()
 	}
 	pr.branch = branch
 	pr.creationTime = &metav1.Time{Time: time.Now()}
@@ -373,11 +1214,23 @@ func NewPushResult(branch string, rev string, commitMsg string, opts ...PushResu
 	return pr, nil
 }
 
+// Duration returns the duration of the push of the commit and its tag to the
+// remote, without the pushes to the additional remotes.
+func (pr PushResult) Duration() time.Duration {
+	return pr.duration
+}
+
 // Commit returns the revision of the pushed commit.
 func (pr PushResult) Commit() *git.Commit {
 	return pr.commit
 }
 
+// Branch returns the branch the commit was pushed to, empty when the commit
+// was only pushed with refspecs.
+func (pr PushResult) Branch() string {
+	return pr.branch
+}
+
 // Time returns the time at which the push was performed.
 func (pr PushResult) Time() *metav1.Time {
 	return pr.creationTime
@@ -388,6 +1241,29 @@ func (pr PushResult) SwitchBranch() bool {
 	return pr.switchBranch
 }
 
+// Tag returns the name of the tag pushed along with the commit, if any.
+func (pr PushResult) Tag() string {
+	return pr.tag
+}
+
+// TruncatedMessageBytes returns the number of bytes omitted from the commit
+// message to fit the maximum size of the commit message, if any.
+func (pr PushResult) TruncatedMessageBytes() int {
+	return pr.truncatedBytes
+}
+
+// RemotePushes returns the results of the pushes to the additional remotes,
+// if any.
+func (pr PushResult) RemotePushes() []RemotePushResult {
+	return pr.remotePushes
+}
+
+// Rebased returns if the commit was made again on top of a push branch which
+// moved since the checkout.
+func (pr PushResult) Rebased() bool {
+	return pr.rebased
+}
+
 // Summary returns a summary of the PushResult.
 func (pr PushResult) Summary() string {
 	var summary strings.Builder
@@ -395,9 +1271,18 @@ func (pr PushResult) Summary() string {
 	if len(shortCommitHash) > 7 {
 		shortCommitHash = shortCommitHash[:7]
 	}
-	summary.WriteString(fmt.Sprintf("pushed commit '%s' to branch '%s'", shortCommitHash, pr.branch))
+	summary.WriteString(fmt.Sprintf("pushed commit '%s' to ", shortCommitHash))
+	if pr.branch != "" {
+		summary.WriteString(fmt.Sprintf("branch '%s'", pr.branch))
+		if len(pr.refspecs) > 0 {
+			summary.WriteString(" and ")
+		}
+	}
 	if len(pr.refspecs) > 0 {
-		summary.WriteString(fmt.Sprintf(" and refspecs '%s'", strings.Join(pr.refspecs, "', '")))
+		summary.WriteString(fmt.Sprintf("refspecs '%s'", strings.Join(pr.refspecs, "', '")))
+	}
+	if pr.tag != "" {
+		summary.WriteString(fmt.Sprintf(" with tag '%s'", pr.tag))
 	}
 	if pr.Commit().Message != "" {
 		summary.WriteString(fmt.Sprintf("\n%s", pr.Commit().Message))
@@ -0,0 +1,150 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"golang.org/x/crypto/ssh"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/fluxcd/pkg/git"
+)
+
+// sshCertificateUserPrefix is the prefix of the usernames given to the Git
+// clients in place of the username of the SSH identities certified by a
+// certificate.
+const sshCertificateUserPrefix = "ssh-certificate-"
+
+// sshCertificate is an SSH identity certified by an OpenSSH user certificate.
+type sshCertificate struct {
+	// user is the username the identity authenticates as.
+	user string
+	cert *ssh.Certificate
+	// signer signs with the identity, presenting the certificate.
+	signer ssh.Signer
+}
+
+// sshCertificates are the certified SSH identities, by the username given in
+// their place to the Git clients, which build the signer of an identity from
+// its private key alone. The SSH transport installed by InstallSSHClient
+// looks them up to authenticate with the certificate instead.
+var sshCertificates sync.Map
+
+// certifySSHIdentity makes the given SSH authentication options authenticate
+// with the OpenSSH user certificate of their identity, when the data of the
+// Secret with the given key has one. The certificate is checked with
+// checkSSHCertificate at the given time.
+func certifySSHIdentity(opts *git.AuthOptions, data map[string][]byte, secretKey types.NamespacedName, now time.Time) error {
+	certData, ok := data[sshCertificateKey]
+	if !ok || opts.Transport != git.SSH {
+		return nil
+	}
+	cert, err := checkSSHCertificate(opts, certData, now)
+	if err != nil {
+		return fmt.Errorf("invalid SSH certificate in secret '%s': %w: %w", secretKey, err, ErrInvalidSourceConfiguration)
+	}
+	opts.Username = registerSSHCertificate(cert, now)
+	return nil
+}
+
+// registerSSHCertificate registers the given certified identity, and returns
+// the username to give to the Git clients in its place. The identities whose
+// certificate expired more than an hour before the given time are forgotten,
+// for the renewed short-lived certificates not to pile up.
+func registerSSHCertificate(c *sshCertificate, now time.Time) string {
+	forgotten := uint64(now.Add(-time.Hour).Unix())
+	sshCertificates.Range(func(key, value any) bool {
+		if cert := value.(*sshCertificate).cert; cert.ValidBefore != ssh.CertTimeInfinity && forgotten >= cert.ValidBefore {
+			sshCertificates.Delete(key)
+		}
+		return true
+	})
+
+	h := sha256.New()
+	h.Write([]byte(c.user))
+	h.Write([]byte{0})
+	h.Write(c.cert.Marshal())
+	user := sshCertificateUserPrefix + hex.EncodeToString(h.Sum(nil))[:32]
+	sshCertificates.Store(user, c)
+	return user
+}
+
+// InstallSSHClient installs the SSH client of the Git operations, which
+// authenticates the certified SSH identities with their certificate.
+func InstallSSHClient() {
+	client.InstallProtocol("ssh", &sshCertTransport{next: gitssh.DefaultClient})
+}
+
+// sshCertTransport is the transport.Transport of the Git operations over
+// SSH, presenting the certificate of the certified identities.
+type sshCertTransport struct {
+	next transport.Transport
+}
+
+var _ transport.Transport = &sshCertTransport{}
+
+func (t *sshCertTransport) NewUploadPackSession(ep *transport.Endpoint, auth transport.AuthMethod) (transport.UploadPackSession, error) {
+	return t.next.NewUploadPackSession(ep, withSSHCertificate(auth))
+}
+
+func (t *sshCertTransport) NewReceivePackSession(ep *transport.Endpoint, auth transport.AuthMethod) (transport.ReceivePackSession, error) {
+	return t.next.NewReceivePackSession(ep, withSSHCertificate(auth))
+}
+
+// withSSHCertificate returns the given authentication method, authenticating
+// with the certificate of the identity when it's certified.
+func withSSHCertificate(auth transport.AuthMethod) transport.AuthMethod {
+	if a, ok := auth.(gitssh.AuthMethod); ok {
+		return &sshCertAuth{AuthMethod: a}
+	}
+	return auth
+}
+
+// sshCertAuth is an SSH authentication method which authenticates with the
+// certificate of the identity of the wrapped method, when it's certified.
+type sshCertAuth struct {
+	gitssh.AuthMethod
+}
+
+func (a *sshCertAuth) ClientConfig() (*ssh.ClientConfig, error) {
+	config, err := a.AuthMethod.ClientConfig()
+	if err != nil || !strings.HasPrefix(config.User, sshCertificateUserPrefix) {
+		return config, err
+	}
+	value, ok := sshCertificates.Load(config.User)
+	if !ok {
+		return nil, fmt.Errorf("no SSH certificate registered for '%s'", config.User)
+	}
+	c := value.(*sshCertificate)
+	// A short-lived certificate may expire during a reconciliation, e.g.
+	// between the pushes of the per-policy branches.
+	if err := checkSSHCertificateValidity(c.cert, time.Now()); err != nil {
+		return nil, err
+	}
+	config.User = c.user
+	config.Auth = []ssh.AuthMethod{ssh.PublicKeys(c.signer)}
+	return config, nil
+}
@@ -0,0 +1,208 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/fluxcd/pkg/git"
+)
+
+// certOnlySSHServer is an SSH server accepting only the users authenticating
+// with a certificate of its certificate authority. It records the key ID of
+// the certificates of the authenticated users, and runs no command.
+type certOnlySSHServer struct {
+	addr       string
+	knownHosts []byte
+	logins     chan string
+}
+
+func startCertOnlySSHServer(t *testing.T, g *WithT, ca ssh.PublicKey) *certOnlySSHServer {
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	g.Expect(err).ToNot(HaveOccurred())
+	hostSigner, err := ssh.NewSignerFromKey(hostKey)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	s := &certOnlySSHServer{logins: make(chan string, 8)}
+	checker := &ssh.CertChecker{
+		IsUserAuthority: func(auth ssh.PublicKey) bool {
+			return bytes.Equal(auth.Marshal(), ca.Marshal())
+		},
+	}
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			perms, err := checker.Authenticate(conn, key)
+			if err == nil {
+				s.logins <- conn.User() + "/" + key.(*ssh.Certificate).KeyId
+			}
+			return perms, err
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	g.Expect(err).ToNot(HaveOccurred())
+	t.Cleanup(func() { l.Close() })
+	s.addr = l.Addr().String()
+	s.knownHosts = []byte(knownhosts.Line([]string{knownhosts.Normalize(s.addr)}, hostSigner.PublicKey()))
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				sconn, chans, reqs, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					return
+				}
+				defer sconn.Close()
+				go ssh.DiscardRequests(reqs)
+				for ch := range chans {
+					ch.Reject(ssh.Prohibited, "no command")
+				}
+			}()
+		}
+	}()
+	return s
+}
+
+func TestSSHCertificate_authentication(t *testing.T) {
+	g := NewWithT(t)
+
+	_, caKey, err := ed25519.GenerateKey(rand.Reader)
+	g.Expect(err).ToNot(HaveOccurred())
+	caSigner, err := ssh.NewSignerFromKey(caKey)
+	g.Expect(err).ToNot(HaveOccurred())
+	server := startCertOnlySSHServer(t, g, caSigner.PublicKey())
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	g.Expect(err).ToNot(HaveOccurred())
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	g.Expect(err).ToNot(HaveOccurred())
+	identity := pem.EncodeToMemory(block)
+	sshPub, err := ssh.NewPublicKey(pub)
+	g.Expect(err).ToNot(HaveOccurred())
+	newCert := func(keyID string, validBefore time.Time) []byte {
+		cert := &ssh.Certificate{
+			Key:             sshPub,
+			KeyId:           keyID,
+			CertType:        ssh.UserCert,
+			ValidPrincipals: []string{"git"},
+			ValidBefore:     uint64(validBefore.Unix()),
+		}
+		g.Expect(cert.SignCert(rand.Reader, caSigner)).To(Succeed())
+		return ssh.MarshalAuthorizedKey(cert)
+	}
+
+	InstallSSHClient()
+	defer client.InstallProtocol("ssh", gitssh.DefaultClient)
+	ep, err := transport.NewEndpoint("ssh://git@" + server.addr + "/repo.git")
+	g.Expect(err).ToNot(HaveOccurred())
+	connect := func(opts *git.AuthOptions) error {
+		auth, err := remoteTransportAuth(opts)
+		g.Expect(err).ToNot(HaveOccurred())
+		c, err := client.NewClient(ep)
+		g.Expect(err).ToNot(HaveOccurred())
+		_, err = c.NewUploadPackSession(ep, auth)
+		return err
+	}
+	secretKey := types.NamespacedName{Namespace: "default", Name: "ssh-credentials"}
+
+	// The server rejects the key alone.
+	opts := &git.AuthOptions{Transport: git.SSH, Username: "git", Identity: identity, KnownHosts: server.knownHosts}
+	g.Expect(connect(opts)).To(MatchError(ContainSubstring("unable to authenticate")))
+	g.Expect(server.logins).To(BeEmpty())
+
+	// The certified identity authenticates with its certificate, as its user,
+	// the server then running no command.
+	data := map[string][]byte{sshCertificateKey: newCert("flux", time.Now().Add(time.Hour))}
+	g.Expect(certifySSHIdentity(opts, data, secretKey, time.Now())).To(Succeed())
+	g.Expect(opts.Username).To(HavePrefix(sshCertificateUserPrefix))
+	err = connect(opts)
+	g.Expect(err).ToNot(MatchError(ContainSubstring("unable to authenticate")))
+	g.Expect(server.logins).To(Receive(Equal("git/flux")))
+
+	// The certificate is checked again on every connection, e.g. for each
+	// push branch, and an expired certificate is reported with the renewal
+	// hint.
+	opts = &git.AuthOptions{Transport: git.SSH, Username: "git", Identity: identity, KnownHosts: server.knownHosts}
+	data = map[string][]byte{sshCertificateKey: newCert("expiring", time.Now().Add(2*time.Second))}
+	g.Expect(certifySSHIdentity(opts, data, secretKey, time.Now())).To(Succeed())
+	time.Sleep(2 * time.Second)
+	g.Expect(connect(opts)).To(MatchError(ContainSubstring("expired at")))
+	g.Expect(server.logins).To(BeEmpty())
+
+	// An expired certificate isn't registered.
+	opts = &git.AuthOptions{Transport: git.SSH, Username: "git", Identity: identity, KnownHosts: server.knownHosts}
+	err = certifySSHIdentity(opts, data, secretKey, time.Now())
+	g.Expect(err).To(MatchError(ErrInvalidSourceConfiguration))
+	g.Expect(err).To(MatchError(ContainSubstring("invalid SSH certificate in secret 'default/ssh-credentials'")))
+	g.Expect(opts.Username).To(Equal("git"))
+}
+
+func Test_registerSSHCertificate(t *testing.T) {
+	g := NewWithT(t)
+
+	_, caKey, err := ed25519.GenerateKey(rand.Reader)
+	g.Expect(err).ToNot(HaveOccurred())
+	caSigner, err := ssh.NewSignerFromKey(caKey)
+	g.Expect(err).ToNot(HaveOccurred())
+	now := time.Date(2024, 6, 5, 9, 0, 0, 0, time.UTC)
+	newCert := func(keyID string, validBefore time.Time) *sshCertificate {
+		cert := &ssh.Certificate{
+			Key:         caSigner.PublicKey(),
+			KeyId:       keyID,
+			CertType:    ssh.UserCert,
+			ValidBefore: uint64(validBefore.Unix()),
+		}
+		g.Expect(cert.SignCert(rand.Reader, caSigner)).To(Succeed())
+		return &sshCertificate{user: "git", cert: cert}
+	}
+
+	old := registerSSHCertificate(newCert("old", now.Add(-30*time.Minute)), now.Add(-time.Hour))
+	currentCert := newCert("current", now.Add(time.Hour))
+	current := registerSSHCertificate(currentCert, now)
+	g.Expect(current).To(HavePrefix(sshCertificateUserPrefix))
+	g.Expect(current).ToNot(Equal(old))
+	g.Expect(registerSSHCertificate(currentCert, now)).To(Equal(current))
+
+	// The certificates expired for more than an hour are forgotten.
+	_, ok := sshCertificates.Load(old)
+	g.Expect(ok).To(BeTrue())
+	registerSSHCertificate(currentCert, now.Add(time.Hour))
+	_, ok = sshCertificates.Load(old)
+	g.Expect(ok).To(BeFalse())
+	_, ok = sshCertificates.Load(current)
+	g.Expect(ok).To(BeTrue())
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-billy/v5/util"
+	extgogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+	"github.com/fluxcd/image-automation-controller/internal/policy"
+	"github.com/fluxcd/image-automation-controller/pkg/update"
+)
+
+// stagedPaths returns the paths within the source of the directories the
+// given result of the policies updated, and of its lock file, if any. They're
+// the directories a glob update path matched, or else the update path itself.
+func (sm SourceManager) stagedPaths(obj *imagev1.ImageUpdateAutomation, result update.ResultV2) []string {
+	var updatePath string
+	switch {
+	case sm.updatePath != nil:
+		updatePath = *sm.updatePath
+	case obj.Spec.Update != nil:
+		updatePath = obj.Spec.Update.Path
+	}
+	base := path.Clean(strings.TrimPrefix(policy.UpdatePathBase(updatePath), "/"))
+
+	paths := []string{base}
+	if len(result.UpdatePaths) > 0 {
+		paths = paths[:0]
+		for _, dir := range result.UpdatePaths {
+			paths = append(paths, path.Join(base, dir))
+		}
+	}
+	if result.LockFile != "" {
+		paths = append(paths, path.Join(base, result.LockFile))
+	}
+	return paths
+}
+
+// discardChangesOutside restores the files of the worktree changed outside of
+// the given paths to their checked out state, removing the new ones, for them
+// not to be committed. It returns the sorted paths of the restored files.
+func (sm SourceManager) discardChangesOutside(paths []string) ([]string, error) {
+	repo, err := sm.openRepository()
+	if err != nil {
+		return nil, err
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load worktree: %w", err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	headCommit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the checked out commit: %w", err)
+	}
+	headTree, err := headCommit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	status, err := wt.Status()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the worktree status: %w", err)
+	}
+
+	var discarded []string
+	for file, st := range status {
+		if st.Worktree == extgogit.Unmodified && st.Staging == extgogit.Unmodified {
+			continue
+		}
+		if slices.ContainsFunc(paths, func(p string) bool { return update.IsWithinPath(file, p) }) {
+			continue
+		}
+		if err := restoreFile(wt, headTree, file); err != nil {
+			return nil, fmt.Errorf("failed to restore '%s': %w", file, err)
+		}
+		discarded = append(discarded, file)
+	}
+	slices.Sort(discarded)
+	return discarded, nil
+}
+
+// restoreFile restores the given file of the worktree, and of its index, to
+// its state in the given tree, removing it when it isn't in the tree.
+func restoreFile(wt *extgogit.Worktree, tree *object.Tree, file string) error {
+	f, err := tree.File(file)
+	if errors.Is(err, object.ErrFileNotFound) {
+		if _, err := wt.Remove(file); err == nil || !errors.Is(err, index.ErrEntryNotFound) {
+			return err
+		}
+		return util.RemoveAll(wt.Filesystem, file)
+	}
+	if err != nil {
+		return err
+	}
+	contents, err := f.Contents()
+	if err != nil {
+		return err
+	}
+	if err := util.RemoveAll(wt.Filesystem, file); err != nil {
+		return err
+	}
+	if f.Mode == filemode.Symlink {
+		err = wt.Filesystem.Symlink(contents, file)
+	} else {
+		var mode os.FileMode
+		if mode, err = f.Mode.ToOSFileMode(); err == nil {
+			err = util.WriteFile(wt.Filesystem, file, []byte(contents), mode)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	_, err = wt.Add(file)
+	return err
+}
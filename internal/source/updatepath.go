@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+	"k8s.io/apimachinery/pkg/types"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+	templatesv1 "github.com/fluxcd/image-automation-controller/pkg/templates/v1"
+)
+
+// IsUpdatePathTemplate returns if the given update path is a template.
+func IsUpdatePathTemplate(updatePath string) bool {
+	return strings.Contains(updatePath, "{{")
+}
+
+// UpdatePath renders the update path of the ImageUpdateAutomation with the
+// values of the commit and the given policies, or returns it as is when it
+// isn't a template. The path is recorded for the commits made with
+// UpdatePathOnly. The returned error wraps ErrInvalidTemplate.
+func (sm *SourceManager) UpdatePath(obj *imagev1.ImageUpdateAutomation, policies []types.NamespacedName) (string, error) {
+	if obj.Spec.Update == nil {
+		return "", nil
+	}
+	if !IsUpdatePathTemplate(obj.Spec.Update.Path) {
+		sm.updatePath = &obj.Spec.Update.Path
+		return obj.Spec.Update.Path, nil
+	}
+	policies = slices.Clone(policies)
+	slices.SortFunc(policies, func(a, b types.NamespacedName) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	p, err := templateUpdatePath(obj.Spec.Update.Path, &templatesv1.UpdatePathData{
+		AutomationObject: sm.automationObjKey,
+		Values:           sm.srcCfg.templateValues,
+		Policies:         policies,
+	})
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidTemplate, err)
+	}
+	sm.updatePath = &p
+	return p, nil
+}
+
+// parseUpdatePathTemplate parses an update path template.
+func parseUpdatePathTemplate(pathTemplate string) (*template.Template, error) {
+	// The same directories are expected to be updated on every run, so only
+	// the hermetic functions are available, and a missing value fails the
+	// rendering instead of updating another directory.
+	t, err := template.New("update path").Funcs(sprig.HermeticTxtFuncMap()).Option("missingkey=error").Parse(pathTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create update path template from spec: %w", err)
+	}
+	return t, nil
+}
+
+// templateUpdatePath renders an update path template, returning a relative
+// path within the repository or an error.
+func templateUpdatePath(pathTemplate string, data *templatesv1.UpdatePathData) (string, error) {
+	t, err := parseUpdatePathTemplate(pathTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	b := &strings.Builder{}
+	if err := t.Execute(b, *data); err != nil {
+		return "", fmt.Errorf("failed to run update path template from spec: %w", err)
+	}
+	p := strings.TrimSpace(b.String())
+	switch {
+	case p == "":
+		return "", errors.New("update path template renders an empty path")
+	case strings.ContainsAny(p, "\n\r"):
+		return "", fmt.Errorf("invalid update path '%s': must not contain line breaks", p)
+	case path.Clean(p) == ".." || strings.HasPrefix(path.Clean(p), "../"):
+		return "", fmt.Errorf("invalid update path '%s': must be within the repository", p)
+	}
+	return p, nil
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+func TestSourceManager_UpdatePath(t *testing.T) {
+	tests := []struct {
+		name       string
+		updatePath string
+		want       string
+		wantErr    string
+	}{
+		{
+			name:       "not a template",
+			updatePath: "./apps/{staging}",
+			want:       "./apps/{staging}",
+		},
+		{
+			name:       "values",
+			updatePath: "./apps/{{ .Values.env }}/{{ .AutomationObject.Name }}",
+			want:       "./apps/staging/podinfo",
+		},
+		{
+			name:       "policies",
+			updatePath: "./apps/{{ (index .Policies 0).Name }}",
+			want:       "./apps/backend",
+		},
+		{
+			name:       "missing value",
+			updatePath: "./apps/{{ .Values.cluster }}",
+			wantErr:    `map has no entry for key "cluster"`,
+		},
+		{
+			name:       "empty path",
+			updatePath: "{{ .Values.empty }}",
+			wantErr:    "renders an empty path",
+		},
+		{
+			name:       "outside of the repository",
+			updatePath: "../{{ .Values.env }}",
+			wantErr:    "must be within the repository",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			sm := SourceManager{
+				srcCfg: &gitSrcCfg{
+					templateValues: map[string]string{"env": "staging", "empty": ""},
+				},
+				automationObjKey: types.NamespacedName{Namespace: "apps", Name: "podinfo"},
+			}
+			obj := &imagev1.ImageUpdateAutomation{}
+			obj.Spec.Update = &imagev1.UpdateStrategy{Path: tt.updatePath}
+			policies := []types.NamespacedName{
+				{Namespace: "apps", Name: "frontend"},
+				{Namespace: "apps", Name: "backend"},
+			}
+
+			got, err := sm.UpdatePath(obj, policies)
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ErrInvalidTemplate))
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// workingDirPrefix is the prefix of the names of the working directories the
+// sources are checked out in, for the orphaned ones to be told apart from the
+// other directories of the working root.
+const workingDirPrefix = "image-automation-"
+
+// workingDirsInUse records the working directories of the SourceManagers not
+// cleaned up yet, for them not to be removed as orphans.
+var workingDirsInUse = struct {
+	sync.Mutex
+	dirs map[string]bool
+}{dirs: map[string]bool{}}
+
+// claimWorkingDir records the given working directory as in use, and returns
+// whether it wasn't already.
+func claimWorkingDir(dir string) bool {
+	workingDirsInUse.Lock()
+	defer workingDirsInUse.Unlock()
+	if workingDirsInUse.dirs[dir] {
+		return false
+	}
+	workingDirsInUse.dirs[dir] = true
+	return true
+}
+
+// releaseWorkingDir records the given working directory as no longer in use.
+func releaseWorkingDir(dir string) {
+	workingDirsInUse.Lock()
+	defer workingDirsInUse.Unlock()
+	delete(workingDirsInUse.dirs, dir)
+}
+
+// makeWorkingDir creates the working directory of the automation with the
+// given UID in the given root directory, or in the default directory for
+// temporary files if it's empty, and records it as in use. The directory is
+// named after the UID, and any directory left with that name, e.g. by a
+// crash, is removed first. A directory with a random suffix is created when
+// the automation has no UID or its directory is already in use.
+func makeWorkingDir(root string, uid types.UID) (string, error) {
+	if root == "" {
+		root = os.TempDir()
+	}
+	if uid != "" {
+		dir := filepath.Join(root, workingDirPrefix+string(uid))
+		if claimWorkingDir(dir) {
+			if err := os.RemoveAll(dir); err != nil {
+				releaseWorkingDir(dir)
+				return "", fmt.Errorf("failed to remove the previous working directory: %w", err)
+			}
+			if err := os.Mkdir(dir, 0o700); err != nil {
+				releaseWorkingDir(dir)
+				return "", err
+			}
+			return dir, nil
+		}
+	}
+	dir, err := os.MkdirTemp(root, workingDirPrefix+string(uid)+"_*")
+	if err != nil {
+		return "", err
+	}
+	claimWorkingDir(dir)
+	return dir, nil
+}
+
+// OrphanedWorkingDir is a working directory removed by
+// RemoveOrphanedWorkingDirs.
+type OrphanedWorkingDir struct {
+	// Path is the path of the directory.
+	Path string
+	// UID is the UID of the automation the directory was created for, empty
+	// if it had none.
+	UID types.UID
+	// Size is the total size of the files of the directory.
+	Size int64
+}
+
+// RemoveOrphanedWorkingDirs removes the working directories of the given root
+// directory, or of the default directory for temporary files if it's empty,
+// which aren't in use by a SourceManager, and returns them. These are the
+// directories of the automations which were deleted, or which aren't
+// currently reconciled and were left by an interrupted reconciliation, e.g.
+// by a crash of the controller. The other directories of the root directory
+// are left as they are.
+func RemoveOrphanedWorkingDirs(root string) ([]OrphanedWorkingDir, error) {
+	if root == "" {
+		root = os.TempDir()
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var removed []OrphanedWorkingDir
+	var errs []error
+	for _, entry := range entries {
+		name, ok := strings.CutPrefix(entry.Name(), workingDirPrefix)
+		if !ok || !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, entry.Name())
+		// The directory is claimed while it's removed, for a SourceManager
+		// not to create it meanwhile.
+		if !claimWorkingDir(dir) {
+			continue
+		}
+		size, err := dirSize(dir)
+		if err == nil {
+			err = os.RemoveAll(dir)
+		}
+		releaseWorkingDir(dir)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove the orphaned working directory '%s': %w", dir, err))
+			continue
+		}
+		uid, _, _ := strings.Cut(name, "_")
+		removed = append(removed, OrphanedWorkingDir{Path: dir, UID: types.UID(uid), Size: size})
+	}
+	return removed, errors.Join(errs...)
+}
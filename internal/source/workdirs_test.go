@@ -0,0 +1,103 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func Test_makeWorkingDir(t *testing.T) {
+	g := NewWithT(t)
+
+	root := t.TempDir()
+	uid := types.UID("8b0b7e4c-3c54-4bd7-a8a3-9d1d3c0e5a61")
+
+	// The directory left by a crash is emptied.
+	stale := filepath.Join(root, workingDirPrefix+string(uid))
+	g.Expect(os.MkdirAll(filepath.Join(stale, "repo"), 0o700)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(stale, "repo", "file"), []byte("stale"), 0o600)).To(Succeed())
+
+	dir, err := makeWorkingDir(root, uid)
+	g.Expect(err).ToNot(HaveOccurred())
+	defer releaseWorkingDir(dir)
+	g.Expect(dir).To(Equal(stale))
+	entries, err := os.ReadDir(dir)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(entries).To(BeEmpty())
+
+	// The directory in use isn't reused.
+	other, err := makeWorkingDir(root, uid)
+	g.Expect(err).ToNot(HaveOccurred())
+	defer releaseWorkingDir(other)
+	g.Expect(other).ToNot(Equal(dir))
+	g.Expect(filepath.Base(other)).To(HavePrefix(workingDirPrefix + string(uid) + "_"))
+	g.Expect(dir).To(BeADirectory())
+
+	// An automation without UID gets a directory of its own.
+	noUID, err := makeWorkingDir(root, "")
+	g.Expect(err).ToNot(HaveOccurred())
+	defer releaseWorkingDir(noUID)
+	g.Expect(filepath.Base(noUID)).To(HavePrefix(workingDirPrefix + "_"))
+}
+
+func TestRemoveOrphanedWorkingDirs(t *testing.T) {
+	g := NewWithT(t)
+
+	root := t.TempDir()
+	orphan := filepath.Join(root, workingDirPrefix+"deleted-uid")
+	g.Expect(os.MkdirAll(filepath.Join(orphan, "repo"), 0o700)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(orphan, "repo", "file"), []byte(strings.Repeat("x", 100)), 0o600)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(orphan, "other"), []byte(strings.Repeat("x", 20)), 0o600)).To(Succeed())
+	g.Expect(os.Mkdir(filepath.Join(root, workingDirPrefix+"other-uid_1234"), 0o700)).To(Succeed())
+	// The directories which aren't working directories are kept.
+	unrelated := filepath.Join(root, "unrelated")
+	g.Expect(os.Mkdir(unrelated, 0o700)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(root, workingDirPrefix+"file"), nil, 0o600)).To(Succeed())
+	// The directory of a reconciliation in progress is kept.
+	inUse, err := makeWorkingDir(root, "reconciling-uid")
+	g.Expect(err).ToNot(HaveOccurred())
+	defer releaseWorkingDir(inUse)
+
+	removed, err := RemoveOrphanedWorkingDirs(root)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(removed).To(ConsistOf(
+		OrphanedWorkingDir{Path: orphan, UID: "deleted-uid", Size: 120},
+		OrphanedWorkingDir{Path: filepath.Join(root, workingDirPrefix+"other-uid_1234"), UID: "other-uid"},
+	))
+	g.Expect(orphan).ToNot(BeADirectory())
+	g.Expect(unrelated).To(BeADirectory())
+	g.Expect(filepath.Join(root, workingDirPrefix+"file")).To(BeARegularFile())
+	g.Expect(inUse).To(BeADirectory())
+
+	// The released directory is an orphan.
+	releaseWorkingDir(inUse)
+	removed, err = RemoveOrphanedWorkingDirs(root)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(removed).To(HaveLen(1))
+	g.Expect(removed[0].UID).To(Equal(types.UID("reconciling-uid")))
+
+	// A missing root has no orphans.
+	removed, err = RemoveOrphanedWorkingDirs(filepath.Join(root, "missing"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(removed).To(BeEmpty())
+}
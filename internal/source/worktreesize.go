@@ -0,0 +1,248 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/fluxcd/pkg/git/gogit"
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-billy/v5/util"
+	extgogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/storage"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// checkWorktreeSize returns an error wrapping ErrWorktreeTooLarge when the
+// size of the checked out source, including its Git directory on disk or its
+// Git objects in memory, exceeds the maximum size of the SourceManager. It's
+// checked once the source is cloned, the clone itself is limited by a
+// cloneLimit.
+func (sm SourceManager) checkWorktreeSize() error {
+	if sm.maxWorktreeSize <= 0 {
+		return nil
+	}
+	size, err := sm.worktreeSize()
+	if err != nil {
+		return fmt.Errorf("failed to measure the size of the worktree: %w", err)
+	}
+	if size > sm.maxWorktreeSize {
+		return fmt.Errorf("size of the checked out source %s exceeds the maximum size %s: %w",
+			resource.NewQuantity(size, resource.BinarySI), resource.NewQuantity(sm.maxWorktreeSize, resource.BinarySI),
+			ErrWorktreeTooLarge)
+	}
+	return nil
+}
+
+// worktreeSize returns the total size of the files of the working directory,
+// or of the in-memory worktree and Git objects.
+func (sm SourceManager) worktreeSize() (int64, error) {
+	var size int64
+	if sm.workTree != nil {
+		err := util.Walk(sm.workTree, "/", func(_ string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.Mode().IsRegular() {
+				size += info.Size()
+			}
+			return nil
+		})
+		if err != nil {
+			return size, err
+		}
+		if s, ok := sm.storer.(*memory.Storage); ok {
+			objects, err := countObjects(s)
+			size += objects.bytes
+			return size, err
+		}
+		return size, nil
+	}
+	return dirSize(sm.workingDir)
+}
+
+// dirSize returns the total size of the regular files of the given directory.
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
+
+// cloneLimit limits the number of bytes a clone writes to the worktree and to
+// the Git objects storage, on disk or in memory, to the maximum worktree size,
+// for a clone of a larger source to fail before it fills the disk or the
+// memory. The bytes written after the clone aren't counted. A nil cloneLimit
+// is unlimited.
+type cloneLimit struct {
+	max     int64
+	written atomic.Int64
+	stopped atomic.Bool
+}
+
+// newCloneLimit returns a cloneLimit of the given maximum number of bytes, or
+// nil when the given maximum isn't positive.
+func newCloneLimit(max int64) *cloneLimit {
+	if max <= 0 {
+		return nil
+	}
+	return &cloneLimit{max: max}
+}
+
+// add counts the given number of written bytes, and returns an error wrapping
+// ErrWorktreeTooLarge when they exceed the limit.
+func (l *cloneLimit) add(n int64) error {
+	if l == nil || l.stopped.Load() {
+		return nil
+	}
+	if l.written.Add(n) > l.max {
+		return l.err()
+	}
+	return nil
+}
+
+// stop stops counting the written bytes, once the clone is done.
+func (l *cloneLimit) stop() {
+	if l != nil {
+		l.stopped.Store(true)
+	}
+}
+
+// exceeded returns if the clone wrote more bytes than the limit.
+func (l *cloneLimit) exceeded() bool {
+	return l != nil && l.written.Load() > l.max
+}
+
+func (l *cloneLimit) err() error {
+	return fmt.Errorf("the clone of the source exceeds the maximum size %s: %w",
+		resource.NewQuantity(l.max, resource.BinarySI), ErrWorktreeTooLarge)
+}
+
+// fs returns the given filesystem with its writes counted.
+func (l *cloneLimit) fs(fs billy.Filesystem) billy.Filesystem {
+	if l == nil {
+		return fs
+	}
+	return &limitedFS{Filesystem: fs, limit: l}
+}
+
+// storer returns the given storer with the objects it stores in memory
+// counted. The objects stored on disk are counted by the filesystem of the
+// storer, see diskStorage, and those of the clone cache aren't counted.
+func (l *cloneLimit) storer(s storage.Storer) storage.Storer {
+	if m, ok := s.(*memory.Storage); ok && l != nil {
+		return &limitedStorer{Storage: m, limit: l}
+	}
+	return s
+}
+
+// diskStorage returns the client option storing the worktree and the Git
+// objects in the given directory, like gogit.WithDiskStorage, with their
+// writes counted.
+func (l *cloneLimit) diskStorage(dir string) gogit.ClientOption {
+	wt := l.fs(osfs.New(dir, osfs.WithBoundOS()))
+	dot := l.fs(osfs.New(filepath.Join(dir, extgogit.GitDirName), osfs.WithBoundOS()))
+	return func(c *gogit.Client) error {
+		if err := gogit.WithStorer(filesystem.NewStorage(dot, cache.NewObjectLRUDefault()))(c); err != nil {
+			return err
+		}
+		return gogit.WithWorkTreeFS(wt)(c)
+	}
+}
+
+// limitedFS is a filesystem whose files count their writes against a
+// cloneLimit.
+type limitedFS struct {
+	billy.Filesystem
+	limit *cloneLimit
+}
+
+func (fs *limitedFS) Create(filename string) (billy.File, error) {
+	return fs.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o666)
+}
+
+func (fs *limitedFS) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	f, err := fs.Filesystem.OpenFile(filename, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &limitedFile{File: f, limit: fs.limit}, nil
+}
+
+func (fs *limitedFS) TempFile(dir, prefix string) (billy.File, error) {
+	f, err := fs.Filesystem.TempFile(dir, prefix)
+	if err != nil {
+		return nil, err
+	}
+	return &limitedFile{File: f, limit: fs.limit}, nil
+}
+
+func (fs *limitedFS) Chroot(path string) (billy.Filesystem, error) {
+	chroot, err := fs.Filesystem.Chroot(path)
+	if err != nil {
+		return nil, err
+	}
+	return &limitedFS{Filesystem: chroot, limit: fs.limit}, nil
+}
+
+// limitedFile is a file of a limitedFS.
+type limitedFile struct {
+	billy.File
+	limit *cloneLimit
+}
+
+func (f *limitedFile) Write(p []byte) (int, error) {
+	if err := f.limit.add(int64(len(p))); err != nil {
+		return 0, err
+	}
+	return f.File.Write(p)
+}
+
+// limitedStorer is an in-memory storer whose objects count against a
+// cloneLimit.
+type limitedStorer struct {
+	*memory.Storage
+	limit *cloneLimit
+}
+
+func (s *limitedStorer) SetEncodedObject(obj plumbing.EncodedObject) (plumbing.Hash, error) {
+	if err := s.limit.add(obj.Size()); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return s.Storage.SetEncodedObject(obj)
+}
@@ -0,0 +1,230 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/memory"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/kubernetes/scheme"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+	"github.com/fluxcd/image-automation-controller/internal/testutil"
+)
+
+func TestSourceManager_checkWorktreeSize(t *testing.T) {
+	g := NewWithT(t)
+
+	dir := t.TempDir()
+	g.Expect(os.MkdirAll(filepath.Join(dir, ".git"), 0o755)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, "deploy.yaml"), []byte(strings.Repeat("a", 600)), 0o644)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, ".git", "pack"), []byte(strings.Repeat("a", 600)), 0o644)).To(Succeed())
+
+	// The Git directory counts as well.
+	sm := SourceManager{workingDir: dir, maxWorktreeSize: 1024}
+	err := sm.checkWorktreeSize()
+	g.Expect(err).To(MatchError(ErrWorktreeTooLarge))
+	g.Expect(err.Error()).To(ContainSubstring("size of the checked out source 1200 exceeds the maximum size 1Ki"))
+
+	sm.maxWorktreeSize = 2048
+	g.Expect(sm.checkWorktreeSize()).To(Succeed())
+	sm.maxWorktreeSize = 0
+	g.Expect(sm.checkWorktreeSize()).To(Succeed())
+
+	// The in-memory worktree is measured instead.
+	wt := memfs.New()
+	g.Expect(util.WriteFile(wt, "apps/deploy.yaml", []byte(strings.Repeat("a", 600)), 0o644)).To(Succeed())
+	sm = SourceManager{workingDir: "/", workTree: wt, maxWorktreeSize: 512}
+	g.Expect(sm.checkWorktreeSize()).To(MatchError(ErrWorktreeTooLarge))
+	sm.maxWorktreeSize = 1024
+	g.Expect(sm.checkWorktreeSize()).To(Succeed())
+
+	// Along with the Git objects stored in memory.
+	storer := memory.NewStorage()
+	obj := storer.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	w, err := obj.Writer()
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = w.Write([]byte(strings.Repeat("a", 600)))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(w.Close()).To(Succeed())
+	_, err = storer.SetEncodedObject(obj)
+	g.Expect(err).ToNot(HaveOccurred())
+	sm.storer = storer
+	g.Expect(sm.checkWorktreeSize()).To(MatchError(ErrWorktreeTooLarge))
+}
+
+func TestSourceManager_CheckoutSource_cloneLimit(t *testing.T) {
+	tests := []struct {
+		name            string
+		inMemory        bool
+		maxWorktreeSize int64
+		wantErr         string
+	}{
+		{
+			name:            "on disk",
+			maxWorktreeSize: 512,
+			wantErr:         "the clone of the source exceeds the maximum size 512",
+		},
+		{
+			name:            "in memory",
+			inMemory:        true,
+			maxWorktreeSize: 512,
+			wantErr:         "the clone of the source exceeds the maximum size 512",
+		},
+		{
+			name:            "on disk within the limit",
+			maxWorktreeSize: 1 << 20,
+		},
+		{
+			name:            "in memory within the limit",
+			inMemory:        true,
+			maxWorktreeSize: 1 << 20,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			gitServer := testutil.SetUpGitTestServer(g)
+			t.Cleanup(func() {
+				g.Expect(os.RemoveAll(gitServer.Root())).ToNot(HaveOccurred())
+				gitServer.StopHTTP()
+			})
+			repoPath := "/config-" + rand.String(5) + ".git"
+			testutil.InitGitRepo(g, gitServer, "testdata/appconfig", "main", repoPath)
+			repoURL, err := getRepoURL(gitServer, repoPath, "http")
+			g.Expect(err).ToNot(HaveOccurred())
+
+			gitRepo := &sourcev1.GitRepository{}
+			gitRepo.Name = "test-repo"
+			gitRepo.Namespace = "test-ns"
+			gitRepo.Spec = sourcev1.GitRepositorySpec{
+				URL:       repoURL,
+				Reference: &sourcev1.GitRepositoryRef{Branch: "main"},
+			}
+			obj := &imagev1.ImageUpdateAutomation{}
+			obj.Name = "test-update"
+			obj.Namespace = "test-ns"
+			obj.Spec = imagev1.ImageUpdateAutomationSpec{
+				SourceRef: imagev1.CrossNamespaceSourceReference{Kind: sourcev1.GitRepositoryKind, Name: gitRepo.Name},
+				GitSpec:   &imagev1.GitSpec{Commit: imagev1.CommitSpec{Author: testAuthor}},
+			}
+			c := fakeclient.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(gitRepo, obj).Build()
+
+			srcOpts := []SourceOption{WithSourceOptionMaxWorktreeSize(tt.maxWorktreeSize)}
+			if tt.inMemory {
+				srcOpts = append(srcOpts, WithSourceOptionInMemory())
+			}
+			sm, err := NewSourceManager(context.TODO(), c, obj, srcOpts...)
+			g.Expect(err).ToNot(HaveOccurred())
+			defer sm.Cleanup()
+
+			_, err = sm.CheckoutSource(context.TODO())
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ErrWorktreeTooLarge))
+				g.Expect(err.Error()).To(ContainSubstring(tt.wantErr))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+		})
+	}
+}
+
+func Test_cloneLimit(t *testing.T) {
+	g := NewWithT(t)
+
+	// util.WriteFile doesn't return the errors of the writes.
+	writeFile := func(fs billy.Filesystem, name string, size int) error {
+		f, err := fs.Create(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = f.Write([]byte(strings.Repeat("a", size)))
+		return err
+	}
+
+	g.Expect(newCloneLimit(0)).To(BeNil())
+	var unlimited *cloneLimit
+	fs := memfs.New()
+	g.Expect(unlimited.fs(fs)).To(BeIdenticalTo(fs))
+	g.Expect(writeFile(unlimited.fs(fs), "deploy.yaml", 2048)).To(Succeed())
+
+	limit := newCloneLimit(1024)
+	limited := limit.fs(memfs.New())
+	g.Expect(writeFile(limited, "apps/deploy.yaml", 600)).To(Succeed())
+	chroot, err := limited.Chroot("apps")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(writeFile(chroot, "service.yaml", 600)).To(MatchError(ErrWorktreeTooLarge))
+	g.Expect(limit.exceeded()).To(BeTrue())
+
+	// The objects stored in memory are counted as well.
+	limit = newCloneLimit(1024)
+	storer := limit.storer(memory.NewStorage())
+	obj := storer.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	obj.SetSize(2048)
+	_, err = storer.SetEncodedObject(obj)
+	g.Expect(err).To(MatchError(ErrWorktreeTooLarge))
+
+	// The writes after the clone aren't counted.
+	limit = newCloneLimit(1024)
+	limit.stop()
+	g.Expect(writeFile(limit.fs(memfs.New()), "deploy.yaml", 2048)).To(Succeed())
+	g.Expect(limit.exceeded()).To(BeFalse())
+}
+
+func TestNewSourceManager_workingDir(t *testing.T) {
+	g := NewWithT(t)
+
+	gitRepo := &sourcev1.GitRepository{}
+	gitRepo.Name = "foo"
+	gitRepo.Namespace = "test-ns"
+	gitRepo.Spec = sourcev1.GitRepositorySpec{
+		URL:       "https://example.com",
+		Reference: &sourcev1.GitRepositoryRef{Branch: "main"},
+	}
+	c := fakeclient.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(gitRepo).Build()
+
+	obj := &imagev1.ImageUpdateAutomation{}
+	obj.Name = "test-update"
+	obj.Namespace = "test-ns"
+	obj.Spec = imagev1.ImageUpdateAutomationSpec{
+		SourceRef: imagev1.CrossNamespaceSourceReference{Kind: sourcev1.GitRepositoryKind, Name: "foo"},
+		GitSpec:   &imagev1.GitSpec{Commit: imagev1.CommitSpec{Author: testAuthor}},
+	}
+
+	dir := t.TempDir()
+	sm, err := NewSourceManager(context.TODO(), c, obj, WithSourceOptionWorkingDir(dir))
+	g.Expect(err).ToNot(HaveOccurred())
+	defer sm.Cleanup()
+	g.Expect(filepath.Dir(sm.WorkDirectory())).To(Equal(dir))
+}
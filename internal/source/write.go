@@ -0,0 +1,179 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	extgogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/fluxcd/pkg/git"
+	"github.com/fluxcd/pkg/git/repository"
+	"github.com/fluxcd/pkg/runtime/acl"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+// writeRemoteName is the name of the remote of the write target, which isn't
+// stored in the configuration of the repository.
+const writeRemoteName = "write"
+
+// writeTarget is the GitRepository the commits are pushed to when it's not the
+// checked out one, e.g. a fork of it.
+type writeTarget struct {
+	key       types.NamespacedName
+	secretRef *meta.LocalObjectReference
+	url       string
+	authOpts  *git.AuthOptions
+	proxyOpts *transport.ProxyOptions
+}
+
+// getWriteTarget returns the GitRepository the push configuration refers to,
+// with its authentication and proxy options, or nil when the commits are
+// pushed to the checked out GitRepository.
+func getWriteTarget(ctx context.Context, c client.Client, namespace string, push *imagev1.PushSpec,
+	noCrossNamespaceRef bool) (*writeTarget, error) {
+	if push == nil || push.SourceRef == nil {
+		return nil, nil
+	}
+	ref := *push.SourceRef
+	if ref.Kind != sourcev1.GitRepositoryKind {
+		return nil, fmt.Errorf("push source kind '%s' not supported: %w", ref.Kind, ErrInvalidSourceConfiguration)
+	}
+	if noCrossNamespaceRef && ref.Namespace != "" && ref.Namespace != namespace {
+		return nil, acl.AccessDeniedError(fmt.Sprintf("can't access '%s', cross-namespace references have been blocked", ref.String()))
+	}
+	key, err := sourceKey(ctx, c, namespace, ref)
+	if err != nil {
+		return nil, err
+	}
+	repo := &sourcev1.GitRepository{}
+	if err := c.Get(ctx, key, repo); err != nil {
+		return nil, fmt.Errorf("failed to get the push git repository '%s': %w", key, err)
+	}
+	target := &writeTarget{key: key, secretRef: repo.Spec.SecretRef, url: repo.Spec.URL}
+	if target.authOpts, err = getAuthOpts(ctx, c, repo); err != nil {
+		return nil, err
+	}
+	if target.proxyOpts, err = getProxyOpts(ctx, c, repo, target.authOpts.Transport); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// pushEndpoint returns the URL of the repository the commits are pushed to,
+// with its authentication and proxy options.
+// The fallback credentials replace its own credentials once they're used.
+func (cfg *gitSrcCfg) pushEndpoint() (string, *git.AuthOptions, *transport.ProxyOptions) {
+	url, authOpts, proxyOpts := cfg.url, cfg.authOpts, cfg.proxyOpts
+	if cfg.writeTarget != nil {
+		url, authOpts, proxyOpts = cfg.writeTarget.url, cfg.writeTarget.authOpts, cfg.writeTarget.proxyOpts
+	}
+	if cfg.fallbackUsed {
+		authOpts = cfg.fallbackAuthOpts
+	}
+	return url, authOpts, proxyOpts
+}
+
+// withFallbackCredentials runs the Git operation with the repository the
+// commits are pushed to, and runs it again with the fallback credentials when
+// the remote rejects its own credentials. The fallback credentials are then
+// used by the next operations.
+func (sm SourceManager) withFallbackCredentials(ctx context.Context, op func() error) error {
+	err := op()
+	if err == nil || sm.srcCfg.fallbackAuthOpts == nil || sm.srcCfg.fallbackUsed || ClassOf(err) != ErrorClassAuth {
+		return err
+	}
+	log.FromContext(ctx).Info("credentials rejected by the remote, retrying with the fallback credentials",
+		"error", err.Error())
+	sm.srcCfg.fallbackUsed = true
+	if fallbackErr := op(); fallbackErr != nil {
+		sm.srcCfg.fallbackUsed = false
+		return fmt.Errorf("%w, and with the fallback credentials: %w", err, fallbackErr)
+	}
+	return nil
+}
+
+// push pushes the given configuration to the repository the commits are
+// pushed to. Without refspecs, the push branch is pushed.
+func (sm SourceManager) push(ctx context.Context, cfg repository.PushConfig) error {
+	return sm.withFallbackCredentials(ctx, func() error {
+		return sm.pushOnce(ctx, cfg)
+	})
+}
+
+// pushOnce pushes the given configuration with the Git client, or with a
+// remote of its own when the commits are pushed to another repository or with
+// the fallback credentials.
+func (sm SourceManager) pushOnce(ctx context.Context, cfg repository.PushConfig) error {
+	if sm.srcCfg.writeTarget == nil && !sm.srcCfg.fallbackUsed {
+		// The remote is already up-to-date when a dated commit made again
+		// was pushed by a previous attempt.
+		if err := sm.gitClient.Push(ctx, cfg); err != nil && !errors.Is(err, extgogit.NoErrAlreadyUpToDate) {
+			return classifyGitError(GitOperationPush, sm.deadlineError(ctx, GitOperationPush, err))
+		}
+		return nil
+	}
+
+	url, authOpts, proxyOpts := sm.srcCfg.pushEndpoint()
+	repo, err := sm.openRepository()
+	if err != nil {
+		return err
+	}
+	auth, err := remoteTransportAuth(authOpts)
+	if err != nil {
+		return fmt.Errorf("failed to construct auth method with options: %w", err)
+	}
+	refspecs := cfg.Refspecs
+	if len(refspecs) == 0 {
+		refspecs = []string{fmt.Sprintf("%s:%[1]s", plumbing.NewBranchReferenceName(sm.srcCfg.pushBranch))}
+	}
+	specs := make([]config.RefSpec, 0, len(refspecs))
+	for _, refspec := range refspecs {
+		specs = append(specs, config.RefSpec(refspec))
+	}
+	r := extgogit.NewRemote(repo.Storer, &config.RemoteConfig{
+		Name: writeRemoteName,
+		URLs: []string{url},
+	})
+	pushOpts := &extgogit.PushOptions{
+		RemoteName: writeRemoteName,
+		RefSpecs:   specs,
+		Force:      cfg.Force,
+		Auth:       auth,
+		CABundle:   authOpts.CAFile,
+		Options:    cfg.Options,
+	}
+	if proxyOpts != nil {
+		pushOpts.ProxyOptions = *proxyOpts
+	}
+	if err := r.PushContext(ctx, pushOpts); err != nil && !errors.Is(err, extgogit.NoErrAlreadyUpToDate) {
+		return classifyGitError(GitOperationPush, sm.deadlineError(ctx, GitOperationPush,
+			fmt.Errorf("failed to push to remote '%s': %w", url, err)))
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package validation validates the Kubernetes manifests changed by an
+// ImageUpdateAutomation against the OpenAPI schemas of the built-in
+// Kubernetes kinds, before they're committed.
+package validation
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	openapi_v2 "github.com/google/gnostic-models/openapiv2"
+	"google.golang.org/protobuf/proto"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"k8s.io/kube-openapi/pkg/validation/strfmt"
+	"k8s.io/kube-openapi/pkg/validation/validate"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/openapi/kubernetesapi/v1_21_2"
+)
+
+// schemaAsset is the embedded OpenAPI document of the Kubernetes API the
+// manifests are validated against. It's the same as the one used by kyaml.
+const schemaAsset = "kubernetesapi/v1_21_2/swagger.pb"
+
+// gvkExtension is the extension of the OpenAPI definitions with the
+// group, version and kind of the objects they describe.
+const gvkExtension = "x-kubernetes-group-version-kind"
+
+// Error is the error of manifests which don't conform to their schema.
+type Error struct {
+	// Problems are the messages of the invalid fields, prefixed with the
+	// file and the object they were found in.
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid manifests: %s", strings.Join(e.Problems, "; "))
+}
+
+type schemas struct {
+	definitions spec.Definitions
+	// byKind is the name of the definition of each "apiVersion/kind".
+	byKind map[string]string
+
+	mu       sync.Mutex
+	expanded map[string]*spec.Schema
+}
+
+var (
+	loadOnce     sync.Once
+	loadedSchema *schemas
+	loadErr      error
+)
+
+// loadSchemas parses the embedded OpenAPI document once.
+func loadSchemas() (*schemas, error) {
+	loadOnce.Do(func() {
+		doc := &openapi_v2.Document{}
+		if err := proto.Unmarshal(v1_21_2.MustAsset(schemaAsset), doc); err != nil {
+			loadErr = fmt.Errorf("failed to parse the Kubernetes OpenAPI schema: %w", err)
+			return
+		}
+		var swagger spec.Swagger
+		if _, err := swagger.FromGnostic(doc); err != nil {
+			loadErr = fmt.Errorf("failed to load the Kubernetes OpenAPI schema: %w", err)
+			return
+		}
+		s := &schemas{
+			definitions: swagger.Definitions,
+			byKind:      map[string]string{},
+			expanded:    map[string]*spec.Schema{},
+		}
+		for name, def := range swagger.Definitions {
+			gvks, ok := def.Extensions[gvkExtension].([]interface{})
+			if !ok {
+				continue
+			}
+			for _, v := range gvks {
+				gvk, ok := v.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				group, _ := gvk["group"].(string)
+				version, _ := gvk["version"].(string)
+				kind, _ := gvk["kind"].(string)
+				apiVersion := version
+				if group != "" {
+					apiVersion = group + "/" + version
+				}
+				s.byKind[apiVersion+"/"+kind] = name
+			}
+		}
+		loadedSchema = s
+	})
+	return loadedSchema, loadErr
+}
+
+// schemaFor returns the schema of the given kind, with its references
+// resolved, or nil if the kind isn't a built-in one.
+func (s *schemas) schemaFor(apiVersion, kind string) *spec.Schema {
+	name, ok := s.byKind[apiVersion+"/"+kind]
+	if !ok {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if schema, ok := s.expanded[name]; ok {
+		return schema
+	}
+	schema := s.expand(s.definitions[name], map[string]bool{name: true})
+	s.expanded[name] = &schema
+	return &schema
+}
+
+// expand resolves the references of the given schema to the definitions,
+// as the validator doesn't follow them. The definitions being expanded are
+// tracked to stop at recursive references, which then accept any value.
+func (s *schemas) expand(schema spec.Schema, expanding map[string]bool) spec.Schema {
+	if ref := schema.Ref.String(); ref != "" {
+		name := strings.TrimPrefix(ref, "#/definitions/")
+		def, ok := s.definitions[name]
+		if !ok || expanding[name] {
+			return spec.Schema{}
+		}
+		expanding[name] = true
+		defer delete(expanding, name)
+		return s.expand(def, expanding)
+	}
+
+	if schema.Properties != nil {
+		props := make(map[string]spec.Schema, len(schema.Properties))
+		for k, v := range schema.Properties {
+			props[k] = s.expand(v, expanding)
+		}
+		schema.Properties = props
+	}
+	if schema.Items != nil {
+		items := &spec.SchemaOrArray{}
+		if schema.Items.Schema != nil {
+			item := s.expand(*schema.Items.Schema, expanding)
+			items.Schema = &item
+		}
+		for _, v := range schema.Items.Schemas {
+			items.Schemas = append(items.Schemas, s.expand(v, expanding))
+		}
+		schema.Items = items
+	}
+	if schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil {
+		additional := s.expand(*schema.AdditionalProperties.Schema, expanding)
+		schema.AdditionalProperties = &spec.SchemaOrBool{Allows: true, Schema: &additional}
+	}
+	schema.AllOf = s.expandAll(schema.AllOf, expanding)
+	schema.AnyOf = s.expandAll(schema.AnyOf, expanding)
+	schema.OneOf = s.expandAll(schema.OneOf, expanding)
+	return schema
+}
+
+func (s *schemas) expandAll(schemas []spec.Schema, expanding map[string]bool) []spec.Schema {
+	if schemas == nil {
+		return nil
+	}
+	out := make([]spec.Schema, 0, len(schemas))
+	for _, v := range schemas {
+		out = append(out, s.expand(v, expanding))
+	}
+	return out
+}
+
+// Validate validates the objects of the given manifest files, keyed by
+// their path, against the schemas of their kinds. The objects of kinds
+// without a built-in schema, like custom resources, are skipped. It returns
+// an *Error listing the invalid fields, if any.
+func Validate(files map[string][]byte) error {
+	s, err := loadSchemas()
+	if err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var problems []string
+	for _, p := range paths {
+		nodes, err := (&kio.ByteReader{
+			Reader:                bytes.NewReader(files[p]),
+			OmitReaderAnnotations: true,
+		}).Read()
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", p, err))
+			continue
+		}
+		for _, node := range nodes {
+			schema := s.schemaFor(node.GetApiVersion(), node.GetKind())
+			if schema == nil {
+				continue
+			}
+			obj, err := node.Map()
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %s", p, err))
+				continue
+			}
+			res := validate.NewSchemaValidator(schema, nil, "", strfmt.Default).Validate(dropNulls(obj))
+			if res.IsValid() {
+				continue
+			}
+			id := fmt.Sprintf("%s: %s/%s", p, node.GetKind(), node.GetName())
+			if ns := node.GetNamespace(); ns != "" {
+				id = fmt.Sprintf("%s: %s/%s/%s", p, node.GetKind(), ns, node.GetName())
+			}
+			for _, e := range res.Errors {
+				problems = append(problems, fmt.Sprintf("%s: %s", id, e))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return &Error{Problems: problems}
+	}
+	return nil
+}
+
+// dropNulls removes the null fields of the object, like the API server
+// does, e.g. the `creationTimestamp: null` written by kubectl.
+func dropNulls(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, f := range v {
+			if f == nil {
+				delete(v, k)
+				continue
+			}
+			v[k] = dropNulls(f)
+		}
+	case []interface{}:
+		for i, f := range v {
+			v[i] = dropNulls(f)
+		}
+	}
+	return v
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+const validDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: apps
+  creationTimestamp: null
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: app
+  template:
+    metadata:
+      labels:
+        app: app
+    spec:
+      containers:
+      - name: app
+        image: ghcr.io/example/app:v1.0.0 # {"$imagepolicy": "apps:app"}
+        ports:
+        - containerPort: 8080
+        resources:
+          limits:
+            cpu: 100m
+`
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		problems []string
+	}{
+		{
+			name:  "valid manifests",
+			files: map[string]string{"deploy.yaml": validDeployment},
+		},
+		{
+			name: "custom resources are skipped",
+			files: map[string]string{"policy.yaml": `apiVersion: image.toolkit.fluxcd.io/v1beta2
+kind: ImagePolicy
+metadata:
+  name: app
+spec:
+  imageRepositoryRef: 42
+`},
+		},
+		{
+			name: "invalid fields",
+			files: map[string]string{
+				"deploy.yaml": validDeployment,
+				"apps/cronjob.yaml": `apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: job
+spec:
+  schedule: "@daily"
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: job
+            image:
+              name: ghcr.io/example/job
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: svc
+  namespace: apps
+spec:
+  ports:
+  - port: http
+`,
+			},
+			problems: []string{
+				"apps/cronjob.yaml: CronJob/job: spec.jobTemplate.spec.template.spec.containers[0].image in body must be of type string: \"object\"",
+				"apps/cronjob.yaml: Service/apps/svc: spec.ports[0].port in body must be of type integer: \"string\"",
+			},
+		},
+		{
+			name:     "unparsable manifests",
+			files:    map[string]string{"broken.yaml": "kind: [Deployment"},
+			problems: []string{"broken.yaml: "},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			files := map[string][]byte{}
+			for p, content := range tt.files {
+				files[p] = []byte(content)
+			}
+			err := Validate(files)
+			if tt.problems == nil {
+				g.Expect(err).ToNot(HaveOccurred())
+				return
+			}
+			var verr *Error
+			g.Expect(errors.As(err, &verr)).To(BeTrue())
+			g.Expect(verr.Problems).To(HaveLen(len(tt.problems)))
+			for i, p := range tt.problems {
+				g.Expect(verr.Problems[i]).To(HavePrefix(p))
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
+
+	"github.com/fluxcd/pkg/apis/meta"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+
+	imagev1beta1 "github.com/fluxcd/image-automation-controller/api/v1beta1"
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+	imagev1beta3 "github.com/fluxcd/image-automation-controller/api/v1beta3"
+)
+
+func newConversionScheme(g *WithT) *runtime.Scheme {
+	s := runtime.NewScheme()
+	g.Expect(imagev1beta1.AddToScheme(s)).To(Succeed())
+	g.Expect(imagev1.AddToScheme(s)).To(Succeed())
+	g.Expect(imagev1beta3.AddToScheme(s)).To(Succeed())
+	return s
+}
+
+func TestConversion_isConvertible(t *testing.T) {
+	g := NewWithT(t)
+
+	ok, err := conversion.IsConvertible(newConversionScheme(g), &imagev1.ImageUpdateAutomation{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(ok).To(BeTrue())
+}
+
+func TestConversion_roundTrip(t *testing.T) {
+	repoRef := imagev1.CrossNamespaceSourceReference{Kind: sourcev1.GitRepositoryKind, Name: "repo"}
+	forkRef := &imagev1.CrossNamespaceSourceReference{Kind: sourcev1.GitRepositoryKind, Name: "fork", Namespace: "forks"}
+	commit := imagev1.CommitSpec{
+		Author:          imagev1.CommitUser{Name: "fluxbot", Email: "fluxbot@example.com"},
+		MessageTemplate: "Update images",
+	}
+	routes := []imagev1.Route{{
+		Name:           "staging",
+		PolicySelector: metav1.LabelSelector{MatchLabels: map[string]string{"env": "staging"}},
+		Push:           imagev1.RoutePushSpec{Branch: "staging"},
+	}}
+	forcePush := true
+
+	tests := []struct {
+		name string
+		spec imagev1.ImageUpdateAutomationSpec
+	}{
+		{
+			name: "without git spec",
+			spec: imagev1.ImageUpdateAutomationSpec{SourceRef: repoRef},
+		},
+		{
+			name: "routes without git spec",
+			spec: imagev1.ImageUpdateAutomationSpec{SourceRef: repoRef, Routes: routes},
+		},
+		{
+			name: "commit only",
+			spec: imagev1.ImageUpdateAutomationSpec{
+				SourceRef: repoRef,
+				GitSpec:   &imagev1.GitSpec{Commit: commit},
+			},
+		},
+		{
+			name: "checkout strategy without reference",
+			spec: imagev1.ImageUpdateAutomationSpec{
+				SourceRef: repoRef,
+				GitSpec: &imagev1.GitSpec{
+					Checkout: &imagev1.GitCheckoutSpec{Strategy: imagev1.CheckoutStrategyFull},
+					Commit:   commit,
+				},
+			},
+		},
+		{
+			name: "push source reference only",
+			spec: imagev1.ImageUpdateAutomationSpec{
+				SourceRef: repoRef,
+				GitSpec: &imagev1.GitSpec{
+					Commit: commit,
+					Push:   &imagev1.PushSpec{SourceRef: forkRef},
+				},
+			},
+		},
+		{
+			name: "empty push",
+			spec: imagev1.ImageUpdateAutomationSpec{
+				SourceRef: repoRef,
+				GitSpec: &imagev1.GitSpec{
+					Commit: commit,
+					Push:   &imagev1.PushSpec{},
+				},
+			},
+		},
+		{
+			name: "all fields",
+			spec: imagev1.ImageUpdateAutomationSpec{
+				SourceRef: repoRef,
+				GitSpec: &imagev1.GitSpec{
+					Checkout: &imagev1.GitCheckoutSpec{
+						Reference: sourcev1.GitRepositoryRef{Branch: "main"},
+						Strategy:  imagev1.CheckoutStrategyShallow,
+					},
+					Commit: commit,
+					Push: &imagev1.PushSpec{
+						SourceRef:         forkRef,
+						Branch:            "image-updates",
+						Options:           map[string]string{"merge_request.create": ""},
+						Checks:            &imagev1.ChecksSpec{Provider: "github"},
+						CommitStatus:      &imagev1.CommitStatusSpec{Provider: "github"},
+						HeadCheck:         imagev1.HeadCheckRebase,
+						FallbackSecretRef: &meta.LocalObjectReference{Name: "fallback"},
+						ForcePush:         &forcePush,
+					},
+					Tag: &imagev1.TagSpec{Name: "latest-update"},
+				},
+				Interval:           metav1.Duration{Duration: 5 * time.Minute},
+				PolicySelector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "podinfo"}},
+				ServiceAccountName: "automation",
+				Update:             &imagev1.UpdateStrategy{Strategy: imagev1.UpdateStrategySetters, Path: "./apps"},
+				Suspend:            true,
+				Overrides:          []imagev1.PolicyOverride{{PolicyName: "podinfo", Tag: "6.0.0"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			hub := &imagev1.ImageUpdateAutomation{
+				ObjectMeta: metav1.ObjectMeta{Name: "podinfo", Namespace: "default"},
+				Spec:       tt.spec,
+				Status:     imagev1.ImageUpdateAutomationStatus{LastPushCommit: "abc123"},
+			}
+
+			spoke := &imagev1beta3.ImageUpdateAutomation{}
+			g.Expect(spoke.ConvertFrom(hub)).To(Succeed())
+			g.Expect(spoke.Spec.Checkout.SourceRef).To(Equal(tt.spec.SourceRef))
+			if tt.spec.GitSpec != nil && tt.spec.GitSpec.Push != nil {
+				g.Expect(spoke.Spec.Write.SourceRef).To(Equal(tt.spec.GitSpec.Push.SourceRef))
+			}
+			g.Expect(spoke.Spec.Write.Routes).To(Equal(tt.spec.Routes))
+			g.Expect(spoke.Status).To(Equal(hub.Status))
+
+			got := &imagev1.ImageUpdateAutomation{}
+			g.Expect(spoke.ConvertTo(got)).To(Succeed())
+			g.Expect(got).To(Equal(hub))
+
+			spokeAgain := &imagev1beta3.ImageUpdateAutomation{}
+			g.Expect(spokeAgain.ConvertFrom(got)).To(Succeed())
+			g.Expect(spokeAgain).To(Equal(spoke))
+		})
+	}
+}
+
+func TestConversion_webhook(t *testing.T) {
+	g := NewWithT(t)
+
+	server := httptest.NewServer(conversion.NewWebhookHandler(newConversionScheme(g)))
+	defer server.Close()
+
+	convert := func(desiredAPIVersion string, obj runtime.Object, into runtime.Object) {
+		t.Helper()
+		raw, err := json.Marshal(obj)
+		g.Expect(err).ToNot(HaveOccurred())
+		review, err := json.Marshal(map[string]any{
+			"apiVersion": "apiextensions.k8s.io/v1",
+			"kind":       "ConversionReview",
+			"request": map[string]any{
+				"uid":               "1",
+				"desiredAPIVersion": desiredAPIVersion,
+				"objects":           []json.RawMessage{raw},
+			},
+		})
+		g.Expect(err).ToNot(HaveOccurred())
+
+		resp, err := http.Post(server.URL, "application/json", bytes.NewReader(review))
+		g.Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+		g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		var result struct {
+			Response struct {
+				Result           metav1.Status     `json:"result"`
+				ConvertedObjects []json.RawMessage `json:"convertedObjects"`
+			} `json:"response"`
+		}
+		g.Expect(json.NewDecoder(resp.Body).Decode(&result)).To(Succeed())
+		g.Expect(result.Response.Result.Status).To(Equal(metav1.StatusSuccess), result.Response.Result.Message)
+		g.Expect(result.Response.ConvertedObjects).To(HaveLen(1))
+		g.Expect(json.Unmarshal(result.Response.ConvertedObjects[0], into)).To(Succeed())
+	}
+
+	fork := &imagev1beta3.ImageUpdateAutomation{
+		TypeMeta:   metav1.TypeMeta{APIVersion: imagev1beta3.GroupVersion.String(), Kind: imagev1beta3.ImageUpdateAutomationKind},
+		ObjectMeta: metav1.ObjectMeta{Name: "podinfo", Namespace: "default"},
+		Spec: imagev1beta3.ImageUpdateAutomationSpec{
+			Checkout: imagev1beta3.CheckoutSpec{
+				SourceRef: imagev1.CrossNamespaceSourceReference{Kind: sourcev1.GitRepositoryKind, Name: "podinfo"},
+				Reference: &sourcev1.GitRepositoryRef{Branch: "main"},
+			},
+			Write: imagev1beta3.WriteSpec{
+				SourceRef: &imagev1.CrossNamespaceSourceReference{Kind: sourcev1.GitRepositoryKind, Name: "podinfo-fork"},
+				Commit:    imagev1.CommitSpec{Author: imagev1.CommitUser{Email: "fluxbot@example.com"}},
+				Push:      &imagev1beta3.PushSpec{Branch: "image-updates"},
+			},
+		},
+	}
+
+	var hub imagev1.ImageUpdateAutomation
+	convert(imagev1.GroupVersion.String(), fork, &hub)
+	g.Expect(hub.APIVersion).To(Equal(imagev1.GroupVersion.String()))
+	g.Expect(hub.Spec.SourceRef.Name).To(Equal("podinfo"))
+	g.Expect(hub.Spec.GitSpec.Checkout.Reference.Branch).To(Equal("main"))
+	g.Expect(hub.Spec.GitSpec.Push.SourceRef.Name).To(Equal("podinfo-fork"))
+	g.Expect(hub.Spec.GitSpec.Push.Branch).To(Equal("image-updates"))
+
+	var spoke imagev1beta3.ImageUpdateAutomation
+	convert(imagev1beta3.GroupVersion.String(), &hub, &spoke)
+	g.Expect(spoke.APIVersion).To(Equal(imagev1beta3.GroupVersion.String()))
+	g.Expect(spoke.Spec).To(Equal(fork.Spec))
+
+	// The v1beta1 automations are converted through v1beta2, with the same
+	// fields.
+	old := &imagev1beta1.ImageUpdateAutomation{
+		TypeMeta:   metav1.TypeMeta{APIVersion: imagev1beta1.GroupVersion.String(), Kind: imagev1beta1.ImageUpdateAutomationKind},
+		ObjectMeta: metav1.ObjectMeta{Name: "podinfo", Namespace: "default"},
+		Spec: imagev1beta1.ImageUpdateAutomationSpec{
+			SourceRef: imagev1beta1.CrossNamespaceSourceReference{Kind: sourcev1.GitRepositoryKind, Name: "podinfo"},
+			GitSpec: &imagev1beta1.GitSpec{
+				Commit: imagev1beta1.CommitSpec{Author: imagev1beta1.CommitUser{Email: "fluxbot@example.com"}},
+				Push:   &imagev1beta1.PushSpec{Branch: "image-updates"},
+			},
+		},
+	}
+	var converted imagev1beta3.ImageUpdateAutomation
+	convert(imagev1beta3.GroupVersion.String(), old, &converted)
+	g.Expect(converted.APIVersion).To(Equal(imagev1beta3.GroupVersion.String()))
+	g.Expect(converted.Spec.Checkout.SourceRef.Name).To(Equal("podinfo"))
+	g.Expect(converted.Spec.Write.Commit.Author.Email).To(Equal("fluxbot@example.com"))
+	g.Expect(converted.Spec.Write.Push.Branch).To(Equal("image-updates"))
+}
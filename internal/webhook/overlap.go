@@ -0,0 +1,173 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhook implements the admission and conversion webhooks of the
+// ImageUpdateAutomations.
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+	"github.com/fluxcd/image-automation-controller/internal/policy"
+	"github.com/fluxcd/image-automation-controller/internal/source"
+	"github.com/fluxcd/image-automation-controller/pkg/update"
+)
+
+// OverlapPolicy is what to do at admission with an ImageUpdateAutomation
+// overlapping another one.
+type OverlapPolicy string
+
+const (
+	// OverlapPolicyDeny refuses the overlapping automations.
+	OverlapPolicyDeny OverlapPolicy = "deny"
+	// OverlapPolicyWarn admits the overlapping automations with a warning.
+	OverlapPolicyWarn OverlapPolicy = "warn"
+)
+
+// OverlapValidator is an admission.CustomValidator detecting the
+// ImageUpdateAutomations overlapping another one of their namespace, i.e.
+// pushing to the same repository and branch with overlapping update paths,
+// which makes them compete for the same files. The automations annotated
+// with imagev1.AllowOverlapAnnotation are always admitted.
+type OverlapValidator struct {
+	Client client.Client
+	Policy OverlapPolicy
+}
+
+// overlapTarget is what an automation updates: the files within the update
+// path pushed to the branch of the repository.
+type overlapTarget struct {
+	url, branch, path string
+}
+
+// SetupWithManager registers the validating webhook of the
+// ImageUpdateAutomations with the given manager.
+func (v *OverlapValidator) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&imagev1.ImageUpdateAutomation{}).
+		WithValidator(v).
+		Complete()
+}
+
+// ValidateCreate checks that the created automation doesn't overlap another
+// one.
+func (v *OverlapValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	auto, ok := obj.(*imagev1.ImageUpdateAutomation)
+	if !ok {
+		return nil, fmt.Errorf("expected an ImageUpdateAutomation, got %T", obj)
+	}
+	return v.validate(ctx, auto)
+}
+
+// ValidateUpdate checks that the updated automation doesn't overlap another
+// one, when its target changed. The automations overlapping before aren't
+// refused any other change.
+func (v *OverlapValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldAuto, ok := oldObj.(*imagev1.ImageUpdateAutomation)
+	if !ok {
+		return nil, fmt.Errorf("expected an ImageUpdateAutomation, got %T", oldObj)
+	}
+	auto, ok := newObj.(*imagev1.ImageUpdateAutomation)
+	if !ok {
+		return nil, fmt.Errorf("expected an ImageUpdateAutomation, got %T", newObj)
+	}
+	oldTarget, oldErr := v.target(ctx, oldAuto)
+	target, err := v.target(ctx, auto)
+	if oldErr == nil && err == nil && oldTarget == target {
+		return nil, nil
+	}
+	return v.validate(ctx, auto)
+}
+
+// ValidateDelete admits any deletion.
+func (v *OverlapValidator) ValidateDelete(context.Context, runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate returns a warning or an error, according to the policy, naming
+// the automations of the namespace the given automation overlaps. The
+// automations whose target can't be resolved, e.g. because their
+// GitRepository doesn't exist yet, are admitted, the controller reporting
+// the problem.
+func (v *OverlapValidator) validate(ctx context.Context, obj *imagev1.ImageUpdateAutomation) (admission.Warnings, error) {
+	if obj.GetAnnotations()[imagev1.AllowOverlapAnnotation] == "true" {
+		return nil, nil
+	}
+	target, err := v.target(ctx, obj)
+	if err != nil {
+		ctrl.LoggerFrom(ctx).V(1).Info("skipping overlap check", "error", err.Error())
+		return nil, nil
+	}
+
+	var autos imagev1.ImageUpdateAutomationList
+	if err := v.Client.List(ctx, &autos, client.InNamespace(obj.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list ImageUpdateAutomations: %w", err)
+	}
+	var overlapping []string
+	for i := range autos.Items {
+		other := &autos.Items[i]
+		if other.Name == obj.Name || !other.DeletionTimestamp.IsZero() {
+			continue
+		}
+		otherTarget, err := v.target(ctx, other)
+		if err != nil || !target.overlaps(otherTarget) {
+			continue
+		}
+		overlapping = append(overlapping, fmt.Sprintf("'%s'", other.Name))
+	}
+	if len(overlapping) == 0 {
+		return nil, nil
+	}
+	slices.Sort(overlapping)
+
+	msg := fmt.Sprintf("ImageUpdateAutomation overlaps %s, pushing to the same repository '%s' and branch '%s' with overlapping update paths; set the annotation %s: \"true\" if intended",
+		strings.Join(overlapping, ", "), target.url, target.branch, imagev1.AllowOverlapAnnotation)
+	if v.Policy == OverlapPolicyWarn {
+		return admission.Warnings{msg}, nil
+	}
+	return nil, fmt.Errorf("%s", msg)
+}
+
+// target returns the target of the given automation. The base directory of
+// a glob or templated update path stands for the path.
+func (v *OverlapValidator) target(ctx context.Context, obj *imagev1.ImageUpdateAutomation) (overlapTarget, error) {
+	url, branch, err := source.PushTargetOf(ctx, v.Client, obj)
+	if err != nil {
+		return overlapTarget{}, err
+	}
+	target := overlapTarget{url: url, branch: branch, path: "."}
+	if obj.Spec.Update != nil {
+		target.path = path.Clean(policy.UpdatePathBase(strings.TrimPrefix(obj.Spec.Update.Path, "/")))
+	}
+	return target, nil
+}
+
+// overlaps returns if the targets push to the same branch of the same
+// repository, with one update path within the other.
+func (t overlapTarget) overlaps(other overlapTarget) bool {
+	return t.url == other.url && t.branch == other.branch &&
+		(update.IsWithinPath(t.path, other.path) || update.IsWithinPath(other.path, t.path))
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1"
+
+	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+)
+
+func TestOverlapValidator(t *testing.T) {
+	newRepo := func(name, url string) client.Object {
+		return &sourcev1.GitRepository{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: sourcev1.GitRepositorySpec{
+				URL:       url,
+				Reference: &sourcev1.GitRepositoryRef{Branch: "main"},
+			},
+		}
+	}
+	newAutomation := func(name, repo, branch, path string) *imagev1.ImageUpdateAutomation {
+		auto := &imagev1.ImageUpdateAutomation{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: imagev1.ImageUpdateAutomationSpec{
+				SourceRef: imagev1.CrossNamespaceSourceReference{Kind: sourcev1.GitRepositoryKind, Name: repo},
+				GitSpec:   &imagev1.GitSpec{},
+				Update:    &imagev1.UpdateStrategy{Strategy: imagev1.UpdateStrategySetters, Path: path},
+			},
+		}
+		if branch != "" {
+			auto.Spec.GitSpec.Push = &imagev1.PushSpec{Branch: branch}
+		}
+		return auto
+	}
+
+	tests := []struct {
+		name        string
+		obj         *imagev1.ImageUpdateAutomation
+		policy      OverlapPolicy
+		wantErr     string
+		wantWarning string
+	}{
+		{
+			name:    "same path",
+			obj:     newAutomation("new", "app", "", "./clusters/prod"),
+			wantErr: "ImageUpdateAutomation overlaps 'existing', pushing to the same repository 'https://github.com/org/app' and branch 'main' with overlapping update paths",
+		},
+		{
+			name:    "parent path of another repository with the same URL",
+			obj:     newAutomation("new", "app-mirror", "", "./clusters"),
+			wantErr: "ImageUpdateAutomation overlaps 'existing'",
+		},
+		{
+			name:    "glob path",
+			obj:     newAutomation("new", "app", "", "./clusters/*/apps"),
+			wantErr: "ImageUpdateAutomation overlaps 'existing'",
+		},
+		{
+			name:    "templated path",
+			obj:     newAutomation("new", "app", "", "./clusters/{{ .Values.env }}"),
+			wantErr: "ImageUpdateAutomation overlaps 'existing'",
+		},
+		{
+			name:        "warning",
+			obj:         newAutomation("new", "app", "", "clusters/prod/apps"),
+			policy:      OverlapPolicyWarn,
+			wantWarning: "ImageUpdateAutomation overlaps 'existing'",
+		},
+		{
+			name: "sibling path",
+			obj:  newAutomation("new", "app", "", "./clusters/staging"),
+		},
+		{
+			name: "other branch",
+			obj:  newAutomation("new", "app", "staging", "./clusters/prod"),
+		},
+		{
+			name: "other repository",
+			obj:  newAutomation("new", "other", "", "./clusters/prod"),
+		},
+		{
+			name: "unknown repository",
+			obj:  newAutomation("new", "missing", "", "./clusters/prod"),
+		},
+		{
+			name: "allowed overlap",
+			obj: func() *imagev1.ImageUpdateAutomation {
+				auto := newAutomation("new", "app", "", "./clusters/prod")
+				auto.Annotations = map[string]string{imagev1.AllowOverlapAnnotation: "true"}
+				return auto
+			}(),
+		},
+		{
+			name: "itself",
+			obj:  newAutomation("existing", "app", "", "./clusters/prod"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			s := runtime.NewScheme()
+			g.Expect(imagev1.AddToScheme(s)).To(Succeed())
+			g.Expect(sourcev1.AddToScheme(s)).To(Succeed())
+			c := fakeclient.NewClientBuilder().WithScheme(s).WithObjects(
+				newRepo("app", "https://github.com/org/app"),
+				newRepo("app-mirror", "https://github.com/org/app.git/"),
+				newRepo("other", "https://github.com/org/other"),
+				newAutomation("existing", "app", "", "./clusters/prod"),
+			).Build()
+			v := &OverlapValidator{Client: c, Policy: OverlapPolicyDeny}
+			if tt.policy != "" {
+				v.Policy = tt.policy
+			}
+
+			warnings, err := v.ValidateCreate(context.TODO(), tt.obj)
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+			} else {
+				g.Expect(err).ToNot(HaveOccurred())
+			}
+			if tt.wantWarning != "" {
+				g.Expect(warnings).To(ConsistOf(ContainSubstring(tt.wantWarning)))
+			} else {
+				g.Expect(warnings).To(BeEmpty())
+			}
+		})
+	}
+}
+
+func TestOverlapValidator_ValidateUpdate(t *testing.T) {
+	g := NewWithT(t)
+
+	s := runtime.NewScheme()
+	g.Expect(imagev1.AddToScheme(s)).To(Succeed())
+	g.Expect(sourcev1.AddToScheme(s)).To(Succeed())
+	newAutomation := func(name, path string) *imagev1.ImageUpdateAutomation {
+		return &imagev1.ImageUpdateAutomation{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: imagev1.ImageUpdateAutomationSpec{
+				SourceRef: imagev1.CrossNamespaceSourceReference{Kind: sourcev1.GitRepositoryKind, Name: "app"},
+				GitSpec:   &imagev1.GitSpec{Push: &imagev1.PushSpec{Branch: "main"}},
+				Update:    &imagev1.UpdateStrategy{Strategy: imagev1.UpdateStrategySetters, Path: path},
+			},
+		}
+	}
+	first, second := newAutomation("first", "./apps"), newAutomation("second", "./apps")
+	c := fakeclient.NewClientBuilder().WithScheme(s).WithObjects(
+		&sourcev1.GitRepository{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+			Spec:       sourcev1.GitRepositorySpec{URL: "https://github.com/org/app"},
+		},
+		first, second,
+	).Build()
+	v := &OverlapValidator{Client: c, Policy: OverlapPolicyDeny}
+
+	// The automations overlapping before can still be changed, but not to
+	// another overlapping target.
+	changed := second.DeepCopy()
+	changed.Spec.Interval = metav1.Duration{Duration: 1}
+	_, err := v.ValidateUpdate(context.TODO(), second, changed)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	changed.Spec.Update.Path = "./apps/nested"
+	_, err = v.ValidateUpdate(context.TODO(), second, changed)
+	g.Expect(err).To(MatchError(ContainSubstring("ImageUpdateAutomation overlaps 'first'")))
+
+	changed.Spec.Update.Path = "./other"
+	_, err = v.ValidateUpdate(context.TODO(), second, changed)
+	g.Expect(err).ToNot(HaveOccurred())
+}
@@ -19,9 +19,13 @@ package main
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"time"
+	_ "time/tzdata"
 
 	flag "github.com/spf13/pflag"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -48,8 +52,13 @@ import (
 
 	"github.com/fluxcd/pkg/git"
 
+	imagev1beta1 "github.com/fluxcd/image-automation-controller/api/v1beta1"
 	imagev1 "github.com/fluxcd/image-automation-controller/api/v1beta2"
+	imagev1beta3 "github.com/fluxcd/image-automation-controller/api/v1beta3"
+	"github.com/fluxcd/image-automation-controller/internal/export"
 	"github.com/fluxcd/image-automation-controller/internal/features"
+	"github.com/fluxcd/image-automation-controller/internal/source"
+	"github.com/fluxcd/image-automation-controller/internal/webhook"
 
 	// +kubebuilder:scaffold:imports
 	"github.com/fluxcd/image-automation-controller/internal/controller"
@@ -67,11 +76,16 @@ var (
 	setupLog = ctrl.Log.WithName("setup")
 )
 
+// VERSION is the version of the controller, set at build time.
+var VERSION = "0.0.0-dev.0"
+
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	utilruntime.Must(imagev1_reflect.AddToScheme(scheme))
 	utilruntime.Must(sourcev1.AddToScheme(scheme))
+	utilruntime.Must(imagev1beta1.AddToScheme(scheme))
 	utilruntime.Must(imagev1.AddToScheme(scheme))
+	utilruntime.Must(imagev1beta3.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
 }
 
@@ -88,12 +102,75 @@ func main() {
 		featureGates          feathelper.FeatureGates
 		watchOptions          helper.WatchOptions
 		concurrent            int
+		policyApplyWorkers    int
+		cloneCacheDir         string
+		failureThreshold      int
+		failureInterval       time.Duration
+		neverUpdateImages     []string
+		changesExportAddress  string
+		defaultServiceAccount string
+		protectedBranches     []string
+		requireSignedCommits  bool
+		faultInjectionRate    float64
+		faultInjectionNS      []string
+		policyDebounceWindow  time.Duration
+		watchImagePolicies    bool
+		startupJitter         time.Duration
+		workingDir            string
+		maxWorktreeSize       string
+		updateMemoryBudget    string
+		gitUserAgent          string
+		gitHTTPHeaders        map[string]string
+		fleetMetrics          bool
+		overlapPolicy         string
 	)
 
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&eventsAddr, "events-addr", "", "The address of the events receiver.")
 	flag.StringVar(&healthAddr, "health-addr", ":9440", "The address the health endpoint binds to.")
 	flag.IntVar(&concurrent, "concurrent", 4, "The number of concurrent resource reconciles.")
+	flag.IntVar(&policyApplyWorkers, "policy-apply-workers", 0,
+		"The number of workers used to screen files and apply image policies within a single reconcile. Defaults to GOMAXPROCS when zero.")
+	flag.StringVar(&workingDir, "working-dir", "",
+		"The directory the sources are checked out in, e.g. on a dedicated volume. Defaults to the directory for temporary files.")
+	flag.StringVar(&maxWorktreeSize, "max-worktree-size", "",
+		"The maximum size of a checked out source, as a quantity, e.g. 2Gi. A larger source fails the reconciliation. Unlimited when empty.")
+	flag.StringVar(&updateMemoryBudget, "update-memory-budget", "",
+		"The estimated memory the YAML files being updated may take at once, as a quantity, e.g. 256Mi. The files are updated in batches within the budget. Unlimited when empty.")
+	flag.StringVar(&cloneCacheDir, "clone-cache-dir", filepath.Join(os.TempDir(), "clone-cache"),
+		"The directory the Git clone cache is stored in, when the GitCloneCache feature gate is enabled.")
+	flag.IntVar(&failureThreshold, "repeated-failure-threshold", 10,
+		"The number of consecutive identical failures after which an automation is marked Stalled and retried at the repeated failure interval. Disabled when zero.")
+	flag.DurationVar(&failureInterval, "repeated-failure-interval", time.Hour,
+		"The interval at which the automations stalled by repeated failures are retried.")
+	flag.StringSliceVar(&neverUpdateImages, "never-update-images", []string{},
+		"The list of the names of the images never to update, e.g. postgres or ghcr.io/org/app. A name matches the images with the same name or ending with '/' followed by the name.")
+	flag.StringVar(&changesExportAddress, "changes-export-address", "",
+		"The address of the HTTP endpoint the JSON report of the changes of each push is POSTed to. Disabled when empty.")
+	flag.StringVar(&defaultServiceAccount, "default-service-account", "",
+		"Default ServiceAccount impersonated to read the policies and the source of the automations which don't set one.")
+	flag.StringSliceVar(&protectedBranches, "protected-branches", []string{},
+		"The list of the patterns of the branches the automations can't push to directly, e.g. main,master,release/*. The automations pushing to a branch per policy aren't affected.")
+	flag.BoolVar(&requireSignedCommits, "require-signed-commits", false,
+		"Refuse to push unsigned commits, stalling the automations without signing key. Without it, the commits must only be signed in the namespaces annotated with image.toolkit.fluxcd.io/require-signed-commits: \"true\".")
+	flag.Float64Var(&faultInjectionRate, "fault-injection-rate", 0.1,
+		"The rate of the pushes failed with a synthetic timeout or rejection, when the GitFaultInjection feature gate is enabled.")
+	flag.StringSliceVar(&faultInjectionNS, "fault-injection-namespaces", []string{},
+		"The list of the test namespaces whose automations faults are injected in, when the GitFaultInjection feature gate is enabled. Required by the feature gate.")
+	flag.DurationVar(&policyDebounceWindow, "policy-debounce-window", 0,
+		"The window for which the changes of the latest image of the ImagePolicies of a namespace are delayed until none changes, before the automations are reconciled. Disabled when zero.")
+	flag.BoolVar(&watchImagePolicies, "watch-image-policies", true,
+		"Reconcile the ImageUpdateAutomations when the latest image of their ImagePolicies changes. When disabled, the automations run at their interval only.")
+	flag.DurationVar(&startupJitter, "startup-jitter", 0,
+		"The window the reconciliations following the start of the controller are spread over, to avoid hammering the Git servers. The requested reconciliations aren't delayed. Disabled when zero.")
+	flag.BoolVar(&fleetMetrics, "fleet-metrics", false,
+		"Enable the metrics summarizing all the ImageUpdateAutomations, e.g. the number of automations by state, for fleet dashboards.")
+	flag.StringVar(&overlapPolicy, "overlapping-automations", "allow",
+		"What to do at admission with an ImageUpdateAutomation pushing to the same repository and branch as another one of its namespace, with overlapping update paths: allow, warn or deny. The validating webhook is only served when not allow.")
+	flag.StringVar(&gitUserAgent, "git-user-agent", controllerName+"/"+VERSION,
+		"The HTTP user agent of the Git operations.")
+	flag.StringToStringVar(&gitHTTPHeaders, "git-http-headers", map[string]string{},
+		"The static headers set on the HTTP requests of the Git operations, e.g. X-Team=platform, for a proxy in front of the Git servers.")
 	flag.StringSliceVar(&git.KexAlgos, "ssh-kex-algos", []string{},
 		"The list of key exchange algorithms to use for ssh connections, arranged from most preferred to the least.")
 	flag.StringSliceVar(&git.HostKeyAlgos, "ssh-hostkey-algos", []string{},
@@ -118,6 +195,43 @@ func main() {
 		os.Exit(1)
 	}
 
+	switch overlapPolicy {
+	case "allow", string(webhook.OverlapPolicyWarn), string(webhook.OverlapPolicyDeny):
+	default:
+		setupLog.Error(fmt.Errorf("'%s' isn't one of allow, warn or deny", overlapPolicy), "invalid --overlapping-automations")
+		os.Exit(1)
+	}
+
+	if err := controller.ValidateProtectedBranches(protectedBranches); err != nil {
+		setupLog.Error(err, "invalid --protected-branches")
+		os.Exit(1)
+	}
+
+	var maxWorktreeBytes int64
+	if maxWorktreeSize != "" {
+		q, err := resource.ParseQuantity(maxWorktreeSize)
+		if err != nil || q.Sign() <= 0 {
+			setupLog.Error(fmt.Errorf("'%s' isn't a positive quantity", maxWorktreeSize), "invalid --max-worktree-size")
+			os.Exit(1)
+		}
+		maxWorktreeBytes = q.Value()
+	}
+	var updateMemoryBytes int64
+	if updateMemoryBudget != "" {
+		q, err := resource.ParseQuantity(updateMemoryBudget)
+		if err != nil || q.Sign() <= 0 {
+			setupLog.Error(fmt.Errorf("'%s' isn't a positive quantity", updateMemoryBudget), "invalid --update-memory-budget")
+			os.Exit(1)
+		}
+		updateMemoryBytes = q.Value()
+	}
+	if workingDir != "" {
+		if err := os.MkdirAll(workingDir, 0o700); err != nil {
+			setupLog.Error(err, "unable to create the working directory")
+			os.Exit(1)
+		}
+	}
+
 	watchNamespace := ""
 	if !watchOptions.AllNamespaces {
 		watchNamespace = os.Getenv("RUNTIME_NAMESPACE")
@@ -163,6 +277,9 @@ func main() {
 		Cache: ctrlcache.Options{
 			ByObject: map[ctrlclient.Object]ctrlcache.ByObject{
 				&imagev1.ImageUpdateAutomation{}: {Label: watchSelector},
+				// Only the metadata and the latest image of the policies
+				// are read.
+				&imagev1_reflect.ImagePolicy{}: {Transform: controller.TransformImagePolicy},
 			},
 		},
 		Metrics: metricsserver.Options{
@@ -195,22 +312,108 @@ func main() {
 		os.Exit(1)
 	}
 
+	var cloneCache *source.CloneCache
+	useCloneCache, err := features.Enabled(features.GitCloneCache)
+	if err != nil {
+		setupLog.Error(err, "unable to check feature gate "+features.GitCloneCache)
+		os.Exit(1)
+	}
+	if useCloneCache {
+		if cloneCache, err = source.NewCloneCache(cloneCacheDir); err != nil {
+			setupLog.Error(err, "unable to create clone cache")
+			os.Exit(1)
+		}
+	}
+
+	followRedirects, err := features.Enabled(features.GitFollowRedirects)
+	if err != nil {
+		setupLog.Error(err, "unable to check feature gate "+features.GitFollowRedirects)
+		os.Exit(1)
+	}
+	source.InstallHTTPClient(source.HTTPOptions{
+		FollowRedirects: followRedirects,
+		UserAgent:       gitUserAgent,
+		Headers:         gitHTTPHeaders,
+	})
+	source.InstallSSHClient()
+
+	var faultInjector *source.FaultInjector
+	useFaultInjection, err := features.Enabled(features.GitFaultInjection)
+	if err != nil {
+		setupLog.Error(err, "unable to check feature gate "+features.GitFaultInjection)
+		os.Exit(1)
+	}
+	if useFaultInjection {
+		if faultInjector, err = source.NewFaultInjector(faultInjectionRate, faultInjectionNS); err != nil {
+			setupLog.Error(err, "unable to enable fault injection")
+			os.Exit(1)
+		}
+		setupLog.Info("injecting faults in the pushes, for testing only", "rate", faultInjectionRate,
+			"namespaces", faultInjectionNS)
+	}
+
 	metricsH := helper.NewMetrics(mgr, metrics.MustMakeRecorder(), imagev1.ImageUpdateAutomationFinalizer)
+	if fleetMetrics {
+		controller.MustMakeFleetMetrics(mgr.GetClient())
+	}
 
 	ctx := ctrl.SetupSignalHandler()
 
+	var changesExporter export.Exporter
+	if changesExportAddress != "" {
+		changesExporter = export.NewHTTPExporter(changesExportAddress)
+	}
+
 	if err := (&controller.ImageUpdateAutomationReconciler{
 		Client:              mgr.GetClient(),
 		EventRecorder:       eventRecorder,
 		Metrics:             metricsH,
 		NoCrossNamespaceRef: aclOptions.NoCrossNamespaceRefs,
 		ControllerName:      controllerName,
+		PolicyApplyWorkers:  policyApplyWorkers,
+		CloneCache:          cloneCache,
+
+		RepeatedFailureThreshold: failureThreshold,
+		RepeatedFailureInterval:  failureInterval,
+		NeverUpdateImages:        neverUpdateImages,
+		DefaultServiceAccount:    defaultServiceAccount,
+		ProtectedBranches:        protectedBranches,
+		RequireSignedCommits:     requireSignedCommits,
+		FaultInjector:            faultInjector,
+		ChangesExporter:          changesExporter,
+		PushMetrics:              controller.MustMakePushMetrics(),
+		PolicyCache:              controller.MustMakePolicyCache(),
+		StartupJitter:            startupJitter,
+		WorkingDir:               workingDir,
+		MaxWorktreeSize:          maxWorktreeBytes,
+		UpdateMemoryBudget:       updateMemoryBytes,
 	}).SetupWithManager(ctx, mgr, controller.ImageUpdateAutomationReconcilerOptions{
-		RateLimiter: helper.GetRateLimiter(rateLimiterOptions),
+		RateLimiter:             helper.GetRateLimiter(rateLimiterOptions),
+		PolicyDebounceWindow:    policyDebounceWindow,
+		DisableImagePolicyWatch: !watchImagePolicies,
 	}); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ImageUpdateAutomation")
 		os.Exit(1)
 	}
+	if err := webhook.SetupConversionWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create conversion webhook", "webhook", "ImageUpdateAutomation")
+		os.Exit(1)
+	}
+	if overlapPolicy != "allow" {
+		if err := (&webhook.OverlapValidator{
+			Client: mgr.GetClient(),
+			Policy: webhook.OverlapPolicy(overlapPolicy),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ImageUpdateAutomation")
+			os.Exit(1)
+		}
+	}
+	// Remove the working directories left by the previous runs, e.g. after a
+	// crash.
+	if err := mgr.Add(controller.MustMakeWorkingDirCleaner(workingDir)); err != nil {
+		setupLog.Error(err, "unable to set up the working directory cleanup")
+		os.Exit(1)
+	}
 	// +kubebuilder:scaffold:builder
 
 	setupLog.Info("starting manager")
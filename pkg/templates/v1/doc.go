@@ -0,0 +1,27 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package v1 defines the data given to the templates of the
+// ImageUpdateAutomations, e.g. to the commit message template, for the
+// templates and the tools rendering them, like the generators of pull request
+// descriptions, to rely on its shape without importing the internal packages
+// of the controller.
+//
+// The types of this version of the package are stable: their fields and
+// methods are only ever added, never renamed, removed or changed, for the
+// existing templates to keep rendering the same. A breaking change is made in
+// a new version of the package.
+package v1
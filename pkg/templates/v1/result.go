@@ -0,0 +1,448 @@
+package v1
+
+import (
+	"cmp"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// ImageRef represents the image reference used to replace a field
+// value in an update.
+type ImageRef interface {
+	// String returns a string representation of the image ref as it
+	// is used in the update; e.g., "helloworld:v1.0.1"
+	String() string
+	// Identifier returns the tag or digest; e.g., "v1.0.1"
+	Identifier() string
+	// Repository returns the repository component of the ImageRef,
+	// with an implied defaults, e.g., "library/helloworld"
+	Repository() string
+	// Registry returns the registry component of the ImageRef, e.g.,
+	// "index.docker.io"
+	Registry() string
+	// Name gives the fully-qualified reference name, e.g.,
+	// "index.docker.io/library/helloworld:v1.0.1"
+	Name() string
+	// Policy gives the namespaced name of the image policy that led
+	// to the update.
+	Policy() types.NamespacedName
+}
+
+// NewImageRef returns the ImageRef of the given image reference, selected by
+// the image policy with the given namespaced name.
+func NewImageRef(ref name.Reference, policy types.NamespacedName) ImageRef {
+	return imageRef{Reference: ref, policy: policy}
+}
+
+type imageRef struct {
+	name.Reference
+	policy types.NamespacedName
+}
+
+// Policy gives the namespaced name of the policy that led to the
+// update.
+func (i imageRef) Policy() types.NamespacedName {
+	return i.policy
+}
+
+// Repository gives the repository component of the image ref.
+func (i imageRef) Repository() string {
+	return i.Context().RepositoryStr()
+}
+
+// Registry gives the registry component of the image ref.
+func (i imageRef) Registry() string {
+	return i.Context().Registry.String()
+}
+
+// ObjectIdentifier holds the identifying data for a particular
+// object. This won't always have a name (e.g., a kustomization.yaml).
+type ObjectIdentifier struct {
+	yaml.ResourceIdentifier
+}
+
+// WorkloadIdentifier identifies the workload running the image of a field of
+// a custom resource, when it isn't the object itself, e.g. the StrimziPodSet
+// of the brokers of a Kafka cluster.
+type WorkloadIdentifier struct {
+	Kind, Name string
+}
+
+// IsZero returns if the workload is unknown.
+func (w WorkloadIdentifier) IsZero() bool {
+	return w == WorkloadIdentifier{}
+}
+
+// String returns the workload as <kind>/<name>.
+func (w WorkloadIdentifier) String() string {
+	if w.IsZero() {
+		return ""
+	}
+	return w.Kind + "/" + w.Name
+}
+
+// Result reports the outcome of an automated update. It has a nested
+// structure file->objects->images. Different projections (e.g., all
+// the images, regardless of object) are available via methods.
+type Result struct {
+	Files map[string]FileResult
+}
+
+// FileResult gives the updates in a particular file.
+type FileResult struct {
+	Objects map[ObjectIdentifier][]ImageRef
+}
+
+// Images returns all the images that were involved in at least one
+// update, once each. They're sorted by the path of the file they were first
+// found in, then by the API version, kind, namespace and name of its object,
+// and in the order of the object within it.
+func (r Result) Images() []ImageRef {
+	seen := make(map[ImageRef]struct{})
+	var result []ImageRef
+	for _, path := range sortedFiles(r.Files) {
+		file := r.Files[path]
+		for _, oid := range sortedObjects(file.Objects) {
+			for _, ref := range file.Objects[oid] {
+				if _, ok := seen[ref]; !ok {
+					seen[ref] = struct{}{}
+					result = append(result, ref)
+				}
+			}
+		}
+	}
+	return result
+}
+
+// Objects returns a map of all the objects against the images updated
+// within, regardless of which file they appear in.
+func (r Result) Objects() map[ObjectIdentifier][]ImageRef {
+	result := make(map[ObjectIdentifier][]ImageRef)
+	for _, path := range sortedFiles(r.Files) {
+		for res, refs := range r.Files[path].Objects {
+			result[res] = append(result[res], refs...)
+		}
+	}
+	return result
+}
+
+// ResultV2 contains Result of update and also the file changes made during the
+// update. This extends the Result to include details about the exact changes
+// made to the files and the objects in them. It has a nested structure
+// file->objects->changes.
+type ResultV2 struct {
+	ImageResult Result
+	FileChanges map[string]ObjectChanges
+	// FileConflicts contains the changes of the fields whose value was
+	// changed since the previous update, with the nested structure of
+	// FileChanges. Depending on the conflict policy of the update, the
+	// conflicting changes were either made, and are also in FileChanges, or
+	// skipped.
+	FileConflicts map[string]ObjectChanges
+	// IgnoredChanges contains the changes which weren't made because their
+	// objects opt out of the updates with IgnoreAnnotation, with the nested
+	// structure of FileChanges.
+	IgnoredChanges map[string]ObjectChanges
+	// UnresolvedMarkers contains the marked fields referring to the setter
+	// of none of the policies of the update, e.g. of a policy in another
+	// namespace, with the nested structure of FileChanges. Only the
+	// OldValue, i.e. the value of the field, the Setter and the Line of
+	// their changes are set.
+	UnresolvedMarkers map[string]ObjectChanges
+	// SkippedFiles contains the files with markers which were skipped, with
+	// the reason they were skipped for, e.g. "Helm template".
+	SkippedFiles map[string]string
+	// LockFile is the path of the lock file, if it was written with a
+	// different content by the update.
+	LockFile string
+	// UpdatePaths contains the directories merged into the result, e.g. the
+	// directories matching a glob update path, relative to its base
+	// directory.
+	UpdatePaths []string
+}
+
+// HasChanges returns whether the update changed any file, including the lock
+// file.
+func (r ResultV2) HasChanges() bool {
+	return len(r.FileChanges) > 0 || r.LockFile != ""
+}
+
+// ObjectChanges contains all the changes made to objects.
+type ObjectChanges map[ObjectIdentifier][]Change
+
+// Change contains the setter that resulted in a Change, the old and the new
+// value after the Change.
+type Change struct {
+	OldValue string
+	NewValue string
+	Setter   string
+	// Workload is the workload running the image of the changed field of a
+	// custom resource, e.g. the StrimziPodSet of the brokers of a Kafka
+	// cluster. It's zero when the object is the workload itself, or when
+	// the workload is unknown.
+	Workload WorkloadIdentifier
+	// Line is the line of the changed field in its file, starting from
+	// one, e.g. to link to it in a Git hosting UI. It's zero when the line
+	// is unknown.
+	Line int
+}
+
+// AddChange adds changes to Resultv2 for a given file, object and changes
+// associated with it.
+func (r *ResultV2) AddChange(file string, objectID ObjectIdentifier, changes ...Change) {
+	if r.FileChanges == nil {
+		r.FileChanges = map[string]ObjectChanges{}
+	}
+	// Create an entry for the file if not present.
+	_, ok := r.FileChanges[file]
+	if !ok {
+		r.FileChanges[file] = ObjectChanges{}
+	}
+	// Append to the changes for the object.
+	r.FileChanges[file][objectID] = append(r.FileChanges[file][objectID], changes...)
+}
+
+// AddConflict adds conflicting changes to Resultv2 for a given file, object
+// and changes associated with it.
+func (r *ResultV2) AddConflict(file string, objectID ObjectIdentifier, changes ...Change) {
+	if r.FileConflicts == nil {
+		r.FileConflicts = map[string]ObjectChanges{}
+	}
+	if _, ok := r.FileConflicts[file]; !ok {
+		r.FileConflicts[file] = ObjectChanges{}
+	}
+	r.FileConflicts[file][objectID] = append(r.FileConflicts[file][objectID], changes...)
+}
+
+// AddIgnored adds the changes of an object opting out of the updates to
+// Resultv2 for a given file, object and changes associated with it.
+func (r *ResultV2) AddIgnored(file string, objectID ObjectIdentifier, changes ...Change) {
+	if r.IgnoredChanges == nil {
+		r.IgnoredChanges = map[string]ObjectChanges{}
+	}
+	if _, ok := r.IgnoredChanges[file]; !ok {
+		r.IgnoredChanges[file] = ObjectChanges{}
+	}
+	r.IgnoredChanges[file][objectID] = append(r.IgnoredChanges[file][objectID], changes...)
+}
+
+// AddUnresolved adds the marked fields referring to the setter of none of
+// the policies to Resultv2 for a given file, object and changes associated
+// with them.
+func (r *ResultV2) AddUnresolved(file string, objectID ObjectIdentifier, changes ...Change) {
+	if r.UnresolvedMarkers == nil {
+		r.UnresolvedMarkers = map[string]ObjectChanges{}
+	}
+	if _, ok := r.UnresolvedMarkers[file]; !ok {
+		r.UnresolvedMarkers[file] = ObjectChanges{}
+	}
+	r.UnresolvedMarkers[file][objectID] = append(r.UnresolvedMarkers[file][objectID], changes...)
+}
+
+// Changes returns all the changes that were made in at least one update, once
+// each. They're sorted by the path of the file they were first made in, then
+// by the API version, kind, namespace and name of its object, and in the order
+// of the object within it. The line of each change is the one of this first
+// file and object.
+func (r ResultV2) Changes() []Change {
+	seen := make(map[Change]struct{})
+	var result []Change
+	for _, file := range sortedFiles(r.FileChanges) {
+		objChanges := r.FileChanges[file]
+		for _, oid := range sortedObjects(objChanges) {
+			for _, change := range objChanges[oid] {
+				key := change
+				key.Line = 0
+				if _, ok := seen[key]; !ok {
+					seen[key] = struct{}{}
+					result = append(result, change)
+				}
+			}
+		}
+	}
+	return result
+}
+
+// Objects returns ObjectChanges, regardless of which file they appear in.
+func (r ResultV2) Objects() ObjectChanges {
+	result := make(ObjectChanges)
+	for _, file := range sortedFiles(r.FileChanges) {
+		for obj, change := range r.FileChanges[file] {
+			result[obj] = change
+		}
+	}
+	return result
+}
+
+// Merge adds the files of the other result to the result, with their paths
+// prefixed with the given directory, e.g. to combine the results of the
+// updates of several directories relative to a common one.
+func (r *ResultV2) Merge(dir string, other ResultV2) {
+	r.add(dir, other)
+	if dir = path.Clean(dir); !slices.Contains(r.UpdatePaths, dir) {
+		r.UpdatePaths = append(r.UpdatePaths, dir)
+	}
+}
+
+// Add adds the files of the other result, of an update of the same
+// directory, to the result, e.g. to combine the results of the updaters run
+// in turn on the directory.
+func (r *ResultV2) Add(other ResultV2) {
+	r.add("", other)
+}
+
+func (r *ResultV2) add(dir string, other ResultV2) {
+	for file, fr := range other.ImageResult.Files {
+		if r.ImageResult.Files == nil {
+			r.ImageResult.Files = map[string]FileResult{}
+		}
+		file = path.Join(dir, file)
+		existing, ok := r.ImageResult.Files[file]
+		if !ok {
+			r.ImageResult.Files[file] = fr
+			continue
+		}
+		for oid, refs := range fr.Objects {
+			for _, ref := range refs {
+				if !slices.Contains(existing.Objects[oid], ref) {
+					existing.Objects[oid] = append(existing.Objects[oid], ref)
+				}
+			}
+		}
+	}
+	for file, changes := range other.FileChanges {
+		for oid, c := range changes {
+			r.AddChange(path.Join(dir, file), oid, c...)
+		}
+	}
+	for file, conflicts := range other.FileConflicts {
+		for oid, c := range conflicts {
+			r.AddConflict(path.Join(dir, file), oid, c...)
+		}
+	}
+	for file, ignored := range other.IgnoredChanges {
+		for oid, c := range ignored {
+			r.AddIgnored(path.Join(dir, file), oid, c...)
+		}
+	}
+	for file, unresolved := range other.UnresolvedMarkers {
+		for oid, c := range unresolved {
+			r.AddUnresolved(path.Join(dir, file), oid, c...)
+		}
+	}
+	for file, reason := range other.SkippedFiles {
+		if r.SkippedFiles == nil {
+			r.SkippedFiles = map[string]string{}
+		}
+		r.SkippedFiles[path.Join(dir, file)] = reason
+	}
+	if other.LockFile != "" {
+		r.LockFile = path.Join(dir, other.LockFile)
+	}
+}
+
+// PathResult is the part of a ResultV2 within a directory, e.g. the
+// directory of an environment.
+type PathResult struct {
+	// Path is the directory, relative to the update path, or to the base
+	// directory of a glob update path. It's "." for the files at the root.
+	Path string
+	// ResultV2 contains the files within the directory, with the same paths
+	// as in the whole result, so that a template can render it like the
+	// whole result.
+	ResultV2
+}
+
+// Paths returns the result grouped by directory, sorted by directory. The
+// files are grouped by the directory they were merged from, when the update
+// path is a glob, and otherwise by the top-level directory they're in. The
+// lock file isn't part of any group.
+func (r ResultV2) Paths() []PathResult {
+	groups := map[string]*ResultV2{}
+	group := func(file string) *ResultV2 {
+		dir := r.pathOf(file)
+		if _, ok := groups[dir]; !ok {
+			groups[dir] = &ResultV2{}
+		}
+		return groups[dir]
+	}
+	for file, fr := range r.ImageResult.Files {
+		g := group(file)
+		if g.ImageResult.Files == nil {
+			g.ImageResult.Files = map[string]FileResult{}
+		}
+		g.ImageResult.Files[file] = fr
+	}
+	for file, changes := range r.FileChanges {
+		for oid, c := range changes {
+			group(file).AddChange(file, oid, c...)
+		}
+	}
+	for file, conflicts := range r.FileConflicts {
+		for oid, c := range conflicts {
+			group(file).AddConflict(file, oid, c...)
+		}
+	}
+	for file, ignored := range r.IgnoredChanges {
+		for oid, c := range ignored {
+			group(file).AddIgnored(file, oid, c...)
+		}
+	}
+	for file, reason := range r.SkippedFiles {
+		g := group(file)
+		if g.SkippedFiles == nil {
+			g.SkippedFiles = map[string]string{}
+		}
+		g.SkippedFiles[file] = reason
+	}
+
+	result := make([]PathResult, 0, len(groups))
+	for _, dir := range sortedFiles(groups) {
+		result = append(result, PathResult{Path: dir, ResultV2: *groups[dir]})
+	}
+	return result
+}
+
+// pathOf returns the directory grouping the given file in Paths: the
+// longest of the UpdatePaths containing the file, or else its top-level
+// directory.
+func (r ResultV2) pathOf(file string) string {
+	if len(r.UpdatePaths) > 0 {
+		dir := "."
+		for _, p := range r.UpdatePaths {
+			if strings.HasPrefix(file, p+"/") && (dir == "." || len(p) > len(dir)) {
+				dir = p
+			}
+		}
+		return dir
+	}
+	if i := strings.Index(file, "/"); i >= 0 {
+		return file[:i]
+	}
+	return "."
+}
+
+// sortedFiles returns the sorted paths of the given map of files, for the
+// results to be deterministic.
+func sortedFiles[V any](files map[string]V) []string {
+	return slices.Sorted(maps.Keys(files))
+}
+
+// sortedObjects returns the identifiers of the given map of objects, sorted by
+// API version, kind, namespace and name, for the results to be deterministic.
+func sortedObjects[V any](objects map[ObjectIdentifier]V) []ObjectIdentifier {
+	return slices.SortedFunc(maps.Keys(objects), func(a, b ObjectIdentifier) int {
+		return cmp.Or(
+			cmp.Compare(a.APIVersion, b.APIVersion),
+			cmp.Compare(a.Kind, b.Kind),
+			cmp.Compare(a.Namespace, b.Namespace),
+			cmp.Compare(a.Name, b.Name),
+		)
+	})
+}
@@ -0,0 +1,287 @@
+package v1
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// mustRef creates an ImageRef for use in tests. It panics if the ref
+// given is invalid.
+func mustRef(ref string) ImageRef {
+	r, err := name.ParseReference(ref)
+	if err != nil {
+		panic(err)
+	}
+	return NewImageRef(r, types.NamespacedName{})
+}
+
+func TestMustRef(t *testing.T) {
+	g := NewWithT(t)
+
+	t.Run("gives each component of an image ref", func(t *testing.T) {
+		ref := mustRef("helloworld:v1.0.1")
+		g.Expect(ref.String()).To(Equal("helloworld:v1.0.1"))
+		g.Expect(ref.Identifier()).To(Equal("v1.0.1"))
+		g.Expect(ref.Repository()).To(Equal("library/helloworld"))
+		g.Expect(ref.Registry()).To(Equal("index.docker.io"))
+		g.Expect(ref.Name()).To(Equal("index.docker.io/library/helloworld:v1.0.1"))
+	})
+
+	t.Run("deals with hostnames and digests", func(t *testing.T) {
+		image := "localhost:5000/org/helloworld@sha256:6745aaad46d795c9836632e1fb62f24b7e7f4c843144da8e47a5465c411a14be"
+		ref := mustRef(image)
+		g.Expect(ref.String()).To(Equal(image))
+		g.Expect(ref.Identifier()).To(Equal("sha256:6745aaad46d795c9836632e1fb62f24b7e7f4c843144da8e47a5465c411a14be"))
+		g.Expect(ref.Repository()).To(Equal("org/helloworld"))
+		g.Expect(ref.Registry()).To(Equal("localhost:5000"))
+		g.Expect(ref.Name()).To(Equal(image))
+	})
+}
+
+func TestUpdateResults(t *testing.T) {
+	g := NewWithT(t)
+
+	var result Result
+	objectNames := []ObjectIdentifier{
+		{yaml.ResourceIdentifier{
+			NameMeta: yaml.NameMeta{Namespace: "ns", Name: "foo"},
+		}},
+		{yaml.ResourceIdentifier{
+			NameMeta: yaml.NameMeta{Namespace: "ns", Name: "bar"},
+		}},
+	}
+
+	result = Result{
+		Files: map[string]FileResult{
+			"foo.yaml": {
+				Objects: map[ObjectIdentifier][]ImageRef{
+					objectNames[0]: {
+						mustRef("image:v1.0"),
+						mustRef("other:v2.0"),
+					},
+				},
+			},
+			"bar.yaml": {
+				Objects: map[ObjectIdentifier][]ImageRef{
+					objectNames[1]: {
+						mustRef("image:v1.0"),
+						mustRef("other:v2.0"),
+					},
+				},
+			},
+		},
+	}
+
+	g.Expect(result.Images()).To(Equal([]ImageRef{
+		mustRef("image:v1.0"),
+		mustRef("other:v2.0"),
+	}))
+
+	g.Expect(result.Objects()).To(Equal(map[ObjectIdentifier][]ImageRef{
+		objectNames[0]: {
+			mustRef("image:v1.0"),
+			mustRef("other:v2.0"),
+		},
+		objectNames[1]: {
+			mustRef("image:v1.0"),
+			mustRef("other:v2.0"),
+		},
+	}))
+}
+
+func TestResultV2(t *testing.T) {
+	g := NewWithT(t)
+
+	var result ResultV2
+	objectNames := []ObjectIdentifier{
+		{yaml.ResourceIdentifier{
+			NameMeta: yaml.NameMeta{Namespace: "ns", Name: "foo"},
+		}},
+		{yaml.ResourceIdentifier{
+			NameMeta: yaml.NameMeta{Namespace: "ns", Name: "bar"},
+		}},
+	}
+
+	result.AddChange("foo.yaml", objectNames[0], Change{
+		OldValue: "aaa",
+		NewValue: "bbb",
+		Setter:   "foo-ns:policy:name",
+	})
+	result.AddChange("bar.yaml", objectNames[1], Change{
+		OldValue: "cccc:v1.0",
+		NewValue: "cccc:v1.2",
+		Setter:   "foo-ns:policy",
+	})
+
+	result = ResultV2{
+		FileChanges: map[string]ObjectChanges{
+			"foo.yaml": {
+				objectNames[0]: []Change{
+					{
+						OldValue: "aaa",
+						NewValue: "bbb",
+						Setter:   "foo-ns:policy:name",
+					},
+				},
+			},
+			"bar.yaml": {
+				objectNames[1]: []Change{
+					{
+						OldValue: "cccc:v1.0",
+						NewValue: "cccc:v1.2",
+						Setter:   "foo-ns:policy",
+					},
+				},
+			},
+		},
+	}
+
+	// The changes are in the order of the files.
+	g.Expect(result.Changes()).To(Equal([]Change{
+		{
+			OldValue: "cccc:v1.0",
+			NewValue: "cccc:v1.2",
+			Setter:   "foo-ns:policy",
+		},
+		{
+			OldValue: "aaa",
+			NewValue: "bbb",
+			Setter:   "foo-ns:policy:name",
+		},
+	}))
+	g.Expect(result.Objects()).To(Equal(ObjectChanges{
+		objectNames[0]: []Change{
+			{
+				OldValue: "aaa",
+				NewValue: "bbb",
+				Setter:   "foo-ns:policy:name",
+			},
+		},
+		objectNames[1]: []Change{
+			{
+				OldValue: "cccc:v1.0",
+				NewValue: "cccc:v1.2",
+				Setter:   "foo-ns:policy",
+			},
+		},
+	}))
+}
+
+func TestResultV2_order(t *testing.T) {
+	g := NewWithT(t)
+
+	// Enough files and objects for the iteration order of the maps to vary.
+	var result ResultV2
+	var wantChanges []Change
+	var wantImages []ImageRef
+	result.ImageResult.Files = map[string]FileResult{}
+	for i := 0; i < 10; i++ {
+		file := fmt.Sprintf("file-%d.yaml", i)
+		result.ImageResult.Files[file] = FileResult{Objects: map[ObjectIdentifier][]ImageRef{}}
+		for _, kind := range []string{"DaemonSet", "Deployment", "StatefulSet"} {
+			oid := ObjectIdentifier{yaml.ResourceIdentifier{
+				TypeMeta: yaml.TypeMeta{APIVersion: "apps/v1", Kind: kind},
+				NameMeta: yaml.NameMeta{Namespace: "ns", Name: "app"},
+			}}
+			change := Change{
+				OldValue: "image:v1.0",
+				NewValue: fmt.Sprintf("image:v1.%d-%s", i, kind),
+				Setter:   "ns:policy",
+			}
+			result.AddChange(file, oid, change)
+			wantChanges = append(wantChanges, change)
+			ref := mustRef(change.NewValue)
+			result.ImageResult.Files[file].Objects[oid] = []ImageRef{ref}
+			wantImages = append(wantImages, ref)
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		g.Expect(result.Changes()).To(Equal(wantChanges))
+		g.Expect(result.ImageResult.Images()).To(Equal(wantImages))
+	}
+}
+
+func TestResultV2_Paths(t *testing.T) {
+	oid := ObjectIdentifier{yaml.ResourceIdentifier{
+		TypeMeta: yaml.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		NameMeta: yaml.NameMeta{Namespace: "ns", Name: "app"},
+	}}
+	change := func(tag string) Change {
+		return Change{OldValue: "image:v1.0", NewValue: "image:" + tag, Setter: "ns:policy"}
+	}
+
+	t.Run("top-level directories", func(t *testing.T) {
+		g := NewWithT(t)
+
+		var result ResultV2
+		result.AddChange("production/app.yaml", oid, change("v1.1"))
+		result.AddChange("staging/app.yaml", oid, change("v1.2"))
+		result.AddChange("staging/nested/app.yaml", oid, change("v1.3"))
+		result.AddChange("app.yaml", oid, change("v1.4"))
+		result.SkippedFiles = map[string]string{"staging/chart.yaml": "Helm template"}
+
+		paths := result.Paths()
+		g.Expect(paths).To(HaveLen(3))
+		g.Expect(paths[0].Path).To(Equal("."))
+		g.Expect(paths[0].Changes()).To(Equal([]Change{change("v1.4")}))
+		g.Expect(paths[1].Path).To(Equal("production"))
+		g.Expect(paths[1].Changes()).To(Equal([]Change{change("v1.1")}))
+		g.Expect(paths[2].Path).To(Equal("staging"))
+		g.Expect(paths[2].Changes()).To(Equal([]Change{change("v1.2"), change("v1.3")}))
+		g.Expect(paths[2].FileChanges).To(HaveKey("staging/nested/app.yaml"))
+		g.Expect(paths[2].SkippedFiles).To(Equal(map[string]string{"staging/chart.yaml": "Helm template"}))
+	})
+
+	t.Run("merged directories", func(t *testing.T) {
+		g := NewWithT(t)
+
+		var prod, staging ResultV2
+		prod.AddChange("app.yaml", oid, change("v1.1"))
+		staging.AddChange("app.yaml", oid, change("v1.2"))
+		staging.AddChange("nested/app.yaml", oid, change("v1.3"))
+
+		var result ResultV2
+		result.Merge("apps/production", prod)
+		result.Merge("apps/staging", staging)
+		result.Merge("apps/staging", ResultV2{})
+		result.Merge("apps/test", ResultV2{})
+		g.Expect(result.UpdatePaths).To(Equal([]string{"apps/production", "apps/staging", "apps/test"}))
+
+		paths := result.Paths()
+		g.Expect(paths).To(HaveLen(2))
+		g.Expect(paths[0].Path).To(Equal("apps/production"))
+		g.Expect(paths[0].FileChanges).To(HaveKey("apps/production/app.yaml"))
+		g.Expect(paths[1].Path).To(Equal("apps/staging"))
+		g.Expect(paths[1].Changes()).To(Equal([]Change{change("v1.2"), change("v1.3")}))
+	})
+}
+
+func TestResultV2_Add(t *testing.T) {
+	g := NewWithT(t)
+
+	oid := ObjectIdentifier{yaml.ResourceIdentifier{
+		TypeMeta: yaml.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		NameMeta: yaml.NameMeta{Namespace: "ns", Name: "app"},
+	}}
+	image, tag := mustRef("image:v1.1"), mustRef("sidecar:v1.1")
+	imageChange := Change{OldValue: "image:v1.0", NewValue: "image:v1.1", Setter: "ns:policy"}
+	tagChange := Change{OldValue: "v1.0", NewValue: "v1.1", Setter: "ns:sidecar:tag"}
+
+	var result, other ResultV2
+	result.AddChange("app.yaml", oid, imageChange)
+	result.ImageResult.Files = map[string]FileResult{"app.yaml": {Objects: map[ObjectIdentifier][]ImageRef{oid: {image}}}}
+	other.AddChange("app.yaml", oid, tagChange)
+	other.ImageResult.Files = map[string]FileResult{"app.yaml": {Objects: map[ObjectIdentifier][]ImageRef{oid: {image, tag}}}}
+
+	// The files of both results are combined, without an update path.
+	result.Add(other)
+	g.Expect(result.Changes()).To(Equal([]Change{imageChange, tagChange}))
+	g.Expect(result.ImageResult.Images()).To(Equal([]ImageRef{image, tag}))
+	g.Expect(result.UpdatePaths).To(BeEmpty())
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// TemplateData is the type of the value given to the commit message
+// template.
+type TemplateData struct {
+	AutomationObject types.NamespacedName
+	Updated          Result
+	Changed          ResultV2
+	Values           map[string]string
+	Source           SourceData
+	// ImageRepositories gives the ImageRepository of each policy, by name
+	// of the policy, when it's enabled for the templates.
+	ImageRepositories map[string]ImageRepositoryData
+}
+
+// ImageRepositoryData describes the ImageRepository a policy selects the
+// images of, in the template data.
+type ImageRepositoryData struct {
+	// Name and Namespace are the name and namespace of the ImageRepository.
+	Name      string
+	Namespace string
+	// Image is the image scanned by the ImageRepository, e.g.
+	// ghcr.io/stefanprodan/podinfo.
+	Image string
+	// Registry is the registry of the image, e.g. ghcr.io.
+	Registry string
+}
+
+// SourceData describes the source the changes are committed to, in the
+// template data.
+type SourceData struct {
+	// URL is the URL of the Git repository, without user information.
+	URL string
+	// Branch is the branch the changes are pushed to.
+	Branch string
+	// Revision is the checked out revision the changes are based on, e.g.
+	// main@sha1:<hash>.
+	Revision string
+}
+
+// UpdatePathData is the type of the value given to the template of the update
+// path.
+type UpdatePathData struct {
+	AutomationObject types.NamespacedName
+	Values           map[string]string
+	// Policies are the policies applied, sorted by namespace and name.
+	Policies []types.NamespacedName
+}
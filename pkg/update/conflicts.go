@@ -0,0 +1,88 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ConflictPolicy decides what to do with a conflict, i.e. a marked field
+// whose value was changed since the previous update, e.g. manually, and so
+// differs from the previous value of its setter.
+type ConflictPolicy string
+
+const (
+	// ConflictPolicyOverwrite updates the conflicting fields like any other
+	// field. This is the default.
+	ConflictPolicyOverwrite ConflictPolicy = "Overwrite"
+	// ConflictPolicySkip leaves the conflicting fields unchanged.
+	ConflictPolicySkip ConflictPolicy = "Skip"
+	// ConflictPolicyFail fails the update if any field is conflicting.
+	ConflictPolicyFail ConflictPolicy = "Fail"
+)
+
+// ErrConflict is the error of an update which failed due to a conflict, with
+// ConflictPolicyFail.
+var ErrConflict = errors.New("conflicting change")
+
+// WithUpdateOptionConflictPolicy configures the update to detect the
+// conflicts with the given previous images of the policies, and to handle
+// them with the given ConflictPolicy. The policies without a previous image
+// can't have conflicts.
+func WithUpdateOptionConflictPolicy(policy ConflictPolicy, previous map[types.NamespacedName]string) UpdateOption {
+	return func(uo *UpdateOptions) {
+		uo.conflictPolicy = policy
+		uo.previousImages = previous
+	}
+}
+
+// conflictCheck detects the conflicts of the setters.
+type conflictCheck struct {
+	policy ConflictPolicy
+	// previous is the previous value of each setter.
+	previous map[string]string
+}
+
+// isConflict returns if the old value of a field set by the given setter
+// differs from both the previous and the new values of the setter.
+func (c *conflictCheck) isConflict(setter, oldValue, newValue string) bool {
+	if c == nil {
+		return false
+	}
+	previous, ok := c.previous[setter]
+	return ok && oldValue != previous && oldValue != newValue
+}
+
+// skip returns if a conflicting field must be left unchanged.
+func (c *conflictCheck) skip() bool {
+	return c != nil && c.policy == ConflictPolicySkip
+}
+
+// fail returns if a conflict must fail the update.
+func (c *conflictCheck) fail() bool {
+	return c != nil && c.policy == ConflictPolicyFail
+}
+
+// error returns the error of a conflicting field, with ConflictPolicyFail.
+// The location of the field is given by the FileError wrapping it.
+func (c *conflictCheck) error(setter, oldValue string) error {
+	return fmt.Errorf("%w: value '%s' differs from the previous value '%s' of the setter",
+		ErrConflict, oldValue, c.previous[setter])
+}
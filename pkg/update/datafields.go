@@ -0,0 +1,554 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/kio/kioutil"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+
+	imagev1_reflect "github.com/fluxcd/image-reflector-controller/api/v1beta2"
+)
+
+// DataField is a field of a document held in the data of ConfigMaps or
+// Secrets, e.g. a JSON configuration file, which can't be marked, set to
+// the value of a setter by UpdateDataFields.
+type DataField struct {
+	// Kind is the kind of the objects holding the document, ConfigMap or
+	// Secret.
+	Kind string
+	// Name is the name of the objects holding the document, in any
+	// namespace.
+	Name string
+	// Key is the key of the document in the data of the objects: in
+	// `data` for a ConfigMap, or in `stringData` for a Secret.
+	Key string
+	// Path is the JSONPath of the fields in the document, with the child
+	// (`.name` or `['name']`), index (`[0]`) and wildcard (`[*]`)
+	// operators only, e.g. `$.services[*].image`.
+	Path string
+	// Setter is the name of the setter the fields are set to, e.g.
+	// "default:app:tag".
+	Setter string
+	// Base64 enables updating the base64-encoded documents: in
+	// `binaryData` for a ConfigMap, or in `data` for a Secret.
+	Base64 bool
+}
+
+// UpdateDataFields takes all YAML files from `dir`, sets the given fields
+// of the documents held in the data of their ConfigMaps and Secrets to the
+// values of the setters of the given policies, and writes the files it
+// updated back. The documents, which can be JSON or YAML, are only changed
+// at the fields, keeping their formatting, and written back the way they
+// were read, i.e. base64-encoded or not.
+//
+// The changes are returned for the objects holding the documents, with the
+// line of each field in its file. The UpdateOptions select the filesystem,
+// the scope of the setters, the maximum semver jump, the conflict policy
+// and the owner of the update, which apply as for the marked fields.
+func UpdateDataFields(tracelog logr.Logger, dir string, policies []imagev1_reflect.ImagePolicy, fields []DataField, options ...UpdateOption) (ResultV2, error) {
+	opts := &UpdateOptions{}
+	for _, o := range options {
+		o(opts)
+	}
+	if len(fields) == 0 {
+		return ResultV2{}, nil
+	}
+
+	paths := make([][]dataPathElement, len(fields))
+	keys := sets.New[string]()
+	for i, field := range fields {
+		path, err := parseDataPath(field.Path)
+		if err != nil {
+			return ResultV2{}, fmt.Errorf("invalid path of data field '%s' of %s '%s': %w", field.Key, field.Kind, field.Name, err)
+		}
+		paths[i] = path
+		keys.Insert(field.Key)
+	}
+
+	setterValues, imageRefs, err := policySetters(tracelog, policies)
+	if err != nil {
+		return ResultV2{}, err
+	}
+	scope := newPathScope(opts.policyPaths)
+	conflicts, err := newConflictCheck(opts)
+	if err != nil {
+		return ResultV2{}, err
+	}
+
+	// The files are screened for the keys of the documents, which they
+	// hold.
+	tokens := sets.List(keys)
+	reader := &ScreeningLocalReader{
+		Path:          dir,
+		Token:         tokens[0],
+		Tokens:        tokens[1:],
+		Trace:         tracelog,
+		Workers:       opts.workers,
+		SymlinkPolicy: opts.symlinkPolicy,
+		Root:          opts.symlinkRoot,
+	}
+	writer := &kio.LocalPackageWriter{
+		PackagePath: dir,
+	}
+	var writeFS filesys.FileSystem = filesys.MakeFsOnDisk()
+	if opts.workTree != nil {
+		fs := workTreeFileSystem{fs: opts.workTree}
+		reader.FileSystem.Set(fs)
+		writeFS = fs
+		writer.PackagePath = filepath.Join(string(filepath.Separator), dir)
+	}
+	nodes, err := reader.Read()
+	if err != nil {
+		return ResultV2{}, err
+	}
+	writable := newWritableFileSystem(writeFS, writer.PackagePath)
+	writable.encodings = reader.Encodings
+	writer.FileSystem.Set(writable)
+	ownership, err := newOwnershipCheck(opts, reader.FileOwners)
+	if err != nil {
+		return ResultV2{}, err
+	}
+
+	result := Result{
+		Files: make(map[string]FileResult),
+	}
+	resultV2 := ResultV2{SkippedFiles: reader.SkippedFiles}
+	recordChange := func(file string, oid ObjectIdentifier, ch Change) {
+		resultV2.AddChange(file, oid, ch)
+		ref, ok := imageRefs[ch.Setter]
+		if !ok {
+			return
+		}
+		fileres, ok := result.Files[file]
+		if !ok {
+			fileres = FileResult{
+				Objects: make(map[ObjectIdentifier][]ImageRef),
+			}
+			result.Files[file] = fileres
+		}
+		if !slices.Contains(fileres.Objects[oid], ImageRef(ref)) {
+			fileres.Objects[oid] = append(fileres.Objects[oid], ref)
+		}
+	}
+
+	filter := kio.FilterFunc(func(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+		filesToUpdate := sets.New[string]()
+		fileDocs := map[string]*documentSelector{}
+		for _, node := range nodes {
+			file, index, err := kioutil.GetFileAnnotations(node)
+			if err != nil {
+				return nil, err
+			}
+			doc, _ := strconv.Atoi(index)
+			if doc == 0 {
+				fileDocs[file] = documentsOf(node)
+			}
+			meta, err := node.GetMeta()
+			if err != nil {
+				continue
+			}
+			oid := ObjectIdentifier{ResourceIdentifier: meta.GetIdentifier()}
+			ignored := isIgnored(node) || !fileDocs[file].selects(doc, node)
+			// fileLine gives the line of a field of the node in its file,
+			// from its line in the document.
+			fileLine := func(line int) int {
+				starts := reader.DocumentLines[file]
+				if doc >= len(starts) || line == 0 {
+					return 0
+				}
+				return starts[doc] + line - 1
+			}
+
+			// The fields of each document are set together, for the
+			// offsets of the fields in the document to hold.
+			for _, key := range tokens {
+				var edits []dataEdit
+				var value *yaml.RNode
+				var encoded bool
+				for i, field := range fields {
+					if field.Key != key || field.Kind != meta.Kind || field.Name != meta.Name {
+						continue
+					}
+					if value == nil {
+						if value, encoded, err = dataValue(node, field); err != nil {
+							return nil, &FileError{Path: file, Document: doc, Err: err}
+						}
+						if value == nil {
+							break
+						}
+					}
+					if encoded && !field.Base64 {
+						return nil, &FileError{Path: file, Document: doc, Line: value.YNode().Line, Setter: field.Setter,
+							Err: fmt.Errorf("document '%s' of %s '%s' is base64-encoded, and base64 isn't enabled for its data fields", key, meta.Kind, meta.Name)}
+					}
+					fieldEdits, err := dataFieldEdits(value, encoded, paths[i])
+					if err != nil {
+						return nil, &FileError{Path: file, Document: doc, Line: value.YNode().Line, Setter: field.Setter,
+							Err: fmt.Errorf("document '%s' of %s '%s': %w", key, meta.Kind, meta.Name, err)}
+					}
+					for _, edit := range fieldEdits {
+						newValue, ok := setterValues[field.Setter]
+						if !ok || newValue == edit.oldValue || !scope.allows(field.Setter, file) {
+							continue
+						}
+						ch := Change{OldValue: edit.oldValue, NewValue: newValue, Setter: field.Setter, Line: fileLine(edit.line)}
+						if ignored {
+							resultV2.AddIgnored(file, oid, ch)
+							continue
+						}
+						if exceedsSemverJump(opts.maxSemverJump, field.Setter, edit.oldValue, newValue) {
+							return nil, &FileError{Path: file, Document: doc, Line: edit.line, Setter: field.Setter,
+								Err: semverJumpError(edit.oldValue, newValue, opts.maxSemverJump)}
+						}
+						if conflicts.isConflict(field.Setter, edit.oldValue, newValue) {
+							if conflicts.fail() {
+								return nil, &FileError{Path: file, Document: doc, Line: edit.line, Setter: field.Setter,
+									Err: conflicts.error(field.Setter, edit.oldValue)}
+							}
+							resultV2.AddConflict(file, oid, ch)
+							if conflicts.skip() {
+								continue
+							}
+						}
+						// A field selected by several data fields is set
+						// by the first one.
+						if slices.ContainsFunc(edits, func(e dataEdit) bool { return e.start == edit.start }) {
+							continue
+						}
+						edit.newValue = newValue
+						edits = append(edits, edit)
+						recordChange(file, oid, ch)
+					}
+				}
+				if len(edits) == 0 {
+					continue
+				}
+				setDataValue(value, encoded, edits)
+				tracelog.Info("setting data fields", "file", file, "key", key, "count", len(edits))
+				filesToUpdate.Insert(file)
+			}
+		}
+
+		var nodesInUpdatedFiles []*yaml.RNode
+		for _, node := range nodes {
+			file, _, _ := kioutil.GetFileAnnotations(node)
+			if filesToUpdate.Has(file) {
+				nodesInUpdatedFiles = append(nodesInUpdatedFiles, node)
+			}
+		}
+		return nodesInUpdatedFiles, nil
+	})
+
+	pipeline := kio.Pipeline{
+		Inputs:  []kio.Reader{&kio.PackageBuffer{Nodes: nodes}},
+		Outputs: []kio.Writer{writer},
+		Filters: []kio.Filter{filter},
+	}
+	if ownership != nil {
+		pipeline.Filters = append(pipeline.Filters, ownership.filter())
+	}
+	if err := pipeline.Execute(); err != nil {
+		return ResultV2{}, err
+	}
+
+	resultV2.ImageResult = result
+	return resultV2, nil
+}
+
+// dataValue returns the value holding the document of the given field in
+// the data of the given object, if any, and whether it's base64-encoded.
+// The plain document is looked up first.
+func dataValue(node *yaml.RNode, field DataField) (*yaml.RNode, bool, error) {
+	plain, encoded := "data", "binaryData"
+	if field.Kind == "Secret" {
+		plain, encoded = "stringData", "data"
+	}
+	value, err := node.Pipe(yaml.Lookup(plain, field.Key))
+	if err != nil || value != nil {
+		return value, false, err
+	}
+	value, err = node.Pipe(yaml.Lookup(encoded, field.Key))
+	return value, value != nil, err
+}
+
+// dataEdit is the replacement of a field of a document, at the given byte
+// offsets of its scalar in the document.
+type dataEdit struct {
+	start, end         int
+	style              yaml.Style
+	oldValue, newValue string
+	// line is the line of the field in the YAML document holding the
+	// document, starting from one.
+	line int
+}
+
+// dataFieldEdits returns the edits of the fields at the given path in the
+// document held by the given value, with their old values.
+func dataFieldEdits(value *yaml.RNode, encoded bool, path []dataPathElement) ([]dataEdit, error) {
+	text, err := dataText(value, encoded)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(text), &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse document: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	nodes := []*yaml.Node{doc.Content[0]}
+	for _, elem := range path {
+		var next []*yaml.Node
+		for _, n := range nodes {
+			switch n.Kind {
+			case yaml.MappingNode:
+				for i := 0; i+1 < len(n.Content); i += 2 {
+					if elem.wildcard || (!elem.isIndex && n.Content[i].Value == elem.key) {
+						next = append(next, n.Content[i+1])
+					}
+				}
+			case yaml.SequenceNode:
+				if elem.wildcard {
+					next = append(next, n.Content...)
+				} else if elem.isIndex && elem.index < len(n.Content) {
+					next = append(next, n.Content[elem.index])
+				}
+			}
+		}
+		nodes = next
+	}
+
+	// The lines of the fields are only known in the YAML document when
+	// the document is a block scalar, starting on the line after its key.
+	line := value.YNode().Line
+	block := !encoded && (value.YNode().Style == yaml.LiteralStyle || value.YNode().Style == yaml.FoldedStyle)
+
+	starts := lineStarts(text)
+	var edits []dataEdit
+	for _, n := range nodes {
+		if n.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("field at line %d isn't a scalar", n.Line)
+		}
+		edit, err := scalarEdit(text, starts, n)
+		if err != nil {
+			return nil, err
+		}
+		edit.line = line
+		if block {
+			edit.line = line + n.Line
+		}
+		edits = append(edits, edit)
+	}
+	return edits, nil
+}
+
+// lineStarts returns the byte offsets the lines of the given text start at.
+func lineStarts(text string) []int {
+	starts := []int{0}
+	for i := range len(text) {
+		if text[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+// scalarEdit returns the edit of the given scalar of the given text, whose
+// lines start at the given byte offsets, with its old value.
+func scalarEdit(text string, starts []int, n *yaml.Node) (dataEdit, error) {
+	start := starts[n.Line-1]
+	for range n.Column - 1 {
+		_, size := utf8.DecodeRuneInString(text[start:])
+		start += size
+	}
+	end, err := scalarEnd(text, start, n)
+	if err != nil {
+		return dataEdit{}, fmt.Errorf("field at line %d: %w", n.Line, err)
+	}
+	return dataEdit{start: start, end: end, style: n.Style, oldValue: n.Value}, nil
+}
+
+// scalarEnd returns the byte offset of the end of the given scalar, which
+// starts at the given offset of the text. Only the single line scalars
+// without properties, i.e. anchors and tags, are supported.
+func scalarEnd(text string, start int, n *yaml.Node) (int, error) {
+	switch n.Style {
+	case yaml.DoubleQuotedStyle:
+		if text[start] != '"' {
+			return 0, fmt.Errorf("unsupported scalar")
+		}
+		for i := start + 1; i < len(text) && text[i] != '\n'; i++ {
+			switch text[i] {
+			case '\\':
+				i++
+			case '"':
+				return i + 1, nil
+			}
+		}
+	case yaml.SingleQuotedStyle:
+		if text[start] != '\'' {
+			return 0, fmt.Errorf("unsupported scalar")
+		}
+		for i := start + 1; i < len(text) && text[i] != '\n'; i++ {
+			if text[i] == '\'' {
+				if i+1 < len(text) && text[i+1] == '\'' {
+					i++
+					continue
+				}
+				return i + 1, nil
+			}
+		}
+	case 0:
+		if strings.HasPrefix(text[start:], n.Value) {
+			return start + len(n.Value), nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported scalar, e.g. multi-line")
+}
+
+// dataText returns the document held by the given value.
+func dataText(value *yaml.RNode, encoded bool) (string, error) {
+	if !encoded {
+		return value.YNode().Value, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(value.YNode().Value)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode base64 document: %w", err)
+	}
+	return string(data), nil
+}
+
+// setDataValue applies the given edits to the document held by the given
+// value, keeping the style of the edited scalars.
+func setDataValue(value *yaml.RNode, encoded bool, edits []dataEdit) {
+	// The error was checked reading the fields.
+	text, _ := dataText(value, encoded)
+	slices.SortFunc(edits, func(a, b dataEdit) int { return b.start - a.start })
+	for _, edit := range edits {
+		text = text[:edit.start] + quoteScalar(edit.newValue, edit.style) + text[edit.end:]
+	}
+	if encoded {
+		text = base64.StdEncoding.EncodeToString([]byte(text))
+	}
+	value.YNode().Value = text
+}
+
+// quoteScalar returns the given value as a scalar of the given style. The
+// double-quoted scalars are quoted as JSON strings, which are valid YAML.
+func quoteScalar(value string, style yaml.Style) string {
+	switch style {
+	case yaml.DoubleQuotedStyle:
+		var b bytes.Buffer
+		enc := json.NewEncoder(&b)
+		enc.SetEscapeHTML(false)
+		// Encoding a string doesn't fail.
+		_ = enc.Encode(value)
+		return strings.TrimSuffix(b.String(), "\n")
+	case yaml.SingleQuotedStyle:
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	default:
+		return value
+	}
+}
+
+// dataPathElement is an element of the JSONPath of a data field: a child,
+// an index or a wildcard.
+type dataPathElement struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// parseDataPath parses the given JSONPath, e.g. `$.services[*].image`,
+// which can be wrapped in braces as with kubectl and omit the root.
+func parseDataPath(path string) ([]dataPathElement, error) {
+	p := strings.TrimSpace(path)
+	if strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}") {
+		p = p[1 : len(p)-1]
+	}
+	p = strings.TrimPrefix(p, "$")
+	if p != "" && p[0] != '.' && p[0] != '[' {
+		p = "." + p
+	}
+
+	var elems []dataPathElement
+	for p != "" {
+		switch {
+		case strings.HasPrefix(p, ".."):
+			return nil, fmt.Errorf("recursive descent isn't supported")
+		case p[0] == '.':
+			p = p[1:]
+			n := strings.IndexAny(p, ".[")
+			if n < 0 {
+				n = len(p)
+			}
+			switch name := p[:n]; name {
+			case "":
+				return nil, fmt.Errorf("empty child name")
+			case "*":
+				elems = append(elems, dataPathElement{wildcard: true})
+			default:
+				elems = append(elems, dataPathElement{key: name})
+			}
+			p = p[n:]
+		case p[0] == '[':
+			if len(p) > 1 && (p[1] == '\'' || p[1] == '"') {
+				n := strings.IndexByte(p[2:], p[1])
+				if n < 0 || !strings.HasPrefix(p[2+n+1:], "]") {
+					return nil, fmt.Errorf("unterminated child name in %q", p)
+				}
+				elems = append(elems, dataPathElement{key: p[2 : 2+n]})
+				p = p[2+n+2:]
+				continue
+			}
+			n := strings.IndexByte(p, ']')
+			if n < 0 {
+				return nil, fmt.Errorf("unterminated subscript in %q", p)
+			}
+			if inner := p[1:n]; inner == "*" {
+				elems = append(elems, dataPathElement{wildcard: true})
+			} else if index, err := strconv.Atoi(inner); err == nil && index >= 0 {
+				elems = append(elems, dataPathElement{index: index, isIndex: true})
+			} else {
+				return nil, fmt.Errorf("unsupported subscript %q", p[:n+1])
+			}
+			p = p[n+1:]
+		default:
+			return nil, fmt.Errorf("unexpected %q", p)
+		}
+	}
+	if len(elems) == 0 {
+		return nil, fmt.Errorf("path selects the whole document")
+	}
+	return elems, nil
+}
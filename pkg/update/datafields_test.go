@@ -0,0 +1,270 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+
+	imagev1_reflect "github.com/fluxcd/image-reflector-controller/api/v1beta2"
+)
+
+func TestUpdateDataFields(t *testing.T) {
+	const configMap = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+  namespace: apps
+data:
+  config.json: |
+    {
+      "image": "image:v1.0.0",
+      "sidecars": [{"name": "proxy", "image": "proxy:v2"}],
+      "tag": 'v1.0.0'
+    }
+  config.yaml: |
+    app:
+      image: image:v1.0.0
+`
+	encoded := base64.StdEncoding.EncodeToString([]byte(`{"image": "image:v1.0.0"}`))
+	updated := base64.StdEncoding.EncodeToString([]byte(`{"image": "image:v1.0.1"}`))
+	secret := `apiVersion: v1
+kind: Secret
+metadata:
+  name: app-secret
+  namespace: apps
+data:
+  config.json: ` + encoded + `
+`
+
+	policy := imagev1_reflect.ImagePolicy{}
+	policy.Namespace = "automation-ns"
+	policy.Name = "policy"
+	policy.Status.LatestImage = "image:v1.0.1"
+
+	tests := []struct {
+		name        string
+		file        string
+		fields      []DataField
+		options     []UpdateOption
+		want        string
+		wantChanges []Change
+		wantErr     string
+	}{
+		{
+			name: "JSON document",
+			file: configMap,
+			fields: []DataField{
+				{Kind: "ConfigMap", Name: "app-config", Key: "config.json", Path: "$.image", Setter: "automation-ns:policy"},
+				{Kind: "ConfigMap", Name: "app-config", Key: "config.json", Path: "$['tag']", Setter: "automation-ns:policy:tag"},
+			},
+			want: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+  namespace: apps
+data:
+  config.json: |
+    {
+      "image": "image:v1.0.1",
+      "sidecars": [{"name": "proxy", "image": "proxy:v2"}],
+      "tag": 'v1.0.1'
+    }
+  config.yaml: |
+    app:
+      image: image:v1.0.0
+`,
+			wantChanges: []Change{
+				{OldValue: "image:v1.0.0", NewValue: "image:v1.0.1", Setter: "automation-ns:policy", Line: 9},
+				{OldValue: "v1.0.0", NewValue: "v1.0.1", Setter: "automation-ns:policy:tag", Line: 11},
+			},
+		},
+		{
+			name: "YAML document with wildcard",
+			file: configMap,
+			fields: []DataField{
+				{Kind: "ConfigMap", Name: "app-config", Key: "config.yaml", Path: "app.*", Setter: "automation-ns:policy"},
+			},
+			want: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+  namespace: apps
+data:
+  config.json: |
+    {
+      "image": "image:v1.0.0",
+      "sidecars": [{"name": "proxy", "image": "proxy:v2"}],
+      "tag": 'v1.0.0'
+    }
+  config.yaml: |
+    app:
+      image: image:v1.0.1
+`,
+			wantChanges: []Change{
+				{OldValue: "image:v1.0.0", NewValue: "image:v1.0.1", Setter: "automation-ns:policy", Line: 15},
+			},
+		},
+		{
+			name: "other object",
+			file: configMap,
+			fields: []DataField{
+				{Kind: "Secret", Name: "app-config", Key: "config.json", Path: "$.image", Setter: "automation-ns:policy"},
+				{Kind: "ConfigMap", Name: "other", Key: "config.json", Path: "$.image", Setter: "automation-ns:policy"},
+			},
+			want: configMap,
+		},
+		{
+			name: "base64-encoded document",
+			file: secret,
+			fields: []DataField{
+				{Kind: "Secret", Name: "app-secret", Key: "config.json", Path: "$.image", Setter: "automation-ns:policy", Base64: true},
+			},
+			want: `apiVersion: v1
+kind: Secret
+metadata:
+  name: app-secret
+  namespace: apps
+data:
+  config.json: ` + updated + `
+`,
+			wantChanges: []Change{
+				{OldValue: "image:v1.0.0", NewValue: "image:v1.0.1", Setter: "automation-ns:policy", Line: 7},
+			},
+		},
+		{
+			name: "base64-encoded document without base64",
+			file: secret,
+			fields: []DataField{
+				{Kind: "Secret", Name: "app-secret", Key: "config.json", Path: "$.image", Setter: "automation-ns:policy"},
+			},
+			wantErr: "'app.yaml' line 7, marker {\"$imagepolicy\": \"automation-ns:policy\"}: document 'config.json' of Secret 'app-secret' is base64-encoded, and base64 isn't enabled for its data fields",
+		},
+		{
+			name: "non-scalar field",
+			file: configMap,
+			fields: []DataField{
+				{Kind: "ConfigMap", Name: "app-config", Key: "config.json", Path: "$.sidecars", Setter: "automation-ns:policy"},
+			},
+			wantErr: "field at line 3 isn't a scalar",
+		},
+		{
+			name: "conflict skipped",
+			file: configMap,
+			fields: []DataField{
+				{Kind: "ConfigMap", Name: "app-config", Key: "config.json", Path: "$.sidecars[0].image", Setter: "automation-ns:policy"},
+			},
+			options: []UpdateOption{WithUpdateOptionConflictPolicy(ConflictPolicySkip, map[types.NamespacedName]string{
+				{Namespace: "automation-ns", Name: "policy"}: "image:v1.0.0",
+			})},
+			want: configMap,
+		},
+		{
+			name: "invalid path",
+			file: configMap,
+			fields: []DataField{
+				{Kind: "ConfigMap", Name: "app-config", Key: "config.json", Path: "$..image", Setter: "automation-ns:policy"},
+			},
+			wantErr: "invalid path of data field 'config.json' of ConfigMap 'app-config': recursive descent isn't supported",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			tmp := t.TempDir()
+			g.Expect(os.WriteFile(filepath.Join(tmp, "app.yaml"), []byte(tt.file), 0o644)).To(Succeed())
+
+			result, err := UpdateDataFields(logr.Discard(), tmp, []imagev1_reflect.ImagePolicy{policy}, tt.fields, tt.options...)
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(result.Changes()).To(Equal(tt.wantChanges))
+
+			data, err := os.ReadFile(filepath.Join(tmp, "app.yaml"))
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(string(data)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestUpdateDataFields_semverJump(t *testing.T) {
+	g := NewWithT(t)
+
+	tmp := t.TempDir()
+	g.Expect(os.WriteFile(filepath.Join(tmp, "app.yaml"), []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+data:
+  config.json: '{"tag": "v1.0.0"}'
+`), 0o644)).To(Succeed())
+
+	policy := imagev1_reflect.ImagePolicy{}
+	policy.Namespace = "automation-ns"
+	policy.Name = "policy"
+	policy.Status.LatestImage = "image:v2.0.0"
+
+	_, err := UpdateDataFields(logr.Discard(), tmp, []imagev1_reflect.ImagePolicy{policy}, []DataField{
+		{Kind: "ConfigMap", Name: "app-config", Key: "config.json", Path: "tag", Setter: "automation-ns:policy:tag"},
+	}, WithUpdateOptionMaxSemverJump(SemverJumpMinor))
+	var fileErr *FileError
+	g.Expect(errors.As(err, &fileErr)).To(BeTrue())
+	g.Expect(fileErr.Line).To(Equal(6))
+	g.Expect(fileErr.Setter).To(Equal("automation-ns:policy:tag"))
+}
+
+func Test_parseDataPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    []dataPathElement
+		wantErr bool
+	}{
+		{path: "$.image", want: []dataPathElement{{key: "image"}}},
+		{path: "{.spec.image}", want: []dataPathElement{{key: "spec"}, {key: "image"}}},
+		{path: "image", want: []dataPathElement{{key: "image"}}},
+		{path: "$['app.kubernetes.io'][0].image", want: []dataPathElement{{key: "app.kubernetes.io"}, {index: 0, isIndex: true}, {key: "image"}}},
+		{path: `$.services[*]["image"]`, want: []dataPathElement{{key: "services"}, {wildcard: true}, {key: "image"}}},
+		{path: "$.*", want: []dataPathElement{{wildcard: true}}},
+		{path: "$", wantErr: true},
+		{path: "$..image", wantErr: true},
+		{path: "$.images[-1]", wantErr: true},
+		{path: "$.images[?(@.name)]", wantErr: true},
+		{path: "$['image'", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			g := NewWithT(t)
+			got, err := parseDataPath(tt.path)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
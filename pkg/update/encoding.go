@@ -0,0 +1,125 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encoding is the character encoding of a YAML file which isn't plain UTF-8,
+// i.e. without a byte order mark. The files are parsed and updated as UTF-8,
+// and written back in their encoding.
+type Encoding string
+
+const (
+	// EncodingUTF8BOM is UTF-8 with a byte order mark.
+	EncodingUTF8BOM Encoding = "UTF-8 BOM"
+	// EncodingUTF16LE is little-endian UTF-16 with a byte order mark.
+	EncodingUTF16LE Encoding = "UTF-16LE"
+	// EncodingUTF16BE is big-endian UTF-16 with a byte order mark.
+	EncodingUTF16BE Encoding = "UTF-16BE"
+	// EncodingLatin1 is ISO-8859-1, assumed for the files without a byte
+	// order mark which aren't valid UTF-8.
+	EncodingLatin1 Encoding = "ISO-8859-1"
+)
+
+var (
+	bomUTF8    = []byte{0xef, 0xbb, 0xbf}
+	bomUTF16LE = []byte{0xff, 0xfe}
+	bomUTF16BE = []byte{0xfe, 0xff}
+)
+
+// decodeFile returns the given content of a file as UTF-8, with its
+// encoding, which is empty for plain UTF-8.
+func decodeFile(data []byte) ([]byte, Encoding, error) {
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		return data[len(bomUTF8):], EncodingUTF8BOM, nil
+	case bytes.HasPrefix(data, bomUTF16LE):
+		text, err := decodeUTF16(data[len(bomUTF16LE):], false)
+		return text, EncodingUTF16LE, err
+	case bytes.HasPrefix(data, bomUTF16BE):
+		text, err := decodeUTF16(data[len(bomUTF16BE):], true)
+		return text, EncodingUTF16BE, err
+	case !utf8.Valid(data):
+		// Every byte is a character of ISO-8859-1, whose code point is
+		// the byte.
+		text := make([]byte, 0, len(data)+len(data)/8)
+		for _, b := range data {
+			text = utf8.AppendRune(text, rune(b))
+		}
+		return text, EncodingLatin1, nil
+	}
+	return data, "", nil
+}
+
+// encodeFile returns the given UTF-8 content of a file in the given encoding.
+func encodeFile(text []byte, enc Encoding) ([]byte, error) {
+	switch enc {
+	case "":
+		return text, nil
+	case EncodingUTF8BOM:
+		return append(bytes.Clone(bomUTF8), text...), nil
+	case EncodingUTF16LE:
+		return encodeUTF16(bomUTF16LE, text, false), nil
+	case EncodingUTF16BE:
+		return encodeUTF16(bomUTF16BE, text, true), nil
+	case EncodingLatin1:
+		data := make([]byte, 0, len(text))
+		for _, r := range string(text) {
+			if r > 0xff {
+				return nil, fmt.Errorf("character %q can't be encoded in %s", r, enc)
+			}
+			data = append(data, byte(r))
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("unknown encoding %q", enc)
+}
+
+func decodeUTF16(data []byte, bigEndian bool) ([]byte, error) {
+	if len(data)%2 != 0 {
+		return nil, errors.New("odd number of bytes in UTF-16 content")
+	}
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		} else {
+			units[i] = uint16(data[2*i+1])<<8 | uint16(data[2*i])
+		}
+	}
+	return []byte(string(utf16.Decode(units))), nil
+}
+
+func encodeUTF16(bom, text []byte, bigEndian bool) []byte {
+	units := utf16.Encode([]rune(string(text)))
+	data := make([]byte, 0, len(bom)+2*len(units))
+	data = append(data, bom...)
+	for _, u := range units {
+		if bigEndian {
+			data = append(data, byte(u>>8), byte(u))
+		} else {
+			data = append(data, byte(u), byte(u>>8))
+		}
+	}
+	return data
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func Test_decodeFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		wantText string
+		wantEnc  Encoding
+		wantErr  bool
+	}{
+		{name: "UTF-8", data: []byte("name: café\n"), wantText: "name: café\n"},
+		{name: "UTF-8 BOM", data: []byte("\xef\xbb\xbfname: café\n"), wantText: "name: café\n", wantEnc: EncodingUTF8BOM},
+		{name: "UTF-16LE", data: []byte("\xff\xfen\x00:\x00 \x00\xe9\x00\n\x00"), wantText: "n: é\n", wantEnc: EncodingUTF16LE},
+		{name: "UTF-16BE", data: []byte("\xfe\xff\x00n\x00:\x00 \x00\xe9\x00\n"), wantText: "n: é\n", wantEnc: EncodingUTF16BE},
+		{name: "ISO-8859-1", data: []byte("name: caf\xe9\n"), wantText: "name: café\n", wantEnc: EncodingLatin1},
+		{name: "odd UTF-16", data: []byte("\xff\xfen\x00:"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			text, enc, err := decodeFile(tt.data)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(string(text)).To(Equal(tt.wantText))
+			g.Expect(enc).To(Equal(tt.wantEnc))
+
+			// The content is encoded back as it was.
+			data, err := encodeFile(text, enc)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(data).To(Equal(tt.data))
+		})
+	}
+}
+
+func Test_encodeFile_unencodable(t *testing.T) {
+	g := NewWithT(t)
+	_, err := encodeFile([]byte("name: 日本\n"), EncodingLatin1)
+	g.Expect(err).To(MatchError(ContainSubstring("can't be encoded in ISO-8859-1")))
+}
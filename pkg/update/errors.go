@@ -0,0 +1,65 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FileError is the error of updating a file, with the location of the
+// offending field and the marker involved when they're known, for the file
+// to be found quickly.
+type FileError struct {
+	// Path is the path of the file, relative to the updated directory.
+	Path string
+	// Document is the index of the YAML document of the field in the file,
+	// starting from zero.
+	Document int
+	// Line is the line of the field in its document, starting from one, or
+	// zero if unknown. The line of a field in the first document is its
+	// line in the file.
+	Line int
+	// Setter is the name of the setter of the marker of the field, if any.
+	Setter string
+	// Err is the underlying error.
+	Err error
+}
+
+// Error returns the location of the error followed by the underlying error,
+// e.g. "'deploy.yaml' line 12, marker {"$imagepolicy": "default:app:tag"}:
+// maximum semver jump exceeded: ...".
+func (e *FileError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "'%s'", e.Path)
+	if e.Document > 0 {
+		fmt.Fprintf(&b, " document %d", e.Document+1)
+	}
+	if e.Line > 0 {
+		fmt.Fprintf(&b, " line %d", e.Line)
+	}
+	if e.Setter != "" {
+		fmt.Fprintf(&b, `, marker {"%s": "%s"}`, SetterShortHand, e.Setter)
+	}
+	fmt.Fprintf(&b, ": %s", e.Err)
+	return b.String()
+}
+
+// Unwrap returns the underlying error.
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
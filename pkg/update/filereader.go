@@ -21,8 +21,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 
 	"github.com/go-logr/logr"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
 	"sigs.k8s.io/kustomize/kyaml/kio"
 	"sigs.k8s.io/kustomize/kyaml/kio/kioutil"
 	"sigs.k8s.io/kustomize/kyaml/yaml"
@@ -38,11 +41,76 @@ type ScreeningLocalReader struct {
 	Token string
 	Path  string
 
+	// Tokens are more tokens for the files to be screened for; the
+	// files containing any of .Token and .Tokens pass screening.
+	Tokens []string
+
 	Trace logr.Logger
 
+	// FileSystem is the file system the files are read from. It
+	// defaults to the disk.
+	FileSystem filesys.FileSystemOrOnDisk
+
+	// Workers is the number of files screened and parsed
+	// concurrently. If zero or less, one worker per usable CPU
+	// (GOMAXPROCS) is used.
+	Workers int
+
+	// ScanTemplates makes the reader return the files with Go
+	// template syntax, e.g. Helm chart templates, in .Templates
+	// instead of skipping them.
+	ScanTemplates bool
+
 	// This records the relative path of each file that passed
 	// screening (i.e., contained the token), but couldn't be parsed.
 	ProblemFiles []string
+
+	// SkippedFiles records the reason each file in .ProblemFiles was
+	// skipped for, i.e. SkipReasonHelmTemplate or
+	// SkipReasonInvalidYAML with the parse error.
+	SkippedFiles map[string]string
+
+	// Templates records the files with Go template syntax which
+	// passed screening, when .ScanTemplates is set.
+	Templates []TemplateFile
+
+	// FileOwners records the owners declared by an OwnerAnnotation
+	// comment in each file which passed screening and has one.
+	FileOwners map[string][]string
+
+	// Encodings records the encoding of each file which passed
+	// screening and isn't plain UTF-8, e.g. with a byte order mark
+	// or in ISO-8859-1. The nodes and the templates of the files are
+	// decoded to UTF-8.
+	Encodings map[string]Encoding
+
+	// DocumentLines records the line each YAML document of each
+	// parsed file starts at, in the order of their index annotation,
+	// to give the lines of the fields of the documents in their
+	// file. A file is missing when its lines are unknown.
+	DocumentLines map[string][]int
+
+	// SymlinkPolicy decides what to do with the YAML files which are
+	// symbolic links. It defaults to SymlinkPolicyFollow. The
+	// symbolic links to directories are never walked.
+	SymlinkPolicy SymlinkPolicy
+
+	// Root is the path the symbolic links are followed within, e.g.
+	// the worktree of a Git repository. It defaults to .Path.
+	Root string
+}
+
+// screenedFile is the outcome of screening and parsing a single file.
+type screenedFile struct {
+	path     string
+	nodes    []*yaml.RNode
+	problem  string
+	template []byte
+	owners   []string
+	docLines []int
+	encoding Encoding
+	// size is the estimated memory taken by the parsed nodes.
+	size int64
 }
 
 // Read scans the .Path recursively for files that contain .Token, and
@@ -52,20 +120,102 @@ type ScreeningLocalReader struct {
 // location. The implementation follows that of
 // [LocalPackageReader.Read](https://godoc.org/sigs.k8s.io/kustomize/kyaml/kio#LocalPackageReader.Read),
 // adapting lightly (mainly to leave features out).
+//
+// The files are screened and parsed by a pool of .Workers; the
+// result is nonetheless in the order the files were walked.
 func (r *ScreeningLocalReader) Read() ([]*yaml.RNode, error) {
+	var result []*yaml.RNode
+	err := r.ReadBatches(0, func(nodes []*yaml.RNode) error {
+		result = nodes
+		return nil
+	})
+	return result, err
+}
+
+// parsedSizeFactor is the estimated ratio of the memory taken by the nodes
+// parsed from a YAML file to the size of the file.
+const parsedSizeFactor = 25
+
+// ReadBatches reads the files like Read, but gives the nodes to fn in
+// batches, each parsed from consecutive files in the order they were
+// walked, e.g. from the same directory, instead of all at once. The
+// estimated memory taken by the nodes of a batch is at most the given
+// budget in bytes, unless a single file exceeds it; all the nodes are in
+// a single batch when the budget is zero or less. The nodes of a file
+// are always in the same batch. Only a batch of files per worker is
+// parsed ahead of the batch given to fn, so that the nodes of the
+// previous batches can be released.
+//
+// The records of the reader, e.g. .DocumentLines, are complete for the
+// files of a batch when it's given to fn, and for all the files once
+// ReadBatches returns. It stops at the first error returned by fn.
+func (r *ScreeningLocalReader) ReadBatches(budget int64, fn func(nodes []*yaml.RNode) error) error {
 	tracelog := r.Trace
 	if (logr.Logger{} == tracelog) {
 		tracelog = logr.Discard()
 	}
 	tracelog.Info("scanning files", "path", r.Path, "token", r.Token)
 
+	files, relativePath, chartDirs, err := r.walk(tracelog)
+	if err != nil {
+		return err
+	}
+
+	chunk := len(files)
+	if budget > 0 {
+		chunk = workerCount(r.Workers)
+	}
+	var batch []*yaml.RNode
+	var size int64
+	for start := 0; start < len(files); start += chunk {
+		screened, err := r.screen(tracelog, files[start:min(start+chunk, len(files))], relativePath, chartDirs)
+		if err != nil {
+			return err
+		}
+		for _, f := range screened {
+			if budget > 0 && len(batch) > 0 && size+f.size > budget {
+				if err := fn(batch); err != nil {
+					return err
+				}
+				batch, size = nil, 0
+			}
+			batch = append(batch, r.record(f)...)
+			size += f.size
+		}
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+	return fn(batch)
+}
+
+// walk returns the YAML files to be screened in .Path, the directory their
+// paths are relative to, and the directories of the Helm charts.
+func (r *ScreeningLocalReader) walk(tracelog logr.Logger) ([]string, string, map[string]struct{}, error) {
 	if r.Path == "" {
-		return nil, fmt.Errorf("must supply path to scan for files")
+		return nil, "", nil, fmt.Errorf("must supply path to scan for files")
 	}
 
-	root, err := filepath.Abs(r.Path)
-	if err != nil {
-		return nil, fmt.Errorf("path field cannot be made absolute: %w", err)
+	// Paths on a file system other than the disk are taken to be
+	// relative to its root.
+	root := filepath.Join(string(filepath.Separator), r.Path)
+	if r.FileSystem.FileSystem == nil {
+		var err error
+		root, err = filepath.Abs(r.Path)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("path field cannot be made absolute: %w", err)
+		}
+	}
+
+	symlinkRoot := root
+	if r.Root != "" {
+		symlinkRoot = filepath.Join(string(filepath.Separator), r.Root)
+		if r.FileSystem.FileSystem == nil {
+			var err error
+			if symlinkRoot, err = filepath.Abs(r.Root); err != nil {
+				return nil, "", nil, fmt.Errorf("root field cannot be made absolute: %w", err)
+			}
+		}
 	}
 
 	// For the filename annotation, I want a directory for filenames
@@ -73,10 +223,11 @@ func (r *ScreeningLocalReader) Read() ([]*yaml.RNode, error) {
 	// or file yet so this must wait until the body of the filepath.Walk.
 	var relativePath string
 
-	tokenbytes := []byte(r.Token)
-
-	var result []*yaml.RNode
-	err = filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+	var files []string
+	// Directories with a Chart.yaml, whose templates can't be
+	// parsed as YAML.
+	chartDirs := map[string]struct{}{}
+	err := r.FileSystem.Walk(root, func(p string, info os.FileInfo, err error) error {
 		if err != nil {
 			return fmt.Errorf("walking path for files: %w", err)
 		}
@@ -93,29 +244,84 @@ func (r *ScreeningLocalReader) Read() ([]*yaml.RNode, error) {
 			return nil
 		}
 
+		if info.Name() == "Chart.yaml" {
+			chartDirs[filepath.Dir(p)] = struct{}{}
+		}
+
 		if ext := filepath.Ext(p); ext != ".yaml" && ext != ".yml" {
 			return nil
 		}
+		if isSymlink(info) {
+			path, err := filepath.Rel(relativePath, p)
+			if err != nil {
+				return fmt.Errorf("relativising path: %w", err)
+			}
+			if r.SymlinkPolicy == SymlinkPolicyIgnore {
+				tracelog.Info("ignoring symbolic link", "path", path)
+				return nil
+			}
+			if err := r.checkSymlink(symlinkRoot, p, path); err != nil {
+				return err
+			}
+		}
+		files = append(files, p)
+		return nil
+	})
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return files, relativePath, chartDirs, nil
+}
 
-		// To check for the token, I need the file contents. This
-		// assumes the file is encoded as UTF8.
-		filebytes, err := os.ReadFile(p)
+// screen screens and parses the given files with the pool of workers,
+// and returns the outcome for each of them, in the same order.
+func (r *ScreeningLocalReader) screen(tracelog logr.Logger, files []string, relativePath string, chartDirs map[string]struct{}) ([]screenedFile, error) {
+	tokens := [][]byte{[]byte(r.Token)}
+	for _, token := range r.Tokens {
+		tokens = append(tokens, []byte(token))
+	}
+
+	screened := make([]screenedFile, len(files))
+	err := parallelFor(r.Workers, len(files), func(i int) error {
+		p := files[i]
+
+		path, err := filepath.Rel(relativePath, p)
 		if err != nil {
-			return fmt.Errorf("reading YAML file: %w", err)
+			return fmt.Errorf("relativising path: %w", err)
 		}
 
-		if !bytes.Contains(filebytes, tokenbytes) {
+		// To check for the token, I need the file contents, decoded
+		// to UTF-8 if it's in another encoding.
+		data, err := r.FileSystem.ReadFile(p)
+		if err != nil {
+			return &FileError{Path: path, Err: fmt.Errorf("reading YAML file: %w", err)}
+		}
+		filebytes, encoding, err := decodeFile(data)
+		if err != nil {
+			tracelog.Info("undecodable file", "path", path, "error", err.Error())
 			return nil
 		}
 
-		path, err := filepath.Rel(relativePath, p)
-		if err != nil {
-			return fmt.Errorf("relativising path: %w", err)
+		if !slices.ContainsFunc(tokens, func(token []byte) bool { return bytes.Contains(filebytes, token) }) {
+			return nil
 		}
 		annotations := map[string]string{
 			kioutil.PathAnnotation: path,
 		}
 
+		screened[i].path = path
+		screened[i].owners = parseOwnerAnnotation(filebytes)
+		screened[i].encoding = encoding
+
+		// Helm chart templates are known to not be YAML, and
+		// skipped without parsing them.
+		if isChartTemplate(p, chartDirs) {
+			tracelog.Info("template file", "path", path)
+			screened[i].problem = SkipReasonHelmTemplate
+			screened[i].template = filebytes
+			return nil
+		}
+
 		tracelog.Info("reading file", "path", path)
 		rdr := &kio.ByteReader{
 			Reader:            bytes.NewBuffer(filebytes),
@@ -129,13 +335,115 @@ func (r *ScreeningLocalReader) Read() ([]*yaml.RNode, error) {
 		// doesn't need to be the end of the matter; we can record
 		// this file as problematic, and continue.
 		if err != nil {
-			tracelog.Info("problem file", "path", path)
-			r.ProblemFiles = append(r.ProblemFiles, path)
+			// Go template actions are the likely cause of the
+			// error, e.g. in a chart which isn't laid out as
+			// usual.
+			if bytes.Contains(filebytes, []byte(templateAction)) {
+				tracelog.Info("template file", "path", path)
+				screened[i].problem = SkipReasonHelmTemplate
+				screened[i].template = filebytes
+				return nil
+			}
+			tracelog.Info("problem file", "path", path, "error", err.Error())
+			screened[i].problem = fmt.Sprintf("%s: %s", SkipReasonInvalidYAML, err)
 			return nil
 		}
-		result = append(result, nodes...)
+		for _, n := range nodes {
+			untagMergeKeys(n.YNode())
+		}
+		screened[i].nodes = nodes
+		screened[i].docLines = documentLines(filebytes, len(nodes))
+		screened[i].size = int64(len(filebytes)) * parsedSizeFactor
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+	return screened, nil
+}
 
-	return result, err
+// record records the given screened file in the reader, and returns its
+// nodes, if it was parsed.
+func (r *ScreeningLocalReader) record(f screenedFile) []*yaml.RNode {
+	if f.owners != nil {
+		if r.FileOwners == nil {
+			r.FileOwners = map[string][]string{}
+		}
+		r.FileOwners[f.path] = f.owners
+	}
+	if f.encoding != "" {
+		if r.Encodings == nil {
+			r.Encodings = map[string]Encoding{}
+		}
+		r.Encodings[f.path] = f.encoding
+	}
+	if f.template != nil && r.ScanTemplates {
+		r.Templates = append(r.Templates, TemplateFile{Path: f.path, Data: f.template})
+		return nil
+	}
+	if f.problem != "" {
+		r.ProblemFiles = append(r.ProblemFiles, f.path)
+		if r.SkippedFiles == nil {
+			r.SkippedFiles = map[string]string{}
+		}
+		r.SkippedFiles[f.path] = f.problem
+		return nil
+	}
+	if f.docLines != nil {
+		if r.DocumentLines == nil {
+			r.DocumentLines = map[string][]int{}
+		}
+		r.DocumentLines[f.path] = f.docLines
+	}
+	return f.nodes
+}
+
+// documentSeparator matches the YAML document separators the same way
+// kio.ByteReader splits the documents of a file.
+var documentSeparator = regexp.MustCompile(`\n---.*\n`)
+
+// documentLines returns the line each of the given number of documents read
+// from the given file starts at. Like kio.ByteReader, it leaves out the empty
+// documents, i.e. those with only comments. It returns nil if the documents
+// don't add up, e.g. when one is an explicit null, which is left out by
+// kio.ByteReader too.
+func documentLines(data []byte, count int) []int {
+	var lines []int
+	start, line := 0, 1
+	addDocument := func(doc []byte) {
+		for _, l := range bytes.Split(doc, []byte("\n")) {
+			l = bytes.TrimSpace(l)
+			if len(l) > 0 && l[0] != '#' && !bytes.HasPrefix(l, []byte("---")) && !bytes.Equal(l, []byte("...")) {
+				lines = append(lines, line)
+				return
+			}
+		}
+	}
+	for _, loc := range documentSeparator.FindAllIndex(data, -1) {
+		addDocument(data[start:loc[0]])
+		// The separator spans the end of the line before it, and its own.
+		line += bytes.Count(data[start:loc[1]], []byte("\n"))
+		start = loc[1]
+	}
+	addDocument(data[start:])
+	if len(lines) != count {
+		return nil
+	}
+	return lines
+}
+
+// untagMergeKeys removes the explicit merge tag the YAML decoder gives to the
+// keys of merged anchors (`<<: *anchor`). Without this, the keys would be
+// written back as `!!merge <<: *anchor`. Anchors and aliases are otherwise
+// preserved as they are read.
+func untagMergeKeys(node *yaml.Node) {
+	if node == nil {
+		return
+	}
+	if node.Kind == yaml.ScalarNode && node.Tag == yaml.MergeTag && node.Value == "<<" {
+		node.Tag = ""
+	}
+	for _, n := range node.Content {
+		untagMergeKeys(n)
+	}
 }
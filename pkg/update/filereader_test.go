@@ -51,3 +51,25 @@ func TestScreeningLocalReader(t *testing.T) {
 	}))
 
 }
+
+func Test_documentLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		count int
+		want  []int
+	}{
+		{name: "single document", data: "# comment\nkind: A\n", count: 1, want: []int{1}},
+		{name: "leading separator", data: "---\nkind: A\n", count: 1, want: []int{1}},
+		{name: "documents", data: "kind: A\n---\nkind: B\n--- # comment\n\nkind: C\n", count: 3, want: []int{1, 3, 5}},
+		{name: "empty documents", data: "# header\n---\n---\n# nothing\n---\nkind: A\n", count: 1, want: []int{6}},
+		{name: "windows line endings", data: "kind: A\r\n---\r\nkind: B\r\n", count: 2, want: []int{1, 3}},
+		{name: "null document", data: "kind: A\n---\nnull\n---\nkind: B\n", count: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(documentLines([]byte(tt.data), tt.count)).To(Equal(tt.want))
+		})
+	}
+}
@@ -18,6 +18,8 @@ package update
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
 
 	"github.com/go-logr/logr"
 	"k8s.io/kube-openapi/pkg/validation/spec"
@@ -50,7 +52,23 @@ import (
 type SetAllCallback struct {
 	SettersSchema *spec.Schema
 	Callback      func(setter, oldValue, newValue string)
-	Trace         logr.Logger
+	// ShouldSet, if not nil, is called before a field is set, and the
+	// field is left unchanged if it returns false.
+	ShouldSet func(setter, oldValue, newValue string) bool
+	// Unresolved, if not nil, is called for each field marked with a
+	// setter missing from the schema, e.g. the setter of a policy in
+	// another namespace, with the value of the field.
+	Unresolved func(setter, value string)
+	Trace      logr.Logger
+
+	// line is the line of the field being set, for the callbacks to
+	// locate it.
+	line int
+	// path is the path of the field being set, see accept.
+	path string
+	// workload is the object patched by the embedded patch holding the
+	// field being set, see FilterPatches.
+	workload WorkloadIdentifier
 }
 
 func (s *SetAllCallback) TraceOrDiscard() logr.Logger {
@@ -68,7 +86,8 @@ func (s *SetAllCallback) Filter(object *yaml.RNode) (*yaml.RNode, error) {
 type visitor interface {
 	// visitScalar is called for each scalar field value on a resource
 	// node is the scalar field value
-	// path is the path to the field; path elements are separated by '.'
+	// path is the path to the field; path elements are separated by '.',
+	// and the elements of a list with a name are '[name=<name>]'
 	visitScalar(node *yaml.RNode, path string, schema *openapi.ResourceSchema) error
 }
 
@@ -103,8 +122,12 @@ func accept(v visitor, object *yaml.RNode, p string, settersSchema *spec.Schema)
 		})
 	case yaml.SequenceNode:
 		return object.VisitElements(func(node *yaml.RNode) error {
-			// Traverse each list element
-			return accept(v, node, p, settersSchema)
+			// Traverse each list element, identified by its name if any
+			ep := p
+			if name := elementName(node); name != "" {
+				ep = fmt.Sprintf("%s[name=%s]", p, name)
+			}
+			return accept(v, node, ep, settersSchema)
 		})
 	case yaml.ScalarNode:
 		fieldSchema := getSchema(object, settersSchema)
@@ -113,6 +136,19 @@ func accept(v visitor, object *yaml.RNode, p string, settersSchema *spec.Schema)
 	return nil
 }
 
+// elementName returns the value of the name field of a list element, or an
+// empty string if it has none, e.g. the name of a container.
+func elementName(node *yaml.RNode) string {
+	if node.YNode().Kind != yaml.MappingNode {
+		return ""
+	}
+	field := node.Field("name")
+	if field == nil || field.Value.YNode().Kind != yaml.ScalarNode {
+		return ""
+	}
+	return field.Value.YNode().Value
+}
+
 type setter struct {
 	Name  string `json:"name"`
 	Value string `json:"value"`
@@ -131,6 +167,11 @@ func (s *SetAllCallback) set(field *yaml.RNode, ext *extension, sch *spec.Schema
 
 	// this has a full setter, set its value
 	old := field.YNode().Value
+	s.line = field.YNode().Line
+	if s.ShouldSet != nil && !s.ShouldSet(ext.Setter.Name, old, ext.Setter.Value) {
+		s.TraceOrDiscard().Info("skipping setter", "setter", ext.Setter.Name, "old", old, "new", ext.Setter.Value)
+		return false, nil
+	}
 	field.YNode().Value = ext.Setter.Value
 	s.TraceOrDiscard().Info("applying setter", "setter", ext.Setter.Name, "old", old, "new", ext.Setter.Value)
 	s.Callback(ext.Setter.Name, old, ext.Setter.Value)
@@ -146,23 +187,59 @@ func (s *SetAllCallback) set(field *yaml.RNode, ext *extension, sch *spec.Schema
 // visitScalar
 func (s *SetAllCallback) visitScalar(object *yaml.RNode, p string, fieldSchema *openapi.ResourceSchema) error {
 	if fieldSchema == nil {
+		s.unresolved(object.YNode(), object.YNode().Line, p)
 		return nil
 	}
 	// get the openAPI for this field describing how to apply the setter
 	ext, err := getExtFromSchema(fieldSchema.Schema)
 	if err != nil {
-		return err
+		return &FileError{Line: object.YNode().Line, Err: fmt.Errorf("invalid marker: %w", err)}
 	}
 	if ext == nil {
+		s.unresolved(object.YNode(), object.YNode().Line, p)
 		return nil
 	}
 
 	s.TraceOrDiscard().Info("found schema extension", "path", p)
+	s.path = p
 	// perform a direct set of the field if it matches
 	_, err = s.set(object, ext, fieldSchema.Schema)
 	return err
 }
 
+// unresolved calls the Unresolved callback for the given field without
+// setter in its schema, at the given line and path, when it's marked with a
+// setter, which is then missing from the schema of the setters.
+func (s *SetAllCallback) unresolved(field *yaml.Node, line int, p string) {
+	if s.Unresolved == nil {
+		return
+	}
+	setter, ok := markerSetter(field)
+	if !ok {
+		return
+	}
+	s.line, s.path = line, p
+	s.Unresolved(setter, field.Value)
+}
+
+// markerSetter returns the name of the setter the given field is marked
+// with, e.g. `# {"$imagepolicy": "ns:policy"}`, if any.
+func markerSetter(field *yaml.Node) (string, bool) {
+	for _, c := range []string{field.LineComment, field.HeadComment} {
+		if !strings.Contains(c, SetterShortHand) {
+			continue
+		}
+		var marker map[string]string
+		if err := json.Unmarshal([]byte(strings.TrimLeft(c, "#")), &marker); err != nil {
+			continue
+		}
+		if setter := marker[SetterShortHand]; setter != "" {
+			return setter, true
+		}
+	}
+	return "", false
+}
+
 func getExtFromSchema(schema *spec.Schema) (*extension, error) {
 	cep := schema.VendorExtensible.Extensions[K8sCliExtensionKey]
 	if cep == nil {
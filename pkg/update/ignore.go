@@ -0,0 +1,114 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// IgnoreAnnotation opts an object out of the updates when set to "true",
+// either as an annotation of the object, or in a comment at the top of its
+// YAML document, e.g. to freeze a single Deployment of a shared file:
+//
+//	# image.toolkit.fluxcd.io/ignore: "true"
+//	apiVersion: apps/v1
+//	kind: Deployment
+//
+// The marked fields of the object are left unchanged, and the changes they
+// would have had are recorded in ResultV2.IgnoredChanges.
+const IgnoreAnnotation = "image.toolkit.fluxcd.io/ignore"
+
+// DocumentsAnnotation restricts the updates of a multi-document file to some
+// of its documents, in a comment at the top of the file. Its value is a
+// comma-separated list of the indices of the documents, starting from zero,
+// or of the names of their objects, e.g. to only update the second document
+// and the object named app of a shared file:
+//
+//	# image.toolkit.fluxcd.io/documents: "1,app"
+//	apiVersion: v1
+//	kind: ConfigMap
+//
+// The marked fields of the other documents are left unchanged, as if they
+// opted out with IgnoreAnnotation.
+const DocumentsAnnotation = "image.toolkit.fluxcd.io/documents"
+
+// isIgnored returns whether the object of the given node opts out of the
+// updates with IgnoreAnnotation.
+func isIgnored(node *yaml.RNode) bool {
+	if node.GetAnnotations()[IgnoreAnnotation] == "true" {
+		return true
+	}
+	value, ok := topComment(node, IgnoreAnnotation)
+	return ok && value == "true"
+}
+
+// topComment returns the value of the given key in a comment at the top of
+// the YAML document of the given node, if any.
+func topComment(node *yaml.RNode, key string) (string, bool) {
+	// A comment at the top of the document is attached to the document, or
+	// to its first key.
+	n := node.YNode()
+	comments := []string{n.HeadComment}
+	if n.Kind == yaml.MappingNode && len(n.Content) > 0 {
+		comments = append(comments, n.Content[0].HeadComment)
+	}
+	for _, comment := range comments {
+		for _, line := range strings.Split(comment, "\n") {
+			k, value, ok := strings.Cut(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "#")), ":")
+			if ok && strings.TrimSpace(k) == key {
+				return strings.Trim(strings.TrimSpace(value), `"'`), true
+			}
+		}
+	}
+	return "", false
+}
+
+// documentSelector is the set of the documents of a file selected with
+// DocumentsAnnotation, by index or by name of their object.
+type documentSelector struct {
+	indices map[int]bool
+	names   map[string]bool
+}
+
+// documentsOf returns the documentSelector of the file of the given node, the
+// first document of the file, or nil if the file doesn't restrict the
+// updated documents.
+func documentsOf(node *yaml.RNode) *documentSelector {
+	value, ok := topComment(node, DocumentsAnnotation)
+	if !ok {
+		return nil
+	}
+	s := &documentSelector{indices: map[int]bool{}, names: map[string]bool{}}
+	for _, doc := range strings.Split(value, ",") {
+		doc = strings.TrimSpace(doc)
+		if index, err := strconv.Atoi(doc); err == nil {
+			s.indices[index] = true
+		} else if doc != "" {
+			s.names[doc] = true
+		}
+	}
+	return s
+}
+
+// selects returns whether the document of the given node, at the given index
+// in its file, is selected. A nil documentSelector selects every document.
+func (s *documentSelector) selects(index int, node *yaml.RNode) bool {
+	return s == nil || s.indices[index] || s.names[node.GetName()]
+}
@@ -0,0 +1,208 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+
+	imagev1_reflect "github.com/fluxcd/image-reflector-controller/api/v1beta2"
+
+	templatesv1 "github.com/fluxcd/image-automation-controller/pkg/templates/v1"
+)
+
+const (
+	// KustomizeImagesDir is the directory of the Kustomize component listing
+	// the images of the policies, maintained in each updated directory.
+	KustomizeImagesDir = "images"
+	// KustomizeImagesFile is the path of the kustomization of the images
+	// component, relative to the updated directory.
+	KustomizeImagesFile = KustomizeImagesDir + "/kustomization.yaml"
+
+	kustomizeComponentAPIVersion = "kustomize.config.k8s.io/v1alpha1"
+	kustomizeComponentKind       = "Component"
+)
+
+// kustomizeImagesHeader is the comment at the top of the kustomization of the
+// images component.
+const kustomizeImagesHeader = "# This file is maintained by the Flux image automation, any manual change may be overwritten.\n"
+
+// KustomizeComponent is the kustomization of a Kustomize component, with the
+// images it sets.
+type KustomizeComponent struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	// Images are the images set by the component. The fields other than the
+	// images aren't supported.
+	Images []KustomizeImage `yaml:"images"`
+}
+
+// KustomizeImage is an image of a KustomizeComponent, in the format of the
+// images field of a kustomization.
+type KustomizeImage struct {
+	// Name is the name of the image, without tag nor digest.
+	Name string `yaml:"name"`
+	// NewTag is the tag of the image, when its reference has one.
+	NewTag string `yaml:"newTag,omitempty"`
+	// Digest is the digest of the image, when its reference has one.
+	Digest string `yaml:"digest,omitempty"`
+}
+
+// kustomizeImage returns the KustomizeImage of the given image reference.
+func kustomizeImage(ref string) KustomizeImage {
+	name, digest, _ := strings.Cut(ref, "@")
+	image := KustomizeImage{Name: name, Digest: digest}
+	// A colon after the last slash separates the tag, any other is part of
+	// the registry host.
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		image.Name, image.NewTag = name[:i], name[i+1:]
+	}
+	return image
+}
+
+// reference returns the image reference of the image.
+func (i KustomizeImage) reference() string {
+	ref := i.Name
+	if i.NewTag != "" {
+		ref += ":" + i.NewTag
+	}
+	if i.Digest != "" {
+		ref += "@" + i.Digest
+	}
+	return ref
+}
+
+// UpdateKustomizeImages maintains a Kustomize component in the
+// KustomizeImagesDir of the given directory, setting the images of the given
+// policies, instead of updating the marked fields of the manifests. The
+// component is created if missing, and the overlays include it with e.g.
+// `components: [images]`. The images of the component which aren't the image
+// of any policy are kept. The changes of the images are returned as the
+// changes of the component, with the UpdateOptions selecting the filesystem
+// and the maximum semver jump of the update.
+func UpdateKustomizeImages(tracelog logr.Logger, dir string, policies []imagev1_reflect.ImagePolicy, options ...UpdateOption) (ResultV2, error) {
+	opts := &UpdateOptions{}
+	for _, o := range options {
+		o(opts)
+	}
+
+	var fs filesys.FileSystem = filesys.MakeFsOnDisk()
+	if opts.workTree != nil {
+		fs = workTreeFileSystem{fs: opts.workTree}
+		dir = filepath.Join(string(filepath.Separator), dir)
+	}
+	fs = newWritableFileSystem(fs, dir)
+	file := filepath.Join(dir, filepath.FromSlash(KustomizeImagesFile))
+
+	component := KustomizeComponent{}
+	if fs.Exists(file) {
+		previous, err := fs.ReadFile(file)
+		if err != nil {
+			return ResultV2{}, fmt.Errorf("failed to read images component: %w", err)
+		}
+		if err := yaml.Unmarshal(previous, &component); err != nil {
+			return ResultV2{}, &FileError{Path: KustomizeImagesFile, Err: fmt.Errorf("failed to parse images component: %w", err)}
+		}
+	}
+	component.APIVersion = kustomizeComponentAPIVersion
+	component.Kind = kustomizeComponentKind
+
+	oid := ObjectIdentifier{ResourceIdentifier: yaml.ResourceIdentifier{
+		TypeMeta: yaml.TypeMeta{APIVersion: kustomizeComponentAPIVersion, Kind: kustomizeComponentKind},
+	}}
+	result := Result{Files: map[string]FileResult{}}
+	var resultV2 ResultV2
+	// owners records the policy setting each image, for two policies not to
+	// set the same image.
+	owners := map[string]types.NamespacedName{}
+	for _, policy := range policies {
+		if policy.Status.LatestImage == "" {
+			continue
+		}
+		ref, _, _, err := parseImage(policy.Status.LatestImage)
+		if err != nil {
+			return ResultV2{}, err
+		}
+		image := kustomizeImage(policy.Status.LatestImage)
+		name := image.Name
+		key := types.NamespacedName{Namespace: policy.Namespace, Name: policy.Name}
+		setter := fmt.Sprintf("%s:%s", policy.Namespace, policy.Name)
+		if owner, ok := owners[name]; ok {
+			return ResultV2{}, &FileError{Path: KustomizeImagesFile, Setter: setter,
+				Err: fmt.Errorf("image '%s' is already set by policy '%s'", name, owner)}
+		}
+		owners[name] = key
+
+		i := slices.IndexFunc(component.Images, func(ki KustomizeImage) bool { return ki.Name == name })
+		var oldValue string
+		if i >= 0 {
+			oldValue = component.Images[i].reference()
+			if component.Images[i] == image {
+				continue
+			}
+			if exceedsSemverJump(opts.maxSemverJump, setter+":tag", component.Images[i].NewTag, image.NewTag) {
+				return ResultV2{}, &FileError{Path: KustomizeImagesFile, Setter: setter,
+					Err: semverJumpError(component.Images[i].NewTag, image.NewTag, opts.maxSemverJump)}
+			}
+			component.Images[i] = image
+		} else {
+			component.Images = append(component.Images, image)
+		}
+		tracelog.Info("setting image", "setter", setter, "old", oldValue, "new", image.reference())
+
+		resultV2.AddChange(KustomizeImagesFile, oid, Change{
+			OldValue: oldValue,
+			NewValue: image.reference(),
+			Setter:   setter,
+		})
+		fileres, ok := result.Files[KustomizeImagesFile]
+		if !ok {
+			fileres = FileResult{Objects: map[ObjectIdentifier][]ImageRef{}}
+			result.Files[KustomizeImagesFile] = fileres
+		}
+		fileres.Objects[oid] = append(fileres.Objects[oid], templatesv1.NewImageRef(ref, key))
+	}
+	slices.SortFunc(component.Images, func(a, b KustomizeImage) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	// The component is only written when an image changed, and created
+	// with the first one.
+	if len(resultV2.FileChanges) == 0 {
+		return ResultV2{}, nil
+	}
+	data, err := yaml.Marshal(component)
+	if err != nil {
+		return ResultV2{}, fmt.Errorf("failed to encode images component: %w", err)
+	}
+	data = append([]byte(kustomizeImagesHeader), data...)
+	if err := fs.MkdirAll(filepath.Dir(file)); err != nil {
+		return ResultV2{}, fmt.Errorf("failed to create images component: %w", err)
+	}
+	if err := fs.WriteFile(file, data); err != nil {
+		return ResultV2{}, fmt.Errorf("failed to write images component: %w", err)
+	}
+	resultV2.ImageResult = result
+	return resultV2, nil
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+
+	imagev1_reflect "github.com/fluxcd/image-reflector-controller/api/v1beta2"
+)
+
+const (
+	// LockFileName is the name of the lock file written at the root of the
+	// update path, listing the images of the policies.
+	LockFileName = "flux-images.lock.yaml"
+	// LockFileSchemaVersion is the version of the schema of the lock file.
+	LockFileSchemaVersion = "v1"
+)
+
+// lockFileHeader is the comment at the top of the lock file.
+const lockFileHeader = "# This file is maintained by the Flux image automation, any manual change may be overwritten.\n"
+
+// LockFile is the machine-readable list of the images of the policies applied
+// to the files of an update path.
+type LockFile struct {
+	SchemaVersion string        `yaml:"schemaVersion"`
+	Images        []LockedImage `yaml:"images"`
+}
+
+// LockedImage is the image of a policy in a LockFile.
+type LockedImage struct {
+	// Policy is the namespaced name of the ImagePolicy.
+	Policy string `yaml:"policy"`
+	// Image is the reference of the image, without digest.
+	Image string `yaml:"image"`
+	// Digest is the digest of the image, when its reference has one.
+	Digest string `yaml:"digest,omitempty"`
+}
+
+// WriteLockFile writes the images of the given policies to the lock file in
+// the given directory, and returns whether its content changed. The images of
+// the policies already in the lock file are replaced, and the others are kept,
+// so that the lock file can be updated with a subset of the policies. The
+// UpdateOptions select the filesystem of the lock file.
+func WriteLockFile(dir string, policies []imagev1_reflect.ImagePolicy, options ...UpdateOption) (bool, error) {
+	opts := &UpdateOptions{}
+	for _, o := range options {
+		o(opts)
+	}
+
+	var fs filesys.FileSystem = filesys.MakeFsOnDisk()
+	if opts.workTree != nil {
+		fs = workTreeFileSystem{fs: opts.workTree}
+		dir = filepath.Join(string(filepath.Separator), dir)
+	}
+	fs = newWritableFileSystem(fs, dir)
+	path := filepath.Join(dir, LockFileName)
+
+	var previous []byte
+	lock := LockFile{SchemaVersion: LockFileSchemaVersion}
+	if fs.Exists(path) {
+		var err error
+		if previous, err = fs.ReadFile(path); err != nil {
+			return false, fmt.Errorf("failed to read lock file: %w", err)
+		}
+		if err := yaml.Unmarshal(previous, &lock); err != nil {
+			return false, &FileError{Path: LockFileName, Err: fmt.Errorf("failed to parse lock file: %w", err)}
+		}
+		lock.SchemaVersion = LockFileSchemaVersion
+	}
+
+	for _, policy := range policies {
+		if policy.Status.LatestImage == "" {
+			continue
+		}
+		image, digest, _ := strings.Cut(policy.Status.LatestImage, "@")
+		locked := LockedImage{
+			Policy: policy.Namespace + "/" + policy.Name,
+			Image:  image,
+			Digest: digest,
+		}
+		if i := slices.IndexFunc(lock.Images, func(l LockedImage) bool { return l.Policy == locked.Policy }); i >= 0 {
+			lock.Images[i] = locked
+		} else {
+			lock.Images = append(lock.Images, locked)
+		}
+	}
+	slices.SortFunc(lock.Images, func(a, b LockedImage) int {
+		return strings.Compare(a.Policy, b.Policy)
+	})
+
+	data, err := yaml.Marshal(lock)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode lock file: %w", err)
+	}
+	data = append([]byte(lockFileHeader), data...)
+	if bytes.Equal(data, previous) {
+		return false, nil
+	}
+	if err := fs.WriteFile(path, data); err != nil {
+		return false, fmt.Errorf("failed to write lock file: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,243 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/kio/kioutil"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// OwnerAnnotation is the comment declaring the owners of a file, e.g.
+// `# flux-owner: team-x`. Several owners can be separated by spaces or commas.
+const OwnerAnnotation = "flux-owner:"
+
+// ErrNotOwned is the error of an update which was refused because it would
+// modify files which aren't owned by the owner of the update.
+var ErrNotOwned = errors.New("files not owned")
+
+// WithUpdateOptionOwner configures the update to only modify the files owned
+// by the given owner, and to fail without modifying any file otherwise. The
+// owners of a file are declared by an OwnerAnnotation comment in the file, or
+// else by the last matching rule of the CODEOWNERS file given with
+// WithUpdateOptionCodeOwners. A file without owners isn't owned by any owner.
+// A leading @ is ignored when comparing owners.
+func WithUpdateOptionOwner(owner string) UpdateOption {
+	return func(uo *UpdateOptions) {
+		uo.owner = owner
+	}
+}
+
+// WithUpdateOptionCodeOwners configures the CODEOWNERS file, in the GitHub
+// format, used to find the owners of the files without an OwnerAnnotation
+// comment. The dir is the path of the updated directory relative to the
+// directory the patterns of the CODEOWNERS file are relative to, i.e. the root
+// of the repository.
+func WithUpdateOptionCodeOwners(data []byte, dir string) UpdateOption {
+	return func(uo *UpdateOptions) {
+		uo.codeOwners = data
+		uo.codeOwnersDir = dir
+	}
+}
+
+// codeOwnersRule is a rule of a CODEOWNERS file.
+type codeOwnersRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// parseCodeOwners parses the rules of a CODEOWNERS file, in the GitHub
+// format.
+func parseCodeOwners(data []byte) ([]codeOwnersRule, error) {
+	var rules []codeOwnersRule
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		pattern, err := codeOwnersPattern(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid CODEOWNERS pattern '%s' on line %d: %w", fields[0], n, err)
+		}
+		rules = append(rules, codeOwnersRule{pattern: pattern, owners: fields[1:]})
+	}
+	return rules, scanner.Err()
+}
+
+// codeOwnersPattern compiles a CODEOWNERS pattern, which follows the rules of
+// the gitignore patterns, into a regular expression matching the paths
+// relative to the root of the repository.
+func codeOwnersPattern(pattern string) (*regexp.Regexp, error) {
+	// A pattern with a separator at the start or in the middle is relative
+	// to the root, any other matches at any depth.
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			b.WriteString("[^/]*")
+		case pattern[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	// A pattern matching a directory matches the files in it.
+	if !strings.HasSuffix(pattern, "/") {
+		b.WriteString("(?:/|$)")
+	}
+	return regexp.Compile(b.String())
+}
+
+// parseOwnerAnnotation returns the owners declared by the first
+// OwnerAnnotation comment of the given file, if any.
+func parseOwnerAnnotation(data []byte) []string {
+	for _, line := range strings.Split(string(data), "\n") {
+		comment, ok := strings.CutPrefix(strings.TrimSpace(line), "#")
+		if !ok {
+			continue
+		}
+		if owners, ok := strings.CutPrefix(strings.TrimSpace(comment), OwnerAnnotation); ok {
+			return strings.FieldsFunc(owners, func(r rune) bool {
+				return r == ',' || r == ' ' || r == '\t'
+			})
+		}
+	}
+	return nil
+}
+
+// ownershipCheck checks that the files to be modified are owned by the owner
+// of the update.
+type ownershipCheck struct {
+	owner      string
+	codeOwners []codeOwnersRule
+	// dir is the path of the updated directory relative to the root of
+	// the CODEOWNERS file.
+	dir string
+	// fileOwners are the owners declared by an OwnerAnnotation comment in
+	// each file.
+	fileOwners map[string][]string
+	// templates are the template files to be modified along with the
+	// files of the nodes.
+	templates []string
+	// checked records if the filter checked the files.
+	checked bool
+}
+
+// newOwnershipCheck returns the ownershipCheck of the given options, with
+// the owners declared in the files, or nil when the update has no owner.
+func newOwnershipCheck(opts *UpdateOptions, fileOwners map[string][]string) (*ownershipCheck, error) {
+	if opts.owner == "" {
+		return nil, nil
+	}
+	codeOwners, err := parseCodeOwners(opts.codeOwners)
+	if err != nil {
+		return nil, err
+	}
+	return &ownershipCheck{
+		owner:      opts.owner,
+		codeOwners: codeOwners,
+		dir:        opts.codeOwnersDir,
+		fileOwners: fileOwners,
+	}, nil
+}
+
+// owners returns the owners of the given file, relative to the updated
+// directory.
+func (c *ownershipCheck) owners(file string) []string {
+	if owners, ok := c.fileOwners[file]; ok {
+		return owners
+	}
+	p := path.Join(c.dir, file)
+	for i := len(c.codeOwners) - 1; i >= 0; i-- {
+		if c.codeOwners[i].pattern.MatchString(p) {
+			return c.codeOwners[i].owners
+		}
+	}
+	return nil
+}
+
+// check returns an error wrapping ErrNotOwned, detailing the owners of each
+// of the given files which isn't owned by the owner of the update.
+func (c *ownershipCheck) check(files []string) error {
+	if c == nil {
+		return nil
+	}
+	owner := strings.TrimPrefix(c.owner, "@")
+	var notOwned []string
+	for _, file := range files {
+		owners := c.owners(file)
+		if slices.ContainsFunc(owners, func(o string) bool { return strings.TrimPrefix(o, "@") == owner }) {
+			continue
+		}
+		if len(owners) == 0 {
+			notOwned = append(notOwned, fmt.Sprintf("'%s' (no owner)", file))
+			continue
+		}
+		notOwned = append(notOwned, fmt.Sprintf("'%s' (owned by %s)", file, strings.Join(owners, ", ")))
+	}
+	if len(notOwned) == 0 {
+		return nil
+	}
+	slices.Sort(notOwned)
+	return fmt.Errorf("%w by '%s': %s", ErrNotOwned, c.owner, strings.Join(notOwned, ", "))
+}
+
+// filter returns a kio.Filter checking the ownership of the files of the
+// nodes and of the templates, before they are written.
+func (c *ownershipCheck) filter() kio.Filter {
+	return kio.FilterFunc(func(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+		c.checked = true
+		files := slices.Clone(c.templates)
+		for _, node := range nodes {
+			file, _, err := kioutil.GetFileAnnotations(node)
+			if err != nil {
+				return nil, err
+			}
+			if !slices.Contains(files, file) {
+				files = append(files, file)
+			}
+		}
+		return nodes, c.check(files)
+	})
+}
@@ -0,0 +1,234 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// kustomizationFiles are the names of the files of the kustomize
+// Kustomizations, which may omit their apiVersion and kind.
+var kustomizationFiles = []string{"kustomization.yaml", "kustomization.yml"}
+
+// embeddedPatch is a patch embedded as a string in a Kustomization, e.g. in
+// a `patch: |` field.
+type embeddedPatch struct {
+	value *yaml.RNode
+	// path is the path of the patch in the Kustomization, e.g.
+	// '.patches[0].patch'.
+	path string
+}
+
+// kustomizationFields returns the node holding the patches of the given
+// object from the given file, and its path, when the object is a kustomize
+// Kustomization or Component, or a Flux Kustomization. It returns nil for
+// the other objects.
+func kustomizationFields(file string, object *yaml.RNode) (*yaml.RNode, string) {
+	meta, err := object.GetMeta()
+	if err != nil && !errors.Is(err, yaml.ErrMissingMetadata) {
+		return nil, ""
+	}
+	group, _, _ := strings.Cut(meta.APIVersion, "/")
+	switch {
+	case isKustomizationFile(file, meta):
+		return object, ""
+	case group == "kustomize.config.k8s.io" && (meta.Kind == "Kustomization" || meta.Kind == "Component"):
+		return object, ""
+	case group == "kustomize.toolkit.fluxcd.io" && meta.Kind == "Kustomization":
+		spec := object.Field("spec")
+		if spec == nil {
+			return nil, ""
+		}
+		return spec.Value, ".spec"
+	}
+	return nil, ""
+}
+
+// embeddedPatches returns the patches embedded as strings in the given
+// object from the given file, when it's a Kustomization: the inline entries
+// of patchesStrategicMerge, which can also be the paths of files, and the
+// patch fields of the entries of patches.
+func embeddedPatches(file string, object *yaml.RNode) []embeddedPatch {
+	fields, prefix := kustomizationFields(file, object)
+	if fields == nil || fields.YNode().Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var patches []embeddedPatch
+	if f := fields.Field("patchesStrategicMerge"); f != nil && f.Value.YNode().Kind == yaml.SequenceNode {
+		for i, entry := range f.Value.YNode().Content {
+			if entry.Kind == yaml.ScalarNode && strings.Contains(entry.Value, "\n") {
+				patches = append(patches, embeddedPatch{
+					value: yaml.NewRNode(entry),
+					path:  fmt.Sprintf("%s.patchesStrategicMerge[%d]", prefix, i),
+				})
+			}
+		}
+	}
+	if f := fields.Field("patches"); f != nil && f.Value.YNode().Kind == yaml.SequenceNode {
+		for i, entry := range f.Value.YNode().Content {
+			if entry.Kind != yaml.MappingNode {
+				continue
+			}
+			if patch := yaml.NewRNode(entry).Field("patch"); patch != nil && patch.Value.YNode().Kind == yaml.ScalarNode {
+				patches = append(patches, embeddedPatch{
+					value: patch.Value,
+					path:  fmt.Sprintf("%s.patches[%d].patch", prefix, i),
+				})
+			}
+		}
+	}
+	return patches
+}
+
+// FilterPatches sets the marked fields of the patches embedded as strings
+// in the given object from the given file, when it's a Kustomization, as
+// Filter does for the fields of the object. The patches are only changed at
+// the fields, keeping their formatting and indentation, and the fields are
+// attributed to the workload patched.
+func (s *SetAllCallback) FilterPatches(file string, object *yaml.RNode) error {
+	defer func() {
+		s.workload = WorkloadIdentifier{}
+	}()
+	for _, patch := range embeddedPatches(file, object) {
+		if err := s.setPatch(patch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setPatch sets the marked fields of the given patch.
+func (s *SetAllCallback) setPatch(patch embeddedPatch) error {
+	text := patch.value.YNode().Value
+	starts := lineStarts(text)
+
+	// The lines of the fields are only known in the Kustomization when the
+	// patch is a block scalar, starting on the line after its key.
+	line := patch.value.YNode().Line
+	block := patch.value.YNode().Style == yaml.LiteralStyle || patch.value.YNode().Style == yaml.FoldedStyle
+	fieldLine := func(n *yaml.Node) int {
+		if block {
+			return line + n.Line
+		}
+		return line
+	}
+
+	var edits []dataEdit
+	var setField func(n *yaml.Node) error
+	setField = func(n *yaml.Node) error {
+		switch n.Kind {
+		case yaml.DocumentNode, yaml.SequenceNode:
+			for _, c := range n.Content {
+				if err := setField(c); err != nil {
+					return err
+				}
+			}
+		case yaml.MappingNode:
+			for i := 1; i < len(n.Content); i += 2 {
+				if err := setField(n.Content[i]); err != nil {
+					return err
+				}
+			}
+		case yaml.ScalarNode:
+			fieldSchema := getSchema(yaml.NewRNode(n), s.SettersSchema)
+			if fieldSchema == nil {
+				s.unresolved(n, fieldLine(n), patch.path)
+				return nil
+			}
+			ext, err := getExtFromSchema(fieldSchema.Schema)
+			if err != nil {
+				return &FileError{Line: fieldLine(n), Err: fmt.Errorf("invalid marker in patch %s: %w", patch.path, err)}
+			}
+			if ext == nil {
+				s.unresolved(n, fieldLine(n), patch.path)
+				return nil
+			}
+			if ext.Setter == nil {
+				return nil
+			}
+			old := n.Value
+			s.line, s.path = fieldLine(n), patch.path
+			if s.ShouldSet != nil && !s.ShouldSet(ext.Setter.Name, old, ext.Setter.Value) {
+				s.TraceOrDiscard().Info("skipping setter", "setter", ext.Setter.Name, "patch", patch.path, "old", old, "new", ext.Setter.Value)
+				return nil
+			}
+			edit, err := scalarEdit(text, starts, n)
+			if err != nil {
+				return &FileError{Line: fieldLine(n), Setter: ext.Setter.Name, Err: fmt.Errorf("patch %s: %w", patch.path, err)}
+			}
+			edit.newValue = ext.Setter.Value
+			edits = append(edits, edit)
+			s.TraceOrDiscard().Info("applying setter", "setter", ext.Setter.Name, "patch", patch.path, "old", old, "new", ext.Setter.Value)
+			s.Callback(ext.Setter.Name, old, ext.Setter.Value)
+		}
+		return nil
+	}
+
+	// A patch of patchesStrategicMerge may hold several documents.
+	dec := yaml.NewDecoder(strings.NewReader(text))
+	for {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return &FileError{Line: line, Err: fmt.Errorf("failed to parse patch %s: %w", patch.path, err)}
+		}
+		s.workload = WorkloadIdentifier{}
+		if len(doc.Content) > 0 {
+			meta, _ := yaml.NewRNode(doc.Content[0]).GetMeta()
+			s.workload = WorkloadIdentifier{Kind: meta.Kind, Name: meta.Name}
+		}
+		if err := setField(&doc); err != nil {
+			return err
+		}
+	}
+	if len(edits) > 0 {
+		setDataValue(patch.value, false, edits)
+	}
+	return nil
+}
+
+// isKustomizationFile returns if the object with the given metadata is a
+// kustomize Kustomization omitting its apiVersion and kind, in a file with
+// the name of a Kustomization.
+func isKustomizationFile(file string, meta yaml.ResourceMeta) bool {
+	return meta.APIVersion == "" && meta.Kind == "" && slices.Contains(kustomizationFiles, filepath.Base(file))
+}
+
+// changedObject returns the identifier of the given object from the given
+// file, holding a changed field, and false if it isn't an object. The
+// Kustomizations omitting their apiVersion and kind are identified as
+// Kustomizations.
+func changedObject(file string, object *yaml.RNode) (ObjectIdentifier, bool) {
+	meta, err := object.GetMeta()
+	if err != nil && !(errors.Is(err, yaml.ErrMissingMetadata) && isKustomizationFile(file, meta)) {
+		return ObjectIdentifier{}, false
+	}
+	if isKustomizationFile(file, meta) {
+		meta.Kind = "Kustomization"
+	}
+	return ObjectIdentifier{ResourceIdentifier: meta.GetIdentifier()}, true
+}
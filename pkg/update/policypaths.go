@@ -0,0 +1,82 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// WithUpdateOptionPolicyPaths restricts the fields set by the given policies
+// to the files within the given paths. The paths are slash-separated and
+// relative to the updated directory; an empty path or "." doesn't restrict the
+// policy. The policies not in the map aren't restricted either.
+func WithUpdateOptionPolicyPaths(paths map[types.NamespacedName]string) UpdateOption {
+	return func(uo *UpdateOptions) {
+		uo.policyPaths = paths
+	}
+}
+
+// pathScope is the directory, relative to the updated directory, within
+// which each setter is restricted to set the fields. The setters not in the
+// scope aren't restricted.
+type pathScope map[string]string
+
+// newPathScope returns the pathScope of the setters of the given policies
+// and paths, or nil if none is restricted.
+func newPathScope(paths map[types.NamespacedName]string) pathScope {
+	var scope pathScope
+	for policy, p := range paths {
+		p = path.Clean(p)
+		if p == "." {
+			continue
+		}
+		if scope == nil {
+			scope = pathScope{}
+		}
+		imageSetter := fmt.Sprintf("%s:%s", policy.Namespace, policy.Name)
+		scope[imageSetter] = p
+		scope[imageSetter+":tag"] = p
+		scope[imageSetter+":name"] = p
+	}
+	return scope
+}
+
+// allows returns if the given setter may set the fields of the given file,
+// relative to the updated directory.
+func (s pathScope) allows(setter, file string) bool {
+	dir, ok := s[setter]
+	if !ok {
+		return true
+	}
+	return IsWithinPath(filepath.ToSlash(file), dir)
+}
+
+// IsWithinPath returns if the given slash-separated path is the given
+// directory or within it. Both paths are cleaned; "." contains any relative
+// path.
+func IsWithinPath(p, dir string) bool {
+	p, dir = path.Clean(p), path.Clean(dir)
+	if dir == "." {
+		return true
+	}
+	return p == dir || strings.HasPrefix(p, dir+"/")
+}
@@ -1,161 +1,56 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
 package update
 
 import (
-	"github.com/google/go-containerregistry/pkg/name"
-	"k8s.io/apimachinery/pkg/types"
-	"sigs.k8s.io/kustomize/kyaml/yaml"
+	templatesv1 "github.com/fluxcd/image-automation-controller/pkg/templates/v1"
 )
 
-// ImageRef represents the image reference used to replace a field
-// value in an update.
-type ImageRef interface {
-	// String returns a string representation of the image ref as it
-	// is used in the update; e.g., "helloworld:v1.0.1"
-	String() string
-	// Identifier returns the tag or digest; e.g., "v1.0.1"
-	Identifier() string
-	// Repository returns the repository component of the ImageRef,
-	// with an implied defaults, e.g., "library/helloworld"
-	Repository() string
-	// Registry returns the registry component of the ImageRef, e.g.,
-	// "index.docker.io"
-	Registry() string
-	// Name gives the fully-qualified reference name, e.g.,
-	// "index.docker.io/library/helloworld:v1.0.1"
-	Name() string
-	// Policy gives the namespaced name of the image policy that led
-	// to the update.
-	Policy() types.NamespacedName
-}
-
-type imageRef struct {
-	name.Reference
-	policy types.NamespacedName
-}
+// The results of the updates are given to the commit templates, and are
+// defined with the other types of the template data in the templates/v1
+// package. They're aliased here for the updates to be used as before.
 
-// Policy gives the namespaced name of the policy that led to the
+// ImageRef represents the image reference used to replace a field value in an
 // update.
-func (i imageRef) Policy() types.NamespacedName {
-	return i.policy
-}
-
-// Repository gives the repository component of the image ref.
-func (i imageRef) Repository() string {
-	return i.Context().RepositoryStr()
-}
+type ImageRef = templatesv1.ImageRef
 
-// Registry gives the registry component of the image ref.
-func (i imageRef) Registry() string {
-	return i.Context().Registry.String()
-}
+// ObjectIdentifier holds the identifying data for a particular object.
+type ObjectIdentifier = templatesv1.ObjectIdentifier
 
-// ObjectIdentifier holds the identifying data for a particular
-// object. This won't always have a name (e.g., a kustomization.yaml).
-type ObjectIdentifier struct {
-	yaml.ResourceIdentifier
-}
+// WorkloadIdentifier identifies the workload running the image of a field of
+// a custom resource, when it isn't the object itself.
+type WorkloadIdentifier = templatesv1.WorkloadIdentifier
 
-// Result reports the outcome of an automated update. It has a nested
-// structure file->objects->images. Different projections (e.g., all
-// the images, regardless of object) are available via methods.
-type Result struct {
-	Files map[string]FileResult
-}
+// Result reports the outcome of an automated update.
+type Result = templatesv1.Result
 
 // FileResult gives the updates in a particular file.
-type FileResult struct {
-	Objects map[ObjectIdentifier][]ImageRef
-}
-
-// Images returns all the images that were involved in at least one
-// update.
-func (r Result) Images() []ImageRef {
-	seen := make(map[ImageRef]struct{})
-	var result []ImageRef
-	for _, file := range r.Files {
-		for _, images := range file.Objects {
-			for _, ref := range images {
-				if _, ok := seen[ref]; !ok {
-					seen[ref] = struct{}{}
-					result = append(result, ref)
-				}
-			}
-		}
-	}
-	return result
-}
-
-// Objects returns a map of all the objects against the images updated
-// within, regardless of which file they appear in.
-func (r Result) Objects() map[ObjectIdentifier][]ImageRef {
-	result := make(map[ObjectIdentifier][]ImageRef)
-	for _, file := range r.Files {
-		for res, refs := range file.Objects {
-			result[res] = append(result[res], refs...)
-		}
-	}
-	return result
-}
+type FileResult = templatesv1.FileResult
 
 // ResultV2 contains Result of update and also the file changes made during the
-// update. This extends the Result to include details about the exact changes
-// made to the files and the objects in them. It has a nested structure
-// file->objects->changes.
-type ResultV2 struct {
-	ImageResult Result
-	FileChanges map[string]ObjectChanges
-}
+// update.
+type ResultV2 = templatesv1.ResultV2
 
 // ObjectChanges contains all the changes made to objects.
-type ObjectChanges map[ObjectIdentifier][]Change
+type ObjectChanges = templatesv1.ObjectChanges
 
 // Change contains the setter that resulted in a Change, the old and the new
 // value after the Change.
-type Change struct {
-	OldValue string
-	NewValue string
-	Setter   string
-}
-
-// AddChange adds changes to Resultv2 for a given file, object and changes
-// associated with it.
-func (r *ResultV2) AddChange(file string, objectID ObjectIdentifier, changes ...Change) {
-	if r.FileChanges == nil {
-		r.FileChanges = map[string]ObjectChanges{}
-	}
-	// Create an entry for the file if not present.
-	_, ok := r.FileChanges[file]
-	if !ok {
-		r.FileChanges[file] = ObjectChanges{}
-	}
-	// Append to the changes for the object.
-	r.FileChanges[file][objectID] = append(r.FileChanges[file][objectID], changes...)
-}
-
-// Changes returns all the changes that were made in at least one update.
-func (r ResultV2) Changes() []Change {
-	seen := make(map[Change]struct{})
-	var result []Change
-	for _, objChanges := range r.FileChanges {
-		for _, changes := range objChanges {
-			for _, change := range changes {
-				if _, ok := seen[change]; !ok {
-					seen[change] = struct{}{}
-					result = append(result, change)
-				}
-			}
-		}
-	}
-	return result
-}
+type Change = templatesv1.Change
 
-// Objects returns ObjectChanges, regardless of which file they appear in.
-func (r ResultV2) Objects() ObjectChanges {
-	result := make(ObjectChanges)
-	for _, objChanges := range r.FileChanges {
-		for obj, change := range objChanges {
-			result[obj] = change
-		}
-	}
-	return result
-}
+// PathResult is the part of a ResultV2 within a directory.
+type PathResult = templatesv1.PathResult
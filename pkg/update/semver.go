@@ -0,0 +1,99 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// SemverJump is the semver distance between two versions.
+type SemverJump string
+
+const (
+	// SemverJumpMajor is a change of the major version.
+	SemverJumpMajor SemverJump = "Major"
+	// SemverJumpMinor is a change of the minor version.
+	SemverJumpMinor SemverJump = "Minor"
+	// SemverJumpPatch is a change of the patch version, or of the
+	// prerelease.
+	SemverJumpPatch SemverJump = "Patch"
+)
+
+// ErrSemverJumpExceeded is the error of an update which was refused because a
+// version would change more than the maximum semver jump.
+var ErrSemverJumpExceeded = errors.New("maximum semver jump exceeded")
+
+// WithUpdateOptionMaxSemverJump configures the update to refuse to change the
+// version in a field by more than the given semver jump, e.g. with
+// SemverJumpMinor the major version can't change. The values which aren't
+// semver are not limited.
+func WithUpdateOptionMaxSemverJump(jump SemverJump) UpdateOption {
+	return func(uo *UpdateOptions) {
+		uo.maxSemverJump = jump
+	}
+}
+
+// exceedsSemverJump returns if the new value of a field set by the given
+// setter changes its version by more than the maximum jump.
+func exceedsSemverJump(maxJump SemverJump, setter, oldValue, newValue string) bool {
+	if maxJump == "" || maxJump == SemverJumpMajor || oldValue == newValue {
+		return false
+	}
+	if strings.HasSuffix(setter, ":name") {
+		return false
+	}
+	// The image setter sets a whole image reference, from which the tag
+	// is compared.
+	if !strings.HasSuffix(setter, ":tag") {
+		var err error
+		if _, oldValue, _, err = parseImage(oldValue); err != nil {
+			return false
+		}
+		if _, newValue, _, err = parseImage(newValue); err != nil {
+			return false
+		}
+	}
+	oldVersion, err := semver.NewVersion(oldValue)
+	if err != nil {
+		return false
+	}
+	newVersion, err := semver.NewVersion(newValue)
+	if err != nil {
+		return false
+	}
+
+	switch {
+	case oldVersion.Major() != newVersion.Major():
+		return true
+	case oldVersion.Minor() != newVersion.Minor():
+		return maxJump == SemverJumpPatch
+	default:
+		return false
+	}
+}
+
+// semverJumpError returns the error of a field whose version would change by
+// more than the maximum jump. The location of the field is given by the
+// FileError wrapping it.
+func semverJumpError(oldValue, newValue string, maxJump SemverJump) error {
+	return fmt.Errorf("%w: value '%s' would change to '%s' (maximum jump: %s)",
+		ErrSemverJumpExceeded, oldValue, newValue, maxJump)
+}
@@ -17,14 +17,20 @@ limitations under the License.
 package update
 
 import (
+	"errors"
 	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 
+	"github.com/go-git/go-billy/v5"
 	"github.com/go-logr/logr"
 	"github.com/google/go-containerregistry/pkg/name"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/kube-openapi/pkg/validation/spec"
 	"sigs.k8s.io/kustomize/kyaml/fieldmeta"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
 	"sigs.k8s.io/kustomize/kyaml/kio"
 	"sigs.k8s.io/kustomize/kyaml/kio/kioutil"
 	"sigs.k8s.io/kustomize/kyaml/openapi"
@@ -32,6 +38,8 @@ import (
 	"sigs.k8s.io/kustomize/kyaml/yaml"
 
 	imagev1_reflect "github.com/fluxcd/image-reflector-controller/api/v1beta2"
+
+	templatesv1 "github.com/fluxcd/image-automation-controller/pkg/templates/v1"
 )
 
 const (
@@ -52,11 +60,61 @@ func init() {
 	openapi.SuppressBuiltInSchemaUse()
 }
 
+// UpdateOptions contains the optional attributes of an update.
+type UpdateOptions struct {
+	workers        int
+	workTree       billy.Filesystem
+	conflictPolicy ConflictPolicy
+	previousImages map[types.NamespacedName]string
+	maxSemverJump  SemverJump
+	scanTemplates  bool
+	owner          string
+	codeOwners     []byte
+	codeOwnersDir  string
+	symlinkPolicy  SymlinkPolicy
+	symlinkRoot    string
+	policyPaths    map[types.NamespacedName]string
+	memoryBudget   int64
+}
+
+// UpdateOption configures the update options.
+type UpdateOption func(*UpdateOptions)
+
+// WithUpdateOptionWorkers configures the number of workers used to
+// screen files and apply setters concurrently. If zero or less, one
+// worker per usable CPU (GOMAXPROCS) is used, which is the default.
+func WithUpdateOptionWorkers(workers int) UpdateOption {
+	return func(uo *UpdateOptions) {
+		uo.workers = workers
+	}
+}
+
+// WithUpdateOptionWorkTree configures the update to read and write the files
+// in the given billy.Filesystem, e.g., the worktree of a Git repository
+// checked out in memory, instead of on disk. The input and output paths are
+// then relative to the root of the filesystem.
+func WithUpdateOptionWorkTree(wt billy.Filesystem) UpdateOption {
+	return func(uo *UpdateOptions) {
+		uo.workTree = wt
+	}
+}
+
+// WithUpdateOptionMemoryBudget configures the update to read and update the
+// YAML files in batches, whose parsed nodes take an estimated memory of at
+// most the given number of bytes, instead of all at once. The result is the
+// same, only the peak memory usage of the update is lower. If zero or less,
+// the files are updated all at once, which is the default.
+func WithUpdateOptionMemoryBudget(bytes int64) UpdateOption {
+	return func(uo *UpdateOptions) {
+		uo.memoryBudget = bytes
+	}
+}
+
 // UpdateWithSetters takes all YAML files from `inpath`, updates any
 // that contain an "in scope" image policy marker, and writes files it
 // updated (and only those files) back to `outpath`.
-func UpdateWithSetters(tracelog logr.Logger, inpath, outpath string, policies []imagev1_reflect.ImagePolicy) (Result, error) {
-	result, err := UpdateV2WithSetters(tracelog, inpath, outpath, policies)
+func UpdateWithSetters(tracelog logr.Logger, inpath, outpath string, policies []imagev1_reflect.ImagePolicy, options ...UpdateOption) (Result, error) {
+	result, err := UpdateV2WithSetters(tracelog, inpath, outpath, policies, options...)
 	return result.ImageResult, err
 }
 
@@ -64,7 +122,12 @@ func UpdateWithSetters(tracelog logr.Logger, inpath, outpath string, policies []
 // that contain an "in scope" image policy marker, and writes files it
 // updated (and only those files) back to `outpath`. It also returns the result
 // of the changes it made as ResultV2.
-func UpdateV2WithSetters(tracelog logr.Logger, inpath, outpath string, policies []imagev1_reflect.ImagePolicy) (ResultV2, error) {
+func UpdateV2WithSetters(tracelog logr.Logger, inpath, outpath string, policies []imagev1_reflect.ImagePolicy, options ...UpdateOption) (ResultV2, error) {
+	opts := &UpdateOptions{}
+	for _, o := range options {
+		o(opts)
+	}
+
 	// the OpenAPI schema is a package variable in kyaml/openapi. In
 	// lieu of being able to isolate invocations (per
 	// https://github.com/kubernetes-sigs/kustomize/issues/3058), I
@@ -115,24 +178,20 @@ func UpdateV2WithSetters(tracelog logr.Logger, inpath, outpath string, policies
 	// which can be used to look up the image ref; the file and object
 	// we will get from `setAll` which keeps track of those as it
 	// iterates.
-	imageRefs := make(map[string]imageRef)
-	setAllCallback := func(file, setterName string, node *yaml.RNode, old, new string) {
+	var imageRefs map[string]ImageRef
+	recordChange := func(file string, oid ObjectIdentifier, workload WorkloadIdentifier, setterName string, line int, old, new string) {
 		ref, ok := imageRefs[setterName]
 		if !ok {
 			return
 		}
 
-		meta, err := node.GetMeta()
-		if err != nil {
-			return
-		}
-		oid := ObjectIdentifier{meta.GetIdentifier()}
-
 		// Record the change.
 		ch := Change{
 			OldValue: old,
 			NewValue: new,
 			Setter:   setterName,
+			Workload: workload,
+			Line:     line,
 		}
 		// Append the change for the file and identifier.
 		resultV2.AddChange(file, oid, ch)
@@ -153,83 +212,262 @@ func UpdateV2WithSetters(tracelog logr.Logger, inpath, outpath string, policies
 		objres = append(objres, ref)
 		fileres.Objects[oid] = objres
 	}
+	setAllCallback := func(file, setterName string, node *yaml.RNode, workload WorkloadIdentifier, line int, old, new string) {
+		oid, ok := changedObject(file, node)
+		if !ok {
+			return
+		}
+		recordChange(file, oid, workload, setterName, line, old, new)
+	}
 
+	setterValues, imageRefs, err := policySetters(tracelog, policies)
+	if err != nil {
+		return ResultV2{}, err
+	}
 	defs := map[string]spec.Schema{}
+	for setter, value := range setterValues {
+		defs[fieldmeta.SetterDefinitionPrefix+setter] = setterSchema(setter, value)
+	}
+
+	settersSchema.Definitions = defs
+	scope := newPathScope(opts.policyPaths)
+
+	conflicts, err := newConflictCheck(opts)
+	if err != nil {
+		return ResultV2{}, err
+	}
+	recordConflict := func(file string, oid ObjectIdentifier, workload WorkloadIdentifier, setterName string, line int, old, new string) {
+		resultV2.AddConflict(file, oid, Change{
+			OldValue: old,
+			NewValue: new,
+			Setter:   setterName,
+			Workload: workload,
+			Line:     line,
+		})
+	}
+	conflictCallback := func(file, setterName string, node *yaml.RNode, workload WorkloadIdentifier, line int, old, new string) {
+		oid, ok := changedObject(file, node)
+		if !ok {
+			return
+		}
+		recordConflict(file, oid, workload, setterName, line, old, new)
+	}
+	ignoreCallback := func(file, setterName string, node *yaml.RNode, workload WorkloadIdentifier, line int, old, new string) {
+		oid, ok := changedObject(file, node)
+		if !ok {
+			return
+		}
+		resultV2.AddIgnored(file, oid, Change{
+			OldValue: old,
+			NewValue: new,
+			Setter:   setterName,
+			Workload: workload,
+			Line:     line,
+		})
+	}
+	recordUnresolved := func(file string, oid ObjectIdentifier, workload WorkloadIdentifier, setterName string, line int, value, _ string) {
+		resultV2.AddUnresolved(file, oid, Change{
+			OldValue: value,
+			Setter:   setterName,
+			Workload: workload,
+			Line:     line,
+		})
+	}
+	unresolvedCallback := func(file, setterName string, node *yaml.RNode, workload WorkloadIdentifier, line int, value, _ string) {
+		oid, ok := changedObject(file, node)
+		if !ok {
+			return
+		}
+		recordUnresolved(file, oid, workload, setterName, line, value, "")
+	}
+
+	// get ready with the reader and writer
+	reader := &ScreeningLocalReader{
+		Path:          inpath,
+		Token:         fmt.Sprintf("%q", SetterShortHand),
+		Trace:         tracelog,
+		Workers:       opts.workers,
+		ScanTemplates: opts.scanTemplates,
+		SymlinkPolicy: opts.symlinkPolicy,
+		Root:          opts.symlinkRoot,
+	}
+	writer := &kio.LocalPackageWriter{
+		PackagePath: outpath,
+	}
+	var writeFS filesys.FileSystem = filesys.MakeFsOnDisk()
+	if opts.workTree != nil {
+		fs := workTreeFileSystem{fs: opts.workTree}
+		reader.FileSystem.Set(fs)
+		writeFS = fs
+		writer.PackagePath = filepath.Join(string(filepath.Separator), outpath)
+	}
+
+	// The files are read in batches within the memory budget, each
+	// updated by a pipeline of its own. The updated files are staged
+	// until all of them were updated, and their ownership checked.
+	writable := newWritableFileSystem(writeFS, writer.PackagePath)
+	staged := newStagedFileSystem(writable)
+	writer.FileSystem.Set(staged)
+	var updatedFiles []string
+	recordUpdatedFiles := kio.FilterFunc(func(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+		for _, node := range nodes {
+			file, _, err := kioutil.GetFileAnnotations(node)
+			if err != nil {
+				return nil, err
+			}
+			if !slices.Contains(updatedFiles, file) {
+				updatedFiles = append(updatedFiles, file)
+			}
+		}
+		return nodes, nil
+	})
+	err = reader.ReadBatches(opts.memoryBudget, func(nodes []*yaml.RNode) error {
+		pipeline := kio.Pipeline{
+			Inputs:  []kio.Reader{&kio.PackageBuffer{Nodes: nodes}},
+			Outputs: []kio.Writer{writer},
+			Filters: []kio.Filter{
+				setAll(&settersSchema, tracelog, opts.workers, scope, opts.maxSemverJump, conflicts, reader.DocumentLines, setAllCallback, conflictCallback, ignoreCallback, unresolvedCallback),
+				recordUpdatedFiles,
+			},
+		}
+		return pipeline.Execute()
+	})
+	if err != nil {
+		return ResultV2{}, err
+	}
+	resultV2.SkippedFiles = reader.SkippedFiles
+
+	// The files are written back in the encoding they were read in.
+	writable.encodings = reader.Encodings
+	staged.writableFileSystem = writable
+
+	// The templates are only written once the files are updated.
+	templates := make([]TemplateFile, 0, len(reader.Templates))
+	for _, tf := range reader.Templates {
+		data, err := updateTemplate(tf, setterValues, scope, opts.maxSemverJump, conflicts, recordChange, recordConflict, recordUnresolved)
+		if err != nil {
+			return ResultV2{}, err
+		}
+		if data != nil {
+			templates = append(templates, TemplateFile{Path: tf.Path, Data: data})
+		}
+	}
+
+	// Check the ownership of the files before writing them.
+	ownership, err := newOwnershipCheck(opts, reader.FileOwners)
+	if err != nil {
+		return ResultV2{}, err
+	}
+	if ownership != nil {
+		files := slices.Clone(updatedFiles)
+		for _, tf := range templates {
+			files = append(files, tf.Path)
+		}
+		if err := ownership.check(files); err != nil {
+			return ResultV2{}, err
+		}
+	}
+
+	if err := staged.commit(); err != nil {
+		return ResultV2{}, err
+	}
+	for _, tf := range templates {
+		if err := writable.WriteFile(filepath.Join(writer.PackagePath, tf.Path), tf.Data); err != nil {
+			return ResultV2{}, fmt.Errorf("writing template file: %w", err)
+		}
+	}
+
+	// Combine the results.
+	resultV2.ImageResult = result
+	return resultV2, nil
+}
+
+// policySetters returns the values of the image, tag and name setters of
+// the given policies, and the image ref of each setter.
+func policySetters(tracelog logr.Logger, policies []imagev1_reflect.ImagePolicy) (map[string]string, map[string]ImageRef, error) {
+	setterValues := map[string]string{}
+	imageRefs := map[string]ImageRef{}
 	for _, policy := range policies {
 		if policy.Status.LatestImage == "" {
 			continue
 		}
-		// Using strict validation would mean any image that omits the
-		// registry would be rejected, so that can't be used
-		// here. Using _weak_ validation means that defaults will be
-		// filled in. Usually this would mean the tag would end up
-		// being `latest` if empty in the input; but I'm assuming here
-		// that the policy won't have a tagless ref.
-		image := policy.Status.LatestImage
-		r, err := name.ParseReference(image, name.WeakValidation)
+		r, tag, name, err := parseImage(policy.Status.LatestImage)
 		if err != nil {
-			return ResultV2{}, fmt.Errorf("encountered invalid image ref %q: %w", policy.Status.LatestImage, err)
+			return nil, nil, err
 		}
-		ref := imageRef{
-			Reference: r,
-			policy: types.NamespacedName{
-				Name:      policy.Name,
-				Namespace: policy.Namespace,
-			},
-		}
-
-		tag := ref.Identifier()
-		// annoyingly, neither the library imported above, nor an
-		// alternative I found, will yield the original image name;
-		// this is an easy way to get it
-		name := strings.TrimSuffix(image, ":"+tag)
+		ref := templatesv1.NewImageRef(r, types.NamespacedName{
+			Name:      policy.Name,
+			Namespace: policy.Namespace,
+		})
 
 		imageSetter := fmt.Sprintf("%s:%s", policy.GetNamespace(), policy.GetName())
 		tracelog.Info("adding setter", "name", imageSetter)
-		defs[fieldmeta.SetterDefinitionPrefix+imageSetter] = setterSchema(imageSetter, policy.Status.LatestImage)
 		imageRefs[imageSetter] = ref
+		setterValues[imageSetter] = policy.Status.LatestImage
 
 		tagSetter := imageSetter + ":tag"
 		tracelog.Info("adding setter", "name", tagSetter)
-		defs[fieldmeta.SetterDefinitionPrefix+tagSetter] = setterSchema(tagSetter, tag)
 		imageRefs[tagSetter] = ref
+		setterValues[tagSetter] = tag
 
 		// Context().Name() gives the image repository _as supplied_
 		nameSetter := imageSetter + ":name"
 		tracelog.Info("adding setter", "name", nameSetter)
-		defs[fieldmeta.SetterDefinitionPrefix+nameSetter] = setterSchema(nameSetter, name)
 		imageRefs[nameSetter] = ref
+		setterValues[nameSetter] = name
 	}
+	return setterValues, imageRefs, nil
+}
 
-	settersSchema.Definitions = defs
-
-	// get ready with the reader and writer
-	reader := &ScreeningLocalReader{
-		Path:  inpath,
-		Token: fmt.Sprintf("%q", SetterShortHand),
-		Trace: tracelog,
+// newConflictCheck returns the conflictCheck of the given options, with
+// the previous values of the setters, or nil when the conflicts aren't
+// detected.
+func newConflictCheck(opts *UpdateOptions) (*conflictCheck, error) {
+	if opts.previousImages == nil {
+		return nil, nil
 	}
-	writer := &kio.LocalPackageWriter{
-		PackagePath: outpath,
+	conflicts := &conflictCheck{
+		policy:   opts.conflictPolicy,
+		previous: map[string]string{},
 	}
-
-	pipeline := kio.Pipeline{
-		Inputs:  []kio.Reader{reader},
-		Outputs: []kio.Writer{writer},
-		Filters: []kio.Filter{
-			setAll(&settersSchema, tracelog, setAllCallback),
-		},
+	for policy, image := range opts.previousImages {
+		_, tag, name, err := parseImage(image)
+		if err != nil {
+			return nil, err
+		}
+		imageSetter := fmt.Sprintf("%s:%s", policy.Namespace, policy.Name)
+		conflicts.previous[imageSetter] = image
+		conflicts.previous[imageSetter+":tag"] = tag
+		conflicts.previous[imageSetter+":name"] = name
 	}
+	return conflicts, nil
+}
 
-	// go!
-	err := pipeline.Execute()
+// parseImage parses the given image reference, and returns it with the values
+// of its tag and name setters.
+func parseImage(image string) (name.Reference, string, string, error) {
+	// Using strict validation would mean any image that omits the
+	// registry would be rejected, so that can't be used
+	// here. Using _weak_ validation means that defaults will be
+	// filled in. Usually this would mean the tag would end up
+	// being `latest` if empty in the input; but I'm assuming here
+	// that the policy won't have a tagless ref.
+	r, err := name.ParseReference(image, name.WeakValidation)
 	if err != nil {
-		return ResultV2{}, err
+		return nil, "", "", fmt.Errorf("encountered invalid image ref %q: %w", image, err)
 	}
 
-	// Combine the results.
-	resultV2.ImageResult = result
-	return resultV2, nil
+	tag := r.Identifier()
+	// The identifier of an image pinned to a digest is the digest,
+	// separated from the name by an at sign.
+	sep := ":"
+	if _, ok := r.(name.Digest); ok {
+		sep = "@"
+	}
+	// annoyingly, neither the library imported above, nor an
+	// alternative I found, will yield the original image name;
+	// this is an easy way to get it
+	return r, tag, strings.TrimSuffix(image, sep+tag), nil
 }
 
 // setAll returns a kio.Filter using the supplied SetAllCallback
@@ -238,39 +476,177 @@ func UpdateV2WithSetters(tracelog logr.Logger, inpath, outpath string, policies
 // files with changed nodes. This is based on
 // [`SetAll`](https://github.com/kubernetes-sigs/kustomize/blob/kyaml/v0.10.16/kyaml/setters2/set.go#L503
 // from kyaml/kio.
-func setAll(schema *spec.Schema, tracelog logr.Logger, callback func(file, setterName string, node *yaml.RNode, old, new string)) kio.Filter {
-	filter := &SetAllCallback{
-		SettersSchema: schema,
-		Trace:         tracelog,
+//
+// The nodes are filtered by a pool of workers. The changes are
+// recorded per node and the callback is called for them afterwards,
+// in the order of the nodes, so that the result is deterministic.
+//
+// The fields of the files outside the path of their setter in the
+// given scope are left unchanged.
+//
+// A field which would change by more than the given maximum semver
+// jump fails the filter. The conflicts found with the given
+// conflictCheck, if any, are handled according to its policy, and the
+// conflictCallback is called for them in the same way.
+//
+// The fields of the objects opting out with IgnoreAnnotation, or not
+// selected with the DocumentsAnnotation of their file, are left unchanged,
+// and the ignoreCallback is called for the changes they would have had.
+//
+// The marked fields of the patches embedded as strings in the
+// Kustomizations are set too, see SetAllCallback.FilterPatches.
+//
+// The unresolvedCallback is called for the fields marked with a setter
+// missing from the schema, outside of the ignored objects, with their
+// value as the old value.
+//
+// The callbacks are given the line of each field in its file, using the
+// given lines the documents of the files start at, or zero when they are
+// unknown, and the workload of the field, if any.
+func setAll(schema *spec.Schema, tracelog logr.Logger, workers int, scope pathScope, maxJump SemverJump, conflicts *conflictCheck, docLines map[string][]int,
+	callback, conflictCallback, ignoreCallback, unresolvedCallback func(file, setterName string, node *yaml.RNode, workload WorkloadIdentifier, line int, old, new string)) kio.Filter {
+	type fieldChange struct {
+		setter, oldValue, newValue string
+		line                       int
+		workload                   WorkloadIdentifier
 	}
 	return kio.FilterFunc(
 		func(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
-			filesToUpdate := sets.String{}
+			paths := make([]string, len(nodes))
+			docs := make([]int, len(nodes))
+			fileDocs := map[string]*documentSelector{}
 			for i := range nodes {
-				path, _, err := kioutil.GetFileAnnotations(nodes[i])
+				path, index, err := kioutil.GetFileAnnotations(nodes[i])
 				if err != nil {
 					return nil, err
 				}
+				paths[i] = path
+				docs[i], _ = strconv.Atoi(index)
+				if docs[i] == 0 {
+					fileDocs[path] = documentsOf(nodes[i])
+				}
+			}
 
+			changes := make([][]fieldChange, len(nodes))
+			nodeConflicts := make([][]fieldChange, len(nodes))
+			nodeJumps := make([][]fieldChange, len(nodes))
+			nodeIgnores := make([][]fieldChange, len(nodes))
+			nodeUnresolved := make([][]fieldChange, len(nodes))
+			err := parallelFor(workers, len(nodes), func(i int) error {
+				ignored := isIgnored(nodes[i]) || !fileDocs[paths[i]].selects(docs[i], nodes[i])
+				filter := &SetAllCallback{
+					SettersSchema: schema,
+					Trace:         tracelog,
+				}
+				meta, _ := nodes[i].GetMeta()
+				// The workload of a field is the object patched by the
+				// patch holding it, or the one the path of the field is
+				// attributed to.
+				workload := func() WorkloadIdentifier {
+					if !filter.workload.IsZero() {
+						return filter.workload
+					}
+					return workloadOf(meta.GetIdentifier(), filter.path)
+				}
 				filter.Callback = func(setter, oldValue, newValue string) {
 					if newValue != oldValue {
-						callback(path, setter, nodes[i], oldValue, newValue)
-						filesToUpdate.Insert(path)
+						changes[i] = append(changes[i], fieldChange{setter, oldValue, newValue, filter.line, workload()})
+					}
+				}
+				filter.Unresolved = func(setter, value string) {
+					if !ignored {
+						nodeUnresolved[i] = append(nodeUnresolved[i], fieldChange{setter, value, "", filter.line, workload()})
+					}
+				}
+				filter.ShouldSet = func(setter, oldValue, newValue string) bool {
+					if !scope.allows(setter, paths[i]) {
+						return false
+					}
+					if ignored {
+						if newValue != oldValue {
+							nodeIgnores[i] = append(nodeIgnores[i], fieldChange{setter, oldValue, newValue, filter.line, workload()})
+						}
+						return false
+					}
+					if exceedsSemverJump(maxJump, setter, oldValue, newValue) {
+						nodeJumps[i] = append(nodeJumps[i], fieldChange{setter, oldValue, newValue, filter.line, workload()})
+						return false
 					}
+					if !conflicts.isConflict(setter, oldValue, newValue) {
+						return true
+					}
+					nodeConflicts[i] = append(nodeConflicts[i], fieldChange{setter, oldValue, newValue, filter.line, workload()})
+					return !conflicts.skip()
+				}
+				_, err := filter.Filter(nodes[i])
+				if err == nil {
+					err = filter.FilterPatches(paths[i], nodes[i])
 				}
-				_, err = filter.Filter(nodes[i])
 				if err != nil {
-					return nil, err
+					fileErr := &FileError{Path: paths[i], Document: docs[i], Err: err}
+					var e *FileError
+					if errors.As(err, &e) {
+						fileErr.Line, fileErr.Setter, fileErr.Err = e.Line, e.Setter, e.Err
+					}
+					return fileErr
 				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			// fileLine gives the line of a field of the given node in its
+			// file, from its line in the document.
+			fileLine := func(i int, line int) int {
+				starts := docLines[paths[i]]
+				if docs[i] >= len(starts) || line == 0 {
+					return 0
+				}
+				return starts[docs[i]] + line - 1
 			}
 
-			var nodesInUpdatedFiles []*yaml.RNode
 			for i := range nodes {
-				path, _, err := kioutil.GetFileAnnotations(nodes[i])
-				if err != nil {
-					return nil, err
+				if len(nodeJumps[i]) > 0 {
+					ch := nodeJumps[i][0]
+					return nil, &FileError{Path: paths[i], Document: docs[i], Line: ch.line, Setter: ch.setter,
+						Err: semverJumpError(ch.oldValue, ch.newValue, maxJump)}
+				}
+			}
+
+			for i := range nodes {
+				for _, ch := range nodeConflicts[i] {
+					if conflicts.fail() {
+						return nil, &FileError{Path: paths[i], Document: docs[i], Line: ch.line, Setter: ch.setter,
+							Err: conflicts.error(ch.setter, ch.oldValue)}
+					}
+					conflictCallback(paths[i], ch.setter, nodes[i], ch.workload, fileLine(i, ch.line), ch.oldValue, ch.newValue)
+				}
+			}
+
+			for i := range nodes {
+				for _, ch := range nodeIgnores[i] {
+					ignoreCallback(paths[i], ch.setter, nodes[i], ch.workload, fileLine(i, ch.line), ch.oldValue, ch.newValue)
+				}
+			}
+
+			for i := range nodes {
+				for _, ch := range nodeUnresolved[i] {
+					unresolvedCallback(paths[i], ch.setter, nodes[i], ch.workload, fileLine(i, ch.line), ch.oldValue, ch.newValue)
 				}
-				if filesToUpdate.Has(path) {
+			}
+
+			filesToUpdate := sets.String{}
+			for i := range nodes {
+				for _, ch := range changes[i] {
+					callback(paths[i], ch.setter, nodes[i], ch.workload, fileLine(i, ch.line), ch.oldValue, ch.newValue)
+					filesToUpdate.Insert(paths[i])
+				}
+			}
+
+			var nodesInUpdatedFiles []*yaml.RNode
+			for i := range nodes {
+				if filesToUpdate.Has(paths[i]) {
 					nodesInUpdatedFiles = append(nodesInUpdatedFiles, nodes[i])
 				}
 			}
@@ -289,3 +665,45 @@ func setterSchema(name, value string) spec.Schema {
 	})
 	return *schema
 }
+
+// updateTemplate sets the fields of the given template to the given setter
+// values, and calls the given callbacks for the changes and the conflicts,
+// which are checked, like the scope of the setters, as in setAll. It returns the updated template, or nil if
+// it's unchanged. The unresolvedCallback is called for the fields marked with
+// none of the setters.
+func updateTemplate(tf TemplateFile, setterValues map[string]string, scope pathScope, maxJump SemverJump, conflicts *conflictCheck,
+	callback, conflictCallback, unresolvedCallback func(file string, oid ObjectIdentifier, workload WorkloadIdentifier, setterName string, line int, old, new string)) ([]byte, error) {
+	lines, fields := scanTemplate(tf.Data)
+	changed := false
+	for _, field := range fields {
+		newValue, ok := setterValues[field.setter]
+		if !ok {
+			unresolvedCallback(tf.Path, field.oid, WorkloadIdentifier{}, field.setter, field.line+1, field.oldValue, "")
+			continue
+		}
+		if newValue == field.oldValue || !scope.allows(field.setter, tf.Path) {
+			continue
+		}
+		if exceedsSemverJump(maxJump, field.setter, field.oldValue, newValue) {
+			return nil, &FileError{Path: tf.Path, Line: field.line + 1, Setter: field.setter,
+				Err: semverJumpError(field.oldValue, newValue, maxJump)}
+		}
+		if conflicts.isConflict(field.setter, field.oldValue, newValue) {
+			if conflicts.fail() {
+				return nil, &FileError{Path: tf.Path, Line: field.line + 1, Setter: field.setter,
+					Err: conflicts.error(field.setter, field.oldValue)}
+			}
+			conflictCallback(tf.Path, field.oid, WorkloadIdentifier{}, field.setter, field.line+1, field.oldValue, newValue)
+			if conflicts.skip() {
+				continue
+			}
+		}
+		setTemplateField(lines, field, newValue)
+		callback(tf.Path, field.oid, WorkloadIdentifier{}, field.setter, field.line+1, field.oldValue, newValue)
+		changed = true
+	}
+	if !changed {
+		return nil, nil
+	}
+	return []byte(strings.Join(lines, "\n")), nil
+}
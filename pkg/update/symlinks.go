@@ -0,0 +1,140 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+)
+
+// SymlinkPolicy decides what to do with the YAML files which are symbolic
+// links.
+type SymlinkPolicy string
+
+const (
+	// SymlinkPolicyFollow updates the targets of the symbolic links which
+	// are within the root of the update, and fails the update for the
+	// others. It's the default.
+	SymlinkPolicyFollow SymlinkPolicy = "Follow"
+	// SymlinkPolicyIgnore leaves the symbolic links and their targets
+	// unchanged.
+	SymlinkPolicyIgnore SymlinkPolicy = "Ignore"
+	// SymlinkPolicyFail fails the update when a symbolic link is found.
+	SymlinkPolicyFail SymlinkPolicy = "Fail"
+)
+
+var (
+	// ErrSymlink is the error of a symbolic link found with
+	// SymlinkPolicyFail.
+	ErrSymlink = errors.New("symbolic link not allowed")
+	// ErrSymlinkOutsideRoot is the error of a symbolic link whose target is
+	// outside of the root of the update.
+	ErrSymlinkOutsideRoot = errors.New("symbolic link target outside of the root")
+)
+
+// maxSymlinks is the maximum number of symbolic links resolved for a path,
+// like the limit of Linux.
+const maxSymlinks = 40
+
+// WithUpdateOptionSymlinkPolicy configures what the update does with the YAML
+// files which are symbolic links. With SymlinkPolicyFollow, the links are
+// never followed outside of the given root, e.g. the worktree of a Git
+// repository, which defaults to the input path of the update.
+func WithUpdateOptionSymlinkPolicy(policy SymlinkPolicy, root string) UpdateOption {
+	return func(uo *UpdateOptions) {
+		uo.symlinkPolicy = policy
+		uo.symlinkRoot = root
+	}
+}
+
+// isSymlink returns if the given file info is the one of a symbolic link.
+func isSymlink(info os.FileInfo) bool {
+	return info.Mode()&os.ModeSymlink != 0
+}
+
+// checkSymlink returns the error of the symbolic link at the given path p
+// with the policy of the reader, or nil if its target is to be updated. The
+// link is reported with the given path, relative to the path of the reader.
+func (r *ScreeningLocalReader) checkSymlink(root, p, path string) error {
+	if r.SymlinkPolicy == SymlinkPolicyFail {
+		return &FileError{Path: path, Err: ErrSymlink}
+	}
+
+	var target string
+	var err error
+	if wt, ok := r.FileSystem.FileSystem.(workTreeFileSystem); ok {
+		target, err = evalWorkTreeSymlinks(wt.fs, p)
+	} else if target, err = filepath.EvalSymlinks(p); err == nil {
+		// The root may be a link too, e.g. in a temporary directory.
+		root, err = filepath.EvalSymlinks(root)
+	}
+	if err != nil {
+		return &FileError{Path: path, Err: fmt.Errorf("failed to resolve symbolic link: %w", err)}
+	}
+	if !isWithin(root, target) {
+		return &FileError{Path: path, Err: ErrSymlinkOutsideRoot}
+	}
+	return nil
+}
+
+// isWithin returns if the given path is the given root, or is within it.
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// evalWorkTreeSymlinks returns the path of the given absolute path of the
+// billy.Filesystem after resolving the symbolic links it contains. An
+// absolute link target is outside of any worktree and refused.
+func evalWorkTreeSymlinks(fs billy.Filesystem, p string) (string, error) {
+	sep := string(filepath.Separator)
+	resolved := sep
+	rest := strings.Split(strings.TrimPrefix(filepath.Clean(p), sep), sep)
+	for links := 0; len(rest) > 0; {
+		next := filepath.Join(resolved, rest[0])
+		rest = rest[1:]
+		info, err := fs.Lstat(next)
+		if err != nil {
+			return "", err
+		}
+		if !isSymlink(info) {
+			resolved = next
+			continue
+		}
+		if links++; links > maxSymlinks {
+			return "", fmt.Errorf("too many levels of symbolic links in '%s'", p)
+		}
+		target, err := fs.Readlink(next)
+		if err != nil {
+			return "", err
+		}
+		if filepath.IsAbs(target) {
+			return "", fmt.Errorf("%w: '%s'", ErrSymlinkOutsideRoot, target)
+		}
+		// The link target is relative to the directory of the link, and
+		// resolved again from the root.
+		target = filepath.Join(resolved, target)
+		rest = append(strings.Split(strings.TrimPrefix(target, sep), sep), rest...)
+		resolved = sep
+	}
+	return resolved, nil
+}
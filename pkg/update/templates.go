@@ -0,0 +1,151 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+const (
+	// SkipReasonHelmTemplate is the reason of a file skipped because it's a
+	// Helm chart template, which can't be parsed as YAML.
+	SkipReasonHelmTemplate = "Helm template"
+	// SkipReasonInvalidYAML is the reason of a file skipped because it can't
+	// be parsed as YAML.
+	SkipReasonInvalidYAML = "invalid YAML"
+)
+
+// TemplateFile is a file with Go template syntax, e.g. a Helm chart
+// template, which is scanned line by line for marked fields instead of
+// being parsed as YAML.
+type TemplateFile struct {
+	// Path is the path of the file, relative to the path of the reader.
+	Path string
+	// Data is the content of the file.
+	Data []byte
+}
+
+// WithUpdateOptionScanTemplates configures the update to scan the files with
+// Go template syntax, e.g. Helm chart templates, for marked fields with a
+// plain scalar value, e.g. `image: foo:v1 # {"$imagepolicy": "ns:foo"}`, and
+// to update them in place. Without this option, these files are skipped.
+func WithUpdateOptionScanTemplates() UpdateOption {
+	return func(uo *UpdateOptions) {
+		uo.scanTemplates = true
+	}
+}
+
+// templateAction is the start of the actions of a Go template.
+const templateAction = "{{"
+
+// isChartTemplate returns if the file at the given path is in the templates
+// directory of one of the given chart directories, i.e. directories with a
+// Chart.yaml file.
+func isChartTemplate(path string, chartDirs map[string]struct{}) bool {
+	for dir := filepath.Dir(path); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if filepath.Base(dir) != "templates" {
+			continue
+		}
+		if _, ok := chartDirs[filepath.Dir(dir)]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// templateFieldPattern matches a line of a template setting a plain, possibly
+// quoted, scalar field marked with a setter, or a marked scalar entry of a
+// list. The field or the entry may be in lists nested in lists, e.g. in the
+// values of a HelmRelease. The groups are the prefix up to the value, the
+// opening quote, the value, the closing quote, and the marker comment with the
+// name of the setter.
+var templateFieldPattern = regexp.MustCompile(`^(\s*(?:(?:-\s+)*[^\s#{-][^#{]*?:\s+|(?:-\s+)+))(["']?)([^\s"'#{}]+)(["']?)(\s+#\s*\{\s*"\$imagepolicy"\s*:\s*"([^"]+)"\s*\}.*)$`)
+
+// templateField is a marked field found in a template.
+type templateField struct {
+	line     int
+	setter   string
+	oldValue string
+	oid      ObjectIdentifier
+}
+
+// scanTemplate splits the given template into lines and returns them with the
+// marked fields it finds. The fields are identified with the apiVersion, kind
+// and metadata name of their document, when these aren't templated.
+func scanTemplate(data []byte) ([]string, []templateField) {
+	lines := strings.Split(string(data), "\n")
+
+	var fields []templateField
+	var docFields []templateField
+	var id yaml.ResourceIdentifier
+	inMetadata := false
+	endDocument := func() {
+		for i := range docFields {
+			docFields[i].oid = ObjectIdentifier{ResourceIdentifier: id}
+		}
+		fields = append(fields, docFields...)
+		docFields = nil
+		id = yaml.ResourceIdentifier{}
+		inMetadata = false
+	}
+	for i, line := range lines {
+		if strings.HasPrefix(line, "---") {
+			endDocument()
+			continue
+		}
+		if line != "" && line[0] != ' ' && line[0] != '#' {
+			inMetadata = strings.HasPrefix(line, "metadata:")
+		}
+		if !strings.Contains(line, templateAction) {
+			_, value, _ := strings.Cut(line, ":")
+			value = strings.TrimSpace(value)
+			switch {
+			case strings.HasPrefix(line, "apiVersion:"):
+				id.APIVersion = value
+			case strings.HasPrefix(line, "kind:"):
+				id.Kind = value
+			case inMetadata && strings.HasPrefix(line, "  name:"):
+				id.Name = value
+			case inMetadata && strings.HasPrefix(line, "  namespace:"):
+				id.Namespace = value
+			}
+		}
+
+		m := templateFieldPattern.FindStringSubmatch(line)
+		if m == nil || m[2] != m[4] {
+			continue
+		}
+		docFields = append(docFields, templateField{
+			line:     i,
+			setter:   m[6],
+			oldValue: m[3],
+		})
+	}
+	endDocument()
+	return lines, fields
+}
+
+// setTemplateField sets the value of the given field in the lines of its
+// template.
+func setTemplateField(lines []string, field templateField, value string) {
+	m := templateFieldPattern.FindStringSubmatch(lines[field.line])
+	lines[field.line] = m[1] + m[2] + value + m[4] + m[5]
+}
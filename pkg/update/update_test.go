@@ -17,15 +17,31 @@ limitations under the License.
 package update
 
 import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/metrics"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-billy/v5/util"
 	"github.com/go-logr/logr"
 	"github.com/google/go-containerregistry/pkg/name"
 	. "github.com/onsi/gomega"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
 	"sigs.k8s.io/kustomize/kyaml/yaml"
 
+	templatesv1 "github.com/fluxcd/image-automation-controller/pkg/templates/v1"
 	"github.com/fluxcd/image-automation-controller/pkg/test"
 	imagev1_reflect "github.com/fluxcd/image-reflector-controller/api/v1beta2"
 )
@@ -59,13 +75,13 @@ func TestUpdateWithSetters(t *testing.T) {
 	g.Expect(err).ToNot(HaveOccurred())
 	test.ExpectMatchingDirectories(g, tmp, "testdata/setters/expected")
 
-	kustomizeResourceID := ObjectIdentifier{yaml.ResourceIdentifier{
+	kustomizeResourceID := ObjectIdentifier{ResourceIdentifier: yaml.ResourceIdentifier{
 		TypeMeta: yaml.TypeMeta{
 			APIVersion: "kustomize.config.k8s.io/v1beta1",
 			Kind:       "Kustomization",
 		},
 	}}
-	markedResourceID := ObjectIdentifier{yaml.ResourceIdentifier{
+	markedResourceID := ObjectIdentifier{ResourceIdentifier: yaml.ResourceIdentifier{
 		TypeMeta: yaml.TypeMeta{
 			APIVersion: "batch/v1beta1",
 			Kind:       "CronJob",
@@ -76,10 +92,10 @@ func TestUpdateWithSetters(t *testing.T) {
 		},
 	}}
 	r, _ := name.ParseReference("index.repo.fake/updated:v1.0.1")
-	expectedImageRef := imageRef{r, types.NamespacedName{
+	expectedImageRef := templatesv1.NewImageRef(r, types.NamespacedName{
 		Name:      "policy",
 		Namespace: "automation-ns",
-	}}
+	})
 
 	expectedResult := Result{
 		Files: map[string]FileResult{
@@ -117,11 +133,13 @@ func TestUpdateWithSetters(t *testing.T) {
 						OldValue: "replaced",
 						NewValue: "index.repo.fake/updated",
 						Setter:   "automation-ns:policy:name",
+						Line:     8,
 					},
 					{
 						OldValue: "v1",
 						NewValue: "v1.0.1",
 						Setter:   "automation-ns:policy:tag",
+						Line:     9,
 					},
 				},
 			},
@@ -131,6 +149,22 @@ func TestUpdateWithSetters(t *testing.T) {
 						OldValue: "image:v1.0.0",
 						NewValue: "index.repo.fake/updated:v1.0.1",
 						Setter:   "automation-ns:policy",
+						Line:     14,
+					},
+				},
+			},
+		},
+		// The marker of the policy in another namespace is reported.
+		UnresolvedMarkers: map[string]ObjectChanges{
+			"otherns.yaml": {
+				ObjectIdentifier{ResourceIdentifier: yaml.ResourceIdentifier{
+					TypeMeta: yaml.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+					NameMeta: yaml.NameMeta{Namespace: "bar", Name: "foo"},
+				}}: []Change{
+					{
+						OldValue: "user:v1.0.0",
+						Setter:   "other-namespace:policy",
+						Line:     11,
 					},
 				},
 			},
@@ -139,3 +173,1544 @@ func TestUpdateWithSetters(t *testing.T) {
 
 	g.Expect(resultV2).To(Equal(expectedResultV2))
 }
+
+func TestUpdateWithSetters_anchors(t *testing.T) {
+	g := NewWithT(t)
+
+	policies := []imagev1_reflect.ImagePolicy{
+		{
+			ObjectMeta: metav1.ObjectMeta{ // name matches marker used in testdata/anchors/{original,expected}
+				Namespace: "automation-ns",
+				Name:      "policy",
+			},
+			Status: imagev1_reflect.ImagePolicyStatus{
+				LatestImage: "index.repo.fake/updated:v1.0.1",
+			},
+		},
+	}
+
+	// Anchors, aliases and merge keys must be written back as they were.
+	tmp := t.TempDir()
+	result, err := UpdateV2WithSetters(logr.Discard(), "testdata/anchors/original", tmp, policies)
+	g.Expect(err).ToNot(HaveOccurred())
+	test.ExpectMatchingDirectories(g, tmp, "testdata/anchors/expected")
+
+	// The anchored value is updated once, not once per alias.
+	g.Expect(result.FileChanges).To(HaveKey("deployment.yaml"))
+	g.Expect(result.Changes()).To(HaveLen(1))
+	for _, changes := range result.FileChanges["deployment.yaml"] {
+		g.Expect(changes).To(HaveLen(2))
+	}
+}
+
+func TestUpdateWithSetters_helmRelease(t *testing.T) {
+	g := NewWithT(t)
+
+	policies := []imagev1_reflect.ImagePolicy{
+		{
+			ObjectMeta: metav1.ObjectMeta{ // name matches marker used in testdata/helmrelease/{original,expected}
+				Namespace: "automation-ns",
+				Name:      "policy",
+			},
+			Status: imagev1_reflect.ImagePolicyStatus{
+				LatestImage: "index.repo.fake/image:v1.0.1",
+			},
+		},
+	}
+
+	// The fields of the values of a HelmRelease are updated however deep
+	// they are, including in numeric-keyed maps and in lists of lists.
+	tmp := t.TempDir()
+	result, err := UpdateV2WithSetters(logr.Discard(), "testdata/helmrelease/original", tmp, policies)
+	g.Expect(err).ToNot(HaveOccurred())
+	test.ExpectMatchingDirectories(g, tmp, "testdata/helmrelease/expected")
+
+	// The changes are those of the HelmRelease.
+	helmRelease := ObjectIdentifier{ResourceIdentifier: yaml.ResourceIdentifier{
+		TypeMeta: yaml.TypeMeta{APIVersion: "helm.toolkit.fluxcd.io/v2", Kind: "HelmRelease"},
+		NameMeta: yaml.NameMeta{Namespace: "apps", Name: "app"},
+	}}
+	g.Expect(result.Objects()).To(HaveKey(helmRelease))
+	g.Expect(result.Objects()).To(HaveLen(1))
+	g.Expect(result.Objects()[helmRelease]).To(HaveLen(7))
+}
+
+func TestUpdateWithSetters_digest(t *testing.T) {
+	g := NewWithT(t)
+
+	policies := []imagev1_reflect.ImagePolicy{
+		{
+			ObjectMeta: metav1.ObjectMeta{ // name matches marker used in testdata/digest/{original,expected}
+				Namespace: "automation-ns",
+				Name:      "policy",
+			},
+			Status: imagev1_reflect.ImagePolicyStatus{
+				LatestImage: "index.repo.fake/updated@sha256:6c3c624b58dbbcd3c0dd82b4c53f04194d1247c6eebdaab7c610cf7d66709b3b",
+			},
+		},
+	}
+
+	// The name of an image pinned to a digest excludes the digest.
+	tmp := t.TempDir()
+	_, err := UpdateV2WithSetters(logr.Discard(), "testdata/digest/original", tmp, policies)
+	g.Expect(err).ToNot(HaveOccurred())
+	test.ExpectMatchingDirectories(g, tmp, "testdata/digest/expected")
+}
+
+func TestUpdateWithSetters_encodings(t *testing.T) {
+	g := NewWithT(t)
+
+	policy := imagev1_reflect.ImagePolicy{}
+	policy.Namespace = "automation-ns"
+	policy.Name = "policy"
+	policy.Status.LatestImage = "image:v1.0.1"
+
+	// The files are written back in their encoding, byte order mark
+	// included.
+	tmp := t.TempDir()
+	result, err := UpdateV2WithSetters(logr.Discard(), "testdata/encodings/original", tmp, []imagev1_reflect.ImagePolicy{policy})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.SkippedFiles).To(BeEmpty())
+	g.Expect(result.FileChanges).To(HaveLen(4))
+	test.ExpectMatchingDirectories(g, tmp, "testdata/encodings/expected")
+
+	// The lines of the changes are those of the decoded files.
+	for _, objChanges := range result.FileChanges {
+		for _, changes := range objChanges {
+			g.Expect(changes).To(HaveLen(1))
+			g.Expect(changes[0].Line).To(Equal(14))
+		}
+	}
+}
+
+func TestUpdateWithSetters_conflicts(t *testing.T) {
+	const deployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: foo
+  namespace: bar
+spec:
+  template:
+    spec:
+      containers:
+      - name: c
+        image: image:v1.0.0 # {"$imagepolicy": "automation-ns:policy"}
+      - name: d
+        image: image:hotfix # {"$imagepolicy": "automation-ns:other"}
+`
+	policies := []imagev1_reflect.ImagePolicy{}
+	previous := map[types.NamespacedName]string{}
+	for _, policyName := range []string{"policy", "other"} {
+		policy := imagev1_reflect.ImagePolicy{}
+		policy.Namespace = "automation-ns"
+		policy.Name = policyName
+		policy.Status.LatestImage = "image:v1.0.1"
+		policies = append(policies, policy)
+		previous[types.NamespacedName{Namespace: "automation-ns", Name: policyName}] = "image:v1.0.0"
+	}
+	conflict := Change{
+		OldValue: "image:hotfix",
+		NewValue: "image:v1.0.1",
+		Setter:   "automation-ns:other",
+		Line:     13,
+	}
+
+	tests := []struct {
+		name          string
+		options       []UpdateOption
+		wantErr       bool
+		wantImages    []string
+		wantChanges   int
+		wantConflicts []Change
+	}{
+		{
+			name:        "without previous images",
+			wantImages:  []string{"image:v1.0.1", "image:v1.0.1"},
+			wantChanges: 2,
+		},
+		{
+			name:          "overwrite",
+			options:       []UpdateOption{WithUpdateOptionConflictPolicy(ConflictPolicyOverwrite, previous)},
+			wantImages:    []string{"image:v1.0.1", "image:v1.0.1"},
+			wantChanges:   2,
+			wantConflicts: []Change{conflict},
+		},
+		{
+			name:          "skip",
+			options:       []UpdateOption{WithUpdateOptionConflictPolicy(ConflictPolicySkip, previous)},
+			wantImages:    []string{"image:v1.0.1", "image:hotfix"},
+			wantChanges:   1,
+			wantConflicts: []Change{conflict},
+		},
+		{
+			name:    "fail",
+			options: []UpdateOption{WithUpdateOptionConflictPolicy(ConflictPolicyFail, previous)},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			dir := t.TempDir()
+			g.Expect(os.WriteFile(filepath.Join(dir, "deployment.yaml"), []byte(deployment), 0o644)).To(Succeed())
+
+			result, err := UpdateV2WithSetters(logr.Discard(), dir, dir, policies, tt.options...)
+			if tt.wantErr {
+				g.Expect(err).To(MatchError(ErrConflict))
+				// Nothing is written.
+				b, err := os.ReadFile(filepath.Join(dir, "deployment.yaml"))
+				g.Expect(err).ToNot(HaveOccurred())
+				g.Expect(string(b)).To(Equal(deployment))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+
+			b, err := os.ReadFile(filepath.Join(dir, "deployment.yaml"))
+			g.Expect(err).ToNot(HaveOccurred())
+			node, err := yaml.Parse(string(b))
+			g.Expect(err).ToNot(HaveOccurred())
+			containers, err := node.Pipe(yaml.Lookup("spec", "template", "spec", "containers"))
+			g.Expect(err).ToNot(HaveOccurred())
+			var images []string
+			for _, c := range containers.Content() {
+				images = append(images, yaml.NewRNode(c).Field("image").Value.YNode().Value)
+			}
+			g.Expect(images).To(Equal(tt.wantImages))
+
+			g.Expect(result.Changes()).To(HaveLen(tt.wantChanges))
+			var conflicts []Change
+			for _, objChanges := range result.FileConflicts {
+				for _, changes := range objChanges {
+					conflicts = append(conflicts, changes...)
+				}
+			}
+			g.Expect(conflicts).To(Equal(tt.wantConflicts))
+		})
+	}
+}
+
+func TestUpdateWithSetters_ignore(t *testing.T) {
+	const deployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: %s
+  namespace: bar
+%s
+spec:
+  template:
+    spec:
+      containers:
+      - name: c
+        image: image:v1.0.0 # {"$imagepolicy": "automation-ns:policy"}
+`
+	policy := imagev1_reflect.ImagePolicy{}
+	policy.Namespace = "automation-ns"
+	policy.Name = "policy"
+	policy.Status.LatestImage = "image:v1.0.1"
+
+	tests := []struct {
+		name        string
+		header      string
+		annotations string
+		wantIgnored bool
+	}{
+		{
+			name: "not ignored",
+		},
+		{
+			name:        "annotation",
+			annotations: "  annotations:\n    image.toolkit.fluxcd.io/ignore: \"true\"",
+			wantIgnored: true,
+		},
+		{
+			name:        "annotation set to false",
+			annotations: "  annotations:\n    image.toolkit.fluxcd.io/ignore: \"false\"",
+		},
+		{
+			name:        "comment",
+			header:      "# Frozen during the incident.\n# image.toolkit.fluxcd.io/ignore: \"true\"\n",
+			wantIgnored: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			// The ignored object shares its file with another object.
+			frozen := tt.header + fmt.Sprintf(deployment, "frozen", tt.annotations)
+			other := fmt.Sprintf(deployment, "other", "")
+			dir := t.TempDir()
+			g.Expect(os.WriteFile(filepath.Join(dir, "deployments.yaml"), []byte(frozen+"---\n"+other), 0o644)).To(Succeed())
+
+			result, err := UpdateV2WithSetters(logr.Discard(), dir, dir, []imagev1_reflect.ImagePolicy{policy})
+			g.Expect(err).ToNot(HaveOccurred())
+
+			b, err := os.ReadFile(filepath.Join(dir, "deployments.yaml"))
+			g.Expect(err).ToNot(HaveOccurred())
+			docs := strings.Split(string(b), "---\n")
+			g.Expect(docs).To(HaveLen(2))
+			g.Expect(docs[1]).To(ContainSubstring("image: image:v1.0.1"))
+
+			frozenID := ObjectIdentifier{ResourceIdentifier: yaml.ResourceIdentifier{
+				TypeMeta: yaml.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+				NameMeta: yaml.NameMeta{Namespace: "bar", Name: "frozen"},
+			}}
+			if !tt.wantIgnored {
+				g.Expect(docs[0]).To(ContainSubstring("image: image:v1.0.1"))
+				g.Expect(result.IgnoredChanges).To(BeEmpty())
+				g.Expect(result.Changes()).To(HaveLen(1))
+				return
+			}
+			g.Expect(docs[0]).To(ContainSubstring("image: image:v1.0.0"))
+			g.Expect(result.FileChanges["deployments.yaml"]).ToNot(HaveKey(frozenID))
+			g.Expect(result.IgnoredChanges).To(Equal(map[string]ObjectChanges{
+				"deployments.yaml": {
+					frozenID: []Change{{
+						OldValue: "image:v1.0.0",
+						NewValue: "image:v1.0.1",
+						Setter:   "automation-ns:policy",
+						Line:     strings.Count(frozen[:strings.Index(frozen, "image:")], "\n") + 1,
+					}},
+				},
+			}))
+		})
+	}
+}
+
+func TestUpdateWithSetters_documents(t *testing.T) {
+	const deployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: %s
+spec:
+  template:
+    spec:
+      containers:
+      - name: c
+        image: image:v1.0.0 # {"$imagepolicy": "automation-ns:policy"}
+`
+	policy := imagev1_reflect.ImagePolicy{}
+	policy.Namespace = "automation-ns"
+	policy.Name = "policy"
+	policy.Status.LatestImage = "image:v1.0.1"
+
+	tests := []struct {
+		name        string
+		header      string
+		wantUpdated []bool
+	}{
+		{
+			name:        "no selection",
+			wantUpdated: []bool{true, true, true},
+		},
+		{
+			name:        "by index",
+			header:      "# image.toolkit.fluxcd.io/documents: \"1\"\n",
+			wantUpdated: []bool{false, true, false},
+		},
+		{
+			name:        "by index and name",
+			header:      "# Shared with the platform team.\n# image.toolkit.fluxcd.io/documents: \"0, third\"\n",
+			wantUpdated: []bool{true, false, true},
+		},
+		{
+			name:        "no document",
+			header:      "# image.toolkit.fluxcd.io/documents: \"\"\n",
+			wantUpdated: []bool{false, false, false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			names := []string{"first", "second", "third"}
+			var docs []string
+			for _, name := range names {
+				docs = append(docs, fmt.Sprintf(deployment, name))
+			}
+			dir := t.TempDir()
+			content := tt.header + strings.Join(docs, "---\n")
+			g.Expect(os.WriteFile(filepath.Join(dir, "deployments.yaml"), []byte(content), 0o644)).To(Succeed())
+
+			result, err := UpdateV2WithSetters(logr.Discard(), dir, dir, []imagev1_reflect.ImagePolicy{policy})
+			g.Expect(err).ToNot(HaveOccurred())
+
+			b, err := os.ReadFile(filepath.Join(dir, "deployments.yaml"))
+			g.Expect(err).ToNot(HaveOccurred())
+			if tt.header != "" {
+				g.Expect(string(b)).To(HavePrefix(tt.header))
+			}
+			docs = strings.Split(string(b), "---\n")
+			g.Expect(docs).To(HaveLen(len(names)))
+			var updated, ignored int
+			for i, want := range tt.wantUpdated {
+				if want {
+					g.Expect(docs[i]).To(ContainSubstring("image: image:v1.0.1"), names[i])
+					updated++
+				} else {
+					g.Expect(docs[i]).To(ContainSubstring("image: image:v1.0.0"), names[i])
+					ignored++
+				}
+			}
+			g.Expect(result.FileChanges["deployments.yaml"]).To(HaveLen(updated))
+			g.Expect(result.IgnoredChanges["deployments.yaml"]).To(HaveLen(ignored))
+		})
+	}
+}
+
+func TestUpdateWithSetters_lines(t *testing.T) {
+	g := NewWithT(t)
+
+	// The lines are those of the file, across its documents, including
+	// the empty ones.
+	const deployments = `# Deployments of the app.
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: foo
+  namespace: bar
+spec:
+  template:
+    spec:
+      containers:
+      - name: c
+        image: image:v1.0.0 # {"$imagepolicy": "automation-ns:policy"}
+---
+# Nothing to see here.
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: baz
+  namespace: bar
+spec:
+  template:
+    spec:
+      containers:
+      - name: c
+        image: image # {"$imagepolicy": "automation-ns:policy:name"}
+      - name: d
+        image: image:v1.0.0 # {"$imagepolicy": "automation-ns:policy"}
+`
+	policy := imagev1_reflect.ImagePolicy{}
+	policy.Namespace = "automation-ns"
+	policy.Name = "policy"
+	policy.Status.LatestImage = "registry/image:v1.0.1"
+
+	dir := t.TempDir()
+	g.Expect(os.WriteFile(filepath.Join(dir, "deployments.yaml"), []byte(deployments), 0o644)).To(Succeed())
+
+	result, err := UpdateV2WithSetters(logr.Discard(), dir, dir, []imagev1_reflect.ImagePolicy{policy})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	var lines []int
+	objects := slices.SortedFunc(maps.Keys(result.FileChanges["deployments.yaml"]), func(a, b ObjectIdentifier) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	for _, oid := range objects {
+		for _, ch := range result.FileChanges["deployments.yaml"][oid] {
+			lines = append(lines, ch.Line)
+		}
+	}
+	g.Expect(lines).To(Equal([]int{27, 29, 13}))
+
+	// The same change is only listed once, at its first line.
+	g.Expect(result.Changes()).To(HaveLen(2))
+}
+
+func TestUpdateWithSetters_maxSemverJump(t *testing.T) {
+	const deployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: foo
+  namespace: bar
+spec:
+  template:
+    spec:
+      containers:
+      - name: c
+        image: image:v1.2.3 # {"$imagepolicy": "automation-ns:policy"}
+`
+	tests := []struct {
+		name        string
+		latestImage string
+		maxJump     SemverJump
+		wantErr     bool
+	}{
+		{name: "patch within patch", latestImage: "image:v1.2.4", maxJump: SemverJumpPatch},
+		{name: "minor exceeds patch", latestImage: "image:v1.3.0", maxJump: SemverJumpPatch, wantErr: true},
+		{name: "minor within minor", latestImage: "image:v1.3.0", maxJump: SemverJumpMinor},
+		{name: "major exceeds minor", latestImage: "image:v2.0.0", maxJump: SemverJumpMinor, wantErr: true},
+		{name: "downgrade exceeds minor", latestImage: "image:v0.9.0", maxJump: SemverJumpMinor, wantErr: true},
+		{name: "major within major", latestImage: "image:v2.0.0", maxJump: SemverJumpMajor},
+		{name: "major without limit", latestImage: "image:v2.0.0"},
+		{name: "not semver", latestImage: "image:main-abc123", maxJump: SemverJumpPatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			dir := t.TempDir()
+			g.Expect(os.WriteFile(filepath.Join(dir, "deployment.yaml"), []byte(deployment), 0o644)).To(Succeed())
+
+			policy := imagev1_reflect.ImagePolicy{}
+			policy.Namespace = "automation-ns"
+			policy.Name = "policy"
+			policy.Status.LatestImage = tt.latestImage
+
+			result, err := UpdateV2WithSetters(logr.Discard(), dir, dir, []imagev1_reflect.ImagePolicy{policy},
+				WithUpdateOptionMaxSemverJump(tt.maxJump))
+			b, rerr := os.ReadFile(filepath.Join(dir, "deployment.yaml"))
+			g.Expect(rerr).ToNot(HaveOccurred())
+			if tt.wantErr {
+				g.Expect(err).To(MatchError(ErrSemverJumpExceeded))
+				g.Expect(string(b)).To(Equal(deployment))
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(result.Changes()).To(HaveLen(1))
+			g.Expect(string(b)).To(ContainSubstring("image: " + tt.latestImage))
+		})
+	}
+}
+
+func TestUpdateWithSetters_fileError(t *testing.T) {
+	g := NewWithT(t)
+
+	const manifests = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  image: image:v1.2.3 # {"$imagepolicy": "automation-ns:other"}
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: foo
+spec:
+  template:
+    spec:
+      containers:
+      - name: c
+        image: image:v1.2.3 # {"$imagepolicy": "automation-ns:policy"}
+`
+	dir := t.TempDir()
+	g.Expect(os.MkdirAll(filepath.Join(dir, "apps"), 0o755)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, "apps", "manifests.yaml"), []byte(manifests), 0o644)).To(Succeed())
+
+	policy := imagev1_reflect.ImagePolicy{}
+	policy.Namespace = "automation-ns"
+	policy.Name = "policy"
+	policy.Status.LatestImage = "image:v2.0.0"
+
+	_, err := UpdateV2WithSetters(logr.Discard(), dir, dir, []imagev1_reflect.ImagePolicy{policy},
+		WithUpdateOptionMaxSemverJump(SemverJumpMinor))
+	g.Expect(err).To(MatchError(ErrSemverJumpExceeded))
+
+	var fileErr *FileError
+	g.Expect(errors.As(err, &fileErr)).To(BeTrue())
+	g.Expect(fileErr.Path).To(Equal(filepath.Join("apps", "manifests.yaml")))
+	g.Expect(fileErr.Document).To(Equal(1))
+	g.Expect(fileErr.Line).To(Equal(10))
+	g.Expect(fileErr.Setter).To(Equal("automation-ns:policy"))
+	g.Expect(err.Error()).To(HavePrefix(`'apps/manifests.yaml' document 2 line 10, marker {"$imagepolicy": "automation-ns:policy"}: maximum semver jump exceeded`))
+}
+
+func TestUpdateWithSetters_workloads(t *testing.T) {
+	g := NewWithT(t)
+
+	const manifests = `apiVersion: kafka.strimzi.io/v1beta2
+kind: Kafka
+metadata:
+  name: events
+spec:
+  kafka:
+    image: kafka:v1.2.3 # {"$imagepolicy": "automation-ns:policy"}
+  entityOperator:
+    topicOperator:
+      image: kafka:v1.2.3 # {"$imagepolicy": "automation-ns:policy"}
+---
+apiVersion: elasticsearch.k8s.elastic.co/v1
+kind: Elasticsearch
+metadata:
+  name: logs
+spec:
+  nodeSets:
+  - name: hot
+    podTemplate:
+      spec:
+        containers:
+        - name: elasticsearch
+          image: kafka:v1.2.3 # {"$imagepolicy": "automation-ns:policy"}
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+      - name: c
+        image: kafka:v1.2.3 # {"$imagepolicy": "automation-ns:policy"}
+`
+	dir := t.TempDir()
+	g.Expect(os.WriteFile(filepath.Join(dir, "manifests.yaml"), []byte(manifests), 0o644)).To(Succeed())
+
+	policy := imagev1_reflect.ImagePolicy{}
+	policy.Namespace = "automation-ns"
+	policy.Name = "policy"
+	policy.Status.LatestImage = "kafka:v1.2.4"
+
+	result, err := UpdateV2WithSetters(logr.Discard(), dir, dir, []imagev1_reflect.ImagePolicy{policy})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	workloads := map[string][]string{}
+	for oid, changes := range result.Objects() {
+		for _, ch := range changes {
+			workloads[oid.Kind+"/"+oid.Name] = append(workloads[oid.Kind+"/"+oid.Name], ch.Workload.String())
+		}
+	}
+	g.Expect(workloads).To(Equal(map[string][]string{
+		"Kafka/events":       {"StrimziPodSet/events-kafka", "Deployment/events-entity-operator"},
+		"Elasticsearch/logs": {"StatefulSet/logs-es-hot"},
+		"Deployment/app":     {""},
+	}))
+}
+
+func TestUpdateWithSetters_patches(t *testing.T) {
+	g := NewWithT(t)
+
+	policy := imagev1_reflect.ImagePolicy{}
+	policy.Namespace = "automation-ns"
+	policy.Name = "policy"
+	policy.Status.LatestImage = "index.repo.fake/image:v1.0.1"
+
+	// The fields of the patches embedded in the Kustomizations are set in
+	// place, including in the Kustomizations without apiVersion and kind.
+	tmp := t.TempDir()
+	result, err := UpdateV2WithSetters(logr.Discard(), "testdata/patches/original", tmp, []imagev1_reflect.ImagePolicy{policy})
+	g.Expect(err).ToNot(HaveOccurred())
+	test.ExpectMatchingDirectories(g, tmp, "testdata/patches/expected")
+
+	// The changes are attributed to the workloads patched.
+	type change struct {
+		line     int
+		workload string
+	}
+	changes := map[string][]change{}
+	for file, objects := range result.FileChanges {
+		for oid, objChanges := range objects {
+			for _, ch := range objChanges {
+				key := file + ":" + oid.Kind + "/" + oid.Name
+				changes[key] = append(changes[key], change{ch.Line, ch.Workload.String()})
+			}
+		}
+	}
+	g.Expect(changes).To(Equal(map[string][]change{
+		"kustomization.yaml:Kustomization/": {
+			{15, "Deployment/app"},
+			{26, "Deployment/worker"},
+			{34, ""},
+			{48, "StatefulSet/db"},
+		},
+		"flux.yaml:Kustomization/apps": {
+			{23, "Deployment/frontend"},
+		},
+	}))
+}
+
+func TestUpdateWithSetters_symlinks(t *testing.T) {
+	const deployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: foo
+spec:
+  template:
+    spec:
+      containers:
+      - name: c
+        image: image:v1.0.0 # {"$imagepolicy": "automation-ns:policy"}
+`
+	policy := imagev1_reflect.ImagePolicy{}
+	policy.Namespace = "automation-ns"
+	policy.Name = "policy"
+	policy.Status.LatestImage = "image:v1.0.1"
+
+	tests := []struct {
+		name        string
+		policy      SymlinkPolicy
+		escape      bool
+		wantErr     error
+		wantUpdated bool
+	}{
+		{name: "follow by default", wantUpdated: true},
+		{name: "follow", policy: SymlinkPolicyFollow, wantUpdated: true},
+		{name: "follow outside of the root", policy: SymlinkPolicyFollow, escape: true, wantErr: ErrSymlinkOutsideRoot},
+		{name: "ignore", policy: SymlinkPolicyIgnore},
+		{name: "ignore outside of the root", policy: SymlinkPolicyIgnore, escape: true},
+		{name: "fail", policy: SymlinkPolicyFail, wantErr: ErrSymlink},
+	}
+	for _, inMemory := range []bool{false, true} {
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%s (in memory: %t)", tt.name, inMemory), func(t *testing.T) {
+				g := NewWithT(t)
+
+				// The repository is in repo/, with the link in repo/apps/.
+				// Its target is either in repo/shared/, or outside of the
+				// repository in shared/.
+				dir := t.TempDir()
+				var fs billy.Filesystem = osfs.New(dir)
+				if inMemory {
+					fs = memfs.New()
+				}
+				target := "repo/shared/deployment.yaml"
+				linkTarget := "../shared/deployment.yaml"
+				if tt.escape {
+					target = "shared/deployment.yaml"
+					linkTarget = "../../shared/deployment.yaml"
+				}
+				g.Expect(util.WriteFile(fs, target, []byte(deployment), 0o644)).To(Succeed())
+				g.Expect(fs.MkdirAll("repo/apps", 0o755)).To(Succeed())
+				g.Expect(fs.Symlink(linkTarget, "repo/apps/deployment.yaml")).To(Succeed())
+
+				root, path := filepath.Join(dir, "repo"), filepath.Join(dir, "repo", "apps")
+				opts := []UpdateOption{WithUpdateOptionSymlinkPolicy(tt.policy, root)}
+				if inMemory {
+					root, path = "repo", "repo/apps"
+					opts = []UpdateOption{WithUpdateOptionSymlinkPolicy(tt.policy, root), WithUpdateOptionWorkTree(fs)}
+				}
+				result, err := UpdateV2WithSetters(logr.Discard(), path, path, []imagev1_reflect.ImagePolicy{policy}, opts...)
+				if tt.wantErr != nil {
+					g.Expect(err).To(MatchError(tt.wantErr))
+					var fileErr *FileError
+					g.Expect(errors.As(err, &fileErr)).To(BeTrue())
+					g.Expect(fileErr.Path).To(Equal("deployment.yaml"))
+				} else {
+					g.Expect(err).ToNot(HaveOccurred())
+				}
+
+				b, err := util.ReadFile(fs, target)
+				g.Expect(err).ToNot(HaveOccurred())
+				if tt.wantUpdated {
+					g.Expect(result.Changes()).To(HaveLen(1))
+					g.Expect(string(b)).To(ContainSubstring("image: image:v1.0.1"))
+					// The link is left as it is.
+					info, err := fs.Lstat("repo/apps/deployment.yaml")
+					g.Expect(err).ToNot(HaveOccurred())
+					g.Expect(isSymlink(info)).To(BeTrue())
+				} else {
+					g.Expect(result.Changes()).To(BeEmpty())
+					g.Expect(string(b)).To(Equal(deployment))
+				}
+			})
+		}
+	}
+}
+
+func Test_exceedsSemverJump(t *testing.T) {
+	tests := []struct {
+		setter   string
+		oldValue string
+		newValue string
+		maxJump  SemverJump
+		want     bool
+	}{
+		{setter: "ns:policy:tag", oldValue: "1.2.3", newValue: "1.3.0", maxJump: SemverJumpPatch, want: true},
+		{setter: "ns:policy:tag", oldValue: "1.2.3", newValue: "1.2.4-rc.1", maxJump: SemverJumpPatch},
+		{setter: "ns:policy", oldValue: "registry:5000/image:v1.2.3", newValue: "registry:5000/image:v2.0.0", maxJump: SemverJumpMinor, want: true},
+		{setter: "ns:policy", oldValue: "image@sha256:" + strings.Repeat("a", 64), newValue: "image:v2.0.0", maxJump: SemverJumpPatch},
+		{setter: "ns:policy:name", oldValue: "image", newValue: "other", maxJump: SemverJumpPatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.setter+" "+tt.oldValue+" "+tt.newValue, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(exceedsSemverJump(tt.maxJump, tt.setter, tt.oldValue, tt.newValue)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestUpdateWithSetters_templates(t *testing.T) {
+	const template = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ include "chart.fullname" . }}
+  namespace: bar
+spec:
+  template:
+    spec:
+      containers:
+      - name: c
+        image: "image:v1.0.0" # {"$imagepolicy": "automation-ns:policy"}
+      {{- if .Values.sidecar.enabled }}
+      - name: sidecar
+        image: {{ .Values.sidecar.image }} # {"$imagepolicy": "automation-ns:policy"}
+      {{- end }}
+`
+	const values = `image:
+  tag: v1.0.0 # {"$imagepolicy": "automation-ns:policy:tag"}
+`
+	files := map[string]string{
+		"chart/Chart.yaml":                "name: chart\n",
+		"chart/values.yaml":               values,
+		"chart/templates/deployment.yaml": template,
+		"other/broken.yaml":               "image: image:v1.0.0 # {\"$imagepolicy\": \"automation-ns:policy\"}\n  - broken\n",
+	}
+
+	policy := imagev1_reflect.ImagePolicy{}
+	policy.Namespace = "automation-ns"
+	policy.Name = "policy"
+	policy.Status.LatestImage = "image:v1.0.1"
+
+	tests := []struct {
+		name         string
+		options      []UpdateOption
+		wantTemplate string
+		wantSkipped  []string
+		wantChanges  int
+	}{
+		{
+			name:         "skip templates",
+			wantTemplate: template,
+			wantSkipped:  []string{"chart/templates/deployment.yaml", "other/broken.yaml"},
+			wantChanges:  1,
+		},
+		{
+			name:         "scan templates",
+			options:      []UpdateOption{WithUpdateOptionScanTemplates()},
+			wantTemplate: strings.Replace(template, `"image:v1.0.0"`, `"image:v1.0.1"`, 1),
+			wantSkipped:  []string{"other/broken.yaml"},
+			wantChanges:  2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			dir := t.TempDir()
+			for path, content := range files {
+				g.Expect(os.MkdirAll(filepath.Dir(filepath.Join(dir, path)), 0o755)).To(Succeed())
+				g.Expect(os.WriteFile(filepath.Join(dir, path), []byte(content), 0o644)).To(Succeed())
+			}
+
+			result, err := UpdateV2WithSetters(logr.Discard(), dir, dir, []imagev1_reflect.ImagePolicy{policy}, tt.options...)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			b, err := os.ReadFile(filepath.Join(dir, "chart/templates/deployment.yaml"))
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(string(b)).To(Equal(tt.wantTemplate))
+			b, err = os.ReadFile(filepath.Join(dir, "chart/values.yaml"))
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(string(b)).To(ContainSubstring("tag: v1.0.1"))
+
+			g.Expect(result.SkippedFiles).To(HaveLen(len(tt.wantSkipped)))
+			for _, path := range tt.wantSkipped {
+				g.Expect(result.SkippedFiles).To(HaveKey(path))
+			}
+			if _, ok := result.SkippedFiles["chart/templates/deployment.yaml"]; ok {
+				g.Expect(result.SkippedFiles["chart/templates/deployment.yaml"]).To(Equal(SkipReasonHelmTemplate))
+			}
+			g.Expect(result.SkippedFiles["other/broken.yaml"]).To(HavePrefix(SkipReasonInvalidYAML))
+
+			changes := 0
+			for _, objChanges := range result.FileChanges {
+				for _, ch := range objChanges {
+					changes += len(ch)
+				}
+			}
+			g.Expect(changes).To(Equal(tt.wantChanges))
+			if tt.wantChanges == 2 {
+				oid := ObjectIdentifier{ResourceIdentifier: yaml.ResourceIdentifier{
+					TypeMeta: yaml.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+					NameMeta: yaml.NameMeta{Namespace: "bar"},
+				}}
+				g.Expect(result.FileChanges["chart/templates/deployment.yaml"]).To(HaveKeyWithValue(oid, []Change{{
+					OldValue: "image:v1.0.0",
+					NewValue: "image:v1.0.1",
+					Setter:   "automation-ns:policy",
+					Line:     11,
+				}}))
+			}
+		})
+	}
+}
+
+func Test_scanTemplate(t *testing.T) {
+	g := NewWithT(t)
+
+	data := []byte(`kind: ConfigMap
+metadata:
+  name: first
+data:
+  image: 'image:v1' # {"$imagepolicy": "ns:policy"}
+  other: value # a comment
+---
+kind: {{ .Values.kind }}
+spec:
+  - tag: v1 # {"$imagepolicy": "ns:policy:tag"}
+    image: "image:v1' # {"$imagepolicy": "ns:policy"}
+    templated: {{ .Values.tag }} # {"$imagepolicy": "ns:policy:tag"}
+---
+apiVersion: helm.toolkit.fluxcd.io/v2
+kind: HelmRelease
+metadata:
+  name: app
+spec:
+  values:
+    replicas: {{ .Values.replicas }}
+    0:
+      image: image:v1 # {"$imagepolicy": "ns:policy"}
+    images:
+    - image:v1 # {"$imagepolicy": "ns:policy"}
+    - - tag: v1 # {"$imagepolicy": "ns:policy:tag"}
+      - - "image:v1" # {"$imagepolicy": "ns:policy"}
+`)
+	helmRelease := ObjectIdentifier{ResourceIdentifier: yaml.ResourceIdentifier{
+		TypeMeta: yaml.TypeMeta{APIVersion: "helm.toolkit.fluxcd.io/v2", Kind: "HelmRelease"},
+		NameMeta: yaml.NameMeta{Name: "app"},
+	}}
+	lines, fields := scanTemplate(data)
+	g.Expect(fields).To(Equal([]templateField{
+		{line: 4, setter: "ns:policy", oldValue: "image:v1", oid: ObjectIdentifier{ResourceIdentifier: yaml.ResourceIdentifier{
+			TypeMeta: yaml.TypeMeta{Kind: "ConfigMap"},
+			NameMeta: yaml.NameMeta{Name: "first"},
+		}}},
+		{line: 9, setter: "ns:policy:tag", oldValue: "v1"},
+		{line: 21, setter: "ns:policy", oldValue: "image:v1", oid: helmRelease},
+		{line: 23, setter: "ns:policy", oldValue: "image:v1", oid: helmRelease},
+		{line: 24, setter: "ns:policy:tag", oldValue: "v1", oid: helmRelease},
+		{line: 25, setter: "ns:policy", oldValue: "image:v1", oid: helmRelease},
+	}))
+
+	setTemplateField(lines, fields[0], "image:v2")
+	setTemplateField(lines, fields[1], "v2")
+	g.Expect(lines[4]).To(Equal(`  image: 'image:v2' # {"$imagepolicy": "ns:policy"}`))
+	g.Expect(lines[9]).To(Equal(`  - tag: v2 # {"$imagepolicy": "ns:policy:tag"}`))
+
+	// The entries of the lists nested in the values of a HelmRelease.
+	setTemplateField(lines, fields[3], "image:v2")
+	setTemplateField(lines, fields[4], "v2")
+	setTemplateField(lines, fields[5], "image:v2")
+	g.Expect(lines[23]).To(Equal(`    - image:v2 # {"$imagepolicy": "ns:policy"}`))
+	g.Expect(lines[24]).To(Equal(`    - - tag: v2 # {"$imagepolicy": "ns:policy:tag"}`))
+	g.Expect(lines[25]).To(Equal(`      - - "image:v2" # {"$imagepolicy": "ns:policy"}`))
+}
+
+func TestUpdateWithSetters_owner(t *testing.T) {
+	const marked = `image: image:v1.0.0 # {"$imagepolicy": "automation-ns:policy"}
+`
+	files := map[string]string{
+		"team-x/annotated.yaml":  "# flux-owner: team-x, team-z\n" + marked,
+		"team-x/codeowners.yaml": marked,
+		"team-y/annotated.yaml":  "# flux-owner: team-y\n" + marked,
+		"shared/unowned.yaml":    marked,
+	}
+	const codeOwners = `# Teams
+/apps/team-x/ @org/team-x
+/apps/team-y/ @org/team-y
+`
+
+	policy := imagev1_reflect.ImagePolicy{}
+	policy.Namespace = "automation-ns"
+	policy.Name = "policy"
+	policy.Status.LatestImage = "image:v1.0.1"
+
+	tests := []struct {
+		name    string
+		path    string
+		owner   string
+		wantErr string
+	}{
+		{name: "annotated", path: "team-x/annotated.yaml", owner: "team-z"},
+		{name: "codeowners", path: "team-x/codeowners.yaml", owner: "org/team-x"},
+		{name: "codeowners with annotation", path: "team-y", owner: "@org/team-y",
+			wantErr: "'annotated.yaml' (owned by team-y)"},
+		{name: "other owner", path: "team-x", owner: "team-x",
+			wantErr: "'codeowners.yaml' (owned by @org/team-x)"},
+		{name: "no owner", path: "shared", owner: "team-x",
+			wantErr: "'unowned.yaml' (no owner)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			dir := t.TempDir()
+			for path, content := range files {
+				g.Expect(os.MkdirAll(filepath.Dir(filepath.Join(dir, "apps", path)), 0o755)).To(Succeed())
+				g.Expect(os.WriteFile(filepath.Join(dir, "apps", path), []byte(content), 0o644)).To(Succeed())
+			}
+			path := filepath.Join(dir, "apps", tt.path)
+			dirPath := filepath.Join("apps", filepath.Dir(tt.path))
+			if filepath.Ext(tt.path) == "" {
+				dirPath = filepath.Join("apps", tt.path)
+			}
+
+			result, err := UpdateV2WithSetters(logr.Discard(), path, path, []imagev1_reflect.ImagePolicy{policy},
+				WithUpdateOptionOwner(tt.owner), WithUpdateOptionCodeOwners([]byte(codeOwners), dirPath))
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ErrNotOwned))
+				g.Expect(err.Error()).To(ContainSubstring(tt.wantErr))
+				g.Expect(result.FileChanges).To(BeEmpty())
+				for p, content := range files {
+					b, err := os.ReadFile(filepath.Join(dir, "apps", p))
+					g.Expect(err).ToNot(HaveOccurred())
+					g.Expect(string(b)).To(Equal(content))
+				}
+				return
+			}
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(result.FileChanges).To(HaveLen(1))
+		})
+	}
+}
+
+func Test_codeOwnersPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{pattern: "*", path: "apps/app.yaml", want: true},
+		{pattern: "*.yaml", path: "apps/app.yaml", want: true},
+		{pattern: "*.yaml", path: "apps/app.yml", want: false},
+		{pattern: "/apps/", path: "apps/team/app.yaml", want: true},
+		{pattern: "/apps/", path: "other/apps/app.yaml", want: false},
+		{pattern: "apps/", path: "other/apps/app.yaml", want: true},
+		{pattern: "apps/*.yaml", path: "apps/app.yaml", want: true},
+		{pattern: "apps/*.yaml", path: "apps/team/app.yaml", want: false},
+		{pattern: "apps/**/app.yaml", path: "apps/team/x/app.yaml", want: true},
+		{pattern: "/apps/app.yaml", path: "apps/app.yaml", want: true},
+		{pattern: "app.yaml", path: "apps/app.yaml.bak", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			g := NewWithT(t)
+			re, err := codeOwnersPattern(tt.pattern)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(re.MatchString(tt.path)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestUpdateWithSetters_workers(t *testing.T) {
+	g := NewWithT(t)
+
+	policies := generatePolicies(10)
+	src := t.TempDir()
+	generateManifests(t, src, 200, policies)
+
+	sequentialOut := t.TempDir()
+	sequential, err := UpdateV2WithSetters(logr.Discard(), src, sequentialOut, policies, WithUpdateOptionWorkers(1))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(sequential.FileChanges).To(HaveLen(100))
+
+	for _, workers := range []int{0, 2, 16} {
+		out := t.TempDir()
+		result, err := UpdateV2WithSetters(logr.Discard(), src, out, policies, WithUpdateOptionWorkers(workers))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result).To(Equal(sequential))
+		test.ExpectMatchingDirectories(g, out, sequentialOut)
+	}
+}
+
+func BenchmarkUpdateV2WithSetters(b *testing.B) {
+	policies := generatePolicies(10)
+	src := b.TempDir()
+	generateManifests(b, src, 2000, policies)
+
+	for name, workers := range map[string]int{
+		"sequential": 1,
+		"parallel":   runtime.GOMAXPROCS(0),
+	} {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				out := b.TempDir()
+				if _, err := UpdateV2WithSetters(logr.Discard(), src, out, policies, WithUpdateOptionWorkers(workers)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateWithSetters_memoryBudget(t *testing.T) {
+	g := NewWithT(t)
+
+	policies := generatePolicies(10)
+	src := t.TempDir()
+	generateManifests(t, src, 200, policies)
+
+	allOut := t.TempDir()
+	all, err := UpdateV2WithSetters(logr.Discard(), src, allOut, policies)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(all.FileChanges).To(HaveLen(100))
+
+	// A budget smaller than a file gives a batch per file.
+	for _, budget := range []int64{1, 64 << 10, 1 << 30} {
+		out := t.TempDir()
+		result, err := UpdateV2WithSetters(logr.Discard(), src, out, policies, WithUpdateOptionMemoryBudget(budget))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(result).To(Equal(all))
+		test.ExpectMatchingDirectories(g, out, allOut)
+	}
+}
+
+func TestUpdateWithSetters_memoryBudgetFailure(t *testing.T) {
+	g := NewWithT(t)
+
+	policies := generatePolicies(10)
+	src := t.TempDir()
+	generateManifests(t, src, 20, policies)
+	// The last file walked exceeds the maximum semver jump.
+	g.Expect(os.WriteFile(filepath.Join(src, "dir-9", "z.yaml"), []byte(`image: index.repo.fake/image-0:v0.1.0 # {"$imagepolicy": "automation-ns:policy-0"}
+`), 0o644)).To(Succeed())
+
+	_, err := UpdateV2WithSetters(logr.Discard(), src, src, policies, WithUpdateOptionMemoryBudget(1),
+		WithUpdateOptionMaxSemverJump(SemverJumpMinor))
+	g.Expect(err).To(MatchError(ErrSemverJumpExceeded))
+
+	// None of the files updated by the previous batches was written.
+	b, err := os.ReadFile(filepath.Join(src, "dir-0", "app-0.yaml"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(b)).To(ContainSubstring("index.repo.fake/image-0:v1.0.0"))
+}
+
+func BenchmarkUpdateV2WithSetters_memoryBudget(b *testing.B) {
+	policies := generatePolicies(10)
+	src := b.TempDir()
+	generateManifests(b, src, 5000, policies)
+
+	for name, budget := range map[string]int64{
+		"unlimited": 0,
+		"1MiB":      1 << 20,
+	} {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				out := b.TempDir()
+				runtime.GC()
+				peak := samplePeakHeap()
+				if _, err := UpdateV2WithSetters(logr.Discard(), src, out, policies, WithUpdateOptionMemoryBudget(budget)); err != nil {
+					b.Fatal(err)
+				}
+				b.ReportMetric(float64(peak())/(1<<20), "peak-heap-MiB")
+			}
+		})
+	}
+}
+
+// samplePeakHeap samples the memory taken by the heap objects until the
+// returned function is called, which returns their peak size in bytes.
+func samplePeakHeap() func() uint64 {
+	sample := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	var peak uint64
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+		for {
+			metrics.Read(sample)
+			peak = max(peak, sample[0].Value.Uint64())
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return func() uint64 {
+		close(done)
+		<-stopped
+		return peak
+	}
+}
+
+// generatePolicies returns n image policies with a latest image, named
+// policy-0 to policy-<n-1> in the automation-ns namespace.
+func generatePolicies(n int) []imagev1_reflect.ImagePolicy {
+	var policies []imagev1_reflect.ImagePolicy
+	for i := 0; i < n; i++ {
+		policies = append(policies, imagev1_reflect.ImagePolicy{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "automation-ns",
+				Name:      fmt.Sprintf("policy-%d", i),
+			},
+			Status: imagev1_reflect.ImagePolicyStatus{
+				LatestImage: fmt.Sprintf("index.repo.fake/image-%d:v1.0.1", i),
+			},
+		})
+	}
+	return policies
+}
+
+// generateManifests writes n Deployment files spread across
+// subdirectories of dir. Every other file has an image marked with one
+// of the given policies; the rest are unmarked.
+func generateManifests(t testing.TB, dir string, n int, policies []imagev1_reflect.ImagePolicy) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		image := fmt.Sprintf("index.repo.fake/image-%d:v1.0.0", i%len(policies))
+		if i%2 == 0 {
+			image += fmt.Sprintf(` # {"$imagepolicy": "automation-ns:policy-%d"}`, i%len(policies))
+		}
+		manifest := fmt.Sprintf(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app-%d
+  namespace: default
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: %s
+`, i, image)
+		sub := filepath.Join(dir, fmt.Sprintf("dir-%d", i%20))
+		if err := os.MkdirAll(sub, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(sub, fmt.Sprintf("app-%d.yaml", i)), []byte(manifest), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestUpdateWithSetters_readOnly(t *testing.T) {
+	g := NewWithT(t)
+
+	const manifest = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: %s
+data:
+  image: %s # {"$imagepolicy": "automation-ns:policy"}
+`
+	dir := t.TempDir()
+	files := map[string]struct {
+		image string
+		mode  os.FileMode
+	}{
+		"readonly.yaml":   {"image:v1.2.3", 0o444},
+		"executable.yaml": {"image:v1.2.3", 0o555},
+		"unchanged.yaml":  {"image:v2.0.0", 0o444},
+	}
+	past := time.Now().Add(-time.Hour)
+	for name, f := range files {
+		path := filepath.Join(dir, name)
+		g.Expect(os.WriteFile(path, []byte(fmt.Sprintf(manifest, name, f.image)), f.mode)).To(Succeed())
+		g.Expect(os.Chtimes(path, past, past)).To(Succeed())
+	}
+
+	policy := imagev1_reflect.ImagePolicy{}
+	policy.Namespace = "automation-ns"
+	policy.Name = "policy"
+	policy.Status.LatestImage = "image:v2.0.0"
+
+	result, err := UpdateV2WithSetters(logr.Discard(), dir, dir, []imagev1_reflect.ImagePolicy{policy})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.FileChanges).To(HaveLen(2))
+
+	// The files are updated with their modes restored, and the unchanged
+	// file isn't written.
+	for name, f := range files {
+		path := filepath.Join(dir, name)
+		data, err := os.ReadFile(path)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(string(data)).To(Equal(fmt.Sprintf(manifest, name, "image:v2.0.0")), name)
+		info, err := os.Stat(path)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(info.Mode().Perm()).To(Equal(f.mode), name)
+		if name == "unchanged.yaml" {
+			g.Expect(info.ModTime()).To(BeTemporally("~", past, time.Second))
+		}
+	}
+}
+
+func Test_writableFileSystem_error(t *testing.T) {
+	g := NewWithT(t)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "apps", "readonly.yaml")
+	g.Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+	g.Expect(os.WriteFile(path, []byte("old"), 0o444)).To(Succeed())
+
+	fs := newWritableFileSystem(filesys.MakeFsOnDisk(), dir)
+	fs.chmod = func(string, os.FileMode) error {
+		return os.ErrPermission
+	}
+	err := fs.WriteFile(path, []byte("new"))
+	g.Expect(err).To(MatchError(os.ErrPermission))
+	var fileErr *FileError
+	g.Expect(errors.As(err, &fileErr)).To(BeTrue())
+	g.Expect(err.Error()).To(Equal("'apps/readonly.yaml': failed to make the read-only file writable: permission denied"))
+
+	data, err := os.ReadFile(path)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(data)).To(Equal("old"))
+}
+
+func TestUpdateWithSetters_workTree(t *testing.T) {
+	g := NewWithT(t)
+
+	policies := []imagev1_reflect.ImagePolicy{
+		{
+			ObjectMeta: metav1.ObjectMeta{ // name matches marker used in testdata/setters/{original,expected}
+				Namespace: "automation-ns",
+				Name:      "policy",
+			},
+			Status: imagev1_reflect.ImagePolicyStatus{
+				LatestImage: "index.repo.fake/updated:v1.0.1",
+			},
+		},
+	}
+
+	// Update the files on disk, for comparison.
+	tmp := t.TempDir()
+	expectedResult, err := UpdateV2WithSetters(logr.Discard(), "testdata/setters/original", tmp, policies)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// Update the same files in memory.
+	wt := memfs.New()
+	original, err := os.ReadDir("testdata/setters/original")
+	g.Expect(err).ToNot(HaveOccurred())
+	for _, f := range original {
+		b, err := os.ReadFile(filepath.Join("testdata/setters/original", f.Name()))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(util.WriteFile(wt, filepath.Join("config", f.Name()), b, 0o644)).To(Succeed())
+	}
+	result, err := UpdateV2WithSetters(logr.Discard(), "config", "config", policies, WithUpdateOptionWorkTree(wt))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result).To(Equal(expectedResult))
+
+	// The updated files match the expected files, and the other files are
+	// left as they were.
+	expected, err := os.ReadDir("testdata/setters/expected")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(expected).ToNot(BeEmpty())
+	for _, f := range original {
+		want, err := os.ReadFile(filepath.Join("testdata/setters/expected", f.Name()))
+		if os.IsNotExist(err) {
+			want, err = os.ReadFile(filepath.Join("testdata/setters/original", f.Name()))
+		}
+		g.Expect(err).ToNot(HaveOccurred())
+		got, err := util.ReadFile(wt, filepath.Join("config", f.Name()))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(string(got)).To(Equal(string(want)), f.Name())
+	}
+}
+
+func TestWriteLockFile(t *testing.T) {
+	g := NewWithT(t)
+
+	policy := func(name, image string) imagev1_reflect.ImagePolicy {
+		p := imagev1_reflect.ImagePolicy{}
+		p.Namespace = "automation-ns"
+		p.Name = name
+		p.Status.LatestImage = image
+		return p
+	}
+	wt := memfs.New()
+	g.Expect(wt.MkdirAll("apps", 0o755)).To(Succeed())
+
+	changed, err := WriteLockFile("apps", []imagev1_reflect.ImagePolicy{
+		policy("redis", "redis:7.2.4@sha256:6745aaad46d795c9836632e1fb62f24b7e7f4c843144da8e47a5465c411a14be"),
+		policy("podinfo", "ghcr.io/stefanprodan/podinfo:6.5.0"),
+		policy("pending", ""),
+	}, WithUpdateOptionWorkTree(wt))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(changed).To(BeTrue())
+	b, err := util.ReadFile(wt, "apps/"+LockFileName)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(b)).To(Equal(lockFileHeader + `schemaVersion: v1
+images:
+- policy: automation-ns/podinfo
+  image: ghcr.io/stefanprodan/podinfo:6.5.0
+- policy: automation-ns/redis
+  image: redis:7.2.4
+  digest: sha256:6745aaad46d795c9836632e1fb62f24b7e7f4c843144da8e47a5465c411a14be
+`))
+
+	// The same images leave the lock file unchanged.
+	changed, err = WriteLockFile("apps", []imagev1_reflect.ImagePolicy{
+		policy("podinfo", "ghcr.io/stefanprodan/podinfo:6.5.0"),
+	}, WithUpdateOptionWorkTree(wt))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(changed).To(BeFalse())
+
+	// The images of a subset of the policies are updated, and the others
+	// are kept.
+	changed, err = WriteLockFile("apps", []imagev1_reflect.ImagePolicy{
+		policy("podinfo", "ghcr.io/stefanprodan/podinfo:6.5.1"),
+	}, WithUpdateOptionWorkTree(wt))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(changed).To(BeTrue())
+	b, err = util.ReadFile(wt, "apps/"+LockFileName)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(b)).To(ContainSubstring("image: ghcr.io/stefanprodan/podinfo:6.5.1\n"))
+	g.Expect(string(b)).To(ContainSubstring("policy: automation-ns/redis\n"))
+
+	// An invalid lock file isn't overwritten.
+	g.Expect(util.WriteFile(wt, "apps/"+LockFileName, []byte("images: {"), 0o644)).To(Succeed())
+	_, err = WriteLockFile("apps", []imagev1_reflect.ImagePolicy{
+		policy("podinfo", "ghcr.io/stefanprodan/podinfo:6.5.1"),
+	}, WithUpdateOptionWorkTree(wt))
+	var fileErr *FileError
+	g.Expect(errors.As(err, &fileErr)).To(BeTrue())
+	g.Expect(fileErr.Path).To(Equal(LockFileName))
+}
+
+func TestUpdateKustomizeImages(t *testing.T) {
+	g := NewWithT(t)
+
+	policy := func(name, image string) imagev1_reflect.ImagePolicy {
+		p := imagev1_reflect.ImagePolicy{}
+		p.Namespace = "automation-ns"
+		p.Name = name
+		p.Status.LatestImage = image
+		return p
+	}
+	wt := memfs.New()
+	g.Expect(wt.MkdirAll("apps/staging", 0o755)).To(Succeed())
+
+	// The component is created with the images of the policies.
+	result, err := UpdateKustomizeImages(logr.Discard(), "apps/staging", []imagev1_reflect.ImagePolicy{
+		policy("redis", "redis:7.2.4@sha256:6745aaad46d795c9836632e1fb62f24b7e7f4c843144da8e47a5465c411a14be"),
+		policy("podinfo", "ghcr.io/stefanprodan/podinfo:6.5.0"),
+		policy("pending", ""),
+	}, WithUpdateOptionWorkTree(wt))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.FileChanges).To(HaveKey(KustomizeImagesFile))
+	g.Expect(result.ImageResult.Images()).To(HaveLen(2))
+	b, err := util.ReadFile(wt, "apps/staging/"+KustomizeImagesFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(b)).To(Equal(kustomizeImagesHeader + `apiVersion: kustomize.config.k8s.io/v1alpha1
+kind: Component
+images:
+- name: ghcr.io/stefanprodan/podinfo
+  newTag: 6.5.0
+- name: redis
+  newTag: 7.2.4
+  digest: sha256:6745aaad46d795c9836632e1fb62f24b7e7f4c843144da8e47a5465c411a14be
+`))
+
+	// The same images leave the component unchanged.
+	result, err = UpdateKustomizeImages(logr.Discard(), "apps/staging", []imagev1_reflect.ImagePolicy{
+		policy("podinfo", "ghcr.io/stefanprodan/podinfo:6.5.0"),
+	}, WithUpdateOptionWorkTree(wt))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.HasChanges()).To(BeFalse())
+
+	// The changed images are reported, and the other images are kept.
+	result, err = UpdateKustomizeImages(logr.Discard(), "apps/staging", []imagev1_reflect.ImagePolicy{
+		policy("podinfo", "ghcr.io/stefanprodan/podinfo:6.5.1"),
+	}, WithUpdateOptionWorkTree(wt))
+	g.Expect(err).ToNot(HaveOccurred())
+	var changes []Change
+	for _, objChanges := range result.FileChanges[KustomizeImagesFile] {
+		changes = append(changes, objChanges...)
+	}
+	g.Expect(changes).To(Equal([]Change{{
+		OldValue: "ghcr.io/stefanprodan/podinfo:6.5.0",
+		NewValue: "ghcr.io/stefanprodan/podinfo:6.5.1",
+		Setter:   "automation-ns:podinfo",
+	}}))
+	b, err = util.ReadFile(wt, "apps/staging/"+KustomizeImagesFile)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(b)).To(ContainSubstring("- name: ghcr.io/stefanprodan/podinfo\n  newTag: 6.5.1\n"))
+	g.Expect(string(b)).To(ContainSubstring("- name: redis\n"))
+
+	// A jump above the maximum fails the update.
+	_, err = UpdateKustomizeImages(logr.Discard(), "apps/staging", []imagev1_reflect.ImagePolicy{
+		policy("podinfo", "ghcr.io/stefanprodan/podinfo:7.0.0"),
+	}, WithUpdateOptionWorkTree(wt), WithUpdateOptionMaxSemverJump(SemverJumpMinor))
+	g.Expect(err).To(MatchError(ErrSemverJumpExceeded))
+
+	// Two policies can't set the same image.
+	_, err = UpdateKustomizeImages(logr.Discard(), "apps/staging", []imagev1_reflect.ImagePolicy{
+		policy("podinfo", "ghcr.io/stefanprodan/podinfo:6.5.1"),
+		policy("podinfo-next", "ghcr.io/stefanprodan/podinfo:6.6.0"),
+	}, WithUpdateOptionWorkTree(wt))
+	g.Expect(err).To(MatchError(ContainSubstring("already set by policy 'automation-ns/podinfo'")))
+
+	// An invalid component isn't overwritten.
+	g.Expect(util.WriteFile(wt, "apps/staging/"+KustomizeImagesFile, []byte("images: {"), 0o644)).To(Succeed())
+	_, err = UpdateKustomizeImages(logr.Discard(), "apps/staging", []imagev1_reflect.ImagePolicy{
+		policy("podinfo", "ghcr.io/stefanprodan/podinfo:6.5.1"),
+	}, WithUpdateOptionWorkTree(wt))
+	var fileErr *FileError
+	g.Expect(errors.As(err, &fileErr)).To(BeTrue())
+	g.Expect(fileErr.Path).To(Equal(KustomizeImagesFile))
+}
+
+func TestUpdateWithSetters_policyPaths(t *testing.T) {
+	g := NewWithT(t)
+
+	manifest := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+      - name: hello
+        image: helloworld:1.0.0 # {"$imagepolicy": "automation-ns:policy"}
+      - name: other
+        image: other:1.0.0 # {"$imagepolicy": "automation-ns:other"}
+`
+	wt := memfs.New()
+	for _, file := range []string{"apps/foo/deploy.yaml", "apps/bar/deploy.yaml"} {
+		g.Expect(util.WriteFile(wt, filepath.Join("config", file), []byte(manifest), 0o644)).To(Succeed())
+	}
+	policies := []imagev1_reflect.ImagePolicy{
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "automation-ns", Name: "policy"},
+			Status:     imagev1_reflect.ImagePolicyStatus{LatestImage: "helloworld:1.0.1"},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "automation-ns", Name: "other"},
+			Status:     imagev1_reflect.ImagePolicyStatus{LatestImage: "other:1.0.1"},
+		},
+	}
+
+	result, err := UpdateV2WithSetters(logr.Discard(), "config", "config", policies, WithUpdateOptionWorkTree(wt),
+		WithUpdateOptionPolicyPaths(map[types.NamespacedName]string{
+			{Namespace: "automation-ns", Name: "policy"}: "apps/foo",
+		}))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.FileChanges).To(HaveLen(2))
+
+	// The restricted policy only sets the fields within its path, the other
+	// policy sets them everywhere.
+	foo, err := util.ReadFile(wt, "config/apps/foo/deploy.yaml")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(foo)).To(ContainSubstring("helloworld:1.0.1"))
+	g.Expect(string(foo)).To(ContainSubstring("other:1.0.1"))
+	bar, err := util.ReadFile(wt, "config/apps/bar/deploy.yaml")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(bar)).To(ContainSubstring("helloworld:1.0.0"))
+	g.Expect(string(bar)).To(ContainSubstring("other:1.0.1"))
+}
+
+func TestIsWithinPath(t *testing.T) {
+	tests := []struct {
+		path, dir string
+		want      bool
+	}{
+		{"apps/foo/deploy.yaml", "apps/foo", true},
+		{"apps/foo", "./apps/foo/", true},
+		{"apps/foobar/deploy.yaml", "apps/foo", false},
+		{"apps", "apps/foo", false},
+		{"apps/foo", ".", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path+" in "+tt.dir, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(IsWithinPath(tt.path, tt.dir)).To(Equal(tt.want))
+		})
+	}
+}
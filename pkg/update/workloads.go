@@ -0,0 +1,89 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"regexp"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// workloadRule names the workload of the fields of a kind of custom resource
+// whose path matches the rule.
+type workloadRule struct {
+	// group is the API group of the custom resource.
+	group string
+	// kind is the kind of the custom resource.
+	kind string
+	// path matches the path of the field, e.g. '.spec.kafka.image', in which
+	// the elements of a list with a name are '[name=<name>]'.
+	path *regexp.Regexp
+	// workloadKind is the kind of the workload.
+	workloadKind string
+	// suffix is the suffix of the name of the workload, appended to the name
+	// of the custom resource. A '$1' is replaced by the first submatch of
+	// the path.
+	suffix string
+}
+
+// workloadRules are the rules of the operators hiding the images of their
+// workloads in nested fields of their custom resources.
+var workloadRules = []workloadRule{
+	// Strimzi.
+	{group: "kafka.strimzi.io", kind: "Kafka", path: regexp.MustCompile(`^\.spec\.kafka\.`), workloadKind: "StrimziPodSet", suffix: "-kafka"},
+	{group: "kafka.strimzi.io", kind: "Kafka", path: regexp.MustCompile(`^\.spec\.zookeeper\.`), workloadKind: "StrimziPodSet", suffix: "-zookeeper"},
+	{group: "kafka.strimzi.io", kind: "Kafka", path: regexp.MustCompile(`^\.spec\.entityOperator\.`), workloadKind: "Deployment", suffix: "-entity-operator"},
+	{group: "kafka.strimzi.io", kind: "Kafka", path: regexp.MustCompile(`^\.spec\.kafkaExporter\.`), workloadKind: "Deployment", suffix: "-kafka-exporter"},
+	{group: "kafka.strimzi.io", kind: "Kafka", path: regexp.MustCompile(`^\.spec\.cruiseControl\.`), workloadKind: "Deployment", suffix: "-cruise-control"},
+	{group: "kafka.strimzi.io", kind: "KafkaConnect", path: regexp.MustCompile(`^\.spec\.`), workloadKind: "StrimziPodSet", suffix: "-connect"},
+	{group: "kafka.strimzi.io", kind: "KafkaMirrorMaker2", path: regexp.MustCompile(`^\.spec\.`), workloadKind: "StrimziPodSet", suffix: "-mirrormaker2"},
+	{group: "kafka.strimzi.io", kind: "KafkaBridge", path: regexp.MustCompile(`^\.spec\.`), workloadKind: "Deployment", suffix: "-bridge"},
+	// Elastic Cloud on Kubernetes. The image of an Elasticsearch cluster is
+	// run by the StatefulSets of all its node sets, so only the images of
+	// the pod templates of the node sets are attributed.
+	{group: "elasticsearch.k8s.elastic.co", kind: "Elasticsearch", path: regexp.MustCompile(`^\.spec\.nodeSets\[name=([^\]]+)\]\.`), workloadKind: "StatefulSet", suffix: "-es-$1"},
+	{group: "kibana.k8s.elastic.co", kind: "Kibana", path: regexp.MustCompile(`^\.spec\.`), workloadKind: "Deployment", suffix: "-kb"},
+	{group: "apm.k8s.elastic.co", kind: "ApmServer", path: regexp.MustCompile(`^\.spec\.`), workloadKind: "Deployment", suffix: "-apm-server"},
+	{group: "logstash.k8s.elastic.co", kind: "Logstash", path: regexp.MustCompile(`^\.spec\.`), workloadKind: "StatefulSet", suffix: "-ls"},
+	{group: "beat.k8s.elastic.co", kind: "Beat", path: regexp.MustCompile(`^\.spec\.daemonSet\.`), workloadKind: "DaemonSet", suffix: "-beat"},
+	{group: "beat.k8s.elastic.co", kind: "Beat", path: regexp.MustCompile(`^\.spec\.deployment\.`), workloadKind: "Deployment", suffix: "-beat"},
+	{group: "agent.k8s.elastic.co", kind: "Agent", path: regexp.MustCompile(`^\.spec\.daemonSet\.`), workloadKind: "DaemonSet", suffix: "-agent"},
+	{group: "agent.k8s.elastic.co", kind: "Agent", path: regexp.MustCompile(`^\.spec\.deployment\.`), workloadKind: "Deployment", suffix: "-agent"},
+}
+
+// workloadOf returns the workload running the image of the field with the
+// given path in the object, or a zero workload if the object isn't a custom
+// resource of a known operator or the field isn't attributed to a workload.
+func workloadOf(id yaml.ResourceIdentifier, path string) WorkloadIdentifier {
+	if id.Name == "" {
+		return WorkloadIdentifier{}
+	}
+	group, _, _ := strings.Cut(id.APIVersion, "/")
+	for _, rule := range workloadRules {
+		if rule.group != group || rule.kind != id.Kind {
+			continue
+		}
+		m := rule.path.FindStringSubmatchIndex(path)
+		if m == nil {
+			continue
+		}
+		suffix := string(rule.path.ExpandString(nil, rule.suffix, path, m))
+		return WorkloadIdentifier{Kind: rule.workloadKind, Name: id.Name + suffix}
+	}
+	return WorkloadIdentifier{}
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+func Test_workloadOf(t *testing.T) {
+	id := func(apiVersion, kind, name string) yaml.ResourceIdentifier {
+		return yaml.ResourceIdentifier{
+			TypeMeta: yaml.TypeMeta{APIVersion: apiVersion, Kind: kind},
+			NameMeta: yaml.NameMeta{Name: name},
+		}
+	}
+
+	tests := []struct {
+		name string
+		id   yaml.ResourceIdentifier
+		path string
+		want WorkloadIdentifier
+	}{
+		{
+			name: "Kafka brokers",
+			id:   id("kafka.strimzi.io/v1beta2", "Kafka", "events"),
+			path: ".spec.kafka.image",
+			want: WorkloadIdentifier{Kind: "StrimziPodSet", Name: "events-kafka"},
+		},
+		{
+			name: "Kafka topic operator",
+			id:   id("kafka.strimzi.io/v1beta2", "Kafka", "events"),
+			path: ".spec.entityOperator.topicOperator.image",
+			want: WorkloadIdentifier{Kind: "Deployment", Name: "events-entity-operator"},
+		},
+		{
+			name: "Elasticsearch node set",
+			id:   id("elasticsearch.k8s.elastic.co/v1", "Elasticsearch", "logs"),
+			path: ".spec.nodeSets[name=hot].podTemplate.spec.containers[name=elasticsearch].image",
+			want: WorkloadIdentifier{Kind: "StatefulSet", Name: "logs-es-hot"},
+		},
+		{
+			name: "Elasticsearch image of all node sets",
+			id:   id("elasticsearch.k8s.elastic.co/v1", "Elasticsearch", "logs"),
+			path: ".spec.image",
+		},
+		{
+			name: "Kibana",
+			id:   id("kibana.k8s.elastic.co/v1", "Kibana", "ui"),
+			path: ".spec.image",
+			want: WorkloadIdentifier{Kind: "Deployment", Name: "ui-kb"},
+		},
+		{
+			name: "same kind in another group",
+			id:   id("example.com/v1", "Kafka", "events"),
+			path: ".spec.kafka.image",
+		},
+		{
+			name: "Deployment",
+			id:   id("apps/v1", "Deployment", "app"),
+			path: ".spec.template.spec.containers[name=app].image",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(workloadOf(tt.id, tt.path)).To(Equal(tt.want))
+		})
+	}
+}
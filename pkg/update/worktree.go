@@ -0,0 +1,125 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+// workTreeFileSystem adapts a billy.Filesystem, like the worktree of a Git
+// repository checked out in memory, to the filesys.FileSystem used by the
+// kyaml readers and writers. Paths are interpreted from the root of the
+// billy.Filesystem.
+type workTreeFileSystem struct {
+	fs billy.Filesystem
+}
+
+var _ filesys.FileSystem = workTreeFileSystem{}
+
+// workTreeFile adds Stat to a billy.File, as required by filesys.File.
+type workTreeFile struct {
+	billy.File
+	fs billy.Filesystem
+}
+
+func (f workTreeFile) Stat() (os.FileInfo, error) {
+	return f.fs.Stat(f.Name())
+}
+
+func (w workTreeFileSystem) Create(path string) (filesys.File, error) {
+	f, err := w.fs.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return workTreeFile{File: f, fs: w.fs}, nil
+}
+
+func (w workTreeFileSystem) Mkdir(path string) error {
+	return w.fs.MkdirAll(path, 0o755)
+}
+
+func (w workTreeFileSystem) MkdirAll(path string) error {
+	return w.fs.MkdirAll(path, 0o755)
+}
+
+func (w workTreeFileSystem) RemoveAll(path string) error {
+	return util.RemoveAll(w.fs, path)
+}
+
+func (w workTreeFileSystem) Open(path string) (filesys.File, error) {
+	f, err := w.fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return workTreeFile{File: f, fs: w.fs}, nil
+}
+
+func (w workTreeFileSystem) IsDir(path string) bool {
+	info, err := w.fs.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func (w workTreeFileSystem) ReadDir(path string) ([]string, error) {
+	infos, err := w.fs.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	return names, nil
+}
+
+func (w workTreeFileSystem) CleanedAbs(path string) (filesys.ConfirmedDir, string, error) {
+	path = filepath.Join(string(filepath.Separator), path)
+	info, err := w.fs.Stat(path)
+	if err != nil {
+		return "", "", fmt.Errorf("'%s' doesn't exist: %w", path, err)
+	}
+	if info.IsDir() {
+		return filesys.ConfirmedDir(path), "", nil
+	}
+	return filesys.ConfirmedDir(filepath.Dir(path)), filepath.Base(path), nil
+}
+
+func (w workTreeFileSystem) Exists(path string) bool {
+	_, err := w.fs.Stat(path)
+	return err == nil
+}
+
+func (w workTreeFileSystem) Glob(pattern string) ([]string, error) {
+	return util.Glob(w.fs, pattern)
+}
+
+func (w workTreeFileSystem) ReadFile(path string) ([]byte, error) {
+	return util.ReadFile(w.fs, path)
+}
+
+func (w workTreeFileSystem) WriteFile(path string, data []byte) error {
+	return util.WriteFile(w.fs, path, data, 0o644)
+}
+
+func (w workTreeFileSystem) Walk(path string, walkFn filepath.WalkFunc) error {
+	return util.Walk(w.fs, path, walkFn)
+}
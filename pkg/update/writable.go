@@ -0,0 +1,154 @@
+/*
+Copyright 2024 The Flux authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package update
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/go-git/go-billy/v5"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+// writableFileSystem wraps the filesys.FileSystem the updated files are
+// written to, for the read-only files of the source to be updated too: the
+// mode of a read-only file is made writable by its owner while the file is
+// written, and restored afterwards, executable bits included. The files whose
+// content doesn't change aren't written at all. The files read in an encoding
+// other than UTF-8 are written back in their encoding.
+type writableFileSystem struct {
+	filesys.FileSystem
+	// dir is the updated directory, the paths of the errors and the
+	// encodings are relative to.
+	dir   string
+	stat  func(path string) (os.FileInfo, error)
+	chmod func(path string, mode os.FileMode) error
+	// encodings is the encoding of each file which isn't plain UTF-8, as
+	// recorded by ScreeningLocalReader.
+	encodings map[string]Encoding
+}
+
+// newWritableFileSystem returns the writableFileSystem of the given
+// filesys.FileSystem, either the disk or a workTreeFileSystem, for the files
+// of the given directory.
+func newWritableFileSystem(fsys filesys.FileSystem, dir string) writableFileSystem {
+	w := writableFileSystem{FileSystem: fsys, dir: dir, stat: os.Stat, chmod: os.Chmod}
+	if wt, ok := fsys.(workTreeFileSystem); ok {
+		w.stat = wt.fs.Stat
+		w.chmod = func(path string, mode os.FileMode) error {
+			// The modes of a worktree which doesn't support them can't
+			// prevent the writes either.
+			if change, ok := wt.fs.(billy.Change); ok {
+				return change.Chmod(path, mode)
+			}
+			return nil
+		}
+	}
+	return w
+}
+
+// WriteFile writes the data to the file at the given path, making it writable
+// for the time of the write if it's read-only. The returned error is a
+// FileError.
+func (w writableFileSystem) WriteFile(path string, data []byte) error {
+	if enc := w.encodings[w.relPath(path)]; enc != "" {
+		encoded, err := encodeFile(data, enc)
+		if err != nil {
+			return w.fileError(path, err)
+		}
+		data = encoded
+	}
+	info, err := w.stat(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return w.fileError(path, w.FileSystem.WriteFile(path, data))
+	case err != nil:
+		return w.fileError(path, err)
+	}
+	if previous, err := w.FileSystem.ReadFile(path); err == nil && bytes.Equal(previous, data) {
+		return nil
+	}
+
+	mode := info.Mode().Perm()
+	if mode&0o200 != 0 {
+		return w.fileError(path, w.FileSystem.WriteFile(path, data))
+	}
+	if err := w.chmod(path, mode|0o200); err != nil {
+		return w.fileError(path, fmt.Errorf("failed to make the read-only file writable: %w", err))
+	}
+	err = w.FileSystem.WriteFile(path, data)
+	if chmodErr := w.chmod(path, mode); chmodErr != nil && err == nil {
+		err = fmt.Errorf("failed to restore the mode %s of the file: %w", mode, chmodErr)
+	}
+	return w.fileError(path, err)
+}
+
+// fileError returns the FileError of the given error of writing the file at
+// the given path, nil if the error is nil.
+func (w writableFileSystem) fileError(path string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &FileError{Path: filepath.ToSlash(w.relPath(path)), Err: err}
+}
+
+// relPath returns the given path relative to the updated directory, or the
+// path itself if it's outside of it.
+func (w writableFileSystem) relPath(path string) string {
+	rel, err := filepath.Rel(w.dir, path)
+	if err != nil {
+		return path
+	}
+	return rel
+}
+
+// stagedFileSystem stages the files written to it in memory until they're
+// written to its writableFileSystem by commit, for the files updated by
+// several pipelines to be written only once all of them succeeded. Only the
+// updated files are staged, not the nodes they're serialized from.
+type stagedFileSystem struct {
+	writableFileSystem
+	files map[string][]byte
+}
+
+// newStagedFileSystem returns a stagedFileSystem staging the files to be
+// written to the given writableFileSystem.
+func newStagedFileSystem(w writableFileSystem) *stagedFileSystem {
+	return &stagedFileSystem{writableFileSystem: w, files: map[string][]byte{}}
+}
+
+// WriteFile stages the data of the file at the given path.
+func (s *stagedFileSystem) WriteFile(path string, data []byte) error {
+	s.files[path] = bytes.Clone(data)
+	return nil
+}
+
+// commit writes the staged files, in the order of their paths.
+func (s *stagedFileSystem) commit() error {
+	for _, p := range slices.Sorted(maps.Keys(s.files)) {
+		if err := s.writableFileSystem.WriteFile(p, s.files[p]); err != nil {
+			return err
+		}
+	}
+	return nil
+}